@@ -0,0 +1,126 @@
+// Package cache keeps read API responses in Redis, shared by all instances,
+// or in process memory when Redis is not configured. Entries expire after a
+// per-namespace TTL, and a namespace is invalidated as a whole when the data
+// behind it changes (e.g. after a crawl run). In memory an invalidation only
+// reaches the instance that made it, so there entries live at most a minute.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+// cacheLog logs cache store failures
+var cacheLog = logging.Component("cache")
+
+// Namespaces of cached responses. TTLs are set per namespace by the
+// cache.ttls runtime setting.
+const (
+	NamespacePrices     = "prices"     // Daily candles
+	NamespaceIndicators = "indicators" // Liquidity metrics, screener results and signals
+	NamespaceStocks     = "stocks"     // Stock list and search results
+)
+
+// Namespaces lists every namespace, to flush the whole cache
+var Namespaces = []string{NamespacePrices, NamespaceIndicators, NamespaceStocks}
+
+// storeWarnInterval limits how often store failures are logged; while the
+// store fails every read goes to the database
+const storeWarnInterval = time.Minute
+
+// Store keeps cache entries. Entries are grouped in namespaces that carry a
+// generation: invalidating a namespace starts a new generation, and entries
+// of older generations are never returned again.
+type Store interface {
+	// Get returns the entry of key in the current generation of namespace,
+	// and that generation
+	Get(ctx context.Context, namespace, key string) (value []byte, generation int64, found bool, err error)
+	// Set stores an entry under generation; it is dropped when the namespace
+	// has moved to a newer generation
+	Set(ctx context.Context, namespace string, generation int64, key string, value []byte, ttl time.Duration) error
+	// Invalidate starts a new generation of namespace
+	Invalidate(ctx context.Context, namespace string) error
+}
+
+// NewStore returns a Redis-backed store shared by all instances when Redis
+// is configured, or an in-memory store per instance otherwise (see MemoryStore)
+func NewStore() Store {
+	if config.Redis != nil {
+		return NewRedisStore(config.Redis)
+	}
+	return NewMemoryStore(defaultMemoryEntries)
+}
+
+// Cache stores JSON-encoded values in a Store. Store failures are logged and
+// treated as misses, so a Redis outage slows reads down without failing them.
+type Cache struct {
+	store Store
+
+	warnMu   sync.Mutex
+	lastWarn time.Time
+}
+
+// New creates a cache over store
+func New(store Store) *Cache {
+	return &Cache{store: store}
+}
+
+// Fetch returns the cached value of key in namespace, or calls load and
+// caches its result for the namespace's TTL. Errors from load are returned
+// and not cached. Without a TTL for the namespace, load is called directly.
+func Fetch[T any](ctx context.Context, c *Cache, namespace, key string, load func(ctx context.Context) (T, error)) (T, error) {
+	ttl := config.Runtime().CacheTTL(namespace)
+	if c == nil || ttl <= 0 {
+		return load(ctx)
+	}
+
+	raw, generation, found, err := c.store.Get(ctx, namespace, key)
+	if err != nil {
+		c.warn("read", namespace, err)
+	}
+	if found {
+		var value T
+		if err := json.Unmarshal(raw, &value); err == nil {
+			return value, nil
+		}
+	}
+
+	value, err := load(ctx)
+	if err != nil {
+		return value, err
+	}
+	if raw, err := json.Marshal(value); err == nil {
+		if err := c.store.Set(ctx, namespace, generation, key, raw, ttl); err != nil {
+			c.warn("write", namespace, err)
+		}
+	}
+	return value, nil
+}
+
+// Invalidate drops every cached entry of namespaces
+func (c *Cache) Invalidate(ctx context.Context, namespaces ...string) {
+	if c == nil {
+		return
+	}
+	for _, namespace := range namespaces {
+		if err := c.store.Invalidate(ctx, namespace); err != nil {
+			cacheLog.Warn("Failed to invalidate cache, entries expire after their TTL", "namespace", namespace, logging.FieldError, err)
+		}
+	}
+}
+
+// warn logs a store failure, at most once per storeWarnInterval
+func (c *Cache) warn(op, namespace string, err error) {
+	c.warnMu.Lock()
+	defer c.warnMu.Unlock()
+	if time.Since(c.lastWarn) < storeWarnInterval {
+		return
+	}
+	c.lastWarn = time.Now()
+	cacheLog.Warn("Cache store failed, serving from the database", "op", op, "namespace", namespace, logging.FieldError, err)
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+)
+
+// useCacheTTLs sets cache.ttls for the test
+func useCacheTTLs(t *testing.T, ttls string) {
+	t.Helper()
+	previous := config.Runtime()
+	t.Cleanup(func() { config.SetRuntime(previous) })
+	cfg, err := config.LoadRuntimeConfig(map[string]string{"cache.ttls": ttls})
+	if err != nil {
+		t.Fatalf("LoadRuntimeConfig() unexpected error: %v", err)
+	}
+	config.SetRuntime(cfg)
+}
+
+func TestFetchCachesUntilInvalidated(t *testing.T) {
+	useCacheTTLs(t, "prices=1m")
+	ctx := context.Background()
+	c := New(NewMemoryStore(100))
+	calls := 0
+	load := func(context.Context) ([]string, error) {
+		calls++
+		return []string{"HPG", "VNM"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		got, err := Fetch(ctx, c, NamespacePrices, "candles:HPG", load)
+		if err != nil || len(got) != 2 || got[1] != "VNM" {
+			t.Fatalf("Fetch() = %v, %v; want [HPG VNM]", got, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("loads before invalidation = %d; want 1", calls)
+	}
+
+	c.Invalidate(ctx, NamespacePrices)
+	Fetch(ctx, c, NamespacePrices, "candles:HPG", load)
+	if calls != 2 {
+		t.Errorf("loads after invalidation = %d; want 2", calls)
+	}
+}
+
+func TestFetchWithoutTTL(t *testing.T) {
+	useCacheTTLs(t, "prices=1m,stocks=0s")
+	ctx := context.Background()
+	c := New(NewMemoryStore(100))
+	calls := 0
+	load := func(context.Context) (int, error) {
+		calls++
+		return calls, nil
+	}
+
+	// stocks is disabled and indicators is not listed
+	for _, namespace := range []string{NamespaceStocks, NamespaceIndicators} {
+		Fetch(ctx, c, namespace, "key", load)
+		Fetch(ctx, c, namespace, "key", load)
+	}
+	if calls != 4 {
+		t.Errorf("loads = %d; want 4", calls)
+	}
+}
+
+func TestFetchDoesNotCacheErrors(t *testing.T) {
+	useCacheTTLs(t, "prices=1m")
+	ctx := context.Background()
+	c := New(NewMemoryStore(100))
+	failure := errors.New("mongo down")
+
+	if _, err := Fetch(ctx, c, NamespacePrices, "key", func(context.Context) (int, error) {
+		return 0, failure
+	}); !errors.Is(err, failure) {
+		t.Fatalf("Fetch() error = %v; want %v", err, failure)
+	}
+	got, err := Fetch(ctx, c, NamespacePrices, "key", func(context.Context) (int, error) {
+		return 7, nil
+	})
+	if err != nil || got != 7 {
+		t.Errorf("Fetch() after error = %d, %v; want 7", got, err)
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(100)
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	store.Set(ctx, NamespacePrices, 0, "key", []byte("1"), time.Minute)
+	if _, _, found, _ := store.Get(ctx, NamespacePrices, "key"); !found {
+		t.Fatal("Get() before expiry found nothing")
+	}
+	now = now.Add(time.Minute)
+	if _, _, found, _ := store.Get(ctx, NamespacePrices, "key"); found {
+		t.Error("Get() after expiry found the entry")
+	}
+}
+
+func TestMemoryStoreCapsTTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(100)
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	// Invalidations on other instances never reach this store
+	store.Set(ctx, NamespacePrices, 0, "key", []byte("1"), time.Hour)
+	now = now.Add(maxMemoryTTL)
+	if _, _, found, _ := store.Get(ctx, NamespacePrices, "key"); found {
+		t.Errorf("Get() after %s found an entry stored for an hour", maxMemoryTTL)
+	}
+}
+
+func TestMemoryStoreDropsStaleGenerations(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(100)
+
+	// A load that started before the invalidation must not be cached after it
+	_, generation, _, _ := store.Get(ctx, NamespacePrices, "key")
+	store.Invalidate(ctx, NamespacePrices)
+	store.Set(ctx, NamespacePrices, generation, "key", []byte("stale"), time.Minute)
+	if _, _, found, _ := store.Get(ctx, NamespacePrices, "key"); found {
+		t.Error("Get() returned an entry written for an older generation")
+	}
+
+	// Other namespaces are not affected
+	store.Set(ctx, NamespaceStocks, 0, "key", []byte("1"), time.Minute)
+	store.Invalidate(ctx, NamespacePrices)
+	if _, _, found, _ := store.Get(ctx, NamespaceStocks, "key"); !found {
+		t.Error("Invalidate(prices) dropped a stocks entry")
+	}
+}
+
+func TestMemoryStoreBound(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	for _, key := range []string{"a", "b", "c"} {
+		store.Set(ctx, NamespacePrices, 0, key, []byte(key), time.Minute)
+	}
+	if store.entries != 2 {
+		t.Errorf("entries = %d; want 2", store.entries)
+	}
+	if _, _, found, _ := store.Get(ctx, NamespacePrices, "c"); !found {
+		t.Error("Get(c) found nothing; the newest entry should be kept")
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMemoryEntries bounds the in-memory store of one instance
+	defaultMemoryEntries = 10000
+	// maxMemoryTTL caps the TTL of in-memory entries, since they outlive
+	// invalidations made on other instances
+	maxMemoryTTL = time.Minute
+)
+
+// MemoryStore keeps entries in process memory. Each instance has its own
+// entries, so only the instance that runs a crawl sees its invalidation;
+// the others serve their entries until they expire, which is why entries
+// are kept at most maxMemoryTTL whatever the namespace's TTL.
+type MemoryStore struct {
+	mu         sync.Mutex
+	maxEntries int
+	entries    int
+	namespaces map[string]*memoryNamespace
+	now        func() time.Time
+}
+
+type memoryNamespace struct {
+	generation int64
+	entries    map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an in-memory store holding at most maxEntries
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{
+		maxEntries: maxEntries,
+		namespaces: make(map[string]*memoryNamespace),
+		now:        time.Now,
+	}
+}
+
+// Get returns the entry of key in namespace
+func (s *MemoryStore) Get(_ context.Context, namespace, key string) ([]byte, int64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ns := s.namespace(namespace)
+	entry, ok := ns.entries[key]
+	if !ok {
+		return nil, ns.generation, false, nil
+	}
+	if !s.now().Before(entry.expiresAt) {
+		delete(ns.entries, key)
+		s.entries--
+		return nil, ns.generation, false, nil
+	}
+	return entry.value, ns.generation, true, nil
+}
+
+// Set stores an entry unless namespace moved past generation
+func (s *MemoryStore) Set(_ context.Context, namespace string, generation int64, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ns := s.namespace(namespace)
+	if generation != ns.generation {
+		return nil
+	}
+	if _, exists := ns.entries[key]; !exists {
+		if s.entries >= s.maxEntries && !s.evict() {
+			return nil
+		}
+		s.entries++
+	}
+	ns.entries[key] = memoryEntry{value: value, expiresAt: s.now().Add(min(ttl, maxMemoryTTL))}
+	return nil
+}
+
+// Invalidate drops the entries of namespace
+func (s *MemoryStore) Invalidate(_ context.Context, namespace string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ns := s.namespace(namespace)
+	s.entries -= len(ns.entries)
+	ns.generation++
+	ns.entries = make(map[string]memoryEntry)
+	return nil
+}
+
+// namespace returns the state of a namespace, creating it on first use.
+// Callers must hold s.mu.
+func (s *MemoryStore) namespace(name string) *memoryNamespace {
+	ns, ok := s.namespaces[name]
+	if !ok {
+		ns = &memoryNamespace{entries: make(map[string]memoryEntry)}
+		s.namespaces[name] = ns
+	}
+	return ns
+}
+
+// evict makes room for one entry: expired entries are dropped first, then
+// an arbitrary one. Callers must hold s.mu.
+func (s *MemoryStore) evict() bool {
+	now := s.now()
+	for _, ns := range s.namespaces {
+		for key, entry := range ns.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(ns.entries, key)
+				s.entries--
+			}
+		}
+	}
+	if s.entries < s.maxEntries {
+		return true
+	}
+	for _, ns := range s.namespaces {
+		for key := range ns.entries {
+			delete(ns.entries, key)
+			s.entries--
+			return true
+		}
+	}
+	return false
+}
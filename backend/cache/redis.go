@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps entries in Redis so all instances share them and see
+// invalidations. The generation of a namespace is kept under
+// cache:{<namespace>}:generation and each entry under
+// cache:{<namespace>}:entry:<key> as "<generation>:<value>"; an entry of an
+// older generation reads as a miss and is left to expire. The braces hash
+// tag keeps a namespace's keys in one Redis Cluster slot, so both are read
+// with one MGET.
+type RedisStore struct {
+	client redis.UniversalClient
+}
+
+// NewRedisStore creates a store backed by Redis
+func NewRedisStore(client redis.UniversalClient) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// Get returns the entry of key in namespace
+func (s *RedisStore) Get(ctx context.Context, namespace, key string) ([]byte, int64, bool, error) {
+	values, err := s.client.MGet(ctx, generationKey(namespace), entryKey(namespace, key)).Result()
+	if err != nil {
+		return nil, 0, false, err
+	}
+	if len(values) != 2 {
+		return nil, 0, false, fmt.Errorf("unexpected reply %v", values)
+	}
+
+	var generation int64
+	if raw, ok := values[0].(string); ok {
+		if generation, err = strconv.ParseInt(raw, 10, 64); err != nil {
+			return nil, 0, false, fmt.Errorf("invalid generation %q", raw)
+		}
+	}
+	raw, ok := values[1].(string)
+	if !ok {
+		return nil, generation, false, nil
+	}
+	prefix, value, ok := bytes.Cut([]byte(raw), []byte(":"))
+	if !ok || string(prefix) != strconv.FormatInt(generation, 10) {
+		return nil, generation, false, nil
+	}
+	return value, generation, true, nil
+}
+
+// Set stores an entry tagged with generation. An entry written for an older
+// generation is never returned, so the generation is not checked again.
+func (s *RedisStore) Set(ctx context.Context, namespace string, generation int64, key string, value []byte, ttl time.Duration) error {
+	tagged := strconv.AppendInt(nil, generation, 10)
+	tagged = append(append(tagged, ':'), value...)
+	return s.client.Set(ctx, entryKey(namespace, key), tagged, ttl).Err()
+}
+
+// Invalidate starts a new generation of namespace
+func (s *RedisStore) Invalidate(ctx context.Context, namespace string) error {
+	return s.client.Incr(ctx, generationKey(namespace)).Err()
+}
+
+func generationKey(namespace string) string {
+	return "cache:{" + namespace + "}:generation"
+}
+
+func entryKey(namespace, key string) string {
+	return "cache:{" + namespace + "}:entry:" + key
+}
@@ -0,0 +1,69 @@
+// Command create-admin provisions an admin account in the admin_users table.
+//
+// It creates a new super_admin with a bcrypt password hash, or resets the
+// password of an existing admin with the same email, lifting its lockout.
+// Use it to bootstrap the first admin after applying the password_hash
+// migration, or to recover an admin locked after failed logins:
+//
+//	go run ./cmd/create-admin -email admin@cpls.com -username admin
+//
+// The password is read from -password, the ADMIN_PASSWORD environment
+// variable, or prompted on stdin (in that order).
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	email := flag.String("email", "", "admin email address (required)")
+	username := flag.String("username", os.Getenv("ADMIN_USERNAME"), "admin username used to log in")
+	password := flag.String("password", "", "admin password (defaults to $ADMIN_PASSWORD, otherwise prompted)")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+
+	if *email == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *password == "" {
+		*password = os.Getenv("ADMIN_PASSWORD")
+	}
+	if *password == "" {
+		fmt.Print("Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			log.Fatalf("Failed to read password: %v", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	if err := config.ConnectPostgres(); err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer config.DisconnectPostgres()
+
+	adminUser, created, err := services.NewAuthService().BootstrapAdmin(*email, *username, *password)
+	if err != nil {
+		log.Fatalf("Failed to provision admin: %v", err)
+	}
+
+	if created {
+		log.Printf("✓ Created super_admin %s (id: %s)", adminUser.Email, adminUser.ID)
+	} else {
+		log.Printf("✓ Updated password for existing admin %s (id: %s)", adminUser.Email, adminUser.ID)
+	}
+}
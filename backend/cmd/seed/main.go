@@ -0,0 +1,76 @@
+// Command seed populates a development environment with sample data: a few
+// stocks with two years of synthetic candles, an admin user and sample
+// member profiles, so the dashboard and APIs can be used without crawling
+// VNDirect:
+//
+//	go run ./cmd/seed -admin-password secret
+//
+// It connects to the stores configured for the server (MONGODB_URI,
+// DATABASE_URL) and skips those that are not set. Existing stocks, candles
+// and profiles are kept; the admin's password is reset. It refuses to run
+// with ENV=production.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	years := flag.Int("years", 2, "years of synthetic candle history per stock")
+	email := flag.String("admin-email", "admin@cpls.local", "email of the seeded admin")
+	username := flag.String("admin-username", "admin", "username of the seeded admin")
+	password := flag.String("admin-password", "", "password of the seeded admin (defaults to $ADMIN_PASSWORD)")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using environment variables")
+	}
+	if os.Getenv("ENV") == "production" {
+		log.Fatal("FATAL: Refusing to seed a production environment (ENV=production)")
+	}
+	if *password == "" {
+		*password = os.Getenv("ADMIN_PASSWORD")
+	}
+
+	if os.Getenv("MONGODB_URI") != "" {
+		if err := config.ConnectMongoDB(); err != nil {
+			log.Fatalf("Failed to connect to MongoDB: %v", err)
+		}
+		defer config.DisconnectMongoDB()
+	} else {
+		log.Println("Warning: MONGODB_URI not set, skipping stocks and candles")
+	}
+	if os.Getenv("DATABASE_URL") != "" {
+		if *password == "" {
+			log.Fatal("FATAL: -admin-password or ADMIN_PASSWORD is required to seed the admin user")
+		}
+		if err := config.ConnectPostgres(); err != nil {
+			log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+		}
+		defer config.DisconnectPostgres()
+	} else {
+		log.Println("Warning: DATABASE_URL not set, skipping the admin user and profiles")
+	}
+
+	report, err := services.SeedDevelopment(context.Background(), services.SeedOptions{
+		Years:         *years,
+		AdminEmail:    *email,
+		AdminUsername: *username,
+		AdminPassword: *password,
+	})
+	if err != nil {
+		log.Fatalf("❌ Seeding failed: %v", err)
+	}
+
+	log.Printf("✓ Seeded %d stocks with %d candles (already present: %v)", report.Stocks, report.Candles, report.SkippedStocks)
+	if report.Admin != "" {
+		log.Printf("✓ Admin %s (created: %v) and %d new sample profiles", report.Admin, report.AdminCreated, report.Profiles)
+	}
+}
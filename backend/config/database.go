@@ -16,6 +16,9 @@ var (
 	MongoClient *mongo.Client
 	// Database is the database instance
 	Database *mongo.Database
+	// CollectionPrefix is prepended to every collection name; dry-run
+	// crawls set it to keep their writes in sandbox collections
+	CollectionPrefix string
 )
 
 // ConnectMongoDB initializes connection to MongoDB
@@ -34,7 +37,7 @@ func ConnectMongoDB() error {
 	defer cancel()
 
 	// Set client options
-	clientOptions := options.Client().ApplyURI(mongoURI)
+	clientOptions := options.Client().ApplyURI(mongoURI).SetMonitor(mongoQueryMonitor())
 
 	// Connect to MongoDB
 	client, err := mongo.Connect(ctx, clientOptions)
@@ -42,15 +45,18 @@ func ConnectMongoDB() error {
 		return fmt.Errorf("failed to connect to MongoDB: %w", err)
 	}
 
+	// The client reconnects on its own, so it is kept even when MongoDB
+	// cannot be reached yet; the store monitor notices when it is back
+	MongoClient = client
+	Database = client.Database(dbName)
+
 	// Ping the database to verify connection
 	err = client.Ping(ctx, nil)
+	SetStoreAvailability(StoreMongo, err)
 	if err != nil {
-		return fmt.Errorf("failed to ping MongoDB: %w", err)
+		return fmt.Errorf("%w: failed to ping MongoDB: %v", ErrStoreUnavailable, err)
 	}
 
-	MongoClient = client
-	Database = client.Database(dbName)
-
 	log.Printf("✓ Connected to MongoDB database: %s", dbName)
 	return nil
 }
@@ -75,5 +81,5 @@ func DisconnectMongoDB() error {
 
 // GetCollection returns a collection from the database
 func GetCollection(collectionName string) *mongo.Collection {
-	return Database.Collection(collectionName)
+	return Database.Collection(CollectionPrefix + collectionName)
 }
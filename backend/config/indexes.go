@@ -0,0 +1,147 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexSetupTimeout bounds one attempt to ensure the indexes of a store
+const indexSetupTimeout = 10 * time.Minute
+
+// MongoIndex is an index the queries of the API rely on
+type MongoIndex struct {
+	Collection string
+	Name       string
+	Keys       bson.D
+	Unique     bool
+}
+
+// MongoIndexes are created at startup when missing, so fresh environments
+// do not silently run full collection scans
+var MongoIndexes = []MongoIndex{
+	{Collection: "stocks", Name: "code_unique", Keys: bson.D{{Key: "code", Value: 1}}, Unique: true},
+	{Collection: "stocks", Name: "updatedAt_code", Keys: bson.D{{Key: "updatedAt", Value: 1}, {Key: "code", Value: 1}}},
+	{Collection: "stock_prices", Name: "code_year", Keys: bson.D{{Key: "code", Value: 1}, {Key: "year", Value: 1}}, Unique: true},
+	{Collection: "stock_prices", Name: "year", Keys: bson.D{{Key: "year", Value: 1}}},
+	{Collection: "crawl_runs", Name: "startedAt_desc", Keys: bson.D{{Key: "startedAt", Value: -1}}},
+	{Collection: "symbol_changes", Name: "type_effectiveDate", Keys: bson.D{{Key: "type", Value: 1}, {Key: "effectiveDate", Value: 1}}},
+	{Collection: "suspect_candles", Name: "status_date", Keys: bson.D{{Key: "status", Value: 1}, {Key: "candle.d", Value: -1}}},
+	{Collection: "futures_contracts", Name: "underlying_lastTradingDate", Keys: bson.D{{Key: "underlying", Value: 1}, {Key: "lastTradingDate", Value: 1}}},
+	{Collection: "volume_anomalies", Name: "date_ratio", Keys: bson.D{{Key: "date", Value: -1}, {Key: "ratio", Value: -1}}},
+}
+
+// PostgresIndex is an index of a Supabase table the API looks rows up by.
+// Migrations create them too; names match so existing ones are kept.
+type PostgresIndex struct {
+	Name    string
+	Table   string
+	Columns string
+	Unique  bool
+}
+
+// PostgresIndexes are created at startup when missing
+var PostgresIndexes = []PostgresIndex{
+	{Name: "idx_admin_users_email", Table: "public.admin_users", Columns: "email"},
+	{Name: "idx_admin_users_username", Table: "public.admin_users", Columns: "username"},
+	{Name: "idx_profiles_email", Table: "public.profiles", Columns: "email"},
+	{Name: "idx_profiles_zalo_id", Table: "public.profiles", Columns: "zalo_id"},
+	{Name: "idx_profiles_membership", Table: "public.profiles", Columns: "membership"},
+}
+
+// Statement returns the SQL creating the index when it does not exist. It
+// is built concurrently so that writes to large tables are not blocked.
+func (i PostgresIndex) Statement() string {
+	unique := ""
+	if i.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX CONCURRENTLY IF NOT EXISTS %s ON %s (%s)", unique, i.Name, i.Table, i.Columns)
+}
+
+// StartIndexSetup ensures MongoIndexes and PostgresIndexes in the
+// background. Stores that are unavailable at startup get their indexes
+// once the store monitor sees them back; a store whose indexes could not
+// all be created is not retried, since the cause (duplicates, permissions)
+// needs an operator.
+func StartIndexSetup(ctx context.Context) {
+	setups := map[string]func(context.Context) error{
+		StoreMongo:    ensureMongoIndexes,
+		StorePostgres: ensurePostgresIndexes,
+	}
+	if MongoClient == nil {
+		delete(setups, StoreMongo)
+	}
+	if PostgresDB == nil {
+		delete(setups, StorePostgres)
+	}
+
+	go func() {
+		for len(setups) > 0 {
+			for store, setup := range setups {
+				if !StoreAvailable(store) {
+					continue
+				}
+				setupCtx, cancel := context.WithTimeout(ctx, indexSetupTimeout)
+				if err := setup(setupCtx); err != nil {
+					log.Printf("⚠️  Some %s indexes are missing: %v", store, err)
+				} else {
+					log.Printf("✓ %s indexes ensured", store)
+				}
+				cancel()
+				delete(setups, store)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(StoreCheckInterval()):
+			}
+		}
+	}()
+}
+
+// ensureMongoIndexes creates the missing MongoIndexes, continuing past
+// failures. An equivalent index under another name counts as present.
+func ensureMongoIndexes(ctx context.Context) error {
+	var failures []string
+	for _, index := range MongoIndexes {
+		model := mongo.IndexModel{
+			Keys:    index.Keys,
+			Options: options.Index().SetName(index.Name).SetUnique(index.Unique),
+		}
+		_, err := GetCollection(index.Collection).Indexes().CreateOne(ctx, model)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "IndexOptionsConflict" {
+			continue
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s.%s: %v", index.Collection, index.Name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// ensurePostgresIndexes creates the missing PostgresIndexes, continuing
+// past failures (e.g. a table not migrated yet)
+func ensurePostgresIndexes(ctx context.Context) error {
+	var failures []string
+	for _, index := range PostgresIndexes {
+		if err := GetDBWithContext(ctx).Exec(index.Statement()).Error; err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", index.Name, err))
+		}
+	}
+	if len(failures) > 0 {
+		return errors.New(strings.Join(failures, "; "))
+	}
+	return nil
+}
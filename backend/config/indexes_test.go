@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestPostgresIndexStatement(t *testing.T) {
+	index := PostgresIndex{Name: "idx_profiles_email", Table: "public.profiles", Columns: "email"}
+	if got := index.Statement(); got != "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_profiles_email ON public.profiles (email)" {
+		t.Errorf("Statement() = %q", got)
+	}
+	index.Unique = true
+	if got := index.Statement(); got != "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_profiles_email ON public.profiles (email)" {
+		t.Errorf("Statement() of a unique index = %q", got)
+	}
+}
+
+func TestIndexNamesUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, index := range MongoIndexes {
+		key := index.Collection + "." + index.Name
+		if seen[key] || len(index.Keys) == 0 {
+			t.Errorf("Mongo index %s is duplicated or has no keys", key)
+		}
+		seen[key] = true
+	}
+	for _, index := range PostgresIndexes {
+		if seen[index.Name] {
+			t.Errorf("Postgres index %s is duplicated", index.Name)
+		}
+		seen[index.Name] = true
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// MarketTimeConfig selects the time zones that dates, "today" and schedule
+// times are anchored to
+type MarketTimeConfig struct {
+	Timezone          string            // IANA zone of market dates and schedule times
+	ExchangeTimezones map[string]string // Exchange code -> IANA zone of its calendar and sessions
+}
+
+// LoadMarketTimeConfig reads MARKET_TIMEZONE (default Asia/Ho_Chi_Minh) and
+// EXCHANGE_TIMEZONES, comma-separated CODE=Zone pairs overriding the zone of
+// registered exchanges (e.g. HNX=Asia/Ho_Chi_Minh)
+func LoadMarketTimeConfig() (MarketTimeConfig, error) {
+	return loadMarketTimeConfig(os.Getenv)
+}
+
+func loadMarketTimeConfig(getenv func(string) string) (MarketTimeConfig, error) {
+	cfg := MarketTimeConfig{
+		Timezone:          strings.TrimSpace(getenv("MARKET_TIMEZONE")),
+		ExchangeTimezones: make(map[string]string),
+	}
+	if cfg.Timezone == "" {
+		cfg.Timezone = markettime.DefaultZone
+	}
+	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		return MarketTimeConfig{}, fmt.Errorf("MARKET_TIMEZONE: unknown time zone %q", cfg.Timezone)
+	}
+	for _, pair := range strings.Split(getenv("EXCHANGE_TIMEZONES"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		code, zone, ok := strings.Cut(pair, "=")
+		code, zone = strings.ToUpper(strings.TrimSpace(code)), strings.TrimSpace(zone)
+		if !ok || code == "" || zone == "" {
+			return MarketTimeConfig{}, fmt.Errorf("EXCHANGE_TIMEZONES: expected CODE=Zone, got %q", pair)
+		}
+		if _, err := time.LoadLocation(zone); err != nil {
+			return MarketTimeConfig{}, fmt.Errorf("EXCHANGE_TIMEZONES: unknown time zone %q for %s", zone, code)
+		}
+		cfg.ExchangeTimezones[code] = zone
+	}
+	return cfg, nil
+}
+
+// Apply sets the market time zone and moves the listed exchanges to their
+// zones. Exchanges must be registered first.
+func (cfg MarketTimeConfig) Apply() error {
+	if err := markettime.SetLocation(cfg.Timezone); err != nil {
+		return fmt.Errorf("MARKET_TIMEZONE: %w", err)
+	}
+	codes := make([]string, 0, len(cfg.ExchangeTimezones))
+	for code := range cfg.ExchangeTimezones {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		if err := models.SetExchangeTimezone(code, cfg.ExchangeTimezones[code]); err != nil {
+			return fmt.Errorf("EXCHANGE_TIMEZONES: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/markettime"
+)
+
+func TestLoadMarketTimeConfig(t *testing.T) {
+	cfg, err := loadMarketTimeConfig(func(string) string { return "" })
+	if err != nil || cfg.Timezone != markettime.DefaultZone || len(cfg.ExchangeTimezones) != 0 {
+		t.Errorf("loadMarketTimeConfig() without settings = %+v, %v; want Asia/Ho_Chi_Minh", cfg, err)
+	}
+
+	env := map[string]string{"MARKET_TIMEZONE": "Asia/Bangkok", "EXCHANGE_TIMEZONES": " hnx=Asia/Ho_Chi_Minh, SGX=Asia/Singapore "}
+	cfg, err = loadMarketTimeConfig(func(name string) string { return env[name] })
+	if err != nil || cfg.Timezone != "Asia/Bangkok" || cfg.ExchangeTimezones["HNX"] != "Asia/Ho_Chi_Minh" || cfg.ExchangeTimezones["SGX"] != "Asia/Singapore" {
+		t.Errorf("loadMarketTimeConfig() = %+v, %v; want Asia/Bangkok with the HNX and SGX zones", cfg, err)
+	}
+
+	for _, env := range []map[string]string{
+		{"MARKET_TIMEZONE": "Mars/Olympus"},
+		{"EXCHANGE_TIMEZONES": "HOSE"},
+		{"EXCHANGE_TIMEZONES": "HOSE=Mars/Olympus"},
+	} {
+		if _, err := loadMarketTimeConfig(func(name string) string { return env[name] }); err == nil {
+			t.Errorf("loadMarketTimeConfig(%v) succeeded; want an error", env)
+		}
+	}
+}
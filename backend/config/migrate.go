@@ -0,0 +1,174 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"gorm.io/gorm"
+)
+
+// DefaultMigrationsDir is where the versioned SQL migrations live, relative
+// to the backend directory (MIGRATIONS_DIR overrides it)
+const DefaultMigrationsDir = "../supabase/migrations"
+
+// schemaMigrationsTable creates the table recording applied migrations. It
+// is created by the migrator itself so that the first migration can be
+// recorded like any other.
+const schemaMigrationsTable = `CREATE TABLE IF NOT EXISTS public.schema_migrations (
+  version TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  checksum TEXT NOT NULL,
+  baseline BOOLEAN NOT NULL DEFAULT false,
+  applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+ALTER TABLE public.schema_migrations ENABLE ROW LEVEL SECURITY;`
+
+// Migration is a versioned SQL file, named <version>_<name>.sql
+type Migration struct {
+	Version  string
+	Name     string
+	Checksum string
+	SQL      string
+}
+
+// MigrationState is a migration with what the database recorded of it
+type MigrationState struct {
+	Migration
+	Applied  *models.SchemaMigration // nil while pending
+	Modified bool                    // The file changed since it was applied
+}
+
+// LoadMigrations reads the *.sql files at the root of fsys, ordered by
+// version. Versions must be unique.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	paths, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(paths))
+	seen := make(map[string]string)
+	for _, file := range paths {
+		version, name, ok := strings.Cut(strings.TrimSuffix(path.Base(file), ".sql"), "_")
+		if !ok || version == "" || name == "" || strings.Trim(version, "0123456789") != "" {
+			return nil, fmt.Errorf("migration %s is not named <version>_<name>.sql", file)
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrations %s and %s share version %s", other, file, version)
+		}
+		seen[version] = file
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", file, err)
+		}
+		sum := sha256.Sum256(data)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     name,
+			Checksum: hex.EncodeToString(sum[:]),
+			SQL:      string(data),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrationStatus returns every migration with its applied record
+func MigrationStatus(ctx context.Context, migrations []Migration) ([]MigrationState, error) {
+	if PostgresDB == nil {
+		return nil, errors.New("PostgreSQL is not connected")
+	}
+	applied, err := appliedMigrations(GetDBWithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	states := make([]MigrationState, len(migrations))
+	for i, migration := range migrations {
+		states[i] = MigrationState{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			states[i].Applied = &record
+			states[i].Modified = !record.Baseline && record.Checksum != migration.Checksum
+		}
+	}
+	return states, nil
+}
+
+// MigrateUp applies the pending migrations in version order, each in its
+// own transaction together with its schema_migrations record, and returns
+// the applied ones. It stops at the first failure. With baseline the
+// pending migrations are only recorded as applied, for databases set up by
+// running the SQL files by hand. Concurrent migrators wait for each other.
+func MigrateUp(ctx context.Context, migrations []Migration, baseline bool) ([]Migration, error) {
+	if PostgresDB == nil {
+		return nil, errors.New("PostgreSQL is not connected")
+	}
+	db := GetDBWithContext(ctx)
+	if err := db.Exec(schemaMigrationsTable).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var done []Migration
+	for _, migration := range migrations {
+		applied := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('schema_migrations'))").Error; err != nil {
+				return fmt.Errorf("failed to lock migrations: %w", err)
+			}
+			var count int64
+			if err := tx.Model(&models.SchemaMigration{}).Where("version = ?", migration.Version).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return nil // Applied by a concurrent migrator
+			}
+			if !baseline {
+				if err := tx.Exec(migration.SQL).Error; err != nil {
+					return err
+				}
+			}
+			applied = true
+			return tx.Create(&models.SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Checksum:  migration.Checksum,
+				Baseline:  baseline,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %s_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		if applied {
+			done = append(done, migration)
+		}
+	}
+	return done, nil
+}
+
+// appliedMigrations returns the schema_migrations records by version; none
+// when the table does not exist yet
+func appliedMigrations(db *gorm.DB) (map[string]models.SchemaMigration, error) {
+	applied := make(map[string]models.SchemaMigration)
+	if !db.Migrator().HasTable(&models.SchemaMigration{}) {
+		return applied, nil
+	}
+	var records []models.SchemaMigration
+	if err := db.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20260207_create_watchlists.sql":  {Data: []byte("CREATE TABLE a ();")},
+		"20260105_create_admin_users.sql": {Data: []byte("CREATE TABLE b ();")},
+		"README.md":                       {Data: []byte("not a migration")},
+	}
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatalf("LoadMigrations failed: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("loaded %d migrations; want 2", len(migrations))
+	}
+	if migrations[0].Version != "20260105" || migrations[0].Name != "create_admin_users" {
+		t.Errorf("first migration = %s_%s; want 20260105_create_admin_users", migrations[0].Version, migrations[0].Name)
+	}
+	if migrations[0].SQL != "CREATE TABLE b ();" || len(migrations[0].Checksum) != 64 {
+		t.Errorf("first migration has SQL %q and checksum %q", migrations[0].SQL, migrations[0].Checksum)
+	}
+
+	invalid := []fstest.MapFS{
+		{"create_jobs.sql": {Data: []byte("")}},
+		{"2026a_create_jobs.sql": {Data: []byte("")}},
+		{"20260212_create_jobs.sql": {Data: []byte("")}, "20260212_create_alerts.sql": {Data: []byte("")}},
+	}
+	for _, fsys := range invalid {
+		if _, err := LoadMigrations(fsys); err == nil {
+			t.Errorf("LoadMigrations(%v) succeeded; want an error", fsys)
+		}
+	}
+}
+
+func TestRepositoryMigrationsLoad(t *testing.T) {
+	migrations, err := LoadMigrations(os.DirFS("../" + DefaultMigrationsDir))
+	if err != nil {
+		t.Fatalf("the repository's migrations do not load: %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations found in " + DefaultMigrationsDir)
+	}
+}
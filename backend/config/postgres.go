@@ -1,6 +1,7 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -46,11 +47,19 @@ func ConnectPostgres() error {
 		// However, it's disabled here to avoid issues with connection pooling in Cloud Run
 		// Enable if you're experiencing performance issues with repeated queries
 		// PrepareStmt: true,
+		// Connections are opened lazily, so an unreachable database does not
+		// fail startup; the ping below reports it
+		DisableAutomaticPing: true,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to PostgreSQL: %w", err)
 	}
 
+	// Count queries per request (see QueryCounter) to surface N+1 patterns
+	if err := registerQueryCounting(db); err != nil {
+		return fmt.Errorf("failed to register query counting callbacks: %w", err)
+	}
+
 	// Get underlying SQL database
 	sqlDB, err := db.DB()
 	if err != nil {
@@ -62,9 +71,13 @@ func ConnectPostgres() error {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(time.Hour)
 
-	// Test connection
-	if err := sqlDB.Ping(); err != nil {
-		return fmt.Errorf("failed to ping PostgreSQL: %w", err)
+	// Test connection. The pool is kept when it fails: database/sql
+	// reconnects, and the store monitor notices when Postgres is back
+	err = sqlDB.Ping()
+	SetStoreAvailability(StorePostgres, err)
+	if err != nil {
+		PostgresDB = db
+		return fmt.Errorf("%w: failed to ping PostgreSQL: %v", ErrStoreUnavailable, err)
 	}
 
 	// Set search_path to public schema (important for Supabase)
@@ -121,3 +134,9 @@ func DisconnectPostgres() error {
 func GetDB() *gorm.DB {
 	return PostgresDB.Debug()
 }
+
+// GetDBWithContext returns GetDB bound to ctx, so queries are cancelled with
+// the request and counted by its QueryCounter
+func GetDBWithContext(ctx context.Context) *gorm.DB {
+	return GetDB().WithContext(ctx)
+}
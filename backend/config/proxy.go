@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Deployment targets, which select the default trusted proxies
+const (
+	DeployTargetCloudRun = "cloudrun" // Google front end; the container is not reachable directly
+	DeployTargetGKE      = "gke"      // Google Cloud load balancer or ingress inside the cluster network
+	DeployTargetNginx    = "nginx"    // Reverse proxy on the same host
+	DeployTargetNone     = "none"     // Directly exposed: forwarded headers are ignored
+)
+
+// ProxyConfig selects which peers may report the client IP through
+// forwarded headers, and which headers they use
+type ProxyConfig struct {
+	Target          string
+	TrustedProxies  []string // CIDRs or IPs; empty trusts no proxy
+	RemoteIPHeaders []string // Checked in order for the client IP
+	// ForwardedHops is how many X-Forwarded-For entries the proxies in front
+	// append; earlier entries come from the client and are dropped. 0 keeps
+	// them all and relies on TrustedProxies alone.
+	ForwardedHops int
+}
+
+// TrustsEveryone reports whether any peer is trusted to set the client IP
+func (p ProxyConfig) TrustsEveryone() bool {
+	for _, proxy := range p.TrustedProxies {
+		if proxy == "0.0.0.0/0" || proxy == "::/0" {
+			return true
+		}
+	}
+	return false
+}
+
+// deployTargetDefaults are the secure defaults of each target
+var deployTargetDefaults = map[string]ProxyConfig{
+	DeployTargetCloudRun: {
+		// Only Google's front end can reach a Cloud Run container, and its
+		// source addresses are not published, so every peer is trusted. The
+		// front end appends the client's address to X-Forwarded-For, so only
+		// that last hop is kept: anything before it was sent by the client.
+		TrustedProxies:  []string{"0.0.0.0/0", "::/0"},
+		RemoteIPHeaders: []string{"X-Forwarded-For"},
+		ForwardedHops:   1,
+	},
+	DeployTargetGKE: {
+		// Cluster networks plus the Google Cloud load balancer and health check ranges
+		TrustedProxies:  []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "35.191.0.0/16", "130.211.0.0/22"},
+		RemoteIPHeaders: []string{"X-Forwarded-For"},
+	},
+	DeployTargetNginx: {
+		TrustedProxies:  []string{"127.0.0.1/32", "::1/128"},
+		RemoteIPHeaders: []string{"X-Real-IP", "X-Forwarded-For"},
+	},
+	DeployTargetNone: {},
+}
+
+// LoadProxyConfig reads DEPLOY_TARGET, TRUSTED_PROXIES, FORWARDED_IP_HEADERS
+// and FORWARDED_HOPS (e.g. 2 behind a load balancer in front of Cloud
+// Run). Without DEPLOY_TARGET the target is detected from
+// the platform's environment (K_SERVICE on Cloud Run,
+// KUBERNETES_SERVICE_HOST on Kubernetes) and is otherwise "none".
+func LoadProxyConfig() (ProxyConfig, error) {
+	return loadProxyConfig(os.Getenv)
+}
+
+func loadProxyConfig(getenv func(string) string) (ProxyConfig, error) {
+	target := strings.ToLower(strings.TrimSpace(getenv("DEPLOY_TARGET")))
+	switch {
+	case target != "":
+	case getenv("K_SERVICE") != "":
+		target = DeployTargetCloudRun
+	case getenv("KUBERNETES_SERVICE_HOST") != "":
+		target = DeployTargetGKE
+	default:
+		target = DeployTargetNone
+	}
+	defaults, ok := deployTargetDefaults[target]
+	if !ok {
+		return ProxyConfig{}, fmt.Errorf("DEPLOY_TARGET: unknown target %q (expected cloudrun, gke, nginx or none)", target)
+	}
+
+	cfg := ProxyConfig{
+		Target:          target,
+		TrustedProxies:  defaults.TrustedProxies,
+		RemoteIPHeaders: defaults.RemoteIPHeaders,
+		ForwardedHops:   defaults.ForwardedHops,
+	}
+	if raw := strings.TrimSpace(getenv("TRUSTED_PROXIES")); raw != "" {
+		proxies, err := parseTrustedProxies(raw)
+		if err != nil {
+			return ProxyConfig{}, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+		cfg.TrustedProxies = proxies
+	}
+	if raw := strings.TrimSpace(getenv("FORWARDED_IP_HEADERS")); raw != "" {
+		headers, err := parseForwardedHeaders(raw)
+		if err != nil {
+			return ProxyConfig{}, fmt.Errorf("FORWARDED_IP_HEADERS: %w", err)
+		}
+		cfg.RemoteIPHeaders = headers
+	}
+	if raw := strings.TrimSpace(getenv("FORWARDED_HOPS")); raw != "" {
+		hops, err := strconv.Atoi(raw)
+		if err != nil || hops < 0 {
+			return ProxyConfig{}, fmt.Errorf("FORWARDED_HOPS: must be a non-negative number, got %q", raw)
+		}
+		cfg.ForwardedHops = hops
+	}
+	return cfg, nil
+}
+
+// parseTrustedProxies parses comma-separated CIDRs or IPs; "none" trusts no proxy
+func parseTrustedProxies(raw string) ([]string, error) {
+	if strings.EqualFold(raw, "none") {
+		return []string{}, nil
+	}
+	proxies := make([]string, 0)
+	for _, entry := range strings.Split(raw, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", entry)
+			}
+		} else if net.ParseIP(entry) == nil {
+			return nil, fmt.Errorf("invalid IP %q", entry)
+		}
+		proxies = append(proxies, entry)
+	}
+	return proxies, nil
+}
+
+// parseForwardedHeaders parses comma-separated header names
+func parseForwardedHeaders(raw string) ([]string, error) {
+	headers := make([]string, 0)
+	for _, header := range strings.Split(raw, ",") {
+		if header = strings.TrimSpace(header); header == "" {
+			continue
+		}
+		if strings.ContainsAny(header, " :\t") {
+			return nil, fmt.Errorf("invalid header name %q", header)
+		}
+		headers = append(headers, http.CanonicalHeaderKey(header))
+	}
+	if len(headers) == 0 {
+		return nil, fmt.Errorf("at least one header is required")
+	}
+	return headers, nil
+}
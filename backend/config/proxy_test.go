@@ -0,0 +1,91 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLoadProxyConfigDetectsTarget(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		wantTarget string
+		wantAll    bool
+	}{
+		{"cloud run", map[string]string{"K_SERVICE": "cpls-backend"}, DeployTargetCloudRun, true},
+		{"kubernetes", map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"}, DeployTargetGKE, false},
+		{"explicit wins", map[string]string{"DEPLOY_TARGET": "Nginx", "K_SERVICE": "cpls-backend"}, DeployTargetNginx, false},
+		{"bare host", map[string]string{}, DeployTargetNone, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := loadProxyConfig(func(name string) string { return tt.env[name] })
+			if err != nil {
+				t.Fatalf("loadProxyConfig() unexpected error: %v", err)
+			}
+			if cfg.Target != tt.wantTarget || cfg.TrustsEveryone() != tt.wantAll {
+				t.Errorf("Target = %s (trusts everyone %v); want %s (%v)", cfg.Target, cfg.TrustsEveryone(), tt.wantTarget, tt.wantAll)
+			}
+		})
+	}
+
+	cfg, _ := loadProxyConfig(func(string) string { return "" })
+	if len(cfg.TrustedProxies) != 0 {
+		t.Errorf("TrustedProxies = %v; want none without a proxy", cfg.TrustedProxies)
+	}
+}
+
+func TestLoadProxyConfigOverrides(t *testing.T) {
+	env := map[string]string{
+		"DEPLOY_TARGET":        "gke",
+		"TRUSTED_PROXIES":      "10.8.0.0/14, 192.168.1.10",
+		"FORWARDED_IP_HEADERS": "x-real-ip",
+	}
+	cfg, err := loadProxyConfig(func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("loadProxyConfig() unexpected error: %v", err)
+	}
+	if want := []string{"10.8.0.0/14", "192.168.1.10"}; !reflect.DeepEqual(cfg.TrustedProxies, want) {
+		t.Errorf("TrustedProxies = %v; want %v", cfg.TrustedProxies, want)
+	}
+	if want := []string{"X-Real-Ip"}; !reflect.DeepEqual(cfg.RemoteIPHeaders, want) {
+		t.Errorf("RemoteIPHeaders = %v; want %v", cfg.RemoteIPHeaders, want)
+	}
+
+	env = map[string]string{"DEPLOY_TARGET": "cloudrun", "TRUSTED_PROXIES": "none"}
+	cfg, err = loadProxyConfig(func(name string) string { return env[name] })
+	if err != nil || len(cfg.TrustedProxies) != 0 {
+		t.Errorf("TRUSTED_PROXIES=none gave %v (%v); want no trusted proxies", cfg.TrustedProxies, err)
+	}
+}
+
+func TestLoadProxyConfigRejectsInvalid(t *testing.T) {
+	tests := []map[string]string{
+		{"DEPLOY_TARGET": "heroku"},
+		{"TRUSTED_PROXIES": "10.0.0.0/33"},
+		{"TRUSTED_PROXIES": "proxy.internal"},
+		{"FORWARDED_IP_HEADERS": "X-Forwarded-For: 1"},
+		{"FORWARDED_IP_HEADERS": " , "},
+	}
+	for _, env := range tests {
+		if _, err := loadProxyConfig(func(name string) string { return env[name] }); err == nil {
+			t.Errorf("loadProxyConfig(%v) expected an error", env)
+		}
+	}
+}
+
+func TestLoadProxyConfigForwardedHops(t *testing.T) {
+	cfg, err := loadProxyConfig(func(name string) string { return map[string]string{"K_SERVICE": "cpls-backend"}[name] })
+	if err != nil || cfg.ForwardedHops != 1 {
+		t.Errorf("Cloud Run ForwardedHops = %d, %v; want the front end's hop only", cfg.ForwardedHops, err)
+	}
+	cfg, err = loadProxyConfig(func(name string) string {
+		return map[string]string{"DEPLOY_TARGET": "cloudrun", "FORWARDED_HOPS": "2"}[name]
+	})
+	if err != nil || cfg.ForwardedHops != 2 {
+		t.Errorf("FORWARDED_HOPS=2 gives %d, %v", cfg.ForwardedHops, err)
+	}
+	if _, err := loadProxyConfig(func(name string) string { return map[string]string{"FORWARDED_HOPS": "-1"}[name] }); err == nil {
+		t.Error("loadProxyConfig() accepted FORWARDED_HOPS=-1")
+	}
+}
@@ -0,0 +1,150 @@
+package config
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"gorm.io/gorm"
+)
+
+// Query backends recorded by QueryCounter
+const (
+	QueryBackendPostgres = "postgres"
+	QueryBackendMongo    = "mongo"
+)
+
+type queryCounterKey struct{}
+
+// QueryCounter counts the database queries issued while serving one request.
+// Queries are grouped by shape (SQL with placeholders, or Mongo command and
+// collection) so repeated shapes reveal N+1 access patterns.
+type QueryCounter struct {
+	mu        sync.Mutex
+	total     int
+	byBackend map[string]int
+	byShape   map[string]int
+}
+
+// QueryShapeCount is the number of times one query shape was executed
+type QueryShapeCount struct {
+	Shape string `json:"shape"`
+	Count int    `json:"count"`
+}
+
+// NewQueryCounter creates an empty QueryCounter
+func NewQueryCounter() *QueryCounter {
+	return &QueryCounter{
+		byBackend: make(map[string]int),
+		byShape:   make(map[string]int),
+	}
+}
+
+// WithQueryCounter returns a context that records queries into counter
+func WithQueryCounter(ctx context.Context, counter *QueryCounter) context.Context {
+	return context.WithValue(ctx, queryCounterKey{}, counter)
+}
+
+// QueryCounterFromContext returns the counter attached to ctx, or nil
+func QueryCounterFromContext(ctx context.Context) *QueryCounter {
+	if ctx == nil {
+		return nil
+	}
+	counter, _ := ctx.Value(queryCounterKey{}).(*QueryCounter)
+	return counter
+}
+
+// Record counts one query of the given backend and shape
+func (qc *QueryCounter) Record(backend, shape string) {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	qc.total++
+	qc.byBackend[backend]++
+	qc.byShape[backend+": "+shape]++
+}
+
+// Total returns the number of queries recorded
+func (qc *QueryCounter) Total() int {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	return qc.total
+}
+
+// ByBackend returns the number of queries recorded for a backend
+func (qc *QueryCounter) ByBackend(backend string) int {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+	return qc.byBackend[backend]
+}
+
+// RepeatedShapes returns the query shapes executed at least minCount times,
+// most frequent first
+func (qc *QueryCounter) RepeatedShapes(minCount int) []QueryShapeCount {
+	qc.mu.Lock()
+	defer qc.mu.Unlock()
+
+	repeated := make([]QueryShapeCount, 0)
+	for shape, count := range qc.byShape {
+		if count >= minCount {
+			repeated = append(repeated, QueryShapeCount{Shape: shape, Count: count})
+		}
+	}
+	sort.Slice(repeated, func(i, j int) bool {
+		if repeated[i].Count != repeated[j].Count {
+			return repeated[i].Count > repeated[j].Count
+		}
+		return repeated[i].Shape < repeated[j].Shape
+	})
+	return repeated
+}
+
+// registerQueryCounting hooks GORM so every statement executed with a
+// request context is recorded in that request's QueryCounter
+func registerQueryCounting(db *gorm.DB) error {
+	record := func(tx *gorm.DB) {
+		if counter := QueryCounterFromContext(tx.Statement.Context); counter != nil {
+			counter.Record(QueryBackendPostgres, tx.Statement.SQL.String())
+		}
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("cpls:count_create", record); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("cpls:count_query", record); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("cpls:count_update", record); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("cpls:count_delete", record); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("cpls:count_row", record); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("cpls:count_raw", record)
+}
+
+// mongoQueryMonitor records Mongo commands issued with a request context
+func mongoQueryMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			counter := QueryCounterFromContext(ctx)
+			if counter == nil {
+				return
+			}
+			switch evt.CommandName {
+			case "hello", "isMaster", "ping", "endSessions", "saslStart", "saslContinue":
+				return
+			}
+
+			shape := evt.CommandName
+			if collection, ok := evt.Command.Lookup(evt.CommandName).StringValueOK(); ok {
+				shape += " " + collection
+			}
+			counter.Record(QueryBackendMongo, shape)
+		},
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"context"
+	"testing"
+)
+
+func TestQueryCounterContext(t *testing.T) {
+	if QueryCounterFromContext(context.Background()) != nil {
+		t.Error("QueryCounterFromContext(background) should be nil")
+	}
+
+	counter := NewQueryCounter()
+	ctx := WithQueryCounter(context.Background(), counter)
+	if QueryCounterFromContext(ctx) != counter {
+		t.Error("QueryCounterFromContext did not return the attached counter")
+	}
+}
+
+func TestQueryCounterRepeatedShapes(t *testing.T) {
+	counter := NewQueryCounter()
+	for i := 0; i < 3; i++ {
+		counter.Record(QueryBackendPostgres, `SELECT * FROM "public"."profiles" WHERE id = $1`)
+	}
+	counter.Record(QueryBackendPostgres, `SELECT count(*) FROM "public"."profiles"`)
+	counter.Record(QueryBackendMongo, "find stocks")
+	counter.Record(QueryBackendMongo, "find stocks")
+
+	if counter.Total() != 6 {
+		t.Errorf("Total() = %d; want 6", counter.Total())
+	}
+	if counter.ByBackend(QueryBackendPostgres) != 4 || counter.ByBackend(QueryBackendMongo) != 2 {
+		t.Errorf("ByBackend() = postgres %d, mongo %d; want 4, 2",
+			counter.ByBackend(QueryBackendPostgres), counter.ByBackend(QueryBackendMongo))
+	}
+
+	repeated := counter.RepeatedShapes(2)
+	if len(repeated) != 2 {
+		t.Fatalf("RepeatedShapes(2) returned %d shapes; want 2", len(repeated))
+	}
+	if repeated[0].Count != 3 || repeated[1].Shape != "mongo: find stocks" {
+		t.Errorf("RepeatedShapes(2) = %+v; want the profile lookup (3) first, then mongo find stocks (2)", repeated)
+	}
+}
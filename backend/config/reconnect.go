@@ -0,0 +1,53 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+// poolCloseGrace is how long a replaced Postgres pool stays open so the
+// queries already running on it can finish
+const poolCloseGrace = 30 * time.Second
+
+// ReconnectPostgres opens a new Supabase connection pool and swaps it in,
+// dropping connections the old pool keeps to a database that failed over or
+// restarted. The old pool is closed after poolCloseGrace. When the new pool
+// cannot be opened the old one is kept.
+func ReconnectPostgres() error {
+	previous := PostgresDB
+	err := ConnectPostgres()
+	if PostgresDB != previous && previous != nil {
+		if sqlDB, dbErr := previous.DB(); dbErr == nil {
+			time.AfterFunc(poolCloseGrace, func() {
+				if closeErr := sqlDB.Close(); closeErr != nil {
+					storeLog.Warn("Failed to close the replaced PostgreSQL pool", logging.FieldError, closeErr)
+				}
+			})
+		}
+	}
+	if err != nil {
+		return err
+	}
+	storeLog.Info("PostgreSQL connection pool replaced")
+	return nil
+}
+
+// ReconnectMongo pings MongoDB and updates its availability. The driver
+// replaces broken connections of its pool by itself, and services hold
+// collections of the connected client, so the client is not replaced.
+func ReconnectMongo(ctx context.Context) error {
+	if MongoClient == nil {
+		return fmt.Errorf("%w: %s", ErrStoreUnavailable, StoreMongo)
+	}
+	ctx, cancel := context.WithTimeout(ctx, storePingTimeout)
+	defer cancel()
+	err := MongoClient.Ping(ctx, nil)
+	SetStoreAvailability(StoreMongo, err)
+	if err != nil {
+		return fmt.Errorf("%w: failed to ping MongoDB: %v", ErrStoreUnavailable, err)
+	}
+	return nil
+}
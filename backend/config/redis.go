@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisDialTimeout    = 5 * time.Second
+	redisCommandTimeout = 2 * time.Second
+	redisMaxIdleConns   = 16
+)
+
+// Redis is the optional global Redis client; nil when REDIS_URL is not set
+var Redis redis.UniversalClient
+
+// ConnectRedis connects to REDIS_URL (redis://[user:password@]host:port[/db],
+// or rediss:// for TLS). Redis is optional: without REDIS_URL it does nothing.
+func ConnectRedis() error {
+	rawURL := os.Getenv("REDIS_URL")
+	if rawURL == "" {
+		return nil
+	}
+
+	client, err := NewRedisClient(rawURL)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisDialTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return fmt.Errorf("failed to ping Redis: %w", err)
+	}
+
+	Redis = client
+	log.Printf("✓ Connected to Redis at %s (db %d)", client.Options().Addr, client.Options().DB)
+	return nil
+}
+
+// DisconnectRedis closes the Redis connection pool
+func DisconnectRedis() {
+	if Redis == nil {
+		return
+	}
+	if err := Redis.Close(); err != nil {
+		log.Printf("⚠️  Failed to close Redis: %v", err)
+	}
+}
+
+// NewRedisClient creates a client for a redis:// or rediss:// URL without connecting
+func NewRedisClient(rawURL string) (*redis.Client, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: expected redis://[user:password@]host:port[/db]: %w", err)
+	}
+	opts.DialTimeout = redisDialTimeout
+	opts.ReadTimeout = redisCommandTimeout
+	opts.WriteTimeout = redisCommandTimeout
+	opts.MaxIdleConns = redisMaxIdleConns
+	return redis.NewClient(opts), nil
+}
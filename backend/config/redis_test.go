@@ -0,0 +1,23 @@
+package config
+
+import "testing"
+
+func TestNewRedisClient(t *testing.T) {
+	client, err := NewRedisClient("redis://:secret@cache.internal/2")
+	if err != nil {
+		t.Fatalf("NewRedisClient() unexpected error: %v", err)
+	}
+	opts := client.Options()
+	if opts.Addr != "cache.internal:6379" || opts.Password != "secret" || opts.DB != 2 || opts.TLSConfig != nil {
+		t.Errorf("NewRedisClient() options = %+v; want cache.internal:6379, db 2, password set, no TLS", opts)
+	}
+
+	client, err = NewRedisClient("rediss://cache.internal:6380")
+	if err != nil || client.Options().TLSConfig == nil {
+		t.Errorf("NewRedisClient(rediss://...) = %v; want TLS", err)
+	}
+
+	if _, err := NewRedisClient("http://cache.internal"); err == nil {
+		t.Error("NewRedisClient(http://...) expected error but got none")
+	}
+}
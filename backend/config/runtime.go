@@ -0,0 +1,1061 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// runtimeLog logs runtime configuration reloads
+var runtimeLog = logging.Component("runtime")
+
+// FeatureFlagPrefix is the settings key prefix for feature flags (e.g. "feature.websocket")
+const FeatureFlagPrefix = "feature."
+
+// Sources of a runtime setting's effective value
+const (
+	SettingSourceDefault = "default"
+	SettingSourceEnv     = "env"
+	SettingSourceStore   = "store"
+)
+
+// RuntimeConfig holds configuration that can be reloaded without restarting
+// the instance. Connection settings (DATABASE_URL, MONGODB_URI, secrets) are
+// deliberately excluded; they are read once at startup.
+type RuntimeConfig struct {
+	CrawlerWorkers         int                    `json:"crawler_workers"`
+	CrawlerBackfillWorkers int                    `json:"crawler_backfill_workers"`
+	CrawlerRequestDelay    time.Duration          `json:"crawler_request_delay"`
+	CrawlerExchanges       []string               `json:"crawler_exchanges"`
+	CrawlerExchangeSources map[string]string      `json:"crawler_sources"` // Exchange -> data source, overriding the registry
+	CrawlerExcludedSymbols []string               `json:"crawler_excluded_symbols"`
+	CrawlerInstrumentTypes []string               `json:"crawler_instrument_types"`
+	CrawlerDerivatives     []string               `json:"crawler_derivatives"`
+	CrawlerSummaryChannels []string               `json:"crawler_summary_channels"`
+	CrawlerSchedule        string                 `json:"crawler_schedule"`
+	CrawlerIntraday        bool                   `json:"crawler_intraday"`
+	CrawlerIntradayStart   string                 `json:"crawler_intraday_start"`
+	CrawlerIntradayEnd     string                 `json:"crawler_intraday_end"`
+	CrawlerIntradayPoll    time.Duration          `json:"crawler_intraday_interval"`
+	CrawlerIntradaySymbols []string               `json:"crawler_intraday_symbols"`
+	CrawlerEndOfDayAt      string                 `json:"crawler_eod_time"`
+	ProviderQuotas         map[string][]RateLimit `json:"provider_quotas"`
+	ProviderQuotaReserve   int                    `json:"provider_quota_reserve"`
+	ProviderCacheMB        int                    `json:"provider_cache_mb"`
+	CrawlerVerifySample    int                    `json:"crawler_verify_sample"`
+	AlertMonitorInterval   time.Duration          `json:"alert_monitor_interval"`
+	QueryWarnThreshold     int                    `json:"db_query_warn_threshold"`
+	QueryRepeatThreshold   int                    `json:"db_query_repeat_threshold"`
+	QueryDebugHeader       bool                   `json:"db_query_debug_header"`
+	ConcurrencyLimits      map[string]int         `json:"concurrency_limits"`
+	ConcurrencyRetryAfter  time.Duration          `json:"concurrency_retry_after"`
+	IntegrityCheckInterval time.Duration          `json:"integrity_check_interval"`
+	RateLimits             map[string]RateLimit   `json:"rate_limits"`
+	TierRateLimits         map[string]RateLimit   `json:"tier_rate_limits"`  // Membership tier -> limit of members on the data APIs
+	TierHistoryDays        map[string]int         `json:"tier_history_days"` // Membership tier -> days of history served; absent for all of it
+	APIResponseFormat      models.ResponseFormat  `json:"api_response_format"`
+	LoginMaxFailures       int                    `json:"login_max_failures"`
+	LoginDelayBase         time.Duration          `json:"login_delay_base"`
+	LoginDelayMax          time.Duration          `json:"login_delay_max"`
+	LoginLockoutDuration   time.Duration          `json:"login_lockout_duration"`
+	PriceStorageEncoding   string                 `json:"price_storage_encoding"`
+	CompositeTimeout       time.Duration          `json:"composite_timeout"`
+	CanaryPercent          map[string]int         `json:"canary_percent"`
+	CanarySubjects         map[string][]string    `json:"canary_subjects"`
+	BackupTime             string                 `json:"backup_time"`
+	BackupRetentionDays    int                    `json:"backup_retention_days"`
+	DigestTime             string                 `json:"digest_time"`
+	SignalTypes            []string               `json:"signal_types"`
+	SignalFastMA           int                    `json:"signal_fast_ma"`
+	SignalSlowMA           int                    `json:"signal_slow_ma"`
+	SignalVolumeMultiple   float64                `json:"signal_volume_multiple"`
+	AnomalyVolumeMultiple  float64                `json:"anomaly_volume_multiple"`
+	AnomalyMinAvgVolume    float64                `json:"anomaly_min_avg_volume"`
+	HealthMaxCrawlAge      time.Duration          `json:"health_max_crawl_age"`
+	HealthStaleSessions    int                    `json:"health_stale_sessions"`
+
+	CacheTTLs             map[string]time.Duration `json:"cache_ttls"` // Cache namespace -> TTL
+	LoadShedMemoryPercent int                      `json:"load_shed_memory_percent"`
+	LoadShedQueueDepth    int                      `json:"load_shed_queue_depth"`
+	LoadShedRetryAfter    time.Duration            `json:"load_shed_retry_after"`
+
+	Environment  string                        `json:"environment"` // ENV; feature flags may target it
+	FeatureFlags map[string]models.FeatureFlag `json:"feature_flags"`
+
+	Sources  map[string]string `json:"sources"` // Setting key -> default, env or store
+	LoadedAt time.Time         `json:"loaded_at"`
+}
+
+// RateLimit allows Requests per Window for one client
+type RateLimit struct {
+	Requests int           `json:"requests"`
+	Window   time.Duration `json:"window"`
+}
+
+// RuntimeSetting describes one reloadable setting
+type RuntimeSetting struct {
+	Key         string `json:"key"`
+	Env         string `json:"env"`
+	Default     string `json:"default"`
+	Description string `json:"description"`
+	apply       func(cfg *RuntimeConfig, value string) error
+}
+
+// RuntimeSettings lists every reloadable setting, in display order
+var RuntimeSettings = []RuntimeSetting{
+	{
+		Key: "crawler.workers", Env: "CRAWLER_WORKERS", Default: "8",
+		Description: "Number of concurrent price crawl workers",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CrawlerWorkers, err = parsePositiveInt(v)
+			return err
+		},
+	},
+	{
+		Key: "crawler.backfill_workers", Env: "CRAWLER_BACKFILL_WORKERS", Default: "2",
+		Description: "Workers fetching the full history of new or long-stale symbols; the crawler.workers refresh the rest first, then help with the backfill",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CrawlerBackfillWorkers, err = parsePositiveInt(v)
+			return err
+		},
+	},
+	{
+		Key: "crawler.request_delay", Env: "CRAWLER_REQUEST_DELAY", Default: "150ms",
+		Description: "Delay each worker waits between provider requests",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CrawlerRequestDelay, err = parseDuration(v, true)
+			return err
+		},
+	},
+	{
+		Key: "crawler.exchanges", Env: "CRAWLER_EXCHANGES", Default: "HOSE,HNX,UPCOM",
+		Description: "Comma-separated exchanges whose symbols are crawled",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			cfg.CrawlerExchanges = splitUpper(v)
+			if len(cfg.CrawlerExchanges) == 0 {
+				return fmt.Errorf("at least one exchange is required")
+			}
+			for _, code := range cfg.CrawlerExchanges {
+				if _, ok := models.LookupExchange(code); !ok {
+					return fmt.Errorf("unknown exchange %q", code)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Key: "crawler.sources", Env: "CRAWLER_SOURCES", Default: "",
+		Description: "Data source of each exchange as EXCHANGE=source pairs (e.g. HOSE=vndirect), overriding the exchange registry",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CrawlerExchangeSources, err = parseExchangeSources(v)
+			return err
+		},
+	},
+	{
+		Key: "crawler.instrument_types", Env: "CRAWLER_INSTRUMENT_TYPES", Default: "stock",
+		Description: "Comma-separated instrument types whose symbols are crawled (stock, etf, cw, bond)",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			cfg.CrawlerInstrumentTypes = models.SplitList(strings.ToLower(v))
+			if len(cfg.CrawlerInstrumentTypes) == 0 {
+				return fmt.Errorf("at least one instrument type is required")
+			}
+			for _, instrumentType := range cfg.CrawlerInstrumentTypes {
+				if !models.ValidInstrumentType(instrumentType) {
+					return fmt.Errorf("unknown instrument type %q", instrumentType)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Key: "crawler.derivatives", Env: "CRAWLER_DERIVATIVES", Default: "VN30",
+		Description: "Comma-separated underlyings whose futures contracts are crawled after each full crawl; empty disables",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			cfg.CrawlerDerivatives = splitUpper(v)
+			return nil
+		},
+	},
+	{
+		Key: "crawler.schedule", Env: "CRAWLER_SCHEDULE", Default: models.CrawlScheduleOff,
+		Description: "off (crawls are started from the API, Telegram or Pub/Sub) or trading_hours (intraday polls and an end-of-day crawl on trading days)",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			v = strings.ToLower(strings.TrimSpace(v))
+			if v != models.CrawlScheduleOff && v != models.CrawlScheduleTradingHours {
+				return fmt.Errorf("expected off or trading_hours")
+			}
+			cfg.CrawlerSchedule = v
+			return nil
+		},
+	},
+	{
+		Key: "crawler.intraday", Env: "CRAWLER_INTRADAY", Default: "true",
+		Description: "Whether trading_hours polls prices within the intraday window; the end-of-day crawl runs either way",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			enabled, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("expected true or false")
+			}
+			cfg.CrawlerIntraday = enabled
+			return nil
+		},
+	},
+	{
+		Key: "crawler.intraday_window", Env: "CRAWLER_INTRADAY_WINDOW", Default: "09:00-15:00",
+		Description: "Exchange local time (HH:MM-HH:MM) during which trading_hours polls prices",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CrawlerIntradayStart, cfg.CrawlerIntradayEnd, err = models.ParseClockRange(v)
+			return err
+		},
+	},
+	{
+		Key: "crawler.intraday_interval", Env: "CRAWLER_INTRADAY_INTERVAL", Default: "15m",
+		Description: "Time between intraday polls; 0 disables them",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CrawlerIntradayPoll, err = parseDuration(v, true)
+			return err
+		},
+	},
+	{
+		Key: "crawler.intraday_symbols", Env: "CRAWLER_INTRADAY_SYMBOLS", Default: "",
+		Description: "Comma-separated symbols polled intraday; empty polls the whole universe",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			cfg.CrawlerIntradaySymbols = splitUpper(v)
+			return nil
+		},
+	},
+	{
+		Key: "crawler.eod_time", Env: "CRAWLER_EOD_TIME", Default: "15:15",
+		Description: "Exchange local time (HH:MM) after which trading_hours runs the day's full crawl",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			if _, err := time.Parse("15:04", v); err != nil {
+				return fmt.Errorf("expected a time like 15:15")
+			}
+			cfg.CrawlerEndOfDayAt = v
+			return nil
+		},
+	},
+	{
+		Key: "crawler.excluded_symbols", Env: "CRAWLER_EXCLUDED_SYMBOLS", Default: "",
+		Description: "Comma-separated symbols skipped by the crawler",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			cfg.CrawlerExcludedSymbols = splitUpper(v)
+			return nil
+		},
+	},
+	{
+		Key: "crawler.summary_channels", Env: "CRAWLER_SUMMARY_CHANNELS", Default: "",
+		Description: "Comma-separated notification channels that receive a summary of every finished crawl run (e.g. telegram)",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			cfg.CrawlerSummaryChannels = models.SplitList(v)
+			return nil
+		},
+	},
+	{
+		Key: "crawler.provider_quotas", Env: "PROVIDER_QUOTAS", Default: "",
+		Description: "Request ceilings of each upstream provider, as provider=requests/window pairs (a provider may have several windows, e.g. vndirect=3000/1h,vndirect=50000/24h); windows must divide a day and start at midnight Vietnam time",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.ProviderQuotas, err = parseProviderQuotas(v)
+			return err
+		},
+	},
+	{
+		Key: "crawler.provider_quota_reserve", Env: "PROVIDER_QUOTA_RESERVE", Default: "5",
+		Description: "Percent of each provider quota kept in reserve: crawling pauses once only this share of a window is left",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			reserve, err := strconv.Atoi(v)
+			if err != nil || reserve < 0 || reserve >= 100 {
+				return fmt.Errorf("expected a percentage from 0 to 99")
+			}
+			cfg.ProviderQuotaReserve = reserve
+			return nil
+		},
+	},
+	{
+		Key: "crawler.provider_cache_mb", Env: "PROVIDER_CACHE_MB", Default: "32",
+		Description: "Megabytes of provider responses kept while crawl runs are active, so identical requests within a run are fetched once; 0 disables the cache",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			mb, err := strconv.Atoi(v)
+			if err != nil || mb < 0 {
+				return fmt.Errorf("expected a non-negative number of megabytes")
+			}
+			cfg.ProviderCacheMB = mb
+			return nil
+		},
+	},
+	{
+		Key: "crawler.verify_sample", Env: "CRAWLER_VERIFY_SAMPLE", Default: "20",
+		Description: "Symbols re-fetched at random after each full crawl run to compare the provider's latest candles with the stored ones (sample_mismatch_ratio alert rules); 0 disables the check",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return fmt.Errorf("expected a non-negative number of symbols")
+			}
+			cfg.CrawlerVerifySample = n
+			return nil
+		},
+	},
+	{
+		Key: "alerts.monitor_interval", Env: "ALERT_MONITOR_INTERVAL", Default: "5m",
+		Description: "How often alert rules are evaluated",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.AlertMonitorInterval, err = parseDuration(v, false)
+			return err
+		},
+	},
+	{
+		Key: "db_query.warn_threshold", Env: "DB_QUERY_WARN_THRESHOLD", Default: "25",
+		Description: "Requests issuing more DB queries than this are logged",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.QueryWarnThreshold, err = parsePositiveInt(v)
+			return err
+		},
+	},
+	{
+		Key: "db_query.repeat_threshold", Env: "DB_QUERY_REPEAT_THRESHOLD", Default: "5",
+		Description: "Query shapes repeated this many times in one request are logged as possible N+1",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.QueryRepeatThreshold, err = parsePositiveInt(v)
+			return err
+		},
+	},
+	{
+		Key: "db_query.debug_header", Env: "DB_QUERY_DEBUG_HEADER", Default: "",
+		Description: "Expose X-DB-Query-Count response header (empty: enabled outside production)",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			if v == "" {
+				cfg.QueryDebugHeader = os.Getenv("ENV") != "production"
+				return nil
+			}
+			enabled, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("expected true or false")
+			}
+			cfg.QueryDebugHeader = enabled
+			return nil
+		},
+	},
+	{
+		Key: "concurrency.limits", Env: "CONCURRENCY_LIMITS", Default: "default=4",
+		Description: "Max simultaneous executions per heavy endpoint (name=limit pairs, 'default' applies to unlisted endpoints)",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.ConcurrencyLimits, err = parseLimits(v)
+			return err
+		},
+	},
+	{
+		Key: "concurrency.retry_after", Env: "CONCURRENCY_RETRY_AFTER", Default: "5s",
+		Description: "Retry-After sent when a heavy endpoint is at its concurrency limit",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.ConcurrencyRetryAfter, err = parseDuration(v, false)
+			return err
+		},
+	},
+	{
+		Key: "integrity.check_interval", Env: "INTEGRITY_CHECK_INTERVAL", Default: "24h",
+		Description: "How often price bucket checksums are verified in the background",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.IntegrityCheckInterval, err = parseDuration(v, false)
+			return err
+		},
+	},
+	{
+		Key: "rate_limit.limits", Env: "RATE_LIMITS", Default: "default=120/1m,auth=10/1m",
+		Description: "Requests allowed per API key (or per IP when anonymous) for each route group, as group=requests/window pairs",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.RateLimits, err = parseRateLimits(v)
+			return err
+		},
+	},
+	{
+		Key: "rate_limit.tiers", Env: "RATE_LIMIT_TIERS", Default: "free=60/1m,premium=600/1m",
+		Description: "Requests allowed per member on the public data APIs by membership tier, as tier=requests/window pairs; replaces the route group limits for members",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.TierRateLimits, err = parseRateLimitPairs(v)
+			if err != nil {
+				return err
+			}
+			for tier := range cfg.TierRateLimits {
+				if !models.ValidMembership(tier) {
+					return fmt.Errorf("unknown membership tier %q", tier)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Key: "tier.history_days", Env: "TIER_HISTORY_DAYS", Default: "free=90",
+		Description: "Days of price history members get on the public data APIs by membership tier, as tier=days pairs; unlisted tiers get the full history",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			cfg.TierHistoryDays = make(map[string]int)
+			for _, pair := range strings.Split(v, ",") {
+				if pair = strings.TrimSpace(pair); pair == "" {
+					continue
+				}
+				tier, rawDays, found := strings.Cut(pair, "=")
+				tier = strings.ToLower(strings.TrimSpace(tier))
+				if !found || !models.ValidMembership(tier) {
+					return fmt.Errorf("expected tier=days pairs with free or premium, got %q", pair)
+				}
+				days, err := parsePositiveInt(strings.TrimSpace(rawDays))
+				if err != nil {
+					return fmt.Errorf("days for %q: %w", tier, err)
+				}
+				cfg.TierHistoryDays[tier] = days
+			}
+			return nil
+		},
+	},
+	{
+		Key: "api.response_format", Env: "API_RESPONSE_FORMAT", Default: "v1",
+		Description: "Response format for /api clients that do not select one (version such as v2, or naming snake_case/camelCase/as_is plus envelope/bare)",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.APIResponseFormat, err = models.ParseResponseFormat(v, models.LegacyResponseFormat)
+			return err
+		},
+	},
+	{
+		Key: "login.max_failures", Env: "LOGIN_MAX_FAILURES", Default: "10",
+		Description: "Consecutive failed logins after which an admin account is locked for login.lockout_duration",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.LoginMaxFailures, err = parsePositiveInt(v)
+			return err
+		},
+	},
+	{
+		Key: "login.delay_base", Env: "LOGIN_DELAY_BASE", Default: "1s",
+		Description: "Wait imposed after the first failed login per username/IP; doubles with each further failure",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.LoginDelayBase, err = parseDuration(v, true)
+			return err
+		},
+	},
+	{
+		Key: "login.delay_max", Env: "LOGIN_DELAY_MAX", Default: "5m",
+		Description: "Longest wait imposed between failed logins",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.LoginDelayMax, err = parseDuration(v, true)
+			return err
+		},
+	},
+	{
+		Key: "login.lockout_duration", Env: "LOGIN_LOCKOUT_DURATION", Default: "15m",
+		Description: "How long an admin account stays locked after login.max_failures failed logins, unless another admin unlocks it",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.LoginLockoutDuration, err = parseDuration(v, true)
+			return err
+		},
+	},
+	{
+		Key: "storage.price_encoding", Env: "PRICE_STORAGE_ENCODING", Default: "plain",
+		Description: "Encoding for price buckets written by the crawler: plain (candle documents) or columnar (delta + zstd, ~85% smaller)",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			switch strings.ToLower(v) {
+			case "plain":
+				cfg.PriceStorageEncoding = models.BucketEncodingPlain
+			case "columnar":
+				cfg.PriceStorageEncoding = models.BucketEncodingColumnar
+			default:
+				return fmt.Errorf("expected plain or columnar")
+			}
+			return nil
+		},
+	},
+	{
+		Key: "composite.timeout", Env: "COMPOSITE_TIMEOUT", Default: "2s",
+		Description: "Time budget of composite endpoints (overview, stock detail); slow optional parts are left out with a warning",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CompositeTimeout, err = parseDuration(v, false)
+			return err
+		},
+	},
+	{
+		Key: "canary.percent", Env: "CANARY_PERCENT", Default: "",
+		Description: "Share of traffic served by the candidate implementation of canary routes (route=percent pairs, 0-100; clients stay on one side)",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CanaryPercent, err = parsePercentages(v)
+			return err
+		},
+	},
+	{
+		Key: "canary.subjects", Env: "CANARY_SUBJECTS", Default: "",
+		Description: "Clients always served by the candidate of canary routes (route=id|id pairs; API key IDs or member profile IDs)",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CanarySubjects, err = parseSubjectLists(v)
+			return err
+		},
+	},
+	{
+		Key: "backup.time", Env: "BACKUP_TIME", Default: "02:30",
+		Description: "Market time (HH:MM, MARKET_TIMEZONE) of the nightly backup to Google Cloud Storage",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			if _, err := time.Parse("15:04", v); err != nil {
+				return fmt.Errorf("expected a time like 02:30")
+			}
+			cfg.BackupTime = v
+			return nil
+		},
+	},
+	{
+		Key: "backup.retention_days", Env: "BACKUP_RETENTION_DAYS", Default: "14",
+		Description: "Nightly backups older than this many days are deleted",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.BackupRetentionDays, err = parsePositiveInt(v)
+			return err
+		},
+	},
+	{
+		Key: "digest.time", Env: "DIGEST_TIME", Default: "17:00",
+		Description: "Market time (HH:MM, MARKET_TIMEZONE) after which the day's end-of-day data is finalized and the data.digest webhook sent",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			if _, err := time.Parse("15:04", v); err != nil {
+				return fmt.Errorf("expected a time like 17:00")
+			}
+			cfg.DigestTime = v
+			return nil
+		},
+	},
+	{
+		Key: "signals.types", Env: "SIGNAL_TYPES", Default: "golden_cross,breakout_52w_high,volume_spike,candle_pattern",
+		Description: "Comma-separated signals computed after each crawl (golden_cross, breakout_52w_high, volume_spike, candle_pattern)",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			cfg.SignalTypes = models.SplitList(v)
+			for _, signalType := range cfg.SignalTypes {
+				if !models.ValidSignalType(signalType) {
+					return fmt.Errorf("unknown signal %q", signalType)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Key: "signals.golden_cross", Env: "SIGNAL_GOLDEN_CROSS", Default: "50/200",
+		Description: "Sessions of the fast and slow moving averages of the golden cross signal (fast/slow)",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			rawFast, rawSlow, found := strings.Cut(v, "/")
+			if !found {
+				return fmt.Errorf("expected fast/slow sessions like 50/200")
+			}
+			if cfg.SignalFastMA, err = parsePositiveInt(strings.TrimSpace(rawFast)); err != nil {
+				return err
+			}
+			if cfg.SignalSlowMA, err = parsePositiveInt(strings.TrimSpace(rawSlow)); err != nil {
+				return err
+			}
+			if cfg.SignalFastMA >= cfg.SignalSlowMA {
+				return fmt.Errorf("the fast average must be shorter than the slow one")
+			}
+			return nil
+		},
+	},
+	{
+		Key: "signals.volume_multiple", Env: "SIGNAL_VOLUME_MULTIPLE", Default: "3",
+		Description: "A volume spike signal fires when a session's volume is this multiple of its 20-session average",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			multiple, err := strconv.ParseFloat(v, 64)
+			if err != nil || multiple <= 1 {
+				return fmt.Errorf("expected a number greater than 1")
+			}
+			cfg.SignalVolumeMultiple = multiple
+			return nil
+		},
+	},
+	{
+		Key: "anomalies.volume_multiple", Env: "VOLUME_ANOMALY_MULTIPLE", Default: "3",
+		Description: "After each crawl, sessions whose volume is this multiple of the symbol's 20-session average are stored as volume anomalies",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			multiple, err := strconv.ParseFloat(v, 64)
+			if err != nil || multiple <= 1 {
+				return fmt.Errorf("expected a number greater than 1")
+			}
+			cfg.AnomalyVolumeMultiple = multiple
+			return nil
+		},
+	},
+	{
+		Key: "anomalies.min_avg_volume", Env: "VOLUME_ANOMALY_MIN_AVG_VOLUME", Default: "10000",
+		Description: "Symbols averaging fewer shares per session than this are not checked for volume anomalies, whose ratios are noise",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			volume, err := strconv.ParseFloat(v, 64)
+			if err != nil || volume < 0 {
+				return fmt.Errorf("expected a non-negative number")
+			}
+			cfg.AnomalyMinAvgVolume = volume
+			return nil
+		},
+	},
+	{
+		Key: "health.max_crawl_age", Env: "HEALTH_MAX_CRAWL_AGE", Default: "72h",
+		Description: "A deep health check reports the crawl as stale once the last successful crawl is older than this (covers weekends)",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.HealthMaxCrawlAge, err = parseDuration(v, false)
+			return err
+		},
+	},
+	{
+		Key: "health.stale_sessions", Env: "HEALTH_STALE_SESSIONS", Default: "3",
+		Description: "The crawler status counts a symbol as stale when it has no candle in this many of its exchange's latest trading sessions",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.HealthStaleSessions, err = parsePositiveInt(v)
+			return err
+		},
+	},
+	{
+		Key: "cache.ttls", Env: "CACHE_TTLS", Default: "prices=5m,indicators=5m,stocks=1m",
+		Description: "How long read API responses are cached per namespace (name=duration pairs: prices, indicators, stocks); namespaces are also invalidated after every crawl run, unlisted or 0s ones are not cached",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.CacheTTLs, err = parseTTLs(v)
+			return err
+		},
+	},
+	{
+		Key: "load_shedding.memory_percent", Env: "LOAD_SHED_MEMORY_PERCENT", Default: "85",
+		Description: "Expensive requests (exports, screener, crawls, backfills) are rejected with 503 while the instance uses more than this percent of its memory limit (GOMEMLIMIT or the container limit); 0 disables the check",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			percent, err := strconv.Atoi(v)
+			if err != nil || percent < 0 || percent > 100 {
+				return fmt.Errorf("expected a percentage from 0 to 100")
+			}
+			cfg.LoadShedMemoryPercent = percent
+			return nil
+		},
+	},
+	{
+		Key: "load_shedding.queue_depth", Env: "LOAD_SHED_QUEUE_DEPTH", Default: "3000",
+		Description: "Expensive requests are also rejected while more than this many crawl symbols and background jobs are queued or running in the instance; 0 disables the check",
+		apply: func(cfg *RuntimeConfig, v string) error {
+			depth, err := strconv.Atoi(v)
+			if err != nil || depth < 0 {
+				return fmt.Errorf("expected a non-negative number")
+			}
+			cfg.LoadShedQueueDepth = depth
+			return nil
+		},
+	},
+	{
+		Key: "load_shedding.retry_after", Env: "LOAD_SHED_RETRY_AFTER", Default: "30s",
+		Description: "Retry-After sent with requests rejected by load shedding",
+		apply: func(cfg *RuntimeConfig, v string) (err error) {
+			cfg.LoadShedRetryAfter, err = parseDuration(v, false)
+			return err
+		},
+	},
+}
+
+var runtimeConfig atomic.Pointer[RuntimeConfig]
+
+// Runtime returns the current runtime configuration snapshot. Callers should
+// call Runtime() at the point of use rather than caching the result, so that
+// reloads take effect.
+func Runtime() *RuntimeConfig {
+	if cfg := runtimeConfig.Load(); cfg != nil {
+		return cfg
+	}
+	// Not loaded from the store yet: fall back to defaults and environment
+	cfg, err := LoadRuntimeConfig(nil)
+	if err != nil {
+		cfg, _ = loadRuntimeConfig(nil, func(string) string { return "" })
+	}
+	runtimeConfig.CompareAndSwap(nil, cfg)
+	return runtimeConfig.Load()
+}
+
+// SetRuntime atomically replaces the runtime configuration
+func SetRuntime(cfg *RuntimeConfig) {
+	runtimeConfig.Store(cfg)
+}
+
+// LoadRuntimeConfig builds a RuntimeConfig from defaults, overridden by
+// environment variables, overridden by stored settings. Keys starting with
+// FeatureFlagPrefix are parsed as feature flags (see models.FeatureFlag);
+// unknown keys are ignored with a warning.
+func LoadRuntimeConfig(stored map[string]string) (*RuntimeConfig, error) {
+	return loadRuntimeConfig(stored, os.Getenv)
+}
+
+func loadRuntimeConfig(stored map[string]string, getenv func(string) string) (*RuntimeConfig, error) {
+	cfg := &RuntimeConfig{
+		FeatureFlags: make(map[string]models.FeatureFlag),
+		Environment:  strings.ToLower(strings.TrimSpace(getenv("ENV"))),
+		Sources:      make(map[string]string),
+		LoadedAt:     time.Now().UTC(),
+	}
+	if cfg.Environment == "" {
+		cfg.Environment = "development"
+	}
+	for name, enabled := range models.DefaultFeatureFlags {
+		cfg.FeatureFlags[name] = models.FeatureFlag{Enabled: enabled}
+	}
+
+	for _, setting := range RuntimeSettings {
+		value, source := setting.Default, SettingSourceDefault
+		if env := getenv(setting.Env); env != "" {
+			value, source = env, SettingSourceEnv
+		}
+		if v, ok := stored[setting.Key]; ok {
+			value, source = v, SettingSourceStore
+		}
+		if err := setting.apply(cfg, strings.TrimSpace(value)); err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s (%s): %w", value, setting.Key, source, err)
+		}
+		cfg.Sources[setting.Key] = source
+	}
+
+	keys := make([]string, 0, len(stored))
+	for key := range stored {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !strings.HasPrefix(key, FeatureFlagPrefix) {
+			// A key written by a newer release, or left behind by an older
+			// one, must not stop every instance from reloading
+			if !IsRuntimeSetting(key) {
+				runtimeLog.Warn("Ignoring unknown runtime setting", "key", key)
+			}
+			continue
+		}
+		flag, err := models.ParseFeatureFlag(stored[key])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: %w", stored[key], key, err)
+		}
+		cfg.FeatureFlags[strings.TrimPrefix(key, FeatureFlagPrefix)] = flag
+		cfg.Sources[key] = SettingSourceStore
+	}
+
+	return cfg, nil
+}
+
+// IsRuntimeSetting reports whether key is a known setting or a feature flag
+func IsRuntimeSetting(key string) bool {
+	if strings.HasPrefix(key, FeatureFlagPrefix) && len(key) > len(FeatureFlagPrefix) {
+		return true
+	}
+	for _, setting := range RuntimeSettings {
+		if setting.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// FeatureEnabled reports whether a feature flag is enabled in this
+// environment for callers without a membership tier (false when unset)
+func (cfg *RuntimeConfig) FeatureEnabled(name string) bool {
+	return cfg.FeatureEnabledFor(name, "")
+}
+
+// FeatureEnabledFor reports whether a feature flag is enabled in this
+// environment for a member of tier (false when unset)
+func (cfg *RuntimeConfig) FeatureEnabledFor(name, tier string) bool {
+	flag, ok := cfg.FeatureFlags[name]
+	return ok && flag.EnabledFor(cfg.Environment, tier)
+}
+
+// EnabledFeatures returns the sorted names of the feature flags enabled in
+// this environment for a member of tier
+func (cfg *RuntimeConfig) EnabledFeatures(tier string) []string {
+	names := make([]string, 0, len(cfg.FeatureFlags))
+	for name := range cfg.FeatureFlags {
+		if cfg.FeatureEnabledFor(name, tier) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsExcludedSymbol reports whether the crawler should skip the symbol
+func (cfg *RuntimeConfig) IsExcludedSymbol(code string) bool {
+	for _, excluded := range cfg.CrawlerExcludedSymbols {
+		if strings.EqualFold(excluded, code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Signals returns the signal settings
+func (cfg *RuntimeConfig) Signals() models.SignalConfig {
+	return models.SignalConfig{
+		Types:          cfg.SignalTypes,
+		FastMA:         cfg.SignalFastMA,
+		SlowMA:         cfg.SignalSlowMA,
+		VolumeMultiple: cfg.SignalVolumeMultiple,
+	}
+}
+
+// CrawlSchedule returns the crawl schedule settings; intraday polls have
+// no interval while crawler.intraday is off
+func (cfg *RuntimeConfig) CrawlSchedule() models.CrawlSchedule {
+	schedule := models.CrawlSchedule{
+		Mode:             cfg.CrawlerSchedule,
+		IntradayStart:    cfg.CrawlerIntradayStart,
+		IntradayEnd:      cfg.CrawlerIntradayEnd,
+		IntradayInterval: cfg.CrawlerIntradayPoll,
+		EndOfDayAt:       cfg.CrawlerEndOfDayAt,
+	}
+	if !cfg.CrawlerIntraday {
+		schedule.IntradayInterval = 0
+	}
+	return schedule
+}
+
+// ConcurrencyLimit returns the concurrency limit for a named endpoint,
+// falling back to the "default" entry
+func (cfg *RuntimeConfig) ConcurrencyLimit(name string) int {
+	if limit, ok := cfg.ConcurrencyLimits[name]; ok {
+		return limit
+	}
+	return cfg.ConcurrencyLimits["default"]
+}
+
+// CacheTTL returns how long responses of a cache namespace are kept; 0
+// disables caching
+func (cfg *RuntimeConfig) CacheTTL(namespace string) time.Duration {
+	return cfg.CacheTTLs[namespace]
+}
+
+// RateLimitFor returns the rate limit of a route group, falling back to the
+// "default" entry
+func (cfg *RuntimeConfig) RateLimitFor(group string) RateLimit {
+	if limit, ok := cfg.RateLimits[group]; ok {
+		return limit
+	}
+	return cfg.RateLimits["default"]
+}
+
+// TierRateLimit returns the rate limit of members of tier on the data APIs
+func (cfg *RuntimeConfig) TierRateLimit(tier string) (RateLimit, bool) {
+	limit, ok := cfg.TierRateLimits[tier]
+	return limit, ok
+}
+
+// HistoryDays returns how many days of price history members of tier get,
+// 0 for all of it
+func (cfg *RuntimeConfig) HistoryDays(tier string) int {
+	return cfg.TierHistoryDays[tier]
+}
+
+// parseRateLimits parses "group=requests/window" pairs separated by commas,
+// requiring a "default" group
+func parseRateLimits(v string) (map[string]RateLimit, error) {
+	limits, err := parseRateLimitPairs(v)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := limits["default"]; !ok {
+		return nil, fmt.Errorf("a 'default' limit is required")
+	}
+	return limits, nil
+}
+
+// parseRateLimitPairs parses "name=requests/window" pairs separated by commas
+func parseRateLimitPairs(v string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		group, spec, found := strings.Cut(pair, "=")
+		group = strings.TrimSpace(group)
+		rawRequests, rawWindow, hasWindow := strings.Cut(spec, "/")
+		if !found || !hasWindow || group == "" {
+			return nil, fmt.Errorf("expected group=requests/window pairs, got %q", pair)
+		}
+		requests, err := parsePositiveInt(strings.TrimSpace(rawRequests))
+		if err != nil {
+			return nil, fmt.Errorf("requests for %q: %w", group, err)
+		}
+		window, err := parseDuration(strings.TrimSpace(rawWindow), false)
+		if err != nil {
+			return nil, fmt.Errorf("window for %q: %w", group, err)
+		}
+		limits[group] = RateLimit{Requests: requests, Window: window}
+	}
+	return limits, nil
+}
+
+// parseProviderQuotas parses "provider=requests/window" pairs separated by
+// commas; a provider may be listed once per window
+func parseProviderQuotas(v string) (map[string][]RateLimit, error) {
+	quotas := make(map[string][]RateLimit)
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		provider, spec, found := strings.Cut(pair, "=")
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		rawRequests, rawWindow, hasWindow := strings.Cut(spec, "/")
+		if !found || !hasWindow || provider == "" {
+			return nil, fmt.Errorf("expected provider=requests/window pairs, got %q", pair)
+		}
+		requests, err := parsePositiveInt(strings.TrimSpace(rawRequests))
+		if err != nil {
+			return nil, fmt.Errorf("requests for %q: %w", provider, err)
+		}
+		window, err := parseDuration(strings.TrimSpace(rawWindow), false)
+		if err != nil {
+			return nil, fmt.Errorf("window for %q: %w", provider, err)
+		}
+		if window > 24*time.Hour || (24*time.Hour)%window != 0 {
+			return nil, fmt.Errorf("window for %q: %s does not divide a day", provider, window)
+		}
+		for _, quota := range quotas[provider] {
+			if quota.Window == window {
+				return nil, fmt.Errorf("window %s listed twice for %q", window, provider)
+			}
+		}
+		quotas[provider] = append(quotas[provider], RateLimit{Requests: requests, Window: window})
+	}
+	return quotas, nil
+}
+
+// CanaryListed reports whether subject is pinned to the candidate of a canary route
+func (cfg *RuntimeConfig) CanaryListed(route, subject string) bool {
+	for _, listed := range cfg.CanarySubjects[route] {
+		if listed == subject {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePercentages parses "name=percent" pairs separated by commas
+func parsePercentages(v string) (map[string]int, error) {
+	percentages := make(map[string]int)
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, rawPercent, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("expected name=percent pairs, got %q", pair)
+		}
+		percent, err := strconv.Atoi(strings.TrimSpace(rawPercent))
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("percent for %q: expected an integer from 0 to 100", name)
+		}
+		percentages[name] = percent
+	}
+	return percentages, nil
+}
+
+// parseSubjectLists parses "name=id|id" pairs separated by commas
+func parseSubjectLists(v string) (map[string][]string, error) {
+	lists := make(map[string][]string)
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, rawList, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("expected name=id|id pairs, got %q", pair)
+		}
+		for _, subject := range strings.Split(rawList, "|") {
+			if subject = strings.TrimSpace(subject); subject != "" {
+				lists[name] = append(lists[name], subject)
+			}
+		}
+	}
+	return lists, nil
+}
+
+// parseExchangeSources parses "EXCHANGE=source" pairs separated by commas;
+// exchanges must be registered
+func parseExchangeSources(v string) (map[string]string, error) {
+	sources := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		code, source, found := strings.Cut(pair, "=")
+		code, source = strings.ToUpper(strings.TrimSpace(code)), strings.ToLower(strings.TrimSpace(source))
+		if !found || code == "" || source == "" {
+			return nil, fmt.Errorf("expected EXCHANGE=source pairs, got %q", pair)
+		}
+		if _, ok := models.LookupExchange(code); !ok {
+			return nil, fmt.Errorf("unknown exchange %q", code)
+		}
+		sources[code] = source
+	}
+	return sources, nil
+}
+
+// parseLimits parses "name=limit" pairs separated by commas
+func parseLimits(v string) (map[string]int, error) {
+	limits := make(map[string]int)
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, rawLimit, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("expected name=limit pairs, got %q", pair)
+		}
+		limit, err := parsePositiveInt(strings.TrimSpace(rawLimit))
+		if err != nil {
+			return nil, fmt.Errorf("limit for %q: %w", name, err)
+		}
+		limits[name] = limit
+	}
+	if _, ok := limits["default"]; !ok {
+		return nil, fmt.Errorf("a 'default' limit is required")
+	}
+	return limits, nil
+}
+
+func parseTTLs(v string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for _, pair := range strings.Split(v, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, rawTTL, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("expected name=duration pairs, got %q", pair)
+		}
+		ttl, err := parseDuration(strings.TrimSpace(rawTTL), true)
+		if err != nil {
+			return nil, fmt.Errorf("TTL for %q: %w", name, err)
+		}
+		ttls[name] = ttl
+	}
+	return ttls, nil
+}
+
+func parsePositiveInt(v string) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("expected a positive integer")
+	}
+	return n, nil
+}
+
+func parseDuration(v string, allowZero bool) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 || (d == 0 && !allowZero) {
+		return 0, fmt.Errorf("expected a positive duration like 150ms or 5m")
+	}
+	return d, nil
+}
+
+func splitUpper(v string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.ToUpper(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
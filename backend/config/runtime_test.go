@@ -0,0 +1,338 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestLoadRuntimeConfigPrecedence(t *testing.T) {
+	env := map[string]string{
+		"CRAWLER_WORKERS":        "4",
+		"ALERT_MONITOR_INTERVAL": "10m",
+	}
+	stored := map[string]string{
+		"crawler.workers":          "2",
+		"crawler.excluded_symbols": "abc, xyz",
+		"feature.realtime_push":    "true",
+		"feature.legacy_envelope":  "false",
+	}
+
+	cfg, err := loadRuntimeConfig(stored, func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+
+	if cfg.CrawlerWorkers != 2 || cfg.Sources["crawler.workers"] != SettingSourceStore {
+		t.Errorf("CrawlerWorkers = %d (%s); want 2 from store", cfg.CrawlerWorkers, cfg.Sources["crawler.workers"])
+	}
+	if cfg.AlertMonitorInterval != 10*time.Minute || cfg.Sources["alerts.monitor_interval"] != SettingSourceEnv {
+		t.Errorf("AlertMonitorInterval = %s (%s); want 10m from env", cfg.AlertMonitorInterval, cfg.Sources["alerts.monitor_interval"])
+	}
+	if cfg.CrawlerRequestDelay != 150*time.Millisecond || cfg.Sources["crawler.request_delay"] != SettingSourceDefault {
+		t.Errorf("CrawlerRequestDelay = %s (%s); want 150ms default", cfg.CrawlerRequestDelay, cfg.Sources["crawler.request_delay"])
+	}
+	if len(cfg.CrawlerExchanges) != 3 || cfg.CrawlerExchanges[0] != "HOSE" {
+		t.Errorf("CrawlerExchanges = %v; want [HOSE HNX UPCOM]", cfg.CrawlerExchanges)
+	}
+	if !cfg.IsExcludedSymbol("ABC") || !cfg.IsExcludedSymbol("xyz") || cfg.IsExcludedSymbol("HPG") {
+		t.Errorf("CrawlerExcludedSymbols = %v; want ABC and XYZ only", cfg.CrawlerExcludedSymbols)
+	}
+	if !cfg.FeatureEnabled("realtime_push") || cfg.FeatureEnabled("legacy_envelope") || cfg.FeatureEnabled("unknown") {
+		t.Errorf("FeatureFlags = %v; want realtime_push enabled only", cfg.FeatureFlags)
+	}
+}
+
+func TestFeatureFlagTargeting(t *testing.T) {
+	env := map[string]string{"ENV": "Production"}
+	stored := map[string]string{
+		"feature.intraday_crawl": "env:production",
+		"feature.new_screener":   "env:production,tier:premium",
+		"feature.postgres_store": "env:staging",
+		"feature.screener":       "false",
+	}
+	cfg, err := loadRuntimeConfig(stored, func(name string) string { return env[name] })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+
+	if cfg.Environment != "production" {
+		t.Errorf("Environment = %q; want production", cfg.Environment)
+	}
+	if !cfg.FeatureEnabled("intraday_crawl") || cfg.FeatureEnabled("postgres_store") || cfg.FeatureEnabled("new_screener") {
+		t.Errorf("FeatureEnabled() does not follow env targets: %v", cfg.FeatureFlags)
+	}
+	if !cfg.FeatureEnabledFor("new_screener", models.MembershipPremium) || cfg.FeatureEnabledFor("new_screener", models.MembershipFree) {
+		t.Errorf("FeatureEnabledFor() does not follow tier targets: %v", cfg.FeatureFlags["new_screener"])
+	}
+	if got, want := cfg.EnabledFeatures(models.MembershipPremium), []string{"intraday_crawl", "new_screener"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("EnabledFeatures(premium) = %v; want %v", got, want)
+	}
+
+	defaults, err := loadRuntimeConfig(nil, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+	if defaults.Environment != "development" || !defaults.FeatureEnabled(models.FeatureScreener) {
+		t.Errorf("defaults: Environment = %q, screener enabled = %v; want development and true",
+			defaults.Environment, defaults.FeatureEnabled(models.FeatureScreener))
+	}
+}
+
+func TestLoadRuntimeConfigRejectsInvalidValues(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	invalid := []map[string]string{
+		{"crawler.workers": "0"},
+		{"crawler.request_delay": "fast"},
+		{"crawler.exchanges": " , "},
+		{"crawler.exchanges": "HOSE,NYSE"},
+		{"alerts.monitor_interval": "0s"},
+		{"db_query.debug_header": "maybe"},
+		{"concurrency.limits": "stock_metadata=2"},
+		{"concurrency.limits": "default=4,exports"},
+		{"concurrency.limits": "default=0"},
+		{"rate_limit.limits": "default=100"},
+		{"rate_limit.limits": "auth=10/1m"},
+		{"rate_limit.tiers": "gold=1000/1m"},
+		{"rate_limit.tiers": "free=60"},
+		{"tier.history_days": "free=0"},
+		{"tier.history_days": "gold=30"},
+		{"feature.realtime_push": "yes please"},
+		{"feature.realtime_push": "tier:gold"},
+		{"canary.percent": "candles=101"},
+		{"canary.percent": "candles"},
+		{"canary.subjects": "=abc"},
+		{"backup.time": "2:30am"},
+		{"crawler.schedule": "hourly"},
+		{"crawler.intraday_window": "15:00-09:00"},
+		{"crawler.eod_time": "3pm"},
+		{"crawler.intraday": "sometimes"},
+		{"crawler.sources": "NYSE=vndirect"},
+		{"crawler.sources": "HOSE"},
+		{"backup.retention_days": "0"},
+		{"cache.ttls": "prices"},
+		{"cache.ttls": "prices=-1m"},
+		{"load_shedding.memory_percent": "101"},
+		{"load_shedding.queue_depth": "-1"},
+	}
+
+	for _, stored := range invalid {
+		if _, err := loadRuntimeConfig(stored, noEnv); err == nil {
+			t.Errorf("loadRuntimeConfig(%v) expected error but got none", stored)
+		}
+	}
+}
+
+func TestLoadRuntimeConfigIgnoresUnknownSettings(t *testing.T) {
+	stored := map[string]string{"unknown.setting": "1", "crawler.workers": "3"}
+	cfg, err := loadRuntimeConfig(stored, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+	if cfg.CrawlerWorkers != 3 {
+		t.Errorf("CrawlerWorkers = %d; want 3 from the store", cfg.CrawlerWorkers)
+	}
+	if _, ok := cfg.Sources["unknown.setting"]; ok {
+		t.Error("unknown setting was recorded in Sources")
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	stored := map[string]string{"concurrency.limits": "default=4, exports=1"}
+	cfg, err := loadRuntimeConfig(stored, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+
+	if got := cfg.ConcurrencyLimit("exports"); got != 1 {
+		t.Errorf("ConcurrencyLimit(exports) = %d; want 1", got)
+	}
+	if got := cfg.ConcurrencyLimit("screener"); got != 4 {
+		t.Errorf("ConcurrencyLimit(screener) = %d; want default 4", got)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	stored := map[string]string{"cache.ttls": "prices=5m, stocks=0s"}
+	cfg, err := loadRuntimeConfig(stored, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+
+	if got := cfg.CacheTTL("prices"); got != 5*time.Minute {
+		t.Errorf("CacheTTL(prices) = %v; want 5m", got)
+	}
+	if got := cfg.CacheTTL("stocks"); got != 0 {
+		t.Errorf("CacheTTL(stocks) = %v; want 0", got)
+	}
+	if got := cfg.CacheTTL("indicators"); got != 0 {
+		t.Errorf("CacheTTL(indicators) = %v; want 0 when unlisted", got)
+	}
+}
+
+func TestRateLimitFor(t *testing.T) {
+	stored := map[string]string{"rate_limit.limits": "default=120/1m, auth=10/30s"}
+	cfg, err := loadRuntimeConfig(stored, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+
+	if got := cfg.RateLimitFor("auth"); got.Requests != 10 || got.Window != 30*time.Second {
+		t.Errorf("RateLimitFor(auth) = %+v; want 10 per 30s", got)
+	}
+	if got := cfg.RateLimitFor("stocks"); got.Requests != 120 || got.Window != time.Minute {
+		t.Errorf("RateLimitFor(stocks) = %+v; want default 120 per 1m", got)
+	}
+}
+
+func TestCanarySettings(t *testing.T) {
+	stored := map[string]string{
+		"canary.percent":  "candles=10, metadata=0",
+		"canary.subjects": "candles=key-1|key-2",
+	}
+	cfg, err := loadRuntimeConfig(stored, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+
+	if cfg.CanaryPercent["candles"] != 10 || cfg.CanaryPercent["metadata"] != 0 {
+		t.Errorf("CanaryPercent = %v; want candles=10, metadata=0", cfg.CanaryPercent)
+	}
+	if !cfg.CanaryListed("candles", "key-2") || cfg.CanaryListed("candles", "key-3") || cfg.CanaryListed("metadata", "key-1") {
+		t.Errorf("CanarySubjects = %v; want key-1 and key-2 on candles only", cfg.CanarySubjects)
+	}
+}
+
+func TestSignalSettings(t *testing.T) {
+	stored := map[string]string{
+		"signals.types":           "golden_cross,volume_spike",
+		"signals.golden_cross":    "20/50",
+		"signals.volume_multiple": "2.5",
+	}
+	cfg, err := loadRuntimeConfig(stored, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+	signals := cfg.Signals()
+	if signals.FastMA != 20 || signals.SlowMA != 50 || signals.VolumeMultiple != 2.5 {
+		t.Errorf("Signals() = %+v; want 20/50 and 2.5", signals)
+	}
+	if !signals.Enabled("volume_spike") || signals.Enabled("breakout_52w_high") {
+		t.Errorf("Types = %v; want golden_cross and volume_spike", signals.Types)
+	}
+
+	for key, value := range map[string]string{
+		"signals.types":           "death_cross",
+		"signals.golden_cross":    "200/50",
+		"signals.volume_multiple": "1",
+	} {
+		if _, err := loadRuntimeConfig(map[string]string{key: value}, func(string) string { return "" }); err == nil {
+			t.Errorf("%s=%s: expected an error", key, value)
+		}
+	}
+}
+
+func TestCrawlScheduleSettings(t *testing.T) {
+	cfg, err := loadRuntimeConfig(map[string]string{
+		"crawler.schedule":          "Trading_Hours",
+		"crawler.intraday_window":   "09:15-14:45",
+		"crawler.intraday_interval": "5m",
+	}, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+	schedule := cfg.CrawlSchedule()
+	if schedule.Mode != models.CrawlScheduleTradingHours || schedule.IntradayStart != "09:15" || schedule.IntradayEnd != "14:45" ||
+		schedule.IntradayInterval != 5*time.Minute || schedule.EndOfDayAt != "15:15" {
+		t.Errorf("CrawlSchedule() = %+v; want trading_hours polling 09:15-14:45 every 5m, end of day at 15:15", schedule)
+	}
+
+	cfg, err = loadRuntimeConfig(map[string]string{"crawler.intraday": "false", "crawler.intraday_interval": "5m"}, func(string) string { return "" })
+	if err != nil || cfg.CrawlSchedule().IntradayInterval != 0 {
+		t.Errorf("CrawlSchedule() with crawler.intraday off = %+v, %v; want no intraday interval", cfg.CrawlSchedule(), err)
+	}
+}
+
+func TestCrawlerSourceSettings(t *testing.T) {
+	cfg, err := loadRuntimeConfig(map[string]string{"crawler.sources": "hose=Fixture, HNX=vndirect"}, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+	if len(cfg.CrawlerExchangeSources) != 2 || cfg.CrawlerExchangeSources["HOSE"] != "fixture" || cfg.CrawlerExchangeSources["HNX"] != "vndirect" {
+		t.Errorf("CrawlerExchangeSources = %v; want HOSE=fixture, HNX=vndirect", cfg.CrawlerExchangeSources)
+	}
+}
+
+func TestTierQuotaSettings(t *testing.T) {
+	cfg, err := loadRuntimeConfig(nil, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+	if limit, ok := cfg.TierRateLimit(models.MembershipFree); !ok || limit != (RateLimit{60, time.Minute}) {
+		t.Errorf("TierRateLimit(free) = %v, %v; want 60/1m", limit, ok)
+	}
+	if cfg.HistoryDays(models.MembershipFree) != 90 || cfg.HistoryDays(models.MembershipPremium) != 0 {
+		t.Errorf("HistoryDays() = free %d, premium %d; want 90 and the full history", cfg.HistoryDays(models.MembershipFree), cfg.HistoryDays(models.MembershipPremium))
+	}
+
+	cfg, err = loadRuntimeConfig(map[string]string{"rate_limit.tiers": "premium=1000/1m", "tier.history_days": "free=30, premium=3650"}, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+	if _, ok := cfg.TierRateLimit(models.MembershipFree); ok {
+		t.Error("TierRateLimit(free) without a free entry should fall back to the group limits")
+	}
+	if cfg.HistoryDays(models.MembershipFree) != 30 || cfg.HistoryDays(models.MembershipPremium) != 3650 {
+		t.Errorf("HistoryDays() = %v; want free 30, premium 3650", cfg.TierHistoryDays)
+	}
+}
+
+func TestProviderQuotaSettings(t *testing.T) {
+	stored := map[string]string{"crawler.provider_quotas": "VNDirect=3000/1h, vndirect=50000/24h, ssi=500/15m"}
+	cfg, err := loadRuntimeConfig(stored, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loadRuntimeConfig() unexpected error: %v", err)
+	}
+	vndirect := cfg.ProviderQuotas["vndirect"]
+	if len(vndirect) != 2 || vndirect[0] != (RateLimit{3000, time.Hour}) || vndirect[1] != (RateLimit{50000, 24 * time.Hour}) {
+		t.Errorf("ProviderQuotas[vndirect] = %v; want 3000/1h and 50000/24h", vndirect)
+	}
+	if ssi := cfg.ProviderQuotas["ssi"]; len(ssi) != 1 || ssi[0].Window != 15*time.Minute {
+		t.Errorf("ProviderQuotas[ssi] = %v; want 500/15m", ssi)
+	}
+	if cfg.ProviderQuotaReserve != 5 {
+		t.Errorf("ProviderQuotaReserve = %d; want default 5", cfg.ProviderQuotaReserve)
+	}
+	if cfg.ProviderCacheMB != 32 {
+		t.Errorf("ProviderCacheMB = %d; want default 32", cfg.ProviderCacheMB)
+	}
+
+	for key, value := range map[string]string{
+		"crawler.provider_quotas":        "vndirect=100/7h",
+		"crawler.provider_quota_reserve": "100",
+		"crawler.provider_cache_mb":      "-1",
+	} {
+		if _, err := loadRuntimeConfig(map[string]string{key: value}, func(string) string { return "" }); err == nil {
+			t.Errorf("%s=%s: expected an error", key, value)
+		}
+	}
+	if _, err := parseProviderQuotas("vndirect=100/1h,vndirect=200/1h"); err == nil {
+		t.Errorf("parseProviderQuotas() with a repeated window: expected an error")
+	}
+}
+
+func TestIsRuntimeSetting(t *testing.T) {
+	tests := map[string]bool{
+		"crawler.workers":  true,
+		"feature.beta_api": true,
+		"feature.":         false,
+		"database_url":     false,
+	}
+	for key, expected := range tests {
+		if IsRuntimeSetting(key) != expected {
+			t.Errorf("IsRuntimeSetting(%q) = %v; want %v", key, !expected, expected)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+// storeLog logs data store availability
+var storeLog = logging.Component("stores")
+
+// Data stores the API depends on
+const (
+	StorePostgres = "postgres" // Supabase: accounts, settings, jobs, webhooks
+	StoreMongo    = "mongodb"  // Stocks, prices and crawl runs
+)
+
+// Stores lists every data store
+var Stores = []string{StorePostgres, StoreMongo}
+
+const (
+	// DefaultStoreCheckInterval is how often the store monitor pings each store
+	DefaultStoreCheckInterval = 15 * time.Second
+	// storePingTimeout bounds one availability check
+	storePingTimeout = 5 * time.Second
+)
+
+// ErrStoreUnavailable is returned when a configured data store cannot be
+// reached. The connection is kept and retried, so callers may start degraded.
+var ErrStoreUnavailable = errors.New("data store unavailable")
+
+// StoreStatus is the last known availability of one data store
+type StoreStatus struct {
+	Name      string    `json:"name"`
+	Available bool      `json:"available"`
+	Since     time.Time `json:"since"` // When the store became (un)available
+	Error     string    `json:"error,omitempty"`
+}
+
+var (
+	storeMu       sync.RWMutex
+	storeStatuses = map[string]*StoreStatus{}
+	storeInterval = DefaultStoreCheckInterval
+)
+
+// SetStoreAvailability records the outcome of reaching a store, logging
+// changes. A nil err marks the store available.
+func SetStoreAvailability(name string, err error) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+
+	status, known := storeStatuses[name]
+	available := err == nil
+	if known && status.Available == available {
+		if err != nil {
+			status.Error = err.Error()
+		}
+		return
+	}
+
+	status = &StoreStatus{Name: name, Available: available, Since: time.Now().UTC()}
+	if err != nil {
+		status.Error = err.Error()
+		storeLog.Error("Data store unavailable, routes using it answer 503", "store", name, logging.FieldError, err)
+	} else if known {
+		storeLog.Info("Data store available again", "store", name)
+	}
+	storeStatuses[name] = status
+}
+
+// StoreAvailable reports whether a store was reachable at its last check.
+// Stores that were never connected are unavailable.
+func StoreAvailable(name string) bool {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	status, ok := storeStatuses[name]
+	return ok && status.Available
+}
+
+// UnavailableStores returns those of names that are not available
+func UnavailableStores(names ...string) []string {
+	var down []string
+	for _, name := range names {
+		if !StoreAvailable(name) {
+			down = append(down, name)
+		}
+	}
+	return down
+}
+
+// StoreStatuses returns the availability of every store that was connected
+func StoreStatuses() []StoreStatus {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	statuses := make([]StoreStatus, 0, len(storeStatuses))
+	for _, name := range Stores {
+		if status, ok := storeStatuses[name]; ok {
+			statuses = append(statuses, *status)
+		}
+	}
+	return statuses
+}
+
+// PostgresAvailable reports whether Supabase is connected and reachable
+func PostgresAvailable() bool {
+	return PostgresDB != nil && StoreAvailable(StorePostgres)
+}
+
+// StoreCheckInterval is the interval of the running store monitor, which
+// is also when a client rejected for an unavailable store should retry
+func StoreCheckInterval() time.Duration {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return storeInterval
+}
+
+// StartStoreMonitor pings the connected stores every interval until ctx is
+// cancelled, so outages after startup are noticed and recoveries picked up
+func StartStoreMonitor(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultStoreCheckInterval
+	}
+	storeMu.Lock()
+	storeInterval = interval
+	storeMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				CheckStores(ctx)
+			}
+		}
+	}()
+}
+
+// CheckStores pings every connected store and records the outcome
+func CheckStores(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, storePingTimeout)
+	defer cancel()
+
+	if PostgresDB != nil {
+		SetStoreAvailability(StorePostgres, pingPostgres(ctx))
+	}
+	if MongoClient != nil {
+		SetStoreAvailability(StoreMongo, MongoClient.Ping(ctx, nil))
+	}
+}
+
+// pingPostgres pings the Supabase connection pool
+func pingPostgres(ctx context.Context) error {
+	sqlDB, err := PostgresDB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// RequiredStoresFromEnv reads REQUIRED_STORES: a comma-separated list of
+// stores the server must reach to start. Other stores may be down at startup;
+// the routes using them answer 503 until they are back.
+func RequiredStoresFromEnv() ([]string, error) {
+	return parseRequiredStores(os.Getenv("REQUIRED_STORES"))
+}
+
+// PriceStoresFromEnv reads PRICE_STORES: the comma-separated stores holding
+// daily candles, mongodb when unset. Candles are read from the first store
+// and written to all of them, so a second store can be filled during a
+// migration while the API keeps reading the first.
+func PriceStoresFromEnv() ([]string, error) {
+	return parsePriceStores(os.Getenv("PRICE_STORES"))
+}
+
+// parsePriceStores validates a PRICE_STORES value, dropping repeated stores
+func parsePriceStores(raw string) ([]string, error) {
+	listed, err := parseRequiredStores(raw)
+	if err != nil {
+		return nil, err
+	}
+	var stores []string
+	for _, name := range listed {
+		repeated := false
+		for _, store := range stores {
+			repeated = repeated || store == name
+		}
+		if !repeated {
+			stores = append(stores, name)
+		}
+	}
+	if len(stores) == 0 {
+		return []string{StoreMongo}, nil
+	}
+	return stores, nil
+}
+
+// parseRequiredStores validates a comma-separated list of store names
+func parseRequiredStores(raw string) ([]string, error) {
+	var required []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		known := false
+		for _, store := range Stores {
+			known = known || store == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown store %q (supported: %s)", name, strings.Join(Stores, ", "))
+		}
+		required = append(required, name)
+	}
+	return required, nil
+}
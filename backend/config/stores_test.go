@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseRequiredStores(t *testing.T) {
+	got, err := parseRequiredStores(" Postgres, ,mongodb")
+	if err != nil || !reflect.DeepEqual(got, []string{StorePostgres, StoreMongo}) {
+		t.Errorf("parseRequiredStores() = %v, %v; want [postgres mongodb]", got, err)
+	}
+	if got, err := parseRequiredStores(""); err != nil || len(got) != 0 {
+		t.Errorf("parseRequiredStores(\"\") = %v, %v; want none", got, err)
+	}
+	if _, err := parseRequiredStores("redis"); err == nil {
+		t.Error("parseRequiredStores(redis) expected an error")
+	}
+}
+
+func TestParsePriceStores(t *testing.T) {
+	if got, err := parsePriceStores(""); err != nil || !reflect.DeepEqual(got, []string{StoreMongo}) {
+		t.Errorf("parsePriceStores(\"\") = %v, %v; want [mongodb]", got, err)
+	}
+	if got, err := parsePriceStores("postgres, mongodb,postgres"); err != nil || !reflect.DeepEqual(got, []string{StorePostgres, StoreMongo}) {
+		t.Errorf("parsePriceStores() = %v, %v; want [postgres mongodb]", got, err)
+	}
+	if _, err := parsePriceStores("mongodb,redis"); err == nil {
+		t.Error("parsePriceStores(mongodb,redis) expected an error")
+	}
+}
+
+func TestStoreAvailability(t *testing.T) {
+	defer func() {
+		storeMu.Lock()
+		storeStatuses = map[string]*StoreStatus{}
+		storeMu.Unlock()
+	}()
+
+	if StoreAvailable(StoreMongo) {
+		t.Error("StoreAvailable() = true for a store never connected")
+	}
+
+	SetStoreAvailability(StorePostgres, nil)
+	SetStoreAvailability(StoreMongo, errors.New("server selection timeout"))
+	if !StoreAvailable(StorePostgres) || StoreAvailable(StoreMongo) {
+		t.Errorf("StoreAvailable() = %v, %v; want postgres up, mongodb down", StoreAvailable(StorePostgres), StoreAvailable(StoreMongo))
+	}
+	if down := UnavailableStores(Stores...); !reflect.DeepEqual(down, []string{StoreMongo}) {
+		t.Errorf("UnavailableStores() = %v; want [mongodb]", down)
+	}
+
+	since := StoreStatuses()[1].Since
+	SetStoreAvailability(StoreMongo, errors.New("connection refused"))
+	if status := StoreStatuses()[1]; status.Since != since || status.Error != "connection refused" {
+		t.Errorf("repeated outage = %+v; want the same since and the latest error", status)
+	}
+
+	SetStoreAvailability(StoreMongo, nil)
+	if down := UnavailableStores(Stores...); len(down) != 0 {
+		t.Errorf("UnavailableStores() after recovery = %v; want none", down)
+	}
+}
@@ -1,23 +1,33 @@
 package controllers
 
 import (
-	"log"
+	"errors"
+	"math"
 	"net/http"
-	"os"
 	"strconv"
+	"time"
 
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/datvt88/CPLS/backend/models"
 	"github.com/datvt88/CPLS/backend/services"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
 
-type AdminController struct{
-	userService *services.UserService
+type AdminController struct {
+	userService       services.UserStore
+	loginService      *services.LoginService
+	profileService    *services.ProfileService
+	preferenceService *services.AdminPreferenceService
 }
 
-func NewAdminController() *AdminController {
+func NewAdminController(userStore services.UserStore, loginService *services.LoginService, profileService *services.ProfileService, preferenceService *services.AdminPreferenceService) *AdminController {
 	return &AdminController{
-		userService: services.NewUserService(),
+		userService:       userStore,
+		loginService:      loginService,
+		profileService:    profileService,
+		preferenceService: preferenceService,
 	}
 }
 
@@ -33,7 +43,8 @@ func (ac *AdminController) ShowLoginPage(c *gin.Context) {
 
 	// Render login page (simple HTML for demonstration)
 	c.HTML(http.StatusOK, "login.html", gin.H{
-		"title": "Admin Login",
+		"title":      "Admin Login",
+		"csrf_token": middleware.CSRFToken(c),
 	})
 }
 
@@ -44,34 +55,74 @@ func (ac *AdminController) ProcessLogin(c *gin.Context) {
 	username := c.PostForm("username")
 	password := c.PostForm("password")
 
-	// Simple authentication (in production, use proper password hashing)
-	adminUser := os.Getenv("ADMIN_USERNAME")
-	adminPass := os.Getenv("ADMIN_PASSWORD")
-
-	if adminUser == "" {
-		adminUser = "admin"
-	}
-	if adminPass == "" {
-		adminPass = "admin123"
-	}
-
-	if username == adminUser && password == adminPass {
-		// Set user in session
-		session.Set("user", username)
-		if err := session.Save(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to save session",
+	adminUser, err := ac.loginService.Login(c.Request.Context(), services.LoginAttempt{
+		Identifier: username,
+		Password:   password,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Channel:    services.LoginChannelDashboard,
+	})
+	if err != nil {
+		var throttled *services.LoginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.HTML(http.StatusTooManyRequests, "login.html", gin.H{
+				"title":      "Admin Login",
+				"error":      "Too many failed attempts, please wait " + throttled.RetryAfter.Round(time.Second).String() + " and try again",
+				"csrf_token": middleware.CSRFToken(c),
+			})
+			return
+		case errors.Is(err, services.ErrAccountLocked):
+			c.HTML(http.StatusForbidden, "login.html", gin.H{
+				"title":      "Admin Login",
+				"error":      "This account is temporarily locked after too many failed attempts; try again later or ask another administrator to unlock it",
+				"csrf_token": middleware.CSRFToken(c),
+			})
+			return
+		case errors.Is(err, services.ErrInvalidCredentials):
+			c.HTML(http.StatusUnauthorized, "login.html", gin.H{
+				"title":      "Admin Login",
+				"error":      "Invalid username or password",
+				"csrf_token": middleware.CSRFToken(c),
 			})
 			return
 		}
 
-		c.Redirect(http.StatusFound, "/admin/dashboard")
-	} else {
-		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
-			"title": "Admin Login",
-			"error": "Invalid username or password",
+		logging.FromContext(c.Request.Context()).Error("ProcessLogin: authentication error", logging.FieldError, err)
+		c.HTML(http.StatusInternalServerError, "login.html", gin.H{
+			"title":      "Admin Login",
+			"error":      "Login is temporarily unavailable, please try again later",
+			"csrf_token": middleware.CSRFToken(c),
 		})
+		return
+	}
+
+	// Set user in session
+	displayName := adminUser.Email
+	if adminUser.Username != nil && *adminUser.Username != "" {
+		displayName = *adminUser.Username
+	}
+	session.Set("user", displayName)
+	session.Set("admin_id", adminUser.ID.String())
+	session.Set("role", adminUser.Role)
+	preferences, err := ac.preferenceService.GetPreferences(c.Request.Context(), adminUser.ID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("ProcessLogin: using default preferences", logging.FieldError, err)
+		preferences = models.DefaultAdminPreferences()
 	}
+	middleware.StoreAdminPreferences(session, preferences)
+	if _, err := middleware.RotateCSRFToken(session); err != nil {
+		logging.FromContext(c.Request.Context()).Error("ProcessLogin failed", logging.FieldError, err)
+	}
+	if err := session.Save(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to save session",
+		})
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/admin/dashboard")
 }
 
 // ShowDashboard renders the admin dashboard
@@ -80,8 +131,10 @@ func (ac *AdminController) ShowDashboard(c *gin.Context) {
 	user := session.Get("user")
 
 	c.HTML(http.StatusOK, "dashboard.html", gin.H{
-		"title": "Admin Dashboard",
-		"user":  user,
+		"title":      "Admin Dashboard",
+		"user":       user,
+		"csrf_token": middleware.CSRFToken(c),
+		"prefs":      middleware.AdminPreferences(c),
 	})
 }
 
@@ -105,18 +158,19 @@ func (ac *AdminController) ShowUsers(c *gin.Context) {
 	user := session.Get("user")
 
 	c.HTML(http.StatusOK, "users.html", gin.H{
-		"title": "User Management",
-		"user":  user,
+		"title":      "User Management",
+		"user":       user,
+		"csrf_token": middleware.CSRFToken(c),
+		"prefs":      middleware.AdminPreferences(c),
 	})
 }
 
 // GetAdminUsers returns all admin users (JSON API)
 func (ac *AdminController) GetAdminUsers(c *gin.Context) {
-	log.Println("=== AdminController.GetAdminUsers: API called ===")
 
 	// Get pagination parameters
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	pageSize := adminLimit(c, "page_size")
 
 	if page < 1 {
 		page = 1
@@ -130,9 +184,9 @@ func (ac *AdminController) GetAdminUsers(c *gin.Context) {
 
 	// If pagination is requested (page > 1 or page_size specified)
 	if page > 1 || c.Query("page_size") != "" {
-		users, count, paginateErr := ac.userService.GetAdminUsersWithPagination(page, pageSize)
+		users, count, paginateErr := ac.userService.GetAdminUsersWithPagination(c.Request.Context(), page, pageSize)
 		if paginateErr != nil {
-			log.Printf("❌ GetAdminUsers: Error fetching paginated admin users: %v", paginateErr)
+			logging.FromContext(c.Request.Context()).Error("GetAdminUsers: error fetching paginated admin users", logging.FieldError, paginateErr)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Failed to fetch admin users",
 				"details": paginateErr.Error(),
@@ -146,9 +200,9 @@ func (ac *AdminController) GetAdminUsers(c *gin.Context) {
 		total = count
 	} else {
 		// Get all users without pagination
-		users, allErr := ac.userService.GetAdminUsers()
+		users, allErr := ac.userService.GetAdminUsers(c.Request.Context())
 		if allErr != nil {
-			log.Printf("❌ GetAdminUsers: Error fetching admin users: %v", allErr)
+			logging.FromContext(c.Request.Context()).Error("GetAdminUsers: error fetching admin users", logging.FieldError, allErr)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Failed to fetch admin users",
 				"details": allErr.Error(),
@@ -162,7 +216,7 @@ func (ac *AdminController) GetAdminUsers(c *gin.Context) {
 		total = int64(len(users))
 	}
 
-	log.Printf("✓ GetAdminUsers: Returning %d admin users (total: %d)", len(adminUsers), total)
+	logging.FromContext(c.Request.Context()).Debug("GetAdminUsers", "returned", len(adminUsers), "total", total)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -175,11 +229,10 @@ func (ac *AdminController) GetAdminUsers(c *gin.Context) {
 
 // GetProfiles returns all user profiles (JSON API)
 func (ac *AdminController) GetProfiles(c *gin.Context) {
-	log.Println("=== AdminController.GetProfiles: API called ===")
 
 	// Get pagination parameters
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	pageSize := adminLimit(c, "page_size")
 
 	if page < 1 {
 		page = 1
@@ -193,9 +246,9 @@ func (ac *AdminController) GetProfiles(c *gin.Context) {
 
 	// If pagination is requested (page > 1 or page_size specified)
 	if page > 1 || c.Query("page_size") != "" {
-		profs, count, paginateErr := ac.userService.GetProfilesWithPagination(page, pageSize)
+		profs, count, paginateErr := ac.userService.GetProfilesWithPagination(c.Request.Context(), page, pageSize)
 		if paginateErr != nil {
-			log.Printf("❌ GetProfiles: Error fetching paginated profiles: %v", paginateErr)
+			logging.FromContext(c.Request.Context()).Error("GetProfiles: error fetching paginated profiles", logging.FieldError, paginateErr)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Failed to fetch profiles",
 				"details": paginateErr.Error(),
@@ -209,9 +262,9 @@ func (ac *AdminController) GetProfiles(c *gin.Context) {
 		total = count
 	} else {
 		// Get all profiles without pagination
-		profs, allErr := ac.userService.GetProfiles()
+		profs, allErr := ac.userService.GetProfiles(c.Request.Context())
 		if allErr != nil {
-			log.Printf("❌ GetProfiles: Error fetching profiles: %v", allErr)
+			logging.FromContext(c.Request.Context()).Error("GetProfiles: error fetching profiles", logging.FieldError, allErr)
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Failed to fetch profiles",
 				"details": allErr.Error(),
@@ -225,7 +278,7 @@ func (ac *AdminController) GetProfiles(c *gin.Context) {
 		total = int64(len(profs))
 	}
 
-	log.Printf("✓ GetProfiles: Returning %d profiles (total: %d)", len(profiles), total)
+	logging.FromContext(c.Request.Context()).Debug("GetProfiles", "returned", len(profiles), "total", total)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -235,3 +288,132 @@ func (ac *AdminController) GetProfiles(c *gin.Context) {
 		"page_size": pageSize,
 	})
 }
+
+// UpdateProfile changes a member's membership tier, membership expiry or
+// active state (JSON API)
+func (ac *AdminController) UpdateProfile(c *gin.Context) {
+	var update services.ProfileUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	actor, _ := sessions.Default(c).Get("user").(string)
+	profile, err := ac.profileService.UpdateProfile(c.Request.Context(), c.Param("id"), update, actor, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProfileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		case errors.Is(err, services.ErrInvalidProfileUpdate):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid profile update",
+				"details": err.Error(),
+			})
+		default:
+			logging.FromContext(c.Request.Context()).Error("UpdateProfile failed", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update profile",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Profile updated", "email", profile.Email, "actor", actor)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    profile,
+	})
+}
+
+// LookupProfiles returns the profiles with the listed IDs in one query,
+// plus the IDs that match no profile (JSON API)
+// Body: {"ids": ["<uuid>", ...]} (at most services.MaxProfileLookup)
+func (ac *AdminController) LookupProfiles(c *gin.Context) {
+	var req struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	profiles, missing, err := ac.profileService.LookupProfiles(c.Request.Context(), req.IDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidProfileLookup) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid profile lookup",
+				"details": err.Error(),
+			})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("LookupProfiles failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to look up profiles",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    profiles,
+		"total":   len(profiles),
+		"missing": missing,
+	})
+}
+
+// UnlockAdminUser clears an admin's lockout after too many failed logins (JSON API)
+func (ac *AdminController) UnlockAdminUser(c *gin.Context) {
+	actor, _ := sessions.Default(c).Get("user").(string)
+	adminUser, err := ac.loginService.Unlock(c.Request.Context(), c.Param("id"), actor, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrAdminUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Admin user not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("UnlockAdminUser failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to unlock admin user",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Admin user unlocked", "email", adminUser.Email, "actor", actor)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    adminUser,
+	})
+}
+
+// GetLoginHistory returns an admin's most recent successful logins (JSON API)
+// Query params: limit (default/max 200)
+func (ac *AdminController) GetLoginHistory(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	history, err := ac.userService.GetLoginHistory(c.Request.Context(), c.Param("id"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrAdminUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Admin user not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("GetLoginHistory failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch login history",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    history,
+		"total":   len(history),
+	})
+}
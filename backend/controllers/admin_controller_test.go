@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services/servicestest"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// adminRouter serves the admin user list of a controller reading from users
+func adminRouter(users *servicestest.UserStore) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("cpls_session", cookie.NewStore([]byte("test-secret"))))
+	ac := NewAdminController(users, nil, nil, nil)
+	router.GET("/admin/api/users", ac.GetAdminUsers)
+	return router
+}
+
+func TestGetAdminUsersPaginates(t *testing.T) {
+	users := &servicestest.UserStore{}
+	for _, email := range []string{"a@cpls.local", "b@cpls.local", "c@cpls.local"} {
+		users.AdminUsers = append(users.AdminUsers, models.AdminUser{ID: uuid.New(), Email: email, Role: "admin", Active: true})
+	}
+
+	rec := httptest.NewRecorder()
+	adminRouter(users).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/api/users?page=2&page_size=2", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d; want 200: %s", rec.Code, rec.Body)
+	}
+	var body struct {
+		Data  []models.AdminUser `json:"data"`
+		Total int64              `json:"total"`
+		Page  int                `json:"page"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if body.Total != 3 || body.Page != 2 || len(body.Data) != 1 || body.Data[0].Email != "c@cpls.local" {
+		t.Errorf("response = %+v; want the third of 3 admins on page 2", body)
+	}
+}
+
+func TestGetAdminUsersReportsStoreErrors(t *testing.T) {
+	users := &servicestest.UserStore{Err: errors.New("connection refused")}
+
+	rec := httptest.NewRecorder()
+	adminRouter(users).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/api/users", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d; want 500", rec.Code)
+	}
+}
@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// AdminPreferenceController manages each admin's display preferences
+type AdminPreferenceController struct {
+	preferenceService *services.AdminPreferenceService
+}
+
+// NewAdminPreferenceController creates a new admin preference controller
+func NewAdminPreferenceController(preferenceService *services.AdminPreferenceService) *AdminPreferenceController {
+	return &AdminPreferenceController{
+		preferenceService: preferenceService,
+	}
+}
+
+// GetPreferences returns the signed-in admin's preferences (JSON API)
+func (pc *AdminPreferenceController) GetPreferences(c *gin.Context) {
+	adminUserID, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+
+	preferences, err := pc.preferenceService.GetPreferences(c.Request.Context(), adminUserID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetPreferences failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch preferences",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      preferences,
+		"languages": models.AdminLanguages,
+	})
+}
+
+// UpdatePreferences saves the signed-in admin's preferences and applies
+// them to the session (JSON API)
+func (pc *AdminPreferenceController) UpdatePreferences(c *gin.Context) {
+	adminUserID, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+
+	var preferences models.AdminPreferences
+	if err := c.ShouldBindJSON(&preferences); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	preferences.AdminUserID = adminUserID
+
+	if err := pc.preferenceService.SavePreferences(c.Request.Context(), &preferences); err != nil {
+		if errors.Is(err, services.ErrInvalidAdminPreferences) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid preferences",
+				"details": err.Error(),
+			})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("UpdatePreferences failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save preferences",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	session := sessions.Default(c)
+	middleware.StoreAdminPreferences(session, preferences)
+	if err := session.Save(); err != nil {
+		logging.FromContext(c.Request.Context()).Error("UpdatePreferences: failed to save session", logging.FieldError, err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    preferences,
+	})
+}
+
+// adminLimit reads a page size or limit query parameter, defaulting to the
+// signed-in admin's preferred page size
+func adminLimit(c *gin.Context, param string) int {
+	if limit, err := strconv.Atoi(c.Query(param)); err == nil && limit > 0 {
+		return limit
+	}
+	return middleware.AdminPreferences(c).PageSize
+}
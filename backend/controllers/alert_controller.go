@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// AlertController handles operational alert rule management
+type AlertController struct {
+	alertService *services.AlertService
+}
+
+// NewAlertController creates a new alert controller
+func NewAlertController(alertService *services.AlertService) *AlertController {
+	return &AlertController{
+		alertService: alertService,
+	}
+}
+
+// alertRuleRequest is the JSON body accepted when creating or updating a rule
+type alertRuleRequest struct {
+	Name            string  `json:"name"`
+	Type            string  `json:"type"`
+	Threshold       float64 `json:"threshold"`
+	Channels        string  `json:"channels"`
+	Enabled         *bool   `json:"enabled"`
+	CooldownMinutes *int    `json:"cooldown_minutes"`
+}
+
+// toModel converts the request into an AlertRule, applying defaults
+func (r alertRuleRequest) toModel() models.AlertRule {
+	rule := models.AlertRule{
+		Name:            r.Name,
+		Type:            r.Type,
+		Threshold:       r.Threshold,
+		Channels:        r.Channels,
+		Enabled:         true,
+		CooldownMinutes: 60,
+	}
+	if r.Enabled != nil {
+		rule.Enabled = *r.Enabled
+	}
+	if r.CooldownMinutes != nil {
+		rule.CooldownMinutes = *r.CooldownMinutes
+	}
+	return rule
+}
+
+// ShowAlertsPage renders the alert rules management page
+func (ac *AlertController) ShowAlertsPage(c *gin.Context) {
+	session := sessions.Default(c)
+	user := session.Get("user")
+
+	c.HTML(http.StatusOK, "alerts.html", gin.H{
+		"title":      "Alert Rules",
+		"user":       user,
+		"csrf_token": middleware.CSRFToken(c),
+		"prefs":      middleware.AdminPreferences(c),
+	})
+}
+
+// ListRules returns all alert rules plus the supported types and channels (JSON API)
+func (ac *AlertController) ListRules(c *gin.Context) {
+	rules, err := ac.alertService.ListRules(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListRules failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch alert rules",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     rules,
+		"total":    len(rules),
+		"types":    models.AlertRuleTypes,
+		"channels": ac.alertService.Channels(),
+	})
+}
+
+// CreateRule creates a new alert rule (JSON API)
+func (ac *AlertController) CreateRule(c *gin.Context) {
+	var req alertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rule := req.toModel()
+	if err := rule.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid alert rule",
+			"details": err.Error(),
+		})
+		return
+	}
+	if user, ok := sessions.Default(c).Get("user").(string); ok {
+		rule.CreatedBy = &user
+	}
+
+	if err := ac.alertService.CreateRule(&rule); err != nil {
+		logging.FromContext(c.Request.Context()).Error("CreateRule failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create alert rule",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+// UpdateRule updates an existing alert rule (JSON API)
+func (ac *AlertController) UpdateRule(c *gin.Context) {
+	var req alertRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	changes := req.toModel()
+	if err := changes.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid alert rule",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rule, err := ac.alertService.UpdateRule(c.Param("id"), changes)
+	if err != nil {
+		if errors.Is(err, services.ErrAlertRuleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("UpdateRule failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update alert rule",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    rule,
+	})
+}
+
+// DeleteRule deletes an alert rule (JSON API)
+func (ac *AlertController) DeleteRule(c *gin.Context) {
+	if err := ac.alertService.DeleteRule(c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrAlertRuleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Alert rule not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("DeleteRule failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete alert rule",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// GetMetrics returns the current data-operation metrics with each rule's
+// evaluation, without sending notifications (JSON API)
+func (ac *AlertController) GetMetrics(c *gin.Context) {
+	metrics, err := ac.alertService.CollectMetrics(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetMetrics failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to collect metrics",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	rules, err := ac.alertService.ListRules(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch alert rules",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	evaluations := make([]gin.H, 0, len(rules))
+	for _, rule := range rules {
+		firing, message := rule.Evaluate(metrics, now)
+		evaluations = append(evaluations, gin.H{
+			"rule_id": rule.ID,
+			"name":    rule.Name,
+			"firing":  firing,
+			"message": message,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"last_successful_crawl_at": metrics.LastSuccessfulCrawlAt,
+			"latest_run":               metrics.LatestRun,
+			"freshest_candle_date":     metrics.FreshestCandleDate,
+			"evaluations":              evaluations,
+		},
+	})
+}
@@ -0,0 +1,162 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyController handles API key management for admins
+type APIKeyController struct {
+	apiKeyService *services.APIKeyService
+}
+
+// NewAPIKeyController creates a new API key controller
+func NewAPIKeyController(apiKeyService *services.APIKeyService) *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// ListKeys returns all API keys without their secrets (JSON API)
+func (kc *APIKeyController) ListKeys(c *gin.Context) {
+	keys, err := kc.apiKeyService.ListKeys(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListKeys failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch API keys",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    keys,
+		"total":   len(keys),
+		"scopes":  models.APIKeyScopes,
+	})
+}
+
+// CreateKey creates a new scoped API key and returns its plaintext once (JSON API)
+func (kc *APIKeyController) CreateKey(c *gin.Context) {
+	var req struct {
+		Name           string   `json:"name" binding:"required"`
+		Scopes         []string `json:"scopes" binding:"required"`
+		ResponseFormat string   `json:"response_format"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": "name and scopes are required",
+		})
+		return
+	}
+	_, err := models.NormalizeScopes(req.Scopes)
+	if err == nil {
+		_, err = models.NormalizeResponseFormat(req.ResponseFormat)
+	}
+	if err != nil || strings.TrimSpace(req.Name) == "" {
+		details := "name is required"
+		if err != nil {
+			details = err.Error()
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid API key",
+			"details": details,
+		})
+		return
+	}
+
+	createdBy, _ := sessions.Default(c).Get("user").(string)
+	key, plaintext, err := kc.apiKeyService.CreateKey(req.Name, req.Scopes, req.ResponseFormat, createdBy)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("CreateKey failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create API key",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("API key created", "name", key.Name, "prefix", key.Prefix, "actor", createdBy, "scopes", key.Scopes)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    key,
+		"key":     plaintext,
+		"note":    "Store this key now; it cannot be shown again",
+	})
+}
+
+// UpdateResponseFormat pins the response format (naming and envelope) served
+// to a key, so a consumer can stay on its current format while the default
+// changes (JSON API)
+func (kc *APIKeyController) UpdateResponseFormat(c *gin.Context) {
+	var req struct {
+		ResponseFormat string `json:"response_format"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if _, err := models.NormalizeResponseFormat(req.ResponseFormat); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid response format",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	key, err := kc.apiKeyService.SetResponseFormat(c.Param("id"), req.ResponseFormat)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("UpdateResponseFormat failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update API key",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    key,
+	})
+}
+
+// RevokeKey revokes an API key (JSON API)
+func (kc *APIKeyController) RevokeKey(c *gin.Context) {
+	key, err := kc.apiKeyService.RevokeKey(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("RevokeKey failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to revoke API key",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("API key revoked", "name", key.Name, "prefix", key.Prefix)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    key,
+	})
+}
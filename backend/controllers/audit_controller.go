@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultAuditDays is the window of the security tab's charts
+	defaultAuditDays = 30
+	// maxAuditDays caps the window of one aggregate query
+	maxAuditDays = 365
+)
+
+// AuditController exposes the audit log to admins
+type AuditController struct {
+	auditService *services.AuditService
+}
+
+// NewAuditController creates a new audit controller
+func NewAuditController(auditService *services.AuditService) *AuditController {
+	return &AuditController{
+		auditService: auditService,
+	}
+}
+
+// ListAuditLogs returns audit log entries, newest first (JSON API)
+// Query params: action, actor, outcome, since (RFC 3339), limit (default: the
+// admin's page size, max 500)
+func (ac *AuditController) ListAuditLogs(c *gin.Context) {
+	filter := services.AuditFilter{
+		Action:  c.Query("action"),
+		Actor:   c.Query("actor"),
+		Outcome: c.Query("outcome"),
+		Limit:   adminLimit(c, "limit"),
+	}
+	if since := c.Query("since"); since != "" {
+		parsed, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid since",
+				"details": "expected an RFC 3339 timestamp such as 2024-01-15T00:00:00Z",
+			})
+			return
+		}
+		filter.Since = &parsed
+	}
+
+	logs, err := ac.auditService.List(c.Request.Context(), filter)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListAuditLogs failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch audit logs",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    logs,
+		"total":   len(logs),
+	})
+}
+
+// auditSince returns the start of the window selected by the days query
+// param (default 30, max 365), answering 400 when it is invalid
+func auditSince(c *gin.Context) (time.Time, bool) {
+	days := defaultAuditDays
+	if raw := c.Query("days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxAuditDays {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid days",
+				"details": "expected a number of days between 1 and 365",
+			})
+			return time.Time{}, false
+		}
+		days = parsed
+	}
+	return time.Now().UTC().AddDate(0, 0, -days), true
+}
+
+// GetAuditActivity returns audited actions per actor and day, oldest day
+// first, for the security tab's activity chart (JSON API)
+// Query params: days (default 30, max 365)
+func (ac *AuditController) GetAuditActivity(c *gin.Context) {
+	since, ok := auditSince(c)
+	if !ok {
+		return
+	}
+
+	activity, err := ac.auditService.ActivityByActor(c.Request.Context(), since)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetAuditActivity failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to aggregate audit activity",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    activity,
+		"total":   len(activity),
+		"since":   since,
+	})
+}
+
+// GetModifiedEntities returns the entities changed most often (JSON API)
+// Query params: days (default 30, max 365), limit (default 10, max 500)
+func (ac *AuditController) GetModifiedEntities(c *gin.Context) {
+	since, ok := auditSince(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	entities, err := ac.auditService.MostModifiedEntities(c.Request.Context(), since, limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetModifiedEntities failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to aggregate modified entities",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    entities,
+		"total":   len(entities),
+		"since":   since,
+	})
+}
+
+// ListSensitiveActions returns the newest sensitive actions: unlocks,
+// profile edits, data exports and erasures, credential changes and logins
+// that did not succeed (JSON API)
+// Query params: days (default 30, max 365), limit (default/max 500)
+func (ac *AuditController) ListSensitiveActions(c *gin.Context) {
+	since, ok := auditSince(c)
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	logs, err := ac.auditService.RecentSensitive(c.Request.Context(), since, limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListSensitiveActions failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch sensitive actions",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    logs,
+		"total":   len(logs),
+	})
+}
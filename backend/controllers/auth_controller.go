@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// AuthController handles JWT issuance for programmatic API access
+type AuthController struct {
+	authService  *services.AuthService
+	loginService *services.LoginService
+	tokenService *services.TokenService
+}
+
+// NewAuthController creates a new auth controller
+func NewAuthController(tokenService *services.TokenService, loginService *services.LoginService) *AuthController {
+	return &AuthController{
+		authService:  services.NewAuthService(),
+		loginService: loginService,
+		tokenService: tokenService,
+	}
+}
+
+// IssueToken exchanges admin credentials for an access/refresh token pair
+// @Summary Issue API tokens
+// @Description Authenticates an admin and returns a JWT access token and refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Token pair"
+// @Failure 401 {object} map[string]interface{} "Invalid credentials"
+// @Router /api/auth/token [post]
+func (ac *AuthController) IssueToken(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" form:"username" binding:"required"`
+		Password string `json:"password" form:"password" binding:"required"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "username and password are required",
+		})
+		return
+	}
+
+	adminUser, err := ac.loginService.Login(c.Request.Context(), services.LoginAttempt{
+		Identifier: req.Username,
+		Password:   req.Password,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		Channel:    services.LoginChannelToken,
+	})
+	if err != nil {
+		var throttled *services.LoginThrottledError
+		if errors.As(err, &throttled) {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"status":  "error",
+				"message": throttled.Error(),
+			})
+			return
+		}
+		if errors.Is(err, services.ErrAccountLocked) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  "error",
+				"message": "Account is temporarily locked after too many failed attempts",
+			})
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Invalid username or password",
+			})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("IssueToken: authentication error", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to authenticate",
+		})
+		return
+	}
+
+	ac.respondWithTokens(c, adminUser)
+}
+
+// RefreshToken exchanges a refresh token for a new token pair
+// @Summary Refresh API tokens
+// @Description Returns a new access token and refresh token for a valid refresh token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Token pair"
+// @Failure 401 {object} map[string]interface{} "Invalid or expired refresh token"
+// @Router /api/auth/refresh [post]
+func (ac *AuthController) RefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" form:"refresh_token" binding:"required"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "refresh_token is required",
+		})
+		return
+	}
+
+	claims, err := ac.tokenService.Verify(req.RefreshToken, services.TokenTypeRefresh)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Invalid or expired refresh token",
+		})
+		return
+	}
+
+	// Re-check the admin so deactivated accounts cannot keep refreshing
+	adminUser, err := ac.authService.GetActiveAdmin(claims.Subject)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  "error",
+				"message": "Account is no longer active",
+			})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("RefreshToken failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to refresh token",
+		})
+		return
+	}
+
+	ac.respondWithTokens(c, adminUser)
+}
+
+// respondWithTokens issues a token pair and writes it as the response
+func (ac *AuthController) respondWithTokens(c *gin.Context, adminUser *models.AdminUser) {
+	pair, err := ac.tokenService.IssueTokens(adminUser)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Failed to issue tokens", "email", adminUser.Email, logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to issue tokens",
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   pair,
+	})
+}
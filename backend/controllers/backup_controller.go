@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// BackupController handles nightly backup status and manual runs
+type BackupController struct {
+	backupService *services.BackupService
+}
+
+// NewBackupController creates a new backup controller
+func NewBackupController(backupService *services.BackupService) *BackupController {
+	return &BackupController{
+		backupService: backupService,
+	}
+}
+
+// respondBackupError maps backup service errors to responses
+func respondBackupError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrBackupNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Backups are disabled",
+			"details": err.Error(),
+		})
+	case errors.Is(err, services.ErrBackupRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": "A backup is already running"})
+	default:
+		logging.FromContext(c.Request.Context()).Error(action+" failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to " + action,
+			"details": err.Error(),
+		})
+	}
+}
+
+// ListBackups returns the stored backup dates, the schedule and the outcome
+// of the latest run on this instance (JSON API)
+func (bc *BackupController) ListBackups(c *gin.Context) {
+	dates, err := bc.backupService.ListBackups(c.Request.Context())
+	if err != nil {
+		respondBackupError(c, "list backups", err)
+		return
+	}
+
+	cfg := config.Runtime()
+	c.JSON(http.StatusOK, gin.H{
+		"success":        true,
+		"data":           dates,
+		"total":          len(dates),
+		"running":        bc.backupService.Running(),
+		"last_run":       bc.backupService.LastReport(),
+		"backup_time":    cfg.BackupTime,
+		"retention_days": cfg.BackupRetentionDays,
+	})
+}
+
+// GetBackup returns the manifest of one backup (JSON API)
+func (bc *BackupController) GetBackup(c *gin.Context) {
+	date := c.Param("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	manifest, err := bc.backupService.GetManifest(c.Request.Context(), date)
+	if err != nil {
+		respondBackupError(c, "fetch backup manifest", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    manifest,
+	})
+}
+
+// RunBackup starts a backup now; the result is reported like a nightly run (JSON API)
+func (bc *BackupController) RunBackup(c *gin.Context) {
+	if err := bc.backupService.Trigger(); err != nil {
+		respondBackupError(c, "start backup", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"message": "Backup started",
+	})
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// CanaryController reports how the implementations of canary routes compare
+type CanaryController struct {
+	metrics *services.CanaryMetrics
+}
+
+// NewCanaryController creates a new canary controller
+func NewCanaryController(metrics *services.CanaryMetrics) *CanaryController {
+	return &CanaryController{
+		metrics: metrics,
+	}
+}
+
+// GetStats returns the latency and error stats of every canary route's
+// control and candidate, with the current split (JSON API)
+func (cc *CanaryController) GetStats(c *gin.Context) {
+	stats, since := cc.metrics.Snapshot()
+	cfg := config.Runtime()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"data":     stats,
+		"since":    since,
+		"percent":  cfg.CanaryPercent,
+		"subjects": cfg.CanarySubjects,
+	})
+}
+
+// ResetStats discards the recorded stats, e.g. after changing a candidate (JSON API)
+func (cc *CanaryController) ResetStats(c *gin.Context) {
+	cc.metrics.Reset()
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// respondComposite writes the response of a composite endpoint: the data
+// with warnings for optional parts that were left out, or an error when a
+// required part timed out (504) or failed (errorStatus, e.g. 404 for an
+// unknown stock)
+func respondComposite(c *gin.Context, report services.BudgetReport, message string, data gin.H) {
+	if report.Err != nil {
+		status := errorStatus(report.Err)
+		if report.TimedOut() {
+			status = http.StatusGatewayTimeout
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": message,
+			"error":   report.Err.Error(),
+			"parts":   report.Parts,
+		})
+		return
+	}
+
+	response := gin.H{
+		"status":  "success",
+		"data":    data,
+		"partial": report.Partial(),
+		"parts":   report.Parts,
+	}
+	if report.Partial() {
+		response["warnings"] = report.Warnings
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// CrawlErrorController handles the crawl error view and its bulk actions
+type CrawlErrorController struct {
+	crawlErrorService *services.CrawlErrorService
+}
+
+// NewCrawlErrorController creates a new crawl error controller
+func NewCrawlErrorController(crawlErrorService *services.CrawlErrorService) *CrawlErrorController {
+	return &CrawlErrorController{
+		crawlErrorService: crawlErrorService,
+	}
+}
+
+// ShowCrawlErrorsPage renders the crawl errors page
+func (cc *CrawlErrorController) ShowCrawlErrorsPage(c *gin.Context) {
+	session := sessions.Default(c)
+	user := session.Get("user")
+
+	c.HTML(http.StatusOK, "crawl_errors.html", gin.H{
+		"title":      "Crawl Errors",
+		"user":       user,
+		"csrf_token": middleware.CSRFToken(c),
+		"prefs":      middleware.AdminPreferences(c),
+	})
+}
+
+// ListErrors returns the symbol errors of a crawl run (JSON API)
+// Query params: run_id (default: latest full run), include_acknowledged=true
+func (cc *CrawlErrorController) ListErrors(c *gin.Context) {
+	list, err := cc.crawlErrorService.ListErrors(c.Request.Context(), c.Query("run_id"), c.Query("include_acknowledged") == "true")
+	if err != nil {
+		if errors.Is(err, services.ErrCrawlRunNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Crawl run not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("ListErrors failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch crawl errors",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+		"total":   len(list.Errors),
+		"actions": services.CrawlErrorActions,
+	})
+}
+
+// BulkAction retries, blacklists or acknowledges many symbols at once and
+// reports the outcome per symbol (JSON API)
+func (cc *CrawlErrorController) BulkAction(c *gin.Context) {
+	var req services.BulkCrawlErrorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	actor, _ := sessions.Default(c).Get("user").(string)
+	result, err := cc.crawlErrorService.BulkAction(c.Request.Context(), req, actor)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidBulkAction):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid bulk action",
+				"details": err.Error(),
+			})
+		case errors.Is(err, services.ErrCrawlRunNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Crawl run not found"})
+		case errors.Is(err, services.ErrCrawlInProgress):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "A crawl is already in progress, retry once it finished",
+				"details": err.Error(),
+			})
+		default:
+			logging.FromContext(c.Request.Context()).Error("BulkAction failed", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to apply bulk action",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+		"total":   len(result.Results),
+	})
+}
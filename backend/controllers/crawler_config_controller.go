@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// CrawlerConfigController serves the crawler settings page of the dashboard
+type CrawlerConfigController struct {
+	crawlerConfigService *services.CrawlerConfigService
+}
+
+// NewCrawlerConfigController creates a new crawler config controller
+func NewCrawlerConfigController(crawlerConfigService *services.CrawlerConfigService) *CrawlerConfigController {
+	return &CrawlerConfigController{
+		crawlerConfigService: crawlerConfigService,
+	}
+}
+
+// GetConfig returns the crawler configuration with the data sources and
+// exchanges it may use (JSON API)
+func (cc *CrawlerConfigController) GetConfig(c *gin.Context) {
+	view, err := cc.crawlerConfigService.Get(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetCrawlerConfig failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch crawler configuration",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    view,
+	})
+}
+
+// UpdateConfig stores the changed crawler settings and reloads the
+// configuration; the crawler picks them up without a restart (JSON API)
+func (cc *CrawlerConfigController) UpdateConfig(c *gin.Context) {
+	var update services.CrawlerConfigUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updatedBy, _ := sessions.Default(c).Get("user").(string)
+	view, err := cc.crawlerConfigService.Update(c.Request.Context(), update, updatedBy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to update crawler configuration",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Crawler configuration updated and reloaded", "actor", updatedBy)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    view,
+	})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/datvt88/CPLS/backend/services"
@@ -13,9 +14,9 @@ type CrawlerController struct {
 }
 
 // NewCrawlerController creates a new crawler controller
-func NewCrawlerController() *CrawlerController {
+func NewCrawlerController(crawlerService *services.CrawlerService) *CrawlerController {
 	return &CrawlerController{
-		crawlerService: services.NewCrawlerService(),
+		crawlerService: crawlerService,
 	}
 }
 
@@ -26,10 +27,28 @@ func NewCrawlerController() *CrawlerController {
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{} "Crawling started successfully"
+// @Failure 409 {object} map[string]interface{} "A crawl is already queued or running"
 // @Router /api/crawler/start [post]
 func (cc *CrawlerController) TriggerCrawl(c *gin.Context) {
-	// Start crawling in background (non-blocking)
+	// Queue the crawl; a job worker of some instance runs it (non-blocking)
 	err := cc.crawlerService.StartCrawling()
+	if errors.Is(err, services.ErrCrawlerShuttingDown) {
+		// Another instance can take the request
+		c.Header("Retry-After", "5")
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "error",
+			"message": "Instance is shutting down, retry shortly",
+		})
+		return
+	}
+	if errors.Is(err, services.ErrCrawlInProgress) {
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  "error",
+			"message": "A crawl is already queued or in progress; check the status endpoint",
+			"error":   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
@@ -56,7 +75,7 @@ func (cc *CrawlerController) TriggerCrawl(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "Status information"
 // @Router /api/crawler/status [get]
 func (cc *CrawlerController) GetStatus(c *gin.Context) {
-	status, err := cc.crawlerService.GetCrawlStatus()
+	status, err := cc.crawlerService.GetCrawlStatus(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  "error",
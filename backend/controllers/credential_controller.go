@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// CredentialController handles data provider credentials and health tests
+type CredentialController struct {
+	credentialService *services.CredentialService
+}
+
+// NewCredentialController creates a new credential controller
+func NewCredentialController(credentialService *services.CredentialService) *CredentialController {
+	return &CredentialController{
+		credentialService: credentialService,
+	}
+}
+
+// ListProviders returns the registered data providers with their stored
+// credentials; values are never returned, only a hint (JSON API)
+func (cc *CredentialController) ListProviders(c *gin.Context) {
+	providers, err := cc.credentialService.ListProviders(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListProviders failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch provider credentials",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"data":       providers,
+		"total":      len(providers),
+		"configured": cc.credentialService.Configured(),
+	})
+}
+
+// SetCredential stores or rotates a provider credential (JSON API)
+// Body: {"value": "..."}
+func (cc *CredentialController) SetCredential(c *gin.Context) {
+	var req struct {
+		Value string `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	actor, _ := sessions.Default(c).Get("user").(string)
+	credential, err := cc.credentialService.SetCredential(c.Request.Context(),
+		c.Param("provider"), c.Param("name"), req.Value, actor, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCredentialsKeyMissing):
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"error":   "Provider credentials are disabled",
+				"details": err.Error(),
+			})
+		case errors.Is(err, services.ErrUnknownProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Data provider not found"})
+		case errors.Is(err, services.ErrInvalidCredential):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid provider credential",
+				"details": err.Error(),
+			})
+		default:
+			logging.FromContext(c.Request.Context()).Error("SetCredential failed", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to store provider credential",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Provider credential updated", "provider", credential.Provider, "name", credential.Name, "actor", actor)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    credential,
+	})
+}
+
+// DeleteCredential removes a provider credential (JSON API)
+func (cc *CredentialController) DeleteCredential(c *gin.Context) {
+	actor, _ := sessions.Default(c).Get("user").(string)
+	err := cc.credentialService.DeleteCredential(c.Request.Context(),
+		c.Param("provider"), c.Param("name"), actor, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrCredentialNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Provider credential not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("DeleteCredential failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete provider credential",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// TestProvider checks a data provider's connectivity with its current
+// credentials. A failing provider is still a successful request; see "ok" (JSON API)
+func (cc *CredentialController) TestProvider(c *gin.Context) {
+	result, err := cc.credentialService.TestProvider(c.Request.Context(), c.Param("provider"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownProvider):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Data provider not found"})
+		case errors.Is(err, services.ErrProviderNotTestable):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Data provider cannot be tested",
+				"details": err.Error(),
+			})
+		default:
+			logging.FromContext(c.Request.Context()).Error("TestProvider failed", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to test data provider",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
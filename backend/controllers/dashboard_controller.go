@@ -0,0 +1,285 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DashboardController manages each admin's dashboard widgets
+type DashboardController struct {
+	dashboardService *services.DashboardService
+}
+
+// NewDashboardController creates a new dashboard controller
+func NewDashboardController(dashboardService *services.DashboardService) *DashboardController {
+	return &DashboardController{
+		dashboardService: dashboardService,
+	}
+}
+
+// widgetRequest is the JSON body accepted when creating or updating a widget
+type widgetRequest struct {
+	Type           string          `json:"type"`
+	Title          string          `json:"title"`
+	Position       int             `json:"position"`
+	Width          int             `json:"width"`
+	RefreshSeconds int             `json:"refresh_seconds"`
+	Settings       json.RawMessage `json:"settings"` // JSON object, e.g. {"code":"HPG"}
+}
+
+// toModel converts the request into a DashboardWidget, applying defaults
+func (r widgetRequest) toModel() models.DashboardWidget {
+	widget := models.DashboardWidget{
+		Type:           r.Type,
+		Title:          r.Title,
+		Position:       r.Position,
+		Width:          r.Width,
+		RefreshSeconds: r.RefreshSeconds,
+		Settings:       string(r.Settings),
+	}
+	if widget.Title == "" {
+		widget.Title = models.WidgetTypes[widget.Type].Description
+	}
+	if widget.Width == 0 {
+		widget.Width = 1
+	}
+	if widget.RefreshSeconds == 0 {
+		widget.RefreshSeconds = models.DefaultWidgetRefreshSeconds
+	}
+	if len(r.Settings) == 0 || string(r.Settings) == "null" {
+		widget.Settings = "{}"
+	}
+	return widget
+}
+
+// ListWidgetTypes returns the widgets an admin can add (JSON API)
+func (dc *DashboardController) ListWidgetTypes(c *gin.Context) {
+	types := make([]models.WidgetType, 0, len(models.WidgetTypes))
+	for _, name := range models.WidgetTypeNames() {
+		types = append(types, models.WidgetTypes[name])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    types,
+		"total":   len(types),
+		"columns": models.DashboardColumns,
+	})
+}
+
+// ListWidgets returns the signed-in admin's widgets in display order (JSON API)
+func (dc *DashboardController) ListWidgets(c *gin.Context) {
+	adminUserID, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+
+	widgets, err := dc.dashboardService.ListWidgets(c.Request.Context(), adminUserID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListWidgets failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch dashboard widgets",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    widgets,
+		"total":   len(widgets),
+	})
+}
+
+// CreateWidget adds a widget to the end of the signed-in admin's dashboard (JSON API)
+func (dc *DashboardController) CreateWidget(c *gin.Context) {
+	adminUserID, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+	var req widgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	widget := req.toModel()
+	if err := widget.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid dashboard widget",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if err := dc.dashboardService.CreateWidget(c.Request.Context(), adminUserID, &widget); err != nil {
+		if errors.Is(err, services.ErrTooManyWidgets) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("CreateWidget failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create dashboard widget",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    widget,
+	})
+}
+
+// UpdateWidget changes one of the signed-in admin's widgets (JSON API)
+func (dc *DashboardController) UpdateWidget(c *gin.Context) {
+	adminUserID, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+	var req widgetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	changes := req.toModel()
+	if err := changes.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid dashboard widget",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	widget, err := dc.dashboardService.UpdateWidget(c.Request.Context(), adminUserID, c.Param("id"), changes)
+	if err != nil {
+		if errors.Is(err, services.ErrWidgetNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dashboard widget not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("UpdateWidget failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update dashboard widget",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    widget,
+	})
+}
+
+// DeleteWidget removes one of the signed-in admin's widgets (JSON API)
+func (dc *DashboardController) DeleteWidget(c *gin.Context) {
+	adminUserID, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+
+	if err := dc.dashboardService.DeleteWidget(c.Request.Context(), adminUserID, c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrWidgetNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Dashboard widget not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("DeleteWidget failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete dashboard widget",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// SaveLayout reorders the signed-in admin's widgets (JSON API)
+// Body: {"ids": ["<widget id>", ...]} listing every widget in display order
+func (dc *DashboardController) SaveLayout(c *gin.Context) {
+	adminUserID, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+	var req struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	widgets, err := dc.dashboardService.SaveLayout(c.Request.Context(), adminUserID, req.IDs)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidLayout) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("SaveLayout failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save dashboard layout",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    widgets,
+		"total":   len(widgets),
+	})
+}
+
+// ResetWidgets restores the default dashboard for the signed-in admin (JSON API)
+func (dc *DashboardController) ResetWidgets(c *gin.Context) {
+	adminUserID, ok := sessionAdminID(c)
+	if !ok {
+		return
+	}
+
+	widgets, err := dc.dashboardService.ResetWidgets(c.Request.Context(), adminUserID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ResetWidgets failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reset dashboard",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    widgets,
+		"total":   len(widgets),
+	})
+}
+
+// sessionAdminID returns the signed-in admin's ID, responding 401 when the
+// session predates admin IDs being stored and the admin must sign in again
+func sessionAdminID(c *gin.Context) (uuid.UUID, bool) {
+	raw, _ := sessions.Default(c).Get("admin_id").(string)
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Session expired, please sign in again"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
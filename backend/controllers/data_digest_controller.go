@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// DataDigestController serves finalized end-of-day datasets and their digests
+type DataDigestController struct {
+	digestService *services.DataDigestService
+}
+
+// NewDataDigestController creates a new data digest controller
+func NewDataDigestController(digestService *services.DataDigestService) *DataDigestController {
+	return &DataDigestController{
+		digestService: digestService,
+	}
+}
+
+// GetEODData returns every symbol's candle of one trading day, the download
+// linked from data.digest webhooks
+// @Summary End-of-day dataset
+// @Description Every symbol's candle of the date with its exchange, sorted by code. checksum is computed over the
+// @Description returned candles and equals the digest's checksum once the date is final (digest is null until then).
+// @Tags market
+// @Produce json
+// @Param date path string true "Trading day (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{} "Candles of the date"
+// @Router /api/market/eod/{date} [get]
+func (dc *DataDigestController) GetEODData(c *gin.Context) {
+	date := c.Param("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid date, expected YYYY-MM-DD",
+		})
+		return
+	}
+	if limitHistoryDate(c, date) != date {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Your membership reads end-of-day data from " + middleware.HistoryStart(c) + " onwards",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	candles, err := dc.digestService.Candles(ctx, date)
+	var digest *models.DataDigest
+	if err == nil {
+		digest, err = dc.digestService.Get(ctx, date)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("GetEODData failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get end-of-day data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"data":     candles,
+		"total":    len(candles),
+		"checksum": models.ComputeDigestChecksum(candles),
+		"digest":   digest,
+	})
+}
+
+// GetDigest returns the stored digest of one date (JSON API)
+func (dc *DataDigestController) GetDigest(c *gin.Context) {
+	date := c.Param("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	digest, err := dc.digestService.Get(c.Request.Context(), date)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("fetch data digest failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch data digest",
+			"details": err.Error(),
+		})
+		return
+	}
+	if digest == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "The date is not finalized yet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    digest,
+	})
+}
+
+// PublishDigest rebuilds the digest of one date from the stored candles and
+// sends the data.digest webhook again, e.g. after a correction (JSON API)
+func (dc *DataDigestController) PublishDigest(c *gin.Context) {
+	date := c.Param("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date, expected YYYY-MM-DD"})
+		return
+	}
+
+	digest, _, err := dc.digestService.Finalize(c.Request.Context(), date, true)
+	switch {
+	case errors.Is(err, services.ErrNotTradingDay):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not a trading day"})
+		return
+	case errors.Is(err, services.ErrDigestNotReady):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "The date cannot be finalized yet",
+			"details": err.Error(),
+		})
+		return
+	case err != nil:
+		logging.FromContext(c.Request.Context()).Error("publish data digest failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to publish data digest",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    digest,
+	})
+}
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// DerivativesController handles futures contract requests
+type DerivativesController struct {
+	derivativesService *services.DerivativesService
+}
+
+// NewDerivativesController creates a new derivatives controller
+func NewDerivativesController(derivativesService *services.DerivativesService) *DerivativesController {
+	return &DerivativesController{
+		derivativesService: derivativesService,
+	}
+}
+
+// ListContracts returns the futures contracts on an underlying
+// @Summary Futures contracts
+// @Description Lists the HNX futures contracts on an underlying crawled after each full crawl (crawler.derivatives),
+// @Description nearest expiry first, each with its contract month, last trading date, multiplier and status
+// @Description (active until its last trading date, then expired).
+// @Tags derivatives
+// @Produce json
+// @Param underlying query string false "Underlying index (default VN30)"
+// @Param status query string false "active or expired"
+// @Success 200 {object} map[string]interface{} "Contracts"
+// @Router /api/derivatives/contracts [get]
+func (dc *DerivativesController) ListContracts(c *gin.Context) {
+	query, err := services.ParseFuturesContractsQuery(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid contracts query",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	contracts, err := dc.derivativesService.Contracts(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListContracts failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get futures contracts",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":     "success",
+		"underlying": query.Underlying,
+		"data":       contracts,
+		"total":      len(contracts),
+	})
+}
+
+// GetCandles returns the daily candles of a futures contract with its open
+// interest
+// @Summary Futures candles
+// @Description Daily candles of a contract, oldest first, each with the open interest at the close (oi).
+// @Description Rolling codes resolve to the contract they stand for today: VN30F1M is the front month,
+// @Description VN30F2M the next one.
+// @Tags derivatives
+// @Produce json
+// @Param code path string true "Contract code, e.g. VN30F2611 or VN30F1M"
+// @Param from query string false "First date (YYYY-MM-DD)"
+// @Param to query string false "Last date (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{} "Contract and candles"
+// @Failure 404 {object} map[string]interface{} "Unknown contract"
+// @Router /api/derivatives/{code}/candles [get]
+func (dc *DerivativesController) GetCandles(c *gin.Context) {
+	query, err := services.ParseFuturesCandlesQuery(c.Param("code"), c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid futures candles query",
+			"error":   err.Error(),
+		})
+		return
+	}
+	query.From = limitHistoryDate(c, query.From)
+
+	candles, err := dc.derivativesService.Candles(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetCandles failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get futures candles",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if candles == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Futures contract not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"code":     candles.Contract.Code,
+		"contract": candles.Contract,
+		"data":     candles.Candles,
+		"total":    len(candles.Candles),
+	})
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"github.com/datvt88/CPLS/backend/format"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// wantsVietnameseDisplay reports whether the client asked for pre-formatted
+// Vietnamese display strings with ?locale=vi. Raw numbers are always returned.
+func wantsVietnameseDisplay(c *gin.Context) bool {
+	return c.Query("locale") == format.LocaleVI
+}
+
+// candleDisplay holds the display strings of one candle
+type candleDisplay struct {
+	O      string `json:"o"`
+	H      string `json:"h"`
+	L      string `json:"l"`
+	C      string `json:"c"`
+	V      string `json:"v"`
+	Change string `json:"change,omitempty"` // Close vs previous close; empty for the first candle
+}
+
+// displayCandle is a candle with optional display strings
+type displayCandle struct {
+	models.CandleData
+	Display *candleDisplay `json:"display,omitempty"`
+}
+
+// vietnameseCandles adds Vietnamese display strings to candles ordered by date
+func vietnameseCandles(candles []models.CandleData) []displayCandle {
+	result := make([]displayCandle, len(candles))
+	for i, candle := range candles {
+		var previous *models.CandleData
+		if i > 0 {
+			previous = &candles[i-1]
+		}
+		result[i] = vietnameseCandle(candle, previous)
+	}
+	return result
+}
+
+// vietnameseCandle adds Vietnamese display strings to candle; the change is
+// computed against previous, the candle before it (nil for the first)
+func vietnameseCandle(candle models.CandleData, previous *models.CandleData) displayCandle {
+	display := &candleDisplay{
+		O: format.Price(candle.O),
+		H: format.Price(candle.H),
+		L: format.Price(candle.L),
+		C: format.Price(candle.C),
+		V: format.Volume(candle.V),
+	}
+	if previous != nil && previous.C != 0 {
+		display.Change = format.Percent((candle.C - previous.C) / previous.C * 100)
+	}
+	return displayCandle{CandleData: candle, Display: display}
+}
@@ -0,0 +1,23 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/services"
+)
+
+// errorStatus maps the typed errors shared by services to the HTTP status
+// they call for: unknown stocks are 404, concurrent bucket writes and crawls
+// 409 and upstream throttling 429. Any other error is a 500.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrStockNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrBucketConflict), errors.Is(err, services.ErrCrawlInProgress):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrProviderThrottled), errors.Is(err, services.ErrProviderQuotaExhausted):
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
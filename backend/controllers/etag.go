@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// representationTag returns the entity tag of a response over the data
+// identified by parts, in the representation the client selected: the
+// response format, the display locale and NDJSON streaming change the body,
+// not the data
+func representationTag(c *gin.Context, parts ...string) string {
+	parts = append(parts, c.Writer.Header().Get(middleware.ResponseFormatHeader), c.Query("locale"), strconv.FormatBool(wantsNDJSON(c)))
+	return models.EntityTag(parts...)
+}
+
+// notModified sets the ETag header and answers 304 Not Modified when the
+// client's If-None-Match already names it. Clients are asked to revalidate
+// before reusing a stored response.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if models.ETagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeController exposes the exchange registry
+type ExchangeController struct{}
+
+// NewExchangeController creates a new exchange controller
+func NewExchangeController() *ExchangeController {
+	return &ExchangeController{}
+}
+
+// exchangeStatus is a registered exchange with its current trading state
+type exchangeStatus struct {
+	models.Exchange
+	TradingDay bool `json:"trading_day"`
+	Open       bool `json:"open"`
+}
+
+// ListExchanges returns the registered exchanges and whether each is trading now
+// @Summary Exchanges
+// @Description Returns every registered exchange with its currency, timezone, trading sessions,
+// @Description weekend days, holidays, price band rule and data source, plus whether it is open now.
+// @Tags stocks
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Exchanges"
+// @Router /api/exchanges [get]
+func (ec *ExchangeController) ListExchanges(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   exchangeStatuses(time.Now()),
+	})
+}
+
+// statusOf returns an exchange with its trading state at now
+func statusOf(exchange models.Exchange, now time.Time) exchangeStatus {
+	return exchangeStatus{
+		Exchange:   exchange,
+		TradingDay: exchange.IsTradingDay(now),
+		Open:       exchange.IsOpen(now),
+	}
+}
+
+// exchangeStatuses returns every registered exchange with its trading state at now
+func exchangeStatuses(now time.Time) []exchangeStatus {
+	exchanges := models.Exchanges()
+	statuses := make([]exchangeStatus, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		statuses = append(statuses, statusOf(exchange, now))
+	}
+	return statuses
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureController tells members which feature flags are on for them
+type FeatureController struct {
+	featureService *services.FeatureService
+}
+
+// NewFeatureController creates a new feature controller
+func NewFeatureController(featureService *services.FeatureService) *FeatureController {
+	return &FeatureController{
+		featureService: featureService,
+	}
+}
+
+// ListFeatures returns the feature flags enabled for the member, with the
+// membership tier and environment they were evaluated for
+// @Summary List enabled features
+// @Tags me
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Enabled feature names"
+// @Router /api/me/features [get]
+func (fc *FeatureController) ListFeatures(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	features, tier, err := fc.featureService.EnabledFeatures(c.Request.Context(), profileID.String())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListFeatures failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch features",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":      "success",
+		"data":        features,
+		"tier":        tier,
+		"environment": config.Runtime().Environment,
+	})
+}
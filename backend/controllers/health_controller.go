@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// HealthController serves the liveness and deep health checks
+type HealthController struct {
+	healthService *services.HealthService
+	loadShedder   *services.LoadShedder
+}
+
+// NewHealthController creates a new health controller
+func NewHealthController(healthService *services.HealthService, loadShedder *services.LoadShedder) *HealthController {
+	return &HealthController{
+		healthService: healthService,
+		loadShedder:   loadShedder,
+	}
+}
+
+// GetHealth answers liveness probes without touching any dependency; while
+// a data store is unavailable it still answers 200 (restarting the instance
+// would not help) but reports "degraded" and the stores that are down, and
+// likewise while expensive requests are shed under load. With
+// ?deep=true it also pings Postgres and MongoDB, checks the age of the last
+// successful crawl and the reachability of the upstream data sources, and
+// answers 503 with the status of each when any of them is degraded.
+// @Summary Health check
+// @Tags status
+// @Produce json
+// @Param deep query bool false "Check every dependency"
+// @Router /health [get]
+func (hc *HealthController) GetHealth(c *gin.Context) {
+	if deep, _ := strconv.ParseBool(c.Query("deep")); !deep {
+		response := gin.H{
+			"status":  "healthy",
+			"service": "CPLS Market Data Crawler",
+			"version": "1.0.0",
+		}
+		if down := config.UnavailableStores(config.Stores...); len(down) > 0 {
+			response["status"] = "degraded"
+			response["unavailable_stores"] = down
+		}
+		if load := hc.loadShedder.State(); load.Shedding {
+			response["status"] = "degraded"
+			response["load_shedding"] = load
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	health := hc.healthService.Deep(c.Request.Context())
+	c.Header("Cache-Control", "no-store")
+	if !health.Healthy() {
+		c.JSON(http.StatusServiceUnavailable, health)
+		return
+	}
+	c.JSON(http.StatusOK, health)
+}
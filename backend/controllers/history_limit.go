@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// historyStartHeader tells members how far back their membership tier reads
+const historyStartHeader = "X-History-Start"
+
+// limitHistoryDate returns from (YYYY-MM-DD, "" for the start of the
+// history) moved up to the earliest date the caller's membership tier may
+// read, announcing that date in X-History-Start
+func limitHistoryDate(c *gin.Context, from string) string {
+	start := middleware.HistoryStart(c)
+	if start == "" {
+		return from
+	}
+	c.Header(historyStartHeader, start)
+	if from < start {
+		return start
+	}
+	return from
+}
+
+// limitHistoryTime is limitHistoryDate for a date parsed as UTC midnight
+func limitHistoryTime(c *gin.Context, from time.Time) time.Time {
+	limited := limitHistoryDate(c, from.Format("2006-01-02"))
+	if start, err := time.Parse("2006-01-02", limited); err == nil && start.After(from) {
+		return start
+	}
+	return from
+}
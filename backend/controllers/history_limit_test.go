@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+func TestLimitHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if got := limitHistoryDate(c, ""); got != "" {
+		t.Errorf("limitHistoryDate() without a tier limit = %q; want the full history", got)
+	}
+
+	recorder := httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(recorder)
+	c.Set(middleware.ContextHistoryStart, "2026-07-17")
+	for from, want := range map[string]string{"": "2026-07-17", "2025-01-02": "2026-07-17", "2026-09-01": "2026-09-01"} {
+		if got := limitHistoryDate(c, from); got != want {
+			t.Errorf("limitHistoryDate(%q) = %q; want %q", from, got, want)
+		}
+	}
+	if got := recorder.Header().Get(historyStartHeader); got != "2026-07-17" {
+		t.Errorf("%s = %q; want the tier's first date", historyStartHeader, got)
+	}
+
+	from := time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC)
+	if got := limitHistoryTime(c, from); !got.Equal(time.Date(2026, 7, 17, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("limitHistoryTime(%s) = %s; want 2026-07-17", from, got)
+	}
+}
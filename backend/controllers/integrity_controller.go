@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// IntegrityController handles price data checksum and verification requests
+type IntegrityController struct {
+	integrityService *services.IntegrityService
+}
+
+// NewIntegrityController creates a new integrity controller
+func NewIntegrityController(integrityService *services.IntegrityService) *IntegrityController {
+	return &IntegrityController{
+		integrityService: integrityService,
+	}
+}
+
+// GetChecksums returns the per-year bucket checksums of a stock
+// @Summary Price bucket checksums
+// @Description Returns the SHA-256 of each yearly price bucket (candles sorted by date)
+// @Description so that mirrors can detect divergence without downloading the candles.
+// @Tags stocks
+// @Produce json
+// @Param code path string true "Stock code"
+// @Success 200 {object} map[string]interface{} "Bucket checksums"
+// @Router /api/stocks/{code}/checksums [get]
+func (ic *IntegrityController) GetChecksums(c *gin.Context) {
+	checksums, err := ic.integrityService.GetChecksums(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get checksums",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if len(checksums) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "No price data for this stock",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   checksums,
+	})
+}
+
+// Verify runs an integrity verification over the selected buckets (JSON API)
+func (ic *IntegrityController) Verify(c *gin.Context) {
+	var opts services.VerifyOptions
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+
+	report, err := ic.integrityService.Verify(c.Request.Context(), opts)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Verify failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to verify price data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
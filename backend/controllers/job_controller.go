@@ -0,0 +1,105 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// JobController lets admins inspect, retry and cancel background jobs
+type JobController struct {
+	jobQueue *services.JobQueue
+}
+
+// NewJobController creates a new job controller
+func NewJobController(jobQueue *services.JobQueue) *JobController {
+	return &JobController{
+		jobQueue: jobQueue,
+	}
+}
+
+// respondJobError maps job queue errors to responses
+func respondJobError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	case errors.Is(err, services.ErrJobState):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Job cannot be changed in its current state",
+			"details": err.Error(),
+		})
+	default:
+		logging.FromContext(c.Request.Context()).Error(action+" failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to " + action,
+			"details": err.Error(),
+		})
+	}
+}
+
+// ListJobs returns background jobs, newest first (JSON API)
+// Query params: kind, status, limit (default: the admin's page size, max 500)
+func (jc *JobController) ListJobs(c *gin.Context) {
+	filter := services.JobFilter{
+		Kind:   c.Query("kind"),
+		Status: c.Query("status"),
+		Limit:  adminLimit(c, "limit"),
+	}
+
+	jobs, err := jc.jobQueue.List(c.Request.Context(), filter)
+	if err != nil {
+		respondJobError(c, "fetch jobs", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    jobs,
+		"total":   len(jobs),
+	})
+}
+
+// GetJob returns one background job (JSON API)
+func (jc *JobController) GetJob(c *gin.Context) {
+	job, err := jc.jobQueue.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondJobError(c, "fetch job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// RetryJob queues a failed or cancelled job again with a fresh set of attempts (JSON API)
+func (jc *JobController) RetryJob(c *gin.Context) {
+	job, err := jc.jobQueue.Retry(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondJobError(c, "requeue job", err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
+
+// CancelJob cancels a queued job (JSON API)
+func (jc *JobController) CancelJob(c *gin.Context) {
+	job, err := jc.jobQueue.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondJobError(c, "cancel job", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    job,
+	})
+}
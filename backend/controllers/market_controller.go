@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// MarketController handles market-wide statistics
+type MarketController struct {
+	sectorBreadthService *services.SectorBreadthService
+	metricsService       *services.StockMetricsService
+	moversService        *services.MarketMoversService
+	anomalyService       *services.VolumeAnomalyService
+}
+
+// NewMarketController creates a new market controller
+func NewMarketController(sectorBreadthService *services.SectorBreadthService, metricsService *services.StockMetricsService, moversService *services.MarketMoversService, anomalyService *services.VolumeAnomalyService) *MarketController {
+	return &MarketController{
+		sectorBreadthService: sectorBreadthService,
+		metricsService:       metricsService,
+		moversService:        moversService,
+		anomalyService:       anomalyService,
+	}
+}
+
+// GetSectorBreadth returns the daily breadth of every sector over a date range
+// @Summary Sector breadth
+// @Description Per trading day and ICB sector: members that traded, advancing/declining/unchanged counts and
+// @Description percentages against the previous close, and the share closing above their 50-session moving
+// @Description average. Computed after every crawl; oldest day first.
+// @Tags market
+// @Produce json
+// @Param from query string false "First date (YYYY-MM-DD, default 30 days before to)"
+// @Param to query string false "Last date (YYYY-MM-DD, default today)"
+// @Param sector query string false "Only this sector"
+// @Success 200 {object} map[string]interface{} "Daily sector breadth"
+// @Router /api/market/sector-breadth [get]
+func (mc *MarketController) GetSectorBreadth(c *gin.Context) {
+	from, to, err := services.ParseBreadthRange(c.Query("from"), c.Query("to"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid date range",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	history, err := mc.sectorBreadthService.History(c.Request.Context(), from, to, strings.TrimSpace(c.Query("sector")))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetSectorBreadth failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get sector breadth",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"from":   from,
+		"to":     to,
+		"data":   history,
+	})
+}
+
+// GetScreener screens symbols by their daily liquidity metrics
+// @Summary Liquidity screener
+// @Description Filters and sorts the liquidity metrics computed after every crawl: average daily volume and
+// @Description traded value, relative volume, volatility and Amihud illiquidity over the last 20 sessions,
+// @Description and the largest position tradable per session at 10% of the average volume.
+// @Tags market
+// @Produce json
+// @Param exchange query string false "Only this exchange (HOSE, HNX, UPCOM)"
+// @Param industry query string false "Only this ICB industry (level 1)"
+// @Param sector query string false "Only this ICB sector (level 2)"
+// @Param min_avg_value query number false "Minimum average daily traded value"
+// @Param min_avg_volume query number false "Minimum average daily volume"
+// @Param min_relative_volume query number false "Minimum relative volume"
+// @Param max_volatility query number false "Maximum volatility (percent)"
+// @Param max_amihud query number false "Maximum Amihud illiquidity"
+// @Param sort query string false "Field to sort by, prefixed with - for descending (default -avg_value20)"
+// @Param limit query int false "Maximum rows (default 50, max 500)"
+// @Success 200 {object} map[string]interface{} "Matching symbols"
+// @Router /api/market/screener [get]
+func (mc *MarketController) GetScreener(c *gin.Context) {
+	filter, err := services.ParseScreenerFilter(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid screener filter",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	rows, err := mc.metricsService.Screen(c.Request.Context(), filter)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetScreener failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to screen stocks",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"total":  len(rows),
+		"data":   rows,
+	})
+}
+
+// GetMovers returns the top gainers and losers, or the most traded symbols,
+// of a trading day
+// @Summary Market movers
+// @Description Ranks the symbols that traded on the date from the stored candles: by=change returns the largest
+// @Description rises (gainers) and falls (losers) against the previous close, by=volume the largest volumes
+// @Description (most_active). Without a date the newest day with candles is ranked.
+// @Tags market
+// @Produce json
+// @Param date query string false "Trading day (YYYY-MM-DD, default the newest with candles)"
+// @Param by query string false "change (default) or volume"
+// @Param exchange query string false "Only these exchanges, comma-separated (HOSE, HNX, UPCOM)"
+// @Param limit query int false "Symbols per ranking (default 10, max 50)"
+// @Success 200 {object} map[string]interface{} "Movers of the date"
+// @Router /api/market/movers [get]
+func (mc *MarketController) GetMovers(c *gin.Context) {
+	query, err := services.ParseMoversQuery(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid movers query",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	movers, err := mc.moversService.Movers(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetMovers failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get market movers",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   movers,
+	})
+}
+
+// GetNewHighsLows lists the symbols that made a new 52-week high or low on
+// the newest trading day
+// @Summary New 52-week highs and lows
+// @Description The symbols whose newest session traded above the high (new_highs) or below the low (new_lows)
+// @Description of the 52 weeks before it, from the metrics computed after every crawl. Symbols listed for less
+// @Description than a year are left out. Each side is ordered by average traded value, most liquid first.
+// @Tags market
+// @Produce json
+// @Param exchange query string false "Only this exchange (HOSE, HNX, UPCOM)"
+// @Param limit query int false "Symbols of each side (default 50, max 500)"
+// @Success 200 {object} map[string]interface{} "New highs and lows"
+// @Router /api/market/new-highs-lows [get]
+func (mc *MarketController) GetNewHighsLows(c *gin.Context) {
+	query, err := services.ParseNewHighsLowsQuery(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid new highs and lows query",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	result, err := mc.metricsService.NewHighsLows(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetNewHighsLows failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get new highs and lows",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   result,
+	})
+}
+
+// GetVolumeAnomalies lists the sessions of unusual volume flagged after each
+// crawl
+// @Summary Volume anomalies
+// @Description Sessions whose volume was at least anomalies.volume_multiple times the symbol's average over the
+// @Description 20 sessions before, detected after every crawl. Newest date first, largest ratio first within a date.
+// @Tags market
+// @Produce json
+// @Param from query string false "First date (YYYY-MM-DD, default 30 days before to)"
+// @Param to query string false "Last date (YYYY-MM-DD, default today)"
+// @Param code query string false "Only this symbol"
+// @Param exchange query string false "Only this exchange (HOSE, HNX, UPCOM)"
+// @Param min_ratio query number false "Minimum volume / average ratio"
+// @Param limit query int false "Maximum rows (default 100, max 500)"
+// @Success 200 {object} map[string]interface{} "Volume anomalies"
+// @Router /api/market/volume-anomalies [get]
+func (mc *MarketController) GetVolumeAnomalies(c *gin.Context) {
+	filter, err := services.ParseVolumeAnomalyFilter(c.Query, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid volume anomaly filter",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	anomalies, err := mc.anomalyService.List(c.Request.Context(), filter)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetVolumeAnomalies failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get volume anomalies",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"from":   filter.From,
+		"to":     filter.To,
+		"data":   anomalies,
+		"total":  len(anomalies),
+	})
+}
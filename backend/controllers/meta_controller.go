@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// MetaController describes the API itself
+type MetaController struct{}
+
+// NewMetaController creates a new meta controller
+func NewMetaController() *MetaController {
+	return &MetaController{}
+}
+
+// GetChanges returns the API versions and the changelog
+// @Summary API changelog
+// @Description Machine-readable registry of the response versions (X-API-Version) and the changes of the API,
+// @Description newest first: added, changed, deprecated and removed endpoints and fields. Deprecated ones carry
+// @Description a sunset date, and their responses Deprecation and Sunset headers.
+// @Tags meta
+// @Produce json
+// @Param since query string false "Only changes on or after this date (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{} "Versions and changes"
+// @Router /api/meta/changes [get]
+func (mc *MetaController) GetChanges(c *gin.Context) {
+	since := c.Query("since")
+	if since != "" {
+		if _, err := time.Parse("2006-01-02", since); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid since date, expected YYYY-MM-DD",
+			})
+			return
+		}
+	}
+
+	changes := models.APIChangesSince(since)
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data": gin.H{
+			"versions": models.APIVersions,
+			"changes":  changes,
+		},
+		"total": len(changes),
+	})
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ndjsonContentType selects streamed responses, one JSON row per line
+	ndjsonContentType = "application/x-ndjson"
+	// ndjsonFlushRows is how many rows are written between flushes
+	ndjsonFlushRows = 500
+)
+
+// wantsNDJSON reports whether the client asked for a streamed NDJSON response
+func wantsNDJSON(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), ndjsonContentType)
+}
+
+// streamNDJSON answers 200 with the rows stream passes to emit, one JSON
+// document per line, as they are read. Headers must be set before it is
+// called. An error after the first row can no longer change the status, so
+// it ends the stream with an error row the client must check for.
+func streamNDJSON(c *gin.Context, stream func(emit func(row interface{}) error) error) {
+	c.Header("Content-Type", ndjsonContentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	rows := 0
+	err := stream(func(row interface{}) error {
+		line, err := middleware.FormatRow(c, row)
+		if err != nil {
+			return err
+		}
+		if _, err := c.Writer.Write(append(line, '\n')); err != nil {
+			return err
+		}
+		rows++
+		if rows%ndjsonFlushRows == 0 {
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("NDJSON stream failed", "rows", rows, logging.FieldError, err)
+		line, _ := middleware.FormatRow(c, gin.H{
+			"status":  "error",
+			"message": "Stream interrupted",
+			"error":   err.Error(),
+		})
+		_, _ = c.Writer.Write(append(line, '\n'))
+	}
+	c.Writer.Flush()
+}
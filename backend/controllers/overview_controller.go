@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// OverviewController serves the market overview composed from several sources
+type OverviewController struct {
+	crawlerService *services.CrawlerService
+	alertService   *services.AlertService
+}
+
+// NewOverviewController creates a new overview controller
+func NewOverviewController(crawlerService *services.CrawlerService, alertService *services.AlertService) *OverviewController {
+	return &OverviewController{
+		crawlerService: crawlerService,
+		alertService:   alertService,
+	}
+}
+
+// GetOverview returns the exchanges' trading state with data coverage and freshness
+// @Summary Market overview
+// @Description Combines exchange trading state, stock and price bucket counts, the latest crawl run and the
+// @Description freshest candle date. Sources that do not answer within the time budget (composite.timeout)
+// @Description are left out: the response then has "partial": true and a warning per missing part.
+// @Tags stocks
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Overview"
+// @Router /api/overview [get]
+func (oc *OverviewController) GetOverview(c *gin.Context) {
+	budget := services.NewDeadlineBudget(c.Request.Context(), "overview", config.Runtime().CompositeTimeout)
+	counts := services.Fetch(budget, "coverage", 1, false, func(ctx context.Context) (map[string]interface{}, error) {
+		return oc.crawlerService.GetCrawlStatus(ctx)
+	})
+	metrics := services.Fetch(budget, "freshness", 1, false, func(ctx context.Context) (models.OpsMetrics, error) {
+		return oc.alertService.CollectMetrics(ctx)
+	})
+	report := budget.Wait()
+
+	data := gin.H{"exchanges": exchangeStatuses(time.Now())}
+	if coverage, ok := counts.Value(); ok {
+		data["total_stocks"] = coverage["total_stocks"]
+		data["total_price_buckets"] = coverage["total_price_buckets"]
+	}
+	if freshness, ok := metrics.Value(); ok {
+		data["latest_run"] = freshness.LatestRun
+		data["last_successful_crawl_at"] = freshness.LastSuccessfulCrawlAt
+		data["freshest_candle_date"] = freshness.FreshestCandleDate
+	}
+	respondComposite(c, report, "Failed to build market overview", data)
+}
@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPaymentWebhookBody caps the webhook body read into memory
+const maxPaymentWebhookBody = 64 << 10
+
+// PaymentController receives payment provider webhooks
+type PaymentController struct {
+	paymentService *services.PaymentService
+}
+
+// NewPaymentController creates a new payment controller
+func NewPaymentController(paymentService *services.PaymentService) *PaymentController {
+	return &PaymentController{
+		paymentService: paymentService,
+	}
+}
+
+// HandleWebhook records a payment notification and upgrades the member's
+// membership when the payment succeeded
+// @Summary Payment provider webhook
+// @Description Signed with X-Payment-Signature: t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+// @Description using the provider's secret from PAYMENT_WEBHOOK_SECRETS. VNPay IPNs (GET) are verified by
+// @Description vnp_SecureHash and MoMo IPNs by their signature field instead. Retried notifications are
+// @Description acknowledged without being applied twice.
+// @Tags payments
+// @Accept json
+// @Produce json
+// @Param provider query string true "Payment provider (stripe, vnpay, momo, ...)"
+// @Router /api/payments/webhook [post]
+// @Router /api/payments/webhook [get]
+func (pc *PaymentController) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPaymentWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"status":  "error",
+			"message": "Webhook body too large",
+		})
+		return
+	}
+
+	provider := c.Query("provider")
+	result, err := pc.paymentService.HandleWebhook(c.Request.Context(), provider,
+		c.GetHeader("X-Payment-Signature"), body, c.Request.URL.Query(), c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPaymentsDisabled):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "message": "Payment webhooks are not configured"})
+		case errors.Is(err, services.ErrInvalidPaymentSignature):
+			logging.FromContext(c.Request.Context()).Warn("Rejected payment webhook", "client_ip", c.ClientIP(), "provider", provider, logging.FieldError, err)
+			c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "Invalid signature"})
+		case errors.Is(err, services.ErrInvalidPayment):
+			c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+		case errors.Is(err, services.ErrProfileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"status": "error", "message": "Profile not found"})
+		default:
+			logging.FromContext(c.Request.Context()).Error("HandleWebhook failed", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to process payment"})
+		}
+		return
+	}
+
+	if !result.Duplicate {
+		logging.FromContext(c.Request.Context()).Info("Payment recorded",
+			"provider", result.Payment.Provider, "transaction_id", result.Payment.TransactionID,
+			"payment_status", result.Payment.Status, "profile_id", result.Payment.ProfileID)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   result,
+	})
+}
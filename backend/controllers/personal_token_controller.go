@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxPersonalTokenDays caps the lifetime a member can request for a token
+const maxPersonalTokenDays = 365
+
+// PersonalTokenController handles members' personal access tokens (/api/me/tokens)
+type PersonalTokenController struct {
+	personalTokenService *services.PersonalTokenService
+}
+
+// NewPersonalTokenController creates a new personal token controller
+func NewPersonalTokenController(personalTokenService *services.PersonalTokenService) *PersonalTokenController {
+	return &PersonalTokenController{
+		personalTokenService: personalTokenService,
+	}
+}
+
+// memberID returns the authenticated member's profile ID
+func memberID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.GetString(middleware.ContextAuthSubject))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  "error",
+			"message": "Invalid member identity",
+		})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// ListTokens returns the member's personal access tokens without their secrets
+// @Summary List personal access tokens
+// @Tags me
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Personal access tokens"
+// @Router /api/me/tokens [get]
+func (pc *PersonalTokenController) ListTokens(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := pc.personalTokenService.ListTokens(c.Request.Context(), profileID)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListTokens failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to fetch personal access tokens",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   tokens,
+		"scopes": models.PersonalTokenScopes,
+	})
+}
+
+// CreateToken creates a read-only personal access token and returns its plaintext once
+// @Summary Create personal access token
+// @Description Creates a token for scripts (Excel, Python). Send it as "Authorization: Bearer <token>"
+// @Description to the market data endpoints. The token is shown only in this response.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body object true "name, optional scopes and expires_in_days"
+// @Success 201 {object} map[string]interface{} "Created token"
+// @Router /api/me/tokens [post]
+func (pc *PersonalTokenController) CreateToken(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name          string   `json:"name" binding:"required"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"` // 0: never expires
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "name is required",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if req.ExpiresInDays < 0 || req.ExpiresInDays > maxPersonalTokenDays {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "expires_in_days must be between 0 (no expiry) and 365",
+		})
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	token, plaintext, err := pc.personalTokenService.CreateToken(c.Request.Context(), profileID, req.Name, req.Scopes, ttl)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, services.ErrProfileNotFound), errors.Is(err, services.ErrProfileInactive):
+			status = http.StatusForbidden
+		case errors.Is(err, services.ErrPersonalTokenLimit):
+			status = http.StatusConflict
+		}
+		c.JSON(status, gin.H{
+			"status":  "error",
+			"message": "Failed to create personal access token",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Personal access token created", "name", token.Name, "prefix", token.Prefix, "profile_id", profileID)
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   token,
+		"token":  plaintext,
+		"note":   "Store this token now; it cannot be shown again",
+	})
+}
+
+// RevokeToken revokes one of the member's personal access tokens
+// @Summary Revoke personal access token
+// @Tags me
+// @Produce json
+// @Param id path string true "Token ID"
+// @Success 200 {object} map[string]interface{} "Revoked token"
+// @Router /api/me/tokens/{id} [delete]
+func (pc *PersonalTokenController) RevokeToken(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	token, err := pc.personalTokenService.RevokeToken(c.Request.Context(), profileID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrPersonalTokenNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "Personal access token not found",
+			})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("RevokeToken failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to revoke personal access token",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   token,
+	})
+}
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PortfolioController handles members' portfolios (/api/me/portfolio)
+type PortfolioController struct {
+	portfolioService *services.PortfolioService
+}
+
+// NewPortfolioController creates a new portfolio controller
+func NewPortfolioController(portfolioService *services.PortfolioService) *PortfolioController {
+	return &PortfolioController{
+		portfolioService: portfolioService,
+	}
+}
+
+// portfolioTradeRequest is the JSON body accepted when recording a trade
+type portfolioTradeRequest struct {
+	Code      string  `json:"code"`
+	Side      string  `json:"side"`
+	Quantity  int64   `json:"quantity"`
+	Price     float64 `json:"price"`
+	Fee       float64 `json:"fee"`
+	TradeDate string  `json:"trade_date"`
+	Note      string  `json:"note"`
+}
+
+// toModel converts the request into a trade of the member, applying defaults
+func (r portfolioTradeRequest) toModel(profileID uuid.UUID) models.PortfolioTrade {
+	trade := models.PortfolioTrade{
+		ProfileID: profileID,
+		Code:      r.Code,
+		Side:      r.Side,
+		Quantity:  r.Quantity,
+		Price:     r.Price,
+		Fee:       r.Fee,
+		TradeDate: r.TradeDate,
+		Note:      r.Note,
+	}
+	if trade.Side == "" {
+		trade.Side = models.TradeSideBuy
+	}
+	if trade.TradeDate == "" {
+		trade.TradeDate = markettime.Today()
+	}
+	return trade
+}
+
+// respondPortfolioError maps portfolio service errors to responses
+func respondPortfolioError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrPortfolioTradeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Trade not found",
+		})
+	case errors.Is(err, services.ErrInvalidPortfolioTrade):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid trade",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, services.ErrInvalidPerformanceQuery):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid performance query",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, services.ErrPortfolioTradeLimit):
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  "error",
+			"message": "Too many trades",
+			"error":   err.Error(),
+		})
+	default:
+		logging.FromContext(c.Request.Context()).Error(action+" failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to " + action,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// GetValuation returns the member's holdings marked to market
+// @Summary Portfolio valuation
+// @Description Builds the member's holdings from their trades (average cost method) and values them with the
+// @Description latest stored daily candle: cost basis, market value, realized and unrealized P&L and allocation
+// @Tags me
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Holdings and totals"
+// @Router /api/me/portfolio [get]
+func (pc *PortfolioController) GetValuation(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	valuation, err := pc.portfolioService.Valuation(c.Request.Context(), profileID)
+	if err != nil {
+		respondPortfolioError(c, "value portfolio", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   valuation,
+	})
+}
+
+// GetPerformance returns the member's portfolio return compared with a market index
+// @Summary Portfolio performance
+// @Description Values the member's holdings with the stored daily closes over the period and returns the
+// @Description time-weighted return (buys and sells are not counted as gains), the maximum drawdown and the
+// @Description same figures for a market index, with a daily series for charts. Returns are in percent.
+// @Tags me
+// @Produce json
+// @Param period query string false "1m, 3m, 6m, ytd, 1y (default), 3y or all (since the first trade)"
+// @Param benchmark query string false "Market index: VNINDEX (default), VN30, HNXINDEX or UPCOMINDEX"
+// @Success 200 {object} map[string]interface{} "Returns, drawdowns and daily series"
+// @Router /api/me/portfolio/performance [get]
+func (pc *PortfolioController) GetPerformance(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	performance, err := pc.portfolioService.Performance(c.Request.Context(), profileID, c.Query("period"), c.Query("benchmark"))
+	if err != nil {
+		respondPortfolioError(c, "compute portfolio performance", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   performance,
+	})
+}
+
+// ListTrades returns the member's recorded trades, newest first
+// @Summary List portfolio trades
+// @Tags me
+// @Produce json
+// @Param code query string false "Only trades of this stock"
+// @Success 200 {object} map[string]interface{} "Trades"
+// @Router /api/me/portfolio/trades [get]
+func (pc *PortfolioController) ListTrades(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	trades, err := pc.portfolioService.ListTrades(c.Request.Context(), profileID, c.Query("code"))
+	if err != nil {
+		respondPortfolioError(c, "fetch portfolio trades", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   trades,
+	})
+}
+
+// RecordTrade records a buy or sell
+// @Summary Record portfolio trade
+// @Description Records a trade. side defaults to "buy" and trade_date to today; price is per share in the
+// @Description candles' unit. A sell cannot exceed the quantity held on its date.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body object true "code, side, quantity, price, optional fee, trade_date (YYYY-MM-DD), note"
+// @Success 201 {object} map[string]interface{} "Recorded trade"
+// @Router /api/me/portfolio/trades [post]
+func (pc *PortfolioController) RecordTrade(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req portfolioTradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	trade := req.toModel(profileID)
+	if err := pc.portfolioService.RecordTrade(c.Request.Context(), &trade); err != nil {
+		respondPortfolioError(c, "record portfolio trade", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   trade,
+	})
+}
+
+// DeleteTrade deletes one of the member's trades
+// @Summary Delete portfolio trade
+// @Tags me
+// @Produce json
+// @Param id path string true "Trade ID"
+// @Success 200 {object} map[string]interface{} "Deleted"
+// @Router /api/me/portfolio/trades/{id} [delete]
+func (pc *PortfolioController) DeleteTrade(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	if err := pc.portfolioService.DeleteTrade(c.Request.Context(), profileID, c.Param("id")); err != nil {
+		respondPortfolioError(c, "delete portfolio trade", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
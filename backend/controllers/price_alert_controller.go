@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PriceAlertController handles members' price alerts (/api/me/alerts)
+type PriceAlertController struct {
+	priceAlertService *services.PriceAlertService
+}
+
+// NewPriceAlertController creates a new price alert controller
+func NewPriceAlertController(priceAlertService *services.PriceAlertService) *PriceAlertController {
+	return &PriceAlertController{
+		priceAlertService: priceAlertService,
+	}
+}
+
+// priceAlertRequest is the JSON body accepted when creating or updating an alert
+type priceAlertRequest struct {
+	Code      string `json:"code"`
+	Condition string `json:"condition"`
+	Channels  string `json:"channels"`
+	Note      string `json:"note"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+// toModel converts the request into a PriceAlert of the member, applying defaults
+func (r priceAlertRequest) toModel(profileID uuid.UUID) models.PriceAlert {
+	alert := models.PriceAlert{
+		ProfileID: profileID,
+		Code:      r.Code,
+		Condition: r.Condition,
+		Channels:  r.Channels,
+		Note:      r.Note,
+		Enabled:   true,
+	}
+	if r.Enabled != nil {
+		alert.Enabled = *r.Enabled
+	}
+	return alert
+}
+
+// respondPriceAlertError maps price alert service errors to responses
+func respondPriceAlertError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrPriceAlertNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Price alert not found",
+		})
+	case errors.Is(err, services.ErrInvalidPriceAlert):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid price alert",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, services.ErrPriceAlertLimit):
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  "error",
+			"message": "Too many price alerts",
+			"error":   err.Error(),
+		})
+	default:
+		logging.FromContext(c.Request.Context()).Error(action+" failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to " + action,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// ListAlerts returns the member's price alerts
+// @Summary List price alerts
+// @Description Lists the member's alerts with their state, plus the supported metrics and delivery channels
+// @Tags me
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Price alerts"
+// @Router /api/me/alerts [get]
+func (pc *PriceAlertController) ListAlerts(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	alerts, err := pc.priceAlertService.ListAlerts(c.Request.Context(), profileID)
+	if err != nil {
+		respondPriceAlertError(c, "fetch price alerts", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"data":     alerts,
+		"metrics":  models.PriceAlertMetrics,
+		"channels": pc.priceAlertService.Channels(),
+	})
+}
+
+// CreateAlert creates a price alert
+// @Summary Create price alert
+// @Description Creates an alert on a stock. condition compares a metric of the latest daily candle with a
+// @Description number, e.g. "close > 30", "rsi < 30" or "change_percent <= -5". Alerts are evaluated after
+// @Description every crawl that stores new candles and notify the listed channels when the condition starts to hold.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body object true "code, condition, optional channels (comma-separated), note, enabled"
+// @Success 201 {object} map[string]interface{} "Created alert"
+// @Router /api/me/alerts [post]
+func (pc *PriceAlertController) CreateAlert(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req priceAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	alert := req.toModel(profileID)
+	if err := pc.priceAlertService.CreateAlert(c.Request.Context(), &alert); err != nil {
+		respondPriceAlertError(c, "create price alert", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   alert,
+	})
+}
+
+// UpdateAlert updates one of the member's price alerts
+// @Summary Update price alert
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param id path string true "Alert ID"
+// @Param request body object true "code, condition, optional channels, note, enabled"
+// @Success 200 {object} map[string]interface{} "Updated alert"
+// @Router /api/me/alerts/{id} [put]
+func (pc *PriceAlertController) UpdateAlert(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req priceAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	alert, err := pc.priceAlertService.UpdateAlert(c.Request.Context(), profileID, c.Param("id"), req.toModel(profileID))
+	if err != nil {
+		respondPriceAlertError(c, "update price alert", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   alert,
+	})
+}
+
+// DeleteAlert deletes one of the member's price alerts and its history
+// @Summary Delete price alert
+// @Tags me
+// @Produce json
+// @Param id path string true "Alert ID"
+// @Success 200 {object} map[string]interface{} "Deleted"
+// @Router /api/me/alerts/{id} [delete]
+func (pc *PriceAlertController) DeleteAlert(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	if err := pc.priceAlertService.DeleteAlert(c.Request.Context(), profileID, c.Param("id")); err != nil {
+		respondPriceAlertError(c, "delete price alert", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// ListHistory returns the member's triggered alerts, newest first
+// @Summary Price alert history
+// @Tags me
+// @Produce json
+// @Param alert_id query string false "Only this alert"
+// @Param limit query int false "Maximum rows (default and max 500)"
+// @Success 200 {object} map[string]interface{} "Triggered alerts"
+// @Router /api/me/alerts/history [get]
+func (pc *PriceAlertController) ListHistory(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	filter := services.PriceAlertEventFilter{AlertID: c.Query("alert_id")}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	events, err := pc.priceAlertService.ListEvents(c.Request.Context(), profileID, filter)
+	if err != nil {
+		respondPriceAlertError(c, "fetch price alert history", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   events,
+	})
+}
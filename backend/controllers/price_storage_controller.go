@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// PriceStorageController handles price bucket storage encoding requests
+type PriceStorageController struct {
+	priceStorageService *services.PriceStorageService
+}
+
+// NewPriceStorageController creates a new price storage controller
+func NewPriceStorageController(priceStorageService *services.PriceStorageService) *PriceStorageController {
+	return &PriceStorageController{
+		priceStorageService: priceStorageService,
+	}
+}
+
+// GetStats returns the bucket count and size per storage encoding (JSON API)
+func (pc *PriceStorageController) GetStats(c *gin.Context) {
+	stats, err := pc.priceStorageService.Stats(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetStats failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get price storage stats",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// Convert re-encodes the selected price buckets (JSON API)
+func (pc *PriceStorageController) Convert(c *gin.Context) {
+	var opts services.ConvertOptions
+	if err := c.ShouldBindJSON(&opts); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	report, err := pc.priceStorageService.Convert(c.Request.Context(), opts)
+	if errors.Is(err, services.ErrInvalidPriceEncoding) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid encoding",
+			"details": err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, services.ErrBucketConflict) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Price bucket changed during conversion",
+			"details": err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Convert failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to convert price buckets",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
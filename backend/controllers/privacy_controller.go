@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PrivacyController handles data export and erasure requests, from members
+// (/api/me) and from admins on their behalf (/admin/api/profiles)
+type PrivacyController struct {
+	privacyService *services.PrivacyService
+}
+
+// NewPrivacyController creates a new privacy controller
+func NewPrivacyController(privacyService *services.PrivacyService) *PrivacyController {
+	return &PrivacyController{
+		privacyService: privacyService,
+	}
+}
+
+// ExportOwnData returns all data held about the member
+// @Summary Export my data
+// @Description Returns the member's profile, personal access tokens (without secrets), price alerts and their
+// @Description history, watchlists, portfolio trades, payments, Zalo messages and admin actions on the profile
+// @Tags me
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Data export"
+// @Router /api/me/data-export [get]
+func (pc *PrivacyController) ExportOwnData(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	export, err := pc.privacyService.Export(c.Request.Context(), profileID, "member:"+profileID.String(), "")
+	if err != nil {
+		if errors.Is(err, services.ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "Profile not found",
+			})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("Failed to export member data", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to export data",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="cpls-data-export.json"`)
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   export,
+	})
+}
+
+// EraseOwnData erases the member's personal data
+// @Summary Erase my data
+// @Description Deletes the member's tokens, alerts, watchlists, trades and Zalo messages and anonymizes the
+// @Description profile and payment records. Irreversible; confirm must repeat the profile's email.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body object true "confirm: the profile's email"
+// @Success 200 {object} map[string]interface{} "Erasure log entry"
+// @Router /api/me/erase [post]
+func (pc *PrivacyController) EraseOwnData(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Confirm string `json:"confirm"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	erasure, err := pc.privacyService.EraseOwn(c.Request.Context(), profileID, req.Confirm)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProfileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{
+				"status":  "error",
+				"message": "Profile not found",
+			})
+		case errors.Is(err, services.ErrErasureNotConfirmed):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Erasure not confirmed",
+				"error":   err.Error(),
+			})
+		case errors.Is(err, services.ErrProfileErased):
+			c.JSON(http.StatusConflict, gin.H{
+				"status":  "error",
+				"message": "Data already erased",
+			})
+		default:
+			logging.FromContext(c.Request.Context()).Error("Failed to erase member data", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"status":  "error",
+				"message": "Failed to erase data",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   erasure,
+	})
+}
+
+// ExportProfile returns all data held about a member (JSON API)
+func (pc *PrivacyController) ExportProfile(c *gin.Context) {
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return
+	}
+
+	actor, _ := sessions.Default(c).Get("user").(string)
+	export, err := pc.privacyService.Export(c.Request.Context(), profileID, actor, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, services.ErrProfileNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("ExportProfile failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to export profile data",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Profile data exported", "profile_id", profileID, "actor", actor)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    export,
+	})
+}
+
+// EraseProfile erases a member's personal data on their request (JSON API)
+func (pc *PrivacyController) EraseProfile(c *gin.Context) {
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	actor, _ := sessions.Default(c).Get("user").(string)
+	erasure, err := pc.privacyService.Erase(c.Request.Context(), profileID, actor, req.Reason, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrProfileNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+		case errors.Is(err, services.ErrProfileErased):
+			c.JSON(http.StatusConflict, gin.H{"error": "Profile data already erased"})
+		default:
+			logging.FromContext(c.Request.Context()).Error("EraseProfile failed", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to erase profile data",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Profile data erased", "profile_id", profileID, "actor", actor)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    erasure,
+	})
+}
+
+// ListErasures returns the data erasure log, newest first (JSON API)
+func (pc *PrivacyController) ListErasures(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	erasures, err := pc.privacyService.ListErasures(c.Request.Context(), c.Query("email"), limit)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListErasures failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch data erasures",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    erasures,
+		"total":   len(erasures),
+	})
+}
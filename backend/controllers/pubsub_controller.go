@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxPubSubPushBody caps the push body read into memory
+const maxPubSubPushBody = 64 << 10
+
+// PubSubController receives scheduled triggers pushed by Cloud Pub/Sub
+type PubSubController struct {
+	pubSubService *services.PubSubPushService
+}
+
+// NewPubSubController creates a new Pub/Sub controller
+func NewPubSubController(pubSubService *services.PubSubPushService) *PubSubController {
+	return &PubSubController{
+		pubSubService: pubSubService,
+	}
+}
+
+// HandlePush runs the trigger of a pushed Pub/Sub message
+// @Summary Pub/Sub push endpoint
+// @Description Messages carry the trigger (crawl, backup or digest) in a "trigger" attribute or as data {"trigger":"crawl"}.
+// @Description Authenticated by the subscription's OIDC token (PUBSUB_AUDIENCE, PUBSUB_SERVICE_ACCOUNT) or
+// @Description ?token=PUBSUB_VERIFICATION_TOKEN. Non-2xx responses make Pub/Sub redeliver the message.
+// @Tags pubsub
+// @Accept json
+// @Produce json
+// @Router /api/pubsub/push [post]
+func (pc *PubSubController) HandlePush(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPubSubPushBody))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"status":  "error",
+			"message": "Push body too large",
+		})
+		return
+	}
+
+	trigger, err := pc.pubSubService.HandlePush(c.Request.Context(), c.GetHeader("Authorization"), c.Query("token"), body)
+	switch {
+	case errors.Is(err, services.ErrPubSubNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "message": "Pub/Sub push is not configured"})
+	case errors.Is(err, services.ErrInvalidPubSubPush):
+		logging.FromContext(c.Request.Context()).Warn("Rejected Pub/Sub push", "client_ip", c.ClientIP(), logging.FieldError, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "Invalid push authentication"})
+	case errors.Is(err, services.ErrInvalidPubSubMessage):
+		// Pub/Sub redelivers until the message expires; dead-letter policies pick up messages that keep failing
+		c.JSON(http.StatusBadRequest, gin.H{"status": "error", "message": err.Error()})
+	case err != nil:
+		logging.FromContext(c.Request.Context()).Error("HandlePubSubPush failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to run trigger",
+			"error":   err.Error(),
+		})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "success", "data": gin.H{"trigger": trigger}})
+	}
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// RunbookController handles the recovery procedures of the admin runbook
+type RunbookController struct {
+	runbookService *services.RunbookService
+}
+
+// NewRunbookController creates a new runbook controller
+func NewRunbookController(runbookService *services.RunbookService) *RunbookController {
+	return &RunbookController{
+		runbookService: runbookService,
+	}
+}
+
+// ListActions returns the runbook actions with their last run on this
+// instance (JSON API)
+func (rc *RunbookController) ListActions(c *gin.Context) {
+	actions := rc.runbookService.Actions()
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    actions,
+		"total":   len(actions),
+	})
+}
+
+// RunAction runs a runbook action. The body repeats the action's name so a
+// stray request cannot run one (JSON API)
+// Body: {"confirm": "flush-caches"}
+func (rc *RunbookController) RunAction(c *gin.Context) {
+	var req struct {
+		Confirm string `json:"confirm"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	action := c.Param("action")
+	if req.Confirm != action {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Confirmation does not match the action",
+			"details": `Send {"confirm": "` + action + `"} to run it`,
+		})
+		return
+	}
+
+	actor, _ := sessions.Default(c).Get("user").(string)
+	result, err := rc.runbookService.Run(c.Request.Context(), action, actor, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownRunbookAction):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Runbook action not found"})
+		case errors.Is(err, services.ErrRunbookBusy):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Another runbook action is running",
+				"details": err.Error(),
+			})
+		case errors.Is(err, services.ErrRunbookCooldown):
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Runbook action ran too recently",
+				"details": err.Error(),
+			})
+		default:
+			logging.FromContext(c.Request.Context()).Error("RunAction failed", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to run runbook action",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Runbook action run", "action", action, "ok", result.OK, "actor", actor)
+	c.JSON(http.StatusOK, gin.H{
+		"success": result.OK,
+		"data":    result,
+	})
+}
@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ScreenerPresetController handles saved screener presets: members' own
+// (/api/me/screens) and the shared ones managed by admins
+// (/admin/api/screener-presets)
+type ScreenerPresetController struct {
+	presetService *services.ScreenerPresetService
+}
+
+// NewScreenerPresetController creates a new screener preset controller
+func NewScreenerPresetController(presetService *services.ScreenerPresetService) *ScreenerPresetController {
+	return &ScreenerPresetController{
+		presetService: presetService,
+	}
+}
+
+// screenerPresetRequest is the JSON body accepted when creating or updating a preset
+type screenerPresetRequest struct {
+	Name     string `json:"name"`
+	Criteria string `json:"criteria"`
+	Notify   bool   `json:"notify"`
+	Channels string `json:"channels"`
+}
+
+// toModel converts the request into a preset of owner (nil: shared)
+func (r screenerPresetRequest) toModel(owner *uuid.UUID) models.ScreenerPreset {
+	return models.ScreenerPreset{
+		ProfileID: owner,
+		Name:      r.Name,
+		Criteria:  r.Criteria,
+		Notify:    r.Notify,
+		Channels:  r.Channels,
+	}
+}
+
+// respondScreenerPresetError maps screener preset service errors to responses
+func respondScreenerPresetError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrScreenerPresetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Screener preset not found",
+		})
+	case errors.Is(err, services.ErrInvalidScreenerPreset):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid screener preset",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, services.ErrScreenerPresetLimit):
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  "error",
+			"message": "Too many screener presets",
+			"error":   err.Error(),
+		})
+	default:
+		logging.FromContext(c.Request.Context()).Error(action+" failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to " + action,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// ListPresets returns the member's presets followed by the shared ones
+// @Summary List screener presets
+// @Description Lists the member's saved screens and the presets shared by admins (without profile_id),
+// @Description plus the delivery channels for notifications
+// @Tags me
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Screener presets"
+// @Router /api/me/screens [get]
+func (sc *ScreenerPresetController) ListPresets(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	presets, err := sc.presetService.ListPresets(c.Request.Context(), &profileID)
+	if err != nil {
+		respondScreenerPresetError(c, "fetch screener presets", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"data":     presets,
+		"channels": sc.presetService.Channels(),
+	})
+}
+
+// CreatePreset saves a screen
+// @Summary Create screener preset
+// @Description Saves named screener criteria: the query string of /api/market/screener, e.g.
+// @Description "exchange=HOSE&min_relative_volume=2&sort=-relative_volume". With notify, the preset is
+// @Description re-run after every crawl and the listed channels are told of symbols that start matching.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param request body object true "name, criteria, optional notify and channels (comma-separated)"
+// @Success 201 {object} map[string]interface{} "Created preset"
+// @Router /api/me/screens [post]
+func (sc *ScreenerPresetController) CreatePreset(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req screenerPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	preset := req.toModel(&profileID)
+	if err := sc.presetService.CreatePreset(c.Request.Context(), &preset); err != nil {
+		respondScreenerPresetError(c, "create screener preset", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   preset,
+	})
+}
+
+// UpdatePreset updates one of the member's presets
+// @Summary Update screener preset
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param id path string true "Preset ID"
+// @Param request body object true "name, criteria, optional notify and channels"
+// @Success 200 {object} map[string]interface{} "Updated preset"
+// @Router /api/me/screens/{id} [put]
+func (sc *ScreenerPresetController) UpdatePreset(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req screenerPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	preset, err := sc.presetService.UpdatePreset(c.Request.Context(), &profileID, c.Param("id"), req.toModel(&profileID))
+	if err != nil {
+		respondScreenerPresetError(c, "update screener preset", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   preset,
+	})
+}
+
+// DeletePreset deletes one of the member's presets
+// @Summary Delete screener preset
+// @Tags me
+// @Produce json
+// @Param id path string true "Preset ID"
+// @Success 200 {object} map[string]interface{} "Deleted"
+// @Router /api/me/screens/{id} [delete]
+func (sc *ScreenerPresetController) DeletePreset(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	if err := sc.presetService.DeletePreset(c.Request.Context(), &profileID, c.Param("id")); err != nil {
+		respondScreenerPresetError(c, "delete screener preset", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// RunPreset screens symbols with a saved preset
+// @Summary Run screener preset
+// @Description Runs one of the member's presets or a shared preset and returns the matching symbols
+// @Tags me
+// @Produce json
+// @Param id path string true "Preset ID"
+// @Success 200 {object} map[string]interface{} "Matching symbols"
+// @Router /api/me/screens/{id}/run [get]
+func (sc *ScreenerPresetController) RunPreset(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	preset, rows, err := sc.presetService.RunPreset(c.Request.Context(), &profileID, c.Param("id"))
+	if err != nil {
+		respondScreenerPresetError(c, "run screener preset", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"preset": preset,
+		"total":  len(rows),
+		"data":   rows,
+	})
+}
+
+// ListSharedPresets returns the presets shared with every member (JSON API)
+func (sc *ScreenerPresetController) ListSharedPresets(c *gin.Context) {
+	presets, err := sc.presetService.ListPresets(c.Request.Context(), nil)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListSharedPresets failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch screener presets",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    presets,
+		"total":   len(presets),
+	})
+}
+
+// CreateSharedPreset shares a new preset with every member (JSON API)
+func (sc *ScreenerPresetController) CreateSharedPreset(c *gin.Context) {
+	var req screenerPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	preset := req.toModel(nil)
+	if err := sc.presetService.CreatePreset(c.Request.Context(), &preset); err != nil {
+		respondSharedPresetError(c, "CreateSharedPreset", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    preset,
+	})
+}
+
+// UpdateSharedPreset updates a shared preset (JSON API)
+func (sc *ScreenerPresetController) UpdateSharedPreset(c *gin.Context) {
+	var req screenerPresetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	preset, err := sc.presetService.UpdatePreset(c.Request.Context(), nil, c.Param("id"), req.toModel(nil))
+	if err != nil {
+		respondSharedPresetError(c, "UpdateSharedPreset", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    preset,
+	})
+}
+
+// DeleteSharedPreset deletes a shared preset (JSON API)
+func (sc *ScreenerPresetController) DeleteSharedPreset(c *gin.Context) {
+	if err := sc.presetService.DeletePreset(c.Request.Context(), nil, c.Param("id")); err != nil {
+		respondSharedPresetError(c, "DeleteSharedPreset", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// respondSharedPresetError maps screener preset service errors to admin API responses
+func respondSharedPresetError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrScreenerPresetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Screener preset not found"})
+	case errors.Is(err, services.ErrInvalidScreenerPreset):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid screener preset",
+			"details": err.Error(),
+		})
+	default:
+		logging.FromContext(c.Request.Context()).Error(action+" failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save screener preset",
+			"details": err.Error(),
+		})
+	}
+}
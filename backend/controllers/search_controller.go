@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SearchController serves the dashboard's global search box
+type SearchController struct {
+	searchService *services.SearchService
+}
+
+// NewSearchController creates a new search controller
+func NewSearchController(searchService *services.SearchService) *SearchController {
+	return &SearchController{
+		searchService: searchService,
+	}
+}
+
+// Search returns the admin users, profiles (email, name or phone), stocks
+// (symbol or company name) and crawl runs (ID, status or failed symbol)
+// matching q, grouped by type (JSON API)
+// Query params: q (at least 2 characters), limit per group (default 5, max 20)
+func (sc *SearchController) Search(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	groups, err := sc.searchService.Search(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrSearchQueryTooShort) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid q",
+				"details": err.Error(),
+			})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("Search failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	total := 0
+	for _, group := range groups {
+		total += len(group.Hits)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    groups,
+		"total":   total,
+	})
+}
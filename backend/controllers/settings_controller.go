@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsController handles runtime configuration management
+type SettingsController struct {
+	settingsService *services.SettingsService
+}
+
+// NewSettingsController creates a new settings controller
+func NewSettingsController(settingsService *services.SettingsService) *SettingsController {
+	return &SettingsController{
+		settingsService: settingsService,
+	}
+}
+
+// GetConfig returns the effective runtime configuration, the stored
+// overrides and the list of reloadable settings (JSON API)
+func (sc *SettingsController) GetConfig(c *gin.Context) {
+	stored, err := sc.settingsService.ListStored(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetConfig failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch settings",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      config.Runtime(),
+		"stored":    stored,
+		"available": config.RuntimeSettings,
+	})
+}
+
+// SetSetting stores a setting and reloads the configuration (JSON API)
+func (sc *SettingsController) SetSetting(c *gin.Context) {
+	var req struct {
+		Value *string `json:"value" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": "value is required",
+		})
+		return
+	}
+
+	key := c.Param("key")
+	if !config.IsRuntimeSetting(key) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unknown setting",
+			"details": key,
+		})
+		return
+	}
+
+	updatedBy, _ := sessions.Default(c).Get("user").(string)
+	cfg, err := sc.settingsService.Set(c.Request.Context(), key, *req.Value, updatedBy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to update setting",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Setting updated and configuration reloaded", "key", key, "actor", updatedBy)
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cfg,
+	})
+}
+
+// DeleteSetting removes a stored override and reloads the configuration (JSON API)
+func (sc *SettingsController) DeleteSetting(c *gin.Context) {
+	cfg, err := sc.settingsService.Delete(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		if errors.Is(err, services.ErrSettingNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Setting not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete setting",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cfg,
+	})
+}
+
+// Reload re-reads the settings store and swaps in the new configuration
+// without restarting the instance or dropping sessions (JSON API)
+func (sc *SettingsController) Reload(c *gin.Context) {
+	cfg, err := sc.settingsService.Reload(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("Reload failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reload configuration (current configuration kept)",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Runtime configuration reloaded via admin API")
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    cfg,
+	})
+}
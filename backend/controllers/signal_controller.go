@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SignalController handles trading signal requests
+type SignalController struct {
+	signalService *services.SignalService
+}
+
+// NewSignalController creates a new signal controller
+func NewSignalController(signalService *services.SignalService) *SignalController {
+	return &SignalController{
+		signalService: signalService,
+	}
+}
+
+// ListSignals returns the signals fired on a date
+// @Summary Trading signals
+// @Description Returns the signals fired by the daily candles of a date, computed after each crawl:
+// @Description golden_cross (fast SMA crossing above the slow SMA), breakout_52w_high (close above the previous
+// @Description 52-week high), volume_spike (volume a multiple of its 20-session average) and candle_pattern
+// @Description (the candlestick patterns the candle completed, in patterns)
+// @Tags stocks
+// @Produce json
+// @Param date query string false "today (default) or YYYY-MM-DD"
+// @Param type query string false "Only this signal type"
+// @Param code query string false "Only this stock"
+// @Success 200 {object} map[string]interface{} "Signals"
+// @Router /api/signals [get]
+func (sc *SignalController) ListSignals(c *gin.Context) {
+	date, err := services.ParseSignalDate(c.Query("date"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid 'date' parameter",
+			"error":   err.Error(),
+		})
+		return
+	}
+	signalType := strings.ToLower(strings.TrimSpace(c.Query("type")))
+	if signalType != "" && !models.ValidSignalType(signalType) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid 'type' parameter",
+			"error":   "expected one of " + strings.Join(models.SignalTypes, ", "),
+		})
+		return
+	}
+
+	signals, err := sc.signalService.List(c.Request.Context(), services.SignalFilter{
+		Date: date,
+		Type: signalType,
+		Code: strings.TrimSpace(c.Query("code")),
+	})
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListSignals failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get signals",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"date":   date,
+		"data":   signals,
+		"total":  len(signals),
+	})
+}
+
+// ListPatterns returns the candlestick patterns of a stock's recent candles
+// @Summary Candlestick patterns
+// @Description Scans the newest sessions of a stock for doji, hammer, shooting_star, bullish_engulfing,
+// @Description bearish_engulfing, morning_star and evening_star, newest first, each with its direction
+// @Description (bullish, bearish or neutral). The newest session's patterns are also candle_pattern signals.
+// @Tags stocks
+// @Produce json
+// @Param code query string true "Stock code"
+// @Param sessions query int false "Newest sessions scanned (default 20, max 250)"
+// @Param pattern query string false "Only this pattern"
+// @Success 200 {object} map[string]interface{} "Patterns"
+// @Router /api/patterns [get]
+func (sc *SignalController) ListPatterns(c *gin.Context) {
+	query, err := services.ParsePatternQuery(c.Query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid pattern query",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	patterns, err := sc.signalService.Patterns(c.Request.Context(), query)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListPatterns failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get candlestick patterns",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"code":     query.Code,
+		"sessions": query.Sessions,
+		"data":     patterns,
+		"total":    len(patterns),
+	})
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// StatusController serves the public status page data
+type StatusController struct {
+	statusService *services.StatusService
+}
+
+// NewStatusController creates a new status controller
+func NewStatusController(statusService *services.StatusService) *StatusController {
+	return &StatusController{
+		statusService: statusService,
+	}
+}
+
+// GetStatus summarizes uptime, the last successful crawl, data freshness per
+// exchange and open incidents. Unauthenticated and cached, for embedding in
+// a public status page.
+// @Summary Public service status
+// @Tags status
+// @Produce json
+// @Router /status [get]
+func (sc *StatusController) GetStatus(c *gin.Context) {
+	status, err := sc.statusService.Status(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetStatus failed", logging.FieldError, err)
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": services.StatusUnknown})
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(services.StatusCacheTTL.Seconds())))
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,643 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/format"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// stockDetailDays is how many days of candles the stock detail includes
+const stockDetailDays = 90
+
+// StockController handles stock universe HTTP requests
+type StockController struct {
+	stockService     services.PriceStore
+	symbolService    *services.SymbolService
+	sparklineService *services.SparklineService
+	metricsService   *services.StockMetricsService
+	maService        *services.MovingAverageService
+}
+
+// NewStockController creates a new stock controller
+func NewStockController(stockService services.PriceStore, symbolService *services.SymbolService, sparklineService *services.SparklineService, metricsService *services.StockMetricsService, maService *services.MovingAverageService) *StockController {
+	return &StockController{
+		stockService:     stockService,
+		symbolService:    symbolService,
+		sparklineService: sparklineService,
+		metricsService:   metricsService,
+		maService:        maService,
+	}
+}
+
+// GetMetadata returns the stock list with company metadata and change timestamps
+// @Summary Bulk stock metadata
+// @Description Returns the full stock list, or only stocks changed after ?since= (RFC3339).
+// @Description Pass the returned next_since value as ?since= on the next call to mirror changes.
+// @Description With Accept: application/x-ndjson the stocks are streamed one per line and next_since
+// @Description is returned in the X-Next-Since header. Filters match ignoring case and keep next_since,
+// @Description so a filtered mirror resumes like an unfiltered one.
+// @Tags stocks
+// @Accept json
+// @Produce json
+// @Produce x-ndjson
+// @Param since query string false "Only return stocks updated after this RFC3339 timestamp"
+// @Param exchange query string false "Only this exchange (HOSE, HNX, UPCOM)"
+// @Param industry query string false "Only this ICB industry (level 1)"
+// @Param sector query string false "Only this ICB sector (level 2)"
+// @Param type query string false "Only this instrument type (stock, etf, cw, bond)"
+// @Success 200 {object} map[string]interface{} "Stock metadata"
+// @Router /api/stocks/metadata [get]
+func (sc *StockController) GetMetadata(c *gin.Context) {
+	var since *time.Time
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid 'since' parameter, expected RFC3339 timestamp (e.g. 2024-01-15T00:00:00Z)",
+				"error":   err.Error(),
+			})
+			return
+		}
+		since = &parsed
+	}
+	filter := services.ParseStockFilter(c.Query)
+
+	if wantsNDJSON(c) {
+		c.Header("X-Next-Since", services.MetadataCursor(since).Format(time.RFC3339Nano))
+		streamNDJSON(c, func(emit func(row interface{}) error) error {
+			_, err := sc.stockService.StreamStockMetadata(c.Request.Context(), since, func(stock models.Stock) error {
+				if !filter.Matches(stock) {
+					return nil
+				}
+				return emit(stock)
+			})
+			return err
+		})
+		return
+	}
+
+	result, err := sc.stockService.GetStockMetadata(c.Request.Context(), since)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get stock metadata",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   services.FilterStockMetadata(result, filter),
+	})
+}
+
+// GetSectors returns the ICB industries and sectors of the listed stocks
+// @Summary Industry classification
+// @Description Lists the ICB industries (level 1) with their sectors (level 2) and how many stocks each
+// @Description classifies: the values the stock list and screener accept as ?industry= and ?sector=.
+// @Description Sectors crawled before industries were stored are listed under an empty industry.
+// @Tags stocks
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Industries with their sectors"
+// @Router /api/stocks/sectors [get]
+func (sc *StockController) GetSectors(c *gin.Context) {
+	result, err := sc.stockService.GetStockMetadata(c.Request.Context(), nil)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetSectors failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get industry classification",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	industries := services.SummarizeClassification(result.Stocks)
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   industries,
+		"total":  len(industries),
+	})
+}
+
+// Search returns the stocks matching q for autocomplete
+// @Summary Search stocks
+// @Description Matches codes starting with q and Vietnamese or English company names containing it,
+// @Description ignoring case and diacritics ("hoa phat group" finds HPG). Code matches come first.
+// @Tags stocks
+// @Produce json
+// @Param q query string true "Code or company name (at least 2 characters)"
+// @Param limit query int false "Maximum results (default 5, max 20)"
+// @Success 200 {object} map[string]interface{} "Matching stocks"
+// @Router /api/stocks/search [get]
+func (sc *StockController) Search(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	stocks, err := sc.stockService.SearchStocks(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrSearchQueryTooShort) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid 'q' parameter",
+				"error":   err.Error(),
+			})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("Stock search failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to search stocks",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   stocks,
+		"total":  len(stocks),
+	})
+}
+
+// GetCandles returns the daily candles of a stock
+// @Summary Daily candles
+// @Description Returns daily OHLCV candles (prices in thousands of đồng) between ?from= and ?to=
+// @Description (YYYY-MM-DD, default: the last 365 days). With ?locale=vi each candle also carries
+// @Description Vietnamese display strings (e.g. "25,50", "1,23 triệu cp", "+2,35%").
+// @Description Former tickers resolve to the current one, and history before a rename is included.
+// @Description Responses carry an ETag; send it as If-None-Match to get 304 Not Modified while unchanged.
+// @Description With Accept: application/x-ndjson the candles are streamed one per line as they are read
+// @Description and the resolved code and its lineage are returned in the X-Symbol and X-Symbol-Lineage headers.
+// @Tags stocks
+// @Produce json
+// @Produce x-ndjson
+// @Param code path string true "Stock code"
+// @Param from query string false "First date (YYYY-MM-DD)"
+// @Param to query string false "Last date (YYYY-MM-DD)"
+// @Param locale query string false "vi for Vietnamese display strings"
+// @Success 200 {object} map[string]interface{} "Candles"
+// @Router /api/stocks/{code}/candles [get]
+func (sc *StockController) GetCandles(c *gin.Context) {
+	sc.serveCandles(c, sc.stockService.GetCandles)
+}
+
+// GetCandlesFilteredInDB serves GetCandles with the date range applied in
+// MongoDB. It is the candidate of the "candles" canary route.
+func (sc *StockController) GetCandlesFilteredInDB(c *gin.Context) {
+	sc.serveCandles(c, sc.stockService.GetCandlesFilteredInDB)
+}
+
+// serveCandles answers a candles request, reading the candles with read
+func (sc *StockController) serveCandles(c *gin.Context, read func(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error)) {
+	to := markettime.Now()
+	from := to.AddDate(-1, 0, 0)
+	for param, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid '" + param + "' parameter, expected YYYY-MM-DD",
+				"error":   err.Error(),
+			})
+			return
+		}
+		*target = parsed
+	}
+	if from.After(to) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "'from' must not be after 'to'",
+		})
+		return
+	}
+	from = limitHistoryTime(c, from)
+
+	symbol, err := sc.symbolService.ResolveAsOf(c.Request.Context(), c.Param("code"), to.Format("2006-01-02"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{
+			"status":  "error",
+			"message": "Failed to resolve stock code",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Unchanged candles are answered with 304 without reading them
+	dataTag, err := sc.stockService.CandlesETag(c.Request.Context(), symbol.ReadCodes(), from, to)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Warn("Candles ETag lookup failed", logging.FieldError, err)
+	}
+	if dataTag != "" {
+		symbolJSON, _ := json.Marshal(symbol)
+		if notModified(c, representationTag(c, dataTag, string(symbolJSON))) {
+			return
+		}
+	}
+
+	if wantsNDJSON(c) {
+		sc.streamCandles(c, symbol, from, to)
+		return
+	}
+
+	candles, err := read(c.Request.Context(), symbol.ReadCodes(), from, to)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{
+			"status":  "error",
+			"message": "Failed to get candles",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if wantsVietnameseDisplay(c) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "success",
+			"data":   vietnameseCandles(candles),
+			"symbol": symbol,
+			"units":  format.Units,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   candles,
+		"symbol": symbol,
+	})
+}
+
+// streamCandles answers a candles request with Accept: application/x-ndjson,
+// one candle per line as the buckets are read. The current code and the
+// lineage of the resolved symbol are sent in the X-Symbol and
+// X-Symbol-Lineage headers.
+func (sc *StockController) streamCandles(c *gin.Context, symbol *services.SymbolResolution, from, to time.Time) {
+	c.Header("X-Symbol", symbol.Code)
+	c.Header("X-Symbol-Lineage", strings.Join(symbol.Lineage, ","))
+	vietnamese := wantsVietnameseDisplay(c)
+
+	streamNDJSON(c, func(emit func(row interface{}) error) error {
+		var previous *models.CandleData
+		_, err := sc.stockService.StreamCandles(c.Request.Context(), symbol.ReadCodes(), from, to, func(candle models.CandleData) error {
+			row := interface{}(candle)
+			if vietnamese {
+				row = vietnameseCandle(candle, previous)
+				previous = &candle
+			}
+			return emit(row)
+		})
+		return err
+	})
+}
+
+// GetSparklines returns the recent closes of several stocks for watchlists
+// @Summary Sparklines
+// @Description Returns the last 30 daily closes (oldest first) of each requested stock in one small payload,
+// @Description in request order. Stocks without stored candles are left out. Updated after every crawl.
+// @Description Responses carry an ETag; send it as If-None-Match to get 304 Not Modified while unchanged.
+// @Tags stocks
+// @Produce json
+// @Param codes query string true "Comma-separated stock codes (at most 100)"
+// @Success 200 {object} map[string]interface{} "Sparklines"
+// @Router /api/stocks/sparklines [get]
+func (sc *StockController) GetSparklines(c *gin.Context) {
+	codes, err := services.ParseSparklineCodes(c.Query("codes"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid 'codes' parameter",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	sparklines, err := sc.sparklineService.GetSparklines(c.Request.Context(), codes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get sparklines",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// Polling watchlists get 304 until a crawl rebuilds one of their sparklines
+	parts := make([]string, 0, len(sparklines))
+	for _, sparkline := range sparklines {
+		parts = append(parts, sparkline.Code+":"+strconv.FormatInt(int64(sparkline.UpdatedAt), 10))
+	}
+	if notModified(c, representationTag(c, parts...)) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   sparklines,
+	})
+}
+
+// GetMovingAverages returns the precomputed moving averages of a stock for
+// charts
+// @Summary Moving averages
+// @Description Returns the MA10, MA20, MA50 and MA200 of the close for the last 250 sessions (oldest first),
+// @Description rebuilt after every crawl that stores new candles, optionally between ?from= and ?to=. An
+// @Description average is left out of a session until the stock has traded for its period.
+// @Tags stocks
+// @Produce json
+// @Param code path string true "Stock code"
+// @Param from query string false "First date (YYYY-MM-DD)"
+// @Param to query string false "Last date (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{} "Moving averages"
+// @Router /api/stocks/{code}/moving-averages [get]
+func (sc *StockController) GetMovingAverages(c *gin.Context) {
+	for _, param := range []string{"from", "to"} {
+		if raw := c.Query(param); raw != "" {
+			if _, err := time.Parse("2006-01-02", raw); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"status":  "error",
+					"message": "Invalid '" + param + "' parameter, expected YYYY-MM-DD",
+					"error":   err.Error(),
+				})
+				return
+			}
+		}
+	}
+
+	series, err := sc.maService.Get(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("GetMovingAverages failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to get moving averages",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if series == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "No moving averages for this stock",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"code":    series.Code,
+		"periods": models.MovingAveragePeriods,
+		"data":    series.Between(limitHistoryDate(c, c.Query("from")), c.Query("to")),
+	})
+}
+
+// GetDetail returns a stock's listing, exchange state, recent candles and
+// liquidity metrics
+// @Summary Stock detail
+// @Description Resolves the ticker, then combines its listing, its exchange's trading state and the last
+// @Description 90 days of candles with the latest close and change. The listing and candles are optional
+// @Description parts: when one does not answer within the time budget (composite.timeout) it is left out
+// @Description and the response has "partial": true with a warning. The daily liquidity metrics are
+// @Description included when computed; with ?position= the sessions needed to trade that many shares too.
+// @Description market_cap is the latest close × the listed shares, when the share count is known.
+// @Description range_52w is the 52-week high and low with the latest close's distance from them.
+// @Tags stocks
+// @Produce json
+// @Param code path string true "Current or former stock code"
+// @Param position query int false "Position size in shares, adds days_to_cover"
+// @Success 200 {object} map[string]interface{} "Stock detail"
+// @Router /api/stocks/{code}/detail [get]
+func (sc *StockController) GetDetail(c *gin.Context) {
+	budget := services.NewDeadlineBudget(c.Request.Context(), "stock detail", config.Runtime().CompositeTimeout)
+
+	// The listing and candles are looked up under the current ticker
+	code := c.Param("code")
+	resolved := services.Fetch(budget, "symbol", 0.3, true, func(ctx context.Context) (*services.SymbolResolution, error) {
+		return sc.symbolService.Resolve(ctx, code)
+	})
+	if report := budget.Wait(); report.Err != nil {
+		respondComposite(c, report, "Failed to resolve stock code", nil)
+		return
+	}
+	symbol, _ := resolved.Value()
+
+	to := markettime.Now()
+	listing := services.Fetch(budget, "listing", 1, false, func(ctx context.Context) (*models.Stock, error) {
+		stock, err := sc.stockService.GetStock(ctx, symbol.Code)
+		if errors.Is(err, services.ErrStockNotFound) {
+			return nil, nil // A former ticker whose history is kept under a delisted code
+		}
+		return stock, err
+	})
+	history := services.Fetch(budget, "candles", 1, false, func(ctx context.Context) ([]models.CandleData, error) {
+		return sc.stockService.GetCandles(ctx, symbol.ReadCodes(), to.AddDate(0, 0, -stockDetailDays), to)
+	})
+	liquidity := services.Fetch(budget, "metrics", 1, false, func(ctx context.Context) (*models.LiquidityMetrics, error) {
+		return sc.metricsService.Get(ctx, symbol.Code)
+	})
+	report := budget.Wait()
+
+	data := gin.H{"symbol": symbol}
+	var shares int64
+	if stock, ok := listing.Value(); ok {
+		data["stock"] = stock
+		if stock != nil {
+			shares = stock.SharesOutstanding
+			if exchange, found := models.LookupExchange(stock.Exchange); found {
+				data["exchange"] = statusOf(exchange, time.Now())
+			}
+		}
+	}
+	if candles, ok := history.Value(); ok {
+		data["candles"] = candles
+		if n := len(candles); n > 0 {
+			data["latest"] = candles[n-1]
+			if n > 1 && candles[n-2].C != 0 {
+				data["change_percent"] = (candles[n-1].C - candles[n-2].C) / candles[n-2].C * 100
+			}
+			if marketCap := models.MarketCap(candles[n-1].C, shares); marketCap > 0 {
+				data["market_cap"] = marketCap
+			}
+		}
+	}
+	if metrics, ok := liquidity.Value(); ok && metrics != nil {
+		data["metrics"] = metrics
+		if metrics.Range52W != nil {
+			data["range_52w"] = metrics.Range52W
+		}
+		if position, err := strconv.ParseInt(c.Query("position"), 10, 64); err == nil && position > 0 {
+			data["days_to_cover"] = models.DaysToCover(position, metrics.AvgVolume)
+		}
+	}
+	respondComposite(c, report, "Failed to build stock detail", data)
+}
+
+// GetSymbolHistory returns the renames and exchange transfers of a stock
+// @Summary Symbol history
+// @Description Resolves a current or former ticker and lists its renames and exchange transfers with effective dates
+// @Tags stocks
+// @Produce json
+// @Param code path string true "Current or former stock code"
+// @Success 200 {object} map[string]interface{} "Symbol resolution"
+// @Router /api/stocks/{code}/symbol-history [get]
+func (sc *StockController) GetSymbolHistory(c *gin.Context) {
+	symbol, err := sc.symbolService.Resolve(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{
+			"status":  "error",
+			"message": "Failed to resolve stock code",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   symbol,
+	})
+}
+
+// historyCSVContentType is the content type of CSV candle histories
+const historyCSVContentType = "text/csv"
+
+// GetHistory returns the candle history of a stock in the shape of vnstock
+// and yfinance-style clients
+// @Summary Candle history (vnstock/yfinance-compatible)
+// @Description Returns OHLCV bars with the columns time, open, high, low, close, volume (ISO dates, prices in
+// @Description thousands of đồng), oldest first, so notebooks written for vnstock's Quote.history or yfinance
+// @Description can read it with few changes. The range is ?start= and ?end= (YYYY-MM-DD, both included) or a
+// @Description yfinance ?period= (5d, 1mo, 3mo, 6mo, 1y, 2y, 5y, 10y, ytd, max; default 1y) ending today.
+// @Description ?interval= is 1D, 1W or 1M (also 1d, 1wk, 1mo); weekly and monthly bars are dated at their first session.
+// @Description With ?format=csv or Accept: text/csv the bars are returned as CSV with a header row, for pandas.read_csv.
+// @Tags stocks
+// @Produce json
+// @Produce text/csv
+// @Param code path string true "Stock code"
+// @Param start query string false "First date (YYYY-MM-DD)"
+// @Param end query string false "Last date (YYYY-MM-DD, default: today)"
+// @Param period query string false "yfinance-style period, instead of start"
+// @Param interval query string false "1D, 1W or 1M"
+// @Param format query string false "csv for CSV"
+// @Success 200 {object} map[string]interface{} "Bars"
+// @Router /api/stocks/{code}/history [get]
+func (sc *StockController) GetHistory(c *gin.Context) {
+	interval, err := models.ParseCandleInterval(c.Query("interval"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid 'interval' parameter",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	end := markettime.Now()
+	if raw := c.Query("end"); raw != "" {
+		if end, err = time.Parse("2006-01-02", raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid 'end' parameter, expected YYYY-MM-DD",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+	var start time.Time
+	if raw := c.Query("start"); raw != "" {
+		if start, err = time.Parse("2006-01-02", raw); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid 'start' parameter, expected YYYY-MM-DD",
+				"error":   err.Error(),
+			})
+			return
+		}
+	} else {
+		period := c.DefaultQuery("period", "1y")
+		if start, err = models.HistoryPeriodStart(period, end); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": "Invalid 'period' parameter",
+				"error":   err.Error(),
+			})
+			return
+		}
+	}
+	if start.After(end) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "'start' must not be after 'end'",
+		})
+		return
+	}
+	start = limitHistoryTime(c, start)
+
+	symbol, err := sc.symbolService.ResolveAsOf(c.Request.Context(), c.Param("code"), end.Format("2006-01-02"))
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{
+			"status":  "error",
+			"message": "Failed to resolve stock code",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	candles, err := sc.stockService.GetCandles(c.Request.Context(), symbol.ReadCodes(), start, end)
+	if err != nil {
+		c.JSON(errorStatus(err), gin.H{
+			"status":  "error",
+			"message": "Failed to get candles",
+			"error":   err.Error(),
+		})
+		return
+	}
+	bars := models.ResampleCandles(candles, interval)
+
+	if c.Query("format") == "csv" || strings.Contains(c.GetHeader("Accept"), historyCSVContentType) {
+		c.Header("X-Symbol", symbol.Code)
+		writeHistoryCSV(c, bars)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":   "success",
+		"data":     bars,
+		"symbol":   symbol,
+		"interval": interval,
+	})
+}
+
+// writeHistoryCSV answers 200 with bars as CSV under a header row
+func writeHistoryCSV(c *gin.Context, bars []models.HistoryBar) {
+	c.Header("Content-Type", historyCSVContentType+"; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(models.HistoryColumns)
+	for _, bar := range bars {
+		_ = w.Write([]string{
+			bar.Time,
+			strconv.FormatFloat(bar.Open, 'f', -1, 64),
+			strconv.FormatFloat(bar.High, 'f', -1, 64),
+			strconv.FormatFloat(bar.Low, 'f', -1, 64),
+			strconv.FormatFloat(bar.Close, 'f', -1, 64),
+			strconv.FormatInt(bar.Volume, 10),
+		})
+	}
+	w.Flush()
+}
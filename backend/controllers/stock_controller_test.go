@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services/servicestest"
+	"github.com/gin-gonic/gin"
+)
+
+func TestStockSearch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prices := &servicestest.PriceStore{Stocks: []models.Stock{
+		{Code: "HPG", CompanyName: "Hoa Phat Group", Exchange: "HOSE"},
+		{Code: "HSG", CompanyName: "Hoa Sen Group", Exchange: "HOSE"},
+		{Code: "VNM", CompanyName: "Vinamilk", Exchange: "HOSE"},
+	}}
+	router := gin.New()
+	router.GET("/api/stocks/search", NewStockController(prices, nil, nil, nil, nil).Search)
+
+	tests := []struct {
+		query  string
+		status int
+		codes  []string
+	}{
+		{"?q=hoa", http.StatusOK, []string{"HPG", "HSG"}},
+		{"?q=hoa&limit=1", http.StatusOK, []string{"HPG"}},
+		{"?q=v", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stocks/search"+tt.query, nil))
+		if rec.Code != tt.status {
+			t.Errorf("search%s status = %d; want %d", tt.query, rec.Code, tt.status)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var body struct {
+			Data []models.Stock `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		var codes []string
+		for _, stock := range body.Data {
+			codes = append(codes, stock.Code)
+		}
+		if len(codes) != len(tt.codes) || (len(codes) > 0 && codes[0] != tt.codes[0]) {
+			t.Errorf("search%s = %v; want %v", tt.query, codes, tt.codes)
+		}
+	}
+}
+
+func TestStockMetadataFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prices := &servicestest.PriceStore{Stocks: []models.Stock{
+		{Code: "HPG", Exchange: "HOSE", Industry: "Basic Materials", Sector: "Basic Resources"},
+		{Code: "SHS", Exchange: "HNX", Industry: "Financials", Sector: "Financial Services"},
+		{Code: "VCB", Exchange: "HOSE", Industry: "Financials", Sector: "Banks"},
+	}}
+	router := gin.New()
+	router.GET("/api/stocks/metadata", NewStockController(prices, nil, nil, nil, nil).GetMetadata)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/stocks/metadata?industry=financials&exchange=HOSE", nil))
+	var body struct {
+		Data struct {
+			Count  int            `json:"count"`
+			Stocks []models.Stock `json:"stocks"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if body.Data.Count != 1 || body.Data.Stocks[0].Code != "VCB" {
+		t.Errorf("metadata?industry=financials&exchange=HOSE = %+v; want VCB", body.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/stocks/metadata?sector=Basic+Resources", nil)
+	req.Header.Set("Accept", "application/x-ndjson")
+	router.ServeHTTP(rec, req)
+	if lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"HPG"`) {
+		t.Errorf("streamed metadata?sector=Basic+Resources = %q; want HPG only", rec.Body.String())
+	}
+}
@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// streamHeartbeat is how often idle streams send a keep-alive so proxies
+// (Cloud Run, nginx) do not close them
+const streamHeartbeat = 30 * time.Second
+
+// StreamController pushes price updates to WebSocket and SSE clients
+type StreamController struct {
+	priceStreamService *services.PriceStreamService
+	allowedOrigins     []string // Browser origins besides the API's own that may open WebSockets
+}
+
+// NewStreamController creates a new stream controller. allowedOrigins lists
+// the web app origins (e.g. "https://cpls.vn") allowed to open WebSockets;
+// "*" allows any.
+func NewStreamController(priceStreamService *services.PriceStreamService, allowedOrigins []string) *StreamController {
+	return &StreamController{
+		priceStreamService: priceStreamService,
+		allowedOrigins:     allowedOrigins,
+	}
+}
+
+// streamMessage is one message sent to or received from a WebSocket client
+type streamMessage struct {
+	Type  string                `json:"type"` // update, subscribed, ping (server); subscribe, unsubscribe (client)
+	Codes []string              `json:"codes,omitempty"`
+	Data  *services.PriceUpdate `json:"data,omitempty"`
+}
+
+// StreamPricesSSE pushes price updates as Server-Sent Events
+// @Summary Real-time price updates (SSE)
+// @Description Streams a "price" event with the latest candle whenever a subscribed symbol's prices are written.
+// @Description ?codes=HPG,VNM selects symbols (default: every symbol).
+// @Tags stocks
+// @Produce text/event-stream
+// @Param codes query string false "Comma-separated stock codes"
+// @Router /api/stream/prices [get]
+func (sc *StreamController) StreamPricesSSE(c *gin.Context) {
+	if !sc.available(c) {
+		return
+	}
+
+	sub := sc.priceStreamService.Subscribe(models.SplitList(c.Query("codes"))...)
+	defer sc.priceStreamService.Unsubscribe(sub)
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.SSEvent("subscribed", gin.H{"codes": sub.Codes()})
+	c.Writer.Flush()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case update, ok := <-sub.Updates:
+			if !ok {
+				return false
+			}
+			c.SSEvent("price", update)
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"dropped": sub.Dropped()})
+		}
+		return true
+	})
+}
+
+// StreamPricesWebSocket pushes price updates over a WebSocket
+// @Summary Real-time price updates (WebSocket)
+// @Description Sends {"type":"update","data":{...}} messages for subscribed symbols. Clients change their
+// @Description subscription with {"type":"subscribe","codes":["HPG"]} and {"type":"unsubscribe","codes":["HPG"]}.
+// @Tags stocks
+// @Param codes query string false "Comma-separated stock codes to subscribe to initially"
+// @Router /api/stream/prices/ws [get]
+func (sc *StreamController) StreamPricesWebSocket(c *gin.Context) {
+	if !sc.available(c) {
+		return
+	}
+
+	codes := models.SplitList(c.Query("codes"))
+	server := websocket.Server{
+		Handshake: func(_ *websocket.Config, req *http.Request) error {
+			return sc.checkOrigin(req)
+		},
+		Handler: func(conn *websocket.Conn) {
+			sc.serveWebSocket(conn, codes)
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// checkOrigin refuses WebSockets opened by pages of other sites: browsers
+// attach the admin session cookie to them, and the same-origin policy does
+// not apply. Clients that are not browsers send no Origin and are let through.
+func (sc *StreamController) checkOrigin(req *http.Request) error {
+	origin := req.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("malformed origin %q", origin)
+	}
+	if strings.EqualFold(parsed.Host, req.Host) {
+		return nil
+	}
+	for _, allowed := range sc.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q is not allowed", origin)
+}
+
+// serveWebSocket relays updates to one WebSocket client until it disconnects
+func (sc *StreamController) serveWebSocket(conn *websocket.Conn, codes []string) {
+	defer conn.Close()
+	sub := sc.priceStreamService.Subscribe(codes...)
+	defer sc.priceStreamService.Unsubscribe(sub)
+
+	// Client messages change the subscription; a read error means it left
+	closed := make(chan struct{})
+	changed := make(chan struct{}, 1)
+	go func() {
+		defer close(closed)
+		for {
+			var message streamMessage
+			if err := websocket.JSON.Receive(conn, &message); err != nil {
+				return
+			}
+			switch strings.ToLower(message.Type) {
+			case "subscribe":
+				sub.Subscribe(message.Codes...)
+			case "unsubscribe":
+				sub.Unsubscribe(message.Codes...)
+			default:
+				continue
+			}
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	if websocket.JSON.Send(conn, streamMessage{Type: "subscribed", Codes: sub.Codes()}) != nil {
+		return
+	}
+	for {
+		var message streamMessage
+		select {
+		case <-closed:
+			return
+		case <-changed:
+			message = streamMessage{Type: "subscribed", Codes: sub.Codes()}
+		case update, ok := <-sub.Updates:
+			if !ok {
+				return
+			}
+			message = streamMessage{Type: "update", Data: &update}
+		case <-heartbeat.C:
+			message = streamMessage{Type: "ping"}
+		}
+		if websocket.JSON.Send(conn, message) != nil {
+			return
+		}
+	}
+}
+
+// available rejects the request when the change stream is not running
+func (sc *StreamController) available(c *gin.Context) bool {
+	if sc.priceStreamService.Available() {
+		return true
+	}
+	c.Header("Retry-After", "60")
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"status":  "error",
+		"message": "Real-time price updates are unavailable (MongoDB change stream not running and no REALTIME_SOURCE)",
+	})
+	return false
+}
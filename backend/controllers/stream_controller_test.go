@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStreamCheckOrigin(t *testing.T) {
+	sc := NewStreamController(nil, []string{"https://cpls.vn/"})
+
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{"no origin", "", true},
+		{"same origin", "https://api.cpls.vn", true},
+		{"configured origin", "https://cpls.vn", true},
+		{"other site", "https://evil.example", false},
+		{"configured host on another scheme", "http://cpls.vn", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "https://api.cpls.vn/api/stream/prices/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if err := sc.checkOrigin(req); (err == nil) != tt.allowed {
+			t.Errorf("%s: checkOrigin() error = %v; want allowed %v", tt.name, err, tt.allowed)
+		}
+	}
+
+	wildcard := NewStreamController(nil, []string{"*"})
+	req := httptest.NewRequest(http.MethodGet, "https://api.cpls.vn/api/stream/prices/ws", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	if err := wildcard.checkOrigin(req); err != nil {
+		t.Errorf("checkOrigin() with \"*\" error = %v; want allowed", err)
+	}
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// SuspectCandleController handles the review of candles held back by the
+// crawler's price band check
+type SuspectCandleController struct {
+	crawlerService *services.CrawlerService
+}
+
+// NewSuspectCandleController creates a new suspect candle controller
+func NewSuspectCandleController(crawlerService *services.CrawlerService) *SuspectCandleController {
+	return &SuspectCandleController{
+		crawlerService: crawlerService,
+	}
+}
+
+// ListSuspects returns suspect candles, newest first (JSON API)
+// Query params: status (default: pending, "all" for every status), limit
+// (default: the admin's page size)
+func (sc *SuspectCandleController) ListSuspects(c *gin.Context) {
+	status := c.DefaultQuery("status", models.SuspectCandleStatusPending)
+	if status == "all" {
+		status = ""
+	}
+
+	suspects, err := sc.crawlerService.ListSuspectCandles(c.Request.Context(), status, adminLimit(c, "limit"))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListSuspects failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch suspect candles",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    suspects,
+		"total":   len(suspects),
+	})
+}
+
+// AcceptSuspect stores a suspect candle as regular data (JSON API)
+func (sc *SuspectCandleController) AcceptSuspect(c *gin.Context) {
+	sc.resolve(c, true)
+}
+
+// RejectSuspect discards a suspect candle (JSON API)
+func (sc *SuspectCandleController) RejectSuspect(c *gin.Context) {
+	sc.resolve(c, false)
+}
+
+func (sc *SuspectCandleController) resolve(c *gin.Context, accept bool) {
+	admin, _ := sessions.Default(c).Get("user").(string)
+	suspect, err := sc.crawlerService.ResolveSuspectCandle(c.Request.Context(), c.Param("id"), accept, admin)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSuspectCandleNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Suspect candle not found"})
+		case errors.Is(err, services.ErrSuspectCandleResolved):
+			c.JSON(http.StatusConflict, gin.H{"error": "Suspect candle was already reviewed"})
+		default:
+			logging.FromContext(c.Request.Context()).Error("ResolveSuspectCandle failed", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to resolve suspect candle",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    suspect,
+	})
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SymbolController handles ticker rename and exchange transfer management
+type SymbolController struct {
+	symbolService *services.SymbolService
+}
+
+// NewSymbolController creates a new symbol controller
+func NewSymbolController(symbolService *services.SymbolService) *SymbolController {
+	return &SymbolController{
+		symbolService: symbolService,
+	}
+}
+
+// ListChanges returns recorded symbol changes, optionally for one ?code= (JSON API)
+func (sc *SymbolController) ListChanges(c *gin.Context) {
+	changes, err := sc.symbolService.ListChanges(c.Request.Context(), c.Query("code"))
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListChanges failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch symbol changes",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    changes,
+		"total":   len(changes),
+	})
+}
+
+// CreateChange records a ticker rename or exchange transfer (JSON API)
+func (sc *SymbolController) CreateChange(c *gin.Context) {
+	var change models.SymbolChange
+	if err := c.ShouldBindJSON(&change); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+	change.Source = models.SymbolChangeSourceAdmin
+
+	if err := sc.symbolService.CreateChange(c.Request.Context(), &change); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to record symbol change",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Info("Symbol change recorded", "type", change.Type, "old_code", change.OldCode, "new_code", change.NewCode, "effective_date", change.EffectiveDate)
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    change,
+	})
+}
+
+// DeleteChange removes a symbol change recorded by mistake (JSON API)
+func (sc *SymbolController) DeleteChange(c *gin.Context) {
+	if err := sc.symbolService.DeleteChange(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrSymbolChangeNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Symbol change not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete symbol change",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
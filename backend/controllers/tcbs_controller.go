@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// TCBSController handles members' TCBS accounts (/api/me/tcbs)
+type TCBSController struct {
+	tcbsService *services.TCBSService
+}
+
+// NewTCBSController creates a new TCBS controller
+func NewTCBSController(tcbsService *services.TCBSService) *TCBSController {
+	return &TCBSController{
+		tcbsService: tcbsService,
+	}
+}
+
+// respondTCBSError maps TCBS service errors to responses
+func respondTCBSError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrTCBSNotConnected), errors.Is(err, services.ErrProfileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "TCBS account is not connected",
+		})
+	case errors.Is(err, services.ErrCredentialsKeyMissing):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "error",
+			"message": "TCBS connections are disabled",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, services.ErrTCBSKeyRejected):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"status":  "error",
+			"message": "TCBS rejected the API key; connect the account again with a valid key",
+		})
+	default:
+		logging.FromContext(c.Request.Context()).Error(action+" failed", logging.FieldError, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"status":  "error",
+			"message": "Failed to " + action,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// Connect validates a TCBS API key and stores it on the member's profile
+// @Summary Connect TCBS account
+// @Description Exchanges the key for a TCBS access token and, if TCBS accepts it, stores it encrypted with
+// @Description tcbs_connected_at. A rejected key (422) leaves any connected key in place.
+// @Tags me
+// @Accept json
+// @Produce json
+// @Param body body object true "{\"api_key\": \"...\"}"
+// @Success 200 {object} map[string]interface{} "Connection time"
+// @Failure 422 {object} map[string]interface{} "API key rejected by TCBS"
+// @Failure 503 {object} map[string]interface{} "PROVIDER_CREDENTIALS_KEY not set"
+// @Router /api/me/tcbs/connect [post]
+func (tc *TCBSController) Connect(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		APIKey string `json:"api_key" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "api_key is required",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	connectedAt, err := tc.tcbsService.Connect(c.Request.Context(), profileID, req.APIKey)
+	if err != nil {
+		respondTCBSError(c, "connect TCBS account", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   gin.H{"connected_at": connectedAt},
+	})
+}
+
+// Disconnect removes the TCBS API key from the member's profile
+// @Summary Disconnect TCBS account
+// @Tags me
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Disconnected"
+// @Router /api/me/tcbs/disconnect [delete]
+func (tc *TCBSController) Disconnect(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	if err := tc.tcbsService.Disconnect(c.Request.Context(), profileID); err != nil {
+		if errors.Is(err, services.ErrProfileNotFound) {
+			respondTCBSError(c, "disconnect TCBS account", err)
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("disconnect TCBS account failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to disconnect TCBS account",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "success",
+		"message": "TCBS account disconnected",
+	})
+}
+
+// GetPortfolio returns the positions and cash of the member's TCBS accounts
+// @Summary TCBS portfolio
+// @Description Reads the member's TCBS accounts with the API key on their profile: positions priced by TCBS and
+// @Description cash balances per sub-account, with totals. 404 until a key is stored, 422 when TCBS rejects it.
+// @Tags me
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Accounts and totals"
+// @Failure 404 {object} map[string]interface{} "No TCBS API key"
+// @Failure 422 {object} map[string]interface{} "API key rejected by TCBS"
+// @Router /api/me/tcbs/portfolio [get]
+func (tc *TCBSController) GetPortfolio(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	portfolio, err := tc.tcbsService.Portfolio(c.Request.Context(), profileID)
+	if err != nil {
+		respondTCBSError(c, "fetch TCBS portfolio", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   portfolio,
+	})
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// maxTelegramUpdateBody caps the webhook body read into memory
+const maxTelegramUpdateBody = 64 << 10
+
+// TelegramController receives bot commands from Telegram
+type TelegramController struct {
+	bot *services.TelegramBot
+}
+
+// NewTelegramController creates a new Telegram controller
+func NewTelegramController(bot *services.TelegramBot) *TelegramController {
+	return &TelegramController{
+		bot: bot,
+	}
+}
+
+// HandleWebhook answers /crawl and /status commands from the operations chat
+// @Summary Telegram bot webhook
+// @Description Register with setWebhook and secret_token=TELEGRAM_WEBHOOK_SECRET; Telegram echoes it in
+// @Description X-Telegram-Bot-Api-Secret-Token. Only messages from TELEGRAM_CHAT_ID are answered.
+// @Tags telegram
+// @Accept json
+// @Produce json
+// @Router /api/telegram/webhook [post]
+func (tc *TelegramController) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxTelegramUpdateBody))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"status":  "error",
+			"message": "Webhook body too large",
+		})
+		return
+	}
+
+	err = tc.bot.HandleUpdate(c.Request.Context(), c.GetHeader("X-Telegram-Bot-Api-Secret-Token"), body)
+	switch {
+	case errors.Is(err, services.ErrTelegramNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "message": "Telegram commands are not configured"})
+	case errors.Is(err, services.ErrInvalidTelegramUpdate):
+		logging.FromContext(c.Request.Context()).Warn("Rejected Telegram update", "client_ip", c.ClientIP(), logging.FieldError, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "Invalid secret token"})
+	case err != nil:
+		// Acknowledged anyway: Telegram retries failed deliveries, which would rerun the command
+		logging.FromContext(c.Request.Context()).Error("HandleTelegramWebhook failed", logging.FieldError, err)
+		c.JSON(http.StatusOK, gin.H{"status": "success"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "success"})
+	}
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// UniverseController exposes daily stock universe snapshots and their differences
+type UniverseController struct {
+	universeService *services.UniverseService
+}
+
+// NewUniverseController creates a new universe controller
+func NewUniverseController(universeService *services.UniverseService) *UniverseController {
+	return &UniverseController{
+		universeService: universeService,
+	}
+}
+
+// ListSnapshots returns the dates and sizes of the stored snapshots, newest first (JSON API)
+func (uc *UniverseController) ListSnapshots(c *gin.Context) {
+	snapshots, err := uc.universeService.ListSnapshots(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListSnapshots failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch universe snapshots",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    snapshots,
+		"total":   len(snapshots),
+	})
+}
+
+// Diff returns the listings, delistings and attribute changes between the
+// universe on two dates (JSON API)
+// Query params: from, to (YYYY-MM-DD)
+func (uc *UniverseController) Diff(c *gin.Context) {
+	diff, err := uc.universeService.Diff(c.Request.Context(), c.Query("from"), c.Query("to"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidUniverseRange):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range", "details": err.Error()})
+		case errors.Is(err, services.ErrUniverseSnapshotNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Universe snapshot not found", "details": err.Error()})
+		default:
+			logging.FromContext(c.Request.Context()).Error("UniverseDiff failed", logging.FieldError, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to compare universe snapshots",
+				"details": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    diff,
+	})
+}
@@ -0,0 +1,297 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// WatchlistController handles members' watchlists (/api/watchlists)
+type WatchlistController struct {
+	watchlistService *services.WatchlistService
+}
+
+// NewWatchlistController creates a new watchlist controller
+func NewWatchlistController(watchlistService *services.WatchlistService) *WatchlistController {
+	return &WatchlistController{
+		watchlistService: watchlistService,
+	}
+}
+
+// watchlistRequest is the JSON body accepted when creating or renaming a watchlist
+type watchlistRequest struct {
+	Name  string   `json:"name"`
+	Codes []string `json:"codes"`
+}
+
+// watchlistSymbolsRequest is the JSON body accepted when adding symbols
+type watchlistSymbolsRequest struct {
+	Codes []string `json:"codes"`
+}
+
+// respondWatchlistError maps watchlist service errors to responses
+func respondWatchlistError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, services.ErrWatchlistNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"status":  "error",
+			"message": "Watchlist not found",
+		})
+	case errors.Is(err, services.ErrInvalidWatchlist):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid watchlist",
+			"error":   err.Error(),
+		})
+	case errors.Is(err, services.ErrWatchlistLimit):
+		c.JSON(http.StatusConflict, gin.H{
+			"status":  "error",
+			"message": "Watchlist limit reached",
+			"error":   err.Error(),
+		})
+	default:
+		logging.FromContext(c.Request.Context()).Error(action+" failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"status":  "error",
+			"message": "Failed to " + action,
+			"error":   err.Error(),
+		})
+	}
+}
+
+// bindWatchlistBody binds a JSON body, answering 400 when it is malformed
+func bindWatchlistBody(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  "error",
+			"message": "Invalid request body",
+			"error":   err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// ListWatchlists returns the member's watchlists
+// @Summary List watchlists
+// @Tags watchlists
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Watchlists with their codes"
+// @Router /api/watchlists [get]
+func (wc *WatchlistController) ListWatchlists(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	watchlists, err := wc.watchlistService.ListWatchlists(c.Request.Context(), profileID)
+	if err != nil {
+		respondWatchlistError(c, "fetch watchlists", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   watchlists,
+	})
+}
+
+// CreateWatchlist creates a watchlist
+// @Summary Create watchlist
+// @Description Creates a named watchlist, optionally seeded with stock codes
+// @Tags watchlists
+// @Accept json
+// @Produce json
+// @Param request body object true "name and optional codes"
+// @Success 201 {object} map[string]interface{} "Created watchlist"
+// @Router /api/watchlists [post]
+func (wc *WatchlistController) CreateWatchlist(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req watchlistRequest
+	if !bindWatchlistBody(c, &req) {
+		return
+	}
+
+	watchlist, err := wc.watchlistService.CreateWatchlist(c.Request.Context(), profileID, req.Name, req.Codes)
+	if err != nil {
+		respondWatchlistError(c, "create watchlist", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"status": "success",
+		"data":   watchlist,
+	})
+}
+
+// GetWatchlist returns one of the member's watchlists
+// @Summary Get watchlist
+// @Tags watchlists
+// @Produce json
+// @Param id path string true "Watchlist ID"
+// @Success 200 {object} map[string]interface{} "Watchlist with its codes"
+// @Router /api/watchlists/{id} [get]
+func (wc *WatchlistController) GetWatchlist(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	watchlist, err := wc.watchlistService.GetWatchlist(c.Request.Context(), profileID, c.Param("id"))
+	if err != nil {
+		respondWatchlistError(c, "fetch watchlist", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   watchlist,
+	})
+}
+
+// RenameWatchlist renames one of the member's watchlists
+// @Summary Rename watchlist
+// @Tags watchlists
+// @Accept json
+// @Produce json
+// @Param id path string true "Watchlist ID"
+// @Param request body object true "name"
+// @Success 200 {object} map[string]interface{} "Updated watchlist"
+// @Router /api/watchlists/{id} [put]
+func (wc *WatchlistController) RenameWatchlist(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req watchlistRequest
+	if !bindWatchlistBody(c, &req) {
+		return
+	}
+
+	watchlist, err := wc.watchlistService.RenameWatchlist(c.Request.Context(), profileID, c.Param("id"), req.Name)
+	if err != nil {
+		respondWatchlistError(c, "rename watchlist", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   watchlist,
+	})
+}
+
+// DeleteWatchlist deletes one of the member's watchlists
+// @Summary Delete watchlist
+// @Tags watchlists
+// @Produce json
+// @Param id path string true "Watchlist ID"
+// @Success 200 {object} map[string]interface{} "Deleted"
+// @Router /api/watchlists/{id} [delete]
+func (wc *WatchlistController) DeleteWatchlist(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	if err := wc.watchlistService.DeleteWatchlist(c.Request.Context(), profileID, c.Param("id")); err != nil {
+		respondWatchlistError(c, "delete watchlist", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+// AddSymbols appends stock codes to one of the member's watchlists
+// @Summary Add watchlist symbols
+// @Description Appends codes to the end of the list; codes already on it are ignored
+// @Tags watchlists
+// @Accept json
+// @Produce json
+// @Param id path string true "Watchlist ID"
+// @Param request body object true "codes"
+// @Success 200 {object} map[string]interface{} "Updated watchlist"
+// @Router /api/watchlists/{id}/symbols [post]
+func (wc *WatchlistController) AddSymbols(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	var req watchlistSymbolsRequest
+	if !bindWatchlistBody(c, &req) {
+		return
+	}
+
+	watchlist, err := wc.watchlistService.AddSymbols(c.Request.Context(), profileID, c.Param("id"), req.Codes)
+	if err != nil {
+		respondWatchlistError(c, "add watchlist symbols", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   watchlist,
+	})
+}
+
+// RemoveSymbol removes a stock code from one of the member's watchlists
+// @Summary Remove watchlist symbol
+// @Tags watchlists
+// @Produce json
+// @Param id path string true "Watchlist ID"
+// @Param code path string true "Stock code"
+// @Success 200 {object} map[string]interface{} "Updated watchlist"
+// @Router /api/watchlists/{id}/symbols/{code} [delete]
+func (wc *WatchlistController) RemoveSymbol(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	watchlist, err := wc.watchlistService.RemoveSymbol(c.Request.Context(), profileID, c.Param("id"), c.Param("code"))
+	if err != nil {
+		respondWatchlistError(c, "remove watchlist symbol", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": "success",
+		"data":   watchlist,
+	})
+}
+
+// GetQuotes returns the latest candle and day change of every symbol on a watchlist
+// @Summary Watchlist quotes
+// @Description Returns one quote per symbol in list order: the latest stored daily candle and its change
+// @Description from the previous close. Symbols without candles have an empty date.
+// @Tags watchlists
+// @Produce json
+// @Param id path string true "Watchlist ID"
+// @Success 200 {object} map[string]interface{} "Quotes"
+// @Router /api/watchlists/{id}/quotes [get]
+func (wc *WatchlistController) GetQuotes(c *gin.Context) {
+	profileID, ok := memberID(c)
+	if !ok {
+		return
+	}
+
+	watchlist, quotes, err := wc.watchlistService.Quotes(c.Request.Context(), profileID, c.Param("id"))
+	if err != nil {
+		respondWatchlistError(c, "fetch watchlist quotes", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "success",
+		"data":      quotes,
+		"watchlist": watchlist,
+	})
+}
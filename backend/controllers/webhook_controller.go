@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookController handles outbound webhook endpoints and their delivery log
+type WebhookController struct {
+	webhookService *services.WebhookService
+}
+
+// NewWebhookController creates a new webhook controller
+func NewWebhookController(webhookService *services.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+// webhookEndpointRequest is the JSON body accepted when creating or updating an endpoint
+type webhookEndpointRequest struct {
+	URL         string `json:"url"`
+	Description string `json:"description"`
+	Events      string `json:"events"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+// toModel converts the request into a WebhookEndpoint, applying defaults
+func (r webhookEndpointRequest) toModel() models.WebhookEndpoint {
+	endpoint := models.WebhookEndpoint{
+		URL:         r.URL,
+		Description: r.Description,
+		Events:      r.Events,
+		Enabled:     true,
+	}
+	if r.Enabled != nil {
+		endpoint.Enabled = *r.Enabled
+	}
+	return endpoint
+}
+
+// ListEndpoints returns all webhook endpoints plus the supported events (JSON API)
+func (wc *WebhookController) ListEndpoints(c *gin.Context) {
+	endpoints, err := wc.webhookService.ListEndpoints(c.Request.Context())
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListEndpoints failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch webhook endpoints",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    endpoints,
+		"total":   len(endpoints),
+		"events":  models.WebhookEvents,
+	})
+}
+
+// CreateEndpoint registers a webhook endpoint. The signing secret is only
+// returned in this response (JSON API)
+func (wc *WebhookController) CreateEndpoint(c *gin.Context) {
+	var req webhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	endpoint := req.toModel()
+	if err := endpoint.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook endpoint",
+			"details": err.Error(),
+		})
+		return
+	}
+	if user, ok := sessions.Default(c).Get("user").(string); ok {
+		endpoint.CreatedBy = &user
+	}
+
+	secret, err := wc.webhookService.CreateEndpoint(c.Request.Context(), &endpoint)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("CreateEndpoint failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create webhook endpoint",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, gin.H{
+		"success": true,
+		"data":    endpoint,
+		"secret":  secret,
+	})
+}
+
+// UpdateEndpoint updates an existing webhook endpoint (JSON API)
+func (wc *WebhookController) UpdateEndpoint(c *gin.Context) {
+	var req webhookEndpointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	changes := req.toModel()
+	if err := changes.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook endpoint",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	endpoint, err := wc.webhookService.UpdateEndpoint(c.Request.Context(), c.Param("id"), changes)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("UpdateEndpoint failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update webhook endpoint",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    endpoint,
+	})
+}
+
+// DeleteEndpoint removes a webhook endpoint and its delivery log (JSON API)
+func (wc *WebhookController) DeleteEndpoint(c *gin.Context) {
+	if err := wc.webhookService.DeleteEndpoint(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("DeleteEndpoint failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete webhook endpoint",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// PingEndpoint queues a "ping" event for an endpoint (JSON API)
+func (wc *WebhookController) PingEndpoint(c *gin.Context) {
+	delivery, err := wc.webhookService.Ping(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("PingEndpoint failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to queue ping",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    delivery,
+	})
+}
+
+// ListDeliveries returns the delivery log, newest first (JSON API)
+// Query params: endpoint_id, status, event, limit (default/max 500)
+func (wc *WebhookController) ListDeliveries(c *gin.Context) {
+	filter := services.WebhookDeliveryFilter{
+		EndpointID: c.Query("endpoint_id"),
+		Status:     c.Query("status"),
+		Event:      c.Query("event"),
+	}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	deliveries, err := wc.webhookService.ListDeliveries(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook endpoint not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("ListDeliveries failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch webhook deliveries",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    deliveries,
+		"total":   len(deliveries),
+	})
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts (JSON API)
+func (wc *WebhookController) Redeliver(c *gin.Context) {
+	delivery, err := wc.webhookService.Redeliver(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrWebhookDeliveryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Webhook delivery not found"})
+			return
+		}
+		logging.FromContext(c.Request.Context()).Error("Redeliver failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to requeue webhook delivery",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"success": true,
+		"data":    delivery,
+	})
+}
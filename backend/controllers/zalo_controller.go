@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxZaloEventBody caps the OA webhook body read into memory
+const maxZaloEventBody = 64 << 10
+
+// ZaloController exposes Zalo message tracking and the OA event webhook
+type ZaloController struct {
+	zaloService *services.ZaloService
+}
+
+// NewZaloController creates a new Zalo controller
+func NewZaloController(zaloService *services.ZaloService) *ZaloController {
+	return &ZaloController{
+		zaloService: zaloService,
+	}
+}
+
+// ListMessages returns messages sent through the Official Account with their
+// delivery status, newest first (JSON API)
+// Query params: status, profile_id, limit (default/max 500)
+func (zc *ZaloController) ListMessages(c *gin.Context) {
+	filter := services.ZaloMessageFilter{
+		Status:    c.Query("status"),
+		ProfileID: c.Query("profile_id"),
+	}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	if filter.ProfileID != "" {
+		if _, err := uuid.Parse(filter.ProfileID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile_id"})
+			return
+		}
+	}
+
+	messages, err := zc.zaloService.ListMessages(c.Request.Context(), filter)
+	if err != nil {
+		logging.FromContext(c.Request.Context()).Error("ListZaloMessages failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to fetch Zalo messages",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    messages,
+		"total":   len(messages),
+	})
+}
+
+// zaloTestRequest selects the recipient of a test message
+type zaloTestRequest struct {
+	ZaloUserID string `json:"zalo_user_id"`
+	ProfileID  string `json:"profile_id"`
+}
+
+// SendTest sends the "test" template to a Zalo user or a member's linked
+// account, to check the OA credentials (JSON API)
+func (zc *ZaloController) SendTest(c *gin.Context) {
+	var req zaloTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil || (req.ZaloUserID == "") == (req.ProfileID == "") {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": "exactly one of zalo_user_id or profile_id is required",
+		})
+		return
+	}
+
+	actor, _ := sessions.Default(c).Get("user").(string)
+	data := map[string]string{"Actor": actor}
+	ctx := c.Request.Context()
+	var err error
+	var message *models.ZaloMessage
+	if req.ProfileID != "" {
+		profileID, parseErr := uuid.Parse(req.ProfileID)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid profile_id"})
+			return
+		}
+		message, err = zc.zaloService.SendToProfile(ctx, profileID, "test", data)
+	} else {
+		message, err = zc.zaloService.Send(ctx, req.ZaloUserID, nil, "test", data)
+	}
+
+	switch {
+	case errors.Is(err, services.ErrZaloNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Zalo Official Account is not configured"})
+	case errors.Is(err, services.ErrProfileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Profile not found"})
+	case err != nil && message == nil:
+		logging.FromContext(c.Request.Context()).Error("SendZaloTest failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send Zalo message", "details": err.Error()})
+	default:
+		// Delivery failures are reported on the recorded message
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": message})
+	}
+}
+
+// HandleWebhook applies Official Account delivery and read receipts
+// @Summary Zalo OA event webhook
+// @Description Signed with X-ZEvent-Signature: mac=<hex SHA-256 of app_id + body + timestamp + ZALO_OA_SECRET_KEY>.
+// @Description Events more than 5 minutes from the server clock are rejected and replayed events are applied once.
+// @Description user_received_message and user_seen_message update the tracked message status; other events are ignored.
+// @Tags zalo
+// @Accept json
+// @Produce json
+// @Router /api/zalo/webhook [post]
+func (zc *ZaloController) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxZaloEventBody))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"status":  "error",
+			"message": "Webhook body too large",
+		})
+		return
+	}
+
+	err = zc.zaloService.HandleEvent(c.Request.Context(), body, c.GetHeader("X-ZEvent-Signature"))
+	switch {
+	case errors.Is(err, services.ErrZaloNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "error", "message": "Zalo webhook is not configured"})
+	case errors.Is(err, services.ErrInvalidZaloEvent):
+		logging.FromContext(c.Request.Context()).Warn("Rejected Zalo event", "client_ip", c.ClientIP(), logging.FieldError, err)
+		c.JSON(http.StatusUnauthorized, gin.H{"status": "error", "message": "Invalid signature"})
+	case err != nil:
+		logging.FromContext(c.Request.Context()).Error("HandleZaloWebhook failed", logging.FieldError, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"status": "error", "message": "Failed to process event"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"status": "success"})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+)
+
+// copyPrices copies the stored candles of -codes (default: every stock and
+// market index) from the -source price store to the -target one, keeping
+// the candles the target already has. It fills a store before PRICE_STORES
+// switches to it; new candles reach it meanwhile by listing it second.
+func copyPrices(args []string) {
+	flags := flag.NewFlagSet("copy-prices", flag.ExitOnError)
+	sourceFlag := flags.String("source", config.StoreMongo, "store to copy from: mongodb or postgres")
+	targetFlag := flags.String("target", "", "store to copy to: mongodb or postgres")
+	codesFlag := flags.String("codes", "", "comma-separated symbols to copy (default: every stock and index)")
+	flags.Parse(args)
+
+	if *targetFlag == "" || *targetFlag == *sourceFlag {
+		log.Fatalf("Invalid -target %q: want the other price store", *targetFlag)
+	}
+	source, err := services.NewPriceRepository([]string{*sourceFlag})
+	if err != nil {
+		log.Fatalf("Invalid -source: %v", err)
+	}
+	target, err := services.NewPriceRepository([]string{*targetFlag})
+	if err != nil {
+		log.Fatalf("Invalid -target: %v", err)
+	}
+	var codes []string
+	for _, code := range strings.Split(*codesFlag, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	_, closeStores := openStores()
+	defer closeStores()
+
+	if len(codes) == 0 {
+		stockService := services.NewStockService(source, cache.New(cache.NewStore()))
+		if _, err := stockService.StreamStockMetadata(ctx, nil, func(stock models.Stock) error {
+			codes = append(codes, stock.Code)
+			return nil
+		}); err != nil {
+			log.Fatalf("❌ Failed to list stocks: %v", err)
+		}
+		for _, exchange := range models.Exchanges() {
+			codes = append(codes, exchange.Indexes...)
+		}
+	}
+
+	copied := 0
+	for i, code := range codes {
+		if ctx.Err() != nil {
+			log.Fatalf("❌ Interrupted after %d of %d symbols", i, len(codes))
+		}
+		n, err := services.CopyCandles(ctx, source, target, code, models.HistoryEpoch, time.Now())
+		if err != nil {
+			log.Fatalf("❌ Failed to copy %s: %v", code, err)
+		}
+		copied += n
+		if (i+1)%100 == 0 {
+			log.Printf("Copied %d of %d symbols", i+1, len(codes))
+		}
+	}
+	log.Printf("✓ Copied %d candles of %d symbols from %s to %s", copied, len(codes), *sourceFlag, *targetFlag)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+)
+
+// crawl runs one crawl in this process and exits once its run listeners
+// are done: the whole universe, or only the stocks of -codes. It exits
+// with status 1 when the run was interrupted or failed.
+func crawl(args []string) {
+	flags := flag.NewFlagSet("crawl", flag.ExitOnError)
+	codesFlag := flags.String("codes", "", "comma-separated symbols to crawl (default: the whole universe)")
+	flags.Parse(args)
+
+	var codes []string
+	for _, code := range strings.Split(*codesFlag, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	_, closeStores := openStores()
+	defer closeStores()
+
+	// Notifications and webhook deliveries are queued as jobs, sent by the server
+	jobQueue := services.NewJobQueue()
+	notificationService, _, _ := newNotificationService(jobQueue)
+	pipeline := newCrawlPipeline(notificationService, services.NewWebhookService(), jobQueue)
+	attachProviderCredentials(services.NewAuditService())
+
+	// On SIGTERM the running crawl is checkpointed like in the server
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+		defer cancel()
+		if err := pipeline.crawler.Shutdown(shutdownCtx); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}()
+
+	run, err := pipeline.crawler.Crawl(codes)
+	if err != nil {
+		log.Fatalf("❌ Crawl failed: %v", err)
+	}
+	log.Printf("✓ Crawl run %s %s: %d/%d symbols succeeded, %d failed",
+		run.ID.Hex(), run.Status, run.SucceededSymbols, run.TotalSymbols, run.FailedSymbols)
+	if run.Status != models.CrawlRunStatusSuccess {
+		closeStores()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+)
+
+// export writes the stored candles of -codes (default: every stock) between
+// -from and -to to -out: stdout, a file, or a gs://bucket/object uploaded
+// once the export is complete
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", services.CandleExportCSV, "csv or ndjson")
+	codesFlag := flags.String("codes", "", "comma-separated symbols to export (default: every stock)")
+	fromFlag := flags.String("from", "", "first date to export, YYYY-MM-DD (default: the whole history)")
+	toFlag := flags.String("to", "", "last date to export, YYYY-MM-DD (default: today)")
+	out := flags.String("out", "", "output file or gs://BUCKET/OBJECT (default: stdout)")
+	flags.Parse(args)
+
+	var from time.Time
+	to := time.Now()
+	var err error
+	if *fromFlag != "" {
+		if from, err = time.Parse("2006-01-02", *fromFlag); err != nil {
+			log.Fatalf("Invalid -from %q: want YYYY-MM-DD", *fromFlag)
+		}
+	}
+	if *toFlag != "" {
+		if to, err = time.Parse("2006-01-02", *toFlag); err != nil {
+			log.Fatalf("Invalid -to %q: want YYYY-MM-DD", *toFlag)
+		}
+	}
+	var codes []string
+	for _, code := range strings.Split(*codesFlag, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	_, closeStores := openStores()
+	defer closeStores()
+	stockService := services.NewStockService(newPriceRepository(), cache.New(cache.NewStore()))
+
+	if len(codes) == 0 {
+		if _, err := stockService.StreamStockMetadata(ctx, nil, func(stock models.Stock) error {
+			codes = append(codes, stock.Code)
+			return nil
+		}); err != nil {
+			log.Fatalf("❌ Failed to list stocks: %v", err)
+		}
+	}
+
+	// gs:// exports are buffered and uploaded in one request
+	var w io.Writer = os.Stdout
+	var buf bytes.Buffer
+	toGCS := strings.HasPrefix(*out, "gs://")
+	bucket, object, _ := strings.Cut(strings.TrimPrefix(*out, "gs://"), "/")
+	if toGCS && (bucket == "" || object == "") {
+		log.Fatalf("Invalid -out %q: want gs://BUCKET/OBJECT", *out)
+	}
+	switch {
+	case toGCS:
+		w = &buf
+	case *out != "":
+		file, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		defer file.Close()
+		w = file
+	}
+
+	exporter, err := services.NewCandleExporter(w, *format)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	for _, code := range codes {
+		if _, err := stockService.StreamCandles(ctx, []string{code}, from, to, func(candle models.CandleData) error {
+			return exporter.Write(code, candle)
+		}); err != nil {
+			log.Fatalf("❌ Failed to export %s: %v", code, err)
+		}
+	}
+	if err := exporter.Flush(); err != nil {
+		log.Fatalf("❌ Failed to write the export: %v", err)
+	}
+
+	if toGCS {
+		contentType := "text/csv"
+		if *format == services.CandleExportNDJSON {
+			contentType = "application/x-ndjson"
+		}
+		if err := services.NewGCSClient(bucket).Upload(ctx, object, contentType, buf.Bytes()); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+	}
+	log.Printf("✓ Exported %d candles of %d symbols", exporter.Rows(), len(codes))
+}
@@ -0,0 +1,75 @@
+// Package format renders numbers the way Vietnamese market data is usually
+// displayed: "." groups thousands, "," separates decimals, prices are in
+// thousands of đồng and volumes are abbreviated (nghìn, triệu, tỷ cổ phiếu).
+package format
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LocaleVI is the ?locale= value that enables Vietnamese display strings
+const LocaleVI = "vi"
+
+// Units describes the unit of each formatted field, for clients to show in headers
+var Units = map[string]string{
+	"price":  "nghìn đồng",
+	"volume": "cổ phiếu",
+}
+
+// Number formats v with the given number of decimals, e.g. 1234567.891 with
+// 2 decimals becomes "1.234.567,89"
+func Number(v float64, decimals int) string {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return "-"
+	}
+
+	raw := strconv.FormatFloat(math.Abs(v), 'f', decimals, 64)
+	intPart, fracPart, _ := strings.Cut(raw, ".")
+
+	var b strings.Builder
+	if v < 0 && strings.Trim(raw, "0.") != "" {
+		b.WriteByte('-')
+	}
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(digit)
+	}
+	if fracPart != "" {
+		b.WriteByte(',')
+		b.WriteString(fracPart)
+	}
+	return b.String()
+}
+
+// Price formats a price quoted in thousands of đồng, e.g. 25.5 becomes "25,50"
+func Price(v float64) string {
+	return Number(v, 2)
+}
+
+// Volume abbreviates a share volume, e.g. 1234567 becomes "1,23 triệu cp"
+func Volume(v int64) string {
+	abs := math.Abs(float64(v))
+	switch {
+	case abs >= 1e9:
+		return Number(float64(v)/1e9, 2) + " tỷ cp"
+	case abs >= 1e6:
+		return Number(float64(v)/1e6, 2) + " triệu cp"
+	case abs >= 1e3:
+		return Number(float64(v)/1e3, 1) + " nghìn cp"
+	default:
+		return Number(float64(v), 0) + " cp"
+	}
+}
+
+// Percent formats a percentage with an explicit sign, e.g. 2.345 becomes "+2,35%"
+func Percent(v float64) string {
+	formatted := Number(v, 2)
+	if v > 0 && formatted != "0,00" {
+		formatted = "+" + formatted
+	}
+	return formatted + "%"
+}
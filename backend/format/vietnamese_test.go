@@ -0,0 +1,65 @@
+package format
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNumber(t *testing.T) {
+	tests := []struct {
+		value    float64
+		decimals int
+		expected string
+	}{
+		{1234567.891, 2, "1.234.567,89"},
+		{999, 0, "999"},
+		{1000, 0, "1.000"},
+		{-45678.5, 1, "-45.678,5"},
+		{-0.001, 2, "0,00"},
+		{math.NaN(), 2, "-"},
+	}
+
+	for _, tt := range tests {
+		result := Number(tt.value, tt.decimals)
+		if result != tt.expected {
+			t.Errorf("Number(%v, %d) = %q; want %q", tt.value, tt.decimals, result, tt.expected)
+		}
+	}
+}
+
+func TestVolume(t *testing.T) {
+	tests := []struct {
+		volume   int64
+		expected string
+	}{
+		{850, "850 cp"},
+		{12300, "12,3 nghìn cp"},
+		{1234567, "1,23 triệu cp"},
+		{2500000000, "2,50 tỷ cp"},
+	}
+
+	for _, tt := range tests {
+		result := Volume(tt.volume)
+		if result != tt.expected {
+			t.Errorf("Volume(%d) = %q; want %q", tt.volume, result, tt.expected)
+		}
+	}
+}
+
+func TestPriceAndPercent(t *testing.T) {
+	if result := Price(25.5); result != "25,50" {
+		t.Errorf("Price(25.5) = %q; want %q", result, "25,50")
+	}
+
+	tests := map[float64]string{
+		2.345:  "+2,35%",
+		-1.2:   "-1,20%",
+		0:      "0,00%",
+		0.0001: "0,00%",
+	}
+	for value, expected := range tests {
+		if result := Percent(value); result != expected {
+			t.Errorf("Percent(%v) = %q; want %q", value, result, expected)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+// Package logging configures the process-wide structured logger (log/slog)
+// and carries request-scoped loggers through contexts. In production the
+// output is one JSON object per line with the field names Cloud Logging
+// parses (severity, message, logging.googleapis.com/trace).
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Field names shared by every log line
+const (
+	FieldComponent = "component"
+	FieldRequestID = "request_id"
+	FieldSymbol    = "symbol"
+	FieldError     = "error"
+)
+
+// Output formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Config selects the output of the process-wide logger
+type Config struct {
+	Format  string
+	Level   slog.Level
+	Project string // Google Cloud project, for linking log lines to request traces
+}
+
+// project is the Google Cloud project configured by Setup
+var project string
+
+// LoadConfig reads the logger configuration from the environment
+func LoadConfig() (Config, error) {
+	return loadConfig(os.Getenv)
+}
+
+// loadConfig reads LOG_FORMAT (json or text; json by default in production,
+// i.e. ENV=production or on Cloud Run), LOG_LEVEL (debug, info, warn or
+// error; default info) and GOOGLE_CLOUD_PROJECT
+func loadConfig(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Format:  FormatText,
+		Level:   slog.LevelInfo,
+		Project: strings.TrimSpace(getenv("GOOGLE_CLOUD_PROJECT")),
+	}
+	if getenv("ENV") == "production" || getenv("K_SERVICE") != "" {
+		cfg.Format = FormatJSON
+	}
+
+	if format := strings.ToLower(strings.TrimSpace(getenv("LOG_FORMAT"))); format != "" {
+		if format != FormatJSON && format != FormatText {
+			return cfg, fmt.Errorf("LOG_FORMAT: expected %s or %s, got %q", FormatJSON, FormatText, format)
+		}
+		cfg.Format = format
+	}
+	if level := strings.TrimSpace(getenv("LOG_LEVEL")); level != "" {
+		if err := cfg.Level.UnmarshalText([]byte(level)); err != nil {
+			return cfg, fmt.Errorf("LOG_LEVEL: expected debug, info, warn or error, got %q", level)
+		}
+	}
+	return cfg, nil
+}
+
+// Setup installs the process-wide logger writing to w, and routes the
+// standard log package through it so existing log.Printf calls become
+// structured lines too (their level is inferred from the ❌/⚠️ markers)
+func Setup(cfg Config, w io.Writer) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: cfg.Level}
+	var handler slog.Handler
+	if cfg.Format == FormatJSON {
+		opts.ReplaceAttr = cloudLoggingAttr
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
+
+	logger := slog.New(handler)
+	slog.SetDefault(logger)
+	project = cfg.Project
+	log.SetFlags(0)
+	log.SetOutput(stdlogWriter{})
+	return logger
+}
+
+// cloudLoggingAttr renames the level and message of JSON lines to the
+// fields Cloud Logging reads
+func cloudLoggingAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		a.Key = "severity"
+		if level, ok := a.Value.Any().(slog.Level); ok && level == slog.LevelWarn {
+			a.Value = slog.StringValue("WARNING")
+		}
+	case slog.MessageKey:
+		a.Key = "message"
+	}
+	return a
+}
+
+// stdlogWriter forwards lines of the standard log package to the default
+// slog logger
+type stdlogWriter struct{}
+
+func (stdlogWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\n")
+	slog.Default().Log(context.Background(), inferLevel(msg), msg)
+	return len(p), nil
+}
+
+// inferLevel maps the markers of unstructured log lines to a level
+func inferLevel(msg string) slog.Level {
+	switch {
+	case strings.HasPrefix(msg, "❌"), strings.HasPrefix(msg, "FATAL"), strings.HasPrefix(msg, "Failed"):
+		return slog.LevelError
+	case strings.HasPrefix(msg, "⚠️"), strings.HasPrefix(msg, "Warning"), strings.HasPrefix(msg, "WARNING"):
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// Component returns a logger tagging its lines with a component. It always
+// writes through the current default logger, so package-level component
+// loggers created before Setup still use the configured output.
+func Component(name string) *slog.Logger {
+	return slog.New(defaultHandler{}).With(FieldComponent, name)
+}
+
+// defaultHandler delegates to the handler of the current default logger,
+// replaying the attributes and groups added to it
+type defaultHandler struct {
+	derive []func(slog.Handler) slog.Handler
+}
+
+func (h defaultHandler) handler() slog.Handler {
+	handler := slog.Default().Handler()
+	for _, derive := range h.derive {
+		handler = derive(handler)
+	}
+	return handler
+}
+
+func (h defaultHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slog.Default().Handler().Enabled(ctx, level)
+}
+
+func (h defaultHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h defaultHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+func (h defaultHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+func (h defaultHandler) with(derive func(slog.Handler) slog.Handler) defaultHandler {
+	return defaultHandler{derive: append(h.derive[:len(h.derive):len(h.derive)], derive)}
+}
+
+// RequestIDHeader carries the ID correlating a request across services
+const RequestIDHeader = "X-Request-ID"
+
+type contextKey struct{}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying a request ID, forwarded on outbound
+// calls made with it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID carried by ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewContext returns ctx carrying logger
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// TraceAttr returns the attribute linking a log line to the request trace
+// in an X-Cloud-Trace-Context header ("TRACE_ID/SPAN_ID;o=1"), when both
+// the header and the Google Cloud project are known
+func TraceAttr(header string) (slog.Attr, bool) {
+	traceID, _, _ := strings.Cut(header, "/")
+	if traceID == "" || project == "" {
+		return slog.Attr{}, false
+	}
+	return slog.String("logging.googleapis.com/trace", "projects/"+project+"/traces/"+traceID), true
+}
@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    Config
+		wantErr bool
+	}{
+		{"defaults", nil, Config{Format: FormatText, Level: slog.LevelInfo}, false},
+		{"production", map[string]string{"ENV": "production"}, Config{Format: FormatJSON, Level: slog.LevelInfo}, false},
+		{"cloud run", map[string]string{"K_SERVICE": "cpls", "GOOGLE_CLOUD_PROJECT": "cpls-prod"}, Config{Format: FormatJSON, Level: slog.LevelInfo, Project: "cpls-prod"}, false},
+		{"explicit", map[string]string{"ENV": "production", "LOG_FORMAT": "Text", "LOG_LEVEL": "debug"}, Config{Format: FormatText, Level: slog.LevelDebug}, false},
+		{"bad format", map[string]string{"LOG_FORMAT": "xml"}, Config{}, true},
+		{"bad level", map[string]string{"LOG_LEVEL": "loud"}, Config{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadConfig(func(key string) string { return tt.env[key] })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("loadConfig() = %+v; want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInferLevel(t *testing.T) {
+	tests := map[string]slog.Level{
+		"❌ Error saving stocks: boom":                       slog.LevelError,
+		"Failed to connect to PostgreSQL: boom":             slog.LevelError,
+		"⚠️  Failed to publish crawl.finished: boom":        slog.LevelWarn,
+		"Warning: Failed to connect to Redis":               slog.LevelWarn,
+		"✓ Stock universe snapshot 2024-03-29 saved":        slog.LevelInfo,
+		"🚀 Starting server on port 8080 (environment: dev)": slog.LevelInfo,
+	}
+	for msg, want := range tests {
+		if got := inferLevel(msg); got != want {
+			t.Errorf("inferLevel(%q) = %v; want %v", msg, got, want)
+		}
+	}
+}
+
+// setupForTest installs a JSON logger writing to a buffer and restores the
+// previous loggers when the test ends
+func setupForTest(t *testing.T, cfg Config) *bytes.Buffer {
+	t.Helper()
+	previous, previousFlags, previousProject := slog.Default(), log.Flags(), project
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetFlags(previousFlags)
+		log.SetOutput(os.Stderr)
+		project = previousProject
+	})
+	var buf bytes.Buffer
+	Setup(cfg, &buf)
+	return &buf
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var line map[string]any
+		if err := decoder.Decode(&line); err != nil {
+			t.Fatalf("invalid JSON line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestSetupJSONForCloudLogging(t *testing.T) {
+	component := Component("crawler") // Created before Setup, like package-level loggers
+	buf := setupForTest(t, Config{Format: FormatJSON, Level: slog.LevelInfo})
+
+	component.Warn("No price data", FieldSymbol, "HPG")
+	log.Printf("❌ Error saving stocks: %v", "boom")
+	component.Debug("Processing") // Below the level
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines; want 2", len(lines))
+	}
+	want := map[string]any{"severity": "WARNING", "message": "No price data", FieldComponent: "crawler", FieldSymbol: "HPG"}
+	for key, value := range want {
+		if lines[0][key] != value {
+			t.Errorf("line 1 %s = %v; want %v", key, lines[0][key], value)
+		}
+	}
+	if lines[1]["severity"] != "ERROR" || lines[1]["message"] != "❌ Error saving stocks: boom" {
+		t.Errorf("standard log line = %v; want ERROR with the original message", lines[1])
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	buf := setupForTest(t, Config{Format: FormatJSON, Level: slog.LevelInfo})
+
+	FromContext(context.Background()).Info("default")
+	ctx := NewContext(context.Background(), slog.Default().With(FieldRequestID, "req-1"))
+	FromContext(ctx).Info("scoped")
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines; want 2", len(lines))
+	}
+	if _, ok := lines[0][FieldRequestID]; ok {
+		t.Errorf("default logger line has %s", FieldRequestID)
+	}
+	if lines[1][FieldRequestID] != "req-1" {
+		t.Errorf("scoped line %s = %v; want req-1", FieldRequestID, lines[1][FieldRequestID])
+	}
+}
+
+func TestTraceAttr(t *testing.T) {
+	setupForTest(t, Config{Format: FormatJSON, Project: "cpls-prod"})
+
+	attr, ok := TraceAttr("105445aa7843bc8bf206b12000100000/1;o=1")
+	if !ok || attr.Value.String() != "projects/cpls-prod/traces/105445aa7843bc8bf206b12000100000" {
+		t.Errorf("TraceAttr() = %v, %v; want the trace of cpls-prod", attr, ok)
+	}
+	if _, ok := TraceAttr(""); ok {
+		t.Error("TraceAttr(\"\") reported a trace")
+	}
+
+	project = ""
+	if _, ok := TraceAttr("105445aa7843bc8bf206b12000100000/1;o=1"); ok {
+		t.Error("TraceAttr() reported a trace without a project")
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	if id := RequestID(context.Background()); id != "" {
+		t.Errorf("RequestID(background) = %q; want empty", id)
+	}
+	if id := RequestID(WithRequestID(context.Background(), "req-1")); id != "req-1" {
+		t.Errorf("RequestID() = %q; want req-1", id)
+	}
+}
@@ -1,58 +1,133 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/datvt88/CPLS/backend/config"
 	"github.com/datvt88/CPLS/backend/controllers"
+	"github.com/datvt88/CPLS/backend/logging"
 	"github.com/datvt88/CPLS/backend/middleware"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-contrib/sessions/cookie"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 )
 
+// command is a subcommand of the binary. Cloud Run runs serve; the others
+// are meant for one-off Cloud Run Jobs of the same image.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+var commands = []command{
+	{"serve", "run the API and admin dashboard (default)", serve},
+	{"crawl", "run one crawl in this process: crawl [-codes HPG,VNM]", crawl},
+	{"migrate", "apply the SQL migrations: migrate [-dir DIR] status|up|baseline [-to VERSION]", migrate},
+	{"export", "export stored candles: export [-format csv|ndjson] [-codes HPG,VNM] [-from DATE] [-to DATE] [-out FILE|gs://BUCKET/OBJECT]", export},
+	{"copy-prices", "copy stored candles between price stores: copy-prices [-source mongodb] -target postgres [-codes HPG,VNM]", copyPrices},
+}
+
 func main() {
 	// Load environment variables from .env file (if exists)
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	// Connect to PostgreSQL (Supabase)
-	if err := config.ConnectPostgres(); err != nil {
-		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	// Structured logging (LOG_FORMAT, LOG_LEVEL): JSON lines for Cloud Logging in production
+	logConfig, err := logging.LoadConfig()
+	if err != nil {
+		log.Fatalf("FATAL: Invalid logging configuration: %v", err)
 	}
-	defer config.DisconnectPostgres()
-
-	// Keep MongoDB connection for backward compatibility (if needed)
-	// Comment out if you want to fully migrate to PostgreSQL
-	// if err := config.ConnectMongoDB(); err != nil {
-	// 	log.Printf("Warning: Failed to connect to MongoDB: %v", err)
-	// }
-	// defer config.DisconnectMongoDB()
+	logging.Setup(logConfig, os.Stderr)
 
-	// Initialize Gin router
-	router := gin.Default()
+	// Dates, "today" and schedule times follow the market's time zone
+	// (MARKET_TIMEZONE, EXCHANGE_TIMEZONES), never the server's
+	marketTime, err := config.LoadMarketTimeConfig()
+	if err == nil {
+		err = marketTime.Apply()
+	}
+	if err != nil {
+		log.Fatalf("FATAL: Invalid market time zone: %v", err)
+	}
 
-	// IMPORTANT: Trust proxies for Cloud Run
-	// Cloud Run uses a Google-managed load balancer in front of the app
-	// We need to trust ALL proxies (0.0.0.0/0 for IPv4, ::/0 for IPv6) because:
-	// 1. Cloud Run is an isolated, managed environment - only Google's LB can access the container
-	// 2. Cloud Run containers are NOT directly accessible from the internet
-	// 3. All external traffic MUST go through Google's load balancer first
-	// 4. We need Gin to recognize X-Forwarded-Proto header to detect HTTPS
-	// 5. Without this, cookies with Secure=true won't be set (causing logout loops)
-	// Note: SetTrustedProxies(nil) would DISABLE proxy trust, not enable it!
-	// Note: This is safe because Cloud Run's network isolation prevents direct container access
-	if err := router.SetTrustedProxies([]string{"0.0.0.0/0", "::/0"}); err != nil {
-		log.Printf("Warning: Failed to set trusted proxies: %v", err)
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			cmd.run(args)
+			return
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\nUsage: %s <command> [flags]\n\n", name, os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", cmd.name, cmd.summary)
 	}
+	os.Exit(2)
+}
+
+// serve runs the HTTP server until SIGTERM or SIGINT
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Parse(args)
+
+	// Cancelled on SIGTERM (sent by Cloud Run before stopping an instance) or
+	// SIGINT; background jobs stop and the server drains before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-	// Load HTML templates
-	router.LoadHTMLGlob("templates/*")
+	settingsService, closeStores := openStores()
+	defer closeStores()
 
+	// Ping both stores periodically to notice outages and recoveries
+	config.StartStoreMonitor(ctx, storeCheckInterval())
+	// Create missing indexes in the background, once each store is reachable
+	config.StartIndexSetup(ctx)
+
+	settingsService.StartAutoReload(ctx, configReloadInterval())
+	watchReloadSignal(settingsService)
+
+	// Initialize Gin router; requests are logged by middleware.RequestLogger
+	router := gin.New()
+	router.Use(middleware.RequestID(), gin.Recovery(), middleware.RequestLogger())
+
+	// Trust forwarded client IPs only from the proxies of the deployment
+	// target (DEPLOY_TARGET, TRUSTED_PROXIES, FORWARDED_IP_HEADERS,
+	// FORWARDED_HOPS). Cloud Run trusts every peer, as only Google's front
+	// end can reach the container, but only the X-Forwarded-For hop it appends.
+	proxyConfig, err := config.LoadProxyConfig()
+	if err != nil {
+		log.Fatalf("FATAL: Invalid proxy configuration: %v", err)
+	}
+	if proxyConfig.TrustsEveryone() && proxyConfig.ForwardedHops == 0 {
+		log.Printf("⚠️  Trusting forwarded headers from every peer on %s: clients can spoof their IP", proxyConfig.Target)
+	}
+	router.RemoteIPHeaders = proxyConfig.RemoteIPHeaders
+	if err := router.SetTrustedProxies(proxyConfig.TrustedProxies); err != nil {
+		log.Fatalf("FATAL: Failed to set trusted proxies: %v", err)
+	}
+	router.Use(middleware.ForwardedHops(proxyConfig.ForwardedHops))
+	log.Printf("✓ Deployment target %s: trusting %d proxy ranges, %d forwarded hops", proxyConfig.Target, len(proxyConfig.TrustedProxies), proxyConfig.ForwardedHops)
+
+	// HTML templates are parsed once by the warm-up routine below
 	// Configure session middleware for Cloud Run
 	sessionSecret := os.Getenv("SESSION_SECRET")
 	if sessionSecret == "" {
@@ -83,46 +158,436 @@ func main() {
 
 	router.Use(sessions.Sessions("admin_session", store))
 
+	// Configure JWT signing for programmatic API access
+	if os.Getenv("JWT_SECRET") == "" {
+		if os.Getenv("ENV") == "production" {
+			log.Fatal("FATAL: JWT_SECRET environment variable must be set in production")
+		}
+		log.Println("WARNING: JWT_SECRET not set. Using default (not recommended for production)")
+		os.Setenv("JWT_SECRET", "default-jwt-secret-change-in-production")
+	}
+	userService := services.NewUserService()
+	tokenService, err := services.NewTokenServiceFromEnv(userService)
+	if err != nil {
+		log.Fatalf("Failed to configure JWT: %v", err)
+	}
+
+	// Member (Supabase Auth) tokens are needed only for /api/me routes
+	memberAuthService, err := services.NewMemberAuthServiceFromEnv(userService)
+	if err != nil {
+		log.Printf("Warning: %v. Member endpoints (/api/me) are disabled", err)
+	}
+
 	// CORS middleware for Cloud Run
 	router.Use(corsMiddleware())
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status":  "healthy",
-			"service": "CPLS Market Data Crawler",
-			"version": "1.0.0",
-		})
-	})
+	// Count DB queries per request and flag likely N+1 patterns
+	router.Use(middleware.QueryCount())
+
+	// Durable background jobs (crawls, retries, backups, notifications) in the
+	// Supabase jobs table, run by whichever instance claims them
+	jobQueue := services.NewJobQueue()
+	jobController := controllers.NewJobController(jobQueue)
+
+	// Notification channels for alert rules and crawl run summaries
+	notificationService, zaloService, telegramService := newNotificationService(jobQueue)
+	zaloController := controllers.NewZaloController(zaloService)
+
+	// Outbound webhooks for crawl and data events, delivered with retries
+	webhookService := services.NewWebhookService()
+	webhookService.StartDispatcher(ctx)
+	webhookController := controllers.NewWebhookController(webhookService)
 
 	// Initialize controllers
-	crawlerController := controllers.NewCrawlerController()
-	adminController := controllers.NewAdminController()
+	pipeline := newCrawlPipeline(notificationService, webhookService, jobQueue)
+	crawlerService, stockService := pipeline.crawler, pipeline.stocks
+	crawlerController := controllers.NewCrawlerController(crawlerService)
+	crawlErrorController := controllers.NewCrawlErrorController(services.NewCrawlErrorService(crawlerService, settingsService))
+	symbolService := services.NewSymbolService()
+	symbolController := controllers.NewSymbolController(symbolService)
+	suspectCandleController := controllers.NewSuspectCandleController(crawlerService)
+	universeController := controllers.NewUniverseController(services.NewUniverseService())
+	signalController := controllers.NewSignalController(pipeline.signals)
+	screenerPresetController := controllers.NewScreenerPresetController(pipeline.screenerPresets)
+	marketController := controllers.NewMarketController(pipeline.sectorBreadth, pipeline.stockMetrics, services.NewMarketMoversService(stockService), pipeline.volumeAnomalies)
+	derivativesController := controllers.NewDerivativesController(pipeline.derivatives)
+	stockController := controllers.NewStockController(stockService, symbolService, pipeline.sparklines, pipeline.stockMetrics, pipeline.movingAverages)
+	// Routes soft-launching a new implementation record both sides for comparison
+	canaryMetrics := services.NewCanaryMetrics()
+	canaryController := controllers.NewCanaryController(canaryMetrics)
+	// Admin logins (dashboard and token endpoint) share throttling state and are audited
+	auditService := services.NewAuditService()
+	auditController := controllers.NewAuditController(auditService)
+	loginService := services.NewLoginService(services.NewAuthService(), auditService)
+	adminPreferenceService := services.NewAdminPreferenceService()
+	adminController := controllers.NewAdminController(userService, loginService, services.NewProfileService(auditService), adminPreferenceService)
+	adminPreferenceController := controllers.NewAdminPreferenceController(adminPreferenceService)
+
+	// Data provider credentials (encrypted in Supabase, rotated from the admin API)
+	credentialService := attachProviderCredentials(auditService)
+	credentialController := controllers.NewCredentialController(credentialService)
+	authController := controllers.NewAuthController(tokenService, loginService)
+	apiKeyService := services.NewAPIKeyService()
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	personalTokenService := services.NewPersonalTokenService(userService)
+	personalTokenController := controllers.NewPersonalTokenController(personalTokenService)
+	settingsController := controllers.NewSettingsController(settingsService)
+	crawlerConfigController := controllers.NewCrawlerConfigController(services.NewCrawlerConfigService(settingsService))
+	// Feature flags (feature.* settings) may target environments and membership tiers
+	featureService := services.NewFeatureService()
+	featureController := controllers.NewFeatureController(featureService)
+
+	// Operational alerting: rules are evaluated periodically and routed to notification channels
+	alertService := services.NewAlertService(notificationService)
+	alertController := controllers.NewAlertController(alertService)
+	alertService.StartMonitor(ctx)
+
+	// Price data integrity: bucket checksums are verified periodically
+	integrityService := services.NewIntegrityService(notificationService)
+	integrityController := controllers.NewIntegrityController(integrityService)
+	integrityService.StartVerificationJob(ctx)
 
-	// Admin routes (with session-based authentication)
-	admin := router.Group("/admin")
+	// Nightly backup of critical collections and tables to Google Cloud Storage
+	// With SCHEDULER=pubsub, backups and digests are triggered by Cloud Scheduler through /api/pubsub/push
+	pubSubScheduled := strings.EqualFold(os.Getenv("SCHEDULER"), "pubsub")
+	backupService := services.NewBackupServiceFromEnv(notificationService, jobQueue)
+	if backupService.Configured() {
+		if !pubSubScheduled {
+			backupService.StartNightlyJob(ctx)
+		}
+	} else {
+		log.Println("Warning: BACKUP_GCS_BUCKET not set. Nightly backups are disabled")
+	}
+	backupController := controllers.NewBackupController(backupService)
+
+	// Partner digest of each day's end-of-day data, sent once the day is final
+	dataDigestService := pipeline.dataDigest
+	if !pubSubScheduled {
+		dataDigestService.StartScheduler(ctx)
+	}
+	dataDigestController := controllers.NewDataDigestController(dataDigestService)
+	// Intraday polls and the end-of-day crawl on trading days (crawler.schedule, off by default)
+	crawlerService.StartSchedule(ctx)
+	priceStorageController := controllers.NewPriceStorageController(services.NewPriceStorageService())
+	exchangeController := controllers.NewExchangeController()
+	priceStreamService := services.NewPriceStreamService()
+	priceStreamService.StartWatching(ctx)
+	// Trades pushed by REALTIME_SOURCE, relayed to the stream and stored as today's candles
+	startRealtimeQuotes(ctx, pipeline.stocks, priceStreamService)
+	streamController := controllers.NewStreamController(priceStreamService, models.SplitList(os.Getenv("WEBSOCKET_ALLOWED_ORIGINS")))
+	dashboardController := controllers.NewDashboardController(services.NewDashboardService())
+	overviewController := controllers.NewOverviewController(crawlerService, alertService)
+	statusService := services.NewStatusService(alertService)
+	statusController := controllers.NewStatusController(statusService)
+	// Expensive routes answer 503 while memory or the crawl and job queues are above their thresholds
+	loadShedder := services.NewLoadShedder()
+	loadShedder.TrackQueue("crawl", crawlerService.PendingSymbols)
+	loadShedder.TrackQueue("jobs", jobQueue.RunningJobs)
+	loadShedder.StartMonitor(ctx)
+	shedUnderLoad := middleware.SheddableUnderLoad(loadShedder)
+	healthController := controllers.NewHealthController(services.NewHealthService(alertService), loadShedder)
+	telegramController := controllers.NewTelegramController(services.NewTelegramBot(telegramService, crawlerService, statusService))
+	pubSubConfig, err := services.PubSubConfigFromEnv()
+	if err != nil {
+		log.Fatalf("FATAL: Invalid Pub/Sub configuration: %v", err)
+	}
+	pubSubService := services.NewPubSubPushService(pubSubConfig, crawlerService, backupService, dataDigestService)
+	if pubSubScheduled && !pubSubService.Configured() {
+		log.Println("Warning: SCHEDULER=pubsub but neither PUBSUB_AUDIENCE nor PUBSUB_VERIFICATION_TOKEN is set. Scheduled jobs will not run")
+	}
+	pubSubController := controllers.NewPubSubController(pubSubService)
+
+	priceAlertController := controllers.NewPriceAlertController(pipeline.priceAlerts)
+	watchlistController := controllers.NewWatchlistController(services.NewWatchlistService(stockService))
+	portfolioController := controllers.NewPortfolioController(services.NewPortfolioService(stockService))
+	tcbsController := controllers.NewTCBSController(newTCBSService())
+	privacyController := controllers.NewPrivacyController(services.NewPrivacyService(auditService))
+	searchController := controllers.NewSearchController(services.NewSearchService())
+	runbookController := controllers.NewRunbookController(services.NewRunbookService(stockService, jobQueue, loadShedder, auditService))
+
+	// Admin routes (with session-based authentication; forms and fetch calls carry a CSRF token)
+	// Routes declare the data stores they use: while one is down they answer
+	// 503, and routes that do not need it keep working
+	usesPostgres := middleware.RequireStores(config.StorePostgres)
+	usesMongo := middleware.RequireStores(config.StoreMongo)
+	usesBoth := middleware.RequireStores(config.StorePostgres, config.StoreMongo)
+
+	// JSON timestamps are shown in each admin's preferred timezone
+	admin := router.Group("/admin", middleware.CSRFProtect(), middleware.AdminTimezone())
 	{
 		// Public routes (no auth required)
 		admin.GET("/login", adminController.ShowLoginPage)
-		admin.POST("/login", adminController.ProcessLogin)
+		admin.POST("/login", usesPostgres, adminController.ProcessLogin)
 
 		// Protected routes (auth required)
 		admin.GET("/dashboard", middleware.AuthRequired(), adminController.ShowDashboard)
 		admin.GET("/users", middleware.AuthRequired(), adminController.ShowUsers)
 		admin.GET("/logout", middleware.AuthRequired(), adminController.Logout)
 
+		// Global search across admin users, profiles, stocks and crawl runs
+		admin.GET("/api/search", middleware.AuthRequired(), usesBoth, searchController.Search)
+
 		// User management API endpoints
-		admin.GET("/api/admin-users", middleware.AuthRequired(), adminController.GetAdminUsers)
-		admin.GET("/api/profiles", middleware.AuthRequired(), adminController.GetProfiles)
+		admin.GET("/api/admin-users", middleware.AuthRequired(), usesPostgres, adminController.GetAdminUsers)
+		admin.POST("/api/admin-users/:id/unlock", middleware.AuthRequired(), usesPostgres, adminController.UnlockAdminUser)
+		admin.GET("/api/admin-users/:id/login-history", middleware.AuthRequired(), usesPostgres, adminController.GetLoginHistory)
+		admin.GET("/api/audit-logs", middleware.AuthRequired(), usesPostgres, auditController.ListAuditLogs)
+		admin.GET("/api/audit-logs/activity", middleware.AuthRequired(), usesPostgres, auditController.GetAuditActivity)
+		admin.GET("/api/audit-logs/entities", middleware.AuthRequired(), usesPostgres, auditController.GetModifiedEntities)
+		admin.GET("/api/audit-logs/sensitive", middleware.AuthRequired(), usesPostgres, auditController.ListSensitiveActions)
+		admin.GET("/api/profiles", middleware.AuthRequired(), usesPostgres, adminController.GetProfiles)
+		admin.POST("/api/profiles/lookup", middleware.AuthRequired(), usesPostgres, adminController.LookupProfiles)
+		admin.PUT("/api/profiles/:id", middleware.AuthRequired(), usesPostgres, adminController.UpdateProfile)
+		admin.GET("/api/profiles/:id/export", middleware.AuthRequired(), usesPostgres, shedUnderLoad, privacyController.ExportProfile)
+		admin.POST("/api/profiles/:id/erase", middleware.AuthRequired(), usesPostgres, privacyController.EraseProfile)
+		admin.GET("/api/data-erasures", middleware.AuthRequired(), usesPostgres, privacyController.ListErasures)
+
+		// Alert rule management
+		admin.GET("/alerts", middleware.AuthRequired(), alertController.ShowAlertsPage)
+		admin.GET("/api/alert-rules", middleware.AuthRequired(), usesPostgres, alertController.ListRules)
+		admin.POST("/api/alert-rules", middleware.AuthRequired(), usesPostgres, alertController.CreateRule)
+		admin.PUT("/api/alert-rules/:id", middleware.AuthRequired(), usesPostgres, alertController.UpdateRule)
+		admin.DELETE("/api/alert-rules/:id", middleware.AuthRequired(), usesPostgres, alertController.DeleteRule)
+		admin.GET("/api/alert-metrics", middleware.AuthRequired(), usesMongo, middleware.ConcurrencyLimit("alert_metrics"), alertController.GetMetrics)
+
+		// Zalo Official Account messages and their delivery status
+		admin.GET("/api/zalo/messages", middleware.AuthRequired(), usesPostgres, zaloController.ListMessages)
+		admin.POST("/api/zalo/test", middleware.AuthRequired(), usesPostgres, zaloController.SendTest)
+
+		// Outbound webhook endpoints and delivery log
+		admin.GET("/api/webhooks", middleware.AuthRequired(), usesPostgres, webhookController.ListEndpoints)
+		admin.POST("/api/webhooks", middleware.AuthRequired(), usesPostgres, webhookController.CreateEndpoint)
+		admin.PUT("/api/webhooks/:id", middleware.AuthRequired(), usesPostgres, webhookController.UpdateEndpoint)
+		admin.DELETE("/api/webhooks/:id", middleware.AuthRequired(), usesPostgres, webhookController.DeleteEndpoint)
+		admin.POST("/api/webhooks/:id/ping", middleware.AuthRequired(), usesPostgres, webhookController.PingEndpoint)
+		admin.GET("/api/webhook-deliveries", middleware.AuthRequired(), usesPostgres, webhookController.ListDeliveries)
+		admin.POST("/api/webhook-deliveries/:id/redeliver", middleware.AuthRequired(), usesPostgres, webhookController.Redeliver)
+
+		// Data provider credentials and connectivity tests
+		admin.GET("/api/provider-credentials", middleware.AuthRequired(), usesPostgres, credentialController.ListProviders)
+		admin.PUT("/api/provider-credentials/:provider/:name", middleware.AuthRequired(), usesPostgres, credentialController.SetCredential)
+		admin.DELETE("/api/provider-credentials/:provider/:name", middleware.AuthRequired(), usesPostgres, credentialController.DeleteCredential)
+		admin.POST("/api/providers/:provider/test", middleware.AuthRequired(), usesPostgres, credentialController.TestProvider)
+
+		// API key management for external data consumers
+		admin.GET("/api/api-keys", middleware.AuthRequired(), usesPostgres, apiKeyController.ListKeys)
+		admin.POST("/api/api-keys", middleware.AuthRequired(), usesPostgres, apiKeyController.CreateKey)
+		admin.PUT("/api/api-keys/:id/response-format", middleware.AuthRequired(), usesPostgres, apiKeyController.UpdateResponseFormat)
+		admin.DELETE("/api/api-keys/:id", middleware.AuthRequired(), usesPostgres, apiKeyController.RevokeKey)
+
+		// Recovery procedures run without shell access (audited; not gated on stores, which they may recover)
+		admin.GET("/api/runbook", middleware.AuthRequired(), runbookController.ListActions)
+		admin.POST("/api/runbook/:action", middleware.AuthRequired(), runbookController.RunAction)
+
+		// Runtime configuration (reloaded without restarting the instance)
+		admin.GET("/api/config", middleware.AuthRequired(), settingsController.GetConfig)
+		admin.POST("/api/config/reload", middleware.AuthRequired(), usesPostgres, settingsController.Reload)
+		admin.PUT("/api/settings/:key", middleware.AuthRequired(), usesPostgres, settingsController.SetSetting)
+		admin.DELETE("/api/settings/:key", middleware.AuthRequired(), usesPostgres, settingsController.DeleteSetting)
+		// Crawler settings page: data sources, workers, schedule and exchanges, stored as crawler.* settings
+		admin.GET("/api/crawler/config", middleware.AuthRequired(), crawlerConfigController.GetConfig)
+		admin.PUT("/api/crawler/config", middleware.AuthRequired(), usesPostgres, crawlerConfigController.UpdateConfig)
+
+		// Canary routes: candidate vs control latency and errors (split in canary.percent / canary.subjects)
+		admin.GET("/api/canary", middleware.AuthRequired(), canaryController.GetStats)
+		admin.POST("/api/canary/reset", middleware.AuthRequired(), canaryController.ResetStats)
+
+		// Per-symbol crawl failures with bulk retry, blacklist and acknowledge
+		admin.GET("/crawl-errors", middleware.AuthRequired(), crawlErrorController.ShowCrawlErrorsPage)
+		admin.GET("/api/crawl-errors", middleware.AuthRequired(), usesMongo, crawlErrorController.ListErrors)
+		admin.POST("/api/crawl-errors/bulk", middleware.AuthRequired(), usesMongo, shedUnderLoad, crawlErrorController.BulkAction)
+		admin.GET("/api/suspect-candles", middleware.AuthRequired(), usesMongo, suspectCandleController.ListSuspects)
+		admin.POST("/api/suspect-candles/:id/accept", middleware.AuthRequired(), usesMongo, suspectCandleController.AcceptSuspect)
+		admin.POST("/api/suspect-candles/:id/reject", middleware.AuthRequired(), usesMongo, suspectCandleController.RejectSuspect)
+
+		// Ticker renames and exchange transfers
+		admin.GET("/api/symbol-changes", middleware.AuthRequired(), usesMongo, symbolController.ListChanges)
+		admin.POST("/api/symbol-changes", middleware.AuthRequired(), usesMongo, symbolController.CreateChange)
+		admin.DELETE("/api/symbol-changes/:id", middleware.AuthRequired(), usesMongo, symbolController.DeleteChange)
+		admin.GET("/api/screener-presets", middleware.AuthRequired(), usesPostgres, screenerPresetController.ListSharedPresets)
+		admin.POST("/api/screener-presets", middleware.AuthRequired(), usesPostgres, screenerPresetController.CreateSharedPreset)
+		admin.PUT("/api/screener-presets/:id", middleware.AuthRequired(), usesPostgres, screenerPresetController.UpdateSharedPreset)
+		admin.DELETE("/api/screener-presets/:id", middleware.AuthRequired(), usesPostgres, screenerPresetController.DeleteSharedPreset)
+
+		// Daily stock universe snapshots (listings, delistings and attribute
+		// changes), also served under /api/admin/universe
+		admin.GET("/api/universe/snapshots", middleware.AuthRequired(), usesMongo, universeController.ListSnapshots)
+		admin.GET("/api/universe/diff", middleware.AuthRequired(), usesMongo, universeController.Diff)
+
+		// Price data integrity verification
+		admin.POST("/api/integrity/verify", middleware.AuthRequired(), usesMongo, shedUnderLoad, middleware.ConcurrencyLimit("integrity_verify"), integrityController.Verify)
+
+		// Nightly backups to Google Cloud Storage
+		admin.GET("/api/backups", middleware.AuthRequired(), backupController.ListBackups)
+		admin.GET("/api/backups/:date", middleware.AuthRequired(), backupController.GetBackup)
+		admin.POST("/api/backups/run", middleware.AuthRequired(), usesBoth, shedUnderLoad, backupController.RunBackup)
+
+		// Background job queue
+		admin.GET("/api/jobs", middleware.AuthRequired(), usesPostgres, jobController.ListJobs)
+		admin.GET("/api/jobs/:id", middleware.AuthRequired(), usesPostgres, jobController.GetJob)
+		admin.POST("/api/jobs/:id/retry", middleware.AuthRequired(), usesPostgres, jobController.RetryJob)
+		admin.POST("/api/jobs/:id/cancel", middleware.AuthRequired(), usesPostgres, jobController.CancelJob)
+
+		// End-of-day data digests sent to partners
+		admin.GET("/api/digests/:date", middleware.AuthRequired(), usesMongo, dataDigestController.GetDigest)
+		admin.POST("/api/digests/:date", middleware.AuthRequired(), usesMongo, dataDigestController.PublishDigest)
+
+		// Price bucket storage encoding (plain or columnar)
+		admin.GET("/api/price-storage", middleware.AuthRequired(), usesMongo, priceStorageController.GetStats)
+		admin.POST("/api/price-storage/convert", middleware.AuthRequired(), usesMongo, shedUnderLoad, middleware.ConcurrencyLimit("price_storage_convert"), priceStorageController.Convert)
+
+		// Per-admin dashboard widgets
+		admin.GET("/api/dashboard/widget-types", middleware.AuthRequired(), dashboardController.ListWidgetTypes)
+		admin.GET("/api/dashboard/widgets", middleware.AuthRequired(), usesPostgres, dashboardController.ListWidgets)
+		admin.POST("/api/dashboard/widgets", middleware.AuthRequired(), usesPostgres, dashboardController.CreateWidget)
+		admin.PUT("/api/dashboard/widgets/:id", middleware.AuthRequired(), usesPostgres, dashboardController.UpdateWidget)
+		admin.DELETE("/api/dashboard/widgets/:id", middleware.AuthRequired(), usesPostgres, dashboardController.DeleteWidget)
+		admin.PUT("/api/dashboard/layout", middleware.AuthRequired(), usesPostgres, dashboardController.SaveLayout)
+		admin.POST("/api/dashboard/reset", middleware.AuthRequired(), usesPostgres, dashboardController.ResetWidgets)
+
+		// Per-admin language, timezone and page size
+		admin.GET("/api/preferences", middleware.AuthRequired(), usesPostgres, adminPreferenceController.GetPreferences)
+		admin.PUT("/api/preferences", middleware.AuthRequired(), usesPostgres, adminPreferenceController.UpdatePreferences)
+	}
+
+	// Per-key / per-IP rate limiting (limits per route group in rate_limit.limits)
+	rateLimiter := services.NewRateLimiter()
+
+	// Admin JSON routes under /api/admin, with the session authentication of
+	// the dashboard routes
+	adminAPI := router.Group("/api/admin", middleware.CSRFProtect(), middleware.AdminTimezone())
+	{
+		adminAPI.GET("/universe/snapshots", middleware.AuthRequired(), usesMongo, universeController.ListSnapshots)
+		adminAPI.GET("/universe/diff", middleware.AuthRequired(), usesMongo, universeController.Diff)
 	}
 
-	// API routes
-	api := router.Group("/api")
+	// Token endpoints (credentials or refresh token required, no bearer token)
+	authAPI := router.Group("/api/auth", middleware.RateLimit("auth", rateLimiter), usesPostgres, middleware.ResponseFormat())
 	{
-		crawler := api.Group("/crawler")
+		authAPI.POST("/token", authController.IssueToken)
+		authAPI.POST("/refresh", authController.RefreshToken)
+	}
+
+	// Public API changelog and deprecations (unauthenticated)
+	metaController := controllers.NewMetaController()
+	router.GET(middleware.APIChangesPath, middleware.RateLimit("stocks", rateLimiter), middleware.ResponseFormat(), metaController.GetChanges)
+
+	// Payment provider webhooks (authenticated by their signature; upgrades memberships)
+	paymentService, err := services.NewPaymentServiceFromEnv(auditService)
+	if err != nil {
+		log.Fatalf("Failed to configure payments: %v", err)
+	}
+	if len(paymentService.Providers()) == 0 {
+		log.Println("Warning: PAYMENT_WEBHOOK_SECRETS not set. Payment webhooks are disabled")
+	}
+	paymentController := controllers.NewPaymentController(paymentService)
+	router.POST("/api/payments/webhook", middleware.RateLimit("payments", rateLimiter), usesPostgres, paymentController.HandleWebhook)
+	router.GET("/api/payments/webhook", middleware.RateLimit("payments", rateLimiter), usesPostgres, paymentController.HandleWebhook) // VNPay IPN
+
+	// Health check endpoint (?deep=true also checks databases, crawl age and upstream APIs)
+	router.GET("/health", healthController.GetHealth)
+
+	// Public status page data (unauthenticated, cached)
+	router.GET("/status", middleware.RateLimit("status", rateLimiter), statusController.GetStatus)
+
+	// Telegram bot commands from the operations chat (authenticated by the webhook secret token)
+	router.POST("/api/telegram/webhook", middleware.RateLimit("telegram", rateLimiter), telegramController.HandleWebhook)
+
+	// Zalo OA delivery and read receipts (authenticated by their signature)
+	router.POST("/api/zalo/webhook", middleware.RateLimit("zalo", rateLimiter), usesPostgres, zaloController.HandleWebhook)
+
+	// Cloud Scheduler triggers delivered by a Pub/Sub push subscription (authenticated by its OIDC token)
+	router.POST("/api/pubsub/push", middleware.RateLimit("pubsub", rateLimiter), usesMongo, pubSubController.HandlePush)
+
+	// Member self-service routes (Supabase access token required)
+	me := router.Group("/api/me", middleware.MemberAuthRequired(memberAuthService), middleware.RateLimit("me", rateLimiter), usesPostgres, middleware.ResponseFormat())
+	{
+		me.GET("/features", featureController.ListFeatures)
+		me.GET("/tokens", personalTokenController.ListTokens)
+		me.POST("/tokens", personalTokenController.CreateToken)
+		me.DELETE("/tokens/:id", personalTokenController.RevokeToken)
+		me.GET("/alerts", priceAlertController.ListAlerts)
+		me.POST("/alerts", usesMongo, priceAlertController.CreateAlert)
+		me.GET("/alerts/history", priceAlertController.ListHistory)
+		me.PUT("/alerts/:id", usesMongo, priceAlertController.UpdateAlert)
+		me.DELETE("/alerts/:id", priceAlertController.DeleteAlert)
+		me.GET("/screens", screenerPresetController.ListPresets)
+		me.POST("/screens", screenerPresetController.CreatePreset)
+		me.PUT("/screens/:id", screenerPresetController.UpdatePreset)
+		me.DELETE("/screens/:id", screenerPresetController.DeletePreset)
+		me.GET("/screens/:id/run", usesMongo, middleware.RequireFeature(featureService, models.FeatureScreener), shedUnderLoad, middleware.ConcurrencyLimit("screener"), screenerPresetController.RunPreset)
+		me.GET("/portfolio", usesMongo, portfolioController.GetValuation)
+		me.GET("/portfolio/performance", usesMongo, portfolioController.GetPerformance)
+		me.GET("/portfolio/trades", portfolioController.ListTrades)
+		me.POST("/portfolio/trades", usesMongo, portfolioController.RecordTrade)
+		me.DELETE("/portfolio/trades/:id", portfolioController.DeleteTrade)
+		me.GET("/tcbs/portfolio", tcbsController.GetPortfolio)
+		me.POST("/tcbs/connect", tcbsController.Connect)
+		me.DELETE("/tcbs/disconnect", tcbsController.Disconnect)
+		me.GET("/data-export", shedUnderLoad, privacyController.ExportOwnData)
+		me.POST("/erase", privacyController.EraseOwnData)
+	}
+
+	// Member watchlists (Supabase Auth token required)
+	watchlists := router.Group("/api/watchlists", middleware.MemberAuthRequired(memberAuthService), middleware.RateLimit("me", rateLimiter), usesPostgres, middleware.ResponseFormat())
+	{
+		watchlists.GET("", watchlistController.ListWatchlists)
+		watchlists.POST("", usesMongo, watchlistController.CreateWatchlist)
+		watchlists.GET("/:id", watchlistController.GetWatchlist)
+		watchlists.PUT("/:id", watchlistController.RenameWatchlist)
+		watchlists.DELETE("/:id", watchlistController.DeleteWatchlist)
+		watchlists.GET("/:id/quotes", usesMongo, watchlistController.GetQuotes)
+		watchlists.POST("/:id/symbols", usesMongo, watchlistController.AddSymbols)
+		watchlists.DELETE("/:id/symbols/:code", watchlistController.RemoveSymbol)
+	}
+
+	// API routes (API key, JWT or personal access token, or admin session required).
+	// Members are held to the rate limit and history depth of their membership tier.
+	// Responses are reshaped to the naming/envelope format selected per key or request.
+	api := router.Group("/api", middleware.APIAuthRequired(tokenService, apiKeyService, personalTokenService), middleware.MembershipTier(featureService), middleware.ResponseFormat())
+	{
+		crawler := api.Group("/crawler", middleware.RateLimit("crawler", rateLimiter), usesMongo)
+		{
+			crawler.POST("/start", middleware.RequireScope(models.ScopeTriggerCrawl), shedUnderLoad, crawlerController.TriggerCrawl)
+			crawler.GET("/status", middleware.RequireScope(models.ScopeReadPrices), crawlerController.GetStatus)
+		}
+
+		api.GET("/exchanges", middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), exchangeController.ListExchanges)
+		api.GET("/overview", usesMongo, middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), overviewController.GetOverview)
+		api.GET("/signals", usesMongo, middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), signalController.ListSignals)
+		api.GET("/patterns", usesMongo, middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), signalController.ListPatterns)
+		api.GET("/market/sector-breadth", usesMongo, middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), marketController.GetSectorBreadth)
+		api.GET("/market/movers", usesMongo, middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), marketController.GetMovers)
+		api.GET("/market/volume-anomalies", usesMongo, middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), marketController.GetVolumeAnomalies)
+		api.GET("/market/new-highs-lows", usesMongo, middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), marketController.GetNewHighsLows)
+		api.GET("/market/eod/:date", usesMongo, middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), dataDigestController.GetEODData)
+		api.GET("/market/screener", usesMongo, middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), middleware.RequireFeature(featureService, models.FeatureScreener), shedUnderLoad, middleware.ConcurrencyLimit("screener"), marketController.GetScreener)
+
+		stocks := api.Group("/stocks", middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), usesMongo)
+		{
+			stocks.GET("/metadata", middleware.ConcurrencyLimit("stock_metadata"), stockController.GetMetadata)
+			stocks.GET("/sparklines", stockController.GetSparklines)
+			stocks.GET("/search", stockController.Search)
+			stocks.GET("/sectors", stockController.GetSectors)
+			stocks.GET("/:code/candles", middleware.Canary(canaryMetrics, "candles", stockController.GetCandlesFilteredInDB), stockController.GetCandles)
+			stocks.GET("/:code/detail", stockController.GetDetail)
+			stocks.GET("/:code/moving-averages", stockController.GetMovingAverages)
+			stocks.GET("/:code/symbol-history", stockController.GetSymbolHistory)
+			stocks.GET("/:code/history", stockController.GetHistory)
+			stocks.GET("/:code/checksums", integrityController.GetChecksums)
+		}
+
+		derivatives := api.Group("/derivatives", middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), usesMongo)
+		{
+			derivatives.GET("/contracts", derivativesController.ListContracts)
+			derivatives.GET("/:code/candles", derivativesController.GetCandles)
+		}
+
+		// Real-time price updates pushed from the price bucket change stream
+		stream := api.Group("/stream", middleware.RequireScope(models.ScopeReadPrices), middleware.RateLimit("stocks", rateLimiter), usesMongo)
 		{
-			crawler.POST("/start", crawlerController.TriggerCrawl)
-			crawler.GET("/status", crawlerController.GetStatus)
+			stream.GET("/prices", streamController.StreamPricesSSE)
+			stream.GET("/prices/ws", streamController.StreamPricesWebSocket)
 		}
 	}
 
@@ -132,10 +597,65 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("🚀 Server starting on port %s", port)
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	server := &http.Server{
+		Addr:              ":" + port,
+		Handler:           router,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	server.RegisterOnShutdown(priceStreamService.CloseAll)
+
+	// Warm up before listening: Cloud Run routes traffic to a new instance
+	// once its port accepts connections, so the first requests of a scale-up
+	// do not pay for cold connections, the stock list or template parsing
+	warmupCtx, cancelWarmup := context.WithTimeout(ctx, warmupTimeout())
+	warmupReport := services.Warmup(warmupCtx, []services.WarmupStep{
+		services.PostgresWarmupStep(),
+		services.MongoWarmupStep(),
+		services.StockListWarmupStep(stockService),
+		templateWarmupStep(router),
+	})
+	cancelWarmup()
+	if router.HTMLRender == nil {
+		log.Fatalf("FATAL: Failed to load HTML templates")
+	}
+	if failed := warmupReport.Failed(); len(failed) > 0 {
+		log.Printf("⚠️  Warm-up finished in %dms, starting degraded (failed: %s)", warmupReport.DurationMs, strings.Join(failed, ", "))
+	} else {
+		log.Printf("✓ Warm-up finished in %dms", warmupReport.DurationMs)
+	}
+	// Claim queued jobs only once every handler is registered
+	jobQueue.Start(ctx, jobWorkers())
+
+	go func() {
+		log.Printf("🚀 Server starting on port %s", port)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop() // A second signal kills the process immediately
+	timeout := shutdownTimeout()
+	log.Printf("🛑 Shutting down: draining requests and crawls (up to %s)", timeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// Stop running crawls right away, so they checkpoint while in-flight
+	// requests drain instead of after; long requests (exports, streams)
+	// would otherwise eat into the crawls' share of the grace period.
+	// Deferred calls close the databases.
+	crawlsDone := make(chan error, 1)
+	go func() { crawlsDone <- crawlerService.Shutdown(shutdownCtx) }()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  HTTP requests still in flight at shutdown: %v", err)
+	}
+	if err := <-crawlsDone; err != nil {
+		log.Printf("⚠️  %v", err)
+	}
+	if err := jobQueue.Wait(shutdownCtx); err != nil {
+		log.Printf("⚠️  %v", err)
 	}
+	log.Println("✓ Shutdown complete")
 }
 
 // corsMiddleware adds CORS headers for Cloud Run
@@ -143,7 +663,8 @@ func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key, X-API-Version, X-Response-Format, If-None-Match, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "ETag, X-Next-Since, X-Symbol, X-Symbol-Lineage, Deprecation, Sunset, Link")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
@@ -154,3 +675,112 @@ func corsMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// configReloadInterval returns how often stored settings are re-read (CONFIG_RELOAD_INTERVAL, default 1m)
+func configReloadInterval() time.Duration {
+	if raw := os.Getenv("CONFIG_RELOAD_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err == nil && interval > 0 {
+			return interval
+		}
+		log.Printf("Warning: Invalid CONFIG_RELOAD_INTERVAL %q, using default", raw)
+	}
+	return time.Minute
+}
+
+// shutdownTimeout returns how long shutdown waits for requests and crawls
+// (SHUTDOWN_TIMEOUT, default 9s: Cloud Run kills the instance 10s after SIGTERM)
+func shutdownTimeout() time.Duration {
+	if raw := os.Getenv("SHUTDOWN_TIMEOUT"); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err == nil && timeout > 0 {
+			return timeout
+		}
+		log.Printf("Warning: Invalid SHUTDOWN_TIMEOUT %q, using default", raw)
+	}
+	return 9 * time.Second
+}
+
+// jobWorkers returns how many jobs this instance runs at once (JOB_WORKERS, default 4)
+func jobWorkers() int {
+	if raw := os.Getenv("JOB_WORKERS"); raw != "" {
+		workers, err := strconv.Atoi(raw)
+		if err == nil && workers > 0 {
+			return workers
+		}
+		log.Printf("Warning: Invalid JOB_WORKERS %q, using default", raw)
+	}
+	return 4
+}
+
+// connectStore connects a data store. Configuration errors are fatal; so is
+// an unreachable store listed in REQUIRED_STORES. Any other store that cannot
+// be reached is retried in the background while the server starts degraded.
+func connectStore(name, store string, connect func() error, required []string) {
+	err := connect()
+	if err == nil {
+		return
+	}
+	if errors.Is(err, config.ErrStoreUnavailable) && !slices.Contains(required, store) {
+		log.Printf("⚠️  Starting without %s, routes using it answer 503 until it is back: %v", name, err)
+		return
+	}
+	log.Fatalf("FATAL: Failed to connect to %s: %v", name, err)
+}
+
+// storeCheckInterval is how often the data stores are pinged
+// (STORE_CHECK_INTERVAL, default 15s)
+func storeCheckInterval() time.Duration {
+	if raw := os.Getenv("STORE_CHECK_INTERVAL"); raw != "" {
+		interval, err := time.ParseDuration(raw)
+		if err == nil && interval > 0 {
+			return interval
+		}
+		log.Printf("Warning: Invalid STORE_CHECK_INTERVAL %q, using default", raw)
+	}
+	return config.DefaultStoreCheckInterval
+}
+
+// warmupTimeout bounds the warm-up routine run before the server listens
+// (WARMUP_TIMEOUT, default 30s)
+func warmupTimeout() time.Duration {
+	if raw := os.Getenv("WARMUP_TIMEOUT"); raw != "" {
+		timeout, err := time.ParseDuration(raw)
+		if err == nil && timeout > 0 {
+			return timeout
+		}
+		log.Printf("Warning: Invalid WARMUP_TIMEOUT %q, using default", raw)
+	}
+	return 30 * time.Second
+}
+
+// templateWarmupStep parses the admin page templates once. Templates loaded
+// with LoadHTMLGlob are re-parsed on every render outside gin's release mode.
+func templateWarmupStep(router *gin.Engine) services.WarmupStep {
+	return services.WarmupStep{Name: "templates", Run: func(context.Context) error {
+		tmpl, err := template.New("").Funcs(router.FuncMap).ParseGlob("templates/*")
+		if err != nil {
+			return err
+		}
+		router.SetHTMLTemplate(tmpl)
+		return nil
+	}}
+}
+
+// watchReloadSignal reloads the runtime configuration on SIGHUP
+func watchReloadSignal(settingsService *services.SettingsService) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		for range hup {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if _, err := settingsService.Reload(ctx); err != nil {
+				log.Printf("❌ SIGHUP: Failed to reload configuration (current configuration kept): %v", err)
+			} else {
+				log.Println("✓ SIGHUP: Runtime configuration reloaded")
+			}
+			cancel()
+		}
+	}()
+}
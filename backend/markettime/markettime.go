@@ -0,0 +1,97 @@
+// Package markettime anchors dates to the market's time zone. Trading days,
+// schedule times (backup.time, digest.time) and candle dates are Vietnam
+// dates whatever the server's own zone: instances run in UTC, where 06:30 in
+// Hanoi is still the previous day. Exchanges may override the zone (see
+// models.Exchange.Location); everything else reads it from here.
+package markettime
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	// Embedded zone database: the runtime image ships without /usr/share/zoneinfo
+	_ "time/tzdata"
+)
+
+// DefaultZone is the market time zone unless MARKET_TIMEZONE sets another
+const DefaultZone = "Asia/Ho_Chi_Minh"
+
+// Layouts of market dates and times of day
+const (
+	DateLayout  = "2006-01-02"
+	ClockLayout = "15:04"
+)
+
+var location atomic.Pointer[time.Location]
+
+func init() {
+	loc, err := time.LoadLocation(DefaultZone)
+	if err != nil {
+		loc = time.FixedZone("ICT", 7*60*60)
+	}
+	location.Store(loc)
+}
+
+// Location returns the market time zone
+func Location() *time.Location {
+	return location.Load()
+}
+
+// SetLocation sets the market time zone by IANA name
+func SetLocation(name string) error {
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" {
+		return fmt.Errorf("unknown time zone %q", name)
+	}
+	location.Store(loc)
+	return nil
+}
+
+// Now returns the current time in the market time zone
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// Today returns the current market date (YYYY-MM-DD)
+func Today() string {
+	return Date(time.Now())
+}
+
+// Date returns the market date (YYYY-MM-DD) of t
+func Date(t time.Time) string {
+	return t.In(Location()).Format(DateLayout)
+}
+
+// ParseDate returns the start of a market date (YYYY-MM-DD) in the market
+// time zone
+func ParseDate(date string) (time.Time, error) {
+	return time.ParseInLocation(DateLayout, date, Location())
+}
+
+// EndOfDate returns the last second of a market date (YYYY-MM-DD)
+func EndOfDate(date string) (time.Time, error) {
+	day, err := ParseDate(date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.AddDate(0, 0, 1).Add(-time.Second), nil
+}
+
+// NextAt returns the first time after now that the clock reads hhmm (HH:MM)
+// in loc, or in the market time zone when loc is nil
+func NextAt(now time.Time, hhmm string, loc *time.Location) (time.Time, error) {
+	at, err := time.Parse(ClockLayout, hhmm)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a time like 02:30: %w", err)
+	}
+	if loc == nil {
+		loc = Location()
+	}
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+	if !next.After(local) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
@@ -0,0 +1,52 @@
+package markettime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateUsesMarketZone(t *testing.T) {
+	// 18:30 UTC is 01:30 the next day in Vietnam
+	late := time.Date(2026, 10, 14, 18, 30, 0, 0, time.UTC)
+	if got := Date(late); got != "2026-10-15" {
+		t.Errorf("Date(2026-10-14 18:30 UTC) = %s; want 2026-10-15", got)
+	}
+
+	day, err := ParseDate("2026-10-15")
+	if err != nil || !day.Equal(time.Date(2026, 10, 14, 17, 0, 0, 0, time.UTC)) {
+		t.Errorf("ParseDate(2026-10-15) = %v, %v; want midnight in Vietnam", day, err)
+	}
+	end, err := EndOfDate("2026-10-15")
+	if err != nil || Date(end) != "2026-10-15" || Date(end.Add(time.Second)) != "2026-10-16" {
+		t.Errorf("EndOfDate(2026-10-15) = %v, %v; want its last second", end, err)
+	}
+}
+
+func TestSetLocation(t *testing.T) {
+	defer SetLocation(DefaultZone)
+	if err := SetLocation("Mars/Olympus"); err == nil {
+		t.Error("SetLocation(Mars/Olympus) succeeded; want an error")
+	}
+	if err := SetLocation("UTC"); err != nil || Date(time.Date(2026, 10, 14, 18, 30, 0, 0, time.UTC)) != "2026-10-14" {
+		t.Errorf("SetLocation(UTC) = %v; want UTC dates", err)
+	}
+}
+
+func TestNextAt(t *testing.T) {
+	vietnam := time.FixedZone("ICT", 7*60*60)
+	before := time.Date(2026, 2, 10, 1, 0, 0, 0, vietnam)
+	if got, err := NextAt(before, "02:30", vietnam); err != nil || !got.Equal(time.Date(2026, 2, 10, 2, 30, 0, 0, vietnam)) {
+		t.Errorf("NextAt(01:00) = %v, %v; want 02:30 the same day", got, err)
+	}
+	after := time.Date(2026, 2, 10, 2, 30, 0, 0, vietnam)
+	if got, err := NextAt(after, "02:30", vietnam); err != nil || !got.Equal(time.Date(2026, 2, 11, 2, 30, 0, 0, vietnam)) {
+		t.Errorf("NextAt(02:30) = %v, %v; want 02:30 the next day", got, err)
+	}
+	// Without a zone the market's applies: 20:00 UTC is 03:00 in Vietnam
+	if got, err := NextAt(time.Date(2026, 2, 10, 20, 0, 0, 0, time.UTC), "02:30", nil); err != nil || Date(got) != "2026-02-12" {
+		t.Errorf("NextAt(20:00 UTC) = %v, %v; want 02:30 on 2026-02-12 in Vietnam", got, err)
+	}
+	if _, err := NextAt(before, "2:30pm", nil); err == nil {
+		t.Error("NextAt(2:30pm) succeeded; want an error")
+	}
+}
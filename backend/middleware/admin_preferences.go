@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Session keys of the signed-in admin's preferences, stored at login and
+// when they are saved so pages render without a database round trip
+const (
+	sessionLanguage = "language"
+	sessionTimezone = "timezone"
+	sessionPageSize = "page_size"
+)
+
+// StoreAdminPreferences copies preferences into the admin session; the
+// caller saves the session
+func StoreAdminPreferences(session sessions.Session, preferences models.AdminPreferences) {
+	session.Set(sessionLanguage, preferences.Language)
+	session.Set(sessionTimezone, preferences.Timezone)
+	session.Set(sessionPageSize, preferences.PageSize)
+}
+
+// AdminPreferences returns the signed-in admin's preferences from the
+// session, defaults filling in what is missing
+func AdminPreferences(c *gin.Context) models.AdminPreferences {
+	session := sessions.Default(c)
+	preferences := models.DefaultAdminPreferences()
+	if language, ok := session.Get(sessionLanguage).(string); ok && language != "" {
+		preferences.Language = language
+	}
+	if timezone, ok := session.Get(sessionTimezone).(string); ok {
+		preferences.Timezone = timezone
+	}
+	if pageSize, ok := session.Get(sessionPageSize).(int); ok && pageSize > 0 {
+		preferences.PageSize = pageSize
+	}
+	return preferences
+}
+
+// AdminTimezone rewrites the timestamps of JSON responses into the signed-in
+// admin's preferred timezone. They denote the same instants, so clients
+// parsing them are unaffected; admins without a preferred timezone get the
+// responses untouched.
+func AdminTimezone() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		location := AdminPreferences(c).Location()
+		if location == nil {
+			c.Next()
+			return
+		}
+
+		writer := &formatWriter{ResponseWriter: c.Writer, transform: func(body []byte, _ http.Header) ([]byte, error) {
+			return localizeTimestamps(body, location)
+		}}
+		c.Writer = writer
+		c.Next()
+		writer.finish()
+	}
+}
+
+// localizeTimestamps rewrites the RFC 3339 timestamps of a JSON document in location
+func localizeTimestamps(body []byte, location *time.Location) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(localizeValue(document, location))
+}
+
+// localizeValue rewrites the timestamps found in value
+func localizeValue(value interface{}, location *time.Location) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = localizeValue(item, location)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = localizeValue(item, location)
+		}
+	case string:
+		// Dates without a time of day ("2026-02-14") are not instants
+		if len(v) < len("2006-01-02T15:04:05Z") {
+			return v
+		}
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return t.In(location).Format(time.RFC3339Nano)
+		}
+	}
+	return value
+}
@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+)
+
+// Authentication methods recorded in ContextAuthMethod
+const (
+	AuthMethodJWT     = "jwt"
+	AuthMethodSession = "session"
+	AuthMethodAPIKey  = "api_key"
+	// AuthMethodPersonalToken is a member's personal access token
+	AuthMethodPersonalToken = "personal_token"
+	// AuthMethodMember is a member's Supabase access token (member routes only)
+	AuthMethodMember = "member"
+)
+
+// Context keys set by APIAuthRequired for downstream handlers
+const (
+	ContextAuthMethod  = "auth_method"  // One of the AuthMethod* values
+	ContextAuthSubject = "auth_subject" // Admin user ID (JWT), username (session), API key ID or profile ID (members)
+	ContextAuthName    = "auth_name"    // Username, email, API key name or token name
+	ContextAuthRole    = "auth_role"    // Admin role (empty for API keys and personal tokens)
+	ContextAuthScopes  = "auth_scopes"  // Granted scopes ([]string, API keys and personal tokens only)
+)
+
+// APIAuthRequired authenticates JSON API requests. It accepts, in order:
+//   - an API key in the "X-API-Key" header (external data consumers)
+//   - a JWT access token or a member's personal access token in the
+//     "Authorization: Bearer" header; the admin or member behind it must
+//     still be active
+//   - the admin dashboard session cookie, so logged-in admins can open API
+//     links (state-changing requests must carry the session's CSRF token)
+func APIAuthRequired(tokenService *services.TokenService, apiKeyService *services.APIKeyService, personalTokenService *services.PersonalTokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+			key, err := apiKeyService.Authenticate(strings.TrimSpace(apiKey))
+			if err != nil {
+				if !errors.Is(err, services.ErrInvalidAPIKey) {
+					if !config.PostgresAvailable() {
+						abortStoresUnavailable(c, []string{config.StorePostgres})
+						return
+					}
+					logging.FromContext(c.Request.Context()).Error("APIAuthRequired failed", logging.FieldError, err)
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+						"status":  "error",
+						"message": "Failed to verify API key",
+					})
+					return
+				}
+				abortUnauthorized(c, "Invalid or revoked API key")
+				return
+			}
+
+			c.Set(ContextAuthMethod, AuthMethodAPIKey)
+			c.Set(ContextAuthSubject, key.ID.String())
+			c.Set(ContextAuthName, key.Name)
+			c.Set(ContextAuthScopes, key.ScopeList())
+			c.Set(ContextResponseFormat, key.ResponseFormat)
+			c.Next()
+			return
+		}
+
+		if header := c.GetHeader("Authorization"); header != "" {
+			scheme, token, found := strings.Cut(header, " ")
+			if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+				abortUnauthorized(c, "Authorization header must use the Bearer scheme")
+				return
+			}
+
+			if token = strings.TrimSpace(token); services.IsPersonalToken(token) {
+				pat, err := personalTokenService.Authenticate(token)
+				if err != nil {
+					if !errors.Is(err, services.ErrInvalidPersonalToken) {
+						if !config.PostgresAvailable() {
+							abortStoresUnavailable(c, []string{config.StorePostgres})
+							return
+						}
+						logging.FromContext(c.Request.Context()).Error("APIAuthRequired failed", logging.FieldError, err)
+						c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+							"status":  "error",
+							"message": "Failed to verify personal access token",
+						})
+						return
+					}
+					abortUnauthorized(c, "Invalid, revoked or expired personal access token")
+					return
+				}
+
+				c.Set(ContextAuthMethod, AuthMethodPersonalToken)
+				c.Set(ContextAuthSubject, pat.ProfileID.String())
+				c.Set(ContextAuthName, pat.Name)
+				c.Set(ContextAuthScopes, pat.ScopeList())
+				c.Next()
+				return
+			}
+
+			claims, err := tokenService.Verify(token, services.TokenTypeAccess)
+			if err != nil {
+				message := "Invalid access token"
+				if errors.Is(err, services.ErrExpiredToken) {
+					message = "Access token has expired"
+				}
+				abortUnauthorized(c, message)
+				return
+			}
+			if err := tokenService.CheckActive(c.Request.Context(), claims); err != nil {
+				switch {
+				case errors.Is(err, services.ErrAdminInactive):
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+						"status":  "error",
+						"message": "Account is deactivated",
+					})
+				case errors.Is(err, services.ErrInvalidToken):
+					abortUnauthorized(c, "Invalid access token")
+				case !config.PostgresAvailable():
+					abortStoresUnavailable(c, []string{config.StorePostgres})
+				default:
+					logging.FromContext(c.Request.Context()).Error("APIAuthRequired failed", logging.FieldError, err)
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+						"status":  "error",
+						"message": "Failed to verify access token",
+					})
+				}
+				return
+			}
+
+			c.Set(ContextAuthMethod, AuthMethodJWT)
+			c.Set(ContextAuthSubject, claims.Subject)
+			c.Set(ContextAuthName, claims.Name)
+			c.Set(ContextAuthRole, claims.Role)
+			c.Next()
+			return
+		}
+
+		session := sessions.Default(c)
+		if user, ok := session.Get("user").(string); ok && user != "" {
+			// Browsers attach the session cookie to cross-site requests too
+			if !validCSRFRequest(c, session) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"status":  "error",
+					"message": "Invalid or missing CSRF token",
+				})
+				return
+			}
+
+			role, _ := session.Get("role").(string)
+			c.Set(ContextAuthMethod, AuthMethodSession)
+			c.Set(ContextAuthSubject, user)
+			c.Set(ContextAuthName, user)
+			c.Set(ContextAuthRole, role)
+			c.Next()
+			return
+		}
+
+		abortUnauthorized(c, "Authentication required")
+	}
+}
+
+// RequireScope restricts a route to API keys and personal tokens granted the
+// given scope. Admins authenticated by JWT or session have every scope.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if method := c.GetString(ContextAuthMethod); method == AuthMethodJWT || method == AuthMethodSession {
+			c.Next()
+			return
+		}
+
+		scopes, _ := c.Get(ContextAuthScopes)
+		if granted, ok := scopes.([]string); ok {
+			for _, s := range granted {
+				if s == scope {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"status":  "error",
+			"message": "Credential is missing the required scope: " + scope,
+		})
+	}
+}
+
+// abortUnauthorized stops the request with a 401 JSON error
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="cpls-api"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"status":  "error",
+		"message": message,
+	})
+}
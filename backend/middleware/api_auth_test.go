@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/datvt88/CPLS/backend/services/servicestest"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestAPIAuthRequiredRejectsDeactivatedAdmins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	active := models.AdminUser{ID: uuid.New(), Email: "active@example.com", Role: "admin", Active: true}
+	inactive := models.AdminUser{ID: uuid.New(), Email: "inactive@example.com", Role: "admin", Active: false}
+	deleted := models.AdminUser{ID: uuid.New(), Email: "deleted@example.com", Role: "admin", Active: true}
+	admins := &servicestest.UserStore{AdminUsers: []models.AdminUser{active, inactive}}
+	tokenService := services.NewTokenService([]byte("jwt-secret"), time.Hour, time.Hour, admins)
+
+	router := gin.New()
+	router.GET("/api/stocks", APIAuthRequired(tokenService, nil, nil), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name   string
+		admin  models.AdminUser
+		status int
+	}{
+		{"active", active, http.StatusNoContent},
+		{"deactivated", inactive, http.StatusForbidden},
+		{"deleted", deleted, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		pair, err := tokenService.IssueTokens(&tt.admin)
+		if err != nil {
+			t.Fatalf("%s: IssueTokens() unexpected error: %v", tt.name, err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/stocks", nil)
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d; want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
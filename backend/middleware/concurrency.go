@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// inFlight tracks simultaneous executions per limited endpoint name
+var inFlight sync.Map // name -> *atomic.Int64
+
+// ConcurrencyLimit caps simultaneous executions of an expensive endpoint.
+// Requests beyond the limit fail fast with 503 and a Retry-After header
+// instead of queueing on the database. Routes sharing a name share the
+// limit. The limit is read from the runtime configuration on every request
+// (concurrency.limits), so it can be tuned without a restart.
+func ConcurrencyLimit(name string) gin.HandlerFunc {
+	counter, _ := inFlight.LoadOrStore(name, new(atomic.Int64))
+	running := counter.(*atomic.Int64)
+
+	return func(c *gin.Context) {
+		cfg := config.Runtime()
+		limit := cfg.ConcurrencyLimit(name)
+
+		if running.Add(1) > int64(limit) {
+			running.Add(-1)
+			retryAfter := int(math.Ceil(cfg.ConcurrencyRetryAfter.Seconds()))
+			logging.FromContext(c.Request.Context()).Warn("Request rejected at concurrency limit", "limit_name", name, "limit", limit)
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"status":  "error",
+				"message": "Server is busy, please retry later",
+				"error":   "too many concurrent requests",
+			})
+			return
+		}
+		defer running.Add(-1)
+
+		c.Next()
+	}
+}
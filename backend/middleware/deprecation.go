@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+// APIChangesPath lists the API versions, changes and deprecations
+const APIChangesPath = "/api/meta/changes"
+
+// setDeprecationHeaders announces the deprecations of models.APIChangelog
+// and models.APIVersions that apply to the matched route in format: a
+// Deprecation header (RFC 9745) with the announcement date, a Sunset header
+// (RFC 8594) with the date the endpoint, field or version stops being
+// served, and a link to the changelog
+func setDeprecationHeaders(c *gin.Context, format models.ResponseFormat) {
+	deprecation, ok := models.DeprecationFor(c.Request.Method, c.FullPath(), format)
+	if !ok {
+		return
+	}
+	c.Header("Deprecation", fmt.Sprintf("@%d", deprecation.Since.Unix()))
+	if deprecation.Sunset != nil {
+		c.Header("Sunset", deprecation.Sunset.Format(http.TimeFormat))
+	}
+	c.Header("Link", fmt.Sprintf(`<%s>; rel="deprecation"; type="application/json"`, APIChangesPath))
+}
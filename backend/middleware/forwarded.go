@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ForwardedHops keeps only the last hops entries of X-Forwarded-For, those
+// appended by the proxies in front of the service, before the client IP is
+// read from it. A client can send any X-Forwarded-For of its own, which the
+// proxies append to, so when every peer is trusted (Cloud Run) the leftmost
+// entry would otherwise be the client's choice. hops <= 0 leaves the header
+// alone.
+func ForwardedHops(hops int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hops <= 0 {
+			c.Next()
+			return
+		}
+		values := c.Request.Header.Values("X-Forwarded-For")
+		if len(values) == 0 {
+			c.Next()
+			return
+		}
+
+		var entries []string
+		for _, value := range values {
+			for _, entry := range strings.Split(value, ",") {
+				if entry = strings.TrimSpace(entry); entry != "" {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		if len(entries) > hops {
+			entries = entries[len(entries)-hops:]
+		}
+		c.Request.Header.Set("X-Forwarded-For", strings.Join(entries, ", "))
+		c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestForwardedHopsIgnoresClientEntries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.RemoteIPHeaders = []string{"X-Forwarded-For"}
+	if err := router.SetTrustedProxies([]string{"0.0.0.0/0", "::/0"}); err != nil {
+		t.Fatal(err)
+	}
+	router.Use(ForwardedHops(1))
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, c.ClientIP()) })
+
+	tests := []struct {
+		name      string
+		forwarded []string
+		want      string
+	}{
+		{"front end hop only", []string{"203.0.113.7"}, "203.0.113.7"},
+		{"spoofed entry", []string{"1.2.3.4, 203.0.113.7"}, "203.0.113.7"},
+		{"repeated header", []string{"1.2.3.4", "203.0.113.7"}, "203.0.113.7"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "169.254.1.1:40000"
+		for _, value := range tt.forwarded {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if got := rec.Body.String(); got != tt.want {
+			t.Errorf("%s: ClientIP() = %s; want %s", tt.name, got, tt.want)
+		}
+	}
+}
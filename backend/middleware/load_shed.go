@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// SheddableUnderLoad marks an expensive route (exports, screener, crawls):
+// while the instance is under memory or queue pressure it answers 503 with
+// a Retry-After header, so the memory goes to the work already running.
+// Routes without it keep serving.
+func SheddableUnderLoad(shedder *services.LoadShedder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shedding, reason := shedder.Shedding()
+		if !shedding {
+			c.Next()
+			return
+		}
+
+		retryAfter := int(math.Ceil(config.Runtime().LoadShedRetryAfter.Seconds()))
+		logging.FromContext(c.Request.Context()).Warn("Request shed under load", "reason", reason)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"status":  "error",
+			"message": "Server is under heavy load, please retry later",
+			"error":   "load shedding: " + reason,
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// MemberAuthRequired authenticates members by the Supabase access token in the
+// "Authorization: Bearer" header and rejects deactivated members.
+// memberAuthService may be nil when SUPABASE_JWT_SECRET is not configured, in
+// which case member routes are unavailable.
+func MemberAuthRequired(memberAuthService *services.MemberAuthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if memberAuthService == nil {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"status":  "error",
+				"message": "Member authentication is not configured",
+			})
+			return
+		}
+
+		scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			abortUnauthorized(c, "Sign in and send your access token in the Authorization: Bearer header")
+			return
+		}
+
+		claims, err := memberAuthService.Verify(strings.TrimSpace(token))
+		if err != nil {
+			message := "Invalid access token"
+			if errors.Is(err, services.ErrExpiredToken) {
+				message = "Access token has expired"
+			}
+			abortUnauthorized(c, message)
+			return
+		}
+
+		if err := memberAuthService.CheckActive(c.Request.Context(), claims); err != nil {
+			switch {
+			case errors.Is(err, services.ErrProfileInactive):
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+					"status":  "error",
+					"message": "Account is deactivated",
+				})
+			case errors.Is(err, services.ErrInvalidToken):
+				abortUnauthorized(c, "Invalid access token")
+			case !config.PostgresAvailable():
+				abortStoresUnavailable(c, []string{config.StorePostgres})
+			default:
+				logging.FromContext(c.Request.Context()).Error("MemberAuthRequired failed", logging.FieldError, err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+					"status":  "error",
+					"message": "Failed to verify member",
+				})
+			}
+			return
+		}
+
+		c.Set(ContextAuthMethod, AuthMethodMember)
+		c.Set(ContextAuthSubject, claims.Subject)
+		c.Set(ContextAuthName, claims.Email)
+		c.Set(ContextAuthRole, claims.AppMetadata.Role)
+		c.Next()
+	}
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/datvt88/CPLS/backend/services/servicestest"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// signMemberToken signs a Supabase-style member access token for subject
+func signMemberToken(t *testing.T, secret []byte, subject string) string {
+	t.Helper()
+	payload, err := json.Marshal(map[string]interface{}{
+		"sub": subject,
+		"aud": "authenticated",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestMemberAuthRequiredRejectsDeactivatedProfiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secret := []byte("supabase-secret")
+	active, inactive := uuid.New(), uuid.New()
+	profiles := &servicestest.UserStore{Profiles: []models.Profile{
+		{ID: active, Active: true},
+		{ID: inactive, Active: false},
+	}}
+	router := gin.New()
+	router.GET("/api/me", MemberAuthRequired(services.NewMemberAuthService(secret, profiles)), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name    string
+		subject string
+		status  int
+	}{
+		{"active", active.String(), http.StatusNoContent},
+		{"deactivated", inactive.String(), http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		req.Header.Set("Authorization", "Bearer "+signMemberToken(t, secret, tt.subject))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s: status = %d; want %d", tt.name, rec.Code, tt.status)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// QueryCountHeader is the debug response header carrying the request's DB query count
+const QueryCountHeader = "X-DB-Query-Count"
+
+// QueryCount attaches a QueryCounter to each request context and reports
+// requests that issue too many queries or repeat the same query shape.
+// Only queries executed with the request context (config.GetDBWithContext,
+// or Mongo calls using c.Request.Context()) are counted. Thresholds are read
+// from the runtime configuration on every request.
+func QueryCount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := config.Runtime()
+		counter := config.NewQueryCounter()
+		c.Request = c.Request.WithContext(config.WithQueryCounter(c.Request.Context(), counter))
+		if cfg.QueryDebugHeader {
+			c.Writer = &queryCountWriter{ResponseWriter: c.Writer, counter: counter}
+		}
+
+		c.Next()
+
+		total := counter.Total()
+		repeated := counter.RepeatedShapes(cfg.QueryRepeatThreshold)
+		if total > cfg.QueryWarnThreshold {
+			logging.FromContext(c.Request.Context()).Warn("Request issued too many DB queries",
+				"route", c.FullPath(), "queries", total,
+				"postgres", counter.ByBackend(config.QueryBackendPostgres), "mongo", counter.ByBackend(config.QueryBackendMongo),
+				"threshold", cfg.QueryWarnThreshold)
+		}
+		for _, shape := range repeated {
+			logging.FromContext(c.Request.Context()).Warn("Possible N+1 query", "route", c.FullPath(), "count", shape.Count, "shape", shape.Shape)
+		}
+	}
+}
+
+// queryCountWriter sets the query count header just before the response
+// headers are flushed, counting every query issued up to that point
+type queryCountWriter struct {
+	gin.ResponseWriter
+	counter *config.QueryCounter
+	written bool
+}
+
+func (w *queryCountWriter) setHeader() {
+	if !w.written {
+		w.written = true
+		w.Header().Set(QueryCountHeader, strconv.Itoa(w.counter.Total()))
+	}
+}
+
+// WriteHeaderNow sets the header before flushing
+func (w *queryCountWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Write sets the header before writing the body
+func (w *queryCountWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString sets the header before writing the body
+func (w *queryCountWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit limits requests to a route group per client. API keys are
+// counted per key, personal tokens and member requests per member, and all
+// other requests per client IP. The limit of the group is read from the
+// runtime configuration (rate_limit.limits) on every request. Members whose
+// tier was resolved by MembershipTier are instead held to the limit of
+// their tier (rate_limit.tiers), counted across route groups. When the
+// limiter's backend fails the request is allowed, so an outage of Redis does
+// not take the API down.
+func RateLimit(group string, limiter services.RateLimiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := config.Runtime()
+		limit := cfg.RateLimitFor(group)
+
+		client := "ip:" + c.ClientIP()
+		switch c.GetString(ContextAuthMethod) {
+		case AuthMethodAPIKey, AuthMethodPersonalToken:
+			client = "key:" + c.GetString(ContextAuthSubject)
+		case AuthMethodMember:
+			client = "member:" + c.GetString(ContextAuthSubject)
+		}
+		bucket := group + ":" + client
+		if tier := c.GetString(ContextAuthTier); tier != "" {
+			if tierLimit, ok := cfg.TierRateLimit(tier); ok {
+				limit, bucket = tierLimit, "tier:"+client
+			}
+		}
+
+		result, err := limiter.Allow(c.Request.Context(), bucket, limit)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Warn("Rate limiter unavailable, allowing request", logging.FieldError, err)
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(result.ResetAt.Unix(), 10))
+
+		if !result.Allowed {
+			retryAfter := int(math.Ceil(time.Until(result.ResetAt).Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"status":  "error",
+				"message": "Rate limit exceeded, retry after " + strconv.Itoa(retryAfter) + "s",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ContextRequestID holds the ID of the current request
+const ContextRequestID = "request_id"
+
+// validRequestID limits the IDs accepted from clients, so they are safe to
+// log and forward
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID assigns each request an ID: the client's X-Request-ID when it is
+// valid, otherwise a new UUID. The ID is echoed in the response header,
+// stored in the gin and request contexts (outbound calls made with the
+// request context forward it), added to request log lines and to the body of
+// JSON error responses as request_id.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(logging.RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = uuid.NewString()
+		}
+		c.Set(ContextRequestID, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+		c.Header(logging.RequestIDHeader, id)
+
+		writer := &requestIDWriter{ResponseWriter: c.Writer, id: id}
+		c.Writer = writer
+		c.Next()
+		writer.finish()
+	}
+}
+
+// requestIDWriter buffers JSON error bodies so the request ID can be added
+// to them; other responses pass straight through
+type requestIDWriter struct {
+	gin.ResponseWriter
+	id        string
+	body      bytes.Buffer
+	buffering bool
+	released  bool
+}
+
+func (w *requestIDWriter) Write(data []byte) (int, error) {
+	if w.buffer() {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *requestIDWriter) WriteString(s string) (int, error) {
+	if w.buffer() {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Size counts a held-back body, so request logs report it
+func (w *requestIDWriter) Size() int {
+	if w.buffering && !w.released {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *requestIDWriter) Flush() {
+	w.release(w.body.Bytes())
+	w.ResponseWriter.Flush()
+}
+
+// buffer reports whether the body being written is a JSON error to hold back;
+// it is decided by the first write
+func (w *requestIDWriter) buffer() bool {
+	if !w.released && !w.buffering {
+		if w.Status() >= http.StatusBadRequest && strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+			w.buffering = true
+		} else {
+			w.released = true
+		}
+	}
+	return w.buffering && !w.released
+}
+
+// finish writes a buffered error body with the request ID added
+func (w *requestIDWriter) finish() {
+	if !w.buffering || w.released {
+		return
+	}
+	body := w.body.Bytes()
+	var document map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err == nil {
+		key := requestIDField(w.Header())
+		if _, exists := document[key]; !exists {
+			document[key] = w.id
+			if withID, err := json.Marshal(document); err == nil {
+				body = withID
+				w.Header().Del("Content-Length")
+			}
+		}
+	}
+	w.release(body)
+}
+
+// release writes body to the underlying writer and stops buffering
+func (w *requestIDWriter) release(body []byte) {
+	if w.released {
+		return
+	}
+	w.released = true
+	w.body.Reset()
+	if len(body) > 0 {
+		_, _ = w.ResponseWriter.Write(body)
+	}
+}
+
+// requestIDField names the request ID field in the response's format
+func requestIDField(header http.Header) string {
+	if spec := header.Get(ResponseFormatHeader); spec != "" {
+		if format, err := models.ParseResponseFormat(spec, config.Runtime().APIResponseFormat); err == nil {
+			return format.FieldName(ContextRequestID)
+		}
+	}
+	return ContextRequestID
+}
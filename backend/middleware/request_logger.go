@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger attaches a logger carrying the request's method, path, ID
+// (see RequestID, which must run first) and trace to the request context (read it with logging.FromContext), and
+// writes one access line per request once it has been handled. It replaces
+// gin's text logger so access lines share the structured output.
+func RequestLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		logger := logging.Component("http").With("method", c.Request.Method, "path", c.Request.URL.Path)
+		if id := logging.RequestID(c.Request.Context()); id != "" {
+			logger = logger.With(logging.FieldRequestID, id)
+		}
+		if trace, ok := logging.TraceAttr(c.GetHeader("X-Cloud-Trace-Context")); ok {
+			logger = logger.With(trace)
+		}
+		c.Request = c.Request.WithContext(logging.NewContext(c.Request.Context(), logger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+		attrs := []any{
+			"status", status,
+			"latency_ms", time.Since(start).Milliseconds(),
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, logging.FieldError, c.Errors.String())
+		}
+		logger.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
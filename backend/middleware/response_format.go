@@ -0,0 +1,201 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// APIVersionHeader selects a response version ("1", "v2")
+	APIVersionHeader = "X-API-Version"
+	// ResponseFormatHeader selects naming and envelope directly ("camelCase,bare");
+	// the effective format is echoed back in the same header
+	ResponseFormatHeader = "X-Response-Format"
+	// ContextResponseFormat holds the format pinned to the authenticated API key
+	ContextResponseFormat = "response_format"
+	// contextResolvedFormat holds the effective format of the request
+	contextResolvedFormat = "resolved_response_format"
+)
+
+// ResponseFormat rewrites JSON responses into the format the client selected,
+// so field naming can be standardized without breaking existing consumers.
+// The format is resolved, each step overriding the previous one, from the
+// server default (api.response_format), the API key's pinned format, the
+// X-API-Version header and the X-Response-Format header. Responses in the
+// legacy format are passed through untouched. Deprecated routes, fields and
+// versions are announced with Deprecation and Sunset headers.
+func ResponseFormat() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		format, err := resolveResponseFormat(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"status":  "error",
+				"message": err.Error(),
+			})
+			return
+		}
+		c.Header(ResponseFormatHeader, format.String())
+		c.Set(contextResolvedFormat, format)
+		setDeprecationHeaders(c, format)
+
+		if format.IsLegacy() {
+			c.Next()
+			return
+		}
+
+		writer := &formatWriter{ResponseWriter: c.Writer, transform: func(body []byte, header http.Header) ([]byte, error) {
+			return reshapeJSON(body, format, header)
+		}}
+		c.Writer = writer
+		c.Next()
+		writer.finish()
+	}
+}
+
+// FormatRow encodes one row of a streamed response (NDJSON) with the naming
+// of the request's response format. Envelopes do not apply to single rows.
+func FormatRow(c *gin.Context, row interface{}) ([]byte, error) {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := c.Get(contextResolvedFormat)
+	format, _ := value.(models.ResponseFormat)
+	if !ok || format.IsLegacy() {
+		return data, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(renameFields(document, format))
+}
+
+// resolveResponseFormat combines the default, key and header selections
+func resolveResponseFormat(c *gin.Context) (models.ResponseFormat, error) {
+	format := config.Runtime().APIResponseFormat
+
+	if pinned := c.GetString(ContextResponseFormat); pinned != "" {
+		if parsed, err := models.ParseResponseFormat(pinned, format); err == nil {
+			format = parsed
+		}
+	}
+
+	if version := strings.TrimSpace(c.GetHeader(APIVersionHeader)); version != "" {
+		versioned, ok := models.ResponseVersions[strings.TrimPrefix(strings.ToLower(version), "v")]
+		if !ok {
+			return format, fmt.Errorf("unsupported API version %q (supported: %s)", version, strings.Join(models.ResponseVersionNames(), ", "))
+		}
+		format = versioned
+	}
+
+	if spec := c.GetHeader(ResponseFormatHeader); spec != "" {
+		return models.ParseResponseFormat(spec, format)
+	}
+	return format, nil
+}
+
+// formatWriter buffers the response body so that JSON can be rewritten by
+// transform once the handler is done. Flushing (streamed responses) switches
+// it to pass-through.
+type formatWriter struct {
+	gin.ResponseWriter
+	transform   func(body []byte, header http.Header) ([]byte, error)
+	body        bytes.Buffer
+	passthrough bool
+}
+
+func (w *formatWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.body.Write(data)
+}
+
+func (w *formatWriter) WriteString(s string) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.WriteString(s)
+	}
+	return w.body.WriteString(s)
+}
+
+func (w *formatWriter) Flush() {
+	w.release(w.body.Bytes())
+	w.ResponseWriter.Flush()
+}
+
+// finish writes the buffered body, transformed when it is JSON
+func (w *formatWriter) finish() {
+	body := w.body.Bytes()
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if reshaped, err := w.transform(body, w.Header()); err == nil {
+			body = reshaped
+			w.Header().Del("Content-Length")
+		}
+	}
+	w.release(body)
+}
+
+// release writes body to the underlying writer and stops buffering
+func (w *formatWriter) release(body []byte) {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	w.body.Reset()
+	if len(body) == 0 {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	_, _ = w.ResponseWriter.Write(body)
+}
+
+// reshapeJSON renames the fields of a JSON document and, for bare responses,
+// replaces a success envelope with its data (the total moves to X-Total-Count)
+func reshapeJSON(body []byte, format models.ResponseFormat, header http.Header) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	if envelope, ok := document.(map[string]interface{}); ok && !format.Envelope && envelope["status"] == "success" {
+		if data, hasData := envelope["data"]; hasData {
+			if total, hasTotal := envelope["total"]; hasTotal {
+				header.Set("X-Total-Count", fmt.Sprint(total))
+			}
+			document = data
+		}
+	}
+
+	return json.Marshal(renameFields(document, format))
+}
+
+// renameFields applies the format's naming to every object key in value
+func renameFields(value interface{}, format models.ResponseFormat) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		renamed := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			renamed[format.FieldName(key)] = renameFields(item, format)
+		}
+		return renamed
+	case []interface{}:
+		for i, item := range v {
+			v[i] = renameFields(item, format)
+		}
+		return v
+	}
+	return value
+}
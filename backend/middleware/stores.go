@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/gin-gonic/gin"
+)
+
+// RequireStores answers 503 with a Retry-After header while any of the
+// given data stores is unavailable, instead of letting the request wait for
+// a database timeout. Routes that do not use the store keep working.
+func RequireStores(stores ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if down := config.UnavailableStores(stores...); len(down) > 0 {
+			abortStoresUnavailable(c, down)
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortStoresUnavailable rejects a request that needs unavailable stores
+func abortStoresUnavailable(c *gin.Context, down []string) {
+	retryAfter := int(math.Ceil(config.StoreCheckInterval().Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+		"status":  "error",
+		"message": "Service temporarily unavailable, please retry later",
+		"error":   strings.Join(down, ", ") + " unavailable",
+	})
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys set by MembershipTier
+const (
+	ContextAuthTier     = "auth_tier"     // Membership tier of member callers (empty for admins and API keys)
+	ContextHistoryStart = "history_start" // Earliest date (YYYY-MM-DD) the caller may read prices of
+)
+
+// MembershipTier looks up the membership tier of member callers (personal
+// tokens and member tokens) for the tier quotas: RateLimit counts their
+// requests against rate_limit.tiers, and handlers serving price history
+// start no earlier than HistoryStart (tier.history_days). When the lookup
+// fails the member is treated as free. Admins and API keys have no tier
+// and keep the route group limits and the full history.
+func MembershipTier(featureService *services.FeatureService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profileID := featureProfileID(c)
+		if profileID == "" {
+			c.Next()
+			return
+		}
+		tier, err := featureService.Tier(c.Request.Context(), profileID)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Warn("Membership tier lookup failed, applying free quotas", logging.FieldError, err)
+			tier = models.MembershipFree
+		}
+		c.Set(ContextAuthTier, tier)
+		if days := config.Runtime().HistoryDays(tier); days > 0 {
+			c.Set(ContextHistoryStart, markettime.Date(markettime.Now().AddDate(0, 0, -days)))
+		}
+		c.Next()
+	}
+}
+
+// HistoryStart returns the earliest date (YYYY-MM-DD) the caller may read
+// prices of, or "" when its history is not limited
+func HistoryStart(c *gin.Context) string {
+	return c.GetString(ContextHistoryStart)
+}
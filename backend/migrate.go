@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/datvt88/CPLS/backend/config"
+)
+
+// migrate applies the versioned SQL migrations of supabase/migrations to the
+// PostgreSQL database and records them in the schema_migrations table, so
+// every environment converges on the same schema without copying SQL into
+// the Supabase editor:
+//
+//	go run . migrate status           # applied, pending and modified migrations
+//	go run . migrate up               # apply the pending ones in order
+//	go run . migrate baseline -to 20260214
+//
+// baseline records migrations as applied without running them: use it once
+// on databases whose schema was created by running the files by hand. The
+// migrations directory is read from -dir, MIGRATIONS_DIR or
+// ../supabase/migrations (in that order).
+func migrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dir := flags.String("dir", os.Getenv("MIGRATIONS_DIR"), "directory of the <version>_<name>.sql migrations")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: migrate [-dir DIR] status|up|baseline [-to VERSION]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *dir == "" {
+		*dir = config.DefaultMigrationsDir
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	migrations, err := config.LoadMigrations(os.DirFS(*dir))
+	if err != nil {
+		log.Fatalf("Failed to load migrations from %s: %v", *dir, err)
+	}
+
+	if err := config.ConnectPostgres(); err != nil {
+		log.Fatalf("Failed to connect to PostgreSQL: %v", err)
+	}
+	defer config.DisconnectPostgres()
+	ctx := context.Background()
+
+	switch command := flags.Arg(0); command {
+	case "status":
+		states, err := config.MigrationStatus(ctx, migrations)
+		if err != nil {
+			log.Fatalf("Failed to read migration status: %v", err)
+		}
+		for _, state := range states {
+			status := "pending"
+			switch {
+			case state.Applied == nil:
+			case state.Modified:
+				status = "MODIFIED since applied " + state.Applied.AppliedAt.Format("2006-01-02 15:04")
+			case state.Applied.Baseline:
+				status = "baseline " + state.Applied.AppliedAt.Format("2006-01-02 15:04")
+			default:
+				status = "applied " + state.Applied.AppliedAt.Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%s  %-45s %s\n", state.Version, state.Name, status)
+		}
+
+	case "up":
+		applied, err := config.MigrateUp(ctx, migrations, false)
+		for _, migration := range applied {
+			log.Printf("✓ Applied %s_%s", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("✓ Database is up to date (%d applied)", len(applied))
+
+	case "baseline":
+		baseline := flag.NewFlagSet("baseline", flag.ExitOnError)
+		to := baseline.String("to", "", "last version already applied by hand (required)")
+		baseline.Parse(flags.Args()[1:])
+		if *to == "" {
+			baseline.Usage()
+			os.Exit(2)
+		}
+		var upTo []config.Migration
+		for _, migration := range migrations {
+			if migration.Version <= *to {
+				upTo = append(upTo, migration)
+			}
+		}
+		recorded, err := config.MigrateUp(ctx, upTo, true)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		log.Printf("✓ Recorded %d migrations up to %s as applied", len(recorded), *to)
+
+	default:
+		log.Printf("Unknown command %q", command)
+		flags.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Admin panel page size limits
+const (
+	DefaultAdminPageSize = 50
+	MinAdminPageSize     = 10
+	MaxAdminPageSize     = 100
+)
+
+// AdminLanguages are the languages the admin panel formats dates and
+// numbers in, with their BCP 47 locale
+var AdminLanguages = map[string]string{
+	"en": "en-US",
+	"vi": "vi-VN",
+}
+
+// AdminPreferences are one admin's display preferences for the admin panel
+type AdminPreferences struct {
+	AdminUserID uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	Language    string    `json:"language"` // A key of AdminLanguages
+	Timezone    string    `json:"timezone"` // IANA name; empty for the browser's timezone
+	PageSize    int       `json:"page_size"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AdminPreferences) TableName() string {
+	return "admin_preferences"
+}
+
+// DefaultAdminPreferences returns the preferences of admins who never saved any
+func DefaultAdminPreferences() AdminPreferences {
+	return AdminPreferences{Language: "en", PageSize: DefaultAdminPageSize}
+}
+
+// Validate checks the language, timezone and page size
+func (p AdminPreferences) Validate() error {
+	if _, ok := AdminLanguages[p.Language]; !ok {
+		return fmt.Errorf("unsupported language %q (supported: en, vi)", p.Language)
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", p.Timezone)
+		}
+	}
+	if p.PageSize < MinAdminPageSize || p.PageSize > MaxAdminPageSize {
+		return fmt.Errorf("page size must be between %d and %d", MinAdminPageSize, MaxAdminPageSize)
+	}
+	return nil
+}
+
+// Locale returns the BCP 47 locale of the preferred language
+func (p AdminPreferences) Locale() string {
+	if locale, ok := AdminLanguages[p.Language]; ok {
+		return locale
+	}
+	return AdminLanguages["en"]
+}
+
+// Location returns the preferred timezone, or nil for the browser's
+func (p AdminPreferences) Location() *time.Location {
+	if p.Timezone == "" {
+		return nil
+	}
+	location, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return nil
+	}
+	return location
+}
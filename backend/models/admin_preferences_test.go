@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestAdminPreferencesValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		preferences AdminPreferences
+		wantErr     bool
+	}{
+		{"defaults", DefaultAdminPreferences(), false},
+		{"vietnamese with timezone", AdminPreferences{Language: "vi", Timezone: "Asia/Ho_Chi_Minh", PageSize: 100}, false},
+		{"unsupported language", AdminPreferences{Language: "fr", PageSize: 50}, true},
+		{"unknown timezone", AdminPreferences{Language: "en", Timezone: "Mars/Olympus", PageSize: 50}, true},
+		{"page size too small", AdminPreferences{Language: "en", PageSize: 5}, true},
+		{"page size too large", AdminPreferences{Language: "en", PageSize: 500}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.preferences.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v; wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAdminPreferencesLocation(t *testing.T) {
+	if location := DefaultAdminPreferences().Location(); location != nil {
+		t.Errorf("default preferences use %v; want the browser's timezone (nil)", location)
+	}
+	preferences := AdminPreferences{Language: "vi", Timezone: "Asia/Ho_Chi_Minh"}
+	if location := preferences.Location(); location == nil || location.String() != "Asia/Ho_Chi_Minh" {
+		t.Errorf("Location() = %v; want Asia/Ho_Chi_Minh", location)
+	}
+	if locale := preferences.Locale(); locale != "vi-VN" {
+		t.Errorf("Locale() = %q; want vi-VN", locale)
+	}
+}
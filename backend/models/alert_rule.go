@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Alert rule types
+const (
+	// AlertRuleNoSuccessfulCrawl fires when no crawl has succeeded within Threshold hours
+	AlertRuleNoSuccessfulCrawl = "no_successful_crawl"
+	// AlertRuleFailedSymbolsRatio fires when more than Threshold percent of symbols failed in the latest run
+	AlertRuleFailedSymbolsRatio = "failed_symbols_ratio"
+	// AlertRuleStaleCandles fires when the freshest stored candle is older than Threshold days
+	AlertRuleStaleCandles = "stale_candles"
+	// AlertRuleSampleMismatchRatio fires when more than Threshold percent of the
+	// candles re-fetched after the latest run differ from the stored ones
+	AlertRuleSampleMismatchRatio = "sample_mismatch_ratio"
+)
+
+// Alert rule states
+const (
+	AlertStateOK     = "ok"
+	AlertStateFiring = "firing"
+)
+
+// AlertRuleTypes lists every supported alert rule type
+var AlertRuleTypes = []string{
+	AlertRuleNoSuccessfulCrawl,
+	AlertRuleFailedSymbolsRatio,
+	AlertRuleStaleCandles,
+	AlertRuleSampleMismatchRatio,
+}
+
+// AlertRule represents the alert_rules table in Supabase
+// Each rule is evaluated periodically by the alert monitor
+type AlertRule struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	Name            string     `gorm:"type:text;not null;column:name" json:"name"`
+	Type            string     `gorm:"type:text;not null;column:type" json:"type"`
+	Threshold       float64    `gorm:"type:double precision;not null;column:threshold" json:"threshold"`
+	Channels        string     `gorm:"type:text;not null;default:'log';column:channels" json:"channels"` // Comma-separated notifier names
+	Enabled         bool       `gorm:"type:boolean;default:true;column:enabled" json:"enabled"`
+	CooldownMinutes int        `gorm:"type:integer;default:60;column:cooldown_minutes" json:"cooldown_minutes"`
+	State           string     `gorm:"type:text;default:'ok';column:state" json:"state"`
+	LastMessage     *string    `gorm:"type:text;column:last_message" json:"last_message,omitempty"`
+	LastEvaluatedAt *time.Time `gorm:"type:timestamptz;column:last_evaluated_at" json:"last_evaluated_at,omitempty"`
+	LastNotifiedAt  *time.Time `gorm:"type:timestamptz;column:last_notified_at" json:"last_notified_at,omitempty"`
+	CreatedBy       *string    `gorm:"type:text;column:created_by" json:"created_by,omitempty"`
+	CreatedAt       time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AlertRule) TableName() string {
+	return "public.alert_rules"
+}
+
+// ChannelList returns the rule's notification channels as a slice
+func (r AlertRule) ChannelList() []string {
+	return SplitList(r.Channels)
+}
+
+// Validate checks that the rule type and threshold are usable
+func (r AlertRule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch r.Type {
+	case AlertRuleNoSuccessfulCrawl, AlertRuleStaleCandles:
+		if r.Threshold <= 0 {
+			return fmt.Errorf("threshold must be greater than 0")
+		}
+	case AlertRuleFailedSymbolsRatio, AlertRuleSampleMismatchRatio:
+		if r.Threshold <= 0 || r.Threshold > 100 {
+			return fmt.Errorf("threshold must be a percentage between 0 and 100")
+		}
+	default:
+		return fmt.Errorf("unknown rule type %q (supported: %s)", r.Type, strings.Join(AlertRuleTypes, ", "))
+	}
+	if len(r.ChannelList()) == 0 {
+		return fmt.Errorf("at least one notification channel is required")
+	}
+	if r.CooldownMinutes < 0 {
+		return fmt.Errorf("cooldown_minutes must not be negative")
+	}
+	return nil
+}
+
+// OpsMetrics is a snapshot of data-operation health used to evaluate alert rules
+type OpsMetrics struct {
+	LastSuccessfulCrawlAt *time.Time         // Finish time of the most recent successful crawl run
+	LatestRun             *CrawlRun          // Most recent finished crawl run
+	LatestVerification    *CrawlVerification // Sampled re-fetch of the most recent verified crawl run
+	FreshestCandleDate    *time.Time         // Date of the newest candle across all buckets
+}
+
+// Evaluate reports whether the rule is firing for the given metrics, with a
+// human-readable explanation either way.
+func (r AlertRule) Evaluate(m OpsMetrics, now time.Time) (bool, string) {
+	switch r.Type {
+	case AlertRuleNoSuccessfulCrawl:
+		if m.LastSuccessfulCrawlAt == nil {
+			return true, "No successful crawl has ever been recorded"
+		}
+		age := now.Sub(*m.LastSuccessfulCrawlAt)
+		if age > time.Duration(r.Threshold*float64(time.Hour)) {
+			return true, fmt.Sprintf("No successful crawl in %.1f hours (threshold: %g hours)", age.Hours(), r.Threshold)
+		}
+		return false, fmt.Sprintf("Last successful crawl %.1f hours ago", age.Hours())
+
+	case AlertRuleFailedSymbolsRatio:
+		if m.LatestRun == nil || m.LatestRun.TotalSymbols == 0 {
+			return false, "No completed crawl run with symbols to evaluate"
+		}
+		pct := m.LatestRun.FailureRatio() * 100
+		if pct > r.Threshold {
+			return true, fmt.Sprintf("%.1f%% of symbols failed in the latest crawl (%d/%d, threshold: %g%%)",
+				pct, m.LatestRun.FailedSymbols, m.LatestRun.TotalSymbols, r.Threshold)
+		}
+		return false, fmt.Sprintf("%.1f%% of symbols failed in the latest crawl", pct)
+
+	case AlertRuleStaleCandles:
+		if m.FreshestCandleDate == nil {
+			return true, "No candle data stored"
+		}
+		days := now.Sub(*m.FreshestCandleDate).Hours() / 24
+		if days > r.Threshold {
+			return true, fmt.Sprintf("Freshest candle is %s, %.1f days old (threshold: %g days)",
+				m.FreshestCandleDate.Format("2006-01-02"), days, r.Threshold)
+		}
+		return false, fmt.Sprintf("Freshest candle is %s", m.FreshestCandleDate.Format("2006-01-02"))
+
+	case AlertRuleSampleMismatchRatio:
+		v := m.LatestVerification
+		if v == nil || v.ComparedCandles == 0 {
+			return false, "No sampled candles to evaluate"
+		}
+		pct := v.MismatchRatio() * 100
+		if pct > r.Threshold {
+			return true, fmt.Sprintf("%.1f%% of sampled candles differ from the provider (%d/%d over %d symbols, threshold: %g%%)",
+				pct, v.MismatchedCandles, v.ComparedCandles, len(v.SampledSymbols), r.Threshold)
+		}
+		return false, fmt.Sprintf("%.1f%% of sampled candles differ from the provider", pct)
+	}
+
+	return false, fmt.Sprintf("Unknown rule type %q", r.Type)
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlertRuleEvaluate(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	hoursAgo := func(h int) *time.Time {
+		ts := now.Add(-time.Duration(h) * time.Hour)
+		return &ts
+	}
+
+	tests := []struct {
+		name    string
+		rule    AlertRule
+		metrics OpsMetrics
+		firing  bool
+	}{
+		{"no crawl ever", AlertRule{Type: AlertRuleNoSuccessfulCrawl, Threshold: 24}, OpsMetrics{}, true},
+		{"recent crawl", AlertRule{Type: AlertRuleNoSuccessfulCrawl, Threshold: 24}, OpsMetrics{LastSuccessfulCrawlAt: hoursAgo(3)}, false},
+		{"old crawl", AlertRule{Type: AlertRuleNoSuccessfulCrawl, Threshold: 24}, OpsMetrics{LastSuccessfulCrawlAt: hoursAgo(30)}, true},
+		{"no run to compare", AlertRule{Type: AlertRuleFailedSymbolsRatio, Threshold: 5}, OpsMetrics{}, false},
+		{"few failures", AlertRule{Type: AlertRuleFailedSymbolsRatio, Threshold: 5},
+			OpsMetrics{LatestRun: &CrawlRun{TotalSymbols: 100, FailedSymbols: 5}}, false},
+		{"many failures", AlertRule{Type: AlertRuleFailedSymbolsRatio, Threshold: 5},
+			OpsMetrics{LatestRun: &CrawlRun{TotalSymbols: 100, FailedSymbols: 6}}, true},
+		{"no candles", AlertRule{Type: AlertRuleStaleCandles, Threshold: 2}, OpsMetrics{}, true},
+		{"fresh candles", AlertRule{Type: AlertRuleStaleCandles, Threshold: 2}, OpsMetrics{FreshestCandleDate: hoursAgo(36)}, false},
+		{"stale candles", AlertRule{Type: AlertRuleStaleCandles, Threshold: 2}, OpsMetrics{FreshestCandleDate: hoursAgo(72)}, true},
+		{"no verification", AlertRule{Type: AlertRuleSampleMismatchRatio, Threshold: 2}, OpsMetrics{}, false},
+		{"few mismatches", AlertRule{Type: AlertRuleSampleMismatchRatio, Threshold: 2},
+			OpsMetrics{LatestVerification: &CrawlVerification{ComparedCandles: 100, MismatchedCandles: 2}}, false},
+		{"many mismatches", AlertRule{Type: AlertRuleSampleMismatchRatio, Threshold: 2},
+			OpsMetrics{LatestVerification: &CrawlVerification{ComparedCandles: 100, MismatchedCandles: 3}}, true},
+	}
+
+	for _, tt := range tests {
+		firing, message := tt.rule.Evaluate(tt.metrics, now)
+		if firing != tt.firing {
+			t.Errorf("%s: Evaluate() firing = %v (%s); want %v", tt.name, firing, message, tt.firing)
+		}
+		if message == "" {
+			t.Errorf("%s: Evaluate() returned empty message", tt.name)
+		}
+	}
+}
+
+func TestAlertRuleValidate(t *testing.T) {
+	tests := []struct {
+		rule     AlertRule
+		hasError bool
+	}{
+		{AlertRule{Name: "crawl", Type: AlertRuleNoSuccessfulCrawl, Threshold: 24, Channels: "log"}, false},
+		{AlertRule{Name: "ratio", Type: AlertRuleFailedSymbolsRatio, Threshold: 150, Channels: "log"}, true},
+		{AlertRule{Name: "stale", Type: AlertRuleStaleCandles, Threshold: 2, Channels: " , "}, true},
+		{AlertRule{Name: "bad", Type: "unknown", Threshold: 1, Channels: "log"}, true},
+		{AlertRule{Type: AlertRuleStaleCandles, Threshold: 2, Channels: "log"}, true},
+	}
+
+	for _, tt := range tests {
+		err := tt.rule.Validate()
+		if (err != nil) != tt.hasError {
+			t.Errorf("Validate(%+v) error = %v; want error: %v", tt.rule, err, tt.hasError)
+		}
+	}
+}
+
+func TestAlertRuleChannelList(t *testing.T) {
+	rule := AlertRule{Channels: "log, webhook,,"}
+	channels := rule.ChannelList()
+	if len(channels) != 2 || channels[0] != "log" || channels[1] != "webhook" {
+		t.Errorf("ChannelList() = %v; want [log webhook]", channels)
+	}
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"time"
+)
+
+// API version statuses
+const (
+	APIVersionCurrent    = "current"
+	APIVersionSupported  = "supported"
+	APIVersionDeprecated = "deprecated" // Still served until its sunset date
+)
+
+// API change kinds
+const (
+	APIChangeAdded      = "added"
+	APIChangeChanged    = "changed"
+	APIChangeDeprecated = "deprecated" // Responses of the endpoint carry Deprecation and Sunset headers
+	APIChangeRemoved    = "removed"
+)
+
+// APIVersion is a response version selectable with X-API-Version
+type APIVersion struct {
+	Version     string `json:"version"` // A key of ResponseVersions
+	Status      string `json:"status"`
+	Description string `json:"description"`
+	Deprecated  string `json:"deprecated,omitempty"` // YYYY-MM-DD
+	Sunset      string `json:"sunset,omitempty"`     // YYYY-MM-DD, last day it is served
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// APIChange is one entry of the public API changelog. Path is a route
+// pattern as registered ("/api/stocks/:code/candles"); Field narrows a
+// change to one response field.
+type APIChange struct {
+	Date        string `json:"date"` // YYYY-MM-DD; for deprecations, when they were announced
+	Kind        string `json:"kind"`
+	Method      string `json:"method,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Field       string `json:"field,omitempty"`
+	Description string `json:"description"`
+	Sunset      string `json:"sunset,omitempty"` // YYYY-MM-DD, for deprecations
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// APIVersions lists the response versions and their support status
+var APIVersions = []APIVersion{
+	{
+		Version:     "1",
+		Status:      APIVersionSupported,
+		Description: "Original responses with mixed camelCase and snake_case field names",
+	},
+	{
+		Version:     "2",
+		Status:      APIVersionCurrent,
+		Description: "snake_case field names with the status/data envelope",
+	},
+}
+
+// APIChangelog lists the changes of the public API, newest first. Entries
+// are never edited once published; a later entry supersedes an earlier one.
+// Deprecating an endpoint or field is done by adding an APIChangeDeprecated
+// entry with its sunset date; the headers follow from it.
+var APIChangelog = []APIChange{
+	{
+		Date:        "2026-10-15",
+		Kind:        APIChangeAdded,
+		Method:      "GET",
+		Path:        "/api/meta/changes",
+		Description: "Machine-readable registry of API versions, changes and deprecations",
+	},
+	{
+		Date:        "2026-10-15",
+		Kind:        APIChangeChanged,
+		Method:      "GET",
+		Path:        "/api/stocks/:code/candles",
+		Description: "Streams candles as NDJSON with Accept: application/x-ndjson",
+	},
+	{
+		Date:        "2026-10-15",
+		Kind:        APIChangeChanged,
+		Method:      "GET",
+		Path:        "/api/stocks/metadata",
+		Description: "Streams stocks as NDJSON with Accept: application/x-ndjson",
+	},
+	{
+		Date:        "2026-10-15",
+		Kind:        APIChangeAdded,
+		Method:      "GET",
+		Path:        "/api/me/screens/:id/run",
+		Description: "Saved screener presets (/api/me/screens) can be run and notify of new matches",
+	},
+	{
+		Date:        "2026-10-15",
+		Kind:        APIChangeChanged,
+		Method:      "GET",
+		Path:        "/api/stocks/:code/candles",
+		Description: "Responses carry an ETag and answer 304 Not Modified to a matching If-None-Match",
+	},
+	{
+		Date:        "2026-10-15",
+		Kind:        APIChangeAdded,
+		Method:      "GET",
+		Path:        "/api/stocks/sparklines",
+		Description: "Bulk sparklines of many symbols",
+	},
+	{
+		Date:        "2026-10-15",
+		Kind:        APIChangeAdded,
+		Description: "Response version 2 and the X-API-Version and X-Response-Format headers",
+	},
+}
+
+// APIChangesSince returns the changelog entries dated on or after since
+// (YYYY-MM-DD), or all of them when since is empty
+func APIChangesSince(since string) []APIChange {
+	changes := make([]APIChange, 0, len(APIChangelog))
+	for _, change := range APIChangelog {
+		if since == "" || change.Date >= since {
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// APIDeprecation is the deprecation in effect for one request
+type APIDeprecation struct {
+	Since  time.Time  // Earliest announcement among the applicable deprecations
+	Sunset *time.Time // Earliest sunset, if any is set
+}
+
+// DeprecationFor returns the deprecation applying to a request for the
+// route pattern path with method, answered in format: deprecations of the
+// endpoint or of its fields, and of the response version format belongs to.
+// It reports false when nothing applies.
+func DeprecationFor(method, path string, format ResponseFormat) (APIDeprecation, bool) {
+	var result APIDeprecation
+	found := false
+	add := func(deprecated, sunset string) {
+		since, err := time.Parse("2006-01-02", deprecated)
+		if err != nil {
+			return
+		}
+		if !found || since.Before(result.Since) {
+			result.Since = since
+		}
+		found = true
+		if end, err := time.Parse("2006-01-02", sunset); err == nil && (result.Sunset == nil || end.Before(*result.Sunset)) {
+			result.Sunset = &end
+		}
+	}
+
+	for _, change := range APIChangelog {
+		if change.Kind == APIChangeDeprecated && change.Path != "" && change.Path == path && (change.Method == "" || change.Method == method) {
+			add(change.Date, change.Sunset)
+		}
+	}
+	for _, version := range APIVersions {
+		if version.Status == APIVersionDeprecated && ResponseVersions[version.Version] == format {
+			add(version.Deprecated, version.Sunset)
+		}
+	}
+	return result, found
+}
@@ -0,0 +1,72 @@
+package models
+
+import "testing"
+
+func TestDeprecationFor(t *testing.T) {
+	changelog, versions := APIChangelog, APIVersions
+	defer func() { APIChangelog, APIVersions = changelog, versions }()
+
+	APIChangelog = []APIChange{
+		{Date: "2026-11-01", Kind: APIChangeDeprecated, Method: "GET", Path: "/api/stocks/:code/detail", Field: "floor", Sunset: "2027-03-31"},
+		{Date: "2026-10-20", Kind: APIChangeDeprecated, Path: "/api/stocks/:code/detail", Sunset: "2027-06-30"},
+		{Date: "2026-10-01", Kind: APIChangeAdded, Method: "GET", Path: "/api/stocks/search"},
+	}
+	APIVersions = []APIVersion{
+		{Version: "1", Status: APIVersionDeprecated, Deprecated: "2026-09-01"},
+		{Version: "2", Status: APIVersionCurrent},
+	}
+	v2 := ResponseVersions["2"]
+
+	deprecation, ok := DeprecationFor("GET", "/api/stocks/:code/detail", v2)
+	if !ok {
+		t.Fatal("expected the detail endpoint to be deprecated")
+	}
+	if got := deprecation.Since.Format("2006-01-02"); got != "2026-10-20" {
+		t.Errorf("Since = %s; want the earliest announcement 2026-10-20", got)
+	}
+	if deprecation.Sunset == nil || deprecation.Sunset.Format("2006-01-02") != "2027-03-31" {
+		t.Errorf("Sunset = %v; want the earliest sunset 2027-03-31", deprecation.Sunset)
+	}
+
+	if _, ok := DeprecationFor("POST", "/api/stocks/:code/detail", v2); !ok {
+		t.Error("a deprecation without method should apply to every method")
+	}
+	if _, ok := DeprecationFor("GET", "/api/stocks/search", v2); ok {
+		t.Error("added endpoints are not deprecated")
+	}
+
+	deprecation, ok = DeprecationFor("GET", "/api/stocks/search", LegacyResponseFormat)
+	if !ok || deprecation.Sunset != nil {
+		t.Errorf("version 1 responses = %+v, %v; want deprecated without sunset", deprecation, ok)
+	}
+}
+
+func TestAPIChangesSince(t *testing.T) {
+	if got := len(APIChangesSince("")); got != len(APIChangelog) {
+		t.Errorf("APIChangesSince(\"\") returned %d changes; want all %d", got, len(APIChangelog))
+	}
+	for _, change := range APIChangesSince("2026-10-15") {
+		if change.Date < "2026-10-15" {
+			t.Errorf("change dated %s returned for since 2026-10-15", change.Date)
+		}
+	}
+	if got := APIChangesSince("9999-01-01"); len(got) != 0 {
+		t.Errorf("APIChangesSince in the future returned %d changes", len(got))
+	}
+}
+
+func TestAPIRegistryIsValid(t *testing.T) {
+	for _, version := range APIVersions {
+		if _, ok := ResponseVersions[version.Version]; !ok {
+			t.Errorf("API version %q is not a response version", version.Version)
+		}
+	}
+	for i, change := range APIChangelog {
+		if i > 0 && change.Date > APIChangelog[i-1].Date {
+			t.Errorf("changelog entry %d (%s) is newer than the one before it", i, change.Date)
+		}
+		if change.Kind == APIChangeDeprecated && change.Sunset == "" {
+			t.Errorf("deprecation of %s %s has no sunset date", change.Method, change.Path)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// API key scopes (capabilities granted to external data consumers)
+const (
+	ScopeReadPrices   = "read_prices"   // Read stocks, prices and crawler status
+	ScopeTriggerCrawl = "trigger_crawl" // Start crawler runs
+)
+
+// APIKeyScopes lists every scope that can be granted to an API key
+var APIKeyScopes = []string{
+	ScopeReadPrices,
+	ScopeTriggerCrawl,
+}
+
+// APIKey represents the api_keys table in Supabase
+// Only a SHA-256 hash of the key is stored; the plaintext is shown once at creation
+type APIKey struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	Name           string     `gorm:"type:text;not null;column:name" json:"name"`
+	Prefix         string     `gorm:"type:text;not null;column:prefix" json:"prefix"` // First characters of the key, for identification
+	KeyHash        string     `gorm:"type:text;not null;unique;column:key_hash" json:"-"`
+	Scopes         string     `gorm:"type:text;not null;column:scopes" json:"scopes"`                              // Comma-separated scopes
+	ResponseFormat string     `gorm:"type:text;not null;default:'';column:response_format" json:"response_format"` // Pinned response format ("" = server default)
+	CreatedBy      *string    `gorm:"type:text;column:created_by" json:"created_by,omitempty"`
+	CreatedAt      time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	LastUsedAt     *time.Time `gorm:"type:timestamptz;column:last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt      *time.Time `gorm:"type:timestamptz;column:revoked_at" json:"revoked_at,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (APIKey) TableName() string {
+	return "public.api_keys"
+}
+
+// ScopeList returns the key's scopes as a slice
+func (k APIKey) ScopeList() []string {
+	return SplitList(k.Scopes)
+}
+
+// HasScope reports whether the key grants the given scope
+func (k APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeResponseFormat validates a response format spec for pinning to a
+// key and returns it in canonical form ("" keeps the server default)
+func NormalizeResponseFormat(spec string) (string, error) {
+	if strings.TrimSpace(spec) == "" {
+		return "", nil
+	}
+	format, err := ParseResponseFormat(spec, LegacyResponseFormat)
+	if err != nil {
+		return "", err
+	}
+	return format.String(), nil
+}
+
+// Active reports whether the key has not been revoked
+func (k APIKey) Active() bool {
+	return k.RevokedAt == nil
+}
+
+// NormalizeScopes validates the requested scopes and returns them de-duplicated
+// in canonical order as a comma-separated string
+func NormalizeScopes(scopes []string) (string, error) {
+	return normalizeScopes(APIKeyScopes, scopes)
+}
+
+// normalizeScopes validates scopes against the allowed list
+func normalizeScopes(allowed, scopes []string) (string, error) {
+	requested := make(map[string]bool)
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+		if !containsString(allowed, scope) {
+			return "", fmt.Errorf("unknown scope %q (supported: %s)", scope, strings.Join(allowed, ", "))
+		}
+		requested[scope] = true
+	}
+	if len(requested) == 0 {
+		return "", fmt.Errorf("at least one scope is required")
+	}
+
+	normalized := make([]string, 0, len(requested))
+	for _, scope := range allowed {
+		if requested[scope] {
+			normalized = append(normalized, scope)
+		}
+	}
+	return strings.Join(normalized, ","), nil
+}
+
+// SplitList splits a comma-separated column value, dropping blank entries
+func SplitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// containsString reports whether list contains value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
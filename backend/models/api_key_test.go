@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeScopes(t *testing.T) {
+	tests := []struct {
+		scopes   []string
+		expected string
+		hasError bool
+	}{
+		{[]string{"trigger_crawl", "read_prices"}, "read_prices,trigger_crawl", false},
+		{[]string{"read_prices", " read_prices ", ""}, "read_prices", false},
+		{[]string{"admin"}, "", true},
+		{[]string{}, "", true},
+	}
+
+	for _, tt := range tests {
+		result, err := NormalizeScopes(tt.scopes)
+		if (err != nil) != tt.hasError {
+			t.Errorf("NormalizeScopes(%v) error = %v; want error: %v", tt.scopes, err, tt.hasError)
+		}
+		if result != tt.expected {
+			t.Errorf("NormalizeScopes(%v) = %q; want %q", tt.scopes, result, tt.expected)
+		}
+	}
+}
+
+func TestAPIKeyHasScope(t *testing.T) {
+	key := APIKey{Scopes: "read_prices"}
+	if !key.HasScope(ScopeReadPrices) {
+		t.Errorf("HasScope(%s) = false; want true", ScopeReadPrices)
+	}
+	if key.HasScope(ScopeTriggerCrawl) {
+		t.Errorf("HasScope(%s) = true; want false", ScopeTriggerCrawl)
+	}
+
+	if !key.Active() {
+		t.Error("Active() = false for key without revoked_at; want true")
+	}
+	revokedAt := time.Now()
+	key.RevokedAt = &revokedAt
+	if key.Active() {
+		t.Error("Active() = true for revoked key; want false")
+	}
+}
+
+func TestNormalizePersonalTokenScopes(t *testing.T) {
+	if result, err := NormalizePersonalTokenScopes(nil); err != nil || result != ScopeReadPrices {
+		t.Errorf("NormalizePersonalTokenScopes(nil) = %q, %v; want %q", result, err, ScopeReadPrices)
+	}
+	if _, err := NormalizePersonalTokenScopes([]string{ScopeTriggerCrawl}); err == nil {
+		t.Errorf("NormalizePersonalTokenScopes(%s) expected error for admin-only scope", ScopeTriggerCrawl)
+	}
+
+	expiresAt := time.Now().Add(-time.Minute)
+	token := PersonalAccessToken{Scopes: ScopeReadPrices, ExpiresAt: &expiresAt}
+	if token.Active(time.Now()) {
+		t.Error("Active() = true for expired token; want false")
+	}
+}
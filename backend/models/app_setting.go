@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// AppSetting represents the app_settings table in Supabase
+// Stored settings override environment variables and are reloaded at runtime
+type AppSetting struct {
+	Key       string    `gorm:"type:text;primary_key;column:key" json:"key"`
+	Value     string    `gorm:"type:text;not null;column:value" json:"value"`
+	UpdatedBy *string   `gorm:"type:text;column:updated_by" json:"updated_by,omitempty"`
+	UpdatedAt time.Time `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AppSetting) TableName() string {
+	return "public.app_settings"
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Audited actions
+const (
+	AuditActionLogin       = "auth.login"        // Admin login attempt (dashboard or token endpoint)
+	AuditActionAdminUnlock = "admin_user.unlock" // Admin account unlocked after a lockout
+	AuditActionProfileEdit = "profile.update"    // Membership or active state of a member changed by an admin
+	AuditActionPayment     = "payment.webhook"   // Payment notification received from a payment provider
+	AuditActionDataExport  = "profile.export"    // All data held about a member exported
+	AuditActionDataErase   = "profile.erase"     // A member's personal data erased or anonymized
+
+	AuditActionCredentialSet    = "provider_credential.set"    // Data provider credential stored or rotated
+	AuditActionCredentialDelete = "provider_credential.delete" // Data provider credential removed
+
+	AuditActionRunbook = "runbook.run" // Recovery procedure run from the admin runbook
+)
+
+// SensitiveAuditActions are reviewed on the dashboard's security tab with
+// every outcome; logins are sensitive only when they did not succeed
+var SensitiveAuditActions = []string{
+	AuditActionAdminUnlock,
+	AuditActionProfileEdit,
+	AuditActionDataExport,
+	AuditActionDataErase,
+	AuditActionCredentialSet,
+	AuditActionCredentialDelete,
+	AuditActionRunbook,
+}
+
+// ModifyingAuditActions change the entity they are recorded for
+var ModifyingAuditActions = []string{
+	AuditActionAdminUnlock,
+	AuditActionProfileEdit,
+	AuditActionPayment,
+	AuditActionDataErase,
+	AuditActionCredentialSet,
+	AuditActionCredentialDelete,
+}
+
+// Audit outcomes
+const (
+	AuditOutcomeSuccess   = "success"
+	AuditOutcomeFailure   = "failure"
+	AuditOutcomeThrottled = "throttled" // Rejected without checking credentials
+	AuditOutcomeLocked    = "locked"    // Account locked, or attempt on a locked account
+)
+
+// AuditLog represents the audit_logs table in Supabase
+// Rows are append-only records of security-relevant actions
+type AuditLog struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	Actor      string    `gorm:"type:text;not null;column:actor" json:"actor"` // Admin username, or the identifier attempted for logins
+	Action     string    `gorm:"type:text;not null;column:action" json:"action"`
+	Outcome    string    `gorm:"type:text;not null;column:outcome" json:"outcome"`
+	EntityType *string   `gorm:"type:text;column:entity_type" json:"entity_type,omitempty"`
+	EntityID   *string   `gorm:"type:text;column:entity_id" json:"entity_id,omitempty"`
+	IP         *string   `gorm:"type:text;column:ip" json:"ip,omitempty"`
+	UserAgent  *string   `gorm:"type:text;column:user_agent" json:"user_agent,omitempty"`
+	Details    *string   `gorm:"type:text;column:details" json:"details,omitempty"`
+	CreatedAt  time.Time `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AuditLog) TableName() string {
+	return "public.audit_logs"
+}
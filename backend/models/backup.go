@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Backup statuses
+const (
+	BackupStatusSucceeded = "succeeded"
+	BackupStatusFailed    = "failed"
+)
+
+// BackupObject is one dumped collection or table: gzip-compressed JSON
+// lines, one document or row per line
+type BackupObject struct {
+	Name    string `json:"name"`   // e.g. "mongo.stocks" or "postgres.payments"
+	Object  string `json:"object"` // Object name in the bucket
+	Records int64  `json:"records"`
+	Bytes   int64  `json:"bytes"`  // Compressed size
+	SHA256  string `json:"sha256"` // Of the compressed object
+}
+
+// BackupManifest describes one nightly backup; it is stored next to the
+// dumped objects as manifest.json
+type BackupManifest struct {
+	Date        string         `json:"date"` // Vietnam date, also the folder name
+	StartedAt   time.Time      `json:"started_at"`
+	CompletedAt time.Time      `json:"completed_at"`
+	Objects     []BackupObject `json:"objects"`
+}
+
+// BackupReport is the outcome of a backup run, including its verification
+type BackupReport struct {
+	Date        string          `json:"date"`
+	Status      string          `json:"status"`
+	StartedAt   time.Time       `json:"started_at"`
+	CompletedAt time.Time       `json:"completed_at"`
+	Manifest    *BackupManifest `json:"manifest,omitempty"`
+	Verified    bool            `json:"verified"`          // Every object was downloaded, decoded and matched the manifest
+	Deleted     []string        `json:"deleted,omitempty"` // Backups removed by retention
+	Error       string          `json:"error,omitempty"`
+}
@@ -0,0 +1,271 @@
+package models
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Bucket history encodings
+const (
+	// BucketEncodingPlain stores history as an array of candle documents
+	BucketEncodingPlain = ""
+	// BucketEncodingColumnar stores history as delta-encoded columns
+	// (dates, opens, highs, lows, closes, volumes) compressed with zstd
+	BucketEncodingColumnar = "zcol1"
+)
+
+const (
+	columnarVersion = 1
+	// rawPriceScale marks prices stored as raw float64 bits because no
+	// decimal scale represents all of them exactly
+	rawPriceScale = 255
+	// maxPriceScale is the most decimal digits tried before falling back to raw
+	maxPriceScale = 6
+	// maxExactInt is the largest integer a float64 represents exactly
+	maxExactInt = 1 << 53
+)
+
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+	zstdDecoder, _ = zstd.NewReader(nil)
+
+	errCorruptColumnar = errors.New("corrupt columnar candle data")
+)
+
+// EncodeCandles packs candles into the columnar encoding. Candles are sorted
+// by date; every value round-trips exactly.
+func EncodeCandles(history []CandleData) []byte {
+	sorted := make([]CandleData, len(history))
+	copy(sorted, history)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].D < sorted[j].D })
+
+	buf := make([]byte, 0, 16+len(sorted)*12)
+	buf = append(buf, columnarVersion)
+	buf = binary.AppendUvarint(buf, uint64(len(sorted)))
+
+	// Dates as days since the Unix epoch: the first absolute, then gaps
+	var previousDay int64
+	for i, candle := range sorted {
+		day := dayNumber(candle.D)
+		if i == 0 {
+			buf = binary.AppendVarint(buf, day)
+		} else {
+			buf = binary.AppendVarint(buf, day-previousDay)
+		}
+		previousDay = day
+	}
+	for _, candle := range sorted {
+		if dayNumber(candle.D) == invalidDay {
+			// Unparseable dates are kept verbatim after the columns
+			buf = binary.AppendUvarint(buf, uint64(len(candle.D)))
+			buf = append(buf, candle.D...)
+		}
+	}
+
+	scale := priceScale(sorted)
+	buf = append(buf, scale)
+	for _, price := range []func(CandleData) float64{
+		func(c CandleData) float64 { return c.O },
+		func(c CandleData) float64 { return c.H },
+		func(c CandleData) float64 { return c.L },
+		func(c CandleData) float64 { return c.C },
+	} {
+		buf = appendPriceColumn(buf, sorted, price, scale)
+	}
+
+	var previousVolume int64
+	for _, candle := range sorted {
+		buf = binary.AppendVarint(buf, candle.V-previousVolume)
+		previousVolume = candle.V
+	}
+
+	return zstdEncoder.EncodeAll(buf, nil)
+}
+
+// DecodeCandles unpacks data produced by EncodeCandles
+func DecodeCandles(packed []byte) ([]CandleData, error) {
+	raw, err := zstdDecoder.DecodeAll(packed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress candles: %w", err)
+	}
+	r := &columnReader{buf: raw}
+
+	if version := r.readByte(); version != columnarVersion {
+		return nil, fmt.Errorf("unsupported columnar candle version %d", version)
+	}
+	count := r.uvarint()
+	if r.err != nil || count > uint64(len(raw)) {
+		return nil, errCorruptColumnar
+	}
+	candles := make([]CandleData, count)
+
+	var day int64
+	invalid := make([]int, 0)
+	for i := range candles {
+		if i == 0 {
+			day = r.varint()
+		} else {
+			day += r.varint()
+		}
+		if day == invalidDay {
+			invalid = append(invalid, i)
+			continue
+		}
+		candles[i].D = time.Unix(day*86400, 0).UTC().Format("2006-01-02")
+	}
+	for _, i := range invalid {
+		candles[i].D = string(r.next(int(r.uvarint())))
+	}
+
+	scale := r.readByte()
+	for _, set := range []func(*CandleData, float64){
+		func(c *CandleData, v float64) { c.O = v },
+		func(c *CandleData, v float64) { c.H = v },
+		func(c *CandleData, v float64) { c.L = v },
+		func(c *CandleData, v float64) { c.C = v },
+	} {
+		readPriceColumn(r, candles, set, scale)
+	}
+
+	var volume int64
+	for i := range candles {
+		volume += r.varint()
+		candles[i].V = volume
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	return candles, nil
+}
+
+// invalidDay marks dates that are not YYYY-MM-DD
+const invalidDay = math.MinInt32
+
+// dayNumber returns the days since the Unix epoch of a YYYY-MM-DD date
+func dayNumber(date string) int64 {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil || t.Format("2006-01-02") != date {
+		return invalidDay
+	}
+	return t.Unix() / 86400
+}
+
+// priceScale returns the fewest decimal digits that represent every price
+// exactly as an integer, or rawPriceScale when none does
+func priceScale(candles []CandleData) byte {
+	for scale := 0; scale <= maxPriceScale; scale++ {
+		multiplier := math.Pow10(scale)
+		exact := true
+		for _, candle := range candles {
+			for _, price := range []float64{candle.O, candle.H, candle.L, candle.C} {
+				scaled := math.Round(price * multiplier)
+				if math.Abs(scaled) >= maxExactInt || scaled/multiplier != price || math.Signbit(price) && price == 0 {
+					exact = false
+					break
+				}
+			}
+			if !exact {
+				break
+			}
+		}
+		if exact {
+			return byte(scale)
+		}
+	}
+	return rawPriceScale
+}
+
+// appendPriceColumn appends one price column as deltas of scaled integers,
+// or as raw float64 bits
+func appendPriceColumn(buf []byte, candles []CandleData, price func(CandleData) float64, scale byte) []byte {
+	if scale == rawPriceScale {
+		for _, candle := range candles {
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(price(candle)))
+		}
+		return buf
+	}
+
+	multiplier := math.Pow10(int(scale))
+	var previous int64
+	for _, candle := range candles {
+		value := int64(math.Round(price(candle) * multiplier))
+		buf = binary.AppendVarint(buf, value-previous)
+		previous = value
+	}
+	return buf
+}
+
+// readPriceColumn reads a column written by appendPriceColumn
+func readPriceColumn(r *columnReader, candles []CandleData, set func(*CandleData, float64), scale byte) {
+	if scale == rawPriceScale {
+		for i := range candles {
+			set(&candles[i], math.Float64frombits(binary.LittleEndian.Uint64(r.next(8))))
+		}
+		return
+	}
+	if scale > maxPriceScale {
+		r.err = errCorruptColumnar
+		return
+	}
+
+	multiplier := math.Pow10(int(scale))
+	var value int64
+	for i := range candles {
+		value += r.varint()
+		set(&candles[i], float64(value)/multiplier)
+	}
+}
+
+// columnReader reads varints from a buffer, recording the first error
+type columnReader struct {
+	buf []byte
+	err error
+}
+
+func (r *columnReader) readByte() byte {
+	b := r.next(1)
+	return b[0]
+}
+
+func (r *columnReader) next(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.buf) {
+		r.err = errCorruptColumnar
+		return make([]byte, max(n, 8))
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *columnReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errCorruptColumnar
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *columnReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errCorruptColumnar
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
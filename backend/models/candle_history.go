@@ -0,0 +1,127 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Candle history intervals. Only daily candles are stored; weekly and
+// monthly bars are resampled from them.
+const (
+	CandleInterval1D = "1D"
+	CandleInterval1W = "1W"
+	CandleInterval1M = "1M"
+)
+
+// candleIntervalAliases maps the interval spellings of vnstock ("1D", "1W",
+// "1M") and yfinance ("1d", "1wk", "1mo") to an interval. "1m" is a minute
+// in both, so it is not accepted as a month.
+var candleIntervalAliases = map[string]string{
+	"1D": CandleInterval1D, "1d": CandleInterval1D, "D": CandleInterval1D,
+	"1W": CandleInterval1W, "1wk": CandleInterval1W, "W": CandleInterval1W,
+	"1M": CandleInterval1M, "1mo": CandleInterval1M, "M": CandleInterval1M,
+}
+
+// ParseCandleInterval returns the interval named by raw, daily when empty
+func ParseCandleInterval(raw string) (string, error) {
+	if raw == "" {
+		return CandleInterval1D, nil
+	}
+	if interval, ok := candleIntervalAliases[raw]; ok {
+		return interval, nil
+	}
+	return "", fmt.Errorf("interval must be one of 1D, 1W, 1M (or 1d, 1wk, 1mo); intraday intervals are not available")
+}
+
+// HistoryEpoch is the first trading session of HOSE, the start of the "max"
+// history period
+var HistoryEpoch = time.Date(2000, time.July, 28, 0, 0, 0, 0, time.UTC)
+
+// HistoryPeriodStart returns the first date of a yfinance-style history
+// period (5d, 1mo, 3mo, 6mo, 1y, 2y, 5y, 10y, ytd, max) ending on to
+func HistoryPeriodStart(period string, to time.Time) (time.Time, error) {
+	switch period {
+	case "5d":
+		return to.AddDate(0, 0, -5), nil
+	case "1mo":
+		return to.AddDate(0, -1, 0), nil
+	case "3mo":
+		return to.AddDate(0, -3, 0), nil
+	case "6mo":
+		return to.AddDate(0, -6, 0), nil
+	case "1y":
+		return to.AddDate(-1, 0, 0), nil
+	case "2y":
+		return to.AddDate(-2, 0, 0), nil
+	case "5y":
+		return to.AddDate(-5, 0, 0), nil
+	case "10y":
+		return to.AddDate(-10, 0, 0), nil
+	case "ytd":
+		return time.Date(to.Year(), time.January, 1, 0, 0, 0, 0, to.Location()), nil
+	case "max":
+		return HistoryEpoch, nil
+	}
+	return time.Time{}, fmt.Errorf("period must be one of 5d, 1mo, 3mo, 6mo, 1y, 2y, 5y, 10y, ytd, max")
+}
+
+// HistoryBar is one row of the candle history in the column names of
+// vnstock and yfinance-style clients: an ISO date and OHLCV, prices in
+// thousands of đồng
+type HistoryBar struct {
+	Time   string  `json:"time"`
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume int64   `json:"volume"`
+}
+
+// HistoryColumns are the columns of a HistoryBar, in order
+var HistoryColumns = []string{"time", "open", "high", "low", "close", "volume"}
+
+// ResampleCandles turns daily candles (oldest first) into bars of interval.
+// A weekly (ISO week) or monthly bar is dated at its first session and takes
+// the first open, the highest high, the lowest low, the last close and the
+// total volume of its sessions.
+func ResampleCandles(candles []CandleData, interval string) []HistoryBar {
+	bars := make([]HistoryBar, 0, len(candles))
+	var period string
+	for _, candle := range candles {
+		key := candle.D
+		if interval != CandleInterval1D {
+			date, err := time.Parse("2006-01-02", candle.D)
+			if err != nil {
+				continue
+			}
+			if interval == CandleInterval1W {
+				year, week := date.ISOWeek()
+				key = fmt.Sprintf("%d-W%02d", year, week)
+			} else {
+				key = date.Format("2006-01")
+			}
+		}
+		if len(bars) > 0 && key == period {
+			bar := &bars[len(bars)-1]
+			if candle.H > bar.High {
+				bar.High = candle.H
+			}
+			if candle.L < bar.Low {
+				bar.Low = candle.L
+			}
+			bar.Close = candle.C
+			bar.Volume += candle.V
+			continue
+		}
+		period = key
+		bars = append(bars, HistoryBar{
+			Time:   candle.D,
+			Open:   candle.O,
+			High:   candle.H,
+			Low:    candle.L,
+			Close:  candle.C,
+			Volume: candle.V,
+		})
+	}
+	return bars
+}
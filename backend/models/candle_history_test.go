@@ -0,0 +1,60 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResampleCandles(t *testing.T) {
+	// Thursday 2026-01-29 to Tuesday 2026-02-03: two ISO weeks, two months
+	candles := []CandleData{
+		{D: "2026-01-29", O: 25, H: 26, L: 24.5, C: 25.5, V: 100},
+		{D: "2026-01-30", O: 25.5, H: 27, L: 25, C: 26.8, V: 200},
+		{D: "2026-02-02", O: 26.8, H: 27.2, L: 26, C: 26.1, V: 300},
+		{D: "2026-02-03", O: 26.1, H: 26.5, L: 25.8, C: 26.4, V: 400},
+	}
+
+	daily := ResampleCandles(candles, CandleInterval1D)
+	if len(daily) != 4 || daily[1] != (HistoryBar{Time: "2026-01-30", Open: 25.5, High: 27, Low: 25, Close: 26.8, Volume: 200}) {
+		t.Errorf("daily = %+v; want one bar per candle", daily)
+	}
+
+	weekly := ResampleCandles(candles, CandleInterval1W)
+	want := []HistoryBar{
+		{Time: "2026-01-29", Open: 25, High: 27, Low: 24.5, Close: 26.8, Volume: 300},
+		{Time: "2026-02-02", Open: 26.8, High: 27.2, Low: 25.8, Close: 26.4, Volume: 700},
+	}
+	if len(weekly) != len(want) || weekly[0] != want[0] || weekly[1] != want[1] {
+		t.Errorf("weekly = %+v; want %+v", weekly, want)
+	}
+
+	// The months split the same way as the weeks here
+	monthly := ResampleCandles(candles, CandleInterval1M)
+	if len(monthly) != len(want) || monthly[0] != want[0] || monthly[1] != want[1] {
+		t.Errorf("monthly = %+v; want %+v", monthly, want)
+	}
+}
+
+func TestParseCandleInterval(t *testing.T) {
+	for raw, want := range map[string]string{"": CandleInterval1D, "1d": CandleInterval1D, "1wk": CandleInterval1W, "1M": CandleInterval1M, "1mo": CandleInterval1M} {
+		if got, err := ParseCandleInterval(raw); err != nil || got != want {
+			t.Errorf("ParseCandleInterval(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	if _, err := ParseCandleInterval("1m"); err == nil {
+		t.Error("ParseCandleInterval(1m) succeeded; want an error for the minute interval")
+	}
+}
+
+func TestHistoryPeriodStart(t *testing.T) {
+	to := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	tests := map[string]string{"1mo": "2026-09-15", "ytd": "2026-01-01", "5y": "2021-10-15", "max": "2000-07-28"}
+	for period, want := range tests {
+		if got, err := HistoryPeriodStart(period, to); err != nil || got.Format("2006-01-02") != want {
+			t.Errorf("HistoryPeriodStart(%s) = %v, %v; want %s", period, got, err, want)
+		}
+	}
+	if _, err := HistoryPeriodStart("1d", to); err == nil {
+		t.Error("HistoryPeriodStart(1d) succeeded; want an error")
+	}
+}
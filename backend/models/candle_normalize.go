@@ -0,0 +1,138 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// candleTimestampLayouts are the date-with-time formats data sources have
+// been seen to send instead of YYYY-MM-DD
+var candleTimestampLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+}
+
+// CandleNormalization counts what NormalizeCandles changed or dropped
+type CandleNormalization struct {
+	Reformatted   int // Dates sent with a time component, reduced to the trading date
+	Duplicates    int // Candles dropped because another candle of the same date took precedence
+	NonTradingDay int // Candles dated on the exchange's weekend
+	Invalid       int // Candles whose date could not be parsed
+}
+
+// Changed reports whether any candle was reformatted or dropped
+func (n CandleNormalization) Changed() bool {
+	return n.Reformatted+n.Duplicates+n.NonTradingDay+n.Invalid > 0
+}
+
+// NormalizeCandles cleans candles as returned by a data source before they
+// are merged into buckets, so provider quirks never turn into two candles of
+// one date. It returns the candles sorted by date and what it changed.
+//
+//   - Dates carrying a time component are reduced to the calendar date in
+//     exchange time; timestamps with a zone are converted to it first, so
+//     "2024-01-14T17:00:00Z" is the session of 2024-01-15 in Vietnam.
+//   - Candles dated on the exchange's weekend are dropped. Holidays are kept:
+//     calendars lag make-up sessions, and a dropped real session cannot be
+//     recovered.
+//   - Of several candles of one date, the first by these rules is kept:
+//     consistent prices (0 < low <= open, close <= high) over inconsistent
+//     ones, traded volume over zero-volume placeholders, a plain date over a
+//     reformatted timestamp, higher volume (a later snapshot of the session),
+//     and finally the source's order.
+func NormalizeCandles(candles []CandleData, exchange Exchange) ([]CandleData, CandleNormalization) {
+	var report CandleNormalization
+
+	type candidate struct {
+		candle      CandleData
+		reformatted bool
+		order       int
+	}
+	best := make(map[string]candidate, len(candles))
+	for i, candle := range candles {
+		date, reformatted, ok := normalizeCandleDate(candle.D, exchange.Location())
+		if !ok {
+			report.Invalid++
+			continue
+		}
+		if reformatted {
+			report.Reformatted++
+		}
+		if isWeekend(exchange, date) {
+			report.NonTradingDay++
+			continue
+		}
+
+		candle.D = date
+		current := candidate{candle: candle, reformatted: reformatted, order: i}
+		if kept, seen := best[date]; seen {
+			report.Duplicates++
+			if !candlePrecedes(current.candle, current.reformatted, kept.candle, kept.reformatted) {
+				continue
+			}
+		}
+		best[date] = current
+	}
+
+	normalized := make([]CandleData, 0, len(best))
+	for _, kept := range best {
+		normalized = append(normalized, kept.candle)
+	}
+	sort.Slice(normalized, func(i, j int) bool { return normalized[i].D < normalized[j].D })
+	return normalized, report
+}
+
+// candlePrecedes reports whether candle a takes precedence over candle b of
+// the same date; ties keep b, the candle seen first
+func candlePrecedes(a CandleData, aReformatted bool, b CandleData, bReformatted bool) bool {
+	if aValid, bValid := consistentCandle(a), consistentCandle(b); aValid != bValid {
+		return aValid
+	}
+	if aTraded, bTraded := a.V > 0, b.V > 0; aTraded != bTraded {
+		return aTraded
+	}
+	if aReformatted != bReformatted {
+		return !aReformatted
+	}
+	return a.V > b.V
+}
+
+// consistentCandle reports whether open and close lie within a positive
+// low-high range
+func consistentCandle(c CandleData) bool {
+	return c.L > 0 && c.L <= c.H &&
+		c.O >= c.L && c.O <= c.H &&
+		c.C >= c.L && c.C <= c.H
+}
+
+// normalizeCandleDate returns raw as YYYY-MM-DD in loc and whether it carried
+// a time component
+func normalizeCandleDate(raw string, loc *time.Location) (string, bool, bool) {
+	raw = strings.TrimSpace(raw)
+	if _, err := time.Parse("2006-01-02", raw); err == nil {
+		return raw, false, true
+	}
+	for _, layout := range candleTimestampLayouts {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return t.In(loc).Format("2006-01-02"), true, true
+		}
+	}
+	return "", false, false
+}
+
+// isWeekend reports whether date (YYYY-MM-DD) falls on the exchange's weekend
+func isWeekend(exchange Exchange, date string) bool {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false
+	}
+	for _, weekend := range exchange.Weekend {
+		if day.Weekday() == weekend {
+			return true
+		}
+	}
+	return false
+}
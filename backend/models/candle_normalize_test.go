@@ -0,0 +1,76 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeCandles(t *testing.T) {
+	hose, ok := LookupExchange("HOSE")
+	if !ok {
+		t.Fatal("HOSE is not registered")
+	}
+
+	candles := []CandleData{
+		// 2024-01-15 (Monday) three times: a zero-volume placeholder, the session and an earlier snapshot
+		{D: "2024-01-15", O: 25, H: 25, L: 25, C: 25, V: 0},
+		{D: "2024-01-15T00:00:00+07:00", O: 25, H: 26, L: 24.5, C: 25.5, V: 1200},
+		{D: "2024-01-15", O: 25, H: 25.8, L: 24.5, C: 25.2, V: 900},
+		// 2024-01-12 (Friday) sent as a UTC timestamp of the previous evening
+		{D: "2024-01-11T17:00:00Z", O: 24, H: 24.5, L: 23.8, C: 24.2, V: 800},
+		// Saturday
+		{D: "2024-01-13", O: 24, H: 24, L: 24, C: 24, V: 10},
+		// Inconsistent prices lose to a consistent candle even with more volume
+		{D: "2024-01-16 00:00:00", O: 30, H: 26, L: 25, C: 25.5, V: 5000},
+		{D: "2024-01-16", O: 25.5, H: 26, L: 25, C: 25.8, V: 1000},
+		{D: "16/01/2024", O: 1, H: 1, L: 1, C: 1, V: 1},
+	}
+
+	got, report := NormalizeCandles(candles, hose)
+	want := []CandleData{
+		{D: "2024-01-12", O: 24, H: 24.5, L: 23.8, C: 24.2, V: 800},
+		{D: "2024-01-15", O: 25, H: 25.8, L: 24.5, C: 25.2, V: 900},
+		{D: "2024-01-16", O: 25.5, H: 26, L: 25, C: 25.8, V: 1000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeCandles() = %+v; want %+v", got, want)
+	}
+
+	wantReport := CandleNormalization{Reformatted: 3, Duplicates: 3, NonTradingDay: 1, Invalid: 1}
+	if report != wantReport || !report.Changed() {
+		t.Errorf("NormalizeCandles() report = %+v; want %+v", report, wantReport)
+	}
+}
+
+func TestNormalizeCandlesPrecedence(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b CandleData
+		aRef bool
+		bRef bool
+		want bool
+	}{
+		{"consistent beats inconsistent", CandleData{O: 10, H: 11, L: 9, C: 10, V: 1}, CandleData{O: 12, H: 11, L: 9, C: 10, V: 100}, false, false, true},
+		{"traded beats placeholder", CandleData{O: 10, H: 10, L: 10, C: 10, V: 5}, CandleData{O: 10, H: 10, L: 10, C: 10}, true, false, true},
+		{"plain date beats timestamp", CandleData{O: 10, H: 10, L: 10, C: 10, V: 5}, CandleData{O: 10, H: 10, L: 10, C: 10, V: 50}, false, true, true},
+		{"higher volume wins", CandleData{O: 10, H: 10, L: 10, C: 10, V: 50}, CandleData{O: 10, H: 10, L: 10, C: 10, V: 5}, false, false, true},
+		{"tie keeps the first", CandleData{O: 10, H: 10, L: 10, C: 10, V: 5}, CandleData{O: 10, H: 10, L: 10, C: 10, V: 5}, false, false, false},
+	}
+	for _, tt := range tests {
+		if got := candlePrecedes(tt.a, tt.aRef, tt.b, tt.bRef); got != tt.want {
+			t.Errorf("%s: candlePrecedes() = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeCandlesClean(t *testing.T) {
+	hose, _ := LookupExchange("HOSE")
+	candles := []CandleData{
+		{D: "2024-01-16", O: 25.5, H: 26, L: 25, C: 25.8, V: 1000},
+		{D: "2024-01-15", O: 25, H: 25.8, L: 24.5, C: 25.2, V: 900},
+	}
+	got, report := NormalizeCandles(candles, hose)
+	if report.Changed() || len(got) != 2 || got[0].D != "2024-01-15" {
+		t.Errorf("NormalizeCandles() = %+v, %+v; want both candles sorted and nothing changed", got, report)
+	}
+}
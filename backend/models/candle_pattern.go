@@ -0,0 +1,136 @@
+package models
+
+import "math"
+
+// Candlestick patterns
+const (
+	PatternDoji             = "doji"              // Open and close nearly equal: indecision
+	PatternHammer           = "hammer"            // Small body on top of a long lower shadow after a decline
+	PatternShootingStar     = "shooting_star"     // Small body under a long upper shadow after a rise
+	PatternBullishEngulfing = "bullish_engulfing" // A rising body covering the previous falling one
+	PatternBearishEngulfing = "bearish_engulfing" // A falling body covering the previous rising one
+	PatternMorningStar      = "morning_star"      // Long fall, small body, then a rise past the middle of the fall
+	PatternEveningStar      = "evening_star"      // Long rise, small body, then a fall past the middle of the rise
+)
+
+// Directions a pattern points to
+const (
+	PatternBullish = "bullish"
+	PatternBearish = "bearish"
+	PatternNeutral = "neutral"
+)
+
+// CandlePatterns lists every pattern with its direction, in display order
+var CandlePatterns = []struct {
+	Name      string
+	Direction string
+}{
+	{PatternDoji, PatternNeutral},
+	{PatternHammer, PatternBullish},
+	{PatternShootingStar, PatternBearish},
+	{PatternBullishEngulfing, PatternBullish},
+	{PatternBearishEngulfing, PatternBearish},
+	{PatternMorningStar, PatternBullish},
+	{PatternEveningStar, PatternBearish},
+}
+
+// patternTrendSessions is how many sessions before a hammer or shooting
+// star set the trend it reverses
+const patternTrendSessions = 5
+
+// ValidCandlePattern reports whether name is a known pattern
+func ValidCandlePattern(name string) bool {
+	return PatternDirection(name) != ""
+}
+
+// PatternDirection returns the direction of a pattern, "" for an unknown one
+func PatternDirection(name string) string {
+	for _, pattern := range CandlePatterns {
+		if pattern.Name == name {
+			return pattern.Direction
+		}
+	}
+	return ""
+}
+
+// CandlePattern is a pattern completed by a symbol's daily candle
+type CandlePattern struct {
+	Code      string  `json:"code"`
+	Date      string  `json:"date"` // Date of the candle completing it
+	Pattern   string  `json:"pattern"`
+	Direction string  `json:"direction"`
+	Close     float64 `json:"close"`
+}
+
+// DetectCandlePatterns returns the patterns completed by candle i of
+// candles (ordered by date), in the order of CandlePatterns. Patterns
+// needing earlier candles than are available are not reported.
+func DetectCandlePatterns(candles []CandleData, i int) []string {
+	patterns := make([]string, 0)
+	if i < 0 || i >= len(candles) {
+		return patterns
+	}
+	c := candles[i]
+	spread := c.H - c.L
+	if spread <= 0 {
+		return patterns
+	}
+	body := math.Abs(c.C - c.O)
+	upper := c.H - math.Max(c.O, c.C)
+	lower := math.Min(c.O, c.C) - c.L
+
+	if body <= 0.1*spread {
+		patterns = append(patterns, PatternDoji)
+	}
+	if i >= patternTrendSessions && body > 0 {
+		trend := c.C - candles[i-patternTrendSessions].C
+		if trend < 0 && lower >= 2*body && upper <= body {
+			patterns = append(patterns, PatternHammer)
+		}
+		if trend > 0 && upper >= 2*body && lower <= body {
+			patterns = append(patterns, PatternShootingStar)
+		}
+	}
+	if i >= 1 {
+		prev := candles[i-1]
+		prevBody := math.Abs(prev.C - prev.O)
+		if prev.C < prev.O && c.C > c.O && c.O <= prev.C && c.C >= prev.O && body > prevBody {
+			patterns = append(patterns, PatternBullishEngulfing)
+		}
+		if prev.C > prev.O && c.C < c.O && c.O >= prev.C && c.C <= prev.O && body > prevBody {
+			patterns = append(patterns, PatternBearishEngulfing)
+		}
+	}
+	if i >= 2 {
+		first, star := candles[i-2], candles[i-1]
+		firstBody := math.Abs(first.C - first.O)
+		longFirst := first.H > first.L && firstBody >= 0.6*(first.H-first.L)
+		smallStar := math.Abs(star.C-star.O) <= 0.3*firstBody
+		middle := (first.O + first.C) / 2
+		if longFirst && smallStar && first.C < first.O && math.Max(star.O, star.C) < first.C && c.C > c.O && c.C > middle {
+			patterns = append(patterns, PatternMorningStar)
+		}
+		if longFirst && smallStar && first.C > first.O && math.Min(star.O, star.C) > first.C && c.C < c.O && c.C < middle {
+			patterns = append(patterns, PatternEveningStar)
+		}
+	}
+	return patterns
+}
+
+// ScanCandlePatterns returns the patterns completed by the last sessions of
+// candles (ordered by date), newest first
+func ScanCandlePatterns(code string, candles []CandleData, sessions int) []CandlePattern {
+	found := make([]CandlePattern, 0)
+	for i := len(candles) - 1; i >= 0 && i >= len(candles)-sessions; i-- {
+		for _, name := range DetectCandlePatterns(candles, i) {
+			found = append(found, CandlePattern{
+				Code:      code,
+				Date:      candles[i].D,
+				Pattern:   name,
+				Direction: PatternDirection(name),
+				Close:     candles[i].C,
+			})
+		}
+	}
+	return found
+}
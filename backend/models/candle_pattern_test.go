@@ -0,0 +1,89 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+// falling returns n candles closing 1 lower each session, from start
+func falling(start float64, n int) []CandleData {
+	candles := make([]CandleData, n)
+	for i := range candles {
+		open := start - float64(i)
+		candles[i] = CandleData{O: open, H: open + 0.2, L: open - 1.2, C: open - 1}
+	}
+	return candles
+}
+
+func TestDetectCandlePatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		candles []CandleData
+		want    []string
+	}{
+		{
+			name:    "doji",
+			candles: []CandleData{{O: 10, H: 11, L: 9, C: 10.05}},
+			want:    []string{PatternDoji},
+		},
+		{
+			name:    "hammer after a decline",
+			candles: append(falling(20, 6), CandleData{O: 13.5, H: 14.1, L: 12, C: 14}),
+			want:    []string{PatternHammer},
+		},
+		{
+			name:    "long lower shadow without a decline",
+			candles: []CandleData{{O: 13.5, H: 14.1, L: 12, C: 14}},
+			want:    []string{},
+		},
+		{
+			name:    "bullish engulfing",
+			candles: []CandleData{{O: 10, H: 10.2, L: 9, C: 9.2}, {O: 9, H: 10.6, L: 8.9, C: 10.5}},
+			want:    []string{PatternBullishEngulfing},
+		},
+		{
+			name:    "bearish engulfing",
+			candles: []CandleData{{O: 9.2, H: 10.2, L: 9, C: 10}, {O: 10.3, H: 10.4, L: 8.8, C: 9}},
+			want:    []string{PatternBearishEngulfing},
+		},
+		{
+			name: "morning star",
+			candles: []CandleData{
+				{O: 12, H: 12.1, L: 9.9, C: 10},
+				{O: 9.6, H: 9.8, L: 9.3, C: 9.5},
+				{O: 9.8, H: 11.6, L: 9.7, C: 11.5},
+			},
+			want: []string{PatternMorningStar},
+		},
+		{
+			name: "evening star",
+			candles: []CandleData{
+				{O: 10, H: 12.1, L: 9.9, C: 12},
+				{O: 12.4, H: 12.7, L: 12.3, C: 12.5},
+				{O: 12.2, H: 12.3, L: 10.4, C: 10.5},
+			},
+			want: []string{PatternEveningStar},
+		},
+	}
+	for _, tt := range tests {
+		got := DetectCandlePatterns(tt.candles, len(tt.candles)-1)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: DetectCandlePatterns() = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScanCandlePatterns(t *testing.T) {
+	candles := []CandleData{
+		{D: "2026-03-02", O: 10, H: 11, L: 9, C: 10},
+		{D: "2026-03-03", O: 10, H: 10.2, L: 9, C: 9.2},
+		{D: "2026-03-04", O: 9, H: 10.6, L: 8.9, C: 10.5},
+	}
+	found := ScanCandlePatterns("HPG", candles, 3)
+	if len(found) != 2 || found[0].Pattern != PatternBullishEngulfing || found[0].Direction != PatternBullish || found[1].Date != "2026-03-02" {
+		t.Errorf("ScanCandlePatterns() = %+v; want the engulfing then the doji", found)
+	}
+	if found := ScanCandlePatterns("HPG", candles, 1); len(found) != 1 {
+		t.Errorf("ScanCandlePatterns(1 session) = %+v; want the newest only", found)
+	}
+}
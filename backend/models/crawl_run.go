@@ -0,0 +1,68 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Crawl run statuses
+const (
+	CrawlRunStatusRunning     = "running"     // Crawl is in progress
+	CrawlRunStatusSuccess     = "success"     // Crawl finished (individual symbols may still have failed)
+	CrawlRunStatusFailed      = "failed"      // Crawl aborted before prices could be fetched
+	CrawlRunStatusInterrupted = "interrupted" // Stopped by a shutdown; symbols not reached are recorded as errors for retry
+)
+
+// Crawl run kinds
+const (
+	CrawlRunKindFull     = "full"     // Whole stock universe (runs stored before kinds existed are full runs)
+	CrawlRunKindRetry    = "retry"    // Selected symbols, retried from the crawl error list or crawled with `crawl -codes`
+	CrawlRunKindIntraday = "intraday" // Recent prices of stored symbols, polled by the schedule during trading hours
+)
+
+// PartialCrawlRunKinds are the kinds of runs that do not refresh the whole
+// universe, left out of full-run metrics and follow-ups
+var PartialCrawlRunKinds = []string{CrawlRunKindRetry, CrawlRunKindIntraday}
+
+// CrawlSymbolError records a symbol whose prices could not be crawled
+type CrawlSymbolError struct {
+	Code           string              `bson:"code" json:"code"`
+	Error          string              `bson:"error" json:"error"`
+	At             primitive.DateTime  `bson:"at" json:"at"`
+	AcknowledgedBy string              `bson:"acknowledgedBy,omitempty" json:"acknowledgedBy,omitempty"`
+	AcknowledgedAt *primitive.DateTime `bson:"acknowledgedAt,omitempty" json:"acknowledgedAt,omitempty"`
+}
+
+// Acknowledged reports whether an admin (or a successful retry) dismissed the error
+func (e CrawlSymbolError) Acknowledged() bool {
+	return e.AcknowledgedAt != nil
+}
+
+// CrawlRun represents one execution of the crawler, stored in the crawl_runs collection
+type CrawlRun struct {
+	ID               primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Status           string              `bson:"status" json:"status"`
+	Kind             string              `bson:"kind,omitempty" json:"kind,omitempty"`
+	RetryOf          *primitive.ObjectID `bson:"retryOf,omitempty" json:"retryOf,omitempty"` // Run whose errors were retried
+	StartedAt        primitive.DateTime  `bson:"startedAt" json:"startedAt"`
+	FinishedAt       *primitive.DateTime `bson:"finishedAt,omitempty" json:"finishedAt,omitempty"`
+	TotalSymbols     int                 `bson:"totalSymbols" json:"totalSymbols"`
+	SucceededSymbols int                 `bson:"succeededSymbols" json:"succeededSymbols"`
+	FailedSymbols    int                 `bson:"failedSymbols" json:"failedSymbols"`
+	Errors           []CrawlSymbolError  `bson:"errors" json:"errors"`
+	SuspectCandles   int                 `bson:"suspectCandles,omitempty" json:"suspectCandles,omitempty"` // New candles held back for review (outside the price band)
+	Message          string              `bson:"message,omitempty" json:"message,omitempty"`
+	Verification     *CrawlVerification  `bson:"verification,omitempty" json:"verification,omitempty"` // Sampled re-fetch after the run finished
+}
+
+// Partial reports whether the run covered only part of the universe
+func (r CrawlRun) Partial() bool {
+	return r.Kind == CrawlRunKindRetry || r.Kind == CrawlRunKindIntraday
+}
+
+// FailureRatio returns the fraction of symbols that failed in this run (0..1)
+func (r CrawlRun) FailureRatio() float64 {
+	if r.TotalSymbols == 0 {
+		return 0
+	}
+	return float64(r.FailedSymbols) / float64(r.TotalSymbols)
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Crawl schedule modes
+const (
+	CrawlScheduleOff          = "off"           // Crawls are started from the API, Telegram or Pub/Sub only
+	CrawlScheduleTradingHours = "trading_hours" // Intraday polls and an end-of-day crawl on trading days
+)
+
+// Crawl windows of the market day
+const (
+	CrawlWindowIntraday = "intraday"   // Within the intraday window: recent prices are polled
+	CrawlWindowEndOfDay = "end_of_day" // After the end-of-day time: the full crawl is due once
+	CrawlWindowIdle     = "idle"       // A trading day outside both windows
+	CrawlWindowClosed   = "closed"     // No crawled exchange trades today
+)
+
+// CrawlSchedule is when the crawler runs by itself. Times of day are in each
+// exchange's local time and only apply on its trading days.
+type CrawlSchedule struct {
+	Mode             string        `json:"mode"`
+	IntradayStart    string        `json:"intraday_start"`    // HH:MM
+	IntradayEnd      string        `json:"intraday_end"`      // HH:MM, exclusive
+	IntradayInterval time.Duration `json:"intraday_interval"` // Between polls; 0 disables polling
+	EndOfDayAt       string        `json:"end_of_day_at"`     // HH:MM
+}
+
+// ParseClockRange parses a HH:MM-HH:MM window of the day
+func ParseClockRange(v string) (string, string, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(v), "-")
+	start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+	if !ok || !validClock(start) || !validClock(end) || start >= end {
+		return "", "", fmt.Errorf("expected a window like 09:00-15:00")
+	}
+	return start, end, nil
+}
+
+// Window returns the crawl window of now: intraday while any crawled
+// exchange trading today is within the intraday window, end of day once
+// all of them are past the end-of-day time
+func (s CrawlSchedule) Window(exchanges []Exchange, now time.Time) string {
+	trading := tradingToday(exchanges, now)
+	if len(trading) == 0 {
+		return CrawlWindowClosed
+	}
+	if endOfDay, _ := s.endOfDay(trading, now); !now.Before(endOfDay) {
+		return CrawlWindowEndOfDay
+	}
+	for _, exchange := range trading {
+		clock := now.In(exchange.Location()).Format("15:04")
+		if clock >= s.IntradayStart && clock < s.IntradayEnd {
+			return CrawlWindowIntraday
+		}
+	}
+	return CrawlWindowIdle
+}
+
+// Due returns the crawl due at now, given when the last crawl of any kind
+// and the last full crawl started: CrawlWindowEndOfDay when no full crawl
+// started since today's end-of-day time, CrawlWindowIntraday when the last
+// crawl is at least IntradayInterval old within the intraday window, or ""
+func (s CrawlSchedule) Due(exchanges []Exchange, now, lastCrawl, lastFullCrawl time.Time) string {
+	if s.Mode != CrawlScheduleTradingHours {
+		return ""
+	}
+	switch s.Window(exchanges, now) {
+	case CrawlWindowEndOfDay:
+		endOfDay, _ := s.endOfDay(tradingToday(exchanges, now), now)
+		if lastFullCrawl.Before(endOfDay) {
+			return CrawlWindowEndOfDay
+		}
+	case CrawlWindowIntraday:
+		if s.IntradayInterval > 0 && now.Sub(lastCrawl) >= s.IntradayInterval {
+			return CrawlWindowIntraday
+		}
+	}
+	return ""
+}
+
+// endOfDay returns the latest end-of-day time of exchanges on the date of
+// now in their local time
+func (s CrawlSchedule) endOfDay(exchanges []Exchange, now time.Time) (time.Time, bool) {
+	at, err := time.Parse("15:04", s.EndOfDayAt)
+	if err != nil || len(exchanges) == 0 {
+		return time.Time{}, false
+	}
+	var latest time.Time
+	for _, exchange := range exchanges {
+		local := now.In(exchange.Location())
+		t := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, exchange.Location())
+		if t.After(latest) {
+			latest = t
+		}
+	}
+	return latest, true
+}
+
+// tradingToday returns the exchanges trading on the date of now
+func tradingToday(exchanges []Exchange, now time.Time) []Exchange {
+	var trading []Exchange
+	for _, exchange := range exchanges {
+		if exchange.IsTradingDay(now) {
+			trading = append(trading, exchange)
+		}
+	}
+	return trading
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCrawlScheduleDue(t *testing.T) {
+	hose, _ := LookupExchange("HOSE")
+	hnx, _ := LookupExchange("HNX")
+	hose.Holidays = []string{"2026-09-02"}
+	hnx.Holidays = []string{"2026-09-02"}
+	exchanges := []Exchange{hose, hnx}
+	schedule := CrawlSchedule{
+		Mode:          CrawlScheduleTradingHours,
+		IntradayStart: "09:00", IntradayEnd: "15:00", IntradayInterval: 15 * time.Minute,
+		EndOfDayAt: "15:15",
+	}
+	ict := time.FixedZone("ICT", 7*60*60)
+	at := func(day, clock string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", day+" "+clock, ict)
+		return t
+	}
+	never := time.Time{}
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		lastCrawl     time.Time
+		lastFullCrawl time.Time
+		window        string
+		due           string
+	}{
+		{"before the session", at("2026-10-15", "08:30"), never, at("2026-10-14", "15:20"), CrawlWindowIdle, ""},
+		{"first poll", at("2026-10-15", "09:00"), at("2026-10-14", "15:20"), at("2026-10-14", "15:20"), CrawlWindowIntraday, CrawlWindowIntraday},
+		{"polled recently", at("2026-10-15", "10:10"), at("2026-10-15", "10:00"), at("2026-10-14", "15:20"), CrawlWindowIntraday, ""},
+		{"poll interval elapsed", at("2026-10-15", "10:15"), at("2026-10-15", "10:00"), at("2026-10-14", "15:20"), CrawlWindowIntraday, CrawlWindowIntraday},
+		{"after polling", at("2026-10-15", "15:05"), at("2026-10-15", "14:45"), at("2026-10-14", "15:20"), CrawlWindowIdle, ""},
+		{"end of day", at("2026-10-15", "15:15"), at("2026-10-15", "14:45"), at("2026-10-14", "15:20"), CrawlWindowEndOfDay, CrawlWindowEndOfDay},
+		{"end of day crawled", at("2026-10-15", "18:00"), at("2026-10-15", "15:16"), at("2026-10-15", "15:16"), CrawlWindowEndOfDay, ""},
+		{"weekend", at("2026-10-17", "10:00"), never, never, CrawlWindowClosed, ""},
+		{"holiday", at("2026-09-02", "10:00"), never, never, CrawlWindowClosed, ""},
+	}
+	for _, tt := range tests {
+		if got := schedule.Window(exchanges, tt.now); got != tt.window {
+			t.Errorf("%s: Window() = %q; want %q", tt.name, got, tt.window)
+		}
+		if got := schedule.Due(exchanges, tt.now, tt.lastCrawl, tt.lastFullCrawl); got != tt.due {
+			t.Errorf("%s: Due() = %q; want %q", tt.name, got, tt.due)
+		}
+	}
+
+	schedule.Mode = CrawlScheduleOff
+	if got := schedule.Due(exchanges, at("2026-10-15", "15:30"), never, never); got != "" {
+		t.Errorf("Due() with the schedule off = %q; want nothing", got)
+	}
+	schedule.Mode, schedule.IntradayInterval = CrawlScheduleTradingHours, 0
+	if got := schedule.Due(exchanges, at("2026-10-15", "10:00"), never, at("2026-10-14", "15:20")); got != "" {
+		t.Errorf("Due() without intraday polling = %q; want nothing", got)
+	}
+}
+
+func TestParseClockRange(t *testing.T) {
+	if start, end, err := ParseClockRange(" 09:00 - 15:00 "); err != nil || start != "09:00" || end != "15:00" {
+		t.Errorf("ParseClockRange() = %s, %s, %v; want 09:00, 15:00", start, end, err)
+	}
+	for _, v := range []string{"09:00", "15:00-09:00", "9:00-15:00", "09:00-25:00"} {
+		if _, _, err := ParseClockRange(v); err == nil {
+			t.Errorf("ParseClockRange(%q) succeeded; want an error", v)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxRecordedMismatches bounds the mismatching candles kept on a crawl run
+const MaxRecordedMismatches = 50
+
+// CandleMismatch is a stored candle that differs from the provider's
+type CandleMismatch struct {
+	Code     string     `bson:"code" json:"code"`
+	Date     string     `bson:"date" json:"date"`
+	Fields   []string   `bson:"fields" json:"fields"` // Differing fields: o, h, l, c, v
+	Stored   CandleData `bson:"stored" json:"stored"`
+	Provider CandleData `bson:"provider" json:"provider"`
+}
+
+// CrawlVerification records the re-fetch of a random sample of symbols
+// after a crawl run, comparing the provider's candles with the stored ones
+type CrawlVerification struct {
+	SampledSymbols    []string           `bson:"sampledSymbols" json:"sampledSymbols"`
+	FailedSymbols     []string           `bson:"failedSymbols,omitempty" json:"failedSymbols,omitempty"` // Could not be re-fetched
+	ComparedCandles   int                `bson:"comparedCandles" json:"comparedCandles"`
+	MismatchedCandles int                `bson:"mismatchedCandles" json:"mismatchedCandles"`
+	MissingCandles    int                `bson:"missingCandles" json:"missingCandles"`             // Provider candles not stored (e.g. held back as suspect)
+	Mismatches        []CandleMismatch   `bson:"mismatches,omitempty" json:"mismatches,omitempty"` // First MaxRecordedMismatches
+	CheckedAt         primitive.DateTime `bson:"checkedAt" json:"checkedAt"`
+}
+
+// MismatchRatio returns the fraction of compared candles that differed (0..1)
+func (v CrawlVerification) MismatchRatio() float64 {
+	if v.ComparedCandles == 0 {
+		return 0
+	}
+	return float64(v.MismatchedCandles) / float64(v.ComparedCandles)
+}
+
+// Add compares the stored candles of a symbol with the provider's and
+// accumulates the result. Only provider dates are checked, since the
+// provider returns just the latest sessions.
+func (v *CrawlVerification) Add(code string, stored, provider []CandleData) {
+	byDate := make(map[string]CandleData, len(stored))
+	for _, candle := range stored {
+		byDate[candle.D] = candle
+	}
+	for _, fetched := range provider {
+		candle, ok := byDate[fetched.D]
+		if !ok {
+			v.MissingCandles++
+			continue
+		}
+		v.ComparedCandles++
+		fields := CandleDifferences(candle, fetched)
+		if len(fields) == 0 {
+			continue
+		}
+		v.MismatchedCandles++
+		if len(v.Mismatches) < MaxRecordedMismatches {
+			v.Mismatches = append(v.Mismatches, CandleMismatch{
+				Code:     code,
+				Date:     fetched.D,
+				Fields:   fields,
+				Stored:   candle,
+				Provider: fetched,
+			})
+		}
+	}
+}
+
+// candlePriceTolerance absorbs float noise; prices are thousands of đồng
+// with at most two decimals
+const candlePriceTolerance = 1e-6
+
+// CandleDifferences returns the fields in which two candles of the same
+// date differ
+func CandleDifferences(a, b CandleData) []string {
+	var fields []string
+	for _, price := range []struct {
+		field string
+		a, b  float64
+	}{{"o", a.O, b.O}, {"h", a.H, b.H}, {"l", a.L, b.L}, {"c", a.C, b.C}} {
+		if math.Abs(price.a-price.b) > candlePriceTolerance {
+			fields = append(fields, price.field)
+		}
+	}
+	if a.V != b.V {
+		fields = append(fields, "v")
+	}
+	return fields
+}
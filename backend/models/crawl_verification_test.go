@@ -0,0 +1,53 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCandleDifferences(t *testing.T) {
+	base := CandleData{D: "2024-06-10", O: 25.1, H: 25.6, L: 24.9, C: 25.3, V: 1000}
+
+	if fields := CandleDifferences(base, base); len(fields) != 0 {
+		t.Errorf("CandleDifferences(same) = %v; want none", fields)
+	}
+	noisy := base
+	noisy.C = 25.3 + 1e-9
+	if fields := CandleDifferences(base, noisy); len(fields) != 0 {
+		t.Errorf("CandleDifferences(float noise) = %v; want none", fields)
+	}
+	changed := base
+	changed.H, changed.V = 25.7, 1200
+	if fields := CandleDifferences(base, changed); !reflect.DeepEqual(fields, []string{"h", "v"}) {
+		t.Errorf("CandleDifferences(changed) = %v; want [h v]", fields)
+	}
+}
+
+func TestCrawlVerificationAdd(t *testing.T) {
+	stored := []CandleData{
+		{D: "2024-06-06", O: 10, H: 11, L: 9, C: 10.5, V: 100},
+		{D: "2024-06-07", O: 10.5, H: 11, L: 10, C: 10.8, V: 200},
+		{D: "2024-06-10", O: 10.8, H: 11.2, L: 10.6, C: 11, V: 300},
+	}
+	provider := []CandleData{
+		{D: "2024-06-07", O: 10.5, H: 11, L: 10, C: 10.8, V: 200},
+		{D: "2024-06-10", O: 10.8, H: 11.2, L: 10.6, C: 11.1, V: 350},
+		{D: "2024-06-11", O: 11, H: 11.5, L: 10.9, C: 11.3, V: 400},
+	}
+
+	var v CrawlVerification
+	v.Add("AAA", stored, provider)
+
+	if v.ComparedCandles != 2 || v.MismatchedCandles != 1 || v.MissingCandles != 1 {
+		t.Errorf("compared/mismatched/missing = %d/%d/%d; want 2/1/1", v.ComparedCandles, v.MismatchedCandles, v.MissingCandles)
+	}
+	if len(v.Mismatches) != 1 || v.Mismatches[0].Date != "2024-06-10" || !reflect.DeepEqual(v.Mismatches[0].Fields, []string{"c", "v"}) {
+		t.Errorf("Mismatches = %+v; want 2024-06-10 differing in c and v", v.Mismatches)
+	}
+	if ratio := v.MismatchRatio(); ratio != 0.5 {
+		t.Errorf("MismatchRatio() = %g; want 0.5", ratio)
+	}
+	if ratio := (CrawlVerification{}).MismatchRatio(); ratio != 0 {
+		t.Errorf("MismatchRatio() without candles = %g; want 0", ratio)
+	}
+}
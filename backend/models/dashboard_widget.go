@@ -0,0 +1,137 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Dashboard widget limits
+const (
+	DashboardColumns            = 4 // Grid columns; a widget spans 1 to DashboardColumns
+	DefaultWidgetRefreshSeconds = 60
+	MinWidgetRefreshSeconds     = 10   // Fastest allowed auto-refresh
+	MaxWidgetRefreshSeconds     = 3600 // Slowest allowed auto-refresh
+	MaxDashboardWidgets         = 24
+)
+
+// WidgetType describes one kind of dashboard widget: the endpoint the
+// dashboard reads its data from and how it is displayed
+type WidgetType struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Endpoint    string   `json:"endpoint"`           // Data source; {code} is filled from settings
+	Display     string   `json:"display"`            // stat, table or chart
+	Rows        string   `json:"rows,omitempty"`     // Field of the response data holding the table rows
+	Settings    []string `json:"settings,omitempty"` // Required settings keys
+}
+
+// WidgetTypes lists the widgets an admin can place on the dashboard
+var WidgetTypes = map[string]WidgetType{
+	"crawler_status": {
+		Name: "crawler_status", Description: "Stock and price bucket counts",
+		Endpoint: "/api/crawler/status", Display: "stat",
+	},
+	"alert_status": {
+		Name: "alert_status", Description: "Alert rules and whether each is firing",
+		Endpoint: "/admin/api/alert-metrics", Display: "table", Rows: "evaluations",
+	},
+	"crawl_errors": {
+		Name: "crawl_errors", Description: "Unacknowledged symbol failures of the latest crawl",
+		Endpoint: "/admin/api/crawl-errors", Display: "table", Rows: "errors",
+	},
+	"price_storage": {
+		Name: "price_storage", Description: "Price bucket count and size per storage encoding",
+		Endpoint: "/admin/api/price-storage", Display: "table", Rows: "encodings",
+	},
+	"recent_logins": {
+		Name: "recent_logins", Description: "Latest admin login attempts",
+		Endpoint: "/admin/api/audit-logs?action=auth.login&limit=10", Display: "table",
+	},
+	"candles_chart": {
+		Name: "candles_chart", Description: "Closing price chart of one stock",
+		Endpoint: "/api/stocks/{code}/candles", Display: "chart", Settings: []string{"code"},
+	},
+}
+
+// WidgetTypeNames returns the widget type names, sorted
+func WidgetTypeNames() []string {
+	names := make([]string, 0, len(WidgetTypes))
+	for name := range WidgetTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DashboardWidget represents the dashboard_widgets table in Supabase
+// Each admin user arranges their own dashboard from these widgets
+type DashboardWidget struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	AdminUserID    uuid.UUID `gorm:"type:uuid;not null;column:admin_user_id" json:"admin_user_id"`
+	Type           string    `gorm:"type:text;not null;column:type" json:"type"`
+	Title          string    `gorm:"type:text;not null;column:title" json:"title"`
+	Position       int       `gorm:"type:integer;not null;default:0;column:position" json:"position"` // Order on the dashboard, ascending
+	Width          int       `gorm:"type:integer;not null;default:1;column:width" json:"width"`       // Grid columns spanned
+	RefreshSeconds int       `gorm:"type:integer;not null;default:60;column:refresh_seconds" json:"refresh_seconds"`
+	Settings       string    `gorm:"type:text;not null;default:'{}';column:settings" json:"settings"` // JSON object of type-specific options
+	CreatedAt      time.Time `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt      time.Time `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (DashboardWidget) TableName() string {
+	return "public.dashboard_widgets"
+}
+
+// Validate checks the widget type, layout and settings
+func (w DashboardWidget) Validate() error {
+	widgetType, ok := WidgetTypes[w.Type]
+	if !ok {
+		return fmt.Errorf("unknown widget type %q (supported: %s)", w.Type, strings.Join(WidgetTypeNames(), ", "))
+	}
+	if strings.TrimSpace(w.Title) == "" {
+		return fmt.Errorf("title is required")
+	}
+	if w.Width < 1 || w.Width > DashboardColumns {
+		return fmt.Errorf("width must be between 1 and %d", DashboardColumns)
+	}
+	if w.RefreshSeconds < MinWidgetRefreshSeconds || w.RefreshSeconds > MaxWidgetRefreshSeconds {
+		return fmt.Errorf("refresh_seconds must be between %d and %d", MinWidgetRefreshSeconds, MaxWidgetRefreshSeconds)
+	}
+	if w.Position < 0 {
+		return fmt.Errorf("position must not be negative")
+	}
+
+	var settings map[string]interface{}
+	if err := json.Unmarshal([]byte(w.Settings), &settings); err != nil || settings == nil {
+		return fmt.Errorf("settings must be a JSON object")
+	}
+	for _, key := range widgetType.Settings {
+		if value, ok := settings[key].(string); !ok || strings.TrimSpace(value) == "" {
+			return fmt.Errorf("%s widgets need a %q setting", w.Type, key)
+		}
+	}
+	return nil
+}
+
+// DefaultDashboardWidgets is the layout shown to admins who have not
+// customized their dashboard
+func DefaultDashboardWidgets() []DashboardWidget {
+	defaults := []DashboardWidget{
+		{Type: "crawler_status", Width: 1, RefreshSeconds: DefaultWidgetRefreshSeconds},
+		{Type: "alert_status", Width: 3, RefreshSeconds: DefaultWidgetRefreshSeconds},
+		{Type: "crawl_errors", Width: 2, RefreshSeconds: 300},
+		{Type: "recent_logins", Width: 2, RefreshSeconds: 300},
+	}
+	for i := range defaults {
+		defaults[i].Title = WidgetTypes[defaults[i].Type].Description
+		defaults[i].Position = i
+		defaults[i].Settings = "{}"
+	}
+	return defaults
+}
@@ -0,0 +1,44 @@
+package models
+
+import "testing"
+
+func TestDashboardWidgetValidate(t *testing.T) {
+	valid := DashboardWidget{Type: "crawler_status", Title: "Crawler", Width: 1, RefreshSeconds: 60, Settings: "{}"}
+
+	tests := []struct {
+		name     string
+		change   func(w *DashboardWidget)
+		hasError bool
+	}{
+		{"valid", func(w *DashboardWidget) {}, false},
+		{"unknown type", func(w *DashboardWidget) { w.Type = "weather" }, true},
+		{"blank title", func(w *DashboardWidget) { w.Title = "  " }, true},
+		{"zero width", func(w *DashboardWidget) { w.Width = 0 }, true},
+		{"too wide", func(w *DashboardWidget) { w.Width = DashboardColumns + 1 }, true},
+		{"full width", func(w *DashboardWidget) { w.Width = DashboardColumns }, false},
+		{"refresh too fast", func(w *DashboardWidget) { w.RefreshSeconds = 5 }, true},
+		{"refresh too slow", func(w *DashboardWidget) { w.RefreshSeconds = 7200 }, true},
+		{"negative position", func(w *DashboardWidget) { w.Position = -1 }, true},
+		{"settings not an object", func(w *DashboardWidget) { w.Settings = "[1,2]" }, true},
+		{"settings not JSON", func(w *DashboardWidget) { w.Settings = "code=HPG" }, true},
+		{"chart without code", func(w *DashboardWidget) { w.Type = "candles_chart" }, true},
+		{"chart with blank code", func(w *DashboardWidget) { w.Type = "candles_chart"; w.Settings = `{"code":" "}` }, true},
+		{"chart with code", func(w *DashboardWidget) { w.Type = "candles_chart"; w.Settings = `{"code":"HPG"}` }, false},
+	}
+
+	for _, tt := range tests {
+		widget := valid
+		tt.change(&widget)
+		if err := widget.Validate(); (err != nil) != tt.hasError {
+			t.Errorf("%s: Validate() error = %v; want error %v", tt.name, err, tt.hasError)
+		}
+	}
+}
+
+func TestDefaultDashboardWidgetsValid(t *testing.T) {
+	for _, widget := range DefaultDashboardWidgets() {
+		if err := widget.Validate(); err != nil {
+			t.Errorf("default widget %s is invalid: %v", widget.Type, err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DigestCandle is one symbol's candle in the end-of-day dataset of a date
+type DigestCandle struct {
+	Code     string `json:"code"`
+	Exchange string `json:"exchange"`
+	CandleData
+}
+
+// DataDigest summarizes the finalized end-of-day dataset of one trading
+// day, stored in the data_digests collection and sent to partners with the
+// data.digest webhook
+type DataDigest struct {
+	Date        string             `bson:"_id" json:"date"`
+	Symbols     int                `bson:"symbols" json:"symbols"`
+	Exchanges   map[string]int     `bson:"exchanges" json:"exchanges"` // Symbols per exchange
+	Volume      int64              `bson:"volume" json:"volume"`       // Total traded volume
+	Checksum    string             `bson:"checksum" json:"checksum"`   // ComputeDigestChecksum of the dataset
+	DownloadURL string             `bson:"downloadUrl" json:"downloadUrl"`
+	CrawlRunID  primitive.ObjectID `bson:"crawlRunId" json:"crawlRunId"` // Full crawl the dataset comes from
+	FinalizedAt primitive.DateTime `bson:"finalizedAt" json:"finalizedAt"`
+}
+
+// NewDataDigest summarizes the candles of date
+func NewDataDigest(date string, candles []DigestCandle) *DataDigest {
+	digest := &DataDigest{
+		Date:      date,
+		Symbols:   len(candles),
+		Exchanges: make(map[string]int),
+		Checksum:  ComputeDigestChecksum(candles),
+	}
+	for _, candle := range candles {
+		digest.Exchanges[candle.Exchange]++
+		digest.Volume += candle.V
+	}
+	return digest
+}
+
+// ComputeDigestChecksum returns the SHA-256 (hex) of a day's candles in a
+// canonical form: one "CODE|o|h|l|c|v" line per symbol, sorted by code.
+// Partners recompute it over the downloaded dataset to confirm they hold
+// exactly the digested data.
+func ComputeDigestChecksum(candles []DigestCandle) string {
+	sorted := make([]DigestCandle, len(candles))
+	copy(sorted, candles)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Code < sorted[j].Code })
+
+	h := sha256.New()
+	buf := make([]byte, 0, 96)
+	for _, candle := range sorted {
+		buf = buf[:0]
+		buf = append(buf, candle.Code...)
+		for _, price := range []float64{candle.O, candle.H, candle.L, candle.C} {
+			buf = append(buf, '|')
+			buf = strconv.AppendFloat(buf, price, 'g', -1, 64)
+		}
+		buf = append(buf, '|')
+		buf = strconv.AppendInt(buf, candle.V, 10)
+		buf = append(buf, '\n')
+		h.Write(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
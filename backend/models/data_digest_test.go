@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestNewDataDigest(t *testing.T) {
+	candles := []DigestCandle{
+		{Code: "VCB", Exchange: "HOSE", CandleData: CandleData{D: "2026-02-10", O: 90, H: 91.5, L: 89, C: 91, V: 1200}},
+		{Code: "ACB", Exchange: "HOSE", CandleData: CandleData{D: "2026-02-10", O: 25, H: 25.4, L: 24.9, C: 25.1, V: 3000}},
+		{Code: "SHS", Exchange: "HNX", CandleData: CandleData{D: "2026-02-10", O: 18, H: 18.2, L: 17.5, C: 17.9, V: 800}},
+	}
+
+	digest := NewDataDigest("2026-02-10", candles)
+	if digest.Symbols != 3 || digest.Exchanges["HOSE"] != 2 || digest.Exchanges["HNX"] != 1 || digest.Volume != 5000 {
+		t.Errorf("digest = %+v", digest)
+	}
+
+	// The checksum ignores order but covers every value
+	reordered := []DigestCandle{candles[2], candles[0], candles[1]}
+	if got := ComputeDigestChecksum(reordered); got != digest.Checksum {
+		t.Errorf("checksum depends on order: %s != %s", got, digest.Checksum)
+	}
+	changed := append([]DigestCandle{}, candles...)
+	changed[1].V++
+	if got := ComputeDigestChecksum(changed); got == digest.Checksum {
+		t.Error("checksum did not change with the volume")
+	}
+}
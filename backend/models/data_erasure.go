@@ -0,0 +1,46 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Who requested an erasure
+const (
+	ErasureRequestedByMember = "member" // The member, through /api/me/erase
+)
+
+// DataErasure represents the data_erasures table in Supabase
+// Compliance log of erased profiles. It outlives the erased data, so it
+// holds only a hash of the email: enough to answer whether an address was
+// erased, not to recover it.
+type DataErasure struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	ProfileID   uuid.UUID `gorm:"type:uuid;not null;column:profile_id" json:"profile_id"`
+	EmailHash   string    `gorm:"type:text;not null;column:email_hash" json:"email_hash"`
+	RequestedBy string    `gorm:"type:text;not null;column:requested_by" json:"requested_by"` // "member" or the admin's username
+	Reason      *string   `gorm:"type:text;column:reason" json:"reason,omitempty"`
+	Rows        string    `gorm:"type:jsonb;not null;column:rows" json:"rows"` // Rows deleted or anonymized per table
+	CreatedAt   time.Time `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (DataErasure) TableName() string {
+	return "public.data_erasures"
+}
+
+// ErasureEmailHash returns the SHA-256 (hex) of a normalized email address
+func ErasureEmailHash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(sum[:])
+}
+
+// ErasedEmail returns the placeholder address of an erased profile; emails
+// are unique and required, so the address cannot simply be cleared
+func ErasedEmail(profileID uuid.UUID) string {
+	return "erased-" + profileID.String() + "@erased.invalid"
+}
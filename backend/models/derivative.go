@@ -0,0 +1,152 @@
+package models
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DerivativesExchange is the exchange listing Vietnamese futures, whose data
+// source serves them
+const DerivativesExchange = "HNX"
+
+// DefaultFuturesUnderlying is the underlying of the contracts listed when
+// none is asked for
+const DefaultFuturesUnderlying = "VN30"
+
+// Futures contract states
+const (
+	FuturesStatusActive  = "active"
+	FuturesStatusExpired = "expired"
+)
+
+// futuresCodePattern matches a contract code: the underlying, F, then the
+// year and month of expiry (VN30F2611 expires in November 2026)
+var futuresCodePattern = regexp.MustCompile(`^([A-Z0-9]+)F(\d{2})(\d{2})$`)
+
+// futuresAliasPattern matches the rolling codes of the nearest contracts:
+// VN30F1M is the front month, VN30F2M the next one
+var futuresAliasPattern = regexp.MustCompile(`^([A-Z0-9]+)F([1-9])M$`)
+
+// FuturesContract is a futures contract, one document per contract in the
+// futures_contracts collection
+type FuturesContract struct {
+	Code             string             `bson:"_id" json:"code"`
+	Underlying       string             `bson:"underlying" json:"underlying"`       // e.g. VN30
+	ContractMonth    string             `bson:"contractMonth" json:"contractMonth"` // YYYY-MM of expiry
+	FirstTradingDate string             `bson:"firstTradingDate,omitempty" json:"firstTradingDate,omitempty"`
+	LastTradingDate  string             `bson:"lastTradingDate" json:"lastTradingDate"`
+	Multiplier       float64            `bson:"multiplier,omitempty" json:"multiplier,omitempty"` // Đồng per index point
+	UpdatedAt        primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	Status           string             `bson:"-" json:"status,omitempty"` // StatusOn the day it is served
+}
+
+// StatusOn returns whether the contract still trades on date (YYYY-MM-DD)
+func (c FuturesContract) StatusOn(date string) string {
+	if c.LastTradingDate != "" && c.LastTradingDate < date {
+		return FuturesStatusExpired
+	}
+	return FuturesStatusActive
+}
+
+// FuturesCandle is the daily candle of a futures contract with its open
+// interest at the close
+type FuturesCandle struct {
+	D  string  `bson:"d" json:"d"`
+	O  float64 `bson:"o" json:"o"`
+	H  float64 `bson:"h" json:"h"`
+	L  float64 `bson:"l" json:"l"`
+	C  float64 `bson:"c" json:"c"`
+	V  int64   `bson:"v" json:"v"`
+	OI int64   `bson:"oi" json:"oi"` // Open contracts
+}
+
+// FuturesPrices is the price history of a contract, one document per
+// contract in the futures_prices collection: contracts trade for at most a
+// year, so they need no yearly buckets
+type FuturesPrices struct {
+	Code       string          `bson:"_id" json:"code"`
+	Underlying string          `bson:"underlying" json:"underlying"`
+	History    []FuturesCandle `bson:"history" json:"history"` // Oldest first
+}
+
+// ValidFuturesStatus reports whether status is a contract state
+func ValidFuturesStatus(status string) bool {
+	return status == FuturesStatusActive || status == FuturesStatusExpired
+}
+
+// ParseFuturesCode returns the underlying and contract month (YYYY-MM) of a
+// contract code
+func ParseFuturesCode(code string) (string, string, bool) {
+	match := futuresCodePattern.FindStringSubmatch(code)
+	if match == nil {
+		return "", "", false
+	}
+	month, _ := strconv.Atoi(match[3])
+	if month < 1 || month > 12 {
+		return "", "", false
+	}
+	return match[1], "20" + match[2] + "-" + match[3], true
+}
+
+// ParseFuturesAlias returns the underlying and the position (1 for the front
+// month) of a rolling contract code such as VN30F1M
+func ParseFuturesAlias(code string) (string, int, bool) {
+	match := futuresAliasPattern.FindStringSubmatch(code)
+	if match == nil {
+		return "", 0, false
+	}
+	position, _ := strconv.Atoi(match[2])
+	return match[1], position, true
+}
+
+// ExpiryDate returns the usual last trading day of a contract month
+// (YYYY-MM): its third Thursday. A holiday moves it earlier, so the
+// provider's date is preferred when published.
+func ExpiryDate(month string) (string, bool) {
+	first, err := time.Parse("2006-01", month)
+	if err != nil {
+		return "", false
+	}
+	offset := (int(time.Thursday) - int(first.Weekday()) + 7) % 7
+	return first.AddDate(0, 0, offset+14).Format("2006-01-02"), true
+}
+
+// NearestContract returns the contract at position (1 for the front month)
+// among those still trading on date, by last trading date
+func NearestContract(contracts []FuturesContract, date string, position int) (FuturesContract, bool) {
+	trading := make([]FuturesContract, 0, len(contracts))
+	for _, contract := range contracts {
+		if contract.StatusOn(date) == FuturesStatusActive {
+			trading = append(trading, contract)
+		}
+	}
+	sort.Slice(trading, func(i, j int) bool { return trading[i].LastTradingDate < trading[j].LastTradingDate })
+	if position < 1 || position > len(trading) {
+		return FuturesContract{}, false
+	}
+	return trading[position-1], true
+}
+
+// MergeFuturesCandles merges fetched candles into the stored history, a
+// fetched date replacing the stored candle, oldest first
+func MergeFuturesCandles(stored, fetched []FuturesCandle) []FuturesCandle {
+	byDate := make(map[string]FuturesCandle, len(stored)+len(fetched))
+	for _, candle := range stored {
+		byDate[candle.D] = candle
+	}
+	for _, candle := range fetched {
+		if candle.D != "" {
+			byDate[candle.D] = candle
+		}
+	}
+	merged := make([]FuturesCandle, 0, len(byDate))
+	for _, candle := range byDate {
+		merged = append(merged, candle)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].D < merged[j].D })
+	return merged
+}
@@ -0,0 +1,64 @@
+package models
+
+import "testing"
+
+func TestParseFuturesCode(t *testing.T) {
+	underlying, month, ok := ParseFuturesCode("VN30F2611")
+	if !ok || underlying != "VN30" || month != "2026-11" {
+		t.Errorf("ParseFuturesCode(VN30F2611) = %q, %q, %v; want VN30, 2026-11", underlying, month, ok)
+	}
+	for _, code := range []string{"HPG", "VN30F2613", "VN30F1M", "vn30f2611"} {
+		if _, _, ok := ParseFuturesCode(code); ok {
+			t.Errorf("ParseFuturesCode(%q) succeeded; want false", code)
+		}
+	}
+
+	underlying, position, ok := ParseFuturesAlias("VN30F2M")
+	if !ok || underlying != "VN30" || position != 2 {
+		t.Errorf("ParseFuturesAlias(VN30F2M) = %q, %d, %v; want VN30, 2", underlying, position, ok)
+	}
+	if _, _, ok := ParseFuturesAlias("VN30F2611"); ok {
+		t.Error("ParseFuturesAlias(VN30F2611) succeeded; want false")
+	}
+}
+
+func TestExpiryDate(t *testing.T) {
+	for month, want := range map[string]string{
+		"2026-11": "2026-11-19", // Starts on a Sunday
+		"2026-10": "2026-10-15", // Starts on a Thursday
+		"2027-01": "2027-01-21",
+	} {
+		if got, ok := ExpiryDate(month); !ok || got != want {
+			t.Errorf("ExpiryDate(%s) = %s, %v; want %s", month, got, ok, want)
+		}
+	}
+}
+
+func TestNearestContract(t *testing.T) {
+	contracts := []FuturesContract{
+		{Code: "VN30F2612", LastTradingDate: "2026-12-17"},
+		{Code: "VN30F2610", LastTradingDate: "2026-10-15"},
+		{Code: "VN30F2611", LastTradingDate: "2026-11-19"},
+	}
+	if front, ok := NearestContract(contracts, "2026-10-15", 1); !ok || front.Code != "VN30F2610" {
+		t.Errorf("front month on its last day = %+v; want VN30F2610", front)
+	}
+	if front, ok := NearestContract(contracts, "2026-10-16", 1); !ok || front.Code != "VN30F2611" {
+		t.Errorf("front month after expiry = %+v; want VN30F2611", front)
+	}
+	if next, ok := NearestContract(contracts, "2026-10-16", 2); !ok || next.Code != "VN30F2612" {
+		t.Errorf("next month = %+v; want VN30F2612", next)
+	}
+	if _, ok := NearestContract(contracts, "2026-10-16", 3); ok {
+		t.Error("third contract after expiry succeeded; want false")
+	}
+}
+
+func TestMergeFuturesCandles(t *testing.T) {
+	stored := []FuturesCandle{{D: "2026-10-13", C: 1400, OI: 40000}, {D: "2026-10-14", C: 1401}}
+	fetched := []FuturesCandle{{D: "2026-10-15", C: 1405, OI: 42000}, {D: "2026-10-14", C: 1402, OI: 41000}}
+	merged := MergeFuturesCandles(stored, fetched)
+	if len(merged) != 3 || merged[1].C != 1402 || merged[1].OI != 41000 || merged[2].D != "2026-10-15" {
+		t.Errorf("MergeFuturesCandles() = %+v; want 3 candles with the re-fetched 2026-10-14", merged)
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import "testing"
+
+func TestEntityTag(t *testing.T) {
+	tag := EntityTag("HPG", "2024-01-01", "HPG_2024:abc:250")
+	if tag != EntityTag("HPG", "2024-01-01", "HPG_2024:abc:250") {
+		t.Error("EntityTag() differs for the same parts")
+	}
+	if tag == EntityTag("HPG", "2024-01-01", "HPG_2024:abc:251") {
+		t.Error("EntityTag() is the same for different parts")
+	}
+	if tag == EntityTag("HPG2024-01-01", "HPG_2024:abc:250") {
+		t.Error("EntityTag() ignores part boundaries")
+	}
+	if len(tag) != 36 || tag[:3] != `W/"` || tag[len(tag)-1] != '"' {
+		t.Errorf("EntityTag() = %s; want a weak tag of 32 hex digits", tag)
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"0123abcd"`
+	tests := []struct {
+		ifNoneMatch string
+		expected    bool
+	}{
+		{`W/"0123abcd"`, true},
+		{`"0123abcd"`, true},
+		{`"ffff", W/"0123abcd"`, true},
+		{`*`, true},
+		{`W/"ffff"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := ETagMatches(tt.ifNoneMatch, etag); got != tt.expected {
+			t.Errorf("ETagMatches(%q) = %v; want %v", tt.ifNoneMatch, got, tt.expected)
+		}
+	}
+}
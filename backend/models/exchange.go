@@ -0,0 +1,310 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/markettime"
+)
+
+// Market data sources that list exchanges' symbols and prices
+const (
+	DataSourceVNDirect = "vndirect"
+	DataSourceSSI      = "ssi"
+)
+
+// TradingSession is one continuous trading window in exchange local time
+type TradingSession struct {
+	Open  string `json:"open"`  // HH:MM
+	Close string `json:"close"` // HH:MM
+}
+
+// PriceBandRule limits how far a price may move from the reference price in
+// one session. A zero Limit means the exchange has no daily limit.
+type PriceBandRule struct {
+	Limit            float64     `json:"limit"`                       // Fraction of the reference price, e.g. 0.07
+	FirstDayLimit    float64     `json:"first_day_limit,omitempty"`   // Limit on a listing's first trading day (0: same as Limit)
+	TickSize         float64     `json:"tick_size,omitempty"`         // Limits are rounded inwards to this step (0: no rounding)
+	TickTiers        []PriceTier `json:"tick_tiers,omitempty"`        // Steps by price level, overriding TickSize from their From price
+	AverageReference bool        `json:"average_reference,omitempty"` // Reference is the previous session's average price, not its close
+}
+
+// PriceTier is the tick size of prices from From up to the next tier. Both
+// are in quoted units, i.e. thousands of đồng on the VN exchanges.
+type PriceTier struct {
+	From float64 `json:"from"`
+	Size float64 `json:"size"`
+}
+
+// hoseTickTiers: 10 đồng below 10,000, 50 đồng from 10,000 to 49,950 and
+// 100 đồng from 50,000
+var hoseTickTiers = []PriceTier{{From: 0, Size: 0.01}, {From: 10, Size: 0.05}, {From: 50, Size: 0.1}}
+
+// Tick returns the price step at price: the tier covering it, or TickSize
+// below the first tier. Tiers are in ascending order of From.
+func (r PriceBandRule) Tick(price float64) float64 {
+	tick := r.TickSize
+	for _, tier := range r.TickTiers {
+		if price < tier.From-1e-9 {
+			break
+		}
+		tick = tier.Size
+	}
+	return tick
+}
+
+// Exchange describes how one exchange trades: quote currency, timezone,
+// trading calendar and price band. Crawler and analytics code reads these
+// from the registry instead of assuming Vietnamese market rules.
+type Exchange struct {
+	Code       string           `json:"code"`
+	Name       string           `json:"name"`
+	Country    string           `json:"country"`
+	Currency   string           `json:"currency"`    // ISO 4217 code, e.g. VND
+	PriceScale float64          `json:"price_scale"` // Currency units per quoted price unit (VN quotes are in thousands of đồng)
+	Timezone   string           `json:"timezone"`    // IANA zone name; "" for the market time zone
+	Sessions   []TradingSession `json:"sessions"`
+	Weekend    []time.Weekday   `json:"weekend"`
+	Holidays   []string         `json:"holidays,omitempty"` // YYYY-MM-DD dates with no trading
+	PriceBand  PriceBandRule    `json:"price_band"`
+	Source     string           `json:"source"`            // Market data source that lists this exchange's symbols
+	Indexes    []string         `json:"indexes,omitempty"` // Market indexes crawled with the exchange, used as portfolio benchmarks
+
+	location *time.Location
+}
+
+// Location returns the exchange's time zone, the market time zone when it
+// has none or an unknown one
+func (e Exchange) Location() *time.Location {
+	if e.location != nil {
+		return e.location
+	}
+	if e.Timezone != "" {
+		if loc, err := time.LoadLocation(e.Timezone); err == nil {
+			return loc
+		}
+	}
+	return markettime.Location()
+}
+
+// Date returns the exchange's calendar date (YYYY-MM-DD) of t
+func (e Exchange) Date(t time.Time) string {
+	return t.In(e.Location()).Format(markettime.DateLayout)
+}
+
+// IsTradingDay reports whether the exchange trades on the calendar date of t
+// in exchange local time
+func (e Exchange) IsTradingDay(t time.Time) bool {
+	local := t.In(e.Location())
+	for _, day := range e.Weekend {
+		if local.Weekday() == day {
+			return false
+		}
+	}
+	date := local.Format("2006-01-02")
+	for _, holiday := range e.Holidays {
+		if holiday == date {
+			return false
+		}
+	}
+	return true
+}
+
+// IsOpen reports whether t falls within one of the exchange's trading sessions
+func (e Exchange) IsOpen(t time.Time) bool {
+	if !e.IsTradingDay(t) {
+		return false
+	}
+	clock := t.In(e.Location()).Format("15:04")
+	for _, session := range e.Sessions {
+		if clock >= session.Open && clock < session.Close {
+			return true
+		}
+	}
+	return false
+}
+
+// ClosesAt returns the end of the exchange's last trading session on the
+// calendar date of t in exchange local time
+func (e Exchange) ClosesAt(t time.Time) time.Time {
+	local := t.In(e.Location())
+	last := "00:00"
+	for _, session := range e.Sessions {
+		if session.Close > last {
+			last = session.Close
+		}
+	}
+	clock, _ := time.Parse("15:04", last)
+	return time.Date(local.Year(), local.Month(), local.Day(), clock.Hour(), clock.Minute(), 0, 0, e.Location())
+}
+
+// RecentSessions returns the dates (YYYY-MM-DD, newest first) of the last n
+// trading days of the exchange that closed by t
+func (e Exchange) RecentSessions(t time.Time, n int) []string {
+	day := t.In(e.Location())
+	if t.Before(e.ClosesAt(t)) {
+		day = day.AddDate(0, 0, -1)
+	}
+	sessions := make([]string, 0, n)
+	// A year without trading days means the calendar is wrong; stop there
+	for limit := 0; len(sessions) < n && limit < 366; limit++ {
+		if e.IsTradingDay(day) {
+			sessions = append(sessions, day.Format("2006-01-02"))
+			limit = 0
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+	return sessions
+}
+
+// PriceLimits returns the floor and ceiling prices allowed for a session
+// with the given reference price. Without a band both equal 0 and +Inf.
+func (e Exchange) PriceLimits(reference float64, firstDay bool) (floor, ceiling float64) {
+	limit := e.PriceBand.Limit
+	if firstDay && e.PriceBand.FirstDayLimit > 0 {
+		limit = e.PriceBand.FirstDayLimit
+	}
+	if limit <= 0 {
+		return 0, math.Inf(1)
+	}
+
+	floor = reference * (1 - limit)
+	ceiling = reference * (1 + limit)
+	// Round in integer ticks to avoid float drift such as 26.749999; each
+	// limit uses the tick of its own price level
+	if tick := e.PriceBand.Tick(floor); tick > 0 {
+		floor = math.Round(math.Ceil(math.Round(floor/tick*1e6)/1e6)*tick*1e6) / 1e6
+	}
+	if tick := e.PriceBand.Tick(ceiling); tick > 0 {
+		ceiling = math.Round(math.Floor(math.Round(ceiling/tick*1e6)/1e6)*tick*1e6) / 1e6
+	}
+	return floor, ceiling
+}
+
+var (
+	exchangesMu sync.RWMutex
+	exchanges   = map[string]Exchange{}
+)
+
+func init() {
+	for _, exchange := range []Exchange{
+		{
+			Code: "HOSE", Name: "Ho Chi Minh Stock Exchange", Country: "VN",
+			Currency: "VND", PriceScale: 1000, Timezone: markettime.DefaultZone,
+			Sessions:  []TradingSession{{Open: "09:00", Close: "11:30"}, {Open: "13:00", Close: "14:45"}},
+			Weekend:   []time.Weekday{time.Saturday, time.Sunday},
+			PriceBand: PriceBandRule{Limit: 0.07, FirstDayLimit: 0.20, TickTiers: hoseTickTiers},
+			Source:    DataSourceVNDirect,
+			Indexes:   []string{"VNINDEX", "VN30"},
+		},
+		{
+			Code: "HNX", Name: "Hanoi Stock Exchange", Country: "VN",
+			Currency: "VND", PriceScale: 1000, Timezone: markettime.DefaultZone,
+			Sessions:  []TradingSession{{Open: "09:00", Close: "11:30"}, {Open: "13:00", Close: "15:00"}},
+			Weekend:   []time.Weekday{time.Saturday, time.Sunday},
+			PriceBand: PriceBandRule{Limit: 0.10, FirstDayLimit: 0.30, TickSize: 0.1},
+			Source:    DataSourceVNDirect,
+			Indexes:   []string{"HNXINDEX"},
+		},
+		{
+			Code: "UPCOM", Name: "Unlisted Public Company Market", Country: "VN",
+			Currency: "VND", PriceScale: 1000, Timezone: markettime.DefaultZone,
+			Sessions:  []TradingSession{{Open: "09:00", Close: "11:30"}, {Open: "13:00", Close: "15:00"}},
+			Weekend:   []time.Weekday{time.Saturday, time.Sunday},
+			PriceBand: PriceBandRule{Limit: 0.15, FirstDayLimit: 0.40, TickSize: 0.1, AverageReference: true},
+			Source:    DataSourceVNDirect,
+			Indexes:   []string{"UPCOMINDEX"},
+		},
+	} {
+		if err := RegisterExchange(exchange); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterExchange adds or replaces an exchange in the registry, so a new
+// market only needs its rules and a data source registered
+func RegisterExchange(exchange Exchange) error {
+	exchange.Code = strings.ToUpper(strings.TrimSpace(exchange.Code))
+	if exchange.Code == "" {
+		return fmt.Errorf("exchange code is required")
+	}
+	if exchange.Source == "" {
+		return fmt.Errorf("exchange %s needs a data source", exchange.Code)
+	}
+	for _, session := range exchange.Sessions {
+		if !validClock(session.Open) || !validClock(session.Close) || session.Open >= session.Close {
+			return fmt.Errorf("exchange %s has an invalid session %s-%s", exchange.Code, session.Open, session.Close)
+		}
+	}
+	if exchange.Timezone != "" {
+		loc, err := time.LoadLocation(exchange.Timezone)
+		if err != nil {
+			return fmt.Errorf("exchange %s has an unknown timezone %q", exchange.Code, exchange.Timezone)
+		}
+		exchange.location = loc
+	}
+
+	exchangesMu.Lock()
+	defer exchangesMu.Unlock()
+	exchanges[exchange.Code] = exchange
+	return nil
+}
+
+// SetExchangeTimezone moves a registered exchange to the IANA zone name, or
+// to the market time zone when name is ""
+func SetExchangeTimezone(code, name string) error {
+	exchange, ok := LookupExchange(code)
+	if !ok {
+		return fmt.Errorf("unknown exchange %q", code)
+	}
+	exchange.Timezone, exchange.location = name, nil
+	return RegisterExchange(exchange)
+}
+
+// LookupExchange returns the registered exchange with the given code
+func LookupExchange(code string) (Exchange, bool) {
+	exchangesMu.RLock()
+	defer exchangesMu.RUnlock()
+	exchange, ok := exchanges[strings.ToUpper(code)]
+	return exchange, ok
+}
+
+// Exchanges returns every registered exchange, sorted by code
+func Exchanges() []Exchange {
+	exchangesMu.RLock()
+	defer exchangesMu.RUnlock()
+
+	list := make([]Exchange, 0, len(exchanges))
+	for _, exchange := range exchanges {
+		list = append(list, exchange)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// IndexExchange returns the registered exchange whose market indexes
+// include code
+func IndexExchange(code string) (Exchange, bool) {
+	exchangesMu.RLock()
+	defer exchangesMu.RUnlock()
+	code = strings.ToUpper(code)
+	for _, exchange := range exchanges {
+		for _, index := range exchange.Indexes {
+			if index == code {
+				return exchange, true
+			}
+		}
+	}
+	return Exchange{}, false
+}
+
+// validClock reports whether s is a HH:MM time of day
+func validClock(s string) bool {
+	t, err := time.Parse("15:04", s)
+	return err == nil && t.Format("15:04") == s
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestExchangeIsOpen(t *testing.T) {
+	hose, ok := LookupExchange("hose")
+	if !ok {
+		t.Fatal("HOSE is not registered")
+	}
+	hose.Holidays = []string{"2024-04-18"}
+
+	tests := []struct {
+		at       string
+		expected bool
+	}{
+		{"2024-01-15T02:30:00Z", true},  // Monday 09:30 ICT
+		{"2024-01-15T05:00:00Z", false}, // Lunch break 12:00 ICT
+		{"2024-01-15T07:44:00Z", true},  // 14:44 ICT
+		{"2024-01-15T07:45:00Z", false}, // HOSE continuous session closed
+		{"2024-01-13T02:30:00Z", false}, // Saturday
+		{"2024-04-18T02:30:00Z", false}, // Holiday
+		{"2024-01-14T23:30:00Z", false}, // Sunday in UTC, Monday 06:30 ICT
+	}
+
+	for _, tt := range tests {
+		at, _ := time.Parse(time.RFC3339, tt.at)
+		if got := hose.IsOpen(at); got != tt.expected {
+			t.Errorf("IsOpen(%s) = %v; want %v", tt.at, got, tt.expected)
+		}
+	}
+}
+
+func TestExchangeClosesAt(t *testing.T) {
+	hose, _ := LookupExchange("HOSE")
+	hnx, _ := LookupExchange("HNX")
+
+	// 2024-01-14T23:30Z is Monday 06:30 ICT, so the close is on Monday
+	at, _ := time.Parse(time.RFC3339, "2024-01-14T23:30:00Z")
+	if got := hose.ClosesAt(at).UTC().Format(time.RFC3339); got != "2024-01-15T07:45:00Z" {
+		t.Errorf("HOSE ClosesAt = %s; want 2024-01-15T07:45:00Z", got)
+	}
+	if got := hnx.ClosesAt(at).UTC().Format(time.RFC3339); got != "2024-01-15T08:00:00Z" {
+		t.Errorf("HNX ClosesAt = %s; want 2024-01-15T08:00:00Z", got)
+	}
+}
+
+func TestExchangeRecentSessions(t *testing.T) {
+	hose, _ := LookupExchange("HOSE")
+	hose.Holidays = []string{"2024-01-12"}
+
+	// Monday 10:00 ICT: Monday has not closed, Friday was a holiday
+	at, _ := time.Parse(time.RFC3339, "2024-01-15T03:00:00Z")
+	got := hose.RecentSessions(at, 3)
+	if want := []string{"2024-01-11", "2024-01-10", "2024-01-09"}; len(got) != 3 || got[0] != want[0] || got[2] != want[2] {
+		t.Errorf("RecentSessions(Monday morning) = %v; want %v", got, want)
+	}
+
+	// After the close Monday is the latest session
+	at, _ = time.Parse(time.RFC3339, "2024-01-15T08:00:00Z")
+	if got := hose.RecentSessions(at, 1); len(got) != 1 || got[0] != "2024-01-15" {
+		t.Errorf("RecentSessions(Monday evening) = %v; want [2024-01-15]", got)
+	}
+}
+
+func TestExchangePriceLimits(t *testing.T) {
+	hose, _ := LookupExchange("HOSE")
+	hnx, _ := LookupExchange("HNX")
+
+	tests := []struct {
+		exchange       Exchange
+		reference      float64
+		firstDay       bool
+		floor, ceiling float64
+	}{
+		{hose, 25.0, false, 23.25, 26.75},
+		{hose, 10.0, true, 8.0, 12.0},
+		// 10 đồng ticks below 10,000, 50 đồng up to 49,950, 100 đồng above
+		{hose, 9.5, false, 8.84, 10.15},
+		{hose, 48.0, false, 44.65, 51.3},
+		{hose, 60.3, false, 56.1, 64.5},
+		{hnx, 12.3, false, 11.1, 13.5},
+		{Exchange{}, 10.0, false, 0, math.Inf(1)},
+	}
+
+	for _, tt := range tests {
+		floor, ceiling := tt.exchange.PriceLimits(tt.reference, tt.firstDay)
+		if math.Abs(floor-tt.floor) > 1e-9 || ceiling != tt.ceiling && math.Abs(ceiling-tt.ceiling) > 1e-9 {
+			t.Errorf("%s PriceLimits(%v, %v) = %v, %v; want %v, %v",
+				tt.exchange.Code, tt.reference, tt.firstDay, floor, ceiling, tt.floor, tt.ceiling)
+		}
+	}
+}
+
+func TestPriceBandTick(t *testing.T) {
+	hose, _ := LookupExchange("HOSE")
+	hnx, _ := LookupExchange("HNX")
+
+	tests := []struct {
+		band  PriceBandRule
+		price float64
+		want  float64
+	}{
+		{hose.PriceBand, 9.99, 0.01},
+		{hose.PriceBand, 10, 0.05},
+		{hose.PriceBand, 49.95, 0.05},
+		{hose.PriceBand, 50, 0.1},
+		{hnx.PriceBand, 75, 0.1},
+		{PriceBandRule{}, 10, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.band.Tick(tt.price); got != tt.want {
+			t.Errorf("Tick(%v) = %v; want %v", tt.price, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterExchangeValidates(t *testing.T) {
+	invalid := []Exchange{
+		{Code: "", Source: "test", Timezone: "UTC"},
+		{Code: "X", Timezone: "UTC"},
+		{Code: "X", Source: "test", Timezone: "Mars/Olympus"},
+		{Code: "X", Source: "test", Timezone: "UTC", Sessions: []TradingSession{{Open: "16:00", Close: "09:30"}}},
+	}
+	for _, exchange := range invalid {
+		if err := RegisterExchange(exchange); err == nil {
+			t.Errorf("RegisterExchange(%+v) accepted an invalid exchange", exchange)
+		}
+	}
+	if _, ok := LookupExchange("X"); ok {
+		t.Error("invalid exchange was registered")
+	}
+}
+
+func TestExchangeTimezones(t *testing.T) {
+	if err := RegisterExchange(Exchange{Code: "TZTEST", Source: "test"}); err != nil {
+		t.Fatalf("RegisterExchange() without a timezone: %v", err)
+	}
+	exchange, _ := LookupExchange("TZTEST")
+	// 18:30 UTC is the next day in the market time zone
+	late := time.Date(2026, 10, 14, 18, 30, 0, 0, time.UTC)
+	if got := exchange.Date(late); got != "2026-10-15" {
+		t.Errorf("Date() without a timezone = %s; want the market date 2026-10-15", got)
+	}
+
+	if err := SetExchangeTimezone("TZTEST", "Europe/London"); err != nil {
+		t.Fatalf("SetExchangeTimezone() unexpected error: %v", err)
+	}
+	exchange, _ = LookupExchange("TZTEST")
+	if got := exchange.Date(late); got != "2026-10-14" {
+		t.Errorf("Date() in Europe/London = %s; want 2026-10-14", got)
+	}
+	if err := SetExchangeTimezone("TZTEST", "Mars/Olympus"); err == nil {
+		t.Error("SetExchangeTimezone(Mars/Olympus) succeeded; want an error")
+	}
+	if err := SetExchangeTimezone("NOPE", "UTC"); err == nil {
+		t.Error("SetExchangeTimezone() of an unknown exchange succeeded; want an error")
+	}
+}
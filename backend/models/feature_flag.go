@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Known feature flags and whether each is on when no setting exists
+const (
+	// FeatureScreener serves GET /api/market/screener
+	FeatureScreener = "screener"
+)
+
+// DefaultFeatureFlags are the flags on before any feature.* setting is
+// stored; every other flag is off until set
+var DefaultFeatureFlags = map[string]bool{
+	FeatureScreener: true,
+}
+
+// FeatureFlag is the parsed value of a feature.<name> setting: "true",
+// "false", or comma-separated targets ("env:production,tier:premium") turning
+// the feature on only in the listed environments and/or for the listed
+// membership tiers. Targets of the same kind are alternatives; both kinds
+// must match when given.
+type FeatureFlag struct {
+	Enabled      bool     `json:"enabled"`
+	Environments []string `json:"environments,omitempty"`
+	Tiers        []string `json:"tiers,omitempty"`
+}
+
+// ParseFeatureFlag parses the value of a feature.<name> setting
+func ParseFeatureFlag(v string) (FeatureFlag, error) {
+	if enabled, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+		return FeatureFlag{Enabled: enabled}, nil
+	}
+
+	flag := FeatureFlag{Enabled: true}
+	for _, target := range strings.Split(v, ",") {
+		if target = strings.TrimSpace(target); target == "" {
+			continue
+		}
+		kind, value, found := strings.Cut(target, ":")
+		value = strings.ToLower(strings.TrimSpace(value))
+		if !found || value == "" {
+			return FeatureFlag{}, fmt.Errorf("expected true, false or env:<name>/tier:<tier> targets")
+		}
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "env":
+			flag.Environments = append(flag.Environments, value)
+		case "tier":
+			if !ValidMembership(value) {
+				return FeatureFlag{}, fmt.Errorf("unknown tier %q (expected %q or %q)", value, MembershipFree, MembershipPremium)
+			}
+			flag.Tiers = append(flag.Tiers, value)
+		default:
+			return FeatureFlag{}, fmt.Errorf("unknown target %q (expected env or tier)", kind)
+		}
+	}
+	if len(flag.Environments) == 0 && len(flag.Tiers) == 0 {
+		return FeatureFlag{}, fmt.Errorf("expected true, false or env:<name>/tier:<tier> targets")
+	}
+	return flag, nil
+}
+
+// EnabledFor reports whether the flag is on in environment for a caller of
+// membership tier. Callers that are not members have no tier ("") and never
+// match a tier-targeted flag.
+func (f FeatureFlag) EnabledFor(environment, tier string) bool {
+	if !f.Enabled {
+		return false
+	}
+	if len(f.Environments) > 0 && !containsFold(f.Environments, environment) {
+		return false
+	}
+	if len(f.Tiers) > 0 && !containsFold(f.Tiers, tier) {
+		return false
+	}
+	return true
+}
+
+// containsFold reports whether values holds value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFeatureFlag(t *testing.T) {
+	tests := []struct {
+		value string
+		want  FeatureFlag
+		valid bool
+	}{
+		{"true", FeatureFlag{Enabled: true}, true},
+		{" 0 ", FeatureFlag{}, true},
+		{"env:production", FeatureFlag{Enabled: true, Environments: []string{"production"}}, true},
+		{"env:Staging, tier:premium", FeatureFlag{Enabled: true, Environments: []string{"staging"}, Tiers: []string{"premium"}}, true},
+		{"tier:gold", FeatureFlag{}, false},
+		{"region:hn", FeatureFlag{}, false},
+		{"env:", FeatureFlag{}, false},
+		{" , ", FeatureFlag{}, false},
+		{"yes please", FeatureFlag{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseFeatureFlag(tt.value)
+		if (err == nil) != tt.valid {
+			t.Errorf("ParseFeatureFlag(%q) error = %v; want valid %v", tt.value, err, tt.valid)
+			continue
+		}
+		if tt.valid && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFeatureFlag(%q) = %+v; want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFeatureFlagEnabledFor(t *testing.T) {
+	flag := FeatureFlag{Enabled: true, Environments: []string{"production", "staging"}, Tiers: []string{MembershipPremium}}
+	tests := []struct {
+		environment, tier string
+		want              bool
+	}{
+		{"production", MembershipPremium, true},
+		{"staging", MembershipPremium, true},
+		{"production", MembershipFree, false},
+		{"production", "", false},
+		{"development", MembershipPremium, false},
+	}
+	for _, tt := range tests {
+		if got := flag.EnabledFor(tt.environment, tt.tier); got != tt.want {
+			t.Errorf("EnabledFor(%q, %q) = %v; want %v", tt.environment, tt.tier, got, tt.want)
+		}
+	}
+	if (FeatureFlag{Enabled: true}).EnabledFor("development", "") != true {
+		t.Error("untargeted flag should be on everywhere")
+	}
+	if (FeatureFlag{}).EnabledFor("production", MembershipPremium) {
+		t.Error("disabled flag should be off everywhere")
+	}
+}
+
+func TestProfileTier(t *testing.T) {
+	now := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	tests := []struct {
+		name    string
+		profile Profile
+		want    string
+	}{
+		{"free", Profile{Membership: MembershipFree}, MembershipFree},
+		{"premium without expiry", Profile{Membership: MembershipPremium}, MembershipPremium},
+		{"premium until later", Profile{Membership: MembershipPremium, MembershipExpiresAt: &future}, MembershipPremium},
+		{"premium expired", Profile{Membership: MembershipPremium, MembershipExpiresAt: &past}, MembershipFree},
+		{"unknown profile", Profile{}, MembershipFree},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.Tier(now); got != tt.want {
+			t.Errorf("%s: Tier() = %q; want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+// RSIPeriod is the look-back of the relative strength index used by price alerts
+const RSIPeriod = 14
+
+// RSI returns Wilder's relative strength index of the closes (oldest first)
+// over period days. It needs at least period+1 closes; the result is more
+// stable with a longer history because of the smoothing.
+func RSI(closes []float64, period int) (float64, bool) {
+	if period <= 0 || len(closes) <= period {
+		return 0, false
+	}
+
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		change := closes[i] - closes[i-1]
+		if change > 0 {
+			gain += change
+		} else {
+			loss -= change
+		}
+	}
+	avgGain, avgLoss := gain/float64(period), loss/float64(period)
+
+	for i := period + 1; i < len(closes); i++ {
+		change := closes[i] - closes[i-1]
+		up, down := 0.0, 0.0
+		if change > 0 {
+			up = change
+		} else {
+			down = -change
+		}
+		avgGain = (avgGain*float64(period-1) + up) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + down) / float64(period)
+	}
+
+	if avgLoss == 0 {
+		if avgGain == 0 {
+			return 50, true
+		}
+		return 100, true
+	}
+	rs := avgGain / avgLoss
+	return 100 - 100/(1+rs), true
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Background job kinds
+const (
+	JobKindCrawl        = "crawl"        // Full crawl of the stock universe
+	JobKindCrawlRetry   = "crawl.retry"  // Re-crawl of selected symbols (backfills failed prices)
+	JobKindCrawlPoll    = "crawl.poll"   // Intraday poll of recent prices, queued by the crawl schedule
+	JobKindBackup       = "backup"       // Export of critical data to Google Cloud Storage
+	JobKindNotification = "notification" // Notification to operations channels
+)
+
+// Background job states
+const (
+	JobStatusQueued    = "queued"  // Waiting for its run_at time and a free worker
+	JobStatusRunning   = "running" // Claimed by a worker until locked_until
+	JobStatusSucceeded = "succeeded"
+	JobStatusFailed    = "failed" // Gave up after MaxAttempts
+	JobStatusCancelled = "cancelled"
+)
+
+// Job priorities: higher runs first among due jobs
+const (
+	JobPriorityLow    = -10
+	JobPriorityNormal = 0
+	JobPriorityHigh   = 10
+)
+
+const (
+	// DefaultJobMaxAttempts is how often a job runs before it fails
+	DefaultJobMaxAttempts = 5
+	// jobRetryBase is the wait after the first failed attempt; it doubles per attempt
+	jobRetryBase = time.Minute
+	// jobRetryMax caps the wait between attempts
+	jobRetryMax = 30 * time.Minute
+)
+
+// Job represents the jobs table in Supabase: one unit of background work,
+// run by whichever instance claims it first and retried with backoff. A
+// job whose instance dies is claimed again once its lock expires.
+type Job struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	Kind        string     `gorm:"type:text;not null;column:kind" json:"kind"`
+	Payload     string     `gorm:"type:text;not null;column:payload" json:"payload"` // JSON arguments of the handler
+	UniqueKey   *string    `gorm:"type:text;column:unique_key" json:"unique_key,omitempty"`
+	Priority    int        `gorm:"type:integer;not null;default:0;column:priority" json:"priority"`
+	Status      string     `gorm:"type:text;not null;column:status" json:"status"`
+	Attempts    int        `gorm:"type:integer;not null;default:0;column:attempts" json:"attempts"`
+	MaxAttempts int        `gorm:"type:integer;not null;column:max_attempts" json:"max_attempts"`
+	RunAt       time.Time  `gorm:"type:timestamptz;not null;column:run_at" json:"run_at"`
+	LockedBy    *string    `gorm:"type:text;column:locked_by" json:"locked_by,omitempty"` // Instance running the job
+	LockedUntil *time.Time `gorm:"type:timestamptz;column:locked_until" json:"locked_until,omitempty"`
+	LastError   *string    `gorm:"type:text;column:last_error" json:"last_error,omitempty"`
+	CreatedBy   *string    `gorm:"type:text;column:created_by" json:"created_by,omitempty"`
+	FinishedAt  *time.Time `gorm:"type:timestamptz;column:finished_at" json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Job) TableName() string {
+	return "public.jobs"
+}
+
+// Finished reports whether the job reached a final state
+func (j Job) Finished() bool {
+	return j.Status == JobStatusSucceeded || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
+
+// JobRetryDelay returns the wait before the next attempt after attempts
+// failed ones: 1m, 2m, 4m, ... capped at 30 minutes
+func JobRetryDelay(attempts int) time.Duration {
+	delay := jobRetryBase
+	for i := 1; i < attempts && delay < jobRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > jobRetryMax {
+		delay = jobRetryMax
+	}
+	return delay
+}
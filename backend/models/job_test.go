@@ -0,0 +1,18 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobRetryDelay(t *testing.T) {
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute}
+	for i, delay := range want {
+		if got := JobRetryDelay(i + 1); got != delay {
+			t.Errorf("JobRetryDelay(%d) = %s; want %s", i+1, got, delay)
+		}
+	}
+	if got := JobRetryDelay(20); got != 30*time.Minute {
+		t.Errorf("JobRetryDelay(20) = %s; want the 30m cap", got)
+	}
+}
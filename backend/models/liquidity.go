@@ -0,0 +1,152 @@
+package models
+
+import (
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// LiquidityWindow is how many sessions the liquidity metrics average over
+	LiquidityWindow = 20
+	// LiquidityParticipation is the share of the average daily volume one
+	// position may trade per session without moving the price much
+	LiquidityParticipation = 0.1
+)
+
+// LiquidityMetrics are the derived liquidity metrics of a symbol as of its
+// newest candle, one document per symbol in the stock_metrics collection.
+// Values are in the units of the candles (prices as quoted, volume in shares).
+type LiquidityMetrics struct {
+	Code               string             `bson:"_id" json:"code"`
+	Exchange           string             `bson:"exchange,omitempty" json:"exchange,omitempty"`
+	Industry           string             `bson:"industry,omitempty" json:"industry,omitempty"`
+	Sector             string             `bson:"sector,omitempty" json:"sector,omitempty"`
+	Date               string             `bson:"date" json:"date"` // Newest candle the metrics are computed at
+	Sessions           int                `bson:"sessions" json:"sessions"`
+	Close              float64            `bson:"close" json:"close"`
+	Volume             int64              `bson:"volume" json:"volume"`
+	AvgVolume          float64            `bson:"avgVolume20" json:"avgVolume20"`       // Average daily volume
+	AvgValue           float64            `bson:"avgValue20" json:"avgValue20"`         // Average daily close × volume
+	RelativeVolume     float64            `bson:"relativeVolume" json:"relativeVolume"` // Latest volume / average of the sessions before it
+	Volatility         float64            `bson:"volatility20" json:"volatility20"`     // Standard deviation of daily close returns, in percent
+	Amihud             float64            `bson:"amihud20" json:"amihud20"`             // Average |return| per billion of traded value; higher is less liquid
+	ZeroVolumeSessions int                `bson:"zeroVolumeSessions" json:"zeroVolumeSessions"`
+	MaxDailyPosition   int64              `bson:"maxDailyPosition" json:"maxDailyPosition"` // Shares tradable per session at LiquidityParticipation of the average volume
+	SharesOutstanding  int64              `bson:"sharesOutstanding,omitempty" json:"sharesOutstanding,omitempty"`
+	MarketCap          float64            `bson:"marketCap,omitempty" json:"marketCap,omitempty"` // Close × shares outstanding; 0 when the share count is unknown
+	Range52W           *Range52W          `bson:"range52w,omitempty" json:"range52w,omitempty"`
+	MovingAverages     *MovingAverages    `bson:"ma,omitempty" json:"ma,omitempty"` // As of Date
+	ComputedAt         primitive.DateTime `bson:"computedAt" json:"computedAt"`
+}
+
+// ComputeLiquidityMetrics derives the liquidity metrics of code from its
+// newest candles (ordered by date), using the last LiquidityWindow sessions
+// plus the one before them for returns. It reports false without candles.
+func ComputeLiquidityMetrics(code string, candles []CandleData, computedAt primitive.DateTime) (LiquidityMetrics, bool) {
+	if len(candles) == 0 {
+		return LiquidityMetrics{}, false
+	}
+	if len(candles) > LiquidityWindow+1 {
+		candles = candles[len(candles)-LiquidityWindow-1:]
+	}
+	window := candles
+	if len(window) > LiquidityWindow {
+		window = window[1:]
+	}
+	last := candles[len(candles)-1]
+
+	metrics := LiquidityMetrics{
+		Code:       code,
+		Date:       last.D,
+		Sessions:   len(window),
+		Close:      last.C,
+		Volume:     last.V,
+		ComputedAt: computedAt,
+	}
+
+	var volume, value float64
+	for _, candle := range window {
+		volume += float64(candle.V)
+		value += candle.C * float64(candle.V)
+		if candle.V == 0 {
+			metrics.ZeroVolumeSessions++
+		}
+	}
+	metrics.AvgVolume = round2(volume / float64(len(window)))
+	metrics.AvgValue = round2(value / float64(len(window)))
+	metrics.MaxDailyPosition = int64(volume / float64(len(window)) * LiquidityParticipation)
+
+	if len(candles) > 1 {
+		var previous float64
+		for _, candle := range candles[:len(candles)-1] {
+			previous += float64(candle.V)
+		}
+		if previous > 0 {
+			metrics.RelativeVolume = round2(float64(last.V) / (previous / float64(len(candles)-1)))
+		}
+	}
+
+	returns := make([]float64, 0, len(candles)-1)
+	var amihud float64
+	amihudSessions := 0
+	for i := 1; i < len(candles); i++ {
+		if candles[i-1].C == 0 {
+			continue
+		}
+		r := candles[i].C/candles[i-1].C - 1
+		returns = append(returns, r)
+		if traded := candles[i].C * float64(candles[i].V); traded > 0 {
+			amihud += math.Abs(r) / traded * 1e9
+			amihudSessions++
+		}
+	}
+	metrics.Volatility = round2(stdDev(returns) * 100)
+	if amihudSessions > 0 {
+		metrics.Amihud = math.Round(amihud/float64(amihudSessions)*1e6) / 1e6
+	}
+	return metrics, true
+}
+
+// MarketCap returns the market capitalization of shares at price, in the
+// units of the price, or 0 when either is unknown
+func MarketCap(price float64, shares int64) float64 {
+	if price <= 0 || shares <= 0 {
+		return 0
+	}
+	return round2(price * float64(shares))
+}
+
+// DaysToCover returns how many sessions trading position shares takes at
+// LiquidityParticipation of avgVolume, or -1 when the symbol does not trade
+func DaysToCover(position int64, avgVolume float64) float64 {
+	if position <= 0 {
+		return 0
+	}
+	if avgVolume <= 0 {
+		return -1
+	}
+	return round2(float64(position) / (avgVolume * LiquidityParticipation))
+}
+
+// stdDev returns the sample standard deviation of values
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	var mean float64
+	for _, v := range values {
+		mean += v
+	}
+	mean /= float64(len(values))
+	var sum float64
+	for _, v := range values {
+		sum += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// round2 rounds v to 2 decimals
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestComputeLiquidityMetrics(t *testing.T) {
+	closes := make([]float64, LiquidityWindow+5)
+	for i := range closes {
+		closes[i] = 10
+	}
+	closes[len(closes)-1] = 11
+	candles := dailyCandles("2024-03-29", closes)
+	candles[len(candles)-1].V = 3000
+	candles[len(candles)-2].V = 0
+
+	metrics, ok := ComputeLiquidityMetrics("ABC", candles, primitive.DateTime(0))
+	if !ok {
+		t.Fatal("ComputeLiquidityMetrics() reported no metrics")
+	}
+	if metrics.Date != "2024-03-29" || metrics.Close != 11 || metrics.Volume != 3000 {
+		t.Errorf("latest = %s %v %d; want 2024-03-29 11 3000", metrics.Date, metrics.Close, metrics.Volume)
+	}
+	if metrics.Sessions != LiquidityWindow {
+		t.Errorf("Sessions = %d; want %d", metrics.Sessions, LiquidityWindow)
+	}
+	// 18 sessions of 1000, one of 0 and one of 3000
+	if metrics.AvgVolume != 1050 {
+		t.Errorf("AvgVolume = %v; want 1050", metrics.AvgVolume)
+	}
+	if metrics.AvgValue != 10650 {
+		t.Errorf("AvgValue = %v; want 10650", metrics.AvgValue)
+	}
+	if metrics.ZeroVolumeSessions != 1 {
+		t.Errorf("ZeroVolumeSessions = %d; want 1", metrics.ZeroVolumeSessions)
+	}
+	if metrics.MaxDailyPosition != 105 {
+		t.Errorf("MaxDailyPosition = %d; want 105", metrics.MaxDailyPosition)
+	}
+	// 3000 against the 20 sessions before it (19 × 1000 + 0)
+	if metrics.RelativeVolume != 3.16 {
+		t.Errorf("RelativeVolume = %v; want 3.16", metrics.RelativeVolume)
+	}
+	if metrics.Volatility <= 0 {
+		t.Errorf("Volatility = %v; want > 0", metrics.Volatility)
+	}
+	if metrics.Amihud <= 0 {
+		t.Errorf("Amihud = %v; want > 0", metrics.Amihud)
+	}
+}
+
+func TestComputeLiquidityMetricsShortHistory(t *testing.T) {
+	if _, ok := ComputeLiquidityMetrics("ABC", nil, primitive.DateTime(0)); ok {
+		t.Error("ComputeLiquidityMetrics(nil) reported metrics")
+	}
+
+	metrics, ok := ComputeLiquidityMetrics("ABC", dailyCandles("2024-03-29", []float64{10}), primitive.DateTime(0))
+	if !ok {
+		t.Fatal("ComputeLiquidityMetrics() reported no metrics for one candle")
+	}
+	if metrics.Sessions != 1 || metrics.AvgVolume != 1000 || metrics.RelativeVolume != 0 || metrics.Volatility != 0 {
+		t.Errorf("one candle = %+v; want 1 session, average 1000, no relative volume or volatility", metrics)
+	}
+}
+
+func TestDaysToCover(t *testing.T) {
+	tests := []struct {
+		position  int64
+		avgVolume float64
+		want      float64
+	}{
+		{10000, 100000, 1},
+		{25000, 100000, 2.5},
+		{0, 100000, 0},
+		{1000, 0, -1},
+	}
+	for _, tt := range tests {
+		if got := DaysToCover(tt.position, tt.avgVolume); got != tt.want {
+			t.Errorf("DaysToCover(%d, %v) = %v; want %v", tt.position, tt.avgVolume, got, tt.want)
+		}
+	}
+}
+
+func TestMarketCap(t *testing.T) {
+	if got := MarketCap(25.5, 6_000_000_000); got != 153_000_000_000 {
+		t.Errorf("MarketCap(25.5, 6e9) = %v; want 1.53e11", got)
+	}
+	if got := MarketCap(25.5, 0); got != 0 {
+		t.Errorf("MarketCap without shares = %v; want 0", got)
+	}
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// LoginHistory represents the login_history table in Supabase
+// One row is appended for every successful admin login
+type LoginHistory struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	AdminUserID uuid.UUID `gorm:"type:uuid;not null;column:admin_user_id" json:"admin_user_id"`
+	IP          *string   `gorm:"type:text;column:ip" json:"ip,omitempty"`
+	UserAgent   *string   `gorm:"type:text;column:user_agent" json:"user_agent,omitempty"`
+	Channel     string    `gorm:"type:text;not null;column:channel" json:"channel"` // dashboard or token
+	CreatedAt   time.Time `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (LoginHistory) TableName() string {
+	return "public.login_history"
+}
@@ -0,0 +1,102 @@
+package models
+
+import "sort"
+
+// Market mover rankings, the ?by= values of /api/market/movers
+const (
+	MoversByChange = "change" // Top gainers and losers by change percent
+	MoversByVolume = "volume" // Most traded by volume
+)
+
+// MarketMover is a symbol's session on one trading day against its
+// previous close
+type MarketMover struct {
+	Code          string  `json:"code"`
+	Exchange      string  `json:"exchange"`
+	Close         float64 `json:"close"`
+	PreviousClose float64 `json:"previous_close"`
+	Change        float64 `json:"change"`
+	ChangePercent float64 `json:"change_percent"`
+	Volume        int64   `json:"volume"`
+	Value         float64 `json:"value"` // Close × volume
+}
+
+// MarketMovers are the top symbols of one trading day
+type MarketMovers struct {
+	Date       string        `json:"date"`
+	By         string        `json:"by"`
+	Exchanges  []string      `json:"exchanges,omitempty"` // Empty for every exchange
+	Symbols    int           `json:"symbols"`             // Symbols that traded on the date
+	Gainers    []MarketMover `json:"gainers,omitempty"`
+	Losers     []MarketMover `json:"losers,omitempty"`
+	MostActive []MarketMover `json:"most_active,omitempty"`
+}
+
+// ComputeMarketMovers ranks the symbols with a candle on date, given their
+// recent candles (ordered by date) and exchange by code. By MoversByChange it
+// returns the limit largest rises and falls against the previous close, by
+// MoversByVolume the limit largest volumes. Symbols without a previous close
+// are only ranked by volume.
+func ComputeMarketMovers(date, by string, exchanges map[string]string, candles map[string][]CandleData, limit int) MarketMovers {
+	result := MarketMovers{Date: date, By: by}
+	movers := make([]MarketMover, 0, len(candles))
+	for code, history := range candles {
+		if len(history) == 0 || history[len(history)-1].D != date {
+			continue
+		}
+		last := history[len(history)-1]
+		mover := MarketMover{
+			Code:     code,
+			Exchange: exchanges[code],
+			Close:    last.C,
+			Volume:   last.V,
+			Value:    round2(last.C * float64(last.V)),
+		}
+		if len(history) > 1 && history[len(history)-2].C > 0 {
+			previous := history[len(history)-2].C
+			mover.PreviousClose = previous
+			mover.Change = round2(last.C - previous)
+			mover.ChangePercent = round2((last.C - previous) / previous * 100)
+		}
+		movers = append(movers, mover)
+	}
+	result.Symbols = len(movers)
+
+	if by == MoversByVolume {
+		sort.Slice(movers, func(i, j int) bool {
+			if movers[i].Volume != movers[j].Volume {
+				return movers[i].Volume > movers[j].Volume
+			}
+			return movers[i].Code < movers[j].Code
+		})
+		result.MostActive = topMovers(movers, limit, func(MarketMover) bool { return true })
+		return result
+	}
+
+	byChange := func(sign float64) func(i, j int) bool {
+		return func(i, j int) bool {
+			if movers[i].ChangePercent != movers[j].ChangePercent {
+				return sign*movers[i].ChangePercent > sign*movers[j].ChangePercent
+			}
+			return movers[i].Code < movers[j].Code
+		}
+	}
+	sort.Slice(movers, byChange(1))
+	result.Gainers = topMovers(movers, limit, func(m MarketMover) bool { return m.ChangePercent > 0 })
+	sort.Slice(movers, byChange(-1))
+	result.Losers = topMovers(movers, limit, func(m MarketMover) bool { return m.ChangePercent < 0 })
+	return result
+}
+
+// topMovers returns up to limit of the leading movers that keep, stopping at
+// the first that does not
+func topMovers(movers []MarketMover, limit int, keep func(MarketMover) bool) []MarketMover {
+	top := make([]MarketMover, 0, limit)
+	for _, mover := range movers {
+		if len(top) == limit || !keep(mover) {
+			break
+		}
+		top = append(top, mover)
+	}
+	return top
+}
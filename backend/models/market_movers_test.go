@@ -0,0 +1,37 @@
+package models
+
+import "testing"
+
+func TestComputeMarketMovers(t *testing.T) {
+	exchanges := map[string]string{"HPG": "HOSE", "VCB": "HOSE", "SHS": "HNX", "FPT": "HOSE", "NEW": "UPCOM"}
+	candles := map[string][]CandleData{
+		"HPG": {{D: "2026-03-02", C: 20}, {D: "2026-03-03", C: 21, V: 500}},  // +5%
+		"VCB": {{D: "2026-03-02", C: 100}, {D: "2026-03-03", C: 97, V: 100}}, // -3%
+		"SHS": {{D: "2026-03-02", C: 10}, {D: "2026-03-03", C: 9.3, V: 900}}, // -7%
+		"FPT": {{D: "2026-03-02", C: 50}, {D: "2026-03-03", C: 50, V: 300}},  // Unchanged
+		"NEW": {{D: "2026-03-03", C: 12, V: 50}},                             // First session
+		"OLD": {{D: "2026-03-02", C: 8, V: 1000}},                            // Did not trade on the date
+	}
+
+	movers := ComputeMarketMovers("2026-03-03", MoversByChange, exchanges, candles, 10)
+	if movers.Symbols != 5 {
+		t.Errorf("Symbols = %d; want the 5 that traded", movers.Symbols)
+	}
+	if len(movers.Gainers) != 1 || movers.Gainers[0].Code != "HPG" || movers.Gainers[0].ChangePercent != 5 || movers.Gainers[0].Value != 10500 {
+		t.Errorf("Gainers = %+v; want HPG at +5%%", movers.Gainers)
+	}
+	if len(movers.Losers) != 2 || movers.Losers[0].Code != "SHS" || movers.Losers[0].Exchange != "HNX" || movers.Losers[1].ChangePercent != -3 {
+		t.Errorf("Losers = %+v; want SHS then VCB", movers.Losers)
+	}
+	if movers.MostActive != nil {
+		t.Errorf("MostActive = %+v; want none when ranking by change", movers.MostActive)
+	}
+
+	movers = ComputeMarketMovers("2026-03-03", MoversByVolume, exchanges, candles, 2)
+	if len(movers.MostActive) != 2 || movers.MostActive[0].Code != "SHS" || movers.MostActive[1].Code != "HPG" {
+		t.Errorf("MostActive = %+v; want SHS and HPG", movers.MostActive)
+	}
+	if movers.Gainers != nil || movers.Losers != nil {
+		t.Error("gainers and losers should be left out when ranking by volume")
+	}
+}
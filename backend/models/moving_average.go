@@ -0,0 +1,141 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// MovingAveragePeriods are the sessions of the simple moving averages of the
+// close precomputed after each crawl
+var MovingAveragePeriods = []int{10, 20, 50, 200}
+
+const (
+	// MovingAverageLength is how many sessions of moving averages a symbol's
+	// series keeps, about a year of chart
+	MovingAverageLength = 250
+	// MovingAverageSessions is how many of a symbol's newest candles give every
+	// point of its series all of its averages
+	MovingAverageSessions = MovingAverageLength + 200 - 1
+)
+
+// MovingAverages are the simple moving averages of the close as of a
+// session, rounded to 2 decimals. An average is 0 (and left out) until the
+// symbol has traded for its period.
+type MovingAverages struct {
+	MA10  float64 `bson:"ma10,omitempty" json:"ma10,omitempty"`
+	MA20  float64 `bson:"ma20,omitempty" json:"ma20,omitempty"`
+	MA50  float64 `bson:"ma50,omitempty" json:"ma50,omitempty"`
+	MA200 float64 `bson:"ma200,omitempty" json:"ma200,omitempty"`
+}
+
+// Get returns the average over period sessions, false for a period other
+// than MovingAveragePeriods or one without enough history
+func (m MovingAverages) Get(period int) (float64, bool) {
+	var value float64
+	switch period {
+	case 10:
+		value = m.MA10
+	case 20:
+		value = m.MA20
+	case 50:
+		value = m.MA50
+	case 200:
+		value = m.MA200
+	}
+	return value, value != 0
+}
+
+// set stores the average over period sessions
+func (m *MovingAverages) set(period int, value float64) {
+	switch period {
+	case 10:
+		m.MA10 = value
+	case 20:
+		m.MA20 = value
+	case 50:
+		m.MA50 = value
+	case 200:
+		m.MA200 = value
+	}
+}
+
+// MovingAveragePoint is the moving averages of one session
+type MovingAveragePoint struct {
+	D              string `bson:"d" json:"d"` // Date (YYYY-MM-DD)
+	MovingAverages `bson:",inline"`
+}
+
+// MovingAverageSeries is the chart series of a symbol's moving averages,
+// one document per symbol in the moving_averages collection, rebuilt after
+// each crawl that stores new candles for it
+type MovingAverageSeries struct {
+	Code      string               `bson:"_id" json:"code"`
+	From      string               `bson:"from" json:"from"`     // Date of the first point
+	To        string               `bson:"to" json:"to"`         // Date of the last point
+	Points    []MovingAveragePoint `bson:"points" json:"points"` // Oldest first, at most MovingAverageLength
+	UpdatedAt primitive.DateTime   `bson:"updatedAt" json:"updatedAt"`
+}
+
+// ComputeMovingAverages returns the moving averages of every session of
+// candles (ordered by date)
+func ComputeMovingAverages(candles []CandleData) []MovingAveragePoint {
+	points := make([]MovingAveragePoint, len(candles))
+	sums := make([]float64, len(MovingAveragePeriods))
+	for i, candle := range candles {
+		points[i].D = candle.D
+		for p, period := range MovingAveragePeriods {
+			sums[p] += candle.C
+			if i >= period {
+				sums[p] -= candles[i-period].C
+			}
+			if i >= period-1 {
+				points[i].set(period, round2(sums[p]/float64(period)))
+			}
+		}
+	}
+	return points
+}
+
+// LatestMovingAverages returns the moving averages as of the last of
+// candles (ordered by date), false without candles
+func LatestMovingAverages(candles []CandleData) (MovingAverages, bool) {
+	var latest MovingAverages
+	if len(candles) == 0 {
+		return latest, false
+	}
+	for _, period := range MovingAveragePeriods {
+		if len(candles) >= period {
+			latest.set(period, round2(closeSMA(candles, period, 0)))
+		}
+	}
+	return latest, true
+}
+
+// NewMovingAverageSeries keeps the last MovingAverageLength points of the
+// moving averages of candles ordered by date, or returns nil when there are
+// none
+func NewMovingAverageSeries(code string, candles []CandleData, updatedAt primitive.DateTime) *MovingAverageSeries {
+	if len(candles) == 0 {
+		return nil
+	}
+	points := ComputeMovingAverages(candles)
+	if len(points) > MovingAverageLength {
+		points = points[len(points)-MovingAverageLength:]
+	}
+	return &MovingAverageSeries{
+		Code:      code,
+		From:      points[0].D,
+		To:        points[len(points)-1].D,
+		Points:    points,
+		UpdatedAt: updatedAt,
+	}
+}
+
+// Between returns the points dated from to to, both included; an empty
+// bound is open
+func (s *MovingAverageSeries) Between(from, to string) []MovingAveragePoint {
+	points := make([]MovingAveragePoint, 0, len(s.Points))
+	for _, point := range s.Points {
+		if (from == "" || point.D >= from) && (to == "" || point.D <= to) {
+			points = append(points, point)
+		}
+	}
+	return points
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestComputeMovingAverages(t *testing.T) {
+	candles := make([]CandleData, 205)
+	for i := range candles {
+		candles[i] = CandleData{D: fmt.Sprintf("d%03d", i), C: float64(i + 1)}
+	}
+
+	points := ComputeMovingAverages(candles)
+	if len(points) != len(candles) {
+		t.Fatalf("ComputeMovingAverages() = %d points; want one per candle", len(points))
+	}
+	if p := points[8]; p.MA10 != 0 || p.MA20 != 0 {
+		t.Errorf("point 8 = %+v; want no averages before 10 sessions", p)
+	}
+	if p := points[9]; p.MA10 != 5.5 || p.MA20 != 0 {
+		t.Errorf("point 9 = %+v; want MA10 5.5 only", p)
+	}
+	last := points[len(points)-1]
+	want := MovingAverages{MA10: 200.5, MA20: 195.5, MA50: 180.5, MA200: 105.5}
+	if last.D != "d204" || last.MovingAverages != want {
+		t.Errorf("last point = %+v; want %+v", last, want)
+	}
+
+	latest, ok := LatestMovingAverages(candles)
+	if !ok || latest != want {
+		t.Errorf("LatestMovingAverages() = %+v, %v; want the last point's averages", latest, ok)
+	}
+	if ma, ok := latest.Get(50); !ok || ma != 180.5 {
+		t.Errorf("Get(50) = %v, %v; want 180.5", ma, ok)
+	}
+	if _, ok := (MovingAverages{MA10: 1}).Get(200); ok {
+		t.Error("Get(200) without MA200 succeeded")
+	}
+}
+
+func TestNewMovingAverageSeries(t *testing.T) {
+	candles := make([]CandleData, MovingAverageLength+10)
+	for i := range candles {
+		candles[i] = CandleData{D: fmt.Sprintf("d%03d", i), C: 10}
+	}
+
+	series := NewMovingAverageSeries("HPG", candles, 0)
+	if len(series.Points) != MovingAverageLength || series.From != "d010" || series.To != "d259" {
+		t.Fatalf("NewMovingAverageSeries() = %d points %s..%s; want the last %d", len(series.Points), series.From, series.To, MovingAverageLength)
+	}
+	if between := series.Between("d100", "d102"); len(between) != 3 || between[0].MA10 != 10 {
+		t.Errorf("Between() = %+v; want 3 points", between)
+	}
+	if NewMovingAverageSeries("HPG", nil, 0) != nil {
+		t.Error("NewMovingAverageSeries(no candles) != nil")
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Payment statuses reported by payment webhooks
+const (
+	PaymentStatusPending   = "pending"
+	PaymentStatusSucceeded = "succeeded" // Upgrades the member to premium
+	PaymentStatusFailed    = "failed"
+)
+
+// ValidPaymentStatus reports whether status is a known payment status
+func ValidPaymentStatus(status string) bool {
+	switch status {
+	case PaymentStatusPending, PaymentStatusSucceeded, PaymentStatusFailed:
+		return true
+	}
+	return false
+}
+
+// Payment represents the payments table in Supabase
+// One row per provider transaction; a succeeded payment extends the
+// member's premium membership by Months
+type Payment struct {
+	ID                  uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	Provider            string     `gorm:"type:text;not null;column:provider" json:"provider"`             // stripe, vnpay, momo, ...
+	TransactionID       string     `gorm:"type:text;not null;column:transaction_id" json:"transaction_id"` // Unique per provider
+	ProfileID           uuid.UUID  `gorm:"type:uuid;not null;column:profile_id" json:"profile_id"`
+	Amount              int64      `gorm:"type:bigint;not null;column:amount" json:"amount"` // Smallest currency unit (đồng for VND)
+	Currency            string     `gorm:"type:text;not null;column:currency" json:"currency"`
+	Status              string     `gorm:"type:text;not null;column:status" json:"status"`
+	Months              int        `gorm:"type:integer;not null;column:months" json:"months"`
+	MembershipExpiresAt *time.Time `gorm:"type:timestamptz;column:membership_expires_at" json:"membership_expires_at,omitempty"` // Expiry after the upgrade was applied
+	Payload             string     `gorm:"type:text;not null;column:payload" json:"-"`                                           // Raw webhook body, kept for reconciliation
+	CreatedAt           time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt           time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Payment) TableName() string {
+	return "public.payments"
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PersonalTokenScopes lists the scopes a member can grant to a personal
+// access token. Personal tokens are read-only; write scopes such as
+// ScopeTriggerCrawl are reserved for admin-issued API keys.
+var PersonalTokenScopes = []string{
+	ScopeReadPrices,
+}
+
+// PersonalAccessToken represents the personal_access_tokens table in Supabase
+// Members create these for their own scripts (Excel, Python); only a SHA-256
+// hash of the token is stored
+type PersonalAccessToken struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	ProfileID  uuid.UUID  `gorm:"type:uuid;not null;column:profile_id" json:"profile_id"`
+	Name       string     `gorm:"type:text;not null;column:name" json:"name"`
+	Prefix     string     `gorm:"type:text;not null;column:prefix" json:"prefix"` // First characters of the token, for identification
+	TokenHash  string     `gorm:"type:text;not null;unique;column:token_hash" json:"-"`
+	Scopes     string     `gorm:"type:text;not null;column:scopes" json:"scopes"` // Comma-separated scopes
+	CreatedAt  time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	ExpiresAt  *time.Time `gorm:"type:timestamptz;column:expires_at" json:"expires_at,omitempty"` // Nil: never expires
+	LastUsedAt *time.Time `gorm:"type:timestamptz;column:last_used_at" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `gorm:"type:timestamptz;column:revoked_at" json:"revoked_at,omitempty"`
+}
+
+// TableName specifies the table name for GORM
+func (PersonalAccessToken) TableName() string {
+	return "public.personal_access_tokens"
+}
+
+// ScopeList returns the token's scopes as a slice
+func (t PersonalAccessToken) ScopeList() []string {
+	return SplitList(t.Scopes)
+}
+
+// Active reports whether the token is neither revoked nor expired at now
+func (t PersonalAccessToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || now.Before(*t.ExpiresAt))
+}
+
+// NormalizePersonalTokenScopes validates personal token scopes, defaulting
+// to read-only market data access when none are requested
+func NormalizePersonalTokenScopes(scopes []string) (string, error) {
+	for _, scope := range scopes {
+		if strings.TrimSpace(scope) != "" {
+			return normalizeScopes(PersonalTokenScopes, scopes)
+		}
+	}
+	return ScopeReadPrices, nil
+}
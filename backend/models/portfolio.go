@@ -0,0 +1,176 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Portfolio trade sides
+const (
+	TradeSideBuy  = "buy"
+	TradeSideSell = "sell"
+)
+
+// PortfolioTrade represents the portfolio_trades table in Supabase
+// One buy or sell a member recorded; holdings are derived from the trades
+type PortfolioTrade struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	ProfileID uuid.UUID `gorm:"type:uuid;not null;column:profile_id" json:"profile_id"`
+	Code      string    `gorm:"type:text;not null;column:code" json:"code"`
+	Side      string    `gorm:"type:text;not null;default:'buy';column:side" json:"side"`
+	Quantity  int64     `gorm:"type:bigint;not null;column:quantity" json:"quantity"`
+	Price     float64   `gorm:"type:double precision;not null;column:price" json:"price"` // Per share, in the candles' unit (thousand VND)
+	Fee       float64   `gorm:"type:double precision;not null;default:0;column:fee" json:"fee"`
+	TradeDate string    `gorm:"type:text;not null;column:trade_date" json:"trade_date"` // YYYY-MM-DD
+	Note      string    `gorm:"type:text;column:note" json:"note,omitempty"`
+	CreatedAt time.Time `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (PortfolioTrade) TableName() string {
+	return "public.portfolio_trades"
+}
+
+// Validate checks the code, side, quantity, price, fee and date of a trade
+func (t PortfolioTrade) Validate() error {
+	if strings.TrimSpace(t.Code) == "" {
+		return fmt.Errorf("code is required")
+	}
+	if t.Side != TradeSideBuy && t.Side != TradeSideSell {
+		return fmt.Errorf("side must be %q or %q", TradeSideBuy, TradeSideSell)
+	}
+	if t.Quantity <= 0 {
+		return fmt.Errorf("quantity must be positive")
+	}
+	if t.Price <= 0 {
+		return fmt.Errorf("price must be positive")
+	}
+	if t.Fee < 0 {
+		return fmt.Errorf("fee must not be negative")
+	}
+	if _, err := time.Parse("2006-01-02", t.TradeDate); err != nil {
+		return fmt.Errorf("trade_date must be YYYY-MM-DD")
+	}
+	return nil
+}
+
+// Holding is an open position built from a member's trades, marked to market
+// with the latest stored candle
+type Holding struct {
+	Code             string   `json:"code"`
+	Quantity         int64    `json:"quantity"`
+	AverageCost      float64  `json:"average_cost"` // Per share, fees included
+	CostBasis        float64  `json:"cost_basis"`
+	RealizedPnL      float64  `json:"realized_pnl"` // From sells, against the average cost
+	Price            *float64 `json:"price,omitempty"`
+	PriceDate        string   `json:"price_date,omitempty"`
+	MarketValue      *float64 `json:"market_value,omitempty"`
+	UnrealizedPnL    *float64 `json:"unrealized_pnl,omitempty"`
+	UnrealizedPnLPct *float64 `json:"unrealized_pnl_percent,omitempty"`
+	Allocation       *float64 `json:"allocation_percent,omitempty"` // Share of the priced market value
+}
+
+// PortfolioValuation is a member's holdings with their totals
+type PortfolioValuation struct {
+	Holdings         []Holding `json:"holdings"`
+	CostBasis        float64   `json:"cost_basis"`
+	MarketValue      float64   `json:"market_value"`   // Holdings with a price only
+	UnrealizedPnL    float64   `json:"unrealized_pnl"` // Holdings with a price only
+	UnrealizedPnLPct *float64  `json:"unrealized_pnl_percent,omitempty"`
+	RealizedPnL      float64   `json:"realized_pnl"`
+	Unpriced         []string  `json:"unpriced,omitempty"` // Codes without a stored candle
+}
+
+// BuildHoldings replays trades ordered by date and returns the holdings per
+// code, sorted by code, with the average cost method. Codes that were sold
+// out are kept when they have realized P&L. It fails when a sell exceeds the
+// quantity held at that point.
+func BuildHoldings(trades []PortfolioTrade) ([]Holding, error) {
+	ordered := make([]PortfolioTrade, len(trades))
+	copy(ordered, trades)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].TradeDate < ordered[j].TradeDate })
+
+	byCode := make(map[string]*Holding)
+	for _, trade := range ordered {
+		holding, ok := byCode[trade.Code]
+		if !ok {
+			holding = &Holding{Code: trade.Code}
+			byCode[trade.Code] = holding
+		}
+		switch trade.Side {
+		case TradeSideBuy:
+			holding.CostBasis += float64(trade.Quantity)*trade.Price + trade.Fee
+			holding.Quantity += trade.Quantity
+		case TradeSideSell:
+			if trade.Quantity > holding.Quantity {
+				return nil, fmt.Errorf("selling %d %s on %s exceeds the %d held", trade.Quantity, trade.Code, trade.TradeDate, holding.Quantity)
+			}
+			cost := holding.CostBasis * float64(trade.Quantity) / float64(holding.Quantity)
+			holding.RealizedPnL += float64(trade.Quantity)*trade.Price - trade.Fee - cost
+			holding.CostBasis -= cost
+			holding.Quantity -= trade.Quantity
+		}
+	}
+
+	holdings := make([]Holding, 0, len(byCode))
+	for _, holding := range byCode {
+		if holding.Quantity == 0 {
+			holding.CostBasis = 0
+			if holding.RealizedPnL == 0 {
+				continue
+			}
+		} else {
+			holding.AverageCost = holding.CostBasis / float64(holding.Quantity)
+		}
+		holdings = append(holdings, *holding)
+	}
+	sort.Slice(holdings, func(i, j int) bool { return holdings[i].Code < holdings[j].Code })
+	return holdings, nil
+}
+
+// ValuePortfolio marks holdings to market with the latest candle of each code
+// and computes P&L, allocation and totals
+func ValuePortfolio(holdings []Holding, latest map[string]CandleData) PortfolioValuation {
+	valuation := PortfolioValuation{Holdings: holdings}
+	for i := range holdings {
+		holding := &holdings[i]
+		valuation.RealizedPnL += holding.RealizedPnL
+		if holding.Quantity == 0 {
+			continue
+		}
+		valuation.CostBasis += holding.CostBasis
+		candle, ok := latest[holding.Code]
+		if !ok {
+			valuation.Unpriced = append(valuation.Unpriced, holding.Code)
+			continue
+		}
+		value := float64(holding.Quantity) * candle.C
+		pnl := value - holding.CostBasis
+		holding.Price, holding.PriceDate = &candle.C, candle.D
+		holding.MarketValue, holding.UnrealizedPnL = &value, &pnl
+		if holding.CostBasis > 0 {
+			percent := pnl / holding.CostBasis * 100
+			holding.UnrealizedPnLPct = &percent
+		}
+		valuation.MarketValue += value
+		valuation.UnrealizedPnL += pnl
+	}
+
+	if valuation.MarketValue > 0 {
+		for i := range holdings {
+			if holdings[i].MarketValue != nil {
+				allocation := *holdings[i].MarketValue / valuation.MarketValue * 100
+				holdings[i].Allocation = &allocation
+			}
+		}
+	}
+	if pricedCost := valuation.MarketValue - valuation.UnrealizedPnL; pricedCost > 0 {
+		percent := valuation.UnrealizedPnL / pricedCost * 100
+		valuation.UnrealizedPnLPct = &percent
+	}
+	return valuation
+}
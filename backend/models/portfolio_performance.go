@@ -0,0 +1,254 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultBenchmark is the market index portfolios are compared with
+const DefaultBenchmark = "VNINDEX"
+
+// Portfolio performance periods
+const (
+	PerformancePeriod1M  = "1m"
+	PerformancePeriod3M  = "3m"
+	PerformancePeriod6M  = "6m"
+	PerformancePeriodYTD = "ytd"
+	PerformancePeriod1Y  = "1y"
+	PerformancePeriod3Y  = "3y"
+	PerformancePeriodAll = "all"
+)
+
+// PerformanceSince returns the base date of a performance period ending on
+// to: returns are measured from the close of that date. The "all" period
+// starts the day before the first trade.
+func PerformanceSince(period string, to time.Time, firstTrade string) (string, error) {
+	var since time.Time
+	switch period {
+	case PerformancePeriod1M:
+		since = to.AddDate(0, -1, 0)
+	case PerformancePeriod3M:
+		since = to.AddDate(0, -3, 0)
+	case PerformancePeriod6M:
+		since = to.AddDate(0, -6, 0)
+	case PerformancePeriodYTD:
+		since = time.Date(to.Year()-1, time.December, 31, 0, 0, 0, 0, to.Location())
+	case PerformancePeriod1Y:
+		since = to.AddDate(-1, 0, 0)
+	case PerformancePeriod3Y:
+		since = to.AddDate(-3, 0, 0)
+	case PerformancePeriodAll:
+		first, err := time.Parse("2006-01-02", firstTrade)
+		if err != nil {
+			return "", fmt.Errorf("invalid first trade date %q", firstTrade)
+		}
+		since = first.AddDate(0, 0, -1)
+	default:
+		return "", fmt.Errorf("period must be one of 1m, 3m, 6m, ytd, 1y, 3y, all")
+	}
+	return since.Format("2006-01-02"), nil
+}
+
+// PerformancePoint is a portfolio's value and cumulative returns at the
+// close of one date
+type PerformancePoint struct {
+	Date            string   `json:"date"`
+	MarketValue     float64  `json:"market_value"`
+	NetFlow         float64  `json:"net_flow,omitempty"`       // Buys minus sells of the date, fees included
+	Return          float64  `json:"return_percent"`           // Time-weighted, since the period's base date
+	BenchmarkReturn *float64 `json:"benchmark_return_percent"` // Since the period's base date; null before the benchmark's first candle
+}
+
+// Drawdown is the largest fall of a return series from a previous peak
+type Drawdown struct {
+	MaxPercent     float64 `json:"max_percent"` // Zero or negative
+	PeakDate       string  `json:"peak_date,omitempty"`
+	TroughDate     string  `json:"trough_date,omitempty"`
+	RecoveredDate  string  `json:"recovered_date,omitempty"` // First date back at the peak after the trough
+	CurrentPercent float64 `json:"current_percent"`          // Below the highest peak, at the last date
+}
+
+// PortfolioPerformance is a member's portfolio return over a period
+// compared with a market index
+type PortfolioPerformance struct {
+	Period             string             `json:"period"`
+	Since              string             `json:"since"` // Base date: returns are measured from its close
+	To                 string             `json:"to,omitempty"`
+	Benchmark          string             `json:"benchmark"`
+	TimeWeightedReturn float64            `json:"time_weighted_return_percent"`
+	BenchmarkReturn    *float64           `json:"benchmark_return_percent"` // Null without benchmark candles
+	ExcessReturn       *float64           `json:"excess_return_percent"`    // Portfolio minus benchmark
+	Drawdown           Drawdown           `json:"drawdown"`
+	BenchmarkDrawdown  *Drawdown          `json:"benchmark_drawdown"`
+	Series             []PerformancePoint `json:"series"`
+}
+
+// ComputePerformance values the holdings built from trades at the close of
+// every date after since up to to and chains their daily returns into a
+// time-weighted return, so buys and sells do not count as gains or losses.
+// Holdings are marked with the latest close in closes (by code, any order)
+// or, until a code has one, its latest trade price. Within a day, buys are
+// invested at the start and sells are withdrawn at the close:
+//
+//	return = (value + sells) / (previous value + buys) - 1
+//
+// The benchmark's return is measured from its last close on or before
+// since. Trades must be valid; sells beyond the quantity held are ignored.
+func ComputePerformance(trades []PortfolioTrade, closes map[string][]CandleData, benchmark []CandleData, since, to string) PortfolioPerformance {
+	performance := PortfolioPerformance{Since: since, Series: []PerformancePoint{}}
+
+	ordered := make([]PortfolioTrade, len(trades))
+	copy(ordered, trades)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].TradeDate < ordered[j].TradeDate })
+
+	closeByDate := make(map[string]map[string]float64)
+	dateSet := make(map[string]bool)
+	for code, candles := range closes {
+		for _, candle := range candles {
+			if candle.D > to {
+				continue
+			}
+			if closeByDate[candle.D] == nil {
+				closeByDate[candle.D] = make(map[string]float64)
+			}
+			closeByDate[candle.D][code] = candle.C
+			dateSet[candle.D] = true
+		}
+	}
+	benchmarkCandles := make([]CandleData, 0, len(benchmark))
+	for _, candle := range benchmark {
+		if candle.D <= to {
+			benchmarkCandles = append(benchmarkCandles, candle)
+			dateSet[candle.D] = true
+		}
+	}
+	sort.Slice(benchmarkCandles, func(i, j int) bool { return benchmarkCandles[i].D < benchmarkCandles[j].D })
+	for _, trade := range ordered {
+		if trade.TradeDate <= to {
+			dateSet[trade.TradeDate] = true
+		}
+	}
+	dates := make([]string, 0, len(dateSet))
+	for date := range dateSet {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+
+	// The benchmark's base is its last close on or before since, or its
+	// first close when it starts later
+	var benchmarkBase float64
+	for _, candle := range benchmarkCandles {
+		if candle.D > since && benchmarkBase != 0 {
+			break
+		}
+		benchmarkBase = candle.C
+	}
+
+	quantities := make(map[string]int64)
+	marks := make(map[string]float64)
+	wealth, benchmarkLevel := 1.0, 0.0
+	var value float64
+	var portfolioLevels, benchmarkLevels []float64
+	next, nextBenchmark := 0, 0
+	for _, date := range dates {
+		var buys, sells float64
+		for ; next < len(ordered) && ordered[next].TradeDate == date; next++ {
+			trade := ordered[next]
+			switch trade.Side {
+			case TradeSideBuy:
+				quantities[trade.Code] += trade.Quantity
+				buys += float64(trade.Quantity)*trade.Price + trade.Fee
+			case TradeSideSell:
+				if trade.Quantity > quantities[trade.Code] {
+					continue
+				}
+				quantities[trade.Code] -= trade.Quantity
+				sells += float64(trade.Quantity)*trade.Price - trade.Fee
+			}
+			marks[trade.Code] = trade.Price
+		}
+		for code, price := range closeByDate[date] {
+			marks[code] = price
+		}
+		for ; nextBenchmark < len(benchmarkCandles) && benchmarkCandles[nextBenchmark].D <= date; nextBenchmark++ {
+			benchmarkLevel = benchmarkCandles[nextBenchmark].C
+		}
+
+		previous := value
+		value = 0
+		for code, quantity := range quantities {
+			value += float64(quantity) * marks[code]
+		}
+		if date <= since {
+			continue
+		}
+
+		if invested := previous + buys; invested > 0 {
+			wealth *= (value + sells) / invested
+		}
+		point := PerformancePoint{
+			Date:        date,
+			MarketValue: value,
+			NetFlow:     buys - sells,
+			Return:      (wealth - 1) * 100,
+		}
+		portfolioLevels = append(portfolioLevels, wealth)
+		if benchmarkBase > 0 && benchmarkLevel > 0 {
+			benchmarkReturn := (benchmarkLevel/benchmarkBase - 1) * 100
+			point.BenchmarkReturn = &benchmarkReturn
+			benchmarkLevels = append(benchmarkLevels, benchmarkLevel/benchmarkBase)
+		} else {
+			benchmarkLevels = append(benchmarkLevels, 0)
+		}
+		performance.Series = append(performance.Series, point)
+	}
+
+	if len(performance.Series) == 0 {
+		return performance
+	}
+	last := performance.Series[len(performance.Series)-1]
+	performance.To = last.Date
+	performance.TimeWeightedReturn = last.Return
+	performance.Drawdown = computeDrawdown(since, performance.Series, portfolioLevels)
+	if last.BenchmarkReturn != nil {
+		benchmarkReturn := *last.BenchmarkReturn
+		excess := performance.TimeWeightedReturn - benchmarkReturn
+		performance.BenchmarkReturn, performance.ExcessReturn = &benchmarkReturn, &excess
+		drawdown := computeDrawdown(since, performance.Series, benchmarkLevels)
+		performance.BenchmarkDrawdown = &drawdown
+	}
+	return performance
+}
+
+// computeDrawdown returns the drawdown of levels (growth of 1 since the base
+// date, zero where unknown) along the dates of series
+func computeDrawdown(since string, series []PerformancePoint, levels []float64) Drawdown {
+	var drawdown Drawdown
+	peak, peakDate := 1.0, since
+	recovering := false
+	for i, level := range levels {
+		if level <= 0 {
+			continue
+		}
+		if level >= peak {
+			peak, peakDate = level, series[i].Date
+			if recovering {
+				drawdown.RecoveredDate = series[i].Date
+				recovering = false
+			}
+			continue
+		}
+		fall := (level/peak - 1) * 100
+		if fall < drawdown.MaxPercent {
+			drawdown.MaxPercent = fall
+			drawdown.PeakDate, drawdown.TroughDate = peakDate, series[i].Date
+			drawdown.RecoveredDate = ""
+			recovering = true
+		}
+	}
+	if last := levels[len(levels)-1]; last > 0 && last < peak {
+		drawdown.CurrentPercent = (last/peak - 1) * 100
+	}
+	return drawdown
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputePerformance(t *testing.T) {
+	// 100 HPG bought at 20 on day 1; the price doubles to 40 on day 2; the
+	// position is doubled at 40 on day 3 and the price falls back to 30
+	trades := []PortfolioTrade{
+		{Code: "HPG", Side: TradeSideBuy, Quantity: 100, Price: 40, TradeDate: "2026-03-04"},
+		{Code: "HPG", Side: TradeSideBuy, Quantity: 100, Price: 20, TradeDate: "2026-03-02"},
+	}
+	closes := map[string][]CandleData{
+		"HPG": {{D: "2026-03-04", C: 30}, {D: "2026-03-02", C: 20}, {D: "2026-03-03", C: 40}},
+	}
+	benchmark := []CandleData{
+		{D: "2026-02-27", C: 1000}, {D: "2026-03-02", C: 1010}, {D: "2026-03-03", C: 1050}, {D: "2026-03-04", C: 1100},
+	}
+
+	performance := ComputePerformance(trades, closes, benchmark, "2026-03-01", "2026-03-04")
+	if len(performance.Series) != 3 || performance.To != "2026-03-04" {
+		t.Fatalf("series = %+v; want 3 dates up to 2026-03-04", performance.Series)
+	}
+	// Time-weighted: x1 on day 1, x2 on day 2, x0.75 on day 3 = +50%, although
+	// the money invested (6000) is worth exactly 6000
+	if math.Abs(performance.TimeWeightedReturn-50) > 1e-9 {
+		t.Errorf("TimeWeightedReturn = %v; want 50", performance.TimeWeightedReturn)
+	}
+	if performance.Series[2].NetFlow != 4000 || performance.Series[2].MarketValue != 6000 {
+		t.Errorf("series = %+v; want a 4000 flow and a 6000 value on day 3", performance.Series)
+	}
+	if performance.BenchmarkReturn == nil || math.Abs(*performance.BenchmarkReturn-10) > 1e-9 {
+		t.Errorf("BenchmarkReturn = %v; want 10 (from the close before the period)", performance.BenchmarkReturn)
+	}
+	if performance.ExcessReturn == nil || math.Abs(*performance.ExcessReturn-40) > 1e-9 {
+		t.Errorf("ExcessReturn = %v; want 40", performance.ExcessReturn)
+	}
+	drawdown := performance.Drawdown
+	if math.Abs(drawdown.MaxPercent+25) > 1e-9 || drawdown.PeakDate != "2026-03-03" || drawdown.TroughDate != "2026-03-04" ||
+		drawdown.RecoveredDate != "" || math.Abs(drawdown.CurrentPercent+25) > 1e-9 {
+		t.Errorf("Drawdown = %+v; want -25%% from 2026-03-03 to 2026-03-04, not recovered", drawdown)
+	}
+	if performance.BenchmarkDrawdown == nil || performance.BenchmarkDrawdown.MaxPercent != 0 {
+		t.Errorf("BenchmarkDrawdown = %+v; want none", performance.BenchmarkDrawdown)
+	}
+}
+
+func TestComputePerformanceSellsAndMissingBenchmark(t *testing.T) {
+	// Bought at 10, half sold at 12 (fee 10) while the close is 11, rest held at 11
+	trades := []PortfolioTrade{
+		{Code: "FPT", Side: TradeSideBuy, Quantity: 100, Price: 10, TradeDate: "2026-03-02"},
+		{Code: "FPT", Side: TradeSideSell, Quantity: 50, Price: 12, Fee: 10, TradeDate: "2026-03-03"},
+	}
+	closes := map[string][]CandleData{"FPT": {{D: "2026-03-02", C: 11}, {D: "2026-03-03", C: 11}}}
+
+	performance := ComputePerformance(trades, closes, nil, "2026-03-01", "2026-03-03")
+	// Day 1: 1100 / 1000; day 2: (550 + 590) / 1100
+	want := (1100.0/1000*1140/1100 - 1) * 100
+	if math.Abs(performance.TimeWeightedReturn-want) > 1e-9 {
+		t.Errorf("TimeWeightedReturn = %v; want %v", performance.TimeWeightedReturn, want)
+	}
+	if performance.BenchmarkReturn != nil || performance.BenchmarkDrawdown != nil || performance.Series[0].BenchmarkReturn != nil {
+		t.Errorf("performance = %+v; want no benchmark figures without benchmark candles", performance)
+	}
+}
+
+func TestPerformanceSince(t *testing.T) {
+	to := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		period string
+		want   string
+	}{
+		{PerformancePeriod1M, "2026-09-15"},
+		{PerformancePeriodYTD, "2025-12-31"},
+		{PerformancePeriod3Y, "2023-10-15"},
+		{PerformancePeriodAll, "2024-05-31"},
+	}
+	for _, tt := range tests {
+		if got, err := PerformanceSince(tt.period, to, "2024-06-01"); err != nil || got != tt.want {
+			t.Errorf("PerformanceSince(%s) = %q, %v; want %q", tt.period, got, err, tt.want)
+		}
+	}
+	if _, err := PerformanceSince("2w", to, ""); err == nil {
+		t.Error("PerformanceSince(2w) succeeded; want an error")
+	}
+}
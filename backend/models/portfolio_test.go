@@ -0,0 +1,103 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPortfolioTradeValidate(t *testing.T) {
+	valid := PortfolioTrade{Code: "HPG", Side: TradeSideBuy, Quantity: 100, Price: 25, TradeDate: "2026-02-02"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate() = %v; want nil", err)
+	}
+
+	cases := map[string]func(*PortfolioTrade){
+		"no code":       func(t *PortfolioTrade) { t.Code = " " },
+		"bad side":      func(t *PortfolioTrade) { t.Side = "short" },
+		"zero quantity": func(t *PortfolioTrade) { t.Quantity = 0 },
+		"zero price":    func(t *PortfolioTrade) { t.Price = 0 },
+		"negative fee":  func(t *PortfolioTrade) { t.Fee = -1 },
+		"bad date":      func(t *PortfolioTrade) { t.TradeDate = "02/02/2026" },
+	}
+	for name, mutate := range cases {
+		trade := valid
+		mutate(&trade)
+		if err := trade.Validate(); err == nil {
+			t.Errorf("%s: Validate() = nil; want an error", name)
+		}
+	}
+}
+
+func TestBuildHoldings(t *testing.T) {
+	trades := []PortfolioTrade{
+		{Code: "HPG", Side: TradeSideSell, Quantity: 100, Price: 30, Fee: 10, TradeDate: "2026-03-01"},
+		{Code: "HPG", Side: TradeSideBuy, Quantity: 100, Price: 20, TradeDate: "2026-01-05"},
+		{Code: "HPG", Side: TradeSideBuy, Quantity: 100, Price: 26, Fee: 20, TradeDate: "2026-02-01"},
+		{Code: "FPT", Side: TradeSideBuy, Quantity: 10, Price: 100, TradeDate: "2026-01-10"},
+		{Code: "FPT", Side: TradeSideSell, Quantity: 10, Price: 110, TradeDate: "2026-01-20"},
+	}
+
+	holdings, err := BuildHoldings(trades)
+	if err != nil {
+		t.Fatalf("BuildHoldings() error = %v", err)
+	}
+	if len(holdings) != 2 || holdings[0].Code != "FPT" || holdings[1].Code != "HPG" {
+		t.Fatalf("holdings = %+v; want FPT then HPG", holdings)
+	}
+
+	fpt := holdings[0]
+	if fpt.Quantity != 0 || fpt.CostBasis != 0 || fpt.RealizedPnL != 100 {
+		t.Errorf("FPT = %+v; want a closed position with 100 realized", fpt)
+	}
+	// 200 shares cost 2000 + 2620 = 4620; selling half releases 2310 of cost
+	hpg := holdings[1]
+	if hpg.Quantity != 100 || hpg.CostBasis != 2310 || hpg.AverageCost != 23.1 {
+		t.Errorf("HPG = %+v; want 100 shares at 23.1", hpg)
+	}
+	if hpg.RealizedPnL != 3000-10-2310 {
+		t.Errorf("HPG realized = %v; want %v", hpg.RealizedPnL, 3000-10-2310)
+	}
+
+	oversold := []PortfolioTrade{
+		{Code: "HPG", Side: TradeSideBuy, Quantity: 100, Price: 20, TradeDate: "2026-02-01"},
+		{Code: "HPG", Side: TradeSideSell, Quantity: 100, Price: 20, TradeDate: "2026-01-01"},
+	}
+	if _, err := BuildHoldings(oversold); err == nil {
+		t.Error("BuildHoldings(sell before buy) succeeded; want an error")
+	}
+}
+
+func TestValuePortfolio(t *testing.T) {
+	holdings := []Holding{
+		{Code: "FPT", Quantity: 10, CostBasis: 1000},
+		{Code: "HPG", Quantity: 100, CostBasis: 2000},
+		{Code: "XYZ", Quantity: 5, CostBasis: 50},
+		{Code: "VNM", RealizedPnL: -40},
+	}
+	latest := map[string]CandleData{
+		"FPT": {D: "2026-02-06", C: 150},
+		"HPG": {D: "2026-02-06", C: 15},
+	}
+
+	valuation := ValuePortfolio(holdings, latest)
+	if valuation.MarketValue != 3000 || valuation.UnrealizedPnL != 0 || valuation.CostBasis != 3050 {
+		t.Errorf("totals = %+v; want market value 3000, unrealized 0, cost 3050", valuation)
+	}
+	if valuation.RealizedPnL != -40 {
+		t.Errorf("RealizedPnL = %v; want -40", valuation.RealizedPnL)
+	}
+	if len(valuation.Unpriced) != 1 || valuation.Unpriced[0] != "XYZ" {
+		t.Errorf("Unpriced = %v; want [XYZ]", valuation.Unpriced)
+	}
+
+	fpt := valuation.Holdings[0]
+	if fpt.UnrealizedPnL == nil || *fpt.UnrealizedPnL != 500 || *fpt.UnrealizedPnLPct != 50 {
+		t.Errorf("FPT P&L = %v; want 500 (50%%)", fpt.UnrealizedPnL)
+	}
+	if fpt.Allocation == nil || math.Abs(*fpt.Allocation-50) > 1e-9 {
+		t.Errorf("FPT allocation = %v; want 50", fpt.Allocation)
+	}
+	if valuation.Holdings[2].MarketValue != nil || valuation.Holdings[2].Allocation != nil {
+		t.Error("unpriced holding has a market value; want none")
+	}
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Price alert metrics, evaluated on the latest stored candle
+const (
+	PriceMetricClose         = "close"
+	PriceMetricOpen          = "open"
+	PriceMetricHigh          = "high"
+	PriceMetricLow           = "low"
+	PriceMetricVolume        = "volume"
+	PriceMetricChangePercent = "change_percent" // Close vs the previous close, in percent
+	PriceMetricRSI           = "rsi"            // RSI(14) of the closes
+	PriceMetricVolumeRatio   = "volume_ratio"   // Volume / average of the VolumeSpikeWindow sessions before, as for volume anomalies
+)
+
+// PriceAlertMetrics lists the metrics a condition can compare
+var PriceAlertMetrics = []string{
+	PriceMetricClose,
+	PriceMetricOpen,
+	PriceMetricHigh,
+	PriceMetricLow,
+	PriceMetricVolume,
+	PriceMetricChangePercent,
+	PriceMetricRSI,
+	PriceMetricVolumeRatio,
+}
+
+// priceMetricAliases maps accepted spellings to their metric
+var priceMetricAliases = map[string]string{
+	"price":  PriceMetricClose,
+	"change": PriceMetricChangePercent,
+	"rsi14":  PriceMetricRSI,
+	"rvol":   PriceMetricVolumeRatio,
+}
+
+// priceAlertOperators lists the comparison operators, longest first for parsing
+var priceAlertOperators = []string{">=", "<=", ">", "<"}
+
+// Price alert states
+const (
+	PriceAlertStateOK        = "ok"
+	PriceAlertStateTriggered = "triggered" // Condition held at the last evaluation; not notified again until it clears
+)
+
+// PriceAlertCondition compares one metric of a stock against a threshold
+type PriceAlertCondition struct {
+	Metric    string
+	Operator  string
+	Threshold float64
+}
+
+// ParsePriceAlertCondition parses conditions such as "close > 30" or "RSI < 30"
+func ParsePriceAlertCondition(raw string) (PriceAlertCondition, error) {
+	text := strings.ToLower(strings.TrimSpace(raw))
+	for _, op := range priceAlertOperators {
+		left, right, found := strings.Cut(text, op)
+		if !found {
+			continue
+		}
+		metric := strings.TrimSpace(left)
+		if alias, ok := priceMetricAliases[metric]; ok {
+			metric = alias
+		}
+		if !containsString(PriceAlertMetrics, metric) {
+			return PriceAlertCondition{}, fmt.Errorf("unknown metric %q (supported: %s)", metric, strings.Join(PriceAlertMetrics, ", "))
+		}
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(right), 64)
+		if err != nil {
+			return PriceAlertCondition{}, fmt.Errorf("threshold must be a number (got %q)", strings.TrimSpace(right))
+		}
+		return PriceAlertCondition{Metric: metric, Operator: op, Threshold: threshold}, nil
+	}
+	return PriceAlertCondition{}, fmt.Errorf("condition must look like \"close > 30\" (operators: >, >=, <, <=)")
+}
+
+// String returns the normalized condition, e.g. "rsi < 30"
+func (c PriceAlertCondition) String() string {
+	return fmt.Sprintf("%s %s %s", c.Metric, c.Operator, strconv.FormatFloat(c.Threshold, 'f', -1, 64))
+}
+
+// Holds reports whether value satisfies the condition
+func (c PriceAlertCondition) Holds(value float64) bool {
+	switch c.Operator {
+	case ">":
+		return value > c.Threshold
+	case ">=":
+		return value >= c.Threshold
+	case "<":
+		return value < c.Threshold
+	case "<=":
+		return value <= c.Threshold
+	}
+	return false
+}
+
+// Value returns the condition's metric for the latest of the candles (ordered
+// by date), or false when there is not enough history to compute it
+func (c PriceAlertCondition) Value(candles []CandleData) (float64, bool) {
+	n := len(candles)
+	if n == 0 {
+		return 0, false
+	}
+	latest := candles[n-1]
+	switch c.Metric {
+	case PriceMetricClose:
+		return latest.C, true
+	case PriceMetricOpen:
+		return latest.O, true
+	case PriceMetricHigh:
+		return latest.H, true
+	case PriceMetricLow:
+		return latest.L, true
+	case PriceMetricVolume:
+		return float64(latest.V), true
+	case PriceMetricChangePercent:
+		if n < 2 || candles[n-2].C == 0 {
+			return 0, false
+		}
+		return (latest.C - candles[n-2].C) / candles[n-2].C * 100, true
+	case PriceMetricRSI:
+		closes := make([]float64, n)
+		for i, candle := range candles {
+			closes[i] = candle.C
+		}
+		return RSI(closes, RSIPeriod)
+	case PriceMetricVolumeRatio:
+		average, ok := previousAverageVolume(candles, VolumeSpikeWindow)
+		if !ok || average <= 0 {
+			return 0, false
+		}
+		return float64(latest.V) / average, true
+	}
+	return 0, false
+}
+
+// PriceAlert represents the price_alerts table in Supabase
+// A member's condition on one stock, evaluated after every crawl that stores
+// new candles for it
+type PriceAlert struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	ProfileID       uuid.UUID  `gorm:"type:uuid;not null;column:profile_id" json:"profile_id"`
+	Code            string     `gorm:"type:text;not null;column:code" json:"code"`
+	Condition       string     `gorm:"type:text;not null;column:condition" json:"condition"`          // Normalized, e.g. "close > 30"
+	Channels        string     `gorm:"type:text;not null;default:'';column:channels" json:"channels"` // Comma-separated notification channels
+	Note            string     `gorm:"type:text;column:note" json:"note,omitempty"`
+	Enabled         bool       `gorm:"type:boolean;default:true;column:enabled" json:"enabled"`
+	State           string     `gorm:"type:text;default:'ok';column:state" json:"state"`
+	LastValue       *float64   `gorm:"type:double precision;column:last_value" json:"last_value,omitempty"`
+	LastEvaluatedAt *time.Time `gorm:"type:timestamptz;column:last_evaluated_at" json:"last_evaluated_at,omitempty"`
+	LastTriggeredAt *time.Time `gorm:"type:timestamptz;column:last_triggered_at" json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt       time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (PriceAlert) TableName() string {
+	return "public.price_alerts"
+}
+
+// ChannelList returns the alert's notification channels as a slice
+func (a PriceAlert) ChannelList() []string {
+	return SplitList(a.Channels)
+}
+
+// ParsedCondition parses the stored condition
+func (a PriceAlert) ParsedCondition() (PriceAlertCondition, error) {
+	return ParsePriceAlertCondition(a.Condition)
+}
+
+// Validate checks that the stock code is set and the condition parses
+func (a PriceAlert) Validate() error {
+	if strings.TrimSpace(a.Code) == "" {
+		return fmt.Errorf("code is required")
+	}
+	_, err := a.ParsedCondition()
+	return err
+}
+
+// PriceAlertEvent represents the price_alert_events table in Supabase
+// One row per time an alert triggered, with the outcome of its delivery
+type PriceAlertEvent struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	AlertID    uuid.UUID `gorm:"type:uuid;not null;column:alert_id" json:"alert_id"`
+	ProfileID  uuid.UUID `gorm:"type:uuid;not null;column:profile_id" json:"profile_id"`
+	Code       string    `gorm:"type:text;not null;column:code" json:"code"`
+	Condition  string    `gorm:"type:text;not null;column:condition" json:"condition"`
+	Value      float64   `gorm:"type:double precision;not null;column:value" json:"value"`
+	CandleDate string    `gorm:"type:text;not null;column:candle_date" json:"candle_date"`
+	Close      float64   `gorm:"type:double precision;column:close" json:"close"`
+	Channels   string    `gorm:"type:text;column:channels" json:"channels"`
+	Error      *string   `gorm:"type:text;column:error" json:"error,omitempty"` // Delivery failures, if any
+	CreatedAt  time.Time `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (PriceAlertEvent) TableName() string {
+	return "public.price_alert_events"
+}
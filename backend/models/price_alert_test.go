@@ -0,0 +1,100 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePriceAlertCondition(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"close > 30", "close > 30"},
+		{"RSI < 30", "rsi < 30"},
+		{"rsi14<=25.5", "rsi <= 25.5"},
+		{"change >= -5", "change_percent >= -5"},
+		{" price<25 ", "close < 25"},
+	}
+	for _, tt := range tests {
+		got, err := ParsePriceAlertCondition(tt.raw)
+		if err != nil {
+			t.Errorf("ParsePriceAlertCondition(%q) error = %v", tt.raw, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("ParsePriceAlertCondition(%q) = %q; want %q", tt.raw, got.String(), tt.want)
+		}
+	}
+
+	for _, raw := range []string{"", "close", "close = 30", "macd > 0", "close > thirty"} {
+		if _, err := ParsePriceAlertCondition(raw); err == nil {
+			t.Errorf("ParsePriceAlertCondition(%q) succeeded; want an error", raw)
+		}
+	}
+}
+
+func TestPriceAlertConditionValue(t *testing.T) {
+	candles := []CandleData{
+		{D: "2026-02-02", O: 24, H: 25, L: 23.5, C: 24.5, V: 1000},
+		{D: "2026-02-03", O: 24.5, H: 26, L: 24.2, C: 25.725, V: 1500},
+	}
+
+	change, _ := ParsePriceAlertCondition("change_percent > 4")
+	value, ok := change.Value(candles)
+	if !ok || math.Abs(value-5) > 1e-9 {
+		t.Errorf("change_percent = %v, %v; want 5", value, ok)
+	}
+	if !change.Holds(value) {
+		t.Error("change_percent > 4 does not hold for 5")
+	}
+
+	volume, _ := ParsePriceAlertCondition("volume >= 1500")
+	if value, _ := volume.Value(candles); !volume.Holds(value) {
+		t.Errorf("volume >= 1500 does not hold for %v", value)
+	}
+
+	// RSI and the volume ratio need more history than two candles
+	rsi, _ := ParsePriceAlertCondition("rsi < 30")
+	if _, ok := rsi.Value(candles); ok {
+		t.Error("rsi computed from 2 candles; want not enough history")
+	}
+	ratio, err := ParsePriceAlertCondition("rvol >= 3")
+	if err != nil || ratio.Metric != PriceMetricVolumeRatio {
+		t.Fatalf("ParsePriceAlertCondition(rvol >= 3) = %+v, %v; want volume_ratio", ratio, err)
+	}
+	if _, ok := ratio.Value(candles); ok {
+		t.Error("volume_ratio computed from 2 candles; want not enough history")
+	}
+	history := make([]CandleData, VolumeSpikeWindow, VolumeSpikeWindow+1)
+	for i := range history {
+		history[i] = CandleData{V: 1000}
+	}
+	history = append(history, CandleData{V: 3500})
+	if value, ok := ratio.Value(history); !ok || value != 3.5 || !ratio.Holds(value) {
+		t.Errorf("volume_ratio = %v, %v; want 3.5", value, ok)
+	}
+}
+
+func TestRSI(t *testing.T) {
+	rising := make([]float64, 30)
+	for i := range rising {
+		rising[i] = float64(10 + i)
+	}
+	if got, ok := RSI(rising, RSIPeriod); !ok || got != 100 {
+		t.Errorf("RSI(only gains) = %v, %v; want 100", got, ok)
+	}
+
+	// Alternating equal gains and losses balance out
+	flat := make([]float64, 30)
+	for i := range flat {
+		flat[i] = 10 + float64(i%2)
+	}
+	if got, _ := RSI(flat, RSIPeriod); math.Abs(got-50) > 5 {
+		t.Errorf("RSI(alternating) = %v; want about 50", got)
+	}
+
+	if _, ok := RSI(rising[:RSIPeriod], RSIPeriod); ok {
+		t.Error("RSI with period closes succeeded; want period+1 required")
+	}
+}
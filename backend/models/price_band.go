@@ -0,0 +1,144 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Suspect candle review statuses
+const (
+	SuspectCandleStatusPending  = "pending"  // Kept out of the buckets until reviewed
+	SuspectCandleStatusAccepted = "accepted" // Stored as regular data by an admin
+	SuspectCandleStatusRejected = "rejected" // Discarded; later crawls do not flag it again
+)
+
+// RelistingGapDays is the calendar gap after which a symbol's next session
+// is treated as a relisting (trading resumed after a suspension of about 25
+// sessions), which exchanges open with the first-day band
+const RelistingGapDays = 36
+
+// priceBandTolerance absorbs float noise in provider prices at the limits
+const priceBandTolerance = 1e-6
+
+// SuspectCandle is a crawled candle outside its exchange's price band,
+// stored in the suspect_candles collection instead of the price buckets
+type SuspectCandle struct {
+	ID            string              `bson:"_id" json:"id"` // Format: "{CODE}_{YYYY-MM-DD}"
+	Code          string              `bson:"code" json:"code"`
+	Exchange      string              `bson:"exchange" json:"exchange"`
+	Candle        CandleData          `bson:"candle" json:"candle"`
+	ReferenceDate string              `bson:"referenceDate" json:"reference_date"` // Session the band was derived from
+	Floor         float64             `bson:"floor" json:"floor"`
+	Ceiling       float64             `bson:"ceiling" json:"ceiling"`
+	FirstDay      bool                `bson:"firstDay,omitempty" json:"first_day,omitempty"` // First-day band applied (listing, transfer, relisting)
+	Reason        string              `bson:"reason" json:"reason"`
+	Status        string              `bson:"status" json:"status"`
+	DetectedAt    primitive.DateTime  `bson:"detectedAt" json:"detected_at"`
+	ResolvedBy    string              `bson:"resolvedBy,omitempty" json:"resolved_by,omitempty"`
+	ResolvedAt    *primitive.DateTime `bson:"resolvedAt,omitempty" json:"resolved_at,omitempty"`
+}
+
+// SuspectCandleID returns the ID of a symbol's suspect candle on date
+func SuspectCandleID(code, date string) string {
+	return code + "_" + date
+}
+
+// CheckPriceBands splits new candles of a symbol (sorted by date) into those
+// within the exchange's price band and suspects. stored holds the symbol's
+// stored candles around them, sorted by date; candles already stored are
+// passed through unchecked. Each candle is checked against the latest
+// earlier candle, stored or accepted:
+//
+//   - The reference is the previous close, or on exchanges whose reference
+//     is the previous session's average price (UPCOM) anywhere between its
+//     low and high.
+//   - firstDays holds the dates a listing started trading on the exchange
+//     (new listing or transfer); they and sessions after a gap of
+//     RelistingGapDays get the first-day band.
+//   - Sessions without volume carry placeholder prices (no ATO/ATC or
+//     continuous match) and are not checked.
+//   - A candle without any earlier candle is a listing's first session with
+//     an unknown reference and is not checked.
+func CheckPriceBands(code string, exchange Exchange, candles, stored []CandleData, firstDays map[string]bool) ([]CandleData, []SuspectCandle) {
+	storedDates := make(map[string]bool, len(stored))
+	for _, candle := range stored {
+		storedDates[candle.D] = true
+	}
+
+	valid := make([]CandleData, 0, len(candles))
+	var suspects []SuspectCandle
+	next := 0 // Stored candles before the current one have been consumed
+	var previous *CandleData
+	for i := range candles {
+		candle := candles[i]
+		for next < len(stored) && stored[next].D < candle.D {
+			previous = &stored[next]
+			next++
+		}
+		if storedDates[candle.D] {
+			valid = append(valid, candle)
+			continue
+		}
+		if previous == nil || candle.V == 0 {
+			valid = append(valid, candle)
+			previous = &candles[i]
+			continue
+		}
+
+		firstDay := firstDays[candle.D] || sessionGap(previous.D, candle.D) >= RelistingGapDays
+		low, high := previous.C, previous.C
+		if exchange.PriceBand.AverageReference && previous.L > 0 && previous.H >= previous.L {
+			low, high = previous.L, previous.H
+		}
+		floor, _ := exchange.PriceLimits(low, firstDay)
+		_, ceiling := exchange.PriceLimits(high, firstDay)
+
+		if reason := bandViolation(candle, floor, ceiling); reason != "" {
+			suspects = append(suspects, SuspectCandle{
+				ID:            SuspectCandleID(code, candle.D),
+				Code:          code,
+				Exchange:      exchange.Code,
+				Candle:        candle,
+				ReferenceDate: previous.D,
+				Floor:         floor,
+				Ceiling:       ceiling,
+				FirstDay:      firstDay,
+				Reason:        reason,
+				Status:        SuspectCandleStatusPending,
+			})
+			continue
+		}
+		valid = append(valid, candle)
+		previous = &candles[i]
+	}
+	return valid, suspects
+}
+
+// bandViolation describes the first price of candle outside [floor, ceiling],
+// or returns "" when all are within
+func bandViolation(candle CandleData, floor, ceiling float64) string {
+	for _, price := range []struct {
+		name  string
+		value float64
+	}{{"open", candle.O}, {"high", candle.H}, {"low", candle.L}, {"close", candle.C}} {
+		switch {
+		case price.value > ceiling*(1+priceBandTolerance):
+			return fmt.Sprintf("%s %g above ceiling %g", price.name, price.value, ceiling)
+		case price.value < floor*(1-priceBandTolerance):
+			return fmt.Sprintf("%s %g below floor %g", price.name, price.value, floor)
+		}
+	}
+	return ""
+}
+
+// sessionGap returns the calendar days between two YYYY-MM-DD dates
+func sessionGap(from, to string) int {
+	start, err1 := time.Parse("2006-01-02", from)
+	end, err2 := time.Parse("2006-01-02", to)
+	if err1 != nil || err2 != nil {
+		return 0
+	}
+	return int(end.Sub(start).Hours() / 24)
+}
@@ -0,0 +1,83 @@
+package models
+
+import "testing"
+
+func bandCandle(date string, o, h, l, c float64) CandleData {
+	return CandleData{D: date, O: o, H: h, L: l, C: c, V: 1000}
+}
+
+func TestCheckPriceBandsHOSE(t *testing.T) {
+	hose, _ := LookupExchange("HOSE")
+	stored := []CandleData{bandCandle("2024-01-15", 25, 25.5, 24.8, 25)}
+	candles := []CandleData{
+		bandCandle("2024-01-15", 25, 25.5, 24.8, 25),       // Already stored: not checked
+		bandCandle("2024-01-16", 25.5, 26.75, 25.2, 26.75), // Ceiling of 25 +7%
+		bandCandle("2024-01-17", 27, 31, 26.9, 30),         // Above 26.75 +7% = 28.6225, in 50 đồng ticks 28.6
+		bandCandle("2024-01-18", 27, 28.6, 26.5, 28),       // Checked against 26.75, not the suspect
+	}
+
+	valid, suspects := CheckPriceBands("HPG", hose, candles, stored, nil)
+	if len(valid) != 3 || valid[2].D != "2024-01-18" {
+		t.Fatalf("valid = %v; want 2024-01-15, 2024-01-16 and 2024-01-18", valid)
+	}
+	if len(suspects) != 1 {
+		t.Fatalf("suspects = %v; want one", suspects)
+	}
+	suspect := suspects[0]
+	if suspect.ID != "HPG_2024-01-17" || suspect.ReferenceDate != "2024-01-16" || suspect.Ceiling != 28.6 ||
+		suspect.Reason != "high 31 above ceiling 28.6" || suspect.Status != SuspectCandleStatusPending {
+		t.Errorf("suspect = %+v; want HPG_2024-01-17 above ceiling 28.6 of 2024-01-16", suspect)
+	}
+}
+
+func TestCheckPriceBandsFirstDay(t *testing.T) {
+	hose, _ := LookupExchange("HOSE")
+	stored := []CandleData{
+		bandCandle("2024-01-15", 20, 20, 20, 20),
+		bandCandle("2024-03-01", 20, 20, 20, 20),
+	}
+	candles := []CandleData{
+		bandCandle("2024-03-04", 23, 23.5, 22, 23.5), // Transfer day: ±20%
+		bandCandle("2024-05-02", 27, 28, 27, 28),     // After a suspension: ±20% of 23.5
+	}
+
+	valid, suspects := CheckPriceBands("ABC", hose, candles, stored, map[string]bool{"2024-03-04": true})
+	if len(valid) != 2 || len(suspects) != 0 {
+		t.Errorf("CheckPriceBands() = %v, %v; want both within the first-day band", valid, suspects)
+	}
+
+	// The same moves on regular days are suspect
+	_, suspects = CheckPriceBands("ABC", hose, candles[:1], stored, nil)
+	if len(suspects) != 1 || suspects[0].FirstDay {
+		t.Errorf("suspects without a transfer = %v; want one with the regular band", suspects)
+	}
+}
+
+func TestCheckPriceBandsUnchecked(t *testing.T) {
+	hose, _ := LookupExchange("HOSE")
+
+	// A listing's first session has no reference; a session without volume
+	// carries placeholder prices
+	placeholder := bandCandle("2024-01-16", 0, 0, 0, 0)
+	placeholder.V = 0
+	candles := []CandleData{bandCandle("2024-01-15", 10, 12, 10, 12), placeholder}
+	valid, suspects := CheckPriceBands("NEW", hose, candles, nil, nil)
+	if len(valid) != 2 || len(suspects) != 0 {
+		t.Errorf("CheckPriceBands() = %v, %v; want both passed through", valid, suspects)
+	}
+}
+
+func TestCheckPriceBandsAverageReference(t *testing.T) {
+	upcom, _ := LookupExchange("UPCOM")
+	stored := []CandleData{bandCandle("2024-01-15", 10, 11, 9, 9)}
+
+	// The reference is the unknown average of 9..11: up to 11 +15% is allowed
+	candles := []CandleData{bandCandle("2024-01-16", 11, 12.6, 11, 12.5)}
+	if _, suspects := CheckPriceBands("UPC", upcom, candles, stored, nil); len(suspects) != 0 {
+		t.Errorf("suspects = %v; want none within the band of the previous high", suspects)
+	}
+	candles = []CandleData{bandCandle("2024-01-16", 11, 12.7, 11, 12.5)}
+	if _, suspects := CheckPriceBands("UPC", upcom, candles, stored, nil); len(suspects) != 1 {
+		t.Errorf("suspects = %v; want one above 12.6", suspects)
+	}
+}
@@ -1,8 +1,14 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sort"
+	"strconv"
 	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 // CandleData represents a single candlestick with shortened field names to save storage
@@ -17,11 +23,131 @@ type CandleData struct {
 
 // PriceBucket represents a bucket of price data for a stock in a specific year
 // This implements the Bucket Pattern to save storage in MongoDB
+//
+// History is always decoded in memory; Encoding selects how it is stored
+// (see BucketEncodingColumnar), so readers never deal with packed data.
 type PriceBucket struct {
-	ID      string       `bson:"_id" json:"id"`          // Format: "{CODE}_{YEAR}" (e.g., "HPG_2024")
-	Code    string       `bson:"code" json:"code"`       // Stock code
-	Year    int          `bson:"year" json:"year"`       // Year
-	History []CandleData `bson:"history" json:"history"` // Array of candles
+	ID       string       `bson:"_id" json:"id"`                                // Format: "{CODE}_{YEAR}" (e.g., "HPG_2024")
+	Code     string       `bson:"code" json:"code"`                             // Stock code
+	Year     int          `bson:"year" json:"year"`                             // Year
+	History  []CandleData `bson:"history" json:"history"`                       // Array of candles
+	Checksum string       `bson:"checksum,omitempty" json:"checksum,omitempty"` // ComputeChecksum(History) at last write
+	Encoding string       `bson:"enc,omitempty" json:"encoding,omitempty"`      // Storage encoding of History
+	// UpdatedAt is when a stored candle was last replaced in place (the
+	// session candle), which changes neither the count nor, for a bucket
+	// failing verification, the checksum
+	UpdatedAt *time.Time `bson:"updatedAt,omitempty" json:"updated_at,omitempty"`
+}
+
+// storedPriceBucket is the document layout of a bucket in MongoDB. Columnar
+// buckets keep their candles in Packed and the newest date in LastDate, so
+// aggregations can find the freshest candle without decoding.
+type storedPriceBucket struct {
+	ID        string       `bson:"_id"`
+	Code      string       `bson:"code"`
+	Year      int          `bson:"year"`
+	History   []CandleData `bson:"history,omitempty"`
+	Packed    []byte       `bson:"packed,omitempty"`
+	Candles   int          `bson:"candles,omitempty"`
+	LastDate  string       `bson:"lastDate,omitempty"`
+	Checksum  string       `bson:"checksum,omitempty"`
+	Encoding  string       `bson:"enc,omitempty"`
+	UpdatedAt *time.Time   `bson:"updatedAt,omitempty"`
+}
+
+// MarshalBSON stores the bucket in its Encoding
+func (b PriceBucket) MarshalBSON() ([]byte, error) {
+	stored := storedPriceBucket{
+		ID:        b.ID,
+		Code:      b.Code,
+		Year:      b.Year,
+		Checksum:  b.Checksum,
+		Encoding:  b.Encoding,
+		UpdatedAt: b.UpdatedAt,
+	}
+
+	switch b.Encoding {
+	case BucketEncodingPlain:
+		stored.History = b.History
+		if stored.History == nil {
+			stored.History = []CandleData{}
+		}
+	case BucketEncodingColumnar:
+		stored.Packed = EncodeCandles(b.History)
+		stored.Candles = len(b.History)
+		for _, candle := range b.History {
+			if candle.D > stored.LastDate {
+				stored.LastDate = candle.D
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown bucket encoding %q", b.Encoding)
+	}
+	return bson.Marshal(stored)
+}
+
+// UnmarshalBSON loads a bucket stored in any encoding
+func (b *PriceBucket) UnmarshalBSON(data []byte) error {
+	var stored storedPriceBucket
+	if err := bson.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+
+	*b = PriceBucket{
+		ID:        stored.ID,
+		Code:      stored.Code,
+		Year:      stored.Year,
+		History:   stored.History,
+		Checksum:  stored.Checksum,
+		Encoding:  stored.Encoding,
+		UpdatedAt: stored.UpdatedAt,
+	}
+	if stored.Encoding == BucketEncodingColumnar && stored.Packed != nil {
+		history, err := DecodeCandles(stored.Packed)
+		if err != nil {
+			return fmt.Errorf("bucket %s: %w", stored.ID, err)
+		}
+		b.History = history
+	}
+	return nil
+}
+
+// ComputeChecksum returns the SHA-256 (hex) of the candles sorted by date.
+// The result does not depend on the order candles were appended in, so the
+// same data stored on different backends or replicas has the same checksum.
+func ComputeChecksum(history []CandleData) string {
+	sorted := make([]CandleData, len(history))
+	copy(sorted, history)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].D < sorted[j].D })
+
+	h := sha256.New()
+	buf := make([]byte, 0, 96)
+	for _, candle := range sorted {
+		buf = buf[:0]
+		buf = append(buf, candle.D...)
+		for _, price := range []float64{candle.O, candle.H, candle.L, candle.C} {
+			buf = append(buf, '|')
+			buf = strconv.AppendFloat(buf, price, 'g', -1, 64)
+		}
+		buf = append(buf, '|')
+		buf = strconv.AppendInt(buf, candle.V, 10)
+		buf = append(buf, '\n')
+		h.Write(buf)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// DuplicateDates returns the dates that appear more than once in the bucket
+func (b *PriceBucket) DuplicateDates() []string {
+	seen := make(map[string]int, len(b.History))
+	duplicates := make([]string, 0)
+	for _, candle := range b.History {
+		seen[candle.D]++
+		if seen[candle.D] == 2 {
+			duplicates = append(duplicates, candle.D)
+		}
+	}
+	return duplicates
 }
 
 // GenerateBucketID creates a bucket ID from code and year
@@ -1,7 +1,11 @@
 package models
 
 import (
+	"reflect"
 	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestGenerateBucketID(t *testing.T) {
@@ -100,3 +104,108 @@ func TestPriceBucketStructure(t *testing.T) {
 		t.Errorf("PriceBucket.History length = %d; want 2", len(bucket.History))
 	}
 }
+
+func TestComputeChecksum(t *testing.T) {
+	history := []CandleData{
+		{D: "2024-01-02", O: 25.1, H: 25.8, L: 24.9, C: 25.5, V: 1200000},
+		{D: "2024-01-03", O: 25.5, H: 26.0, L: 25.2, C: 25.9, V: 980000},
+	}
+	reordered := []CandleData{history[1], history[0]}
+
+	checksum := ComputeChecksum(history)
+	if len(checksum) != 64 {
+		t.Fatalf("ComputeChecksum() length = %d; want 64 hex chars", len(checksum))
+	}
+	if ComputeChecksum(reordered) != checksum {
+		t.Errorf("ComputeChecksum() depends on candle order")
+	}
+
+	corrupted := []CandleData{history[0], history[1]}
+	corrupted[1].C = 25.8
+	if ComputeChecksum(corrupted) == checksum {
+		t.Errorf("ComputeChecksum() did not change when a close price changed")
+	}
+	if ComputeChecksum(history[:1]) == checksum {
+		t.Errorf("ComputeChecksum() did not change when a candle was removed")
+	}
+}
+
+func TestDuplicateDates(t *testing.T) {
+	bucket := PriceBucket{History: []CandleData{
+		{D: "2024-01-02"}, {D: "2024-01-03"}, {D: "2024-01-02"}, {D: "2024-01-02"},
+	}}
+	duplicates := bucket.DuplicateDates()
+	if len(duplicates) != 1 || duplicates[0] != "2024-01-02" {
+		t.Errorf("DuplicateDates() = %v; want [2024-01-02]", duplicates)
+	}
+}
+
+func TestEncodeCandlesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		history []CandleData
+	}{
+		{"empty", []CandleData{}},
+		{"two decimals", []CandleData{
+			{D: "2024-01-03", O: 27.15, H: 27.5, L: 26.9, C: 27.35, V: 18_540_300},
+			{D: "2024-01-02", O: 26.8, H: 27.2, L: 26.55, C: 27.15, V: 21_004_100},
+			{D: "2024-01-05", O: 27.35, H: 27.35, L: 26.05, C: 26.1, V: 0},
+		}},
+		{"raw floats", []CandleData{
+			{D: "2024-01-02", O: 1.0 / 3, H: 0.1 + 0.2, L: -0.0, C: 12345.6789012, V: 1},
+		}},
+		{"duplicate and invalid dates", []CandleData{
+			{D: "2024-01-02", O: 10, H: 11, L: 9, C: 10.5, V: 100},
+			{D: "2024-01-02", O: 10, H: 11, L: 9, C: 10.5, V: 100},
+			{D: "not-a-date", O: 1, H: 1, L: 1, C: 1, V: 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		decoded, err := DecodeCandles(EncodeCandles(tt.history))
+		if err != nil {
+			t.Errorf("%s: DecodeCandles() unexpected error: %v", tt.name, err)
+			continue
+		}
+		if len(decoded) != len(tt.history) || ComputeChecksum(decoded) != ComputeChecksum(tt.history) {
+			t.Errorf("%s: round trip = %+v; want the candles of %+v", tt.name, decoded, tt.history)
+		}
+	}
+
+	if _, err := DecodeCandles([]byte("not zstd")); err == nil {
+		t.Error("DecodeCandles(garbage) expected error but got none")
+	}
+}
+
+func TestPriceBucketBSONEncodings(t *testing.T) {
+	history := []CandleData{
+		{D: "2024-01-02", O: 26.8, H: 27.2, L: 26.55, C: 27.15, V: 21_004_100},
+		{D: "2024-01-03", O: 27.15, H: 27.5, L: 26.9, C: 27.35, V: 18_540_300},
+	}
+
+	updated := time.Date(2024, 1, 3, 7, 30, 0, 0, time.UTC)
+
+	for _, encoding := range []string{BucketEncodingPlain, BucketEncodingColumnar} {
+		bucket := PriceBucket{ID: "HPG_2024", Code: "HPG", Year: 2024, History: history, Checksum: ComputeChecksum(history), Encoding: encoding, UpdatedAt: &updated}
+		data, err := bson.Marshal(bucket)
+		if err != nil {
+			t.Fatalf("bson.Marshal(%q) unexpected error: %v", encoding, err)
+		}
+
+		var stored bson.M
+		_ = bson.Unmarshal(data, &stored)
+		_, hasHistory := stored["history"]
+		_, hasPacked := stored["packed"]
+		if hasHistory == (encoding == BucketEncodingColumnar) || hasPacked != (encoding == BucketEncodingColumnar) {
+			t.Errorf("%q stored fields = %v; want history only for plain, packed only for columnar", encoding, stored)
+		}
+
+		var decoded PriceBucket
+		if err := bson.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("bson.Unmarshal(%q) unexpected error: %v", encoding, err)
+		}
+		if !reflect.DeepEqual(decoded, bucket) {
+			t.Errorf("%q round trip = %+v; want %+v", encoding, decoded, bucket)
+		}
+	}
+}
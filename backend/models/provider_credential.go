@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// credentialNamePattern restricts provider and credential names to what can
+// also be spelled as an environment variable (PROVIDER_NAME)
+var credentialNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// ProviderCredential represents the provider_credentials table in Supabase
+// One secret (API key, token, ...) of a market data provider, encrypted at
+// rest; only the last characters of the value are kept in plaintext
+type ProviderCredential struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	Provider       string     `gorm:"type:text;not null;column:provider" json:"provider"` // Data source name, e.g. "vndirect"
+	Name           string     `gorm:"type:text;not null;column:name" json:"name"`         // e.g. "api_key"
+	EncryptedValue string     `gorm:"type:text;not null;column:encrypted_value" json:"-"`
+	Hint           string     `gorm:"type:text;column:hint" json:"hint"` // Last characters of the value, for identification
+	UpdatedBy      *string    `gorm:"type:text;column:updated_by" json:"updated_by,omitempty"`
+	LastTestedAt   *time.Time `gorm:"type:timestamptz;column:last_tested_at" json:"last_tested_at,omitempty"`
+	LastTestOK     *bool      `gorm:"type:boolean;column:last_test_ok" json:"last_test_ok,omitempty"`
+	LastTestError  *string    `gorm:"type:text;column:last_test_error" json:"last_test_error,omitempty"`
+	CreatedAt      time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (ProviderCredential) TableName() string {
+	return "public.provider_credentials"
+}
+
+// ValidateCredentialKey checks a provider and credential name pair
+func ValidateCredentialKey(provider, name string) error {
+	if !credentialNamePattern.MatchString(provider) {
+		return fmt.Errorf("invalid provider %q", provider)
+	}
+	if !credentialNamePattern.MatchString(name) {
+		return fmt.Errorf("credential name must be lowercase letters, digits and underscores (got %q)", name)
+	}
+	return nil
+}
+
+// CredentialHint returns the last four characters of a secret, or nothing
+// when the secret is too short to reveal any of it
+func CredentialHint(value string) string {
+	runes := []rune(value)
+	if len(runes) < 12 {
+		return ""
+	}
+	return "…" + string(runes[len(runes)-4:])
+}
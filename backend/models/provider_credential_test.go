@@ -0,0 +1,23 @@
+package models
+
+import "testing"
+
+func TestValidateCredentialKey(t *testing.T) {
+	if err := ValidateCredentialKey("vndirect", "api_key"); err != nil {
+		t.Errorf("ValidateCredentialKey(vndirect, api_key) = %v; want nil", err)
+	}
+	for _, name := range []string{"", "API_KEY", "api-key", "1key"} {
+		if err := ValidateCredentialKey("vndirect", name); err == nil {
+			t.Errorf("ValidateCredentialKey(vndirect, %q) = nil; want an error", name)
+		}
+	}
+}
+
+func TestCredentialHint(t *testing.T) {
+	if got := CredentialHint("sk_live_0123456789abcd"); got != "…abcd" {
+		t.Errorf("CredentialHint() = %q; want …abcd", got)
+	}
+	if got := CredentialHint("short"); got != "" {
+		t.Errorf("CredentialHint(short) = %q; want nothing revealed", got)
+	}
+}
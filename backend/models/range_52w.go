@@ -0,0 +1,69 @@
+package models
+
+// Range52WSessions is how many of a symbol's newest candles cover its 52
+// weeks with room for the candle before them, which tells a full year of
+// history from a recent listing
+const Range52WSessions = 260
+
+// Range52W is a symbol's trading range over the 52 weeks up to its newest
+// candle
+type Range52W struct {
+	High            float64 `bson:"high" json:"high"`
+	HighDate        string  `bson:"highDate" json:"highDate"`
+	Low             float64 `bson:"low" json:"low"`
+	LowDate         string  `bson:"lowDate" json:"lowDate"`
+	FromHighPercent float64 `bson:"fromHighPercent" json:"fromHighPercent"` // Close vs High; 0 at the high, negative below
+	FromLowPercent  float64 `bson:"fromLowPercent" json:"fromLowPercent"`   // Close vs Low; 0 at the low, positive above
+	FullYear        bool    `bson:"fullYear" json:"fullYear"`               // The history covers the 52 weeks; a recent listing's range is since its listing
+	NewHigh         bool    `bson:"newHigh" json:"newHigh"`                 // The newest session's high topped the previous 52 weeks
+	NewLow          bool    `bson:"newLow" json:"newLow"`                   // The newest session's low went below the previous 52 weeks
+}
+
+// ComputeRange52W computes the 52-week range of a symbol from its newest
+// candles (ordered by date). New highs and lows are only reported with a
+// full year of history. It reports false without candles.
+func ComputeRange52W(candles []CandleData) (Range52W, bool) {
+	if len(candles) == 0 {
+		return Range52W{}, false
+	}
+	last := candles[len(candles)-1]
+	cutoff, ok := cutoff52Weeks(last.D)
+	if !ok {
+		return Range52W{}, false
+	}
+
+	r := Range52W{FullYear: candles[0].D <= cutoff}
+	found := false
+	for _, candle := range candles {
+		if candle.D <= cutoff {
+			continue
+		}
+		if !found || candle.H > r.High {
+			r.High, r.HighDate = candle.H, candle.D
+		}
+		if !found || (candle.L > 0 && candle.L < r.Low) {
+			r.Low, r.LowDate = candle.L, candle.D
+		}
+		found = true
+	}
+	if r.High > 0 {
+		r.FromHighPercent = round2((last.C/r.High - 1) * 100)
+	}
+	if r.Low > 0 {
+		r.FromLowPercent = round2((last.C/r.Low - 1) * 100)
+	}
+	if high, low, ok := previous52WeekRange(candles); ok {
+		r.NewHigh = last.H > high
+		r.NewLow = low > 0 && last.L < low
+	}
+	return r, true
+}
+
+// NewHighsLows are the symbols that made a new 52-week high or low on the
+// newest trading day of the stored metrics
+type NewHighsLows struct {
+	Date     string             `json:"date"`
+	Exchange string             `json:"exchange,omitempty"`
+	Highs    []LiquidityMetrics `json:"new_highs"`
+	Lows     []LiquidityMetrics `json:"new_lows"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// yearOfCandles returns a candle per week from a year and a week before end
+// to end, all trading between low and high
+func yearOfCandles(end string, low, high float64) []CandleData {
+	last, _ := time.Parse("2006-01-02", end)
+	var candles []CandleData
+	for day := last.AddDate(-1, 0, -7); day.Before(last); day = day.AddDate(0, 0, 7) {
+		candles = append(candles, CandleData{D: day.Format("2006-01-02"), H: high, L: low, C: (low + high) / 2})
+	}
+	return candles
+}
+
+func TestComputeRange52W(t *testing.T) {
+	candles := yearOfCandles("2026-03-03", 20, 30)
+	candles[0].H = 50 // More than 52 weeks ago, outside the range
+	candles = append(candles, CandleData{D: "2026-03-03", H: 31, L: 27, C: 31})
+
+	r, ok := ComputeRange52W(candles)
+	if !ok || !r.FullYear {
+		t.Fatalf("ComputeRange52W() = %+v, %v; want a full year", r, ok)
+	}
+	if r.High != 31 || r.HighDate != "2026-03-03" || r.Low != 20 || r.FromHighPercent != 0 || r.FromLowPercent != 55 {
+		t.Errorf("range = %+v; want 20..31 closing at the high", r)
+	}
+	if !r.NewHigh || r.NewLow {
+		t.Errorf("range = %+v; want a new high only", r)
+	}
+
+	candles[len(candles)-1] = CandleData{D: "2026-03-03", H: 21, L: 18, C: 19}
+	if r, _ := ComputeRange52W(candles); r.NewHigh || !r.NewLow || r.Low != 18 || r.FromHighPercent != -36.67 {
+		t.Errorf("range = %+v; want a new low 36.67%% below the high", r)
+	}
+
+	// A recent listing has a range but no new highs
+	recent := []CandleData{{D: "2026-03-02", H: 10, L: 9, C: 9.5}, {D: "2026-03-03", H: 12, L: 10, C: 12}}
+	if r, ok := ComputeRange52W(recent); !ok || r.FullYear || r.NewHigh || r.High != 12 || r.Low != 9 {
+		t.Errorf("recent listing range = %+v, %v; want 9..12 without a new high", r, ok)
+	}
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// JSON field naming styles for API responses
+const (
+	NamingAsIs  = "as_is"      // Field names as each handler emits them (mixed, pre-versioning behavior)
+	NamingSnake = "snake_case" // total_stocks, started_at
+	NamingCamel = "camelCase"  // totalStocks, startedAt
+)
+
+// Envelope modes for successful API responses
+const (
+	EnvelopeOn  = "envelope" // {"status": "success", "data": ...}
+	EnvelopeOff = "bare"     // Only the data payload
+)
+
+// ResponseFormat controls how JSON API responses are shaped for a client
+type ResponseFormat struct {
+	Naming   string `json:"naming"`
+	Envelope bool   `json:"envelope"`
+}
+
+// LegacyResponseFormat is the shape responses had before formats were selectable
+var LegacyResponseFormat = ResponseFormat{Naming: NamingAsIs, Envelope: true}
+
+// ResponseVersions maps API versions to their response format. Version 1 keeps
+// the existing mixed field names; version 2 standardizes on snake_case.
+var ResponseVersions = map[string]ResponseFormat{
+	"1": LegacyResponseFormat,
+	"2": {Naming: NamingSnake, Envelope: true},
+}
+
+// IsLegacy reports whether responses can be sent unchanged
+func (f ResponseFormat) IsLegacy() bool {
+	return f == LegacyResponseFormat
+}
+
+// String returns the format as a spec accepted by ParseResponseFormat
+func (f ResponseFormat) String() string {
+	envelope := EnvelopeOn
+	if !f.Envelope {
+		envelope = EnvelopeOff
+	}
+	return f.Naming + "," + envelope
+}
+
+// ParseResponseFormat parses a comma-separated format spec such as
+// "snake_case", "camelCase,bare" or "v2,bare". A version ("v2") selects that
+// version's format; naming and envelope tokens override parts of it. Parts
+// not mentioned keep the values of base.
+func ParseResponseFormat(spec string, base ResponseFormat) (ResponseFormat, error) {
+	format := base
+	for _, token := range SplitList(spec) {
+		switch strings.ToLower(token) {
+		case strings.ToLower(NamingAsIs):
+			format.Naming = NamingAsIs
+		case strings.ToLower(NamingSnake):
+			format.Naming = NamingSnake
+		case strings.ToLower(NamingCamel):
+			format.Naming = NamingCamel
+		case EnvelopeOn:
+			format.Envelope = true
+		case EnvelopeOff:
+			format.Envelope = false
+		default:
+			version, ok := ResponseVersions[strings.TrimPrefix(strings.ToLower(token), "v")]
+			if !ok {
+				return base, fmt.Errorf("unknown response format %q (supported: %s, %s, %s, %s, %s or a version %s)",
+					token, NamingAsIs, NamingSnake, NamingCamel, EnvelopeOn, EnvelopeOff, strings.Join(ResponseVersionNames(), ", "))
+			}
+			format = version
+		}
+	}
+	return format, nil
+}
+
+// ResponseVersionNames returns the supported API versions ("v1", "v2", ...)
+func ResponseVersionNames() []string {
+	names := make([]string, 0, len(ResponseVersions))
+	for version := range ResponseVersions {
+		names = append(names, "v"+version)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FieldName converts a JSON field name to the format's naming style. Keys
+// that are not plain identifiers in the source style (stock codes, dates,
+// keys with uppercase initials) are left alone so data-keyed maps survive.
+func (f ResponseFormat) FieldName(key string) string {
+	switch f.Naming {
+	case NamingSnake:
+		return camelToSnake(key)
+	case NamingCamel:
+		return snakeToCamel(key)
+	}
+	return key
+}
+
+// camelToSnake converts "startedAt" to "started_at"
+func camelToSnake(key string) string {
+	if !isIdentifier(key, false) {
+		return key
+	}
+	var b strings.Builder
+	for i, r := range key {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// snakeToCamel converts "started_at" to "startedAt"
+func snakeToCamel(key string) string {
+	if !isIdentifier(key, true) || !strings.Contains(key, "_") {
+		return key
+	}
+	var b strings.Builder
+	upper := false
+	for _, r := range key {
+		if r == '_' {
+			upper = b.Len() > 0
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isIdentifier reports whether key starts with a lowercase letter and
+// contains only letters and digits (and underscores when allowed, in which
+// case letters must be lowercase)
+func isIdentifier(key string, underscores bool) bool {
+	for i, r := range key {
+		switch {
+		case i == 0 && !unicode.IsLower(r):
+			return false
+		case r == '_' && underscores:
+		case unicode.IsUpper(r) && underscores:
+			return false
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			return false
+		}
+	}
+	return key != ""
+}
@@ -0,0 +1,59 @@
+package models
+
+import "testing"
+
+func TestParseResponseFormat(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected ResponseFormat
+		wantErr  bool
+	}{
+		{"", LegacyResponseFormat, false},
+		{"snake_case", ResponseFormat{Naming: NamingSnake, Envelope: true}, false},
+		{"camelcase,bare", ResponseFormat{Naming: NamingCamel, Envelope: false}, false},
+		{"v2", ResponseFormat{Naming: NamingSnake, Envelope: true}, false},
+		{"v2, bare", ResponseFormat{Naming: NamingSnake, Envelope: false}, false},
+		{"kebab-case", LegacyResponseFormat, true},
+		{"v9", LegacyResponseFormat, true},
+	}
+
+	for _, tt := range tests {
+		format, err := ParseResponseFormat(tt.spec, LegacyResponseFormat)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseResponseFormat(%q) error = %v; wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if format != tt.expected {
+			t.Errorf("ParseResponseFormat(%q) = %+v; want %+v", tt.spec, format, tt.expected)
+		}
+	}
+}
+
+func TestResponseFormatFieldName(t *testing.T) {
+	snake := ResponseFormat{Naming: NamingSnake}
+	camel := ResponseFormat{Naming: NamingCamel}
+
+	tests := []struct {
+		format   ResponseFormat
+		key      string
+		expected string
+	}{
+		{snake, "startedAt", "started_at"},
+		{snake, "totalSymbols", "total_symbols"},
+		{snake, "total_stocks", "total_stocks"},
+		{snake, "VNM", "VNM"},
+		{snake, "2024-01-15", "2024-01-15"},
+		{snake, "_id", "_id"},
+		{camel, "total_stocks", "totalStocks"},
+		{camel, "startedAt", "startedAt"},
+		{camel, "retry_run_id", "retryRunId"},
+		{camel, "VN30", "VN30"},
+		{LegacyResponseFormat, "startedAt", "startedAt"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.format.FieldName(tt.key); got != tt.expected {
+			t.Errorf("%s FieldName(%q) = %q; want %q", tt.format.Naming, tt.key, got, tt.expected)
+		}
+	}
+}
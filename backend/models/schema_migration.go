@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// SchemaMigration records a versioned SQL migration applied to the database
+type SchemaMigration struct {
+	Version   string    `gorm:"primaryKey" json:"version"` // Date prefix of the file name, e.g. "20260212"
+	Name      string    `json:"name"`                      // File name without version and extension
+	Checksum  string    `json:"checksum"`                  // SHA-256 of the file when applied
+	Baseline  bool      `json:"baseline"`                  // Recorded as applied without running it
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// TableName specifies the table name for GORM
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
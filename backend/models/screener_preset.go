@@ -0,0 +1,84 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxScreenerPresetsPerProfile caps the presets one member can keep
+	MaxScreenerPresetsPerProfile = 20
+	// maxScreenerPresetName caps the length of a preset's name
+	maxScreenerPresetName = 100
+)
+
+// ScreenerPreset represents the screener_presets table in Supabase
+// A named screen (criteria and sort) saved by a member, or shared with all
+// members by an admin when ProfileID is nil. With Notify, the member is told
+// when symbols start matching after a crawl.
+type ScreenerPreset struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	ProfileID    *uuid.UUID `gorm:"type:uuid;column:profile_id" json:"profile_id,omitempty"` // Nil for shared presets
+	Name         string     `gorm:"type:text;not null;column:name" json:"name"`
+	Criteria     string     `gorm:"type:text;not null;column:criteria" json:"criteria"` // Screener query string, e.g. "exchange=HOSE&sort=-relative_volume"
+	Notify       bool       `gorm:"type:boolean;default:false;column:notify" json:"notify"`
+	Channels     string     `gorm:"type:text;not null;default:'';column:channels" json:"channels"` // Comma-separated notification channels
+	MatchedCodes *string    `gorm:"type:text;column:matched_codes" json:"matched_codes,omitempty"` // Comma-separated matches of the last evaluation; nil before the first
+	LastRunAt    *time.Time `gorm:"type:timestamptz;column:last_run_at" json:"last_run_at,omitempty"`
+	CreatedAt    time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt    time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (ScreenerPreset) TableName() string {
+	return "public.screener_presets"
+}
+
+// Shared reports whether the preset is an admin's, visible to all members
+func (p ScreenerPreset) Shared() bool {
+	return p.ProfileID == nil
+}
+
+// ChannelList returns the preset's notification channels as a slice
+func (p ScreenerPreset) ChannelList() []string {
+	return SplitList(p.Channels)
+}
+
+// Validate checks the name and that only members' presets notify
+func (p ScreenerPreset) Validate() error {
+	name := strings.TrimSpace(p.Name)
+	if name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(name) > maxScreenerPresetName {
+		return fmt.Errorf("name must be at most %d characters", maxScreenerPresetName)
+	}
+	if p.Notify && p.Shared() {
+		return fmt.Errorf("shared presets cannot notify")
+	}
+	if p.Notify && len(p.ChannelList()) == 0 {
+		return fmt.Errorf("notify requires at least one channel")
+	}
+	return nil
+}
+
+// NewMatches returns the codes that match now but did not at the previous
+// evaluation, in the order of codes
+func (p ScreenerPreset) NewMatches(codes []string) []string {
+	previous := make(map[string]bool)
+	if p.MatchedCodes != nil {
+		for _, code := range SplitList(*p.MatchedCodes) {
+			previous[code] = true
+		}
+	}
+	added := make([]string, 0)
+	for _, code := range codes {
+		if !previous[code] {
+			added = append(added, code)
+		}
+	}
+	return added
+}
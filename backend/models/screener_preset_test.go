@@ -0,0 +1,42 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestScreenerPresetValidate(t *testing.T) {
+	owner := uuid.New()
+	tests := []struct {
+		name   string
+		preset ScreenerPreset
+		valid  bool
+	}{
+		{"member preset", ScreenerPreset{ProfileID: &owner, Name: "Breakouts"}, true},
+		{"notifying member preset", ScreenerPreset{ProfileID: &owner, Name: "Breakouts", Notify: true, Channels: "telegram"}, true},
+		{"shared preset", ScreenerPreset{Name: "Liquid banks"}, true},
+		{"missing name", ScreenerPreset{ProfileID: &owner, Name: "  "}, false},
+		{"notify without channels", ScreenerPreset{ProfileID: &owner, Name: "Breakouts", Notify: true}, false},
+		{"notifying shared preset", ScreenerPreset{Name: "Liquid banks", Notify: true, Channels: "telegram"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.preset.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v; want valid = %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestScreenerPresetNewMatches(t *testing.T) {
+	preset := ScreenerPreset{}
+	if got := preset.NewMatches([]string{"HPG", "VNM"}); !reflect.DeepEqual(got, []string{"HPG", "VNM"}) {
+		t.Errorf("NewMatches() before any evaluation = %v; want all", got)
+	}
+
+	matched := "HPG,FPT"
+	preset.MatchedCodes = &matched
+	if got := preset.NewMatches([]string{"VNM", "HPG", "MWG"}); !reflect.DeepEqual(got, []string{"VNM", "MWG"}) {
+		t.Errorf("NewMatches() = %v; want [VNM MWG]", got)
+	}
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"math"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BreadthMAPeriod is the moving average members are compared with
+const BreadthMAPeriod = 50
+
+// SectorBreadth is the share of a sector's members that rose, fell and
+// closed above their moving average on one trading day
+type SectorBreadth struct {
+	Sector           string  `bson:"sector" json:"sector"`
+	Members          int     `bson:"members" json:"members"` // Members that traded on the date
+	Advancing        int     `bson:"advancing" json:"advancing"`
+	Declining        int     `bson:"declining" json:"declining"`
+	Unchanged        int     `bson:"unchanged" json:"unchanged"`
+	AdvancingPercent float64 `bson:"advancingPercent" json:"advancingPercent"`
+	DecliningPercent float64 `bson:"decliningPercent" json:"decliningPercent"`
+	MAMembers        int     `bson:"maMembers" json:"maMembers"` // Members with BreadthMAPeriod sessions of history
+	AboveMA          int     `bson:"aboveMa50" json:"aboveMa50"`
+	AboveMAPercent   float64 `bson:"aboveMa50Percent" json:"aboveMa50Percent"` // Of MAMembers
+	// Cap-weighted aggregates over the members with a known share count
+	CapMembers               int     `bson:"capMembers" json:"capMembers"`
+	MarketCap                float64 `bson:"marketCap" json:"marketCap"`                               // Total at the date's close
+	CapWeightedChangePercent float64 `bson:"capWeightedChangePercent" json:"capWeightedChangePercent"` // Change of the total against the previous close
+}
+
+// SectorBreadthSnapshot is the breadth of every sector on one trading day,
+// stored in the sector_breadth collection
+type SectorBreadthSnapshot struct {
+	Date       string             `bson:"_id" json:"date"`
+	Sectors    []SectorBreadth    `bson:"sectors" json:"sectors"` // By sector name
+	ComputedAt primitive.DateTime `bson:"computedAt" json:"computedAt"`
+}
+
+// ComputeSectorBreadth computes the breadth of each sector on date from the
+// recent candles (ordered by date) of its members, weighting the change by
+// market cap for the members in shares (shares outstanding by code). Members
+// without a candle on date or the one before it are left out.
+func ComputeSectorBreadth(date string, sectors map[string]string, shares map[string]int64, candles map[string][]CandleData) []SectorBreadth {
+	bySector := make(map[string]*SectorBreadth)
+	previousCaps := make(map[string]float64)
+	for code, sector := range sectors {
+		history := candles[code]
+		if sector == "" || len(history) < 2 || history[len(history)-1].D != date {
+			continue
+		}
+		breadth, ok := bySector[sector]
+		if !ok {
+			breadth = &SectorBreadth{Sector: sector}
+			bySector[sector] = breadth
+		}
+
+		last, previous := history[len(history)-1], history[len(history)-2]
+		breadth.Members++
+		switch {
+		case last.C > previous.C:
+			breadth.Advancing++
+		case last.C < previous.C:
+			breadth.Declining++
+		default:
+			breadth.Unchanged++
+		}
+		if lastCap, previousCap := MarketCap(last.C, shares[code]), MarketCap(previous.C, shares[code]); lastCap > 0 && previousCap > 0 {
+			breadth.CapMembers++
+			breadth.MarketCap += lastCap
+			previousCaps[sector] += previousCap
+		}
+		if len(history) >= BreadthMAPeriod {
+			breadth.MAMembers++
+			if last.C > closeSMA(history, BreadthMAPeriod, 0) {
+				breadth.AboveMA++
+			}
+		}
+	}
+
+	result := make([]SectorBreadth, 0, len(bySector))
+	for _, breadth := range bySector {
+		breadth.AdvancingPercent = percentOf(breadth.Advancing, breadth.Members)
+		breadth.DecliningPercent = percentOf(breadth.Declining, breadth.Members)
+		breadth.AboveMAPercent = percentOf(breadth.AboveMA, breadth.MAMembers)
+		if previous := previousCaps[breadth.Sector]; previous > 0 {
+			breadth.MarketCap = math.Round(breadth.MarketCap*100) / 100
+			breadth.CapWeightedChangePercent = math.Round((breadth.MarketCap/previous-1)*10000) / 100
+		}
+		result = append(result, *breadth)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Sector < result[j].Sector })
+	return result
+}
+
+// percentOf returns part as a percentage of total, rounded to 2 decimals
+func percentOf(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)*10000/float64(total)) / 100
+}
@@ -0,0 +1,57 @@
+package models
+
+import "testing"
+
+func TestComputeSectorBreadth(t *testing.T) {
+	flat := make([]float64, BreadthMAPeriod)
+	for i := range flat {
+		flat[i] = 10
+	}
+	up := append(append([]float64{}, flat...), 12)  // Above its MA50
+	down := append(append([]float64{}, flat...), 9) // Below its MA50
+	sectors := map[string]string{
+		"HPG": "Basic Resources",
+		"HSG": "Basic Resources",
+		"NKG": "Basic Resources",
+		"VCB": "Banks",
+		"NEW": "Banks",
+		"OLD": "Banks",
+		"XYZ": "",
+	}
+	candles := map[string][]CandleData{
+		"HPG": dailyCandles("2026-02-10", up),
+		"HSG": dailyCandles("2026-02-10", down),
+		"NKG": dailyCandles("2026-02-10", flat),
+		"VCB": dailyCandles("2026-02-10", up),
+		"NEW": dailyCandles("2026-02-10", []float64{10, 11}), // Too short for the MA
+		"OLD": dailyCandles("2026-02-09", up),                // Did not trade on the date
+		"XYZ": dailyCandles("2026-02-10", up),                // Unclassified
+	}
+
+	shares := map[string]int64{"HPG": 100, "HSG": 300, "VCB": 50}
+
+	breadth := ComputeSectorBreadth("2026-02-10", sectors, shares, candles)
+	if len(breadth) != 2 || breadth[0].Sector != "Banks" || breadth[1].Sector != "Basic Resources" {
+		t.Fatalf("sectors = %+v; want Banks and Basic Resources", breadth)
+	}
+
+	banks := breadth[0]
+	if banks.Members != 2 || banks.Advancing != 2 || banks.MAMembers != 1 || banks.AboveMA != 1 || banks.AboveMAPercent != 100 {
+		t.Errorf("Banks = %+v", banks)
+	}
+	resources := breadth[1]
+	if resources.Members != 3 || resources.Advancing != 1 || resources.Declining != 1 || resources.Unchanged != 1 {
+		t.Errorf("Basic Resources = %+v", resources)
+	}
+	if resources.AdvancingPercent != 33.33 || resources.AboveMAPercent != 33.33 {
+		t.Errorf("Basic Resources percentages = %v, %v; want 33.33", resources.AdvancingPercent, resources.AboveMAPercent)
+	}
+	// HPG 100 × 10 → 12 and HSG 300 × 10 → 9: 4000 → 3900
+	if resources.CapMembers != 2 || resources.MarketCap != 3900 || resources.CapWeightedChangePercent != -2.5 {
+		t.Errorf("Basic Resources cap-weighted = %d members, %v, %v%%; want 2, 3900, -2.5%%",
+			resources.CapMembers, resources.MarketCap, resources.CapWeightedChangePercent)
+	}
+	if banks.CapMembers != 1 || banks.CapWeightedChangePercent != 20 {
+		t.Errorf("Banks cap-weighted = %d members, %v%%; want VCB alone at +20%%", banks.CapMembers, banks.CapWeightedChangePercent)
+	}
+}
@@ -0,0 +1,188 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Signal types
+const (
+	SignalGoldenCross = "golden_cross"      // Fast SMA of the close crossed above the slow SMA
+	SignalBreakout52W = "breakout_52w_high" // Close above the highest high of the previous 52 weeks
+	SignalVolumeSpike = "volume_spike"      // Volume a multiple of its average over VolumeSpikeWindow sessions
+	SignalPattern     = "candle_pattern"    // The candle completed one or more candlestick patterns
+)
+
+// SignalTypes lists every signal type, in display order
+var SignalTypes = []string{SignalGoldenCross, SignalBreakout52W, SignalVolumeSpike, SignalPattern}
+
+// VolumeSpikeWindow is how many previous sessions the volume of a spike is compared with
+const VolumeSpikeWindow = 20
+
+// ValidSignalType reports whether name is a known signal type
+func ValidSignalType(name string) bool {
+	for _, signalType := range SignalTypes {
+		if signalType == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SignalConfig selects the signals computed after each crawl and their parameters
+type SignalConfig struct {
+	Types          []string `json:"types"`
+	FastMA         int      `json:"fast_ma"` // Sessions of the fast SMA of a golden cross
+	SlowMA         int      `json:"slow_ma"` // Sessions of the slow SMA of a golden cross
+	VolumeMultiple float64  `json:"volume_multiple"`
+}
+
+// Enabled reports whether a signal type is computed
+func (c SignalConfig) Enabled(signalType string) bool {
+	for _, enabled := range c.Types {
+		if enabled == signalType {
+			return true
+		}
+	}
+	return false
+}
+
+// Signal is one signal fired by a symbol's daily candle. One document per
+// symbol, date and type in the signals collection.
+type Signal struct {
+	ID        string             `bson:"_id" json:"id"` // Format: "{CODE}_{DATE}_{TYPE}"
+	Code      string             `bson:"code" json:"code"`
+	Date      string             `bson:"date" json:"date"` // Date of the candle that fired it
+	Type      string             `bson:"type" json:"type"`
+	Close     float64            `bson:"close" json:"close"`
+	Value     float64            `bson:"value" json:"value"`                           // Fast SMA, close or volume
+	Reference float64            `bson:"reference" json:"reference"`                   // What Value crossed: slow SMA, previous 52-week high or average volume
+	Patterns  []string           `bson:"patterns,omitempty" json:"patterns,omitempty"` // Of a candle_pattern signal
+	CreatedAt primitive.DateTime `bson:"createdAt" json:"createdAt"`
+}
+
+// NewSignal creates a signal with its ID
+func NewSignal(code, date, signalType string, closePrice, value, reference float64, createdAt primitive.DateTime) Signal {
+	return Signal{
+		ID:        code + "_" + date + "_" + signalType,
+		Code:      code,
+		Date:      date,
+		Type:      signalType,
+		Close:     closePrice,
+		Value:     value,
+		Reference: reference,
+		CreatedAt: createdAt,
+	}
+}
+
+// DetectSignals returns the signals fired by the last of the candles
+// (ordered by date) under cfg. Signals needing more history than is
+// available are not reported.
+func DetectSignals(code string, candles []CandleData, cfg SignalConfig, createdAt primitive.DateTime) []Signal {
+	signals := make([]Signal, 0)
+	if len(candles) == 0 {
+		return signals
+	}
+	last := candles[len(candles)-1]
+
+	if cfg.Enabled(SignalGoldenCross) && cfg.FastMA > 0 && cfg.SlowMA > cfg.FastMA && len(candles) > cfg.SlowMA {
+		fast, slow := closeSMA(candles, cfg.FastMA, 0), closeSMA(candles, cfg.SlowMA, 0)
+		prevFast, prevSlow := closeSMA(candles, cfg.FastMA, 1), closeSMA(candles, cfg.SlowMA, 1)
+		if prevFast <= prevSlow && fast > slow {
+			signals = append(signals, NewSignal(code, last.D, SignalGoldenCross, last.C, fast, slow, createdAt))
+		}
+	}
+
+	if cfg.Enabled(SignalBreakout52W) {
+		if high, ok := previous52WeekHigh(candles); ok && last.C > high {
+			signals = append(signals, NewSignal(code, last.D, SignalBreakout52W, last.C, last.C, high, createdAt))
+		}
+	}
+
+	if cfg.Enabled(SignalVolumeSpike) && cfg.VolumeMultiple > 0 {
+		if average, ok := previousAverageVolume(candles, VolumeSpikeWindow); ok && average > 0 && float64(last.V) >= cfg.VolumeMultiple*average {
+			signals = append(signals, NewSignal(code, last.D, SignalVolumeSpike, last.C, float64(last.V), average, createdAt))
+		}
+	}
+
+	if cfg.Enabled(SignalPattern) {
+		if patterns := DetectCandlePatterns(candles, len(candles)-1); len(patterns) > 0 {
+			signal := NewSignal(code, last.D, SignalPattern, last.C, last.C, 0, createdAt)
+			signal.Patterns = patterns
+			signals = append(signals, signal)
+		}
+	}
+
+	return signals
+}
+
+// previousAverageVolume returns the average volume of the window sessions
+// before the last candle, or false with fewer
+func previousAverageVolume(candles []CandleData, window int) (float64, bool) {
+	if len(candles) <= window {
+		return 0, false
+	}
+	var total int64
+	for _, candle := range candles[len(candles)-1-window : len(candles)-1] {
+		total += candle.V
+	}
+	return float64(total) / float64(window), true
+}
+
+// closeSMA returns the simple moving average of the close over period
+// sessions, ending skip sessions before the last candle
+func closeSMA(candles []CandleData, period, skip int) float64 {
+	end := len(candles) - skip
+	var sum float64
+	for _, candle := range candles[end-period : end] {
+		sum += candle.C
+	}
+	return sum / float64(period)
+}
+
+// previous52WeekHigh returns the highest high of the 52 weeks before the
+// last candle. It needs candles from at least 52 weeks before, so a recent
+// listing has no 52-week high yet.
+func previous52WeekHigh(candles []CandleData) (float64, bool) {
+	high, _, ok := previous52WeekRange(candles)
+	return high, ok
+}
+
+// previous52WeekRange returns the highest high and lowest low of the 52
+// weeks before the last candle, with the same history requirement as
+// previous52WeekHigh
+func previous52WeekRange(candles []CandleData) (float64, float64, bool) {
+	if len(candles) < 2 {
+		return 0, 0, false
+	}
+	cutoff, ok := cutoff52Weeks(candles[len(candles)-1].D)
+	if !ok || candles[0].D > cutoff {
+		return 0, 0, false
+	}
+
+	high, low, found := 0.0, 0.0, false
+	for _, candle := range candles[:len(candles)-1] {
+		if candle.D <= cutoff {
+			continue
+		}
+		if !found || candle.H > high {
+			high = candle.H
+		}
+		if !found || candle.L < low {
+			low = candle.L
+		}
+		found = true
+	}
+	return high, low, found
+}
+
+// cutoff52Weeks returns the date a year before date: candles after it are
+// within its 52 weeks
+func cutoff52Weeks(date string) (string, bool) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return "", false
+	}
+	return day.AddDate(-1, 0, 0).Format("2006-01-02"), true
+}
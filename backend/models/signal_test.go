@@ -0,0 +1,115 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// dailyCandles returns candles on consecutive days ending on end, with the
+// closes given (oldest first); high equals close and volume is 1000
+func dailyCandles(end string, closes []float64) []CandleData {
+	last, _ := time.Parse("2006-01-02", end)
+	candles := make([]CandleData, len(closes))
+	for i, c := range closes {
+		date := last.AddDate(0, 0, i-len(closes)+1).Format("2006-01-02")
+		candles[i] = CandleData{D: date, O: c, H: c, L: c, C: c, V: 1000}
+	}
+	return candles
+}
+
+func signalTypes(signals []Signal) []string {
+	types := make([]string, len(signals))
+	for i, signal := range signals {
+		types[i] = signal.Type
+	}
+	return types
+}
+
+func TestDetectSignalsGoldenCross(t *testing.T) {
+	cfg := SignalConfig{Types: []string{SignalGoldenCross}, FastMA: 2, SlowMA: 4}
+
+	// Fast SMA 10 <= slow 10 on the previous day, 13 > 11.5 on the last
+	candles := dailyCandles("2026-02-10", []float64{10, 10, 10, 10, 16})
+	signals := DetectSignals("HPG", candles, cfg, 0)
+	if len(signals) != 1 || signals[0].Type != SignalGoldenCross {
+		t.Fatalf("signals = %v; want a golden cross", signalTypes(signals))
+	}
+	if signals[0].ID != "HPG_2026-02-10_golden_cross" || signals[0].Value != 13 || signals[0].Reference != 11.5 {
+		t.Errorf("signal = %+v", signals[0])
+	}
+
+	// Already above: no new cross
+	candles = dailyCandles("2026-02-10", []float64{10, 10, 10, 14, 16})
+	if signals := DetectSignals("HPG", candles, cfg, 0); len(signals) != 0 {
+		t.Errorf("signals = %v; want none once the fast SMA is already above", signalTypes(signals))
+	}
+
+	// Too little history for the slow SMA of the previous day
+	if signals := DetectSignals("HPG", candles[1:], cfg, 0); len(signals) != 0 {
+		t.Errorf("signals = %v; want none without enough history", signalTypes(signals))
+	}
+}
+
+func TestDetectSignalsBreakout(t *testing.T) {
+	cfg := SignalConfig{Types: []string{SignalBreakout52W}}
+	closes := make([]float64, 400)
+	for i := range closes {
+		closes[i] = 20
+	}
+	closes[10] = 50 // More than a year before the last candle: outside the window
+	closes[200] = 30
+	closes[399] = 31
+
+	signals := DetectSignals("HPG", dailyCandles("2026-02-10", closes), cfg, 0)
+	if len(signals) != 1 || signals[0].Reference != 30 {
+		t.Fatalf("signals = %+v; want a breakout above 30", signals)
+	}
+
+	closes[399] = 30
+	if signals := DetectSignals("HPG", dailyCandles("2026-02-10", closes), cfg, 0); len(signals) != 0 {
+		t.Errorf("signals = %v; want none when the close only equals the high", signalTypes(signals))
+	}
+
+	// Listed for less than a year
+	closes[399] = 31
+	if signals := DetectSignals("HPG", dailyCandles("2026-02-10", closes[200:]), cfg, 0); len(signals) != 0 {
+		t.Errorf("signals = %v; want none for a recent listing", signalTypes(signals))
+	}
+}
+
+func TestDetectSignalsVolumeSpike(t *testing.T) {
+	cfg := SignalConfig{Types: []string{SignalVolumeSpike}, VolumeMultiple: 3}
+	candles := dailyCandles("2026-02-10", make([]float64, VolumeSpikeWindow+1))
+	candles[len(candles)-1].V = 3000
+
+	signals := DetectSignals("HPG", candles, cfg, 0)
+	if len(signals) != 1 || signals[0].Value != 3000 || signals[0].Reference != 1000 {
+		t.Fatalf("signals = %+v; want a volume spike of 3000 over 1000", signals)
+	}
+
+	candles[len(candles)-1].V = 2999
+	if signals := DetectSignals("HPG", candles, cfg, 0); len(signals) != 0 {
+		t.Errorf("signals = %v; want none below the multiple", signalTypes(signals))
+	}
+
+	cfg.Types = nil
+	candles[len(candles)-1].V = 9000
+	if signals := DetectSignals("HPG", candles, cfg, 0); len(signals) != 0 {
+		t.Errorf("signals = %v; want none when the type is disabled", signalTypes(signals))
+	}
+}
+
+func TestDetectSignalsPattern(t *testing.T) {
+	cfg := SignalConfig{Types: []string{SignalPattern}}
+	candles := []CandleData{
+		{D: "2026-02-09", O: 10, H: 10.2, L: 9, C: 9.2},
+		{D: "2026-02-10", O: 9, H: 10.6, L: 8.9, C: 10.5},
+	}
+	signals := DetectSignals("HPG", candles, cfg, 0)
+	if len(signals) != 1 || signals[0].ID != "HPG_2026-02-10_candle_pattern" || len(signals[0].Patterns) != 1 || signals[0].Patterns[0] != PatternBullishEngulfing {
+		t.Fatalf("signals = %+v; want a bullish engulfing pattern", signals)
+	}
+	if signals := DetectSignals("HPG", candles[:1], cfg, 0); len(signals) != 0 {
+		t.Errorf("signals = %+v; want none without a pattern", signals)
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// SparklineLength is how many daily closes a sparkline keeps
+const SparklineLength = 30
+
+// Sparkline is the tiny per-symbol series of recent closes drawn in mobile
+// watchlists. One document per symbol, rebuilt after each crawl that stores
+// new candles for it.
+type Sparkline struct {
+	Code      string             `bson:"_id" json:"code"`
+	From      string             `bson:"from" json:"from"`     // Date of the first close
+	To        string             `bson:"to" json:"to"`         // Date of the last close
+	Closes    []float64          `bson:"closes" json:"closes"` // Oldest first, at most SparklineLength
+	UpdatedAt primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+}
+
+// NewSparkline keeps the last SparklineLength closes of candles ordered by
+// date, or returns nil when there are none
+func NewSparkline(code string, candles []CandleData, updatedAt primitive.DateTime) *Sparkline {
+	if len(candles) == 0 {
+		return nil
+	}
+	if len(candles) > SparklineLength {
+		candles = candles[len(candles)-SparklineLength:]
+	}
+	closes := make([]float64, len(candles))
+	for i, candle := range candles {
+		closes[i] = candle.C
+	}
+	return &Sparkline{
+		Code:      code,
+		From:      candles[0].D,
+		To:        candles[len(candles)-1].D,
+		Closes:    closes,
+		UpdatedAt: updatedAt,
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewSparkline(t *testing.T) {
+	candles := make([]CandleData, 45)
+	for i := range candles {
+		candles[i] = CandleData{D: fmt.Sprintf("2026-01-%02d", i%28+1), C: float64(i)}
+	}
+
+	sparkline := NewSparkline("HPG", candles, 0)
+	if len(sparkline.Closes) != SparklineLength {
+		t.Fatalf("len(Closes) = %d; want %d", len(sparkline.Closes), SparklineLength)
+	}
+	if sparkline.Closes[0] != 15 || sparkline.Closes[SparklineLength-1] != 44 {
+		t.Errorf("Closes = %v; want the last %d closes, oldest first", sparkline.Closes, SparklineLength)
+	}
+	if sparkline.From != candles[15].D || sparkline.To != candles[44].D {
+		t.Errorf("range = %s..%s; want %s..%s", sparkline.From, sparkline.To, candles[15].D, candles[44].D)
+	}
+
+	if NewSparkline("HPG", nil, 0) != nil {
+		t.Error("NewSparkline(no candles) != nil")
+	}
+}
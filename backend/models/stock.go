@@ -1,17 +1,90 @@
 package models
 
 import (
+	"strings"
+	"unicode"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Stock represents a stock/company information
 type Stock struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
-	Code        string             `bson:"code" json:"code"`               // Stock code (e.g., "HPG")
-	CompanyName string             `bson:"companyName" json:"companyName"` // Company name
-	Exchange    string             `bson:"exchange" json:"exchange"`       // HOSE, HNX, UPCOM
-	Type        string             `bson:"type" json:"type"`               // stock, bond, etc.
-	Status      string             `bson:"status" json:"status"`           // listed, delisted, etc.
-	CreatedAt   primitive.DateTime `bson:"createdAt" json:"createdAt"`
-	UpdatedAt   primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Code              string             `bson:"code" json:"code"`                                               // Stock code (e.g., "HPG")
+	CompanyName       string             `bson:"companyName" json:"companyName"`                                 // Company name (Vietnamese)
+	CompanyNameEn     string             `bson:"companyNameEn,omitempty" json:"companyNameEn,omitempty"`         // English company name; empty when the provider has none
+	Exchange          string             `bson:"exchange" json:"exchange"`                                       // HOSE, HNX, UPCOM
+	Type              string             `bson:"type" json:"type"`                                               // An InstrumentTypes entry
+	Status            string             `bson:"status" json:"status"`                                           // listed, delisted, etc.
+	Industry          string             `bson:"industry,omitempty" json:"industry,omitempty"`                   // ICB industry (level 1); empty when unclassified
+	Sector            string             `bson:"sector,omitempty" json:"sector,omitempty"`                       // ICB sector (level 2); empty when unclassified
+	ICBCode           string             `bson:"icbCode,omitempty" json:"icbCode,omitempty"`                     // ICB code of the sector (e.g. "1700")
+	SharesOutstanding int64              `bson:"sharesOutstanding,omitempty" json:"sharesOutstanding,omitempty"` // Listed shares; 0 when unknown
+	Underlying        string             `bson:"underlying,omitempty" json:"underlying,omitempty"`               // Covered warrant: underlying stock; ETF: tracked index
+	Issuer            string             `bson:"issuer,omitempty" json:"issuer,omitempty"`                       // Covered warrant or bond issuer, ETF fund manager
+	ExercisePrice     float64            `bson:"exercisePrice,omitempty" json:"exercisePrice,omitempty"`         // Covered warrant, in thousands of đồng
+	ExerciseRatio     string             `bson:"exerciseRatio,omitempty" json:"exerciseRatio,omitempty"`         // Covered warrant, warrants per underlying share (e.g. "2:1")
+	MaturityDate      string             `bson:"maturityDate,omitempty" json:"maturityDate,omitempty"`           // Covered warrant or bond (YYYY-MM-DD)
+	CouponRate        float64            `bson:"couponRate,omitempty" json:"couponRate,omitempty"`               // Bond, percent a year
+	SearchNames       []string           `bson:"searchNames,omitempty" json:"-"`                                 // Folded company names matched by stock search (see FoldName)
+	CreatedAt         primitive.DateTime `bson:"createdAt" json:"createdAt"`
+	UpdatedAt         primitive.DateTime `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Instrument types of listed symbols
+const (
+	InstrumentStock          = "stock"
+	InstrumentETF            = "etf"
+	InstrumentCoveredWarrant = "cw"
+	InstrumentBond           = "bond"
+)
+
+// InstrumentTypes lists every instrument type, in display order
+var InstrumentTypes = []string{InstrumentStock, InstrumentETF, InstrumentCoveredWarrant, InstrumentBond}
+
+// ValidInstrumentType reports whether name is a known instrument type
+func ValidInstrumentType(name string) bool {
+	for _, instrumentType := range InstrumentTypes {
+		if instrumentType == name {
+			return true
+		}
+	}
+	return false
+}
+
+// IndustryClassification is the ICB classification of a symbol
+type IndustryClassification struct {
+	Industry string `json:"industry,omitempty"` // Level 1, e.g. "Basic Materials"
+	Sector   string `json:"sector"`             // Level 2, e.g. "Basic Resources"
+	ICBCode  string `json:"icbCode,omitempty"`  // Code of the level 2 sector
+}
+
+// FoldName lower-cases a name, strips Vietnamese diacritics (đ becomes d)
+// and collapses whitespace, so "Tập đoàn Hòa Phát" and "tap doan hoa phat"
+// fold to the same string
+func FoldName(name string) string {
+	fold := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(fold, name)
+	if err != nil {
+		folded = name
+	}
+	folded = strings.NewReplacer("đ", "d", "Đ", "d").Replace(strings.ToLower(folded))
+	return strings.Join(strings.Fields(folded), " ")
+}
+
+// StockSearchNames returns the distinct folded Vietnamese and English
+// company names of a stock
+func StockSearchNames(companyName, companyNameEn string) []string {
+	names := make([]string, 0, 2)
+	for _, name := range []string{companyName, companyNameEn} {
+		folded := FoldName(name)
+		if folded == "" || (len(names) > 0 && names[0] == folded) {
+			continue
+		}
+		names = append(names, folded)
+	}
+	return names
 }
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// StockCandle represents the stock_candles table in Supabase
+// One daily candle of a stock or market index per row: the Postgres layout
+// of the candles MongoDB keeps in yearly PriceBuckets.
+type StockCandle struct {
+	Code      string    `gorm:"type:text;primaryKey;column:code"`
+	Date      time.Time `gorm:"type:date;primaryKey;column:date"`
+	Open      float64   `gorm:"type:double precision;not null;column:open"`
+	High      float64   `gorm:"type:double precision;not null;column:high"`
+	Low       float64   `gorm:"type:double precision;not null;column:low"`
+	Close     float64   `gorm:"type:double precision;not null;column:close"`
+	Volume    int64     `gorm:"type:bigint;not null;column:volume"`
+	CreatedAt time.Time `gorm:"type:timestamptz;default:now();column:created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamptz;default:now();column:updated_at"` // Set when the session candle is replaced
+}
+
+// TableName specifies the table name for GORM
+func (StockCandle) TableName() string {
+	return "public.stock_candles"
+}
+
+// NewStockCandle returns the row of candle for code. The candle's date must
+// be valid.
+func NewStockCandle(code string, candle CandleData) (StockCandle, error) {
+	date, err := time.Parse("2006-01-02", candle.D)
+	if err != nil {
+		return StockCandle{}, err
+	}
+	return StockCandle{
+		Code:   code,
+		Date:   date,
+		Open:   candle.O,
+		High:   candle.H,
+		Low:    candle.L,
+		Close:  candle.C,
+		Volume: candle.V,
+	}, nil
+}
+
+// Candle returns the row as a CandleData
+func (c StockCandle) Candle() CandleData {
+	return CandleData{
+		D: c.Date.Format("2006-01-02"),
+		O: c.Open,
+		H: c.High,
+		L: c.Low,
+		C: c.Close,
+		V: c.Volume,
+	}
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFoldName(t *testing.T) {
+	tests := map[string]string{
+		"Tập đoàn Hòa Phát":                "tap doan hoa phat",
+		"  CTCP   Đầu tư Thế Giới Di Động": "ctcp dau tu the gioi di dong",
+		"Hoa Phat Group JSC":               "hoa phat group jsc",
+		"":                                 "",
+	}
+	for name, want := range tests {
+		if got := FoldName(name); got != want {
+			t.Errorf("FoldName(%q) = %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestStockSearchNames(t *testing.T) {
+	tests := []struct {
+		vi, en string
+		want   []string
+	}{
+		{"Công ty Cổ phần Tập đoàn Hòa Phát", "Hoa Phat Group Joint Stock Company", []string{"cong ty co phan tap doan hoa phat", "hoa phat group joint stock company"}},
+		{"Vinamilk", "VINAMILK", []string{"vinamilk"}},
+		{"Ngân hàng Á Châu", "", []string{"ngan hang a chau"}},
+		{"", "", []string{}},
+	}
+	for _, tt := range tests {
+		if got := StockSearchNames(tt.vi, tt.en); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("StockSearchNames(%q, %q) = %v; want %v", tt.vi, tt.en, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Symbol change types
+const (
+	SymbolChangeRename           = "rename"            // Ticker renamed (e.g. after a merger or rebrand)
+	SymbolChangeExchangeTransfer = "exchange_transfer" // Listing moved between exchanges (e.g. UPCOM to HOSE)
+)
+
+// Symbol change sources
+const (
+	SymbolChangeSourceAdmin   = "admin"
+	SymbolChangeSourceCrawler = "crawler" // Exchange transfer detected while refreshing the stock list
+)
+
+// SymbolChange records a ticker rename or exchange transfer with its effective date
+type SymbolChange struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Type          string             `bson:"type" json:"type"`
+	OldCode       string             `bson:"oldCode" json:"oldCode"`
+	NewCode       string             `bson:"newCode" json:"newCode"` // Same as OldCode for exchange transfers
+	OldExchange   string             `bson:"oldExchange,omitempty" json:"oldExchange,omitempty"`
+	NewExchange   string             `bson:"newExchange,omitempty" json:"newExchange,omitempty"`
+	EffectiveDate string             `bson:"effectiveDate" json:"effectiveDate"` // YYYY-MM-DD
+	Note          string             `bson:"note,omitempty" json:"note,omitempty"`
+	Source        string             `bson:"source" json:"source"`
+	CreatedAt     primitive.DateTime `bson:"createdAt" json:"createdAt"`
+}
+
+// Normalize upper-cases codes and exchanges and validates the change
+func (sc *SymbolChange) Normalize() error {
+	sc.OldCode = strings.ToUpper(strings.TrimSpace(sc.OldCode))
+	sc.NewCode = strings.ToUpper(strings.TrimSpace(sc.NewCode))
+	sc.OldExchange = strings.ToUpper(strings.TrimSpace(sc.OldExchange))
+	sc.NewExchange = strings.ToUpper(strings.TrimSpace(sc.NewExchange))
+
+	if _, err := time.Parse("2006-01-02", sc.EffectiveDate); err != nil {
+		return fmt.Errorf("effectiveDate must be YYYY-MM-DD")
+	}
+
+	switch sc.Type {
+	case SymbolChangeRename:
+		if sc.OldCode == "" || sc.NewCode == "" || sc.OldCode == sc.NewCode {
+			return fmt.Errorf("a rename needs different oldCode and newCode")
+		}
+	case SymbolChangeExchangeTransfer:
+		if sc.NewCode == "" {
+			sc.NewCode = sc.OldCode
+		}
+		if sc.OldCode == "" || sc.OldCode != sc.NewCode {
+			return fmt.Errorf("an exchange transfer needs oldCode (and no different newCode)")
+		}
+		if sc.OldExchange == "" || sc.NewExchange == "" || sc.OldExchange == sc.NewExchange {
+			return fmt.Errorf("an exchange transfer needs different oldExchange and newExchange")
+		}
+	default:
+		return fmt.Errorf("type must be %q or %q", SymbolChangeRename, SymbolChangeExchangeTransfer)
+	}
+	return nil
+}
+
+// LineageCode is a code of a symbol's lineage with the dates its candles
+// belong to the symbol: from From (inclusive) to To (exclusive), open-ended
+// where empty. A ticker renamed away keeps its old candles in the lineage
+// of its new code only before the rename, and a ticker taken over later
+// contributes only its candles since.
+type LineageCode struct {
+	Code string `json:"code"`
+	From string `json:"from,omitempty"` // YYYY-MM-DD
+	To   string `json:"to,omitempty"`   // YYYY-MM-DD
+}
+
+// String formats the code for price reads: CODE, or CODE@FROM..TO when its
+// window is limited (ParseLineageCode reads it back)
+func (lc LineageCode) String() string {
+	if lc.From == "" && lc.To == "" {
+		return lc.Code
+	}
+	return lc.Code + "@" + lc.From + ".." + lc.To
+}
+
+// Covers reports whether date (YYYY-MM-DD) is in the window
+func (lc LineageCode) Covers(date string) bool {
+	return (lc.From == "" || date >= lc.From) && (lc.To == "" || date < lc.To)
+}
+
+// ParseLineageCode parses a code of a price read, with or without a window,
+// upper-casing the code
+func ParseLineageCode(s string) LineageCode {
+	code, window, _ := strings.Cut(strings.TrimSpace(s), "@")
+	from, to, _ := strings.Cut(window, "..")
+	return LineageCode{Code: strings.ToUpper(code), From: from, To: to}
+}
+
+// ResolveSymbol follows renames from code as it was on asOf (YYYY-MM-DD,
+// empty for today) to the current ticker and returns it with the lineage:
+// every code connected to it by renames, current code first, then older
+// codes from most to least recent, each limited to the dates it denoted the
+// symbol. A code renamed before asOf still resolves to its successor unless
+// it was taken over since, by a rename to it or, when listed, by a new
+// listing.
+func ResolveSymbol(changes []SymbolChange, code, asOf string, listed bool) (string, []LineageCode) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if asOf == "" {
+		asOf = "9999-12-31"
+	}
+
+	renames := make([]SymbolChange, 0, len(changes))
+	for _, change := range changes {
+		if change.Type == SymbolChangeRename {
+			renames = append(renames, change)
+		}
+	}
+	sort.SliceStable(renames, func(i, j int) bool { return renames[i].EffectiveDate < renames[j].EffectiveDate })
+
+	away := make(map[string][]SymbolChange) // By old code, oldest first
+	into := make(map[string][]SymbolChange) // By new code, oldest first
+	for _, rename := range renames {
+		away[rename.OldCode] = append(away[rename.OldCode], rename)
+		into[rename.NewCode] = append(into[rename.NewCode], rename)
+	}
+
+	// The first rename after the date a code was reached moves the symbol
+	// on. Dates only grow along the chain after the first step, so cyclic
+	// renames end too.
+	current, date := code, asOf
+	for first := true; ; first = false {
+		var next *SymbolChange
+		for i := range away[current] {
+			if away[current][i].EffectiveDate > date {
+				next = &away[current][i]
+				break
+			}
+		}
+		if next == nil && first {
+			next = formerTickerRename(away[code], into[code], asOf, listed)
+		}
+		if next == nil {
+			break
+		}
+		current, date = next.NewCode, next.EffectiveDate
+	}
+
+	lineage := []LineageCode{{Code: current, From: lastRenameBefore(away[current], "9999-12-31")}}
+	// Windows end earlier at every step back, so the walk ends; a code
+	// renamed back to appears once per window
+	for i := 0; i < len(lineage); i++ {
+		successor := lineage[i]
+		predecessors := into[successor.Code]
+		for j := len(predecessors) - 1; j >= 0; j-- {
+			rename := predecessors[j]
+			if !successor.Covers(rename.EffectiveDate) {
+				continue
+			}
+			lineage = append(lineage, LineageCode{
+				Code: rename.OldCode,
+				From: lastRenameBefore(away[rename.OldCode], rename.EffectiveDate),
+				To:   rename.EffectiveDate,
+			})
+		}
+	}
+	return current, lineage
+}
+
+// formerTickerRename returns the latest rename of a code renamed away on or
+// before asOf, so that former tickers still find their symbol, or nil when
+// the code was taken over since
+func formerTickerRename(away, into []SymbolChange, asOf string, listed bool) *SymbolChange {
+	var latest *SymbolChange
+	for i := range away {
+		if away[i].EffectiveDate <= asOf {
+			latest = &away[i]
+		}
+	}
+	if latest == nil || listed {
+		return nil
+	}
+	for _, rename := range into {
+		if rename.EffectiveDate > latest.EffectiveDate && rename.EffectiveDate <= asOf {
+			return nil
+		}
+	}
+	return latest
+}
+
+// lastRenameBefore returns the date of the latest rename before date, when a
+// code last denoted another symbol ("" if never)
+func lastRenameBefore(away []SymbolChange, date string) string {
+	last := ""
+	for _, rename := range away {
+		if rename.EffectiveDate < date {
+			last = rename.EffectiveDate
+		}
+	}
+	return last
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveSymbol(t *testing.T) {
+	changes := []SymbolChange{
+		{Type: SymbolChangeRename, OldCode: "BBB", NewCode: "CCC", EffectiveDate: "2023-06-01"},
+		{Type: SymbolChangeRename, OldCode: "AAA", NewCode: "BBB", EffectiveDate: "2020-03-15"},
+		{Type: SymbolChangeExchangeTransfer, OldCode: "CCC", NewCode: "CCC", OldExchange: "UPCOM", NewExchange: "HOSE", EffectiveDate: "2024-01-10"},
+		{Type: SymbolChangeRename, OldCode: "XXX", NewCode: "YYY", EffectiveDate: "2021-01-01"},
+		{Type: SymbolChangeRename, OldCode: "YYY", NewCode: "XXX", EffectiveDate: "2022-01-01"},
+	}
+	xxxBack := []LineageCode{{Code: "XXX", From: "2021-01-01"}, {Code: "YYY", To: "2022-01-01"}, {Code: "XXX", To: "2021-01-01"}}
+	aaaToCCC := []LineageCode{{Code: "CCC"}, {Code: "BBB", To: "2023-06-01"}, {Code: "AAA", To: "2020-03-15"}}
+
+	tests := []struct {
+		code            string
+		asOf            string
+		listed          bool
+		expectedCurrent string
+		expectedLineage []LineageCode
+	}{
+		{"aaa", "", false, "CCC", aaaToCCC},
+		{"CCC", "", true, "CCC", aaaToCCC},
+		{"HPG", "", true, "HPG", []LineageCode{{Code: "HPG"}}},
+		// Before the rename AAA was the symbol now called CCC
+		{"AAA", "2019-05-02", true, "CCC", aaaToCCC},
+		// A new listing took the AAA ticker over after the rename
+		{"AAA", "2025-01-02", true, "AAA", []LineageCode{{Code: "AAA", From: "2020-03-15"}}},
+		// XXX was renamed to YYY and back; it is XXX again today
+		{"XXX", "", false, "XXX", xxxBack},
+		{"XXX", "2021-06-01", false, "XXX", xxxBack},
+		{"XXX", "2020-06-01", false, "XXX", xxxBack},
+	}
+
+	for _, tt := range tests {
+		current, lineage := ResolveSymbol(changes, tt.code, tt.asOf, tt.listed)
+		if current != tt.expectedCurrent || !reflect.DeepEqual(lineage, tt.expectedLineage) {
+			t.Errorf("ResolveSymbol(%s, %q) = %s, %v; want %s, %v",
+				tt.code, tt.asOf, current, lineage, tt.expectedCurrent, tt.expectedLineage)
+		}
+	}
+}
+
+func TestLineageCodeWindow(t *testing.T) {
+	bbb := ParseLineageCode("bbb@2020-03-15..2023-06-01")
+	if bbb != (LineageCode{Code: "BBB", From: "2020-03-15", To: "2023-06-01"}) || bbb.String() != "BBB@2020-03-15..2023-06-01" {
+		t.Errorf("ParseLineageCode() = %+v (%s); want BBB from 2020-03-15 to 2023-06-01", bbb, bbb)
+	}
+	if !bbb.Covers("2020-03-15") || bbb.Covers("2023-06-01") || bbb.Covers("2019-12-31") {
+		t.Error("Covers() should include From and exclude To")
+	}
+	if hpg := ParseLineageCode("HPG"); hpg.String() != "HPG" || !hpg.Covers("2000-01-03") {
+		t.Errorf("ParseLineageCode(HPG) = %+v; want an open window", hpg)
+	}
+}
+
+func TestSymbolChangeNormalize(t *testing.T) {
+	transfer := SymbolChange{Type: SymbolChangeExchangeTransfer, OldCode: "abc", OldExchange: "upcom", NewExchange: "HOSE", EffectiveDate: "2024-01-10"}
+	if err := transfer.Normalize(); err != nil {
+		t.Fatalf("Normalize() unexpected error: %v", err)
+	}
+	if transfer.NewCode != "ABC" || transfer.OldExchange != "UPCOM" {
+		t.Errorf("Normalize() = %+v; want NewCode ABC and OldExchange UPCOM", transfer)
+	}
+
+	invalid := []SymbolChange{
+		{Type: SymbolChangeRename, OldCode: "AAA", NewCode: "AAA", EffectiveDate: "2024-01-10"},
+		{Type: SymbolChangeRename, OldCode: "AAA", NewCode: "BBB", EffectiveDate: "10/01/2024"},
+		{Type: SymbolChangeExchangeTransfer, OldCode: "AAA", OldExchange: "HOSE", NewExchange: "HOSE", EffectiveDate: "2024-01-10"},
+		{Type: "merger", OldCode: "AAA", NewCode: "BBB", EffectiveDate: "2024-01-10"},
+	}
+	for _, change := range invalid {
+		if err := change.Normalize(); err == nil {
+			t.Errorf("Normalize(%+v) expected error but got none", change)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// TCBSPosition is a stock position of a member's TCBS account, priced as
+// reported by TCBS
+type TCBSPosition struct {
+	Code          string  `json:"code"`
+	Quantity      int64   `json:"quantity"`
+	Available     int64   `json:"available"` // Settled shares that can be sold
+	CostPrice     float64 `json:"cost_price"`
+	MarketPrice   float64 `json:"market_price"`
+	MarketValue   float64 `json:"market_value"`
+	UnrealizedPnL float64 `json:"unrealized_pnl"`
+}
+
+// TCBSBalance is the cash position of a TCBS account, in VND
+type TCBSBalance struct {
+	Cash        float64 `json:"cash"`
+	Available   float64 `json:"available"`
+	BuyingPower float64 `json:"buying_power"`
+	Debt        float64 `json:"debt"`
+	TotalAsset  float64 `json:"total_asset"`
+}
+
+// TCBSAccount is one sub-account of a member's TCBS custody account
+type TCBSAccount struct {
+	AccountNo string         `json:"account_no"`
+	Type      string         `json:"type"`
+	Positions []TCBSPosition `json:"positions"`
+	Balance   TCBSBalance    `json:"balance"`
+}
+
+// TCBSPortfolio is a member's TCBS accounts with their totals
+type TCBSPortfolio struct {
+	Accounts      []TCBSAccount `json:"accounts"`
+	MarketValue   float64       `json:"market_value"`
+	UnrealizedPnL float64       `json:"unrealized_pnl"`
+	Cash          float64       `json:"cash"`
+	TotalAsset    float64       `json:"total_asset"`
+	ConnectedAt   *time.Time    `json:"connected_at,omitempty"`
+	FetchedAt     time.Time     `json:"fetched_at"`
+}
+
+// NewTCBSPosition values quantity shares bought at costPrice at marketPrice
+func NewTCBSPosition(code string, quantity, available int64, costPrice, marketPrice float64) TCBSPosition {
+	return TCBSPosition{
+		Code:          code,
+		Quantity:      quantity,
+		Available:     available,
+		CostPrice:     costPrice,
+		MarketPrice:   marketPrice,
+		MarketValue:   float64(quantity) * marketPrice,
+		UnrealizedPnL: float64(quantity) * (marketPrice - costPrice),
+	}
+}
+
+// SummarizeTCBSPortfolio totals the positions and balances of accounts
+func SummarizeTCBSPortfolio(accounts []TCBSAccount, fetchedAt time.Time) TCBSPortfolio {
+	portfolio := TCBSPortfolio{Accounts: accounts, FetchedAt: fetchedAt}
+	if portfolio.Accounts == nil {
+		portfolio.Accounts = []TCBSAccount{}
+	}
+	for _, account := range accounts {
+		for _, position := range account.Positions {
+			portfolio.MarketValue += position.MarketValue
+			portfolio.UnrealizedPnL += position.UnrealizedPnL
+		}
+		portfolio.Cash += account.Balance.Cash
+		portfolio.TotalAsset += account.Balance.TotalAsset
+	}
+	return portfolio
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeTCBSPortfolio(t *testing.T) {
+	accounts := []TCBSAccount{
+		{AccountNo: "0001", Positions: []TCBSPosition{NewTCBSPosition("HPG", 1000, 800, 24500, 26100)}, Balance: TCBSBalance{Cash: 5e6, TotalAsset: 31.1e6}},
+		{AccountNo: "0001M", Positions: []TCBSPosition{NewTCBSPosition("FPT", 100, 100, 120000, 115000)}, Balance: TCBSBalance{Cash: 1e6, TotalAsset: 12.5e6}},
+	}
+	portfolio := SummarizeTCBSPortfolio(accounts, time.Now())
+	if portfolio.MarketValue != 26.1e6+11.5e6 || portfolio.UnrealizedPnL != 1.6e6-0.5e6 {
+		t.Errorf("MarketValue, UnrealizedPnL = %v, %v; want 37.6e6, 1.1e6", portfolio.MarketValue, portfolio.UnrealizedPnL)
+	}
+	if portfolio.Cash != 6e6 || portfolio.TotalAsset != 43.6e6 {
+		t.Errorf("Cash, TotalAsset = %v, %v; want 6e6, 43.6e6", portfolio.Cash, portfolio.TotalAsset)
+	}
+	if empty := SummarizeTCBSPortfolio(nil, time.Now()); empty.Accounts == nil {
+		t.Error("SummarizeTCBSPortfolio(nil) accounts should be an empty list")
+	}
+}
@@ -0,0 +1,120 @@
+package models
+
+import (
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UniverseStock is a stock's listing attributes as captured in a snapshot
+type UniverseStock struct {
+	Code        string `bson:"code" json:"code"`
+	CompanyName string `bson:"companyName" json:"companyName"`
+	Exchange    string `bson:"exchange" json:"exchange"`
+	Type        string `bson:"type" json:"type"`
+	Status      string `bson:"status" json:"status"`
+}
+
+// UniverseSnapshot is the stock list as crawled on one day. A later full
+// crawl on the same day replaces it.
+type UniverseSnapshot struct {
+	Date    string             `bson:"_id" json:"date"` // YYYY-MM-DD, Vietnam time
+	TakenAt primitive.DateTime `bson:"takenAt" json:"takenAt"`
+	Count   int                `bson:"count" json:"count"`
+	Stocks  []UniverseStock    `bson:"stocks,omitempty" json:"stocks,omitempty"` // Ordered by code
+}
+
+// NewUniverseSnapshot captures the listing attributes of stocks, ordered by code
+func NewUniverseSnapshot(date string, takenAt primitive.DateTime, stocks []Stock) *UniverseSnapshot {
+	captured := make([]UniverseStock, 0, len(stocks))
+	for _, stock := range stocks {
+		captured = append(captured, UniverseStock{
+			Code:        stock.Code,
+			CompanyName: stock.CompanyName,
+			Exchange:    stock.Exchange,
+			Type:        stock.Type,
+			Status:      stock.Status,
+		})
+	}
+	sort.Slice(captured, func(i, j int) bool { return captured[i].Code < captured[j].Code })
+
+	return &UniverseSnapshot{Date: date, TakenAt: takenAt, Count: len(captured), Stocks: captured}
+}
+
+// UniverseFieldChange is one attribute that differs between two snapshots
+type UniverseFieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// UniverseChange lists the attribute changes of a stock present in both snapshots
+type UniverseChange struct {
+	Code    string                `json:"code"`
+	Changes []UniverseFieldChange `json:"changes"`
+}
+
+// UniverseDiff is the difference between the stock universe on two dates
+type UniverseDiff struct {
+	From         string           `json:"from"`          // Requested start date
+	To           string           `json:"to"`            // Requested end date
+	FromSnapshot string           `json:"from_snapshot"` // Latest snapshot on or before From
+	ToSnapshot   string           `json:"to_snapshot"`   // Latest snapshot on or before To
+	Listed       []UniverseStock  `json:"listed"`
+	Delisted     []UniverseStock  `json:"delisted"`
+	Changed      []UniverseChange `json:"changed"`
+}
+
+// DiffUniverse compares two stock lists: stocks only in to are listings,
+// stocks only in from are delistings, and stocks in both with different
+// attributes are changes. Results are ordered by code.
+func DiffUniverse(from, to []UniverseStock) *UniverseDiff {
+	diff := &UniverseDiff{
+		Listed:   []UniverseStock{},
+		Delisted: []UniverseStock{},
+		Changed:  []UniverseChange{},
+	}
+
+	before := make(map[string]UniverseStock, len(from))
+	for _, stock := range from {
+		before[stock.Code] = stock
+	}
+	after := make(map[string]bool, len(to))
+	for _, stock := range to {
+		after[stock.Code] = true
+		old, ok := before[stock.Code]
+		if !ok {
+			diff.Listed = append(diff.Listed, stock)
+			continue
+		}
+		if changes := universeFieldChanges(old, stock); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, UniverseChange{Code: stock.Code, Changes: changes})
+		}
+	}
+	for _, stock := range from {
+		if !after[stock.Code] {
+			diff.Delisted = append(diff.Delisted, stock)
+		}
+	}
+
+	sort.Slice(diff.Listed, func(i, j int) bool { return diff.Listed[i].Code < diff.Listed[j].Code })
+	sort.Slice(diff.Delisted, func(i, j int) bool { return diff.Delisted[i].Code < diff.Delisted[j].Code })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].Code < diff.Changed[j].Code })
+	return diff
+}
+
+// universeFieldChanges returns the attributes that differ between two captures of a stock
+func universeFieldChanges(from, to UniverseStock) []UniverseFieldChange {
+	var changes []UniverseFieldChange
+	for _, field := range []struct{ name, from, to string }{
+		{"companyName", from.CompanyName, to.CompanyName},
+		{"exchange", from.Exchange, to.Exchange},
+		{"type", from.Type, to.Type},
+		{"status", from.Status, to.Status},
+	} {
+		if field.from != field.to {
+			changes = append(changes, UniverseFieldChange{Field: field.name, From: field.from, To: field.to})
+		}
+	}
+	return changes
+}
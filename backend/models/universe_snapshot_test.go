@@ -0,0 +1,53 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffUniverse(t *testing.T) {
+	from := []UniverseStock{
+		{Code: "VNM", CompanyName: "Vinamilk", Exchange: "HOSE", Type: "STOCK", Status: "listed"},
+		{Code: "ABC", CompanyName: "ABC Corp", Exchange: "UPCOM", Type: "STOCK", Status: "listed"},
+		{Code: "OLD", CompanyName: "Old Co", Exchange: "HNX", Type: "STOCK", Status: "listed"},
+	}
+	to := []UniverseStock{
+		{Code: "VNM", CompanyName: "Vinamilk", Exchange: "HOSE", Type: "STOCK", Status: "listed"},
+		{Code: "ABC", CompanyName: "ABC Group", Exchange: "HOSE", Type: "STOCK", Status: "listed"},
+		{Code: "NEW", CompanyName: "New Co", Exchange: "HOSE", Type: "STOCK", Status: "listed"},
+	}
+
+	diff := DiffUniverse(from, to)
+
+	if len(diff.Listed) != 1 || diff.Listed[0].Code != "NEW" {
+		t.Errorf("Listed = %+v; want NEW", diff.Listed)
+	}
+	if len(diff.Delisted) != 1 || diff.Delisted[0].Code != "OLD" {
+		t.Errorf("Delisted = %+v; want OLD", diff.Delisted)
+	}
+	want := []UniverseChange{{Code: "ABC", Changes: []UniverseFieldChange{
+		{Field: "companyName", From: "ABC Corp", To: "ABC Group"},
+		{Field: "exchange", From: "UPCOM", To: "HOSE"},
+	}}}
+	if !reflect.DeepEqual(diff.Changed, want) {
+		t.Errorf("Changed = %+v; want %+v", diff.Changed, want)
+	}
+}
+
+func TestDiffUniverseIdentical(t *testing.T) {
+	stocks := []UniverseStock{{Code: "HPG", Exchange: "HOSE"}}
+	diff := DiffUniverse(stocks, stocks)
+	if len(diff.Listed)+len(diff.Delisted)+len(diff.Changed) != 0 {
+		t.Errorf("identical lists produced a diff: %+v", diff)
+	}
+	if diff.Listed == nil || diff.Delisted == nil || diff.Changed == nil {
+		t.Errorf("empty results should be empty slices, not nil")
+	}
+}
+
+func TestNewUniverseSnapshotSortsByCode(t *testing.T) {
+	snapshot := NewUniverseSnapshot("2026-02-03", 0, []Stock{{Code: "VNM"}, {Code: "ACB"}, {Code: "HPG"}})
+	if snapshot.Count != 3 || snapshot.Stocks[0].Code != "ACB" || snapshot.Stocks[2].Code != "VNM" {
+		t.Errorf("snapshot = %+v; want 3 stocks ordered by code", snapshot)
+	}
+}
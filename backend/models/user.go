@@ -18,6 +18,16 @@ type AdminUser struct {
 	CreatedAt time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
 	UpdatedAt time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
 	LastLogin *time.Time `gorm:"type:timestamptz;column:last_login" json:"last_login,omitempty"`
+	// PasswordHash is the bcrypt hash of the admin's password (never serialized)
+	PasswordHash *string `gorm:"type:text;column:password_hash" json:"-"`
+	// FailedLoginAttempts counts consecutive failed logins; the account is locked at login.max_failures
+	FailedLoginAttempts int        `gorm:"type:integer;default:0;column:failed_login_attempts" json:"failed_login_attempts"`
+	LockedAt            *time.Time `gorm:"type:timestamptz;column:locked_at" json:"locked_at,omitempty"`
+	// LockedUntil ends the lockout after login.lockout_duration, unless an admin unlocks it earlier
+	LockedUntil *time.Time `gorm:"type:timestamptz;column:locked_until" json:"locked_until,omitempty"`
+	// DashboardSeededAt is when the default dashboard widgets were saved, so
+	// an admin who later deletes all of them keeps an empty dashboard
+	DashboardSeededAt *time.Time `gorm:"type:timestamptz;column:dashboard_seeded_at" json:"-"`
 }
 
 // TableName specifies the table name for GORM
@@ -26,6 +36,23 @@ func (AdminUser) TableName() string {
 	return "public.admin_users"
 }
 
+// Locked reports whether the account is locked after too many failed
+// logins: the lockout lasts until LockedUntil
+func (u AdminUser) Locked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
+// Membership tiers of a profile
+const (
+	MembershipFree    = "free"
+	MembershipPremium = "premium"
+)
+
+// ValidMembership reports whether tier is a known membership tier
+func ValidMembership(tier string) bool {
+	return tier == MembershipFree || tier == MembershipPremium
+}
+
 // Profile represents the profiles table in Supabase
 // This table stores user profiles linked to auth.users
 type Profile struct {
@@ -43,6 +70,8 @@ type Profile struct {
 	MembershipExpiresAt *time.Time `gorm:"type:timestamptz;column:membership_expires_at" json:"membership_expires_at,omitempty"`
 	TCBSAPIKey          *string    `gorm:"type:text;column:tcbs_api_key" json:"tcbs_api_key,omitempty"`
 	TCBSConnectedAt     *time.Time `gorm:"type:timestamptz;column:tcbs_connected_at" json:"tcbs_connected_at,omitempty"`
+	Active              bool       `gorm:"type:boolean;default:true;column:active" json:"active"`
+	DeactivatedAt       *time.Time `gorm:"type:timestamptz;column:deactivated_at" json:"deactivated_at,omitempty"`
 	CreatedAt           time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
 	UpdatedAt           time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
 }
@@ -52,3 +81,12 @@ type Profile struct {
 func (Profile) TableName() string {
 	return "public.profiles"
 }
+
+// Tier returns the membership tier in effect at now: premium members whose
+// membership has expired are treated as free
+func (p *Profile) Tier(now time.Time) string {
+	if p.Membership == MembershipPremium && (p.MembershipExpiresAt == nil || p.MembershipExpiresAt.After(now)) {
+		return MembershipPremium
+	}
+	return MembershipFree
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAdminUserLocked(t *testing.T) {
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+	tests := []struct {
+		name string
+		user AdminUser
+		want bool
+	}{
+		{"never locked", AdminUser{}, false},
+		{"lockout running", AdminUser{LockedAt: &past, LockedUntil: &future}, true},
+		{"lockout expired", AdminUser{LockedAt: &past, LockedUntil: &past}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.user.Locked(); got != tt.want {
+			t.Errorf("%s: Locked() = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// VolumeAnomaly is a session whose volume was an unusual multiple of the
+// symbol's average over the VolumeSpikeWindow sessions before it. One
+// document per symbol and date in the volume_anomalies collection.
+type VolumeAnomaly struct {
+	ID            string             `bson:"_id" json:"id"` // Format: "{CODE}_{DATE}"
+	Code          string             `bson:"code" json:"code"`
+	Exchange      string             `bson:"exchange,omitempty" json:"exchange,omitempty"`
+	Sector        string             `bson:"sector,omitempty" json:"sector,omitempty"`
+	Date          string             `bson:"date" json:"date"`
+	Close         float64            `bson:"close" json:"close"`
+	ChangePercent float64            `bson:"changePercent" json:"changePercent"` // Close vs the previous close
+	Volume        int64              `bson:"volume" json:"volume"`
+	AvgVolume     float64            `bson:"avgVolume20" json:"avgVolume20"` // Of the sessions before the date
+	Ratio         float64            `bson:"ratio" json:"ratio"`             // Volume / AvgVolume
+	Value         float64            `bson:"value" json:"value"`             // Close × volume
+	DetectedAt    primitive.DateTime `bson:"detectedAt" json:"detectedAt"`
+}
+
+// DetectVolumeAnomaly reports the last of the candles (ordered by date) as
+// an anomaly when its volume is at least multiple times the average of the
+// VolumeSpikeWindow sessions before it, and that average at least
+// minAvgVolume. Symbols with less history are not judged.
+func DetectVolumeAnomaly(code string, candles []CandleData, multiple, minAvgVolume float64, detectedAt primitive.DateTime) (VolumeAnomaly, bool) {
+	average, ok := previousAverageVolume(candles, VolumeSpikeWindow)
+	if !ok || average <= 0 || average < minAvgVolume {
+		return VolumeAnomaly{}, false
+	}
+	last, previous := candles[len(candles)-1], candles[len(candles)-2]
+	ratio := float64(last.V) / average
+	if ratio < multiple {
+		return VolumeAnomaly{}, false
+	}
+
+	anomaly := VolumeAnomaly{
+		ID:         code + "_" + last.D,
+		Code:       code,
+		Date:       last.D,
+		Close:      last.C,
+		Volume:     last.V,
+		AvgVolume:  round2(average),
+		Ratio:      round2(ratio),
+		Value:      round2(last.C * float64(last.V)),
+		DetectedAt: detectedAt,
+	}
+	if previous.C > 0 {
+		anomaly.ChangePercent = round2((last.C - previous.C) / previous.C * 100)
+	}
+	return anomaly, true
+}
@@ -0,0 +1,29 @@
+package models
+
+import "testing"
+
+func TestDetectVolumeAnomaly(t *testing.T) {
+	candles := make([]CandleData, 0, VolumeSpikeWindow+1)
+	for i := 0; i < VolumeSpikeWindow; i++ {
+		candles = append(candles, CandleData{D: "2026-02-01", C: 20, V: 100_000})
+	}
+	candles = append(candles, CandleData{D: "2026-03-03", C: 21, V: 450_000})
+
+	anomaly, ok := DetectVolumeAnomaly("HPG", candles, 3, 10_000, 0)
+	if !ok {
+		t.Fatal("4.5× the average volume should be an anomaly")
+	}
+	if anomaly.ID != "HPG_2026-03-03" || anomaly.Ratio != 4.5 || anomaly.AvgVolume != 100_000 || anomaly.ChangePercent != 5 || anomaly.Value != 9_450_000 {
+		t.Errorf("anomaly = %+v", anomaly)
+	}
+
+	if _, ok := DetectVolumeAnomaly("HPG", candles, 5, 10_000, 0); ok {
+		t.Error("4.5× should not reach a multiple of 5")
+	}
+	if _, ok := DetectVolumeAnomaly("HPG", candles, 3, 200_000, 0); ok {
+		t.Error("an average below the minimum should not be judged")
+	}
+	if _, ok := DetectVolumeAnomaly("HPG", candles[1:], 3, 10_000, 0); ok {
+		t.Error("fewer than 20 previous sessions should not be judged")
+	}
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxWatchlistsPerProfile caps the watchlists one member can keep
+	MaxWatchlistsPerProfile = 20
+	// MaxWatchlistSymbols caps the symbols of one watchlist
+	MaxWatchlistSymbols = 100
+)
+
+// Watchlist represents the watchlists table in Supabase
+// A member's named list of stock codes; Codes is loaded from watchlist_symbols
+type Watchlist struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	ProfileID uuid.UUID `gorm:"type:uuid;not null;column:profile_id" json:"profile_id"`
+	Name      string    `gorm:"type:text;not null;column:name" json:"name"`
+	Codes     []string  `gorm:"-" json:"codes"` // In the member's order
+	CreatedAt time.Time `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (Watchlist) TableName() string {
+	return "public.watchlists"
+}
+
+// WatchlistSymbol represents the watchlist_symbols table in Supabase
+type WatchlistSymbol struct {
+	WatchlistID uuid.UUID `gorm:"type:uuid;primaryKey;column:watchlist_id" json:"watchlist_id"`
+	Code        string    `gorm:"type:text;primaryKey;column:code" json:"code"`
+	Position    int       `gorm:"type:integer;not null;column:position" json:"position"`
+	AddedAt     time.Time `gorm:"type:timestamptz;default:now();column:added_at" json:"added_at"`
+}
+
+// TableName specifies the table name for GORM
+func (WatchlistSymbol) TableName() string {
+	return "public.watchlist_symbols"
+}
+
+// NormalizeWatchlistName trims a watchlist name and checks its length
+func NormalizeWatchlistName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("name is required")
+	}
+	if len([]rune(name)) > 100 {
+		return "", fmt.Errorf("name must be at most 100 characters")
+	}
+	return name, nil
+}
+
+// NormalizeStockCodes upper-cases codes and drops blanks and duplicates,
+// keeping the first occurrence's position
+func NormalizeStockCodes(codes []string) []string {
+	seen := make(map[string]bool, len(codes))
+	normalized := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		normalized = append(normalized, code)
+	}
+	return normalized
+}
+
+// Quote is a stock's latest daily candle with its change from the previous close
+type Quote struct {
+	Code          string   `json:"code"`
+	CompanyName   string   `json:"company_name,omitempty"`
+	Exchange      string   `json:"exchange,omitempty"`
+	Date          string   `json:"date,omitempty"` // Empty when no candle is stored
+	Open          float64  `json:"open"`
+	High          float64  `json:"high"`
+	Low           float64  `json:"low"`
+	Close         float64  `json:"close"`
+	Volume        int64    `json:"volume"`
+	Change        *float64 `json:"change,omitempty"` // Close minus the previous close
+	ChangePercent *float64 `json:"change_percent,omitempty"`
+}
+
+// NewQuote builds the quote of a stock from its most recent candles ordered
+// by date; stock may be nil for codes that are not listed
+func NewQuote(code string, stock *Stock, candles []CandleData) Quote {
+	quote := Quote{Code: code}
+	if stock != nil {
+		quote.CompanyName = stock.CompanyName
+		quote.Exchange = stock.Exchange
+	}
+	n := len(candles)
+	if n == 0 {
+		return quote
+	}
+	latest := candles[n-1]
+	quote.Date, quote.Open, quote.High, quote.Low, quote.Close, quote.Volume =
+		latest.D, latest.O, latest.H, latest.L, latest.C, latest.V
+	if n > 1 && candles[n-2].C != 0 {
+		change := latest.C - candles[n-2].C
+		percent := change / candles[n-2].C * 100
+		quote.Change, quote.ChangePercent = &change, &percent
+	}
+	return quote
+}
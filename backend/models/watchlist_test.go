@@ -0,0 +1,53 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeStockCodes(t *testing.T) {
+	got := NormalizeStockCodes([]string{" hpg", "VNM", "", "Hpg", "fpt "})
+	want := []string{"HPG", "VNM", "FPT"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeStockCodes = %v; want %v", got, want)
+	}
+}
+
+func TestNormalizeWatchlistName(t *testing.T) {
+	if name, err := NormalizeWatchlistName("  Ngân hàng "); err != nil || name != "Ngân hàng" {
+		t.Errorf("NormalizeWatchlistName = %q, %v; want %q", name, err, "Ngân hàng")
+	}
+	for _, name := range []string{"", "   ", strings.Repeat("a", 101)} {
+		if _, err := NormalizeWatchlistName(name); err == nil {
+			t.Errorf("NormalizeWatchlistName(%q) succeeded; want an error", name)
+		}
+	}
+}
+
+func TestNewQuote(t *testing.T) {
+	stock := &Stock{CompanyName: "Hoa Phat", Exchange: "HOSE"}
+	candles := []CandleData{
+		{D: "2026-02-05", C: 25},
+		{D: "2026-02-06", O: 25, H: 27, L: 24.5, C: 26.5, V: 1200},
+	}
+
+	quote := NewQuote("HPG", stock, candles)
+	if quote.Date != "2026-02-06" || quote.Close != 26.5 || quote.Volume != 1200 || quote.Exchange != "HOSE" {
+		t.Errorf("NewQuote = %+v; want the latest candle of HPG on HOSE", quote)
+	}
+	if quote.Change == nil || *quote.Change != 1.5 {
+		t.Errorf("Change = %v; want 1.5", quote.Change)
+	}
+	if quote.ChangePercent == nil || *quote.ChangePercent != 6 {
+		t.Errorf("ChangePercent = %v; want 6", quote.ChangePercent)
+	}
+
+	single := NewQuote("HPG", nil, candles[1:])
+	if single.Change != nil || single.ChangePercent != nil {
+		t.Error("NewQuote(one candle) has a change; want none")
+	}
+	if empty := NewQuote("XYZ", nil, nil); empty.Date != "" || empty.Close != 0 {
+		t.Errorf("NewQuote(no candles) = %+v; want only the code", empty)
+	}
+}
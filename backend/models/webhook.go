@@ -0,0 +1,163 @@
+package models
+
+import (
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Webhook event types
+const (
+	WebhookEventCrawlCompleted = "crawl.completed" // A crawl run finished (some symbols may have failed)
+	WebhookEventCrawlFailed    = "crawl.failed"    // A crawl run aborted
+	WebhookEventCandleNew      = "candle.new"      // A crawl run stored new candles (one event per run)
+	WebhookEventDataDigest     = "data.digest"     // The end-of-day dataset of a trading day is final
+	WebhookEventPing           = "ping"            // Test delivery sent from the admin API
+)
+
+// WebhookEvents lists the events endpoints can subscribe to
+var WebhookEvents = []string{
+	WebhookEventCrawlCompleted,
+	WebhookEventCrawlFailed,
+	WebhookEventCandleNew,
+	WebhookEventDataDigest,
+}
+
+// Webhook delivery states
+const (
+	WebhookDeliveryPending   = "pending" // Waiting for its first attempt or a retry
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // Gave up after MaxWebhookAttempts
+)
+
+const (
+	// MaxWebhookAttempts is how often a delivery is tried before it fails
+	MaxWebhookAttempts = 8
+	// webhookRetryBase is the wait after the first failed attempt; it doubles per attempt
+	webhookRetryBase = 30 * time.Second
+	// webhookRetryMax caps the wait between attempts
+	webhookRetryMax = time.Hour
+)
+
+// WebhookEndpoint represents the webhook_endpoints table in Supabase
+// Each endpoint receives signed JSON payloads for the events it subscribes to
+type WebhookEndpoint struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	URL         string    `gorm:"type:text;not null;column:url" json:"url"`
+	Description string    `gorm:"type:text;column:description" json:"description,omitempty"`
+	Events      string    `gorm:"type:text;not null;column:events" json:"events"` // Comma-separated event types
+	Secret      string    `gorm:"type:text;not null;column:secret" json:"-"`      // HMAC signing secret
+	Enabled     bool      `gorm:"type:boolean;default:true;column:enabled" json:"enabled"`
+	CreatedBy   *string   `gorm:"type:text;column:created_by" json:"created_by,omitempty"`
+	CreatedAt   time.Time `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (WebhookEndpoint) TableName() string {
+	return "public.webhook_endpoints"
+}
+
+// EventList returns the endpoint's subscribed events as a slice
+func (e WebhookEndpoint) EventList() []string {
+	return SplitList(e.Events)
+}
+
+// Subscribes reports whether the endpoint receives the event
+func (e WebhookEndpoint) Subscribes(event string) bool {
+	return containsString(e.EventList(), event)
+}
+
+// Validate checks that the URL is an absolute HTTP(S) URL of a public host
+// and the events are known. Host names are checked again when a delivery
+// dials the resolved address.
+func (e WebhookEndpoint) Validate() error {
+	parsed, err := url.Parse(strings.TrimSpace(e.URL))
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "https" && parsed.Scheme != "http") {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	host := strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") {
+		return fmt.Errorf("url must point to a public host")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !IsPublicWebhookAddr(addr) {
+		return fmt.Errorf("url must point to a public host")
+	}
+	events := e.EventList()
+	if len(events) == 0 {
+		return fmt.Errorf("at least one event is required (supported: %s)", strings.Join(WebhookEvents, ", "))
+	}
+	for _, event := range events {
+		if !containsString(WebhookEvents, event) {
+			return fmt.Errorf("unknown event %q (supported: %s)", event, strings.Join(WebhookEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// nonPublicPrefixes are the ranges that are neither private nor loopback nor
+// link-local per net/netip but still must not be reached by webhooks
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"), // Carrier-grade NAT, also Alibaba Cloud metadata
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // Benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // Reserved, including broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, maps IPv4 addresses
+}
+
+// IsPublicWebhookAddr reports whether webhooks may be delivered to addr: it
+// must not be private, loopback, link-local (which covers the
+// 169.254.169.254 metadata server), multicast or otherwise reserved
+func IsPublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// WebhookDelivery represents the webhook_deliveries table in Supabase
+// One row per event and endpoint, retried with exponential backoff
+type WebhookDelivery struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	EndpointID     uuid.UUID  `gorm:"type:uuid;not null;column:endpoint_id" json:"endpoint_id"`
+	Event          string     `gorm:"type:text;not null;column:event" json:"event"`
+	Payload        string     `gorm:"type:text;not null;column:payload" json:"payload"` // Exact JSON body that is signed and sent
+	Status         string     `gorm:"type:text;not null;column:status" json:"status"`
+	Attempts       int        `gorm:"type:integer;not null;default:0;column:attempts" json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"type:timestamptz;column:next_attempt_at" json:"next_attempt_at,omitempty"`
+	LastStatusCode *int       `gorm:"type:integer;column:last_status_code" json:"last_status_code,omitempty"`
+	LastError      *string    `gorm:"type:text;column:last_error" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `gorm:"type:timestamptz;column:delivered_at" json:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (WebhookDelivery) TableName() string {
+	return "public.webhook_deliveries"
+}
+
+// WebhookRetryDelay returns the wait before the next attempt after attempts
+// failed ones: 30s, 1m, 2m, ... capped at one hour
+func WebhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > webhookRetryMax {
+		delay = webhookRetryMax
+	}
+	return delay
+}
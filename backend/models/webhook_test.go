@@ -0,0 +1,73 @@
+package models
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestWebhookEndpointValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint WebhookEndpoint
+		valid    bool
+	}{
+		{"valid", WebhookEndpoint{URL: "https://example.com/hooks", Events: "crawl.completed, candle.new"}, true},
+		{"http allowed", WebhookEndpoint{URL: "http://203.0.113.5:9000/cpls", Events: "crawl.failed"}, true},
+		{"private address", WebhookEndpoint{URL: "http://10.0.0.5:9000/cpls", Events: "crawl.failed"}, false},
+		{"metadata server", WebhookEndpoint{URL: "http://169.254.169.254/computeMetadata/v1/", Events: "crawl.failed"}, false},
+		{"loopback ipv6", WebhookEndpoint{URL: "http://[::1]:8080/", Events: "crawl.failed"}, false},
+		{"localhost", WebhookEndpoint{URL: "http://localhost:8080/", Events: "crawl.failed"}, false},
+		{"relative url", WebhookEndpoint{URL: "/hooks", Events: "crawl.failed"}, false},
+		{"other scheme", WebhookEndpoint{URL: "ftp://example.com", Events: "crawl.failed"}, false},
+		{"no events", WebhookEndpoint{URL: "https://example.com", Events: " , "}, false},
+		{"unknown event", WebhookEndpoint{URL: "https://example.com", Events: "crawl.started"}, false},
+		{"ping is not subscribable", WebhookEndpoint{URL: "https://example.com", Events: "ping"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.endpoint.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() error = %v; want valid %v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestIsPublicWebhookAddr(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.100.100.200":  false,
+		"0.0.0.0":          false,
+		"::ffff:127.0.0.1": false,
+		"fd00:ec2::254":    false,
+		"fe80::1":          false,
+	}
+	for addr, want := range tests {
+		if got := IsPublicWebhookAddr(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsPublicWebhookAddr(%s) = %v; want %v", addr, got, want)
+		}
+	}
+}
+
+func TestWebhookEndpointSubscribes(t *testing.T) {
+	endpoint := WebhookEndpoint{Events: "crawl.completed,candle.new"}
+	if !endpoint.Subscribes(WebhookEventCandleNew) || endpoint.Subscribes(WebhookEventCrawlFailed) {
+		t.Errorf("Subscribes() does not follow the events list %q", endpoint.Events)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute}
+	for i, delay := range want {
+		if got := WebhookRetryDelay(i + 1); got != delay {
+			t.Errorf("WebhookRetryDelay(%d) = %s; want %s", i+1, got, delay)
+		}
+	}
+	if got := WebhookRetryDelay(20); got != time.Hour {
+		t.Errorf("WebhookRetryDelay(20) = %s; want the 1h cap", got)
+	}
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Zalo message delivery statuses, in the order a message moves through them
+const (
+	ZaloMessagePending  = "pending"    // Recorded, not yet accepted by Zalo
+	ZaloMessageSent     = "sent"       // Accepted by the Official Account API
+	ZaloMessageReceived = "received"   // Zalo reported delivery to the user's device
+	ZaloMessageSeen     = "seen"       // The user opened the message
+	ZaloMessageFailed   = "failed"     // Rejected by Zalo or not deliverable
+	ZaloMessageNoZaloID = "no_zalo_id" // Recipient profile has no linked Zalo account
+)
+
+// ZaloMessage represents the zalo_messages table in Supabase
+// Every message sent through the Zalo Official Account is tracked here
+type ZaloMessage struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;column:id" json:"id"`
+	ProfileID  *uuid.UUID `gorm:"type:uuid;column:profile_id" json:"profile_id,omitempty"` // Member recipient; nil for admin recipients
+	ZaloUserID string     `gorm:"type:text;not null;column:zalo_user_id" json:"zalo_user_id"`
+	Template   string     `gorm:"type:text;not null;column:template" json:"template"`
+	Text       string     `gorm:"type:text;not null;column:text" json:"text"`
+	Status     string     `gorm:"type:text;not null;column:status" json:"status"`
+	MessageID  *string    `gorm:"type:text;column:message_id" json:"message_id,omitempty"` // Zalo message ID once sent
+	Error      *string    `gorm:"type:text;column:error" json:"error,omitempty"`
+	CreatedAt  time.Time  `gorm:"type:timestamptz;default:now();column:created_at" json:"created_at"`
+	SentAt     *time.Time `gorm:"type:timestamptz;column:sent_at" json:"sent_at,omitempty"`
+	UpdatedAt  time.Time  `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (ZaloMessage) TableName() string {
+	return "public.zalo_messages"
+}
+
+// ZaloEvent represents the zalo_events table in Supabase
+// OA webhook events processed recently, so a replayed event is applied once
+type ZaloEvent struct {
+	EventID    string    `gorm:"type:text;primary_key;column:event_id" json:"event_id"` // Event signature; Zalo events carry no ID
+	EventName  string    `gorm:"type:text;not null;column:event_name" json:"event_name"`
+	ReceivedAt time.Time `gorm:"type:timestamptz;default:now();column:received_at" json:"received_at"`
+}
+
+// TableName specifies the table name for GORM
+func (ZaloEvent) TableName() string {
+	return "public.zalo_events"
+}
+
+// OAuthToken represents the oauth_tokens table in Supabase
+// Access and refresh tokens of third-party APIs whose refresh tokens rotate,
+// so the latest pair survives restarts
+type OAuthToken struct {
+	Provider     string    `gorm:"type:text;primary_key;column:provider" json:"provider"`
+	AccessToken  string    `gorm:"type:text;not null;column:access_token" json:"-"`
+	RefreshToken string    `gorm:"type:text;not null;column:refresh_token" json:"-"`
+	ExpiresAt    time.Time `gorm:"type:timestamptz;not null;column:expires_at" json:"expires_at"`
+	UpdatedAt    time.Time `gorm:"type:timestamptz;default:now();column:updated_at" json:"updated_at"`
+}
+
+// TableName specifies the table name for GORM
+func (OAuthToken) TableName() string {
+	return "public.oauth_tokens"
+}
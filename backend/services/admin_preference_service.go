@@ -0,0 +1,61 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidAdminPreferences is returned when a language, timezone or page size is rejected
+var ErrInvalidAdminPreferences = errors.New("invalid admin preferences")
+
+// AdminPreferenceService stores each admin user's display preferences
+type AdminPreferenceService struct{}
+
+// NewAdminPreferenceService creates a new AdminPreferenceService instance
+func NewAdminPreferenceService() *AdminPreferenceService {
+	return &AdminPreferenceService{}
+}
+
+// GetPreferences returns an admin's preferences, or the defaults when the
+// admin never saved any
+func (s *AdminPreferenceService) GetPreferences(ctx context.Context, adminUserID uuid.UUID) (models.AdminPreferences, error) {
+	var preferences models.AdminPreferences
+	err := config.GetDBWithContext(ctx).First(&preferences, "admin_user_id = ?", adminUserID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		preferences = models.DefaultAdminPreferences()
+		preferences.AdminUserID = adminUserID
+		return preferences, nil
+	}
+	if err != nil {
+		return models.AdminPreferences{}, fmt.Errorf("failed to fetch admin preferences: %w", err)
+	}
+	return preferences, nil
+}
+
+// SavePreferences validates and stores an admin's preferences
+func (s *AdminPreferenceService) SavePreferences(ctx context.Context, preferences *models.AdminPreferences) error {
+	preferences.Language = strings.ToLower(strings.TrimSpace(preferences.Language))
+	preferences.Timezone = strings.TrimSpace(preferences.Timezone)
+	if err := preferences.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAdminPreferences, err)
+	}
+
+	preferences.UpdatedAt = time.Now().UTC()
+	err := config.GetDBWithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "admin_user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"language", "timezone", "page_size", "updated_at"}),
+	}).Create(preferences).Error
+	if err != nil {
+		return fmt.Errorf("failed to save admin preferences: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm"
+)
+
+// alertLog logs the alert monitor
+var alertLog = logging.Component("alerts")
+
+// ErrAlertRuleNotFound is returned when an alert rule ID does not exist
+var ErrAlertRuleNotFound = errors.New("alert rule not found")
+
+// AlertService manages operational alert rules and evaluates them
+type AlertService struct {
+	runCollection   *mongo.Collection
+	priceCollection *mongo.Collection
+	notifications   *NotificationService
+}
+
+// NewAlertService creates a new AlertService instance
+func NewAlertService(notifications *NotificationService) *AlertService {
+	return &AlertService{
+		runCollection:   config.GetCollection("crawl_runs"),
+		priceCollection: config.GetCollection("stock_prices"),
+		notifications:   notifications,
+	}
+}
+
+// Channels returns the notification channels rules may route to
+func (s *AlertService) Channels() []string {
+	return s.notifications.Channels()
+}
+
+// ListRules returns all alert rules ordered by creation time
+func (s *AlertService) ListRules(ctx context.Context) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	if err := config.GetDBWithContext(ctx).Order("created_at ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// FiringRules returns the enabled rules currently firing, oldest notification first
+func (s *AlertService) FiringRules(ctx context.Context) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	err := config.GetDBWithContext(ctx).
+		Where("enabled = ? AND state = ?", true, models.AlertStateFiring).
+		Order("last_notified_at ASC").
+		Find(&rules).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch firing alert rules: %w", err)
+	}
+	return rules, nil
+}
+
+// CreateRule validates and stores a new alert rule
+func (s *AlertService) CreateRule(rule *models.AlertRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	rule.ID = uuid.New()
+	rule.State = models.AlertStateOK
+	if err := config.GetDB().Create(rule).Error; err != nil {
+		return fmt.Errorf("failed to create alert rule: %w", err)
+	}
+	return nil
+}
+
+// UpdateRule replaces the editable fields of an existing alert rule
+func (s *AlertService) UpdateRule(id string, changes models.AlertRule) (*models.AlertRule, error) {
+	rule, err := s.getRule(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Name = changes.Name
+	rule.Type = changes.Type
+	rule.Threshold = changes.Threshold
+	rule.Channels = changes.Channels
+	rule.Enabled = changes.Enabled
+	rule.CooldownMinutes = changes.CooldownMinutes
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	rule.UpdatedAt = time.Now().UTC()
+	if err := config.GetDB().Model(rule).
+		Select("name", "type", "threshold", "channels", "enabled", "cooldown_minutes", "updated_at").
+		Updates(rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to update alert rule: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule removes an alert rule
+func (s *AlertService) DeleteRule(id string) error {
+	result := config.GetDB().Delete(&models.AlertRule{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAlertRuleNotFound
+	}
+	return nil
+}
+
+// getRule loads a single alert rule by ID
+func (s *AlertService) getRule(id string) (*models.AlertRule, error) {
+	var rule models.AlertRule
+	err := config.GetDB().First(&rule, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAlertRuleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch alert rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// CollectMetrics gathers the data-operation metrics alert rules are evaluated against
+func (s *AlertService) CollectMetrics(ctx context.Context) (models.OpsMetrics, error) {
+	var metrics models.OpsMetrics
+
+	// Most recent successful full crawl run (also used for the failure ratio);
+	// retry and intraday runs cover only part of the universe and would skew both metrics
+	var latest models.CrawlRun
+	opts := options.FindOne().SetSort(bson.D{{Key: "finishedAt", Value: -1}})
+	filter := bson.M{"status": models.CrawlRunStatusSuccess, "kind": bson.M{"$nin": models.PartialCrawlRunKinds}}
+	err := s.runCollection.FindOne(ctx, filter, opts).Decode(&latest)
+	if err == nil {
+		metrics.LatestRun = &latest
+		if latest.FinishedAt != nil {
+			finishedAt := latest.FinishedAt.Time()
+			metrics.LastSuccessfulCrawlAt = &finishedAt
+		}
+	} else if err != mongo.ErrNoDocuments {
+		return metrics, fmt.Errorf("failed to fetch latest crawl run: %w", err)
+	}
+
+	// The latest run may still be verifying its sample, so the newest run
+	// carrying a verification is used
+	var verified models.CrawlRun
+	filter = bson.M{"verification": bson.M{"$exists": true}}
+	err = s.runCollection.FindOne(ctx, filter, opts).Decode(&verified)
+	if err == nil {
+		metrics.LatestVerification = verified.Verification
+	} else if err != mongo.ErrNoDocuments {
+		return metrics, fmt.Errorf("failed to fetch latest crawl verification: %w", err)
+	}
+
+	freshest, err := s.freshestCandleDate(ctx)
+	if err != nil {
+		return metrics, err
+	}
+	metrics.FreshestCandleDate = freshest
+
+	return metrics, nil
+}
+
+// freshestCandleDate returns the newest candle date across all price buckets
+func (s *AlertService) freshestCandleDate(ctx context.Context) (*time.Time, error) {
+	// Only buckets of the latest year can contain the freshest candle
+	var newest models.PriceBucket
+	opts := options.FindOne().SetSort(bson.D{{Key: "year", Value: -1}}).SetProjection(bson.M{"year": 1})
+	err := s.priceCollection.FindOne(ctx, bson.M{}, opts).Decode(&newest)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find latest price bucket: %w", err)
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"year": newest.Year}}},
+		// Columnar buckets record their newest date; plain ones are scanned
+		{{Key: "$project", Value: bson.M{"last": bson.M{"$ifNull": bson.A{"$lastDate", bson.M{"$max": "$history.d"}}}}}},
+		{{Key: "$group", Value: bson.M{"_id": nil, "last": bson.M{"$max": "$last"}}}},
+	}
+	cursor, err := s.priceCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate freshest candle: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var result []struct {
+		Last string `bson:"last"`
+	}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode freshest candle: %w", err)
+	}
+	if len(result) == 0 || result[0].Last == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("2006-01-02", result[0].Last)
+	if err != nil {
+		return nil, fmt.Errorf("invalid candle date %q: %w", result[0].Last, err)
+	}
+	return &date, nil
+}
+
+// EvaluateRules evaluates every enabled rule once, notifying channels when a
+// rule starts firing, keeps firing past its cooldown, or recovers.
+func (s *AlertService) EvaluateRules(ctx context.Context) error {
+	var rules []models.AlertRule
+	if err := config.GetDB().Where("enabled = ?", true).Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to fetch enabled alert rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+
+	metrics, err := s.CollectMetrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for i := range rules {
+		s.evaluateRule(ctx, &rules[i], metrics, now)
+	}
+	return nil
+}
+
+// evaluateRule evaluates a single rule and persists its new state
+func (s *AlertService) evaluateRule(ctx context.Context, rule *models.AlertRule, metrics models.OpsMetrics, now time.Time) {
+	firing, message := rule.Evaluate(metrics, now)
+	wasFiring := rule.State == models.AlertStateFiring
+
+	notify := false
+	severity := ""
+	switch {
+	case firing && !wasFiring:
+		notify, severity = true, SeverityCritical
+	case firing && wasFiring:
+		cooldown := time.Duration(rule.CooldownMinutes) * time.Minute
+		if rule.LastNotifiedAt == nil || now.Sub(*rule.LastNotifiedAt) >= cooldown {
+			notify, severity = true, SeverityCritical
+		}
+	case !firing && wasFiring:
+		notify, severity = true, SeverityResolved
+	}
+
+	updates := map[string]interface{}{
+		"state":             models.AlertStateOK,
+		"last_message":      message,
+		"last_evaluated_at": now,
+	}
+	if firing {
+		updates["state"] = models.AlertStateFiring
+	}
+
+	if notify {
+		title := fmt.Sprintf("Alert: %s", rule.Name)
+		if severity == SeverityResolved {
+			title = fmt.Sprintf("Resolved: %s", rule.Name)
+		}
+		err := s.notifications.Send(ctx, rule.ChannelList(), Notification{
+			Title:    title,
+			Message:  message,
+			Severity: severity,
+			Source:   "alert_monitor",
+			Fields: map[string]interface{}{
+				"rule_id":   rule.ID.String(),
+				"rule_type": rule.Type,
+				"threshold": rule.Threshold,
+			},
+		})
+		if err != nil {
+			alertLog.Warn("Failed to evaluate alert rule", "rule", rule.Name, logging.FieldError, err)
+		}
+		updates["last_notified_at"] = now
+	}
+
+	if err := config.GetDB().Model(rule).UpdateColumns(updates).Error; err != nil {
+		alertLog.Warn("Failed to save alert rule state", "rule", rule.Name, logging.FieldError, err)
+	}
+}
+
+// StartMonitor evaluates alert rules until ctx is cancelled. The interval is
+// re-read from the runtime configuration after every evaluation.
+func (s *AlertService) StartMonitor(ctx context.Context) {
+	go func() {
+		interval := config.Runtime().AlertMonitorInterval
+		alertLog.Info("Alert monitor started", "interval", interval.String())
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				alertLog.Info("Alert monitor stopped")
+				return
+			case <-timer.C:
+				evalCtx, cancel := context.WithTimeout(ctx, interval)
+				if err := s.EvaluateRules(evalCtx); err != nil {
+					alertLog.Warn("Alert monitor pass failed", logging.FieldError, err)
+				}
+				cancel()
+
+				interval = config.Runtime().AlertMonitorInterval
+				timer.Reset(interval)
+			}
+		}
+	}()
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// apiKeyPrefix marks CPLS API keys so they are easy to recognise in secret scanners
+	apiKeyPrefix = "cpls_"
+	// apiKeyDisplayLength is how many leading characters are stored in plaintext for identification
+	apiKeyDisplayLength = 12
+	// apiKeyTouchInterval limits how often last_used_at is written for a busy key
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrInvalidAPIKey is returned when a key does not exist or has been revoked
+	ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+	// ErrAPIKeyNotFound is returned when an API key ID does not exist
+	ErrAPIKeyNotFound = errors.New("API key not found")
+)
+
+// APIKeyService manages API keys for external data consumers
+type APIKeyService struct{}
+
+// NewAPIKeyService creates a new APIKeyService instance
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{}
+}
+
+// hashAPIKey returns the hex-encoded SHA-256 hash of a plaintext key
+func hashAPIKey(plaintext string) string {
+	sum := sha256.Sum256([]byte(plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateKey generates a new API key with the given scopes. The plaintext key
+// is returned only here; afterwards only its hash is known.
+func (s *APIKeyService) CreateKey(name string, scopes []string, responseFormat, createdBy string) (*models.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("name is required")
+	}
+	normalized, err := models.NormalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if responseFormat, err = models.NormalizeResponseFormat(responseFormat); err != nil {
+		return nil, "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	plaintext := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &models.APIKey{
+		ID:             uuid.New(),
+		Name:           name,
+		Prefix:         plaintext[:apiKeyDisplayLength],
+		KeyHash:        hashAPIKey(plaintext),
+		Scopes:         normalized,
+		ResponseFormat: responseFormat,
+	}
+	if createdBy != "" {
+		key.CreatedBy = &createdBy
+	}
+
+	if err := config.GetDB().Create(key).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+	return key, plaintext, nil
+}
+
+// ListKeys returns all API keys, newest first
+func (s *APIKeyService) ListKeys(ctx context.Context) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := config.GetDBWithContext(ctx).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeKey marks an API key as revoked; revoked keys are rejected immediately
+func (s *APIKeyService) RevokeKey(id string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := config.GetDB().First(&key, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+
+	if key.RevokedAt == nil {
+		now := time.Now().UTC()
+		if err := config.GetDB().Model(&key).UpdateColumn("revoked_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to revoke API key: %w", err)
+		}
+		key.RevokedAt = &now
+	}
+	return &key, nil
+}
+
+// SetResponseFormat pins the response format served to a key ("" reverts to the server default)
+func (s *APIKeyService) SetResponseFormat(id, responseFormat string) (*models.APIKey, error) {
+	normalized, err := models.NormalizeResponseFormat(responseFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	var key models.APIKey
+	err = config.GetDB().First(&key, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch API key: %w", err)
+	}
+
+	if err := config.GetDB().Model(&key).UpdateColumn("response_format", normalized).Error; err != nil {
+		return nil, fmt.Errorf("failed to update API key: %w", err)
+	}
+	key.ResponseFormat = normalized
+	return &key, nil
+}
+
+// Authenticate resolves a plaintext key to an active API key
+func (s *APIKeyService) Authenticate(plaintext string) (*models.APIKey, error) {
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var key models.APIKey
+	err := config.GetDB().Where("key_hash = ? AND revoked_at IS NULL", hashAPIKey(plaintext)).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up API key: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := config.GetDB().Model(&key).UpdateColumn("last_used_at", now).Error; err != nil {
+			authLog.Warn("Failed to update last_used_at of API key", "prefix", key.Prefix, logging.FieldError, err)
+		}
+	}
+
+	return &key, nil
+}
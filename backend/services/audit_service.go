@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+)
+
+// maxAuditLogLimit caps the rows returned by one audit log query
+const maxAuditLogLimit = 500
+
+const (
+	// auditTimeZone is the zone audit activity is bucketed into days in
+	auditTimeZone = "Asia/Ho_Chi_Minh"
+	// defaultAuditEntityLimit is how many entities MostModifiedEntities returns by default
+	defaultAuditEntityLimit = 10
+)
+
+// AuditActivity counts the audited actions of one actor on one day
+type AuditActivity struct {
+	Date     string `json:"date"` // YYYY-MM-DD in Vietnam time
+	Actor    string `json:"actor"`
+	Actions  int64  `json:"actions"`
+	Failures int64  `json:"failures"` // Actions whose outcome was not success
+}
+
+// AuditEntityActivity counts the changes recorded for one entity
+type AuditEntityActivity struct {
+	EntityType    string    `json:"entity_type"`
+	EntityID      string    `json:"entity_id"`
+	Changes       int64     `json:"changes"`
+	Actors        int64     `json:"actors"` // Distinct actors that changed it
+	LastChangedAt time.Time `json:"last_changed_at"`
+}
+
+// AuditEntry describes one action to record in the audit log
+type AuditEntry struct {
+	Actor      string
+	Action     string
+	Outcome    string
+	EntityType string
+	EntityID   string
+	IP         string
+	UserAgent  string
+	Details    map[string]interface{}
+}
+
+// AuditFilter selects audit log rows (empty fields match everything)
+type AuditFilter struct {
+	Action  string
+	Actor   string
+	Outcome string
+	Since   *time.Time
+	Limit   int
+}
+
+// AuditService records security-relevant actions in the audit_logs table
+type AuditService struct{}
+
+// NewAuditService creates a new AuditService instance
+func NewAuditService() *AuditService {
+	return &AuditService{}
+}
+
+// Record appends an entry to the audit log. Failures are logged rather than
+// returned so that auditing never blocks the action being audited.
+func (s *AuditService) Record(ctx context.Context, entry AuditEntry) {
+	row := models.AuditLog{
+		ID:         uuid.New(),
+		Actor:      entry.Actor,
+		Action:     entry.Action,
+		Outcome:    entry.Outcome,
+		EntityType: optionalString(entry.EntityType),
+		EntityID:   optionalString(entry.EntityID),
+		IP:         optionalString(entry.IP),
+		UserAgent:  optionalString(entry.UserAgent),
+	}
+	if len(entry.Details) > 0 {
+		if details, err := json.Marshal(entry.Details); err == nil {
+			row.Details = optionalString(string(details))
+		}
+	}
+
+	if config.PostgresDB == nil {
+		logging.FromContext(ctx).Warn("Supabase not connected, audit log not recorded", "action", entry.Action, "outcome", entry.Outcome, "actor", entry.Actor, "details", entry.Details)
+		return
+	}
+	if err := config.GetDBWithContext(ctx).Create(&row).Error; err != nil {
+		logging.FromContext(ctx).Warn("Failed to record audit log", "action", entry.Action, "outcome", entry.Outcome, "actor", entry.Actor, logging.FieldError, err)
+	}
+}
+
+// List returns audit log rows matching filter, newest first
+func (s *AuditService) List(ctx context.Context, filter AuditFilter) ([]models.AuditLog, error) {
+	if filter.Limit <= 0 || filter.Limit > maxAuditLogLimit {
+		filter.Limit = maxAuditLogLimit
+	}
+
+	query := config.GetDBWithContext(ctx).Order("created_at DESC").Limit(filter.Limit)
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", *filter.Since)
+	}
+
+	var logs []models.AuditLog
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch audit logs: %w", err)
+	}
+	return logs, nil
+}
+
+// ActivityByActor counts audited actions per actor and day since since,
+// oldest day first. Payment webhooks are left out: they are not admin actions.
+func (s *AuditService) ActivityByActor(ctx context.Context, since time.Time) ([]AuditActivity, error) {
+	day := fmt.Sprintf("to_char(created_at AT TIME ZONE '%s', 'YYYY-MM-DD')", auditTimeZone)
+	var activity []AuditActivity
+	err := config.GetDBWithContext(ctx).Model(&models.AuditLog{}).
+		Select(day+" AS date, actor, COUNT(*) AS actions, COUNT(*) FILTER (WHERE outcome <> ?) AS failures", models.AuditOutcomeSuccess).
+		Where("created_at >= ? AND action <> ?", since, models.AuditActionPayment).
+		Group("date, actor").
+		Order("date ASC, actions DESC, actor ASC").
+		Scan(&activity).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate audit activity: %w", err)
+	}
+	return activity, nil
+}
+
+// MostModifiedEntities returns the entities with the most successful
+// changes since since, most changed first
+func (s *AuditService) MostModifiedEntities(ctx context.Context, since time.Time, limit int) ([]AuditEntityActivity, error) {
+	if limit <= 0 {
+		limit = defaultAuditEntityLimit
+	}
+	if limit > maxAuditLogLimit {
+		limit = maxAuditLogLimit
+	}
+
+	var entities []AuditEntityActivity
+	err := config.GetDBWithContext(ctx).Model(&models.AuditLog{}).
+		Select("entity_type, entity_id, COUNT(*) AS changes, COUNT(DISTINCT actor) AS actors, MAX(created_at) AS last_changed_at").
+		Where("created_at >= ? AND entity_id IS NOT NULL AND outcome = ? AND action IN ?", since, models.AuditOutcomeSuccess, models.ModifyingAuditActions).
+		Group("entity_type, entity_id").
+		Order("changes DESC, last_changed_at DESC").
+		Limit(limit).
+		Scan(&entities).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate modified entities: %w", err)
+	}
+	return entities, nil
+}
+
+// RecentSensitive returns the newest sensitive actions since since: the
+// actions in models.SensitiveAuditActions and logins that did not succeed
+func (s *AuditService) RecentSensitive(ctx context.Context, since time.Time, limit int) ([]models.AuditLog, error) {
+	if limit <= 0 || limit > maxAuditLogLimit {
+		limit = maxAuditLogLimit
+	}
+
+	var logs []models.AuditLog
+	err := config.GetDBWithContext(ctx).
+		Where("created_at >= ?", since).
+		Where("action IN ? OR (action = ? AND outcome <> ?)", models.SensitiveAuditActions, models.AuditActionLogin, models.AuditOutcomeSuccess).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sensitive audit logs: %w", err)
+	}
+	return logs, nil
+}
+
+// optionalString returns nil for empty strings, for nullable columns
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
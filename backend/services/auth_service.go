@@ -0,0 +1,272 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// authLog logs credential checks, which run before a request is attributed to anyone
+var authLog = logging.Component("auth")
+
+const (
+	// MinPasswordLength is the minimum accepted length for admin passwords
+	MinPasswordLength = 8
+
+	// bcryptCost is the work factor used when hashing admin passwords
+	bcryptCost = 12
+)
+
+var (
+	// ErrInvalidCredentials is returned when the username/password pair does not match
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	// ErrAccountLocked is returned when the admin was locked after too many failed logins
+	ErrAccountLocked = errors.New("account is locked")
+	// ErrAdminUserNotFound is returned when an admin user ID does not exist
+	ErrAdminUserNotFound = errors.New("admin user not found")
+	// ErrPasswordTooShort is returned when a new password is shorter than MinPasswordLength
+	ErrPasswordTooShort = fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+
+	// dummyPasswordHash is compared against when the user does not exist so that
+	// unknown usernames take as long to reject as wrong passwords
+	dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("cpls-dummy-password"), bcryptCost)
+)
+
+// AuthService handles admin authentication against the admin_users table
+type AuthService struct{}
+
+// NewAuthService creates a new AuthService instance
+func NewAuthService() *AuthService {
+	return &AuthService{}
+}
+
+// HashPassword returns the bcrypt hash of a plaintext password
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", ErrPasswordTooShort
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Authenticate verifies an admin's credentials. The identifier may be either
+// the username or the email address. Inactive admins and admins without a
+// password hash are rejected with ErrInvalidCredentials; locked admins with
+// ErrAccountLocked, only once the password is verified, so the lockout tells
+// nothing to someone guessing usernames or passwords.
+func (s *AuthService) Authenticate(identifier, password string) (*models.AdminUser, error) {
+	identifier = strings.TrimSpace(identifier)
+	if identifier == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	var adminUser models.AdminUser
+	db := config.GetDB()
+
+	err := db.Where("(username = ? OR email = ?) AND active = ?", identifier, strings.ToLower(identifier), true).
+		First(&adminUser).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		authLog.Error("Failed to look up admin user", logging.FieldError, err)
+		return nil, fmt.Errorf("failed to look up admin user: %w", err)
+	}
+
+	if adminUser.PasswordHash == nil || *adminUser.PasswordHash == "" {
+		authLog.Warn("Admin has no password set, run cmd/create-admin", "admin_user_id", adminUser.ID.String())
+		bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(*adminUser.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if adminUser.Locked() {
+		return nil, ErrAccountLocked
+	}
+
+	return &adminUser, nil
+}
+
+// BootstrapAdmin creates an active super_admin with the given credentials, or
+// resets the password of the existing admin with that email and lifts its
+// lockout. It is intended for provisioning the first admin account from the
+// command line, and for recovering a locked one.
+func (s *AuthService) BootstrapAdmin(email, username, password string) (*models.AdminUser, bool, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	username = strings.TrimSpace(username)
+	if email == "" {
+		return nil, false, fmt.Errorf("email is required")
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Silence SQL logging so the password hash does not end up in the logs
+	db := config.PostgresDB.Session(&gorm.Session{Logger: logger.Default.LogMode(logger.Silent)})
+
+	var adminUser models.AdminUser
+	err = db.Where("email = ?", email).First(&adminUser).Error
+	if err == nil {
+		updates := map[string]interface{}{
+			"password_hash":         hash,
+			"active":                true,
+			"failed_login_attempts": 0,
+			"locked_at":             nil,
+			"locked_until":          nil,
+		}
+		if username != "" {
+			updates["username"] = username
+		}
+		if err := db.Model(&adminUser).Updates(updates).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to update admin user: %w", err)
+		}
+		return &adminUser, false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to look up admin user: %w", err)
+	}
+
+	adminUser = models.AdminUser{
+		ID:           uuid.New(),
+		Email:        email,
+		Role:         "super_admin",
+		Active:       true,
+		PasswordHash: &hash,
+	}
+	if username != "" {
+		adminUser.Username = &username
+	}
+	if err := db.Create(&adminUser).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to create admin user: %w", err)
+	}
+	return &adminUser, true, nil
+}
+
+// GetActiveAdmin loads an active admin user by ID, returning ErrInvalidCredentials
+// when the admin no longer exists, has been deactivated or is locked
+func (s *AuthService) GetActiveAdmin(id string) (*models.AdminUser, error) {
+	var adminUser models.AdminUser
+	err := config.GetDB().Where("id = ? AND active = ? AND (locked_until IS NULL OR locked_until <= now())", id, true).First(&adminUser).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up admin user: %w", err)
+	}
+	return &adminUser, nil
+}
+
+// RecordLoginFailure counts a failed login for the admin with the given
+// username or email and locks the account for lockout once maxFailures
+// consecutive failures are reached. Failures while locked do not extend the
+// lockout; the count starts over once it has expired. It returns nil when no
+// such admin exists.
+func (s *AuthService) RecordLoginFailure(identifier string, maxFailures int, lockout time.Duration) (*models.AdminUser, error) {
+	identifier = strings.TrimSpace(identifier)
+	if identifier == "" {
+		return nil, nil
+	}
+
+	// Every SET expression sees the row as it was before the update
+	var adminUsers []models.AdminUser
+	err := config.GetDB().Raw(`
+		UPDATE public.admin_users
+		SET failed_login_attempts = CASE WHEN @expired THEN 1 ELSE failed_login_attempts + 1 END,
+		    locked_at = CASE
+		        WHEN locked_until > now() THEN locked_at
+		        WHEN (CASE WHEN @expired THEN 1 ELSE failed_login_attempts + 1 END) >= @max THEN now()
+		        ELSE NULL END,
+		    locked_until = CASE
+		        WHEN locked_until > now() THEN locked_until
+		        WHEN (CASE WHEN @expired THEN 1 ELSE failed_login_attempts + 1 END) >= @max THEN now() + make_interval(secs => @lockout)
+		        ELSE NULL END
+		WHERE username = @identifier OR email = @email
+		RETURNING *`, map[string]interface{}{
+		"expired":    gorm.Expr("locked_until IS NOT NULL AND locked_until <= now()"),
+		"max":        maxFailures,
+		"lockout":    lockout.Seconds(),
+		"identifier": identifier,
+		"email":      strings.ToLower(identifier),
+	}).Scan(&adminUsers).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failed login: %w", err)
+	}
+	if len(adminUsers) == 0 {
+		return nil, nil
+	}
+	return &adminUsers[0], nil
+}
+
+// RecordLogin stores a successful login: it updates last_login, clears the
+// failed login counter and appends the login to the admin's history
+func (s *AuthService) RecordLogin(adminUser *models.AdminUser, ip, userAgent, channel string) error {
+	now := time.Now().UTC()
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(adminUser).UpdateColumns(map[string]interface{}{
+			"last_login":            now,
+			"failed_login_attempts": 0,
+			"locked_at":             nil,
+			"locked_until":          nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.LoginHistory{
+			ID:          uuid.New(),
+			AdminUserID: adminUser.ID,
+			IP:          optionalString(ip),
+			UserAgent:   optionalString(userAgent),
+			Channel:     channel,
+			CreatedAt:   now,
+		}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record login: %w", err)
+	}
+	adminUser.LastLogin = &now
+	adminUser.FailedLoginAttempts = 0
+	adminUser.LockedAt, adminUser.LockedUntil = nil, nil
+	return nil
+}
+
+// UnlockAdmin clears an admin's lockout and failed login counter
+func (s *AuthService) UnlockAdmin(id string) (*models.AdminUser, error) {
+	var adminUser models.AdminUser
+	err := config.GetDB().First(&adminUser, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAdminUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up admin user: %w", err)
+	}
+
+	err = config.GetDB().Model(&adminUser).UpdateColumns(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"locked_at":             nil,
+		"locked_until":          nil,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to unlock admin user: %w", err)
+	}
+	adminUser.FailedLoginAttempts = 0
+	adminUser.LockedAt, adminUser.LockedUntil = nil, nil
+	return &adminUser, nil
+}
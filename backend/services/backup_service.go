@@ -0,0 +1,552 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// backupLog logs nightly backups
+var backupLog = logging.Component("backup")
+
+var (
+	// ErrBackupNotConfigured is returned when BACKUP_GCS_BUCKET is not set
+	ErrBackupNotConfigured = errors.New("backups are not configured (BACKUP_GCS_BUCKET)")
+	// ErrBackupRunning is returned when a backup is already in progress
+	ErrBackupRunning = errors.New("a backup is already running")
+)
+
+// backupMongoCollections lists the MongoDB collections dumped by a backup,
+// with the fields left out. Price buckets are backed up as metadata only
+// (code, year, checksum, encoding); their candles can be crawled again.
+var backupMongoCollections = []struct {
+	collection string
+	exclude    []string
+}{
+	{collection: "stocks"},
+	{collection: "stock_prices", exclude: []string{"history", "packed"}},
+}
+
+// backupPostgresTables lists the Supabase tables dumped by a backup
+var backupPostgresTables = []string{
+	"public.admin_users",
+	"public.profiles",
+	"public.payments",
+}
+
+// BackupService dumps critical collections and tables to Google Cloud
+// Storage every night, verifies that the dump can be restored and rotates
+// old backups
+type BackupService struct {
+	gcs           *GCSClient
+	prefix        string
+	notifications *NotificationService
+	jobs          *JobQueue // Runs backups started with Trigger
+
+	mu      sync.Mutex
+	running bool
+	last    *models.BackupReport
+}
+
+// NewBackupServiceFromEnv creates a BackupService writing to BACKUP_GCS_BUCKET
+// under BACKUP_GCS_PREFIX (default "cpls-backups"). Backups are disabled
+// when the bucket is not set. The backup job handler is registered on jobs.
+func NewBackupServiceFromEnv(notifications *NotificationService, jobs *JobQueue) *BackupService {
+	s := &BackupService{
+		prefix:        strings.Trim(os.Getenv("BACKUP_GCS_PREFIX"), "/"),
+		notifications: notifications,
+		jobs:          jobs,
+	}
+	jobs.Handle(models.JobKindBackup, s.runBackupJob)
+	if s.prefix == "" {
+		s.prefix = "cpls-backups"
+	}
+	if bucket := os.Getenv("BACKUP_GCS_BUCKET"); bucket != "" {
+		s.gcs = NewGCSClient(bucket)
+	}
+	return s
+}
+
+// Configured reports whether a backup bucket is set
+func (s *BackupService) Configured() bool {
+	return s.gcs != nil
+}
+
+// LastReport returns the outcome of the latest backup run of this instance, or nil
+func (s *BackupService) LastReport() *models.BackupReport {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// StartNightlyJob runs a backup every night at backup.time (market time)
+func (s *BackupService) StartNightlyJob(ctx context.Context) {
+	if !s.Configured() {
+		return
+	}
+	go func() {
+		for {
+			next := nextBackupAt(time.Now(), config.Runtime().BackupTime)
+			backupLog.Info("Nightly backup scheduled", "destination", "gs://"+s.gcs.Bucket()+"/"+s.prefix, "next", next.Format(time.RFC3339))
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				runCtx, cancel := context.WithTimeout(ctx, 2*time.Hour)
+				if _, err := s.Run(runCtx); err != nil && !errors.Is(err, ErrBackupRunning) {
+					backupLog.Warn("Nightly backup failed", logging.FieldError, err)
+				}
+				cancel()
+			}
+		}
+	}()
+}
+
+// Running reports whether a backup is in progress on this instance
+func (s *BackupService) Running() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+// Trigger queues a backup on the job queue, failing fast when backups are
+// not configured or one is already queued or running
+func (s *BackupService) Trigger() error {
+	if !s.Configured() {
+		return ErrBackupNotConfigured
+	}
+	if s.Running() {
+		return ErrBackupRunning
+	}
+	_, err := s.jobs.Enqueue(context.Background(), models.JobKindBackup, struct{}{}, JobOptions{
+		UniqueKey:   models.JobKindBackup,
+		MaxAttempts: 3,
+	})
+	if errors.Is(err, ErrJobDuplicate) {
+		return ErrBackupRunning
+	}
+	return err
+}
+
+// runBackupJob runs a backup job, waiting while the nightly backup runs on
+// this instance
+func (s *BackupService) runBackupJob(ctx context.Context, job *models.Job) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Hour)
+	defer cancel()
+	_, err := s.Run(ctx)
+	if errors.Is(err, ErrBackupRunning) {
+		return DeferJob(time.Minute, err)
+	}
+	return err
+}
+
+// Run dumps every backed-up collection and table, verifies the upload,
+// deletes backups past retention and notifies every channel of the result
+func (s *BackupService) Run(ctx context.Context) (*models.BackupReport, error) {
+	if !s.Configured() {
+		return nil, ErrBackupNotConfigured
+	}
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return nil, ErrBackupRunning
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	report := s.run(ctx)
+
+	s.mu.Lock()
+	s.running = false
+	s.last = report
+	s.mu.Unlock()
+
+	s.notify(ctx, report)
+	if report.Status == models.BackupStatusFailed {
+		return report, errors.New(report.Error)
+	}
+	return report, nil
+}
+
+// run performs a backup and returns its report
+func (s *BackupService) run(ctx context.Context) *models.BackupReport {
+	startedAt := time.Now().UTC()
+	date := markettime.Date(startedAt)
+	report := &models.BackupReport{Date: date, Status: models.BackupStatusFailed, StartedAt: startedAt}
+	fail := func(err error) *models.BackupReport {
+		report.Error = err.Error()
+		report.CompletedAt = time.Now().UTC()
+		backupLog.Error("Backup failed", "date", date, logging.FieldError, err)
+		return report
+	}
+
+	manifest, err := s.dump(ctx, date, startedAt)
+	if err != nil {
+		return fail(err)
+	}
+	report.Manifest = manifest
+
+	if err := s.verify(ctx, manifest); err != nil {
+		return fail(fmt.Errorf("restore verification failed: %w", err))
+	}
+	report.Verified = true
+
+	deleted, err := s.rotate(ctx, date)
+	report.Deleted = deleted
+	if err != nil {
+		return fail(fmt.Errorf("retention rotation failed: %w", err))
+	}
+
+	report.Status = models.BackupStatusSucceeded
+	report.CompletedAt = time.Now().UTC()
+	backupLog.Info("Backup stored and verified", "date", date, "objects", len(manifest.Objects), "deleted_backups", len(deleted))
+	return report
+}
+
+// ListBackups returns the dates of the stored backups, newest first
+func (s *BackupService) ListBackups(ctx context.Context) ([]string, error) {
+	if !s.Configured() {
+		return nil, ErrBackupNotConfigured
+	}
+	prefixes, err := s.gcs.ListPrefixes(ctx, s.prefix+"/")
+	if err != nil {
+		return nil, err
+	}
+	dates := backupDates(prefixes, s.prefix)
+	sort.Sort(sort.Reverse(sort.StringSlice(dates)))
+	return dates, nil
+}
+
+// GetManifest returns the manifest of the backup taken on date
+func (s *BackupService) GetManifest(ctx context.Context, date string) (*models.BackupManifest, error) {
+	if !s.Configured() {
+		return nil, ErrBackupNotConfigured
+	}
+	data, err := s.gcs.Download(ctx, s.object(date, "manifest.json"))
+	if err != nil {
+		return nil, err
+	}
+	var manifest models.BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode backup manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// dump uploads every collection and table, then the manifest
+func (s *BackupService) dump(ctx context.Context, date string, startedAt time.Time) (*models.BackupManifest, error) {
+	manifest := &models.BackupManifest{Date: date, StartedAt: startedAt}
+
+	for _, source := range backupMongoCollections {
+		name := "mongo." + source.collection
+		data, records, err := s.dumpCollection(ctx, source.collection, source.exclude)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		object, err := s.upload(ctx, date, name, data, records)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Objects = append(manifest.Objects, object)
+	}
+
+	for _, table := range backupPostgresTables {
+		name := "postgres." + strings.TrimPrefix(table, "public.")
+		data, records, err := s.dumpTable(ctx, table)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		object, err := s.upload(ctx, date, name, data, records)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Objects = append(manifest.Objects, object)
+	}
+
+	manifest.CompletedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+	if err := s.gcs.Upload(ctx, s.object(date, "manifest.json"), "application/json", data); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// dumpCollection encodes a MongoDB collection as canonical extended JSON lines
+func (s *BackupService) dumpCollection(ctx context.Context, collection string, exclude []string) ([]byte, int64, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	if len(exclude) > 0 {
+		projection := bson.M{}
+		for _, field := range exclude {
+			projection[field] = 0
+		}
+		opts.SetProjection(projection)
+	}
+	cursor, err := config.GetCollection(collection).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	return encodeBackupLines(func(emit func([]byte) error) error {
+		for cursor.Next(ctx) {
+			line, err := bson.MarshalExtJSON(cursor.Current, true, false)
+			if err != nil {
+				return fmt.Errorf("failed to encode document: %w", err)
+			}
+			if err := emit(line); err != nil {
+				return err
+			}
+		}
+		return cursor.Err()
+	})
+}
+
+// dumpTable encodes the rows of a Supabase table as JSON lines
+func (s *BackupService) dumpTable(ctx context.Context, table string) ([]byte, int64, error) {
+	db := config.GetDBWithContext(ctx)
+	rows, err := db.Table(table).Rows()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query: %w", err)
+	}
+	defer rows.Close()
+
+	return encodeBackupLines(func(emit func([]byte) error) error {
+		for rows.Next() {
+			row := make(map[string]interface{})
+			if err := db.ScanRows(rows, &row); err != nil {
+				return fmt.Errorf("failed to scan row: %w", err)
+			}
+			line, err := json.Marshal(row)
+			if err != nil {
+				return fmt.Errorf("failed to encode row: %w", err)
+			}
+			if err := emit(line); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
+
+// upload stores a dumped object and describes it for the manifest
+func (s *BackupService) upload(ctx context.Context, date, name string, data []byte, records int64) (models.BackupObject, error) {
+	object := models.BackupObject{
+		Name:    name,
+		Object:  s.object(date, name+".jsonl.gz"),
+		Records: records,
+		Bytes:   int64(len(data)),
+		SHA256:  sha256Hex(data),
+	}
+	if err := s.gcs.Upload(ctx, object.Object, "application/gzip", data); err != nil {
+		return object, err
+	}
+	return object, nil
+}
+
+// verify downloads every object of a backup and checks that it matches the
+// manifest and that every record decodes, as a restore would need
+func (s *BackupService) verify(ctx context.Context, manifest *models.BackupManifest) error {
+	for _, object := range manifest.Objects {
+		data, err := s.gcs.Download(ctx, object.Object)
+		if err != nil {
+			return err
+		}
+		if err := verifyBackupObject(object, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotate deletes the backups older than backup.retention_days
+func (s *BackupService) rotate(ctx context.Context, today string) ([]string, error) {
+	dates, err := s.ListBackups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deleted := make([]string, 0)
+	for _, date := range expiredBackupDates(dates, today, config.Runtime().BackupRetentionDays) {
+		objects, err := s.gcs.ListObjects(ctx, s.object(date, ""))
+		if err != nil {
+			return deleted, err
+		}
+		for _, object := range objects {
+			if err := s.gcs.Delete(ctx, object); err != nil {
+				return deleted, err
+			}
+		}
+		deleted = append(deleted, date)
+	}
+	return deleted, nil
+}
+
+// notify reports a backup run on every notification channel
+func (s *BackupService) notify(ctx context.Context, report *models.BackupReport) {
+	if s.notifications == nil {
+		return
+	}
+	n := Notification{
+		Title:    fmt.Sprintf("Backup %s succeeded", report.Date),
+		Severity: SeverityInfo,
+		Source:   "backup",
+		Fields: map[string]interface{}{
+			"bucket":   s.gcs.Bucket(),
+			"verified": report.Verified,
+			"deleted":  len(report.Deleted),
+		},
+	}
+	if report.Manifest != nil {
+		var size int64
+		for _, object := range report.Manifest.Objects {
+			size += object.Bytes
+		}
+		n.Message = fmt.Sprintf("%d objects (%d bytes) stored and verified", len(report.Manifest.Objects), size)
+	}
+	if report.Status == models.BackupStatusFailed {
+		n.Title = fmt.Sprintf("Backup %s failed", report.Date)
+		n.Message = report.Error
+		n.Severity = SeverityCritical
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 15*time.Second)
+	defer cancel()
+	if err := s.notifications.Send(ctx, s.notifications.Channels(), n); err != nil {
+		backupLog.Warn("Failed to send backup notification", logging.FieldError, err)
+	}
+}
+
+// object returns the object name of a file in the backup of date
+func (s *BackupService) object(date, file string) string {
+	return s.prefix + "/" + date + "/" + file
+}
+
+// encodeBackupLines gzips the lines produced by write, one per record
+func encodeBackupLines(write func(emit func([]byte) error) error) ([]byte, int64, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	var records int64
+	err := write(func(line []byte) error {
+		records++
+		if _, err := gz.Write(line); err != nil {
+			return err
+		}
+		_, err := gz.Write([]byte{'\n'})
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), records, nil
+}
+
+// verifyBackupObject checks the checksum and record count of a downloaded
+// object and that every line decodes: MongoDB dumps as extended JSON,
+// Supabase dumps as JSON objects
+func verifyBackupObject(object models.BackupObject, data []byte) error {
+	if sum := sha256Hex(data); sum != object.SHA256 {
+		return fmt.Errorf("%s: checksum %s does not match the manifest (%s)", object.Name, sum, object.SHA256)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", object.Name, err)
+	}
+	defer gz.Close()
+
+	mongoDump := strings.HasPrefix(object.Name, "mongo.")
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var records int64
+	for scanner.Scan() {
+		records++
+		var err error
+		if mongoDump {
+			var doc bson.D
+			err = bson.UnmarshalExtJSON(scanner.Bytes(), true, &doc)
+		} else {
+			var row map[string]interface{}
+			err = json.Unmarshal(scanner.Bytes(), &row)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: record %d does not decode: %w", object.Name, records, err)
+		}
+	}
+	if err := scanner.Err(); err != nil && err != io.EOF {
+		return fmt.Errorf("%s: %w", object.Name, err)
+	}
+	if records != object.Records {
+		return fmt.Errorf("%s: %d records restored, manifest lists %d", object.Name, records, object.Records)
+	}
+	return nil
+}
+
+// backupDates extracts the backup dates from "prefix/YYYY-MM-DD/" folder names
+func backupDates(prefixes []string, prefix string) []string {
+	dates := make([]string, 0, len(prefixes))
+	for _, folder := range prefixes {
+		date := strings.TrimSuffix(strings.TrimPrefix(folder, prefix+"/"), "/")
+		if _, err := time.Parse("2006-01-02", date); err == nil {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+// expiredBackupDates returns the dates more than retentionDays before today
+func expiredBackupDates(dates []string, today string, retentionDays int) []string {
+	current, err := time.Parse("2006-01-02", today)
+	if err != nil {
+		return nil
+	}
+	cutoff := current.AddDate(0, 0, -retentionDays).Format("2006-01-02")
+	expired := make([]string, 0)
+	for _, date := range dates {
+		if date < cutoff {
+			expired = append(expired, date)
+		}
+	}
+	sort.Strings(expired)
+	return expired
+}
+
+// nextBackupAt returns the next time of day hhmm in the market time zone
+// strictly after now, 02:30 when hhmm is invalid
+func nextBackupAt(now time.Time, hhmm string) time.Time {
+	next, err := markettime.NextAt(now, hhmm, nil)
+	if err != nil {
+		next, _ = markettime.NextAt(now, "02:30", nil)
+	}
+	return next
+}
+
+// sha256Hex returns the hex SHA-256 of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestBackupObjectRoundTrip(t *testing.T) {
+	lines := []string{`{"_id":{"$oid":"65a1b2c3d4e5f60718293a4b"},"code":"HPG"}`, `{"_id":"HPG_2025","year":{"$numberInt":"2025"}}`}
+	data, records, err := encodeBackupLines(func(emit func([]byte) error) error {
+		for _, line := range lines {
+			if err := emit([]byte(line)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("encodeBackupLines() error = %v", err)
+	}
+	if records != 2 {
+		t.Fatalf("records = %d; want 2", records)
+	}
+
+	object := models.BackupObject{Name: "mongo.stocks", Records: records, SHA256: sha256Hex(data)}
+	if err := verifyBackupObject(object, data); err != nil {
+		t.Errorf("verifyBackupObject() = %v; want nil", err)
+	}
+
+	wrongCount := object
+	wrongCount.Records = 3
+	if err := verifyBackupObject(wrongCount, data); err == nil {
+		t.Error("verifyBackupObject(wrong record count) = nil; want an error")
+	}
+	corrupted := append([]byte{}, data...)
+	corrupted[len(corrupted)-1] ^= 0xff
+	if err := verifyBackupObject(object, corrupted); err == nil {
+		t.Error("verifyBackupObject(corrupted) = nil; want an error")
+	}
+
+	bad, n, _ := encodeBackupLines(func(emit func([]byte) error) error { return emit([]byte("{not json")) })
+	if err := verifyBackupObject(models.BackupObject{Name: "postgres.payments", Records: n, SHA256: sha256Hex(bad)}, bad); err == nil {
+		t.Error("verifyBackupObject(undecodable row) = nil; want an error")
+	}
+}
+
+func TestExpiredBackupDates(t *testing.T) {
+	dates := backupDates([]string{
+		"cpls-backups/2026-02-10/",
+		"cpls-backups/2026-01-27/",
+		"cpls-backups/2026-01-26/",
+		"cpls-backups/tmp/",
+	}, "cpls-backups")
+	if len(dates) != 3 {
+		t.Fatalf("backupDates() = %v; want the 3 dated folders", dates)
+	}
+
+	got := expiredBackupDates(dates, "2026-02-10", 14)
+	if want := []string{"2026-01-26"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expiredBackupDates() = %v; want %v", got, want)
+	}
+}
+
+func TestNextBackupAt(t *testing.T) {
+	vietnam := time.FixedZone("ICT", 7*60*60)
+	before := time.Date(2026, 2, 10, 1, 0, 0, 0, vietnam)
+	if got, want := nextBackupAt(before, "02:30"), time.Date(2026, 2, 10, 2, 30, 0, 0, vietnam); !got.Equal(want) {
+		t.Errorf("nextBackupAt(01:00) = %v; want %v", got, want)
+	}
+	after := time.Date(2026, 2, 10, 2, 30, 0, 0, vietnam)
+	if got, want := nextBackupAt(after, "02:30"), time.Date(2026, 2, 11, 2, 30, 0, 0, vietnam); !got.Equal(want) {
+		t.Errorf("nextBackupAt(02:30) = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Canary route variants
+const (
+	CanaryControl   = "control"   // The current implementation
+	CanaryCandidate = "candidate" // The implementation being soft-launched
+)
+
+// canaryLatencySamples is how many recent latencies are kept per variant for percentiles
+const canaryLatencySamples = 1024
+
+// CanaryVariantStats summarizes the requests one variant of a canary route served
+type CanaryVariantStats struct {
+	Requests   int64   `json:"requests"`
+	Errors     int64   `json:"errors"` // 5xx responses
+	ErrorRate  float64 `json:"error_rate"`
+	MeanMillis float64 `json:"mean_ms"`
+	P50Millis  float64 `json:"p50_ms"` // Over the most recent requests
+	P95Millis  float64 `json:"p95_ms"`
+	MaxMillis  float64 `json:"max_ms"`
+}
+
+// CanaryRouteStats compares the variants of one canary route
+type CanaryRouteStats struct {
+	Route     string             `json:"route"`
+	Control   CanaryVariantStats `json:"control"`
+	Candidate CanaryVariantStats `json:"candidate"`
+}
+
+// canaryVariant accumulates the observations of one variant
+type canaryVariant struct {
+	requests int64
+	errors   int64
+	total    time.Duration
+	max      time.Duration
+	samples  []time.Duration // Ring buffer of recent latencies
+	next     int
+}
+
+// CanaryMetrics records latency and errors per canary route and variant so
+// that a candidate implementation can be compared with the one it replaces
+type CanaryMetrics struct {
+	mu      sync.Mutex
+	routes  map[string]map[string]*canaryVariant
+	resetAt time.Time
+}
+
+// NewCanaryMetrics creates an empty CanaryMetrics
+func NewCanaryMetrics() *CanaryMetrics {
+	return &CanaryMetrics{
+		routes:  make(map[string]map[string]*canaryVariant),
+		resetAt: time.Now().UTC(),
+	}
+}
+
+// Observe records one request served by a variant of a route
+func (m *CanaryMetrics) Observe(route, variant string, latency time.Duration, failed bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	variants, ok := m.routes[route]
+	if !ok {
+		variants = make(map[string]*canaryVariant)
+		m.routes[route] = variants
+	}
+	v, ok := variants[variant]
+	if !ok {
+		v = &canaryVariant{samples: make([]time.Duration, 0, canaryLatencySamples)}
+		variants[variant] = v
+	}
+
+	v.requests++
+	if failed {
+		v.errors++
+	}
+	v.total += latency
+	if latency > v.max {
+		v.max = latency
+	}
+	if len(v.samples) < canaryLatencySamples {
+		v.samples = append(v.samples, latency)
+	} else {
+		v.samples[v.next] = latency
+		v.next = (v.next + 1) % canaryLatencySamples
+	}
+}
+
+// Snapshot returns the stats of every observed route, sorted by route, and
+// the time they were last reset
+func (m *CanaryMetrics) Snapshot() ([]CanaryRouteStats, time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]CanaryRouteStats, 0, len(m.routes))
+	for route, variants := range m.routes {
+		stats = append(stats, CanaryRouteStats{
+			Route:     route,
+			Control:   variants[CanaryControl].stats(),
+			Candidate: variants[CanaryCandidate].stats(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Route < stats[j].Route })
+	return stats, m.resetAt
+}
+
+// Reset discards every observation
+func (m *CanaryMetrics) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routes = make(map[string]map[string]*canaryVariant)
+	m.resetAt = time.Now().UTC()
+}
+
+// stats summarizes a variant; a nil variant has served no requests
+func (v *canaryVariant) stats() CanaryVariantStats {
+	if v == nil || v.requests == 0 {
+		return CanaryVariantStats{}
+	}
+	sorted := make([]time.Duration, len(v.samples))
+	copy(sorted, v.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	return CanaryVariantStats{
+		Requests:   v.requests,
+		Errors:     v.errors,
+		ErrorRate:  float64(v.errors) / float64(v.requests),
+		MeanMillis: millis(v.total / time.Duration(v.requests)),
+		P50Millis:  millis(percentile(sorted, 50)),
+		P95Millis:  millis(percentile(sorted, 95)),
+		MaxMillis:  millis(v.max),
+	}
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// millis converts a duration to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCanaryMetricsSnapshot(t *testing.T) {
+	metrics := NewCanaryMetrics()
+	for i := 1; i <= 100; i++ {
+		metrics.Observe("candles", CanaryControl, time.Duration(i)*time.Millisecond, false)
+	}
+	metrics.Observe("candles", CanaryCandidate, 4*time.Millisecond, false)
+	metrics.Observe("candles", CanaryCandidate, 8*time.Millisecond, true)
+
+	stats, _ := metrics.Snapshot()
+	if len(stats) != 1 || stats[0].Route != "candles" {
+		t.Fatalf("Snapshot() = %+v; want the candles route only", stats)
+	}
+	control, candidate := stats[0].Control, stats[0].Candidate
+	if control.Requests != 100 || control.Errors != 0 || control.MeanMillis != 50.5 {
+		t.Errorf("control = %+v; want 100 requests, no errors, mean 50.5ms", control)
+	}
+	if control.P50Millis != 50 || control.P95Millis != 95 || control.MaxMillis != 100 {
+		t.Errorf("control percentiles = %+v; want p50 50, p95 95, max 100", control)
+	}
+	if candidate.Requests != 2 || candidate.Errors != 1 || candidate.ErrorRate != 0.5 || candidate.MeanMillis != 6 {
+		t.Errorf("candidate = %+v; want 2 requests, 1 error, mean 6ms", candidate)
+	}
+
+	metrics.Reset()
+	if stats, _ := metrics.Snapshot(); len(stats) != 0 {
+		t.Errorf("Snapshot() after Reset = %+v; want none", stats)
+	}
+}
+
+func TestCanaryMetricsKeepsRecentSamples(t *testing.T) {
+	metrics := NewCanaryMetrics()
+	for i := 0; i < canaryLatencySamples; i++ {
+		metrics.Observe("candles", CanaryControl, time.Second, false)
+	}
+	for i := 0; i < canaryLatencySamples; i++ {
+		metrics.Observe("candles", CanaryControl, time.Millisecond, false)
+	}
+
+	stats, _ := metrics.Snapshot()
+	if got := stats[0].Control; got.P95Millis != 1 || got.MaxMillis != 1000 {
+		t.Errorf("control = %+v; want p95 over the recent 1ms samples and max 1000ms", got)
+	}
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// Candle export formats
+const (
+	CandleExportCSV    = "csv"
+	CandleExportNDJSON = "ndjson"
+)
+
+// candleExportHeader is the header row of CSV exports
+var candleExportHeader = []string{"code", "date", "open", "high", "low", "close", "volume"}
+
+// exportedCandle is one NDJSON line of an export
+type exportedCandle struct {
+	Code string `json:"code"`
+	models.CandleData
+}
+
+// CandleExporter writes candles of several symbols to one file, as CSV with
+// a header row or as NDJSON
+type CandleExporter struct {
+	out  *bufio.Writer
+	csv  *csv.Writer
+	json *json.Encoder
+	rows int
+}
+
+// NewCandleExporter creates an exporter writing format to w
+func NewCandleExporter(w io.Writer, format string) (*CandleExporter, error) {
+	e := &CandleExporter{out: bufio.NewWriter(w)}
+	switch format {
+	case CandleExportCSV:
+		e.csv = csv.NewWriter(e.out)
+		if err := e.csv.Write(candleExportHeader); err != nil {
+			return nil, err
+		}
+	case CandleExportNDJSON:
+		e.json = json.NewEncoder(e.out)
+	default:
+		return nil, fmt.Errorf("unknown export format %q (supported: %s, %s)", format, CandleExportCSV, CandleExportNDJSON)
+	}
+	return e, nil
+}
+
+// Write adds the candle of code
+func (e *CandleExporter) Write(code string, candle models.CandleData) error {
+	e.rows++
+	if e.json != nil {
+		return e.json.Encode(exportedCandle{Code: code, CandleData: candle})
+	}
+	return e.csv.Write([]string{
+		code,
+		candle.D,
+		strconv.FormatFloat(candle.O, 'f', -1, 64),
+		strconv.FormatFloat(candle.H, 'f', -1, 64),
+		strconv.FormatFloat(candle.L, 'f', -1, 64),
+		strconv.FormatFloat(candle.C, 'f', -1, 64),
+		strconv.FormatInt(candle.V, 10),
+	})
+}
+
+// Flush writes any buffered candles to the underlying writer
+func (e *CandleExporter) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return e.out.Flush()
+}
+
+// Rows returns how many candles were written
+func (e *CandleExporter) Rows() int {
+	return e.rows
+}
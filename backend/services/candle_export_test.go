@@ -0,0 +1,50 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestCandleExporter(t *testing.T) {
+	candles := []models.CandleData{
+		{D: "2024-06-10", O: 25.1, H: 25.6, L: 24.9, C: 25.35, V: 1200},
+		{D: "2024-06-11", O: 25.35, H: 25.5, L: 25, C: 25.2, V: 900},
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{CandleExportCSV, "code,date,open,high,low,close,volume\n" +
+			"HPG,2024-06-10,25.1,25.6,24.9,25.35,1200\n" +
+			"HPG,2024-06-11,25.35,25.5,25,25.2,900\n"},
+		{CandleExportNDJSON, `{"code":"HPG","d":"2024-06-10","o":25.1,"h":25.6,"l":24.9,"c":25.35,"v":1200}` + "\n" +
+			`{"code":"HPG","d":"2024-06-11","o":25.35,"h":25.5,"l":25,"c":25.2,"v":900}` + "\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		exporter, err := NewCandleExporter(&buf, tt.format)
+		if err != nil {
+			t.Fatalf("NewCandleExporter(%s): %v", tt.format, err)
+		}
+		for _, candle := range candles {
+			if err := exporter.Write("HPG", candle); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+		}
+		if err := exporter.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s export =\n%s\nwant\n%s", tt.format, buf.String(), tt.want)
+		}
+		if exporter.Rows() != 2 {
+			t.Errorf("%s Rows() = %d; want 2", tt.format, exporter.Rows())
+		}
+	}
+
+	if _, err := NewCandleExporter(&bytes.Buffer{}, "xlsx"); err == nil {
+		t.Error("NewCandleExporter(xlsx) succeeded; want an error")
+	}
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Bulk actions on the crawl error list
+const (
+	CrawlErrorActionRetry       = "retry"       // Re-crawl the symbols' prices now
+	CrawlErrorActionBlacklist   = "blacklist"   // Add the symbols to crawler.excluded_symbols
+	CrawlErrorActionAcknowledge = "acknowledge" // Dismiss the errors from the list
+)
+
+// CrawlErrorActions lists the supported bulk actions
+var CrawlErrorActions = []string{CrawlErrorActionRetry, CrawlErrorActionBlacklist, CrawlErrorActionAcknowledge}
+
+// Per-symbol outcomes of a bulk action
+const (
+	SymbolResultQueued              = "queued"
+	SymbolResultBlacklisted         = "blacklisted"
+	SymbolResultAlreadyBlacklisted  = "already_blacklisted"
+	SymbolResultAcknowledged        = "acknowledged"
+	SymbolResultAlreadyAcknowledged = "already_acknowledged"
+	SymbolResultNotFound            = "not_found"
+	SymbolResultSkipped             = "skipped"
+)
+
+// maxBulkSymbols caps the symbols accepted by one bulk request
+const maxBulkSymbols = 5000
+
+var (
+	// ErrCrawlRunNotFound is returned when the requested crawl run does not exist
+	ErrCrawlRunNotFound = errors.New("crawl run not found")
+	// ErrInvalidBulkAction is returned for unknown actions or empty symbol lists
+	ErrInvalidBulkAction = errors.New("invalid bulk action")
+)
+
+// CrawlErrorList is the crawl error view of one run
+type CrawlErrorList struct {
+	Run    *models.CrawlRun          `json:"run"`
+	Errors []models.CrawlSymbolError `json:"errors"`
+	Hidden int                       `json:"hidden"` // Acknowledged errors not listed
+}
+
+// BulkCrawlErrorRequest is one bulk action over selected symbols
+type BulkCrawlErrorRequest struct {
+	Action string   `json:"action"`
+	Codes  []string `json:"codes"`
+	RunID  string   `json:"run_id"` // Run the errors belong to (default: latest full run)
+}
+
+// SymbolActionResult is the outcome of a bulk action for one symbol
+type SymbolActionResult struct {
+	Code    string `json:"code"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+// BulkCrawlErrorResult reports a bulk action per symbol
+type BulkCrawlErrorResult struct {
+	Action     string               `json:"action"`
+	RunID      string               `json:"run_id"`
+	RetryRunID string               `json:"retry_run_id,omitempty"` // Run queued by a retry
+	Summary    map[string]int       `json:"summary"`                // Status -> number of symbols
+	Results    []SymbolActionResult `json:"results"`
+}
+
+func (r *BulkCrawlErrorResult) add(code, status, message string) {
+	r.Results = append(r.Results, SymbolActionResult{Code: code, Status: status, Message: message})
+	r.Summary[status]++
+}
+
+// CrawlErrorService lists per-symbol crawl failures and applies bulk actions to them
+type CrawlErrorService struct {
+	runCollection   *mongo.Collection
+	stockCollection *mongo.Collection
+	crawlerService  *CrawlerService
+	settingsService *SettingsService
+}
+
+// NewCrawlErrorService creates a new CrawlErrorService instance
+func NewCrawlErrorService(crawlerService *CrawlerService, settingsService *SettingsService) *CrawlErrorService {
+	return &CrawlErrorService{
+		runCollection:   config.GetCollection("crawl_runs"),
+		stockCollection: config.GetCollection("stocks"),
+		crawlerService:  crawlerService,
+		settingsService: settingsService,
+	}
+}
+
+// findRun returns the run with the given ID, or the latest finished full run when runID is empty
+func (s *CrawlErrorService) findRun(ctx context.Context, runID string) (*models.CrawlRun, error) {
+	filter := bson.M{
+		"status": bson.M{"$ne": models.CrawlRunStatusRunning},
+		"kind":   bson.M{"$nin": models.PartialCrawlRunKinds},
+	}
+	if runID != "" {
+		objectID, err := primitive.ObjectIDFromHex(runID)
+		if err != nil {
+			return nil, ErrCrawlRunNotFound
+		}
+		filter = bson.M{"_id": objectID}
+	}
+
+	var run models.CrawlRun
+	opts := options.FindOne().SetSort(bson.D{{Key: "startedAt", Value: -1}})
+	err := s.runCollection.FindOne(ctx, filter, opts).Decode(&run)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrCrawlRunNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch crawl run: %w", err)
+	}
+	return &run, nil
+}
+
+// ListErrors returns the symbol errors of a run, hiding acknowledged ones
+// unless includeAcknowledged is set
+func (s *CrawlErrorService) ListErrors(ctx context.Context, runID string, includeAcknowledged bool) (*CrawlErrorList, error) {
+	run, err := s.findRun(ctx, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &CrawlErrorList{Run: run, Errors: make([]models.CrawlSymbolError, 0, len(run.Errors))}
+	for _, symbolErr := range run.Errors {
+		if symbolErr.Acknowledged() && !includeAcknowledged {
+			list.Hidden++
+			continue
+		}
+		list.Errors = append(list.Errors, symbolErr)
+	}
+	sort.Slice(list.Errors, func(i, j int) bool { return list.Errors[i].Code < list.Errors[j].Code })
+
+	// The run document is returned for its summary; errors are listed separately
+	run.Errors = nil
+	return list, nil
+}
+
+// BulkAction applies one action to many symbols and reports the outcome per symbol
+func (s *CrawlErrorService) BulkAction(ctx context.Context, req BulkCrawlErrorRequest, actor string) (*BulkCrawlErrorResult, error) {
+	codes := normalizeCodes(req.Codes)
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("%w: at least one symbol is required", ErrInvalidBulkAction)
+	}
+	if len(codes) > maxBulkSymbols {
+		return nil, fmt.Errorf("%w: at most %d symbols per request", ErrInvalidBulkAction, maxBulkSymbols)
+	}
+
+	run, err := s.findRun(ctx, req.RunID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BulkCrawlErrorResult{
+		Action:  req.Action,
+		RunID:   run.ID.Hex(),
+		Summary: make(map[string]int),
+		Results: make([]SymbolActionResult, 0, len(codes)),
+	}
+
+	switch req.Action {
+	case CrawlErrorActionAcknowledge:
+		err = s.acknowledge(ctx, run, codes, actor, result)
+	case CrawlErrorActionBlacklist:
+		err = s.blacklist(ctx, codes, actor, result)
+	case CrawlErrorActionRetry:
+		err = s.retry(ctx, run, codes, result)
+	default:
+		return nil, fmt.Errorf("%w: action must be one of %s", ErrInvalidBulkAction, strings.Join(CrawlErrorActions, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *CrawlErrorService) acknowledge(ctx context.Context, run *models.CrawlRun, codes []string, actor string, result *BulkCrawlErrorResult) error {
+	state := make(map[string]bool) // code -> already acknowledged
+	for _, symbolErr := range run.Errors {
+		state[symbolErr.Code] = state[symbolErr.Code] || symbolErr.Acknowledged()
+	}
+
+	pending := make([]string, 0, len(codes))
+	for _, code := range codes {
+		if acknowledged, ok := state[code]; ok && !acknowledged {
+			pending = append(pending, code)
+		}
+	}
+	if _, err := AcknowledgeCrawlErrors(ctx, s.runCollection, run.ID, pending, actor); err != nil {
+		return err
+	}
+
+	for _, code := range codes {
+		acknowledged, ok := state[code]
+		switch {
+		case !ok:
+			result.add(code, SymbolResultNotFound, "no error recorded for this symbol in the run")
+		case acknowledged:
+			result.add(code, SymbolResultAlreadyAcknowledged, "")
+		default:
+			result.add(code, SymbolResultAcknowledged, "")
+		}
+	}
+	return nil
+}
+
+func (s *CrawlErrorService) blacklist(ctx context.Context, codes []string, actor string, result *BulkCrawlErrorResult) error {
+	excluded := config.Runtime().CrawlerExcludedSymbols
+	updated := append([]string{}, excluded...)
+	for _, code := range codes {
+		if config.Runtime().IsExcludedSymbol(code) {
+			result.add(code, SymbolResultAlreadyBlacklisted, "")
+			continue
+		}
+		updated = append(updated, code)
+		result.add(code, SymbolResultBlacklisted, "")
+	}
+
+	if len(updated) == len(excluded) {
+		return nil
+	}
+	if _, err := s.settingsService.Set(ctx, "crawler.excluded_symbols", strings.Join(updated, ","), actor); err != nil {
+		return fmt.Errorf("failed to update excluded symbols: %w", err)
+	}
+	return nil
+}
+
+func (s *CrawlErrorService) retry(ctx context.Context, run *models.CrawlRun, codes []string, result *BulkCrawlErrorResult) error {
+	cursor, err := s.stockCollection.Find(ctx, bson.M{"code": bson.M{"$in": codes}})
+	if err != nil {
+		return fmt.Errorf("failed to look up stocks: %w", err)
+	}
+	var found []models.Stock
+	if err := cursor.All(ctx, &found); err != nil {
+		return fmt.Errorf("failed to decode stocks: %w", err)
+	}
+	byCode := make(map[string]models.Stock, len(found))
+	for _, stock := range found {
+		byCode[stock.Code] = stock
+	}
+
+	cfg := config.Runtime()
+	queued := make([]models.Stock, 0, len(codes))
+	for _, code := range codes {
+		stock, ok := byCode[code]
+		switch {
+		case !ok:
+			result.add(code, SymbolResultNotFound, "unknown stock code")
+		case cfg.IsExcludedSymbol(code):
+			result.add(code, SymbolResultSkipped, "symbol is blacklisted")
+		default:
+			queued = append(queued, stock)
+			result.add(code, SymbolResultQueued, "")
+		}
+	}
+
+	if len(queued) > 0 {
+		retryRun, err := s.crawlerService.RetrySymbols(queued, &run.ID)
+		if err != nil {
+			return err
+		}
+		result.RetryRunID = retryRun.ID.Hex()
+	}
+	return nil
+}
+
+// AcknowledgeCrawlErrors marks the unacknowledged errors of the given symbols
+// in a run as acknowledged by actor
+func AcknowledgeCrawlErrors(ctx context.Context, runs *mongo.Collection, runID primitive.ObjectID, codes []string, actor string) (int64, error) {
+	if len(codes) == 0 {
+		return 0, nil
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	update := bson.M{"$set": bson.M{
+		"errors.$[e].acknowledgedBy": actor,
+		"errors.$[e].acknowledgedAt": now,
+	}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{
+		bson.M{"e.code": bson.M{"$in": codes}, "e.acknowledgedAt": bson.M{"$exists": false}},
+	}})
+
+	result, err := runs.UpdateOne(ctx, bson.M{"_id": runID}, update, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acknowledge crawl errors: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// normalizeCodes upper-cases and de-duplicates stock codes, keeping their order
+func normalizeCodes(codes []string) []string {
+	seen := make(map[string]bool, len(codes))
+	normalized := make([]string, 0, len(codes))
+	for _, code := range codes {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code != "" && !seen[code] {
+			seen[code] = true
+			normalized = append(normalized, code)
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+// ErrCrawlInProgress is returned when a crawl is requested while another one
+// runs, on this instance or any other
+var ErrCrawlInProgress = errors.New("a crawl is already in progress")
+
+// crawlLockKey is the Postgres advisory lock key held while a crawl runs
+// ("CPLS" in ASCII)
+const crawlLockKey int64 = 0x43504c53
+
+// localCrawlLock stands in for the advisory lock when Postgres is not
+// configured, so crawls are at least serialized within the instance
+var localCrawlLock atomic.Bool
+
+// crawlLock is a held cluster-wide crawl lock
+type crawlLock struct {
+	conn *sql.Conn // Session holding the advisory lock; nil for the local lock
+}
+
+// acquireCrawlLock takes the cluster-wide crawl lock without waiting, or
+// returns ErrCrawlInProgress. The lock is a session-level Postgres advisory
+// lock on a connection set aside for the crawl: if the instance dies, the
+// connection drops and Postgres releases the lock. While a configured
+// Postgres is unavailable the lock fails closed: another instance may be
+// crawling, so ErrCrawlInProgress is returned.
+func acquireCrawlLock(ctx context.Context) (*crawlLock, error) {
+	if config.PostgresDB == nil {
+		if !localCrawlLock.CompareAndSwap(false, true) {
+			return nil, ErrCrawlInProgress
+		}
+		return &crawlLock{}, nil
+	}
+	if !config.PostgresAvailable() {
+		return nil, fmt.Errorf("%w: %w: %s", ErrCrawlInProgress, config.ErrStoreUnavailable, config.StorePostgres)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	sqlDB, err := config.PostgresDB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get crawl lock connection: %w", err)
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get crawl lock connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", crawlLockKey).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to take crawl lock: %w", err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, ErrCrawlInProgress
+	}
+	return &crawlLock{conn: conn}, nil
+}
+
+// Release lets the next crawl start. Failing to unlock explicitly is
+// harmless: the connection is discarded, which ends the session and its lock.
+func (l *crawlLock) Release() {
+	if l.conn == nil {
+		localCrawlLock.Store(false)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var released bool
+	err := l.conn.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", crawlLockKey).Scan(&released)
+	if err != nil || !released {
+		crawlLog.Warn("Failed to release crawl lock, closing its session", "released", released, logging.FieldError, err)
+		// Return the connection to the driver as broken so its session ends
+		l.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+	l.conn.Close()
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"gorm.io/gorm"
+)
+
+func TestCrawlLockWithoutPostgresSerializesCrawls(t *testing.T) {
+	lock, err := acquireCrawlLock(context.Background())
+	if err != nil {
+		t.Fatalf("acquireCrawlLock() unexpected error: %v", err)
+	}
+	if _, err := acquireCrawlLock(context.Background()); err != ErrCrawlInProgress {
+		t.Errorf("second acquireCrawlLock() = %v; want ErrCrawlInProgress", err)
+	}
+
+	lock.Release()
+	again, err := acquireCrawlLock(context.Background())
+	if err != nil {
+		t.Fatalf("acquireCrawlLock() after Release: %v", err)
+	}
+	again.Release()
+}
+
+func TestCrawlLockFailsClosedWhilePostgresIsUnavailable(t *testing.T) {
+	// Configured but never reachable
+	previous := config.PostgresDB
+	config.PostgresDB = &gorm.DB{}
+	defer func() { config.PostgresDB = previous }()
+
+	if _, err := acquireCrawlLock(context.Background()); !errors.Is(err, ErrCrawlInProgress) {
+		t.Errorf("acquireCrawlLock() = %v; want ErrCrawlInProgress", err)
+	}
+	q := NewJobQueue()
+	q.Handle("test", func(context.Context, *models.Job) error { return nil })
+	if _, err := q.Enqueue(context.Background(), "test", nil, JobOptions{}); !errors.Is(err, config.ErrStoreUnavailable) {
+		t.Errorf("Enqueue() = %v; want ErrStoreUnavailable", err)
+	}
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// crawlScheduleTick is how often the crawl schedule checks for a due crawl
+const crawlScheduleTick = time.Minute
+
+// StartSchedule queues crawls by crawler.schedule: intraday polls within
+// the intraday window and the full crawl after the end-of-day time, on the
+// trading days of the crawled exchanges. The last runs are read from
+// crawl_runs and jobs are unique, so every instance may run the schedule.
+func (cs *CrawlerService) StartSchedule(ctx context.Context) {
+	go func() {
+		crawlLog.Info("Crawl schedule started")
+		ticker := time.NewTicker(crawlScheduleTick)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				crawlLog.Info("Crawl schedule stopped")
+				return
+			case <-ticker.C:
+				if err := cs.runSchedule(ctx, time.Now()); err != nil {
+					crawlLog.Warn("Crawl schedule check failed", logging.FieldError, err)
+				}
+			}
+		}
+	}()
+}
+
+// runSchedule queues the crawl due at now, if any
+func (cs *CrawlerService) runSchedule(ctx context.Context, now time.Time) error {
+	cfg := config.Runtime()
+	schedule := cfg.CrawlSchedule()
+	if schedule.Mode == models.CrawlScheduleOff || DryRun() {
+		return nil
+	}
+	exchanges := make([]models.Exchange, 0, len(cfg.CrawlerExchanges))
+	for _, code := range cfg.CrawlerExchanges {
+		if exchange, ok := models.LookupExchange(code); ok {
+			exchanges = append(exchanges, exchange)
+		}
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	last, lastFull, err := cs.LastRunStarts(lookupCtx)
+	if err != nil {
+		return err
+	}
+
+	switch schedule.Due(exchanges, now, last, lastFull) {
+	case models.CrawlWindowEndOfDay:
+		err = cs.StartCrawling()
+		if err == nil {
+			crawlLog.Info("Crawl schedule queued the end-of-day crawl")
+		}
+	case models.CrawlWindowIntraday:
+		err = cs.PollIntraday(cfg.CrawlerIntradaySymbols)
+	}
+	if errors.Is(err, ErrCrawlInProgress) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// ExchangeCrawlStatus is the coverage and freshness of one exchange's symbols
+type ExchangeCrawlStatus struct {
+	Stocks          int    `json:"stocks"`
+	Fresh           int    `json:"fresh"`                      // Symbols with a candle in the latest stale_sessions sessions
+	Stale           int    `json:"stale"`                      // Symbols without one, including those with no candles
+	NewestCandle    string `json:"newest_candle,omitempty"`    // Newest candle date of any symbol
+	StaleSince      string `json:"stale_since,omitempty"`      // Symbols whose newest candle is older than this date are stale
+	LatestSession   string `json:"latest_session,omitempty"`   // Latest trading session that closed
+	UnknownCalendar bool   `json:"unknown_calendar,omitempty"` // The exchange is not registered, so freshness is not judged
+}
+
+// CrawlRunStatus summarizes the latest crawl run for monitoring
+type CrawlRunStatus struct {
+	ID                   string     `json:"id"`
+	Kind                 string     `json:"kind"`
+	Status               string     `json:"status"`
+	StartedAt            time.Time  `json:"started_at"`
+	FinishedAt           *time.Time `json:"finished_at,omitempty"`
+	DurationSeconds      float64    `json:"duration_seconds"` // Up to now while running
+	TotalSymbols         int        `json:"total_symbols"`
+	SucceededSymbols     int        `json:"succeeded_symbols"`
+	FailedSymbols        int        `json:"failed_symbols"`
+	Errors               int        `json:"errors"`
+	UnacknowledgedErrors int        `json:"unacknowledged_errors"`
+	SuspectCandles       int        `json:"suspect_candles"`
+}
+
+// newCrawlRunStatus summarizes run as of now
+func newCrawlRunStatus(run models.CrawlRun, now time.Time) *CrawlRunStatus {
+	status := &CrawlRunStatus{
+		ID:               run.ID.Hex(),
+		Kind:             run.Kind,
+		Status:           run.Status,
+		StartedAt:        run.StartedAt.Time().UTC(),
+		TotalSymbols:     run.TotalSymbols,
+		SucceededSymbols: run.SucceededSymbols,
+		FailedSymbols:    run.FailedSymbols,
+		Errors:           len(run.Errors),
+		SuspectCandles:   run.SuspectCandles,
+	}
+	if status.Kind == "" {
+		status.Kind = models.CrawlRunKindFull
+	}
+	end := now
+	if run.FinishedAt != nil {
+		finishedAt := run.FinishedAt.Time().UTC()
+		status.FinishedAt, end = &finishedAt, finishedAt
+	}
+	status.DurationSeconds = end.Sub(status.StartedAt).Round(time.Second).Seconds()
+	for _, symbolErr := range run.Errors {
+		if !symbolErr.Acknowledged() {
+			status.UnacknowledgedErrors++
+		}
+	}
+	return status
+}
+
+// exchangeFreshness counts the stocks of each exchange and how many have no
+// candle in the exchange's last sessions trading sessions as of now, given
+// the newest candle date of each code. It returns the breakdown by exchange
+// and the number of stale symbols.
+func exchangeFreshness(stocks []models.Stock, newest map[string]string, sessions int, now time.Time) (map[string]*ExchangeCrawlStatus, int) {
+	breakdown := make(map[string]*ExchangeCrawlStatus)
+	for _, stock := range stocks {
+		status, ok := breakdown[stock.Exchange]
+		if !ok {
+			status = &ExchangeCrawlStatus{}
+			breakdown[stock.Exchange] = status
+			if exchange, registered := models.LookupExchange(stock.Exchange); registered {
+				recent := exchange.RecentSessions(now, sessions)
+				if len(recent) > 0 {
+					status.LatestSession = recent[0]
+					status.StaleSince = recent[len(recent)-1]
+				}
+			} else {
+				status.UnknownCalendar = true
+			}
+		}
+		status.Stocks++
+
+		last := newest[stock.Code]
+		if last > status.NewestCandle {
+			status.NewestCandle = last
+		}
+		if status.UnknownCalendar {
+			continue
+		}
+		if last != "" && last >= status.StaleSince {
+			status.Fresh++
+		} else {
+			status.Stale++
+		}
+	}
+
+	stale := 0
+	for _, status := range breakdown {
+		stale += status.Stale
+	}
+	return breakdown, stale
+}
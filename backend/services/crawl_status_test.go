@@ -0,0 +1,65 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestExchangeFreshness(t *testing.T) {
+	// Thursday 2024-01-18 after the close: the last 3 sessions are 16, 17 and 18
+	now := time.Date(2024, 1, 18, 9, 0, 0, 0, time.UTC)
+	stocks := []models.Stock{
+		{Code: "HPG", Exchange: "HOSE"},
+		{Code: "FPT", Exchange: "HOSE"},
+		{Code: "NEW", Exchange: "HOSE"},
+		{Code: "SHS", Exchange: "HNX"},
+		{Code: "XYZ", Exchange: "OTC"},
+	}
+	newest := map[string]string{"HPG": "2024-01-18", "FPT": "2024-01-15", "SHS": "2024-01-16", "XYZ": "2024-01-01"}
+
+	breakdown, stale := exchangeFreshness(stocks, newest, 3, now)
+	hose := breakdown["HOSE"]
+	if hose.Stocks != 3 || hose.Fresh != 1 || hose.Stale != 2 || hose.StaleSince != "2024-01-16" || hose.LatestSession != "2024-01-18" {
+		t.Errorf("HOSE = %+v; want 3 stocks, HPG fresh, FPT and NEW stale since 2024-01-16", hose)
+	}
+	if hnx := breakdown["HNX"]; hnx.Fresh != 1 || hnx.Stale != 0 {
+		t.Errorf("HNX = %+v; want SHS fresh on the oldest counted session", hnx)
+	}
+	if otc := breakdown["OTC"]; !otc.UnknownCalendar || otc.Stocks != 1 || otc.Stale != 0 || otc.NewestCandle != "2024-01-01" {
+		t.Errorf("OTC = %+v; want counted without judging freshness", otc)
+	}
+	if stale != 2 {
+		t.Errorf("stale = %d; want 2", stale)
+	}
+}
+
+func TestNewCrawlRunStatus(t *testing.T) {
+	started := time.Date(2024, 1, 18, 9, 0, 0, 0, time.UTC)
+	acknowledged := primitive.NewDateTimeFromTime(started)
+	run := models.CrawlRun{
+		Status:       models.CrawlRunStatusRunning,
+		StartedAt:    primitive.NewDateTimeFromTime(started),
+		TotalSymbols: 10,
+		Errors: []models.CrawlSymbolError{
+			{Code: "HPG", Error: "timeout"},
+			{Code: "FPT", Error: "timeout", AcknowledgedAt: &acknowledged},
+		},
+	}
+
+	status := newCrawlRunStatus(run, started.Add(90*time.Second))
+	if status.Kind != models.CrawlRunKindFull || status.FinishedAt != nil || status.DurationSeconds != 90 {
+		t.Errorf("running status = %+v; want a full run running for 90s", status)
+	}
+	if status.Errors != 2 || status.UnacknowledgedErrors != 1 {
+		t.Errorf("errors = %d (%d unacknowledged); want 2 (1)", status.Errors, status.UnacknowledgedErrors)
+	}
+
+	finished := primitive.NewDateTimeFromTime(started.Add(time.Minute))
+	run.FinishedAt = &finished
+	if status := newCrawlRunStatus(run, started.Add(time.Hour)); status.FinishedAt == nil || status.DurationSeconds != 60 {
+		t.Errorf("finished status = %+v; want a 60s run", status)
+	}
+}
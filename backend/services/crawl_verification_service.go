@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// verifySessions is how many of the latest sessions are compared for each
+// sampled symbol. It differs from the session counts the crawl requests, so
+// the re-fetch never reuses a provider response cached for the run.
+const verifySessions = 5
+
+// errNoRecentPrices is recorded for sampled symbols whose data source can
+// only fetch the full history, which the run's cache would answer
+var errNoRecentPrices = errors.New("data source cannot fetch recent prices")
+
+// CrawlVerificationService re-fetches a random sample of symbols after each
+// full crawl run and compares the provider's latest candles with the stored
+// ones. The mismatch rate is recorded on the run, where
+// sample_mismatch_ratio alert rules pick it up.
+type CrawlVerificationService struct {
+	stockCollection *mongo.Collection
+	priceCollection *mongo.Collection
+	runCollection   *mongo.Collection
+}
+
+// NewCrawlVerificationService creates a new crawl verification service
+func NewCrawlVerificationService() *CrawlVerificationService {
+	return &CrawlVerificationService{
+		stockCollection: config.GetCollection("stocks"),
+		priceCollection: config.GetCollection("stock_prices"),
+		runCollection:   config.GetCollection("crawl_runs"),
+	}
+}
+
+// VerifyRun is a CrawlRunListener verifying a sample of crawler.verify_sample
+// symbols of a full run and storing the result on it. Retry runs cover too
+// few symbols to sample.
+func (s *CrawlVerificationService) VerifyRun(run *models.CrawlRun, newDates map[string]string) {
+	size := config.Runtime().CrawlerVerifySample
+	if size == 0 || run.Partial() || run.SucceededSymbols == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	verification, err := s.Verify(ctx, run, size)
+	if err != nil {
+		crawlLog.Warn("Failed to verify crawl run", "run_id", run.ID.Hex(), logging.FieldError, err)
+		return
+	}
+	run.Verification = verification
+	if _, err := s.runCollection.UpdateOne(ctx, bson.M{"_id": run.ID}, bson.M{"$set": bson.M{"verification": verification}}); err != nil {
+		crawlLog.Warn("Failed to record crawl run verification", "run_id", run.ID.Hex(), logging.FieldError, err)
+		return
+	}
+	crawlLog.Info("Crawl run verified against the provider", "run_id", run.ID.Hex(),
+		"mismatched_candles", verification.MismatchedCandles, "compared_candles", verification.ComparedCandles,
+		"sampled_symbols", len(verification.SampledSymbols), "failed_symbols", len(verification.FailedSymbols))
+}
+
+// Verify re-fetches the latest candles of up to size random symbols that
+// run crawled successfully and compares them with the stored candles
+func (s *CrawlVerificationService) Verify(ctx context.Context, run *models.CrawlRun, size int) (*models.CrawlVerification, error) {
+	stocks, err := s.sampleStocks(ctx, run, size)
+	if err != nil {
+		return nil, err
+	}
+
+	verification := &models.CrawlVerification{
+		SampledSymbols: make([]string, 0, len(stocks)),
+		CheckedAt:      primitive.NewDateTimeFromTime(time.Now()),
+	}
+	requestDelay := config.Runtime().CrawlerRequestDelay
+	for i, stock := range stocks {
+		verification.SampledSymbols = append(verification.SampledSymbols, stock.Code)
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(requestDelay):
+			}
+		}
+
+		provider, err := fetchVerificationCandles(stock)
+		if err != nil {
+			crawlLog.Warn("Failed to re-fetch symbol for verification", logging.FieldSymbol, stock.Code, logging.FieldError, err)
+			verification.FailedSymbols = append(verification.FailedSymbols, stock.Code)
+			continue
+		}
+		exchange, _ := models.LookupExchange(stock.Exchange)
+		provider, _ = models.NormalizeCandles(provider, exchange)
+
+		stored, err := s.storedCandles(ctx, stock.Code, provider)
+		if err != nil {
+			return nil, err
+		}
+		verification.Add(stock.Code, stored, provider)
+	}
+	return verification, nil
+}
+
+// sampleStocks picks up to size random stocks of the crawled exchanges,
+// leaving out the symbols that failed in run
+func (s *CrawlVerificationService) sampleStocks(ctx context.Context, run *models.CrawlRun, size int) ([]models.Stock, error) {
+	cfg := config.Runtime()
+	excluded := append([]string{}, cfg.CrawlerExcludedSymbols...)
+	for _, symbolErr := range run.Errors {
+		excluded = append(excluded, symbolErr.Code)
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"exchange": bson.M{"$in": cfg.CrawlerExchanges}, "code": bson.M{"$nin": excluded}}}},
+		{{Key: "$sample", Value: bson.M{"size": size}}},
+	}
+	cursor, err := s.stockCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample stocks: %w", err)
+	}
+	var stocks []models.Stock
+	if err := cursor.All(ctx, &stocks); err != nil {
+		return nil, fmt.Errorf("failed to decode sampled stocks: %w", err)
+	}
+	return stocks, nil
+}
+
+// fetchVerificationCandles fetches the latest verifySessions candles of a
+// stock from its exchange's data source, unless its quota is nearly used up
+func fetchVerificationCandles(stock models.Stock) ([]models.CandleData, error) {
+	source, err := MarketDataSourceFor(stock.Exchange)
+	if err != nil {
+		return nil, err
+	}
+	fetcher, ok := source.(RecentPriceFetcher)
+	if !ok {
+		return nil, errNoRecentPrices
+	}
+	if exhausted, _ := ProviderQuotas().Exhausted(source.Name()); exhausted {
+		return nil, ErrProviderQuotaExhausted
+	}
+	return fetcher.FetchRecentPrices(stock, verifySessions)
+}
+
+// storedCandles returns the stored candles of code in the years of candles
+func (s *CrawlVerificationService) storedCandles(ctx context.Context, code string, candles []models.CandleData) ([]models.CandleData, error) {
+	years := make([]int, 0, 2)
+	seen := make(map[int]bool)
+	for _, candle := range candles {
+		year, err := models.GetYearFromDate(candle.D)
+		if err != nil || seen[year] {
+			continue
+		}
+		seen[year] = true
+		years = append(years, year)
+	}
+	if len(years) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := s.priceCollection.Find(ctx, bson.M{"code": code, "year": bson.M{"$in": years}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stored candles of %s: %w", code, err)
+	}
+	var buckets []models.PriceBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to decode stored candles of %s: %w", code, err)
+	}
+	var stored []models.CandleData
+	for _, bucket := range buckets {
+		stored = append(stored, bucket.History...)
+	}
+	return stored, nil
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// CrawlerConfig is the crawler's slice of the runtime configuration, as
+// edited on the dashboard settings page. The crawler reads its settings at
+// the start of each run and the schedule every minute, so saved changes
+// apply without a restart.
+type CrawlerConfig struct {
+	Sources          map[string]string `json:"sources"` // Exchange -> data source
+	Exchanges        []string          `json:"exchanges"`
+	Workers          int               `json:"workers"`
+	BackfillWorkers  int               `json:"backfill_workers"`
+	RequestDelay     string            `json:"request_delay"`
+	Schedule         string            `json:"schedule"`
+	Intraday         bool              `json:"intraday"`
+	IntradayWindow   string            `json:"intraday_window"`
+	IntradayInterval string            `json:"intraday_interval"`
+	IntradaySymbols  []string          `json:"intraday_symbols"`
+	EndOfDayTime     string            `json:"eod_time"`
+}
+
+// CrawlerConfigUpdate changes the crawler settings it sets; nil fields are
+// left unchanged
+type CrawlerConfigUpdate struct {
+	Sources          map[string]string `json:"sources"`
+	Exchanges        []string          `json:"exchanges"`
+	Workers          *int              `json:"workers"`
+	BackfillWorkers  *int              `json:"backfill_workers"`
+	RequestDelay     *string           `json:"request_delay"`
+	Schedule         *string           `json:"schedule"`
+	Intraday         *bool             `json:"intraday"`
+	IntradayWindow   *string           `json:"intraday_window"`
+	IntradayInterval *string           `json:"intraday_interval"`
+	IntradaySymbols  []string          `json:"intraday_symbols"`
+	EndOfDayTime     *string           `json:"eod_time"`
+}
+
+// CrawlerConfigView is the crawler configuration with what the settings
+// page offers: the registered data sources and exchanges, and the settings
+// stored in the database rather than taken from the environment or defaults
+type CrawlerConfigView struct {
+	Config    CrawlerConfig           `json:"config"`
+	Sources   []string                `json:"available_sources"`
+	Exchanges []models.Exchange       `json:"available_exchanges"`
+	Stored    map[string]string       `json:"stored"`
+	Settings  []config.RuntimeSetting `json:"settings"`
+}
+
+// CrawlerConfigService reads and updates the crawler settings through the
+// settings store
+type CrawlerConfigService struct {
+	settings *SettingsService
+}
+
+// NewCrawlerConfigService creates a CrawlerConfigService storing through settings
+func NewCrawlerConfigService(settings *SettingsService) *CrawlerConfigService {
+	return &CrawlerConfigService{settings: settings}
+}
+
+// Get returns the effective crawler configuration
+func (s *CrawlerConfigService) Get(ctx context.Context) (*CrawlerConfigView, error) {
+	stored, err := s.settings.ListStored(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.view(config.Runtime(), stored), nil
+}
+
+// Update validates and stores the changed settings together, then reloads
+// the configuration
+func (s *CrawlerConfigService) Update(ctx context.Context, update CrawlerConfigUpdate, updatedBy string) (*CrawlerConfigView, error) {
+	changes, err := crawlerSettingChanges(update)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := s.settings.SetMany(ctx, changes, updatedBy)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.settings.ListStored(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.view(cfg, stored), nil
+}
+
+// view describes cfg for the settings page
+func (s *CrawlerConfigService) view(cfg *config.RuntimeConfig, stored map[string]models.AppSetting) *CrawlerConfigView {
+	view := &CrawlerConfigView{
+		Config:    crawlerConfigOf(cfg),
+		Sources:   make([]string, 0),
+		Exchanges: models.Exchanges(),
+		Stored:    make(map[string]string),
+		Settings:  make([]config.RuntimeSetting, 0),
+	}
+	for _, source := range MarketDataSources() {
+		view.Sources = append(view.Sources, source.Name())
+	}
+	for _, setting := range config.RuntimeSettings {
+		if !strings.HasPrefix(setting.Key, "crawler.") {
+			continue
+		}
+		view.Settings = append(view.Settings, setting)
+		if value, ok := stored[setting.Key]; ok {
+			view.Stored[setting.Key] = value.Value
+		}
+	}
+	return view
+}
+
+// crawlerConfigOf returns the crawler settings of cfg, with the data source
+// of every registered exchange
+func crawlerConfigOf(cfg *config.RuntimeConfig) CrawlerConfig {
+	sources := make(map[string]string)
+	for _, exchange := range models.Exchanges() {
+		sources[exchange.Code] = exchange.Source
+		if source, ok := cfg.CrawlerExchangeSources[exchange.Code]; ok {
+			sources[exchange.Code] = source
+		}
+	}
+	return CrawlerConfig{
+		Sources:          sources,
+		Exchanges:        cfg.CrawlerExchanges,
+		Workers:          cfg.CrawlerWorkers,
+		BackfillWorkers:  cfg.CrawlerBackfillWorkers,
+		RequestDelay:     cfg.CrawlerRequestDelay.String(),
+		Schedule:         cfg.CrawlerSchedule,
+		Intraday:         cfg.CrawlerIntraday,
+		IntradayWindow:   cfg.CrawlerIntradayStart + "-" + cfg.CrawlerIntradayEnd,
+		IntradayInterval: cfg.CrawlerIntradayPoll.String(),
+		IntradaySymbols:  cfg.CrawlerIntradaySymbols,
+		EndOfDayTime:     cfg.CrawlerEndOfDayAt,
+	}
+}
+
+// crawlerSettingChanges returns the setting values of update, keyed by
+// setting key. Data sources must be registered; the other values are
+// validated when the configuration is loaded.
+func crawlerSettingChanges(update CrawlerConfigUpdate) (map[string]string, error) {
+	changes := make(map[string]string)
+	if update.Sources != nil {
+		pairs := make([]string, 0, len(update.Sources))
+		for code, name := range update.Sources {
+			code, name = strings.ToUpper(strings.TrimSpace(code)), strings.ToLower(strings.TrimSpace(name))
+			if _, ok := LookupMarketDataSource(name); !ok {
+				return nil, fmt.Errorf("unknown data source %q for %s", name, code)
+			}
+			// Exchanges on their registry source need no override
+			if exchange, ok := models.LookupExchange(code); ok && exchange.Source == name {
+				continue
+			}
+			pairs = append(pairs, code+"="+name)
+		}
+		sort.Strings(pairs)
+		changes["crawler.sources"] = strings.Join(pairs, ",")
+	}
+	if update.Exchanges != nil {
+		changes["crawler.exchanges"] = strings.Join(update.Exchanges, ",")
+	}
+	if update.Workers != nil {
+		changes["crawler.workers"] = strconv.Itoa(*update.Workers)
+	}
+	if update.BackfillWorkers != nil {
+		changes["crawler.backfill_workers"] = strconv.Itoa(*update.BackfillWorkers)
+	}
+	if update.RequestDelay != nil {
+		changes["crawler.request_delay"] = *update.RequestDelay
+	}
+	if update.Schedule != nil {
+		changes["crawler.schedule"] = *update.Schedule
+	}
+	if update.Intraday != nil {
+		changes["crawler.intraday"] = strconv.FormatBool(*update.Intraday)
+	}
+	if update.IntradayWindow != nil {
+		changes["crawler.intraday_window"] = *update.IntradayWindow
+	}
+	if update.IntradayInterval != nil {
+		changes["crawler.intraday_interval"] = *update.IntradayInterval
+	}
+	if update.IntradaySymbols != nil {
+		changes["crawler.intraday_symbols"] = strings.Join(update.IntradaySymbols, ",")
+	}
+	if update.EndOfDayTime != nil {
+		changes["crawler.eod_time"] = *update.EndOfDayTime
+	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no settings to change")
+	}
+	return changes, nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/config"
+)
+
+func TestCrawlerSettingChanges(t *testing.T) {
+	workers, intraday, schedule := 12, false, "trading_hours"
+	changes, err := crawlerSettingChanges(CrawlerConfigUpdate{
+		Sources:   map[string]string{"hose": "VNDirect"},
+		Exchanges: []string{"HOSE", "HNX"},
+		Workers:   &workers,
+		Schedule:  &schedule,
+		Intraday:  &intraday,
+	})
+	if err != nil {
+		t.Fatalf("crawlerSettingChanges() unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"crawler.sources":   "", // HOSE is served by vndirect already
+		"crawler.exchanges": "HOSE,HNX",
+		"crawler.workers":   "12",
+		"crawler.schedule":  "trading_hours",
+		"crawler.intraday":  "false",
+	}
+	if len(changes) != len(want) {
+		t.Errorf("crawlerSettingChanges() = %v; want %v", changes, want)
+	}
+	for key, value := range want {
+		if changes[key] != value {
+			t.Errorf("changes[%s] = %q; want %q", key, changes[key], value)
+		}
+	}
+
+	if _, err := crawlerSettingChanges(CrawlerConfigUpdate{Sources: map[string]string{"HOSE": "bloomberg"}}); err == nil {
+		t.Error("crawlerSettingChanges() with an unregistered data source expected an error")
+	}
+	if _, err := crawlerSettingChanges(CrawlerConfigUpdate{}); err == nil {
+		t.Error("crawlerSettingChanges() without changes expected an error")
+	}
+}
+
+func TestCrawlerConfigOf(t *testing.T) {
+	cfg := config.Runtime()
+	crawler := crawlerConfigOf(cfg)
+	if crawler.Sources["HOSE"] != "vndirect" || crawler.IntradayWindow != cfg.CrawlerIntradayStart+"-"+cfg.CrawlerIntradayEnd ||
+		crawler.Workers != cfg.CrawlerWorkers {
+		t.Errorf("crawlerConfigOf() = %+v; want the runtime settings with HOSE on vndirect", crawler)
+	}
+}
@@ -3,160 +3,852 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/markettime"
 	"github.com/datvt88/CPLS/backend/models"
-	"github.com/go-resty/resty/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-const (
-	// VNDirect API URLs
-	stockListURL  = "https://api-finfo.vndirect.com.vn/v4/stocks"
-	stockPriceURL = "https://api-finfo.vndirect.com.vn/v4/stock_prices"
+// Worker count, request delay and symbol filters are read from
+// config.Runtime() at the start of each crawl so they can be reloaded.
+// Symbols and prices come from the MarketDataSource of each exchange.
+
+// CrawlerService handles the crawling logic
+type CrawlerService struct {
+	stockCollection   *mongo.Collection
+	priceCollection   *mongo.Collection // Counted in the crawl status; candles are written through prices
+	prices            PriceRepository
+	runCollection     *mongo.Collection
+	suspectCollection *mongo.Collection // Candles outside their price band, awaiting review
+	symbolService     *SymbolService
+	universeService   *UniverseService
+	notifications     *NotificationService // Receives run summaries (crawler.summary_channels)
+	webhooks          *WebhookService      // Receives crawl.* and candle.new events
+	jobs              *JobQueue            // Runs crawl, crawl.retry and crawl.poll jobs
+	runListeners      []CrawlRunListener
+	pendingSymbols    atomic.Int64 // Symbols queued by running crawls and not yet taken by a worker
+
+	// ctx is cancelled by Shutdown; workers stop taking symbols once it is
+	ctx    context.Context
+	cancel context.CancelFunc
+	runs   sync.WaitGroup
+}
 
-	// Worker pool configuration
-	numWorkers = 8 // Number of concurrent workers
+var (
+	// ErrCrawlerShuttingDown is returned when a crawl is requested after Shutdown
+	ErrCrawlerShuttingDown = errors.New("crawler is shutting down")
+	// errCrawlInterrupted is recorded for the symbols a shutdown kept a run from reaching
+	errCrawlInterrupted = errors.New("not crawled: interrupted by shutdown")
+)
 
-	// Rate limiting
-	requestDelay = 150 * time.Millisecond // Delay between requests
+const (
+	// crawlRefreshMaxGapDays is how old a symbol's newest stored candle may be
+	// for a refresh; older or missing history is backfilled
+	crawlRefreshMaxGapDays = 14
+	// crawlRefreshSessions is how many of the latest sessions a refresh fetches,
+	// enough to cover crawlRefreshMaxGapDays
+	crawlRefreshSessions = 20
+	// indexHistorySessions is how many sessions of a market index are
+	// backfilled: three years of portfolio benchmark history
+	indexHistorySessions = 750
 )
 
-// VNDirectStockResponse represents the response from VNDirect stock list API
-type VNDirectStockResponse struct {
-	Data []struct {
-		Code        string `json:"code"`
-		CompanyName string `json:"companyName"`
-		Exchange    string `json:"exchange"`
-		Type        string `json:"type"`
-		Status      string `json:"status"`
-	} `json:"data"`
-}
-
-// VNDirectPriceResponse represents the response from VNDirect price API
-type VNDirectPriceResponse struct {
-	Data []struct {
-		Code   string  `json:"code"`
-		Date   string  `json:"date"`
-		Open   float64 `json:"open"`
-		High   float64 `json:"high"`
-		Low    float64 `json:"low"`
-		Close  float64 `json:"close"`
-		Volume int64   `json:"volume"`
-	} `json:"data"`
+// crawlLog tags crawler lines with component=crawler; per-symbol lines also
+// carry the symbol and worker
+var crawlLog = logging.Component("crawler")
+
+// maxQuotaPause is the longest a worker waits for a provider's quota window
+// to reset; beyond it the remaining symbols of the provider are skipped
+const maxQuotaPause = time.Hour
+
+// CrawlRunListener is called after a crawl run completed, with the newest
+// new candle date of every symbol that gained candles
+type CrawlRunListener func(run *models.CrawlRun, newDates map[string]string)
+
+// crawlRunTracker accumulates per-symbol results while workers run
+type crawlRunTracker struct {
+	mu           sync.Mutex
+	succeeded    int
+	interrupted  int
+	quotaSkipped int
+	suspects     int // New candles held back as outside their price band
+	errors       []models.CrawlSymbolError
+	newDates     map[string]string // Newest new candle date per symbol that gained candles
 }
 
-// CrawlerService handles the crawling logic
-type CrawlerService struct {
-	client          *resty.Client
-	stockCollection *mongo.Collection
-	priceCollection *mongo.Collection
+// recordSuccess marks a symbol as crawled successfully
+func (t *crawlRunTracker) recordSuccess() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.succeeded++
+}
+
+// recordNewCandles notes that a symbol gained candles up to date
+func (t *crawlRunTracker) recordNewCandles(code, date string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.newDates == nil {
+		t.newDates = make(map[string]string)
+	}
+	t.newDates[code] = date
+}
+
+// recordSuspects counts new candles held back for review
+func (t *crawlRunTracker) recordSuspects(n int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.suspects += n
 }
 
-// NewCrawlerService creates a new crawler service instance
-func NewCrawlerService() *CrawlerService {
-	client := resty.New()
-	client.SetTimeout(30 * time.Second)
-	client.SetRetryCount(3)
-	client.SetRetryWaitTime(2 * time.Second)
+// recordFailure marks a symbol as failed with the given error
+func (t *crawlRunTracker) recordFailure(code string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.errors = append(t.errors, models.CrawlSymbolError{
+		Code:  code,
+		Error: err.Error(),
+		At:    primitive.NewDateTimeFromTime(time.Now()),
+	})
+}
+
+// recordInterrupted marks a symbol as not reached before a shutdown
+func (t *crawlRunTracker) recordInterrupted(code string) {
+	t.recordFailure(code, errCrawlInterrupted)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.interrupted++
+}
+
+// recordQuotaSkipped marks a symbol as skipped because its provider's quota was nearly used up
+func (t *crawlRunTracker) recordQuotaSkipped(code string) {
+	t.recordFailure(code, ErrProviderQuotaExhausted)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.quotaSkipped++
+}
 
-	return &CrawlerService{
-		client:          client,
-		stockCollection: config.GetCollection("stocks"),
-		priceCollection: config.GetCollection("stock_prices"),
+// NewCrawlerService creates a new crawler service instance storing candles
+// in prices and registers the crawl job handlers on jobs
+func NewCrawlerService(prices PriceRepository, notifications *NotificationService, webhooks *WebhookService, jobs *JobQueue) *CrawlerService {
+	ctx, cancel := context.WithCancel(context.Background())
+	cs := &CrawlerService{
+		stockCollection:   config.GetCollection("stocks"),
+		priceCollection:   config.GetCollection("stock_prices"),
+		prices:            prices,
+		runCollection:     config.GetCollection("crawl_runs"),
+		suspectCollection: config.GetCollection("suspect_candles"),
+		symbolService:     NewSymbolService(),
+		universeService:   NewUniverseService(),
+		notifications:     notifications,
+		webhooks:          webhooks,
+		jobs:              jobs,
+		ctx:               ctx,
+		cancel:            cancel,
 	}
+	jobs.Handle(models.JobKindCrawl, cs.runCrawlJob)
+	jobs.Handle(models.JobKindCrawlRetry, cs.runRetryJob)
+	jobs.Handle(models.JobKindCrawlPoll, cs.runPollJob)
+	return cs
 }
 
-// StartCrawling starts the crawling process in the background
-func (cs *CrawlerService) StartCrawling() error {
-	// Run in goroutine to avoid blocking
+// Shutdown stops running crawls and waits until they are checkpointed or
+// ctx expires. Workers finish the symbol they are saving, so no bucket is
+// left half-written; symbols not reached are recorded as errors of the
+// interrupted run, from which they can be retried. Later crawl requests
+// are rejected.
+func (cs *CrawlerService) Shutdown(ctx context.Context) error {
+	cs.cancel()
+
+	done := make(chan struct{})
 	go func() {
-		log.Println("🚀 Starting market data crawling process...")
+		cs.runs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("crawl runs still in progress: %w", ctx.Err())
+	}
+}
 
-		// Step 1: Fetch and save stock list
-		stocks, err := cs.fetchStockList()
-		if err != nil {
-			log.Printf("❌ Error fetching stock list: %v", err)
-			return
+// OnRunFinished registers a listener called after every completed run.
+// Listeners must be registered before crawling starts.
+func (cs *CrawlerService) OnRunFinished(listener CrawlRunListener) {
+	cs.runListeners = append(cs.runListeners, listener)
+}
+
+// PendingSymbols returns how many symbols of the running crawls wait for a
+// worker in this instance
+func (cs *CrawlerService) PendingSymbols() int {
+	return int(cs.pendingSymbols.Load())
+}
+
+// crawlJobKey is the unique key of full crawl jobs: one at a time is queued or running
+const crawlJobKey = "crawl"
+
+// pollJobKey is the unique key of intraday poll jobs
+const pollJobKey = "crawl.poll"
+
+// crawlLockRetry is how long a crawl job waits when another crawl holds the lock
+const crawlLockRetry = time.Minute
+
+// retryJobPayload is the payload of a crawl.retry job
+type retryJobPayload struct {
+	RunID   primitive.ObjectID  `json:"run_id"`
+	RetryOf *primitive.ObjectID `json:"retry_of,omitempty"`
+	Stocks  []models.Stock      `json:"stocks"`
+}
+
+// pollJobPayload is the payload of a crawl.poll job
+type pollJobPayload struct {
+	Codes []string `json:"codes,omitempty"` // Stored symbols to poll; empty for all of them
+}
+
+// StartCrawling queues a full crawl on the job queue; whichever instance
+// claims it runs it. It returns ErrCrawlInProgress while a full crawl is
+// already queued or running.
+func (cs *CrawlerService) StartCrawling() error {
+	if cs.ctx.Err() != nil {
+		return ErrCrawlerShuttingDown
+	}
+	_, err := cs.jobs.Enqueue(cs.ctx, models.JobKindCrawl, struct{}{}, JobOptions{
+		Priority:  models.JobPriorityHigh,
+		UniqueKey: crawlJobKey,
+	})
+	if errors.Is(err, ErrJobDuplicate) {
+		return ErrCrawlInProgress
+	}
+	return err
+}
+
+// runCrawlJob runs a full crawl job. A failed run is retried by the queue;
+// a run interrupted by a shutdown is queued again for another instance.
+func (cs *CrawlerService) runCrawlJob(ctx context.Context, job *models.Job) error {
+	if cs.ctx.Err() != nil {
+		return ErrCrawlerShuttingDown
+	}
+	lock, err := acquireCrawlLock(cs.ctx)
+	if errors.Is(err, ErrCrawlInProgress) {
+		return DeferJob(crawlLockRetry, err)
+	}
+	if err != nil {
+		return err
+	}
+	cs.runs.Add(1)
+	defer cs.runs.Done()
+	defer lock.Release()
+
+	crawlLog.Info("Starting market data crawl", "job_id", job.ID.String())
+	run, err := cs.crawlUniverse()
+	if err != nil {
+		return err
+	}
+	if run.Status == models.CrawlRunStatusInterrupted {
+		return DeferJob(0, errCrawlInterrupted)
+	}
+	return nil
+}
+
+// crawlUniverse runs a full crawl: the stock list of the configured
+// exchanges, then the prices of every symbol. It returns an error when the
+// run failed before prices could be fetched.
+func (cs *CrawlerService) crawlUniverse() (*models.CrawlRun, error) {
+	run := cs.beginRun(newCrawlRun(models.CrawlRunKindFull, nil))
+	defer ProviderCache().BeginRun()()
+
+	// Step 1: Fetch and save stock list
+	stocks, err := cs.fetchStockList()
+	if err != nil {
+		crawlLog.Error("Failed to fetch stock list", "run_id", run.ID.Hex(), logging.FieldError, err)
+		cs.failRun(run, fmt.Sprintf("failed to fetch stock list: %v", err))
+		return run, err
+	}
+
+	crawlLog.Info("Fetched stock list", "run_id", run.ID.Hex(), "stocks", len(stocks), "exchanges", len(config.Runtime().CrawlerExchanges))
+
+	// Step 2: Save stocks to database
+	err = cs.saveStocks(stocks)
+	if err != nil {
+		crawlLog.Error("Failed to save stocks", "run_id", run.ID.Hex(), logging.FieldError, err)
+		cs.failRun(run, fmt.Sprintf("failed to save stocks: %v", err))
+		return run, err
+	}
+
+	crawlLog.Info("Saved stocks to database", "run_id", run.ID.Hex())
+	cs.snapshotUniverse(stocks)
+
+	// Step 3: Crawl prices for all stocks using worker pool
+	tracker := &crawlRunTracker{}
+	cs.crawlPricesWithWorkerPool(stocks, tracker)
+	// Step 4: Refresh the market indexes used as portfolio benchmarks
+	cs.crawlIndexes()
+	cs.finishRun(run, len(stocks), tracker)
+	if run.Status != models.CrawlRunStatusInterrupted {
+		crawlLog.Info("Market data crawl completed", "run_id", run.ID.Hex())
+	}
+	return run, nil
+}
+
+// Crawl runs a crawl in this process, without the job queue, and returns
+// its run once finished: a full crawl, or when codes are given a retry run
+// over the prices of those stored stocks. One-off jobs use it to crawl
+// without a server. It returns ErrCrawlInProgress while another crawl holds
+// the lock.
+func (cs *CrawlerService) Crawl(codes []string) (*models.CrawlRun, error) {
+	if cs.ctx.Err() != nil {
+		return nil, ErrCrawlerShuttingDown
+	}
+	lock, err := acquireCrawlLock(cs.ctx)
+	if err != nil {
+		return nil, err
+	}
+	cs.runs.Add(1)
+	defer cs.runs.Done()
+	defer lock.Release()
+
+	if len(codes) == 0 {
+		return cs.crawlUniverse()
+	}
+
+	stocks, err := cs.storedStocks(codes)
+	if err != nil {
+		return nil, err
+	}
+	if len(stocks) < len(codes) {
+		found := make(map[string]bool, len(stocks))
+		for _, stock := range stocks {
+			found[stock.Code] = true
+		}
+		var unknown []string
+		for _, code := range codes {
+			if !found[code] {
+				unknown = append(unknown, code)
+			}
 		}
+		return nil, fmt.Errorf("unknown symbols (crawl the whole universe first): %s", strings.Join(unknown, ", "))
+	}
 
-		log.Printf("✓ Fetched %d stocks from VNDirect", len(stocks))
+	run := cs.beginRun(newCrawlRun(models.CrawlRunKindRetry, nil))
+	crawlLog.Info("Crawling selected symbols", "run_id", run.ID.Hex(), "symbols", len(stocks))
+	defer ProviderCache().BeginRun()()
+	tracker := &crawlRunTracker{}
+	cs.crawlPricesWithWorkerPool(stocks, tracker)
+	cs.finishRun(run, len(stocks), tracker)
+	return run, nil
+}
 
-		// Step 2: Save stocks to database
-		err = cs.saveStocks(stocks)
+// storedStocks returns the stored stocks with the given codes, or all of
+// them when codes is empty
+func (cs *CrawlerService) storedStocks(codes []string) ([]models.Stock, error) {
+	ctx, cancel := context.WithTimeout(cs.ctx, 30*time.Second)
+	defer cancel()
+	filter := bson.M{}
+	if len(codes) > 0 {
+		filter["code"] = bson.M{"$in": codes}
+	}
+	cursor, err := cs.stockCollection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up stocks: %w", err)
+	}
+	var stocks []models.Stock
+	if err := cursor.All(ctx, &stocks); err != nil {
+		return nil, fmt.Errorf("failed to decode stocks: %w", err)
+	}
+	return stocks, nil
+}
+
+// PollIntraday queues an intraday poll of the recent prices of the stored
+// stocks with the given codes (all of them when empty) and of the market
+// indexes, without refreshing the stock list. It returns
+// ErrCrawlInProgress while a poll is already queued or running.
+func (cs *CrawlerService) PollIntraday(codes []string) error {
+	if cs.ctx.Err() != nil {
+		return ErrCrawlerShuttingDown
+	}
+	_, err := cs.jobs.Enqueue(cs.ctx, models.JobKindCrawlPoll, pollJobPayload{Codes: codes}, JobOptions{
+		UniqueKey: pollJobKey,
+	})
+	if errors.Is(err, ErrJobDuplicate) {
+		return ErrCrawlInProgress
+	}
+	return err
+}
+
+// runPollJob runs a crawl.poll job. Unlike other crawl jobs it is dropped
+// rather than deferred while another crawl holds the lock: the schedule
+// queues the next poll soon enough.
+func (cs *CrawlerService) runPollJob(ctx context.Context, job *models.Job) error {
+	var payload pollJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return fmt.Errorf("invalid poll job payload: %w", err)
+	}
+	if cs.ctx.Err() != nil {
+		return ErrCrawlerShuttingDown
+	}
+	lock, err := acquireCrawlLock(cs.ctx)
+	if errors.Is(err, ErrCrawlInProgress) {
+		crawlLog.Info("Skipping intraday poll while another crawl runs", "job_id", job.ID.String())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	cs.runs.Add(1)
+	defer cs.runs.Done()
+	defer lock.Release()
+
+	stocks, err := cs.storedStocks(payload.Codes)
+	if err != nil {
+		return err
+	}
+	run := cs.beginRun(newCrawlRun(models.CrawlRunKindIntraday, nil))
+	crawlLog.Info("Polling intraday prices", "run_id", run.ID.Hex(), "symbols", len(stocks), "job_id", job.ID.String())
+	defer ProviderCache().BeginRun()()
+	tracker := &crawlRunTracker{}
+	cs.crawlPricesWithWorkerPool(stocks, tracker)
+	cs.crawlIndexes()
+	cs.finishRun(run, len(stocks), tracker)
+	return nil
+}
+
+// LastRunStarts returns when the latest crawl run of any kind and the
+// latest full run started, zero when there was none
+func (cs *CrawlerService) LastRunStarts(ctx context.Context) (last, lastFull time.Time, err error) {
+	opts := options.FindOne().SetSort(bson.D{{Key: "startedAt", Value: -1}}).SetProjection(bson.M{"startedAt": 1})
+	find := func(filter bson.M) (time.Time, error) {
+		var run models.CrawlRun
+		err := cs.runCollection.FindOne(ctx, filter, opts).Decode(&run)
+		if err == mongo.ErrNoDocuments {
+			return time.Time{}, nil
+		}
 		if err != nil {
-			log.Printf("❌ Error saving stocks: %v", err)
-			return
+			return time.Time{}, fmt.Errorf("failed to find the latest crawl run: %w", err)
 		}
+		return run.StartedAt.Time(), nil
+	}
+	if last, err = find(bson.M{}); err != nil {
+		return
+	}
+	lastFull, err = find(bson.M{"kind": bson.M{"$nin": models.PartialCrawlRunKinds}})
+	return
+}
 
-		log.Printf("✓ Saved stocks to database")
+// RetrySymbols queues a re-crawl of the prices of the given stocks as a
+// retry run, returned before it starts. Symbols that succeed are
+// acknowledged in the run they failed in. The job waits while another
+// crawl runs.
+func (cs *CrawlerService) RetrySymbols(stocks []models.Stock, retryOf *primitive.ObjectID) (*models.CrawlRun, error) {
+	if cs.ctx.Err() != nil {
+		return nil, ErrCrawlerShuttingDown
+	}
+	run := newCrawlRun(models.CrawlRunKindRetry, retryOf)
+	payload := retryJobPayload{RunID: run.ID, RetryOf: retryOf, Stocks: stocks}
+	if _, err := cs.jobs.Enqueue(cs.ctx, models.JobKindCrawlRetry, payload, JobOptions{}); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
 
-		// Step 3: Crawl prices for all stocks using worker pool
-		cs.crawlPricesWithWorkerPool(stocks)
+// runRetryJob runs a crawl.retry job
+func (cs *CrawlerService) runRetryJob(ctx context.Context, job *models.Job) error {
+	var payload retryJobPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return fmt.Errorf("invalid retry job payload: %w", err)
+	}
+	if cs.ctx.Err() != nil {
+		return ErrCrawlerShuttingDown
+	}
+	lock, err := acquireCrawlLock(cs.ctx)
+	if errors.Is(err, ErrCrawlInProgress) {
+		return DeferJob(crawlLockRetry, err)
+	}
+	if err != nil {
+		return err
+	}
+	cs.runs.Add(1)
+	defer cs.runs.Done()
+	defer lock.Release()
 
-		log.Println("✅ Crawling process completed!")
-	}()
+	run := newCrawlRun(models.CrawlRunKindRetry, payload.RetryOf)
+	run.ID = payload.RunID
+	cs.beginRun(run)
+	crawlLog.Info("Retrying prices", "run_id", run.ID.Hex(), "symbols", len(payload.Stocks), "job_id", job.ID.String())
+	defer ProviderCache().BeginRun()()
+	tracker := &crawlRunTracker{}
+	cs.crawlPricesWithWorkerPool(payload.Stocks, tracker)
+	cs.finishRun(run, len(payload.Stocks), tracker)
 
+	if payload.RetryOf != nil {
+		cs.acknowledgeRetried(*payload.RetryOf, payload.Stocks, run)
+	}
+	if run.Status == models.CrawlRunStatusInterrupted {
+		return DeferJob(0, errCrawlInterrupted)
+	}
 	return nil
 }
 
-// fetchStockList fetches the list of stocks from VNDirect
-func (cs *CrawlerService) fetchStockList() ([]models.Stock, error) {
-	url := fmt.Sprintf("%s?q=type:stock~status:listed~floor:HOSE,HNX,UPCOM&size=9999", stockListURL)
+// acknowledgeRetried acknowledges the errors of symbols that a retry run fixed
+func (cs *CrawlerService) acknowledgeRetried(sourceRunID primitive.ObjectID, stocks []models.Stock, retryRun *models.CrawlRun) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	failed := make(map[string]bool, len(retryRun.Errors))
+	for _, symbolErr := range retryRun.Errors {
+		failed[symbolErr.Code] = true
+	}
+	fixed := make([]string, 0, len(stocks))
+	for _, stock := range stocks {
+		if !failed[stock.Code] {
+			fixed = append(fixed, stock.Code)
+		}
+	}
+	if len(fixed) == 0 {
+		return
+	}
+
+	if _, err := AcknowledgeCrawlErrors(ctx, cs.runCollection, sourceRunID, fixed, "retry:"+retryRun.ID.Hex()); err != nil {
+		crawlLog.Warn("Failed to acknowledge retried symbols", "run_id", sourceRunID.Hex(), logging.FieldError, err)
+	}
+}
+
+// newCrawlRun returns a crawl run that is about to start
+func newCrawlRun(kind string, retryOf *primitive.ObjectID) *models.CrawlRun {
+	return &models.CrawlRun{
+		ID:        primitive.NewObjectID(),
+		Status:    models.CrawlRunStatusRunning,
+		Kind:      kind,
+		RetryOf:   retryOf,
+		StartedAt: primitive.NewDateTimeFromTime(time.Now()),
+		Errors:    []models.CrawlSymbolError{},
+	}
+}
+
+// beginRun records the start of a crawl run, replacing the record of an
+// earlier attempt of the same job. Tracking failures are logged but never
+// abort the crawl itself.
+func (cs *CrawlerService) beginRun(run *models.CrawlRun) *models.CrawlRun {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	run.StartedAt = primitive.NewDateTimeFromTime(time.Now())
+	opts := options.Replace().SetUpsert(true)
+	if _, err := cs.runCollection.ReplaceOne(ctx, bson.M{"_id": run.ID}, run, opts); err != nil {
+		crawlLog.Warn("Failed to record crawl run start", logging.FieldError, err)
+	}
+	return run
+}
+
+// failRun marks a crawl run as aborted
+func (cs *CrawlerService) failRun(run *models.CrawlRun, message string) {
+	run.Status = models.CrawlRunStatusFailed
+	run.Message = message
+	cs.saveRun(run)
+	cs.sendRunSummary(run)
+	cs.publishRunEvents(run, nil)
+}
+
+// finishRun stores the final per-symbol results of a crawl run
+func (cs *CrawlerService) finishRun(run *models.CrawlRun, total int, tracker *crawlRunTracker) {
+	tracker.mu.Lock()
+	run.TotalSymbols = total
+	run.SucceededSymbols = tracker.succeeded
+	run.FailedSymbols = len(tracker.errors)
+	run.Errors = tracker.errors
+	run.SuspectCandles = tracker.suspects
+	interrupted := tracker.interrupted
+	quotaSkipped := tracker.quotaSkipped
+	newDates := tracker.newDates
+	tracker.mu.Unlock()
+
+	if run.Errors == nil {
+		run.Errors = []models.CrawlSymbolError{}
+	}
+	if interrupted > 0 {
+		// Listeners and notifications could outlast the shutdown grace
+		// period; the next run covers the symbols that gained candles
+		run.Status = models.CrawlRunStatusInterrupted
+		run.Message = fmt.Sprintf("interrupted by shutdown: %d symbols not crawled", interrupted)
+		cs.saveRun(run)
+		crawlLog.Warn("Crawl run interrupted", "run_id", run.ID.Hex(),
+			"succeeded", run.SucceededSymbols, "total", run.TotalSymbols, "not_crawled", interrupted)
+		return
+	}
+	run.Status = models.CrawlRunStatusSuccess
+	if quotaSkipped > 0 {
+		run.Message = fmt.Sprintf("provider quota exhausted: %d symbols not crawled", quotaSkipped)
+	}
+	cs.saveRun(run)
+
+	crawlLog.Info("Crawl run finished", "run_id", run.ID.Hex(),
+		"succeeded", run.SucceededSymbols, "total", run.TotalSymbols, "failed", run.FailedSymbols)
+	cs.sendRunSummary(run)
+	cs.publishRunEvents(run, newDates)
+	for _, listener := range cs.runListeners {
+		listener(run, newDates)
+	}
+}
+
+// publishRunEvents queues the webhook events of a finished run:
+// crawl.completed or crawl.failed, and candle.new when symbols gained candles
+func (cs *CrawlerService) publishRunEvents(run *models.CrawlRun, newDates map[string]string) {
+	if cs.webhooks == nil || DryRun() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	resp, err := cs.client.R().Get(url)
+	event := models.WebhookEventCrawlCompleted
+	if run.Status == models.CrawlRunStatusFailed {
+		event = models.WebhookEventCrawlFailed
+	}
+	data := map[string]interface{}{
+		"run_id":            run.ID.Hex(),
+		"kind":              run.Kind,
+		"status":            run.Status,
+		"message":           run.Message,
+		"total_symbols":     run.TotalSymbols,
+		"succeeded_symbols": run.SucceededSymbols,
+		"failed_symbols":    run.FailedSymbols,
+		"started_at":        run.StartedAt.Time().UTC(),
+	}
+	if run.FinishedAt != nil {
+		data["finished_at"] = run.FinishedAt.Time().UTC()
+	}
+	if err := cs.webhooks.Publish(ctx, event, data); err != nil {
+		crawlLog.Warn("Failed to publish webhook event", "event", event, logging.FieldError, err)
+	}
+
+	if len(newDates) == 0 {
+		return
+	}
+	symbols := make([]map[string]string, 0, len(newDates))
+	for code, date := range newDates {
+		symbols = append(symbols, map[string]string{"code": code, "date": date})
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i]["code"] < symbols[j]["code"] })
+	err := cs.webhooks.Publish(ctx, models.WebhookEventCandleNew, map[string]interface{}{
+		"run_id":  run.ID.Hex(),
+		"count":   len(symbols),
+		"symbols": symbols,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch stock list: %w", err)
+		crawlLog.Warn("Failed to publish webhook event", "event", models.WebhookEventCandleNew, logging.FieldError, err)
+	}
+}
+
+// sendRunSummary notifies the configured summary channels of a finished
+// run. Intraday polls run every few minutes and only notify of failures.
+func (cs *CrawlerService) sendRunSummary(run *models.CrawlRun) {
+	channels := config.Runtime().CrawlerSummaryChannels
+	if cs.notifications == nil || len(channels) == 0 || DryRun() {
+		return
+	}
+	if run.Kind == models.CrawlRunKindIntraday && run.Status == models.CrawlRunStatusSuccess && run.FailedSymbols == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if err := cs.notifications.SendInBackground(ctx, channels, crawlRunSummary(run)); err != nil {
+		crawlLog.Warn("Failed to send crawl run summary", "run_id", run.ID.Hex(), logging.FieldError, err)
 	}
+}
 
-	var apiResp VNDirectStockResponse
-	err = json.Unmarshal(resp.Body(), &apiResp)
+// crawlRunSummary describes a finished run as a notification
+func crawlRunSummary(run *models.CrawlRun) Notification {
+	n := Notification{
+		Severity: SeverityInfo,
+		Source:   "crawler",
+		Fields: map[string]interface{}{
+			"run_id": run.ID.Hex(),
+			"kind":   run.Kind,
+		},
+	}
+	if run.Status == models.CrawlRunStatusFailed {
+		n.Title = fmt.Sprintf("Crawl %s failed", run.Kind)
+		n.Message = run.Message
+		n.Severity = SeverityCritical
+		return n
+	}
+
+	n.Title = fmt.Sprintf("Crawl %s finished", run.Kind)
+	n.Message = fmt.Sprintf("%d/%d symbols succeeded, %d failed", run.SucceededSymbols, run.TotalSymbols, run.FailedSymbols)
+	if run.StartedAt != 0 && run.FinishedAt != nil {
+		n.Message += fmt.Sprintf(" in %s", run.FinishedAt.Time().Sub(run.StartedAt.Time()).Round(time.Second))
+	}
+	if run.FailedSymbols > 0 {
+		n.Severity = SeverityWarning
+		codes := make([]string, 0, len(run.Errors))
+		for i, symbolErr := range run.Errors {
+			if i == 10 {
+				codes = append(codes, fmt.Sprintf("+%d more", len(run.Errors)-i))
+				break
+			}
+			codes = append(codes, symbolErr.Code)
+		}
+		n.Fields["failed"] = strings.Join(codes, ", ")
+	}
+	return n
+}
+
+// saveRun persists the final state of a crawl run
+func (cs *CrawlerService) saveRun(run *models.CrawlRun) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	finishedAt := primitive.NewDateTimeFromTime(time.Now())
+	run.FinishedAt = &finishedAt
+
+	opts := options.Replace().SetUpsert(true)
+	if _, err := cs.runCollection.ReplaceOne(ctx, bson.M{"_id": run.ID}, run, opts); err != nil {
+		crawlLog.Warn("Failed to record crawl run result", "run_id", run.ID.Hex(), logging.FieldError, err)
+	}
+}
+
+// fetchStockList fetches the symbols of the configured exchanges and
+// instrument types from the data source of each exchange
+func (cs *CrawlerService) fetchStockList() ([]models.Stock, error) {
+	cfg := config.Runtime()
+	groups, err := groupExchangesBySource(cfg.CrawlerExchanges)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse stock list response: %w", err)
+		return nil, err
 	}
 
-	stocks := make([]models.Stock, 0, len(apiResp.Data))
+	stocks := make([]models.Stock, 0)
 	now := primitive.NewDateTimeFromTime(time.Now())
-
-	for _, item := range apiResp.Data {
-		stock := models.Stock{
-			Code:        item.Code,
-			CompanyName: item.CompanyName,
-			Exchange:    item.Exchange,
-			Type:        item.Type,
-			Status:      item.Status,
-			CreatedAt:   now,
-			UpdatedAt:   now,
+	for _, group := range groups {
+		fetched, err := fetchInstruments(group.source, group.exchanges, cfg.CrawlerInstrumentTypes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", group.source.Name(), err)
+		}
+		classifyStocks(group.source, fetched)
+		countShares(group.source, fetched)
+		for _, stock := range fetched {
+			if cfg.IsExcludedSymbol(stock.Code) {
+				continue
+			}
+			stock.CreatedAt = now
+			stock.UpdatedAt = now
+			stocks = append(stocks, stock)
 		}
-		stocks = append(stocks, stock)
 	}
 
 	return stocks, nil
 }
 
+// classifyStocks sets the ICB classification of stocks from source, when it
+// publishes one. It is optional: without it the stored classification is kept.
+func classifyStocks(source MarketDataSource, stocks []models.Stock) {
+	if classifier, ok := source.(IndustryClassifier); ok {
+		classification, err := classifier.FetchClassification()
+		if err != nil {
+			crawlLog.Warn("Failed to fetch industry classification", "provider", source.Name(), logging.FieldError, err)
+			return
+		}
+		for i := range stocks {
+			c := classification[stocks[i].Code]
+			stocks[i].Industry, stocks[i].Sector, stocks[i].ICBCode = c.Industry, c.Sector, c.ICBCode
+		}
+		return
+	}
+	if classifier, ok := source.(SectorClassifier); ok {
+		sectors, err := classifier.FetchSectors()
+		if err != nil {
+			crawlLog.Warn("Failed to fetch sectors", "provider", source.Name(), logging.FieldError, err)
+			return
+		}
+		for i := range stocks {
+			stocks[i].Sector = sectors[stocks[i].Code]
+		}
+	}
+}
+
+// countShares sets the shares outstanding of stocks from source, when it
+// publishes them. Like the classification it is optional: stocks without a
+// count keep the stored one.
+func countShares(source MarketDataSource, stocks []models.Stock) {
+	fetcher, ok := source.(ShareCountFetcher)
+	if !ok {
+		return
+	}
+	shares, err := fetcher.FetchSharesOutstanding()
+	if err != nil {
+		crawlLog.Warn("Failed to fetch shares outstanding", "provider", source.Name(), logging.FieldError, err)
+		return
+	}
+	for i := range stocks {
+		stocks[i].SharesOutstanding = shares[stocks[i].Code]
+	}
+}
+
 // saveStocks saves or updates stocks in the database
 func (cs *CrawlerService) saveStocks(stocks []models.Stock) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var errorCount int
+	// Load current stocks so unchanged records keep their updatedAt timestamp.
+	// Downstream mirrors rely on updatedAt to fetch deltas.
+	existing, err := cs.loadExistingStocks(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errorCount, unchangedCount int
 	for _, stock := range stocks {
+		if current, ok := existing[stock.Code]; ok && !stockMetadataChanged(current, stock) {
+			unchangedCount++
+			continue
+		}
+		if current, ok := existing[stock.Code]; ok && current.Exchange != "" && current.Exchange != stock.Exchange {
+			cs.recordExchangeTransfer(ctx, current, stock)
+		}
+
 		filter := bson.M{"code": stock.Code}
+		set := bson.M{
+			"companyName": stock.CompanyName,
+			"exchange":    stock.Exchange,
+			"type":        stock.Type,
+			"status":      stock.Status,
+			"updatedAt":   stock.UpdatedAt,
+		}
+		if stock.Sector != "" {
+			set["sector"] = stock.Sector
+		}
+		if stock.Industry != "" {
+			set["industry"] = stock.Industry
+		}
+		if stock.ICBCode != "" {
+			set["icbCode"] = stock.ICBCode
+		}
+		if stock.SharesOutstanding > 0 {
+			set["sharesOutstanding"] = stock.SharesOutstanding
+		}
+		for field, value := range instrumentTerms(stock) {
+			set[field] = value
+		}
+		// Keep the stored English name when the provider sent none
+		nameEn := stock.CompanyNameEn
+		if nameEn != "" {
+			set["companyNameEn"] = nameEn
+		} else {
+			nameEn = existing[stock.Code].CompanyNameEn
+		}
+		set["searchNames"] = models.StockSearchNames(stock.CompanyName, nameEn)
 		update := bson.M{
-			"$set": bson.M{
-				"companyName": stock.CompanyName,
-				"exchange":    stock.Exchange,
-				"type":        stock.Type,
-				"status":      stock.Status,
-				"updatedAt":   stock.UpdatedAt,
-			},
+			"$set": set,
 			"$setOnInsert": bson.M{
 				"createdAt": stock.CreatedAt,
 			},
@@ -165,182 +857,417 @@ func (cs *CrawlerService) saveStocks(stocks []models.Stock) error {
 		opts := options.Update().SetUpsert(true)
 		_, err := cs.stockCollection.UpdateOne(ctx, filter, update, opts)
 		if err != nil {
-			log.Printf("⚠️  Failed to upsert stock %s: %v", stock.Code, err)
+			crawlLog.Warn("Failed to upsert stock", logging.FieldSymbol, stock.Code, logging.FieldError, err)
 			errorCount++
 		}
 	}
 
 	if errorCount > 0 {
-		log.Printf("⚠️  Failed to save %d out of %d stocks", errorCount, len(stocks))
+		crawlLog.Warn("Failed to save some stocks", "failed", errorCount, "stocks", len(stocks))
 	}
+	crawlLog.Info("Stocks saved", "unchanged", unchangedCount, "upserted", len(stocks)-unchangedCount-errorCount)
 
 	return nil
 }
 
-// crawlPricesWithWorkerPool crawls prices using a worker pool pattern
-func (cs *CrawlerService) crawlPricesWithWorkerPool(stocks []models.Stock) {
-	// Create a channel for jobs
-	jobs := make(chan models.Stock, len(stocks))
-	var wg sync.WaitGroup
+// snapshotUniverse stores the crawled stock list as today's universe
+// snapshot. Failures are logged but never abort the crawl.
+func (cs *CrawlerService) snapshotUniverse(stocks []models.Stock) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Start workers
-	for i := 0; i < numWorkers; i++ {
-		wg.Add(1)
-		go cs.priceWorker(i+1, jobs, &wg)
+	snapshot, err := cs.universeService.SaveSnapshot(ctx, stocks, time.Now())
+	if err != nil {
+		crawlLog.Warn("Failed to snapshot stock universe", logging.FieldError, err)
+		return
 	}
+	crawlLog.Info("Stock universe snapshot saved", "date", snapshot.Date, "stocks", snapshot.Count)
+}
 
-	// Send jobs to workers
-	for _, stock := range stocks {
-		jobs <- stock
+// recordExchangeTransfer records a listing that moved between exchanges,
+// effective from the day the move was detected
+func (cs *CrawlerService) recordExchangeTransfer(ctx context.Context, current, crawled models.Stock) {
+	change := &models.SymbolChange{
+		Type:          models.SymbolChangeExchangeTransfer,
+		OldCode:       current.Code,
+		OldExchange:   current.Exchange,
+		NewExchange:   crawled.Exchange,
+		EffectiveDate: markettime.Today(),
+		Source:        models.SymbolChangeSourceCrawler,
 	}
-	close(jobs)
-
-	// Wait for all workers to finish
-	wg.Wait()
+	if err := cs.symbolService.CreateChange(ctx, change); err != nil {
+		crawlLog.Warn("Failed to record exchange transfer", logging.FieldSymbol, current.Code, logging.FieldError, err)
+		return
+	}
+	crawlLog.Info("Exchange transfer recorded", logging.FieldSymbol, current.Code, "from", current.Exchange, "to", crawled.Exchange)
 }
 
-// priceWorker is a worker that processes price fetching jobs
-func (cs *CrawlerService) priceWorker(id int, jobs <-chan models.Stock, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	for stock := range jobs {
-		log.Printf("Worker #%d: Processing %s", id, stock.Code)
+// loadExistingStocks returns the stocks currently stored, keyed by code
+func (cs *CrawlerService) loadExistingStocks(ctx context.Context) (map[string]models.Stock, error) {
+	cursor, err := cs.stockCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing stocks: %w", err)
+	}
+	defer cursor.Close(ctx)
 
-		// Fetch price data from API
-		prices, err := cs.fetchStockPrices(stock.Code)
-		if err != nil {
-			log.Printf("❌ Worker #%d: Failed to fetch prices for %s: %v", id, stock.Code, err)
-			continue
-		}
+	var current []models.Stock
+	if err := cursor.All(ctx, &current); err != nil {
+		return nil, fmt.Errorf("failed to decode existing stocks: %w", err)
+	}
 
-		if len(prices) == 0 {
-			log.Printf("⚠️  Worker #%d: No price data for %s", id, stock.Code)
-			continue
-		}
+	byCode := make(map[string]models.Stock, len(current))
+	for _, stock := range current {
+		byCode[stock.Code] = stock
+	}
+	return byCode, nil
+}
 
-		// Save prices to database using bucket pattern
-		err = cs.savePricesToBuckets(stock.Code, prices)
-		if err != nil {
-			log.Printf("❌ Worker #%d: Failed to save prices for %s: %v", id, stock.Code, err)
-			continue
+// instrumentTerms returns the type-specific fields of an ETF, covered
+// warrant or bond the provider sent, by stored field name
+func instrumentTerms(stock models.Stock) bson.M {
+	terms := bson.M{}
+	for field, value := range map[string]string{
+		"underlying":    stock.Underlying,
+		"issuer":        stock.Issuer,
+		"exerciseRatio": stock.ExerciseRatio,
+		"maturityDate":  stock.MaturityDate,
+	} {
+		if value != "" {
+			terms[field] = value
 		}
-
-		log.Printf("✓ Worker #%d: Saved %d price records for %s", id, len(prices), stock.Code)
-
-		// Rate limiting: sleep between requests
-		time.Sleep(requestDelay)
 	}
+	if stock.ExercisePrice > 0 {
+		terms["exercisePrice"] = stock.ExercisePrice
+	}
+	if stock.CouponRate > 0 {
+		terms["couponRate"] = stock.CouponRate
+	}
+	return terms
 }
 
-// fetchStockPrices fetches price history for a stock code
-func (cs *CrawlerService) fetchStockPrices(code string) ([]models.CandleData, error) {
-	url := fmt.Sprintf("%s?sort=date:desc&q=code:%s&size=270", stockPriceURL, code)
+// stockMetadataChanged reports whether any crawled field differs from the
+// stored stock. A missing classification, share count or instrument term
+// (unavailable from the provider) or English name is no change; stocks stored before search names existed are changed.
+func stockMetadataChanged(current, crawled models.Stock) bool {
+	return current.CompanyName != crawled.CompanyName ||
+		(crawled.CompanyNameEn != "" && current.CompanyNameEn != crawled.CompanyNameEn) ||
+		len(current.SearchNames) == 0 ||
+		current.Exchange != crawled.Exchange ||
+		current.Type != crawled.Type ||
+		current.Status != crawled.Status ||
+		(crawled.Sector != "" && current.Sector != crawled.Sector) ||
+		(crawled.Industry != "" && current.Industry != crawled.Industry) ||
+		(crawled.ICBCode != "" && current.ICBCode != crawled.ICBCode) ||
+		(crawled.SharesOutstanding > 0 && current.SharesOutstanding != crawled.SharesOutstanding) ||
+		(crawled.Underlying != "" && current.Underlying != crawled.Underlying) ||
+		(crawled.Issuer != "" && current.Issuer != crawled.Issuer) ||
+		(crawled.ExercisePrice > 0 && current.ExercisePrice != crawled.ExercisePrice) ||
+		(crawled.ExerciseRatio != "" && current.ExerciseRatio != crawled.ExerciseRatio) ||
+		(crawled.MaturityDate != "" && current.MaturityDate != crawled.MaturityDate) ||
+		(crawled.CouponRate > 0 && current.CouponRate != crawled.CouponRate)
+}
+
+// crawlPricesWithWorkerPool crawls prices with two worker pools, so the
+// full history fetches of a few new or long-stale symbols cannot hold up the
+// daily refresh of the rest: refresh workers take the refresh queue first
+// and then help the backfill workers drain the backfill queue
+func (cs *CrawlerService) crawlPricesWithWorkerPool(stocks []models.Stock, tracker *crawlRunTracker) {
+	cfg := config.Runtime()
 
-	resp, err := cs.client.R().Get(url)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	latest, err := cs.prices.LatestCandleDates(ctx)
+	cancel()
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch prices: %w", err)
+		// Backfilling everything is slower but never misses history
+		crawlLog.Warn("Failed to load latest candle dates, backfilling every symbol", logging.FieldError, err)
 	}
+	cutoff := markettime.Now().AddDate(0, 0, -crawlRefreshMaxGapDays).Format(markettime.DateLayout)
+	refresh, backfill := partitionCrawlJobs(stocks, latest, cutoff)
+	crawlLog.Info("Crawl queues built", "refresh", len(refresh), "backfill", len(backfill))
 
-	var apiResp VNDirectPriceResponse
-	err = json.Unmarshal(resp.Body(), &apiResp)
+	// Listings that moved exchange open with the first-day price band
+	ctx, cancel = context.WithTimeout(context.Background(), 30*time.Second)
+	transfers, err := cs.symbolService.ExchangeTransferDates(ctx)
+	cancel()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse price response: %w", err)
+		crawlLog.Warn("Failed to load exchange transfers, checking their first sessions with the regular band", logging.FieldError, err)
 	}
-
-	candles := make([]models.CandleData, 0, len(apiResp.Data))
-	for _, item := range apiResp.Data {
-		candle := models.CandleData{
-			D: item.Date,
-			O: item.Open,
-			H: item.High,
-			L: item.Low,
-			C: item.Close,
-			V: item.Volume,
+	for _, jobs := range [][]crawlJob{refresh, backfill} {
+		for i := range jobs {
+			jobs[i].transferDates = transfers[jobs[i].stock.Code]
 		}
-		candles = append(candles, candle)
 	}
 
-	return candles, nil
+	cs.pendingSymbols.Add(int64(len(refresh) + len(backfill)))
+	refreshJobs, backfillJobs := crawlQueue(refresh), crawlQueue(backfill)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.CrawlerWorkers; i++ {
+		wg.Add(1)
+		go cs.priceWorker(i+1, cfg.CrawlerRequestDelay, tracker, &wg, refreshJobs, backfillJobs)
+	}
+	for i := 0; i < cfg.CrawlerBackfillWorkers; i++ {
+		wg.Add(1)
+		go cs.priceWorker(cfg.CrawlerWorkers+i+1, cfg.CrawlerRequestDelay, tracker, &wg, backfillJobs)
+	}
+	wg.Wait()
 }
 
-// savePricesToBuckets saves price data to MongoDB using bucket pattern
-func (cs *CrawlerService) savePricesToBuckets(code string, candles []models.CandleData) error {
+// crawlIndexes stores the latest levels of the market indexes of the crawled
+// exchanges next to the symbols' candles, backfilling indexes without recent
+// history. Indexes are not symbols of the run: failures are logged only.
+func (cs *CrawlerService) crawlIndexes() {
+	cfg := config.Runtime()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+	latest, err := cs.prices.LatestCandleDates(ctx)
+	cancel()
+	if err != nil {
+		crawlLog.Warn("Failed to load latest candle dates, backfilling every index", logging.FieldError, err)
+	}
+	cutoff := markettime.Now().AddDate(0, 0, -crawlRefreshMaxGapDays).Format(markettime.DateLayout)
 
-	// Group candles by year
-	bucketsByYear := make(map[int][]models.CandleData)
-	for _, candle := range candles {
-		year, err := models.GetYearFromDate(candle.D)
+	for _, code := range cfg.CrawlerExchanges {
+		exchange, ok := models.LookupExchange(code)
+		if !ok || len(exchange.Indexes) == 0 {
+			continue
+		}
+		source, err := MarketDataSourceFor(code)
 		if err != nil {
-			log.Printf("⚠️  Invalid date format for %s: %s", code, candle.D)
 			continue
 		}
-		bucketsByYear[year] = append(bucketsByYear[year], candle)
+		fetcher, ok := source.(IndexPriceFetcher)
+		if !ok {
+			continue
+		}
+		for _, index := range exchange.Indexes {
+			if cs.ctx.Err() != nil {
+				return
+			}
+			if exhausted, _ := ProviderQuotas().Exhausted(source.Name()); exhausted {
+				crawlLog.Warn("Provider quota nearly used up, skipping market indexes", "provider", source.Name())
+				return
+			}
+			sessions := crawlRefreshSessions
+			if date, ok := latest[index]; !ok || date < cutoff {
+				sessions = indexHistorySessions
+			}
+			candles, err := fetcher.FetchIndexPrices(index, sessions)
+			if err != nil {
+				crawlLog.Warn("Failed to fetch index prices", logging.FieldSymbol, index, logging.FieldError, err)
+				continue
+			}
+			candles, _ = models.NormalizeCandles(candles, exchange)
+			if len(candles) == 0 {
+				continue
+			}
+			if _, err := cs.saveCandles(index, candles); err != nil {
+				crawlLog.Warn("Failed to save index prices", logging.FieldSymbol, index, logging.FieldError, err)
+				continue
+			}
+			crawlLog.Info("Saved index levels", logging.FieldSymbol, index, "records", len(candles))
+		}
+	}
+}
+
+// crawlJob is one symbol of a crawl queue
+type crawlJob struct {
+	stock         models.Stock
+	backfill      bool     // Fetch the full history rather than the latest sessions
+	transferDates []string // Effective dates of the symbol's exchange transfers
+}
+
+// partitionCrawlJobs splits stocks into those whose stored candles reach
+// cutoff (refreshed with their latest sessions) and those without recent
+// candles (backfilled with their full history), keeping their order
+func partitionCrawlJobs(stocks []models.Stock, latest map[string]string, cutoff string) (refresh, backfill []crawlJob) {
+	for _, stock := range stocks {
+		if date, ok := latest[stock.Code]; ok && date >= cutoff {
+			refresh = append(refresh, crawlJob{stock: stock})
+		} else {
+			backfill = append(backfill, crawlJob{stock: stock, backfill: true})
+		}
 	}
+	return refresh, backfill
+}
 
-	// Save each year's data to its bucket
-	for year, yearCandles := range bucketsByYear {
-		bucketID := models.GenerateBucketID(code, year)
+// crawlQueue returns a closed channel holding jobs
+func crawlQueue(jobs []crawlJob) <-chan crawlJob {
+	queue := make(chan crawlJob, len(jobs))
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	return queue
+}
 
-		// Check if bucket exists
-		filter := bson.M{"_id": bucketID}
-		var existingBucket models.PriceBucket
-		err := cs.priceCollection.FindOne(ctx, filter).Decode(&existingBucket)
+// priceWorker processes the jobs of each queue in turn until all are drained
+func (cs *CrawlerService) priceWorker(id int, requestDelay time.Duration, tracker *crawlRunTracker, wg *sync.WaitGroup, queues ...<-chan crawlJob) {
+	defer wg.Done()
 
-		if err == mongo.ErrNoDocuments {
-			// Create new bucket
-			newBucket := models.PriceBucket{
-				ID:      bucketID,
-				Code:    code,
-				Year:    year,
-				History: yearCandles,
+	for _, jobs := range queues {
+		for job := range jobs {
+			cs.pendingSymbols.Add(-1)
+			stock := job.stock
+			if cs.ctx.Err() != nil {
+				tracker.recordInterrupted(stock.Code)
+				continue
 			}
+			if err := cs.awaitProviderQuota(id, stock); err != nil {
+				if errors.Is(err, errCrawlInterrupted) {
+					tracker.recordInterrupted(stock.Code)
+				} else {
+					tracker.recordQuotaSkipped(stock.Code)
+				}
+				continue
+			}
+			stockLog := crawlLog.With("worker", id, logging.FieldSymbol, stock.Code)
+			stockLog.Debug("Processing")
 
-			_, err := cs.priceCollection.InsertOne(ctx, newBucket)
+			// Fetch price data from the exchange's data source
+			prices, err := cs.fetchStockPrices(stock, job.backfill)
 			if err != nil {
-				return fmt.Errorf("failed to insert new bucket: %w", err)
+				stockLog.Error("Failed to fetch prices", logging.FieldError, err)
+				tracker.recordFailure(stock.Code, err)
+				continue
 			}
-		} else if err == nil {
-			// Bucket exists - merge data without duplicates
-			existingDates := make(map[string]bool)
-			for _, candle := range existingBucket.History {
-				existingDates[candle.D] = true
+
+			// Provider quirks (timestamps, weekend dates, repeated dates) must not
+			// become duplicate candles in the buckets
+			exchange, _ := models.LookupExchange(stock.Exchange)
+			prices, normalization := models.NormalizeCandles(prices, exchange)
+			if normalization.Changed() {
+				stockLog.Warn("Normalized provider candles",
+					"reformatted", normalization.Reformatted,
+					"duplicates", normalization.Duplicates,
+					"non_trading_day", normalization.NonTradingDay,
+					"invalid", normalization.Invalid)
 			}
 
-			// Add only new candles
-			newCandles := make([]models.CandleData, 0)
-			for _, candle := range yearCandles {
-				if !existingDates[candle.D] {
-					newCandles = append(newCandles, candle)
-				}
+			// Candles outside the exchange's price band are held back for review
+			prices, suspects := cs.screenPriceBands(stock, exchange, prices, job.transferDates)
+			if suspects > 0 {
+				tracker.recordSuspects(suspects)
 			}
 
-			if len(newCandles) > 0 {
-				update := bson.M{
-					"$push": bson.M{
-						"history": bson.M{
-							"$each": newCandles,
-						},
-					},
-				}
+			if len(prices) == 0 {
+				stockLog.Warn("No price data")
+				tracker.recordSuccess()
+				continue
+			}
 
-				_, err := cs.priceCollection.UpdateOne(ctx, filter, update)
-				if err != nil {
-					return fmt.Errorf("failed to update bucket: %w", err)
-				}
+			// Save prices to the price repository
+			newest, err := cs.saveCandles(stock.Code, prices)
+			if err != nil {
+				stockLog.Error("Failed to save prices", logging.FieldError, err)
+				tracker.recordFailure(stock.Code, err)
+				continue
+			}
+			tracker.recordSuccess()
+			if newest != "" {
+				tracker.recordNewCandles(stock.Code, newest)
+			}
+
+			stockLog.Info("Saved price records", "records", len(prices), "backfill", job.backfill)
+
+			// Rate limiting: sleep between requests
+			select {
+			case <-cs.ctx.Done():
+			case <-time.After(requestDelay):
 			}
-		} else {
-			return fmt.Errorf("failed to check bucket existence: %w", err)
 		}
 	}
+}
 
-	return nil
+// awaitProviderQuota waits while the quota of stock's data source is nearly
+// used up. It gives up with ErrProviderQuotaExhausted when the quota resets
+// after more than maxQuotaPause, or with errCrawlInterrupted on shutdown.
+func (cs *CrawlerService) awaitProviderQuota(workerID int, stock models.Stock) error {
+	source, err := MarketDataSourceFor(stock.Exchange)
+	if err != nil {
+		return nil // Reported by the fetch itself
+	}
+	for {
+		exhausted, resumeAt := ProviderQuotas().Exhausted(source.Name())
+		if !exhausted {
+			return nil
+		}
+		wait := time.Until(resumeAt)
+		if wait > maxQuotaPause {
+			return ErrProviderQuotaExhausted
+		}
+		crawlLog.Info("Provider quota nearly used up, pausing", "worker", workerID, "provider", source.Name(), "resume_at", resumeAt.Format(time.RFC3339))
+		select {
+		case <-cs.ctx.Done():
+			return errCrawlInterrupted
+		case <-time.After(wait):
+		}
+	}
+}
+
+// fetchStockPrices fetches price history for a stock from its exchange's
+// data source: the full history for a backfill, otherwise only the latest
+// sessions when the source supports it
+func (cs *CrawlerService) fetchStockPrices(stock models.Stock, backfill bool) ([]models.CandleData, error) {
+	source, err := MarketDataSourceFor(stock.Exchange)
+	if err != nil {
+		return nil, err
+	}
+	if fetcher, ok := source.(RecentPriceFetcher); ok && !backfill {
+		return fetcher.FetchRecentPrices(stock, crawlRefreshSessions)
+	}
+	return source.FetchPrices(stock)
+}
+
+// saveCandles stores the candles of code in the price repository and
+// returns the date of the newest candle that was not stored yet ("" if none).
+// The candle of today's session replaces the stored one, so intraday polls
+// and the end-of-day crawl keep it current.
+func (cs *CrawlerService) saveCandles(code string, candles []models.CandleData) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	today := markettime.Today()
+	past := make([]models.CandleData, 0, len(candles))
+	var session *models.CandleData
+	for i := range candles {
+		if candles[i].D == today {
+			session = &candles[i]
+			continue
+		}
+		past = append(past, candles[i])
+	}
+
+	newest := ""
+	if len(past) > 0 {
+		var err error
+		if newest, err = cs.prices.SaveCandles(ctx, code, past); err != nil {
+			return "", err
+		}
+	}
+	if session != nil {
+		added, err := cs.prices.SaveSessionCandle(ctx, code, *session)
+		if err != nil {
+			return "", err
+		}
+		if added {
+			newest = today
+		}
+	}
+	return newest, nil
+}
+
+// newestCandleDate returns the later of newest and the newest candle date
+func newestCandleDate(newest string, candles []models.CandleData) string {
+	for _, candle := range candles {
+		if candle.D > newest {
+			newest = candle.D
+		}
+	}
+	return newest
 }
 
 // GetCrawlStatus returns the current status of the crawler (for monitoring)
-func (cs *CrawlerService) GetCrawlStatus() (map[string]interface{}, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (cs *CrawlerService) GetCrawlStatus(ctx context.Context) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	stockCount, err := cs.stockCollection.CountDocuments(ctx, bson.M{})
@@ -348,14 +1275,50 @@ func (cs *CrawlerService) GetCrawlStatus() (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	bucketCount, err := cs.priceCollection.CountDocuments(ctx, bson.M{})
-	if err != nil {
-		return nil, err
+	status := map[string]interface{}{
+		"total_stocks":    stockCount,
+		"price_store":     cs.prices.Store(),
+		"provider_quotas": ProviderQuotas().Usage(),
+		"provider_cache":  ProviderCache().Stats(),
+		"timestamp":       time.Now().Format(time.RFC3339),
+	}
+	if cs.prices.Store() == config.StoreMongo {
+		bucketCount, err := cs.priceCollection.CountDocuments(ctx, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		status["total_price_buckets"] = bucketCount
+	}
+
+	// Latest run of any kind, with its timing and errors
+	var run models.CrawlRun
+	opts := options.FindOne().SetSort(bson.D{{Key: "startedAt", Value: -1}})
+	switch err := cs.runCollection.FindOne(ctx, bson.M{}, opts).Decode(&run); err {
+	case nil:
+		status["last_run"] = newCrawlRunStatus(run, time.Now())
+	case mongo.ErrNoDocuments:
+		status["last_run"] = nil
+	default:
+		return nil, fmt.Errorf("failed to fetch latest crawl run: %w", err)
 	}
 
-	return map[string]interface{}{
-		"total_stocks":        stockCount,
-		"total_price_buckets": bucketCount,
-		"timestamp":           time.Now().Format(time.RFC3339),
-	}, nil
+	// Per exchange: symbols, and how many have no candle in the latest sessions
+	var stocks []models.Stock
+	cursor, err := cs.stockCollection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"code": 1, "exchange": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stocks: %w", err)
+	}
+	if err := cursor.All(ctx, &stocks); err != nil {
+		return nil, fmt.Errorf("failed to decode stocks: %w", err)
+	}
+	newest, err := cs.prices.LatestCandleDates(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read latest candle dates: %w", err)
+	}
+	sessions := config.Runtime().HealthStaleSessions
+	breakdown, stale := exchangeFreshness(stocks, newest, sessions, time.Now())
+	status["exchanges"] = breakdown
+	status["stale_symbols"] = stale
+	status["stale_sessions"] = sessions
+	return status, nil
 }
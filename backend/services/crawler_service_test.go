@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestPriceWorkerStopsAfterShutdown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cs := &CrawlerService{ctx: ctx, cancel: cancel}
+
+	refresh := crawlQueue([]crawlJob{{stock: models.Stock{Code: "HPG", Exchange: "HOSE"}}})
+	backfill := crawlQueue([]crawlJob{{stock: models.Stock{Code: "VNM", Exchange: "HOSE"}, backfill: true}})
+
+	tracker := &crawlRunTracker{}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	cs.priceWorker(1, 0, tracker, &wg, refresh, backfill)
+
+	if tracker.interrupted != 2 || tracker.succeeded != 0 {
+		t.Fatalf("interrupted = %d, succeeded = %d; want 2 and 0", tracker.interrupted, tracker.succeeded)
+	}
+	if len(tracker.errors) != 2 || tracker.errors[0].Error != errCrawlInterrupted.Error() {
+		t.Errorf("errors = %+v; want both symbols recorded as interrupted", tracker.errors)
+	}
+}
+
+func TestCrawlerShutdownRejectsNewCrawls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cs := &CrawlerService{ctx: ctx, cancel: cancel}
+
+	if err := cs.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() with no runs: %v", err)
+	}
+	if err := cs.StartCrawling(); err != ErrCrawlerShuttingDown {
+		t.Errorf("StartCrawling() after Shutdown = %v; want ErrCrawlerShuttingDown", err)
+	}
+}
+
+func TestPartitionCrawlJobs(t *testing.T) {
+	stocks := []models.Stock{{Code: "HPG"}, {Code: "NEW"}, {Code: "VNM"}, {Code: "OLD"}, {Code: "FPT"}}
+	latest := map[string]string{"HPG": "2026-03-06", "VNM": "2026-02-23", "OLD": "2025-11-14", "FPT": "2026-03-09"}
+
+	refresh, backfill := partitionCrawlJobs(stocks, latest, "2026-02-23")
+	codes := func(jobs []crawlJob, wantBackfill bool) []string {
+		result := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			if job.backfill != wantBackfill {
+				t.Errorf("%s backfill = %v; want %v", job.stock.Code, job.backfill, wantBackfill)
+			}
+			result = append(result, job.stock.Code)
+		}
+		return result
+	}
+	if got := codes(refresh, false); len(got) != 3 || got[0] != "HPG" || got[1] != "VNM" || got[2] != "FPT" {
+		t.Errorf("refresh = %v; want [HPG VNM FPT]", got)
+	}
+	if got := codes(backfill, true); len(got) != 2 || got[0] != "NEW" || got[1] != "OLD" {
+		t.Errorf("backfill = %v; want [NEW OLD]", got)
+	}
+}
+
+func TestFirstSessions(t *testing.T) {
+	stored := []models.CandleData{{D: "2024-02-28"}, {D: "2024-03-01"}}
+	candles := []models.CandleData{{D: "2024-03-04"}, {D: "2024-03-05"}}
+
+	// Transfers are recorded when detected: the weekend before its first session
+	first := firstSessions([]string{"2024-03-02", "2024-02-29"}, stored, candles)
+	if len(first) != 2 || !first["2024-03-04"] || !first["2024-03-01"] {
+		t.Errorf("firstSessions() = %v; want 2024-03-01 and 2024-03-04", first)
+	}
+	if first := firstSessions([]string{"2024-04-01"}, stored, candles); len(first) != 0 {
+		t.Errorf("firstSessions() without a later session = %v; want none", first)
+	}
+}
+
+func TestInstrumentTermsChangeMetadata(t *testing.T) {
+	stored := models.Stock{Code: "CHPG2401", CompanyName: "CW", Type: models.InstrumentCoveredWarrant, SearchNames: []string{"cw"},
+		Underlying: "HPG", ExercisePrice: 28.5, ExerciseRatio: "2:1", MaturityDate: "2026-12-28"}
+
+	crawled := stored
+	crawled.SearchNames = nil
+	if stockMetadataChanged(stored, crawled) {
+		t.Error("stockMetadataChanged() with the same terms = true")
+	}
+	crawled.ExercisePrice, crawled.MaturityDate = 0, ""
+	if stockMetadataChanged(stored, crawled) {
+		t.Error("stockMetadataChanged() without terms from the provider = true; want the stored ones kept")
+	}
+	crawled.ExercisePrice = 27.9 // Adjusted after a dividend
+	if !stockMetadataChanged(stored, crawled) {
+		t.Error("stockMetadataChanged() with a new exercise price = false")
+	}
+
+	terms := instrumentTerms(crawled)
+	if len(terms) != 3 || terms["exercisePrice"] != 27.9 || terms["underlying"] != "HPG" || terms["exerciseRatio"] != "2:1" {
+		t.Errorf("instrumentTerms() = %v; want underlying, exercisePrice and exerciseRatio", terms)
+	}
+}
+
+func TestSaveCandlesReplacesSessionCandle(t *testing.T) {
+	repo := &memoryPriceRepository{candles: map[string][]models.CandleData{}}
+	cs := &CrawlerService{prices: repo}
+	today := markettime.Today()
+	yesterday := markettime.Now().AddDate(0, 0, -1).Format(markettime.DateLayout)
+
+	// Two intraday polls of the open session, then the end-of-day crawl
+	saves := [][]models.CandleData{
+		{{D: yesterday, C: 25, V: 900}, {D: today, O: 25, H: 25.5, L: 25, C: 25.2, V: 1000}},
+		{{D: yesterday, C: 25, V: 900}, {D: today, O: 25, H: 25.8, L: 24.9, C: 25.6, V: 2500}},
+		{{D: yesterday, C: 99, V: 1}, {D: today, O: 25, H: 26, L: 24.9, C: 25.9, V: 4100}},
+	}
+	wantNewest := []string{today, "", ""}
+	for i, candles := range saves {
+		newest, err := cs.saveCandles("HPG", candles)
+		if err != nil || newest != wantNewest[i] {
+			t.Fatalf("saveCandles() #%d = %q, %v; want %q", i+1, newest, err, wantNewest[i])
+		}
+	}
+
+	stored := repo.candles["HPG"]
+	if len(stored) != 2 {
+		t.Fatalf("stored %d candles; want 2", len(stored))
+	}
+	for _, candle := range stored {
+		switch candle.D {
+		case yesterday:
+			if candle.C != 25 {
+				t.Errorf("past candle close = %v; want 25, kept as first stored", candle.C)
+			}
+		case today:
+			if candle.C != 25.9 || candle.V != 4100 || candle.H != 26 {
+				t.Errorf("session candle = %+v; want the end-of-day close 25.9 and volume 4100", candle)
+			}
+		}
+	}
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// credentialLog logs provider credential loading and tests
+var credentialLog = logging.Component("credentials")
+
+const (
+	// credentialCacheTTL bounds how long a decrypted credential is reused
+	// before it is read again, so other instances pick up a rotation
+	credentialCacheTTL = time.Minute
+	// providerTestTimeout bounds one provider health test
+	providerTestTimeout = 15 * time.Second
+)
+
+var (
+	// ErrCredentialsKeyMissing is returned when PROVIDER_CREDENTIALS_KEY is not set
+	ErrCredentialsKeyMissing = errors.New("PROVIDER_CREDENTIALS_KEY is not set")
+	// ErrCredentialNotFound is returned when a provider credential does not exist
+	ErrCredentialNotFound = errors.New("provider credential not found")
+	// ErrUnknownProvider is returned for a provider that is not a registered data source
+	ErrUnknownProvider = errors.New("unknown data provider")
+	// ErrProviderNotTestable is returned when a data source has no health check
+	ErrProviderNotTestable = errors.New("data provider has no health check")
+	// ErrInvalidCredential is returned when a credential name or value is rejected
+	ErrInvalidCredential = errors.New("invalid provider credential")
+)
+
+// ProviderInfo describes a registered data source for the admin API
+type ProviderInfo struct {
+	Name        string                      `json:"name"`
+	Testable    bool                        `json:"testable"`
+	Credentials []models.ProviderCredential `json:"credentials"`
+}
+
+// ProviderTestResult is the outcome of a provider health test
+type ProviderTestResult struct {
+	Provider  string    `json:"provider"`
+	OK        bool      `json:"ok"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMS int64     `json:"latency_ms"`
+	TestedAt  time.Time `json:"tested_at"`
+}
+
+// CredentialService stores the credentials of market data providers
+// encrypted in the provider_credentials table. Data sources read them on
+// every request through Lookup, falling back to <PROVIDER>_<NAME>
+// environment variables, so a token can be rotated from the admin API.
+type CredentialService struct {
+	aead         cipher.AEAD // nil when PROVIDER_CREDENTIALS_KEY is not set
+	auditService *AuditService
+
+	mu       sync.Mutex
+	cache    map[string]string // provider/name → plaintext
+	loadedAt time.Time
+}
+
+// NewCredentialServiceFromEnv creates a CredentialService encrypting with
+// PROVIDER_CREDENTIALS_KEY (32 bytes, base64 or hex). Without the key only
+// environment variables are available. Changes are recorded in the audit log.
+func NewCredentialServiceFromEnv(auditService *AuditService) (*CredentialService, error) {
+	s := &CredentialService{auditService: auditService}
+	raw := strings.TrimSpace(os.Getenv("PROVIDER_CREDENTIALS_KEY"))
+	if raw == "" {
+		return s, nil
+	}
+	aead, err := newCredentialCipher(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROVIDER_CREDENTIALS_KEY: %w", err)
+	}
+	s.aead = aead
+	return s, nil
+}
+
+// newCredentialCipher creates an AES-256-GCM cipher from a base64 or hex key
+func newCredentialCipher(raw string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil || len(key) != 32 {
+		key, err = hex.DecodeString(raw)
+	}
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("key must be 32 bytes, base64 or hex encoded")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// credentialCacheKey identifies a credential in the cache; it is also the
+// additional data of its ciphertext, so a value copied to another row does
+// not decrypt
+func credentialCacheKey(provider, name string) string {
+	return provider + "/" + name
+}
+
+// encryptCredential returns base64(nonce || ciphertext) bound to provider/name
+func encryptCredential(aead cipher.AEAD, provider, name, value string) (string, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(credentialCacheKey(provider, name)))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptCredential reverses encryptCredential
+func decryptCredential(aead cipher.AEAD, provider, name, encrypted string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("malformed ciphertext")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(credentialCacheKey(provider, name)))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt (wrong PROVIDER_CREDENTIALS_KEY?)")
+	}
+	return string(plaintext), nil
+}
+
+// AttachSources hands a credential lookup to every registered data source
+// that authenticates with provider credentials
+func (s *CredentialService) AttachSources() {
+	for _, source := range MarketDataSources() {
+		if credentialed, ok := source.(CredentialedSource); ok {
+			provider := source.Name()
+			credentialed.UseCredentials(func(ctx context.Context, name string) (string, error) {
+				return s.Lookup(ctx, provider, name)
+			})
+		}
+	}
+}
+
+// ListProviders returns the registered data sources with their stored credentials
+func (s *CredentialService) ListProviders(ctx context.Context) ([]ProviderInfo, error) {
+	var credentials []models.ProviderCredential
+	if err := config.GetDBWithContext(ctx).Order("provider ASC, name ASC").Find(&credentials).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch provider credentials: %w", err)
+	}
+
+	byProvider := make(map[string][]models.ProviderCredential)
+	for _, credential := range credentials {
+		byProvider[credential.Provider] = append(byProvider[credential.Provider], credential)
+	}
+
+	sources := MarketDataSources()
+	providers := make([]ProviderInfo, 0, len(sources))
+	for _, source := range sources {
+		_, testable := source.(ProviderHealthChecker)
+		stored := byProvider[source.Name()]
+		if stored == nil {
+			stored = []models.ProviderCredential{}
+		}
+		providers = append(providers, ProviderInfo{Name: source.Name(), Testable: testable, Credentials: stored})
+	}
+	return providers, nil
+}
+
+// Configured reports whether credentials can be stored (PROVIDER_CREDENTIALS_KEY is set)
+func (s *CredentialService) Configured() bool {
+	return s.aead != nil
+}
+
+// SetCredential encrypts and stores a credential, replacing the previous value
+func (s *CredentialService) SetCredential(ctx context.Context, provider, name, value, actor, ip string) (*models.ProviderCredential, error) {
+	if s.aead == nil {
+		return nil, ErrCredentialsKeyMissing
+	}
+	if _, ok := LookupMarketDataSource(provider); !ok {
+		return nil, ErrUnknownProvider
+	}
+	if err := models.ValidateCredentialKey(provider, name); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	if value == "" {
+		return nil, fmt.Errorf("%w: value is required", ErrInvalidCredential)
+	}
+
+	encrypted, err := encryptCredential(s.aead, provider, name, value)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	credential := &models.ProviderCredential{
+		ID:             uuid.New(),
+		Provider:       provider,
+		Name:           name,
+		EncryptedValue: encrypted,
+		Hint:           models.CredentialHint(value),
+		UpdatedBy:      optionalString(actor),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	// A new value invalidates the previous test result
+	err = config.GetDBWithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "provider"}, {Name: "name"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"encrypted_value": credential.EncryptedValue,
+			"hint":            credential.Hint,
+			"updated_by":      credential.UpdatedBy,
+			"updated_at":      now,
+			"last_tested_at":  nil,
+			"last_test_ok":    nil,
+			"last_test_error": nil,
+		}),
+	}).Create(credential).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to store provider credential: %w", err)
+	}
+	s.invalidate()
+	// Reload so that a replaced credential keeps its ID and creation time
+	var stored models.ProviderCredential
+	if err := config.GetDBWithContext(ctx).First(&stored, "provider = ? AND name = ?", provider, name).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch provider credential: %w", err)
+	}
+
+	s.auditService.Record(ctx, AuditEntry{
+		Actor:      actor,
+		Action:     models.AuditActionCredentialSet,
+		Outcome:    models.AuditOutcomeSuccess,
+		EntityType: "provider_credential",
+		EntityID:   credentialCacheKey(provider, name),
+		IP:         ip,
+		Details:    map[string]interface{}{"hint": stored.Hint},
+	})
+	return &stored, nil
+}
+
+// DeleteCredential removes a stored credential; the data source falls back
+// to its environment variable, if any
+func (s *CredentialService) DeleteCredential(ctx context.Context, provider, name, actor, ip string) error {
+	result := config.GetDBWithContext(ctx).
+		Where("provider = ? AND name = ?", provider, name).
+		Delete(&models.ProviderCredential{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete provider credential: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrCredentialNotFound
+	}
+	s.invalidate()
+
+	s.auditService.Record(ctx, AuditEntry{
+		Actor:      actor,
+		Action:     models.AuditActionCredentialDelete,
+		Outcome:    models.AuditOutcomeSuccess,
+		EntityType: "provider_credential",
+		EntityID:   credentialCacheKey(provider, name),
+		IP:         ip,
+	})
+	return nil
+}
+
+// Lookup returns the plaintext of a credential, falling back to the
+// <PROVIDER>_<NAME> environment variable, or "" when neither is set
+func (s *CredentialService) Lookup(ctx context.Context, provider, name string) (string, error) {
+	if s.aead != nil {
+		credentials, err := s.load(ctx)
+		if err != nil {
+			return "", err
+		}
+		if value, ok := credentials[credentialCacheKey(provider, name)]; ok {
+			return value, nil
+		}
+	}
+	return os.Getenv(strings.ToUpper(provider + "_" + name)), nil
+}
+
+// load returns all decrypted credentials, reading them again once the cache expires
+func (s *CredentialService) load(ctx context.Context) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache != nil && time.Since(s.loadedAt) < credentialCacheTTL {
+		return s.cache, nil
+	}
+
+	var rows []models.ProviderCredential
+	if err := config.GetDBWithContext(ctx).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch provider credentials: %w", err)
+	}
+	credentials := make(map[string]string, len(rows))
+	for _, row := range rows {
+		value, err := decryptCredential(s.aead, row.Provider, row.Name, row.EncryptedValue)
+		if err != nil {
+			credentialLog.Warn("Skipping provider credential", "provider", row.Provider, "name", row.Name, logging.FieldError, err)
+			continue
+		}
+		credentials[credentialCacheKey(row.Provider, row.Name)] = value
+	}
+	s.cache = credentials
+	s.loadedAt = time.Now()
+	return credentials, nil
+}
+
+// invalidate makes the next Lookup read the credentials again
+func (s *CredentialService) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache = nil
+}
+
+// TestProvider runs a data source's health check with its current
+// credentials and records the result on the provider's credentials
+func (s *CredentialService) TestProvider(ctx context.Context, provider string) (*ProviderTestResult, error) {
+	source, ok := LookupMarketDataSource(provider)
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	checker, ok := source.(ProviderHealthChecker)
+	if !ok {
+		return nil, ErrProviderNotTestable
+	}
+
+	testCtx, cancel := context.WithTimeout(ctx, providerTestTimeout)
+	defer cancel()
+	started := time.Now()
+	err := checker.HealthCheck(testCtx)
+	result := &ProviderTestResult{
+		Provider:  provider,
+		OK:        err == nil,
+		LatencyMS: time.Since(started).Milliseconds(),
+		TestedAt:  started.UTC(),
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	if err := config.GetDBWithContext(ctx).Model(&models.ProviderCredential{}).
+		Where("provider = ?", provider).
+		Updates(map[string]interface{}{
+			"last_tested_at":  result.TestedAt,
+			"last_test_ok":    result.OK,
+			"last_test_error": optionalString(result.Error),
+		}).Error; err != nil {
+		credentialLog.Warn("Failed to record provider test result", "provider", provider, logging.FieldError, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestCredentialEncryptionRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	aead, err := newCredentialCipher(base64.StdEncoding.EncodeToString(key))
+	if err != nil {
+		t.Fatalf("newCredentialCipher(base64) error = %v", err)
+	}
+
+	encrypted, err := encryptCredential(aead, "tcbs", "api_key", "secret-token-123")
+	if err != nil {
+		t.Fatalf("encryptCredential() error = %v", err)
+	}
+	if strings.Contains(encrypted, "secret-token-123") {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+	again, _ := encryptCredential(aead, "tcbs", "api_key", "secret-token-123")
+	if again == encrypted {
+		t.Error("encrypting twice gave the same ciphertext; want a fresh nonce")
+	}
+
+	plaintext, err := decryptCredential(aead, "tcbs", "api_key", encrypted)
+	if err != nil || plaintext != "secret-token-123" {
+		t.Errorf("decryptCredential() = %q, %v; want the original value", plaintext, err)
+	}
+	// The ciphertext is bound to its provider and name
+	if _, err := decryptCredential(aead, "ssi", "api_key", encrypted); err == nil {
+		t.Error("decrypting under another provider succeeded; want an error")
+	}
+
+	// The same key in hex decrypts it too
+	hexCipher, err := newCredentialCipher(hex.EncodeToString(key))
+	if err != nil {
+		t.Fatalf("newCredentialCipher(hex) error = %v", err)
+	}
+	if plaintext, err := decryptCredential(hexCipher, "tcbs", "api_key", encrypted); err != nil || plaintext != "secret-token-123" {
+		t.Errorf("decrypt with hex key = %q, %v; want the original value", plaintext, err)
+	}
+}
+
+func TestNewCredentialCipherRejectsShortKeys(t *testing.T) {
+	for _, raw := range []string{"too-short", base64.StdEncoding.EncodeToString(make([]byte, 16))} {
+		if _, err := newCredentialCipher(raw); err == nil {
+			t.Errorf("newCredentialCipher(%q) succeeded; want an error", raw)
+		}
+	}
+}
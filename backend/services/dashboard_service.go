@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrWidgetNotFound is returned when a widget ID does not exist on the admin's dashboard
+	ErrWidgetNotFound = errors.New("dashboard widget not found")
+	// ErrTooManyWidgets is returned when a dashboard already has MaxDashboardWidgets widgets
+	ErrTooManyWidgets = fmt.Errorf("a dashboard holds at most %d widgets", models.MaxDashboardWidgets)
+	// ErrInvalidLayout is returned when a layout does not list exactly the dashboard's widgets
+	ErrInvalidLayout = errors.New("layout must list every widget of the dashboard exactly once")
+)
+
+// DashboardService stores each admin user's dashboard widget configuration
+type DashboardService struct{}
+
+// NewDashboardService creates a new DashboardService instance
+func NewDashboardService() *DashboardService {
+	return &DashboardService{}
+}
+
+// ListWidgets returns an admin's widgets in display order. On an admin's
+// first visit the default layout is saved so its widgets can be edited like
+// any other; an admin who has since deleted every widget gets an empty list.
+func (s *DashboardService) ListWidgets(ctx context.Context, adminUserID uuid.UUID) ([]models.DashboardWidget, error) {
+	db := config.GetDBWithContext(ctx)
+
+	widgets := []models.DashboardWidget{}
+	err := db.Where("admin_user_id = ?", adminUserID).Order("position ASC, created_at ASC").Find(&widgets).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dashboard widgets: %w", err)
+	}
+	if len(widgets) > 0 {
+		return widgets, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Claiming the seed marks it done, so it happens once even when
+		// two first visits race
+		result := tx.Model(&models.AdminUser{}).
+			Where("id = ? AND dashboard_seeded_at IS NULL", adminUserID).
+			Update("dashboard_seeded_at", time.Now().UTC())
+		if result.Error != nil {
+			return fmt.Errorf("failed to mark dashboard as seeded: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		widgets, err = s.seedDefaults(tx, adminUserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return widgets, nil
+}
+
+// ResetWidgets replaces an admin's widgets with the default layout
+func (s *DashboardService) ResetWidgets(ctx context.Context, adminUserID uuid.UUID) ([]models.DashboardWidget, error) {
+	var widgets []models.DashboardWidget
+	err := config.GetDBWithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("admin_user_id = ?", adminUserID).Delete(&models.DashboardWidget{}).Error; err != nil {
+			return fmt.Errorf("failed to delete dashboard widgets: %w", err)
+		}
+		err := tx.Model(&models.AdminUser{}).Where("id = ?", adminUserID).
+			Update("dashboard_seeded_at", time.Now().UTC()).Error
+		if err != nil {
+			return fmt.Errorf("failed to mark dashboard as seeded: %w", err)
+		}
+		widgets, err = s.seedDefaults(tx, adminUserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return widgets, nil
+}
+
+// CreateWidget validates a widget and appends it to the admin's dashboard
+func (s *DashboardService) CreateWidget(ctx context.Context, adminUserID uuid.UUID, widget *models.DashboardWidget) error {
+	if err := widget.Validate(); err != nil {
+		return err
+	}
+
+	db := config.GetDBWithContext(ctx)
+	var count int64
+	if err := db.Model(&models.DashboardWidget{}).Where("admin_user_id = ?", adminUserID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count dashboard widgets: %w", err)
+	}
+	if count >= models.MaxDashboardWidgets {
+		return ErrTooManyWidgets
+	}
+	widget.Position = int(count)
+
+	now := time.Now().UTC()
+	widget.ID = uuid.New()
+	widget.AdminUserID = adminUserID
+	widget.CreatedAt = now
+	widget.UpdatedAt = now
+	if err := db.Create(widget).Error; err != nil {
+		return fmt.Errorf("failed to create dashboard widget: %w", err)
+	}
+	return nil
+}
+
+// UpdateWidget replaces the editable fields of one of the admin's widgets
+func (s *DashboardService) UpdateWidget(ctx context.Context, adminUserID uuid.UUID, id string, changes models.DashboardWidget) (*models.DashboardWidget, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrWidgetNotFound
+	}
+	db := config.GetDBWithContext(ctx)
+
+	var widget models.DashboardWidget
+	err := db.First(&widget, "id = ? AND admin_user_id = ?", id, adminUserID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWidgetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dashboard widget: %w", err)
+	}
+
+	widget.Type = changes.Type
+	widget.Title = changes.Title
+	widget.Position = changes.Position
+	widget.Width = changes.Width
+	widget.RefreshSeconds = changes.RefreshSeconds
+	widget.Settings = changes.Settings
+	if err := widget.Validate(); err != nil {
+		return nil, err
+	}
+
+	widget.UpdatedAt = time.Now().UTC()
+	if err := db.Model(&widget).
+		Select("type", "title", "position", "width", "refresh_seconds", "settings", "updated_at").
+		Updates(&widget).Error; err != nil {
+		return nil, fmt.Errorf("failed to update dashboard widget: %w", err)
+	}
+	return &widget, nil
+}
+
+// DeleteWidget removes one of the admin's widgets
+func (s *DashboardService) DeleteWidget(ctx context.Context, adminUserID uuid.UUID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrWidgetNotFound
+	}
+	result := config.GetDBWithContext(ctx).Delete(&models.DashboardWidget{}, "id = ? AND admin_user_id = ?", id, adminUserID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete dashboard widget: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWidgetNotFound
+	}
+	return nil
+}
+
+// SaveLayout reorders the admin's widgets: ids lists every widget in its new
+// display order
+func (s *DashboardService) SaveLayout(ctx context.Context, adminUserID uuid.UUID, ids []string) ([]models.DashboardWidget, error) {
+	db := config.GetDBWithContext(ctx)
+
+	var widgets []models.DashboardWidget
+	if err := db.Where("admin_user_id = ?", adminUserID).Find(&widgets).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch dashboard widgets: %w", err)
+	}
+	positions, err := layoutPositions(widgets, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for i := range widgets {
+			widgets[i].Position = positions[widgets[i].ID.String()]
+			widgets[i].UpdatedAt = now
+			if err := tx.Model(&widgets[i]).UpdateColumns(map[string]interface{}{
+				"position":   widgets[i].Position,
+				"updated_at": now,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to save dashboard layout: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]models.DashboardWidget, len(widgets))
+	for _, widget := range widgets {
+		ordered[widget.Position] = widget
+	}
+	return ordered, nil
+}
+
+// seedDefaults saves the default layout for an admin
+func (s *DashboardService) seedDefaults(db *gorm.DB, adminUserID uuid.UUID) ([]models.DashboardWidget, error) {
+	now := time.Now().UTC()
+	widgets := models.DefaultDashboardWidgets()
+	for i := range widgets {
+		widgets[i].ID = uuid.New()
+		widgets[i].AdminUserID = adminUserID
+		widgets[i].CreatedAt = now
+		widgets[i].UpdatedAt = now
+	}
+	if err := db.Create(&widgets).Error; err != nil {
+		return nil, fmt.Errorf("failed to save default dashboard widgets: %w", err)
+	}
+	return widgets, nil
+}
+
+// layoutPositions maps each widget ID to its index in ids, checking that ids
+// names every widget exactly once
+func layoutPositions(widgets []models.DashboardWidget, ids []string) (map[string]int, error) {
+	if len(ids) != len(widgets) {
+		return nil, ErrInvalidLayout
+	}
+	known := make(map[string]bool, len(widgets))
+	for _, widget := range widgets {
+		known[widget.ID.String()] = true
+	}
+
+	positions := make(map[string]int, len(ids))
+	for i, id := range ids {
+		parsed, err := uuid.Parse(id)
+		if err != nil || !known[parsed.String()] {
+			return nil, ErrInvalidLayout
+		}
+		if _, seen := positions[parsed.String()]; seen {
+			return nil, ErrInvalidLayout
+		}
+		positions[parsed.String()] = i
+	}
+	return positions, nil
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+)
+
+func TestLayoutPositions(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+	widgets := []models.DashboardWidget{{ID: a}, {ID: b}, {ID: c}}
+
+	positions, err := layoutPositions(widgets, []string{c.String(), a.String(), b.String()})
+	if err != nil {
+		t.Fatalf("layoutPositions() error = %v", err)
+	}
+	if positions[c.String()] != 0 || positions[a.String()] != 1 || positions[b.String()] != 2 {
+		t.Errorf("layoutPositions() = %v; want c, a, b", positions)
+	}
+
+	invalid := map[string][]string{
+		"missing widget":   {a.String(), b.String()},
+		"duplicate widget": {a.String(), a.String(), b.String()},
+		"unknown widget":   {a.String(), b.String(), uuid.New().String()},
+		"malformed id":     {a.String(), b.String(), "not-a-uuid"},
+	}
+	for name, ids := range invalid {
+		if _, err := layoutPositions(widgets, ids); !errors.Is(err, ErrInvalidLayout) {
+			t.Errorf("%s: layoutPositions() error = %v; want ErrInvalidLayout", name, err)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// digestLog logs the end-of-day data digests
+var digestLog = logging.Component("data_digest")
+
+var (
+	// ErrDigestNotReady is returned when a date's end-of-day data cannot be
+	// finalized yet: no successful full crawl has run since the close
+	ErrDigestNotReady = errors.New("no successful full crawl since the close of that date yet")
+	// ErrNotTradingDay is returned for digests of dates without a session
+	ErrNotTradingDay = errors.New("not a trading day")
+)
+
+// DataDigestService finalizes the end-of-day dataset of each trading day
+// and tells partners with a signed data.digest webhook. A day is final once
+// digest.time has passed and a full crawl succeeded after every exchange
+// closed; its digest is stored once in the data_digests collection, so each
+// day is announced exactly once however many instances run.
+type DataDigestService struct {
+	digestCollection *mongo.Collection
+	priceCollection  *mongo.Collection
+	stockCollection  *mongo.Collection
+	runCollection    *mongo.Collection
+	webhooks         *WebhookService
+	publicURL        string
+}
+
+// NewDataDigestService creates a DataDigestService. Download URLs in
+// digests are absolute when PUBLIC_API_URL is set.
+func NewDataDigestService(webhooks *WebhookService) *DataDigestService {
+	return &DataDigestService{
+		digestCollection: config.GetCollection("data_digests"),
+		priceCollection:  config.GetCollection("stock_prices"),
+		stockCollection:  config.GetCollection("stocks"),
+		runCollection:    config.GetCollection("crawl_runs"),
+		webhooks:         webhooks,
+		publicURL:        strings.TrimRight(os.Getenv("PUBLIC_API_URL"), "/"),
+	}
+}
+
+// StartScheduler finalizes each day at digest.time (market time). Days
+// whose crawl finishes later are finalized by FinalizeRun.
+func (s *DataDigestService) StartScheduler(ctx context.Context) {
+	go func() {
+		for {
+			next := nextBackupAt(time.Now(), config.Runtime().DigestTime)
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+				s.FinalizeToday(ctx)
+			}
+		}
+	}()
+}
+
+// FinalizeRun finalizes today once a full crawl succeeds after digest.time.
+// It is registered as a crawl run listener.
+func (s *DataDigestService) FinalizeRun(run *models.CrawlRun, newDates map[string]string) {
+	if run.Status != models.CrawlRunStatusSuccess || run.Partial() || DryRun() {
+		return
+	}
+	now := markettime.Now()
+	if now.Format("15:04") < config.Runtime().DigestTime {
+		return
+	}
+	s.FinalizeToday(context.Background())
+}
+
+// FinalizeToday finalizes today's data if it is a trading day and not yet
+// final; outcomes are logged
+func (s *DataDigestService) FinalizeToday(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	date := markettime.Today()
+	digest, created, err := s.Finalize(ctx, date, false)
+	switch {
+	case errors.Is(err, ErrNotTradingDay):
+	case errors.Is(err, ErrDigestNotReady):
+		digestLog.Info("Data digest deferred", "date", date, "reason", err.Error())
+	case err != nil:
+		digestLog.Warn("Failed to finalize the data digest", "date", date, logging.FieldError, err)
+	case created:
+		digestLog.Info("Data digest published", "date", date, "symbols", digest.Symbols, "checksum", digest.Checksum)
+	}
+}
+
+// Finalize builds the digest of date from its stored candles and publishes
+// it. A date already finalized returns its stored digest unless republish is
+// set, in which case the digest is rebuilt, replaced and sent again. created
+// reports whether a webhook was queued.
+func (s *DataDigestService) Finalize(ctx context.Context, date string, republish bool) (*models.DataDigest, bool, error) {
+	if !republish {
+		existing, err := s.Get(ctx, date)
+		if err != nil || existing != nil {
+			return existing, false, err
+		}
+	}
+
+	run, err := s.finalRun(ctx, date)
+	if err != nil {
+		return nil, false, err
+	}
+	candles, err := s.Candles(ctx, date)
+	if err != nil {
+		return nil, false, err
+	}
+
+	digest := models.NewDataDigest(date, candles)
+	digest.DownloadURL = s.publicURL + "/api/market/eod/" + date
+	digest.CrawlRunID = run.ID
+	digest.FinalizedAt = primitive.NewDateTimeFromTime(time.Now())
+	if republish {
+		opts := options.Replace().SetUpsert(true)
+		if _, err := s.digestCollection.ReplaceOne(ctx, bson.M{"_id": date}, digest, opts); err != nil {
+			return nil, false, fmt.Errorf("failed to save data digest: %w", err)
+		}
+	} else if _, err := s.digestCollection.InsertOne(ctx, digest); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			// Another instance finalized the date first and sent the webhook
+			stored, err := s.Get(ctx, date)
+			if err != nil || stored == nil {
+				return digest, false, err
+			}
+			return stored, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to save data digest: %w", err)
+	}
+
+	err = s.webhooks.Publish(ctx, models.WebhookEventDataDigest, map[string]interface{}{
+		"date":         digest.Date,
+		"symbols":      digest.Symbols,
+		"exchanges":    digest.Exchanges,
+		"volume":       digest.Volume,
+		"checksum":     digest.Checksum,
+		"download_url": digest.DownloadURL,
+		"crawl_run_id": digest.CrawlRunID.Hex(),
+		"finalized_at": digest.FinalizedAt.Time().UTC(),
+	})
+	if err != nil {
+		return digest, false, fmt.Errorf("failed to publish data digest: %w", err)
+	}
+	return digest, true, nil
+}
+
+// Get returns the stored digest of date, or nil if it is not final yet
+func (s *DataDigestService) Get(ctx context.Context, date string) (*models.DataDigest, error) {
+	var digest models.DataDigest
+	err := s.digestCollection.FindOne(ctx, bson.M{"_id": date}).Decode(&digest)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read data digest: %w", err)
+	}
+	return &digest, nil
+}
+
+// finalRun returns the newest successful full crawl that started after every
+// exchange closed on date
+func (s *DataDigestService) finalRun(ctx context.Context, date string) (*models.CrawlRun, error) {
+	closed, err := marketClose(date)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{
+		"status":    models.CrawlRunStatusSuccess,
+		"kind":      bson.M{"$nin": models.PartialCrawlRunKinds},
+		"startedAt": bson.M{"$gte": primitive.NewDateTimeFromTime(closed)},
+	}
+	var run models.CrawlRun
+	opts := options.FindOne().SetSort(bson.D{{Key: "finishedAt", Value: -1}})
+	err = s.runCollection.FindOne(ctx, filter, opts).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrDigestNotReady
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch crawl runs: %w", err)
+	}
+	return &run, nil
+}
+
+// marketClose returns when the last exchange trading on date closes, or
+// ErrNotTradingDay when none trades
+func marketClose(date string) (time.Time, error) {
+	day, err := markettime.ParseDate(date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date in YYYY-MM-DD format")
+	}
+	var closed time.Time
+	for _, exchange := range models.Exchanges() {
+		if !exchange.IsTradingDay(day) {
+			continue
+		}
+		if closes := exchange.ClosesAt(day); closes.After(closed) {
+			closed = closes
+		}
+	}
+	if closed.IsZero() {
+		return time.Time{}, ErrNotTradingDay
+	}
+	return closed, nil
+}
+
+// Candles returns every symbol's candle of date with its exchange, sorted
+// by code: the dataset a digest describes
+func (s *DataDigestService) Candles(ctx context.Context, date string) ([]models.DigestCandle, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, fmt.Errorf("expected a date in YYYY-MM-DD format")
+	}
+
+	cursor, err := s.priceCollection.Find(ctx, bson.M{"year": day.Year()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price buckets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	byCode := make(map[string]models.CandleData)
+	for cursor.Next(ctx) {
+		var bucket models.PriceBucket
+		if err := cursor.Decode(&bucket); err != nil {
+			return nil, fmt.Errorf("failed to decode price bucket: %w", err)
+		}
+		if _, ok := models.IndexExchange(bucket.Code); ok {
+			continue // Market index levels are stored with the candles but are not symbols
+		}
+		for _, candle := range bucket.History {
+			if candle.D == date {
+				byCode[bucket.Code] = candle
+				break
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read price buckets: %w", err)
+	}
+
+	exchanges, err := s.stockExchanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	candles := make([]models.DigestCandle, 0, len(byCode))
+	for code, candle := range byCode {
+		candles = append(candles, models.DigestCandle{Code: code, Exchange: exchanges[code], CandleData: candle})
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].Code < candles[j].Code })
+	return candles, nil
+}
+
+// stockExchanges returns the exchange of every stock, keyed by code
+func (s *DataDigestService) stockExchanges(ctx context.Context) (map[string]string, error) {
+	opts := options.Find().SetProjection(bson.M{"code": 1, "exchange": 1})
+	cursor, err := s.stockCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stocks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stocks []models.Stock
+	if err := cursor.All(ctx, &stocks); err != nil {
+		return nil, fmt.Errorf("failed to decode stocks: %w", err)
+	}
+	exchanges := make(map[string]string, len(stocks))
+	for _, stock := range stocks {
+		exchanges[stock.Code] = stock.Exchange
+	}
+	return exchanges, nil
+}
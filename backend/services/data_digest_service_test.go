@@ -0,0 +1,25 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMarketClose(t *testing.T) {
+	// HNX and UPCOM close after HOSE, at 15:00 ICT
+	closed, err := marketClose("2026-02-10")
+	if err != nil {
+		t.Fatalf("marketClose() error = %v", err)
+	}
+	if got := closed.UTC().Format(time.RFC3339); got != "2026-02-10T08:00:00Z" {
+		t.Errorf("marketClose() = %s; want 2026-02-10T08:00:00Z", got)
+	}
+
+	if _, err := marketClose("2026-02-14"); !errors.Is(err, ErrNotTradingDay) {
+		t.Errorf("Saturday: error = %v; want ErrNotTradingDay", err)
+	}
+	if _, err := marketClose("10/02/2026"); err == nil {
+		t.Error("expected an error for a malformed date")
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+// Outcomes of one part of a composite request
+const (
+	BudgetPartOK      = "ok"
+	BudgetPartTimeout = "timeout" // Did not finish within its share of the deadline
+	BudgetPartFailed  = "error"
+)
+
+// BudgetPart reports how one sub-fetch of a composite request went
+type BudgetPart struct {
+	Name      string `json:"name"`
+	Required  bool   `json:"required"`
+	Status    string `json:"status"`
+	BudgetMS  int64  `json:"budget_ms"`  // Time the part was allowed
+	ElapsedMS int64  `json:"elapsed_ms"` // Time it took, or waited before giving up
+	Error     string `json:"error,omitempty"`
+}
+
+// BudgetReport summarizes the parts awaited by DeadlineBudget.Wait
+type BudgetReport struct {
+	Parts    []BudgetPart `json:"parts"`
+	Warnings []string     `json:"warnings,omitempty"` // One per optional part that timed out or failed
+	Err      error        `json:"-"`                  // First required part that timed out or failed
+}
+
+// Partial reports whether any optional part is missing from the response
+func (r BudgetReport) Partial() bool {
+	return len(r.Warnings) > 0
+}
+
+// TimedOut reports whether the required part that failed ran out of time
+func (r BudgetReport) TimedOut() bool {
+	return errors.Is(r.Err, context.DeadlineExceeded)
+}
+
+// budgetPart tracks one running sub-fetch
+type budgetPart struct {
+	BudgetPart
+	started time.Time
+	budget  time.Duration
+	done    chan struct{} // Closed when the fetch returns
+	err     error         // Set before done is closed
+}
+
+// DeadlineBudget shares one deadline among the sub-fetches of a composite
+// endpoint (overview, stock detail). Each part runs concurrently with its
+// share of the total time; a part that overruns is abandoned and reported
+// instead of holding up the whole response, so slow optional sources
+// degrade the response rather than fail it.
+type DeadlineBudget struct {
+	name     string
+	ctx      context.Context
+	total    time.Duration
+	deadline time.Time
+
+	mu      sync.Mutex
+	pending []*budgetPart
+	parts   []BudgetPart
+}
+
+// NewDeadlineBudget creates a budget of total for the named endpoint, never
+// extending past the deadline of ctx
+func NewDeadlineBudget(ctx context.Context, name string, total time.Duration) *DeadlineBudget {
+	deadline := time.Now().Add(total)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	return &DeadlineBudget{name: name, ctx: ctx, total: total, deadline: deadline}
+}
+
+// Remaining returns the time left before the budget's deadline
+func (b *DeadlineBudget) Remaining() time.Duration {
+	if remaining := time.Until(b.deadline); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// BudgetFetch is the result of one sub-fetch started with Fetch
+type BudgetFetch[T any] struct {
+	part  *budgetPart
+	value T
+}
+
+// Value returns the fetched value once the part has finished successfully
+// within its budget; otherwise it reports false
+func (f *BudgetFetch[T]) Value() (T, bool) {
+	var zero T
+	select {
+	case <-f.part.done:
+		if f.part.err == nil && f.part.Status == BudgetPartOK {
+			return f.value, true
+		}
+	default:
+	}
+	return zero, false
+}
+
+// Fetch starts fetch in the background with share (0-1] of the total budget,
+// capped by the time remaining. Required parts fail the request when they do
+// not finish; optional ones become warnings. Call Wait before reading the
+// result.
+func Fetch[T any](b *DeadlineBudget, name string, share float64, required bool, fetch func(ctx context.Context) (T, error)) *BudgetFetch[T] {
+	budget := time.Duration(float64(b.total) * share)
+	if remaining := b.Remaining(); budget <= 0 || budget > remaining {
+		budget = remaining
+	}
+
+	part := &budgetPart{
+		BudgetPart: BudgetPart{Name: name, Required: required, BudgetMS: budget.Milliseconds()},
+		started:    time.Now(),
+		budget:     budget,
+		done:       make(chan struct{}),
+	}
+	result := &BudgetFetch[T]{part: part}
+
+	ctx, cancel := context.WithTimeout(b.ctx, budget)
+	go func() {
+		defer cancel()
+		defer close(part.done)
+		value, err := fetch(ctx)
+		if err == nil && ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		result.value, part.err = value, err
+	}()
+
+	b.mu.Lock()
+	b.pending = append(b.pending, part)
+	b.mu.Unlock()
+	return result
+}
+
+// Wait blocks until every part started since the last Wait has finished or
+// used up its budget, and reports on all parts so far. Parts still running
+// are abandoned: their results are never read.
+func (b *DeadlineBudget) Wait() BudgetReport {
+	b.mu.Lock()
+	pending := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	for _, part := range pending {
+		timer := time.NewTimer(time.Until(part.started.Add(part.budget)))
+		select {
+		case <-part.done:
+			switch {
+			case part.err == nil:
+				part.Status = BudgetPartOK
+			case errors.Is(part.err, context.DeadlineExceeded):
+				part.Status = BudgetPartTimeout
+			default:
+				part.Status = BudgetPartFailed
+			}
+		case <-timer.C:
+			part.Status = BudgetPartTimeout
+		}
+		timer.Stop()
+		part.ElapsedMS = time.Since(part.started).Milliseconds()
+		if part.Status == BudgetPartTimeout {
+			part.Error = fmt.Sprintf("timed out after %dms", part.budget.Milliseconds())
+			logging.FromContext(b.ctx).Warn("Budget part timed out", "budget", b.name, "part", part.Name, "after", part.budget.String())
+		} else if part.Status == BudgetPartFailed {
+			part.Error = part.err.Error()
+			logging.FromContext(b.ctx).Warn("Budget part failed", "budget", b.name, "part", part.Name, logging.FieldError, part.err)
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, part := range pending {
+		b.parts = append(b.parts, part.BudgetPart)
+	}
+
+	report := BudgetReport{Parts: append([]BudgetPart(nil), b.parts...)}
+	for _, part := range report.Parts {
+		if part.Status == BudgetPartOK {
+			continue
+		}
+		if part.Required {
+			if report.Err == nil {
+				cause := errors.New(part.Error)
+				if part.Status == BudgetPartTimeout {
+					cause = context.DeadlineExceeded
+				}
+				report.Err = fmt.Errorf("%s: %w", part.Name, cause)
+			}
+			continue
+		}
+		report.Warnings = append(report.Warnings, part.Name+" unavailable: "+part.Error)
+	}
+	return report
+}
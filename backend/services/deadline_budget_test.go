@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeadlineBudgetPartialResponse(t *testing.T) {
+	budget := NewDeadlineBudget(context.Background(), "test", 200*time.Millisecond)
+
+	fast := Fetch(budget, "fast", 1, true, func(ctx context.Context) (string, error) {
+		return "ok", nil
+	})
+	slow := Fetch(budget, "slow", 0.25, false, func(ctx context.Context) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	stuck := Fetch(budget, "stuck", 0.25, false, func(ctx context.Context) (string, error) {
+		time.Sleep(time.Second) // Ignores its context
+		return "late", nil
+	})
+	broken := Fetch(budget, "broken", 1, false, func(ctx context.Context) (string, error) {
+		return "", errors.New("connection refused")
+	})
+
+	started := time.Now()
+	report := budget.Wait()
+	if elapsed := time.Since(started); elapsed > 150*time.Millisecond {
+		t.Errorf("Wait() took %s; want it to give up on slow parts after their share", elapsed)
+	}
+
+	if report.Err != nil {
+		t.Fatalf("Wait() error = %v; want only optional parts to fail", report.Err)
+	}
+	if !report.Partial() || len(report.Warnings) != 3 {
+		t.Errorf("Wait() warnings = %v; want one per missing optional part", report.Warnings)
+	}
+	want := map[string]string{"fast": BudgetPartOK, "slow": BudgetPartTimeout, "stuck": BudgetPartTimeout, "broken": BudgetPartFailed}
+	for _, part := range report.Parts {
+		if part.Status != want[part.Name] {
+			t.Errorf("part %s status = %s; want %s", part.Name, part.Status, want[part.Name])
+		}
+	}
+
+	if value, ok := fast.Value(); !ok || value != "ok" {
+		t.Errorf("fast.Value() = %q, %v; want ok, true", value, ok)
+	}
+	for name, fetch := range map[string]*BudgetFetch[string]{"slow": slow, "stuck": stuck, "broken": broken} {
+		if _, ok := fetch.Value(); ok {
+			t.Errorf("%s.Value() ok; want no value from a part that did not finish in time", name)
+		}
+	}
+}
+
+func TestDeadlineBudgetRequiredTimeout(t *testing.T) {
+	budget := NewDeadlineBudget(context.Background(), "test", 50*time.Millisecond)
+	Fetch(budget, "symbol", 1, true, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	report := budget.Wait()
+	if report.Err == nil || !report.TimedOut() {
+		t.Errorf("Wait() error = %v; want a timeout of the required part", report.Err)
+	}
+}
+
+func TestDeadlineBudgetSharesRemainingTime(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	budget := NewDeadlineBudget(ctx, "test", time.Hour)
+	if remaining := budget.Remaining(); remaining > 100*time.Millisecond {
+		t.Errorf("Remaining() = %s; want at most the context deadline", remaining)
+	}
+
+	Fetch(budget, "first", 1, false, func(ctx context.Context) (int, error) { return 1, nil })
+	report := budget.Wait()
+	if got := report.Parts[0].BudgetMS; got > 100 {
+		t.Errorf("part budget = %dms; want it capped by the remaining time", got)
+	}
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// derivativesLog logs the futures crawl
+var derivativesLog = logging.Component("derivatives")
+
+// futuresRefreshSessions is how many candles are re-fetched for a contract
+// whose history is already stored; the overlap picks up late open interest
+const futuresRefreshSessions = 10
+
+// DerivativesService maintains the futures_contracts and futures_prices
+// collections: the listed futures of the underlyings in crawler.derivatives
+// and their daily candles with open interest
+type DerivativesService struct {
+	contractsCollection *mongo.Collection
+	pricesCollection    *mongo.Collection
+	readCache           *cache.Cache // Shared with the stock service
+}
+
+// NewDerivativesService creates a new DerivativesService instance
+func NewDerivativesService(stockService *StockService) *DerivativesService {
+	return &DerivativesService{
+		contractsCollection: config.GetCollection("futures_contracts"),
+		pricesCollection:    config.GetCollection("futures_prices"),
+		readCache:           stockService.readCache,
+	}
+}
+
+// CrawlRun crawls the futures after a full crawl run. It is registered as a
+// crawl run listener.
+func (s *DerivativesService) CrawlRun(run *models.CrawlRun, newDates map[string]string) {
+	underlyings := config.Runtime().CrawlerDerivatives
+	if len(underlyings) == 0 || run.Partial() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	crawled, err := s.Crawl(ctx, underlyings)
+	s.readCache.Invalidate(ctx, cache.NamespacePrices)
+	if err != nil {
+		derivativesLog.Warn("Failed to crawl futures", "run_id", run.ID.Hex(), logging.FieldError, err)
+		return
+	}
+	derivativesLog.Info("Futures crawled", "contracts", crawled)
+}
+
+// Crawl stores the listed contracts on underlyings and merges their newest
+// candles into the stored history. It returns how many contracts were
+// crawled; a contract whose prices fail is logged and skipped.
+func (s *DerivativesService) Crawl(ctx context.Context, underlyings []string) (int, error) {
+	source, err := MarketDataSourceFor(models.DerivativesExchange)
+	if err != nil {
+		return 0, err
+	}
+	futures, ok := source.(FuturesSource)
+	if !ok {
+		return 0, fmt.Errorf("%s does not publish futures", source.Name())
+	}
+
+	now := time.Now()
+	today := markettime.Date(now)
+	crawled := 0
+	for _, underlying := range underlyings {
+		listed, err := futures.FetchFuturesContracts(underlying)
+		if err != nil {
+			return crawled, fmt.Errorf("failed to list %s futures: %w", underlying, err)
+		}
+		contracts := normalizeFuturesContracts(listed, underlying, primitive.NewDateTimeFromTime(now))
+		if err := s.saveContracts(ctx, contracts); err != nil {
+			return crawled, err
+		}
+
+		for _, contract := range contracts {
+			if contract.FirstTradingDate > today {
+				continue
+			}
+			if err := s.crawlPrices(ctx, futures, contract); err != nil {
+				derivativesLog.Warn("Failed to crawl contract prices", logging.FieldSymbol, contract.Code, logging.FieldError, err)
+				continue
+			}
+			crawled++
+		}
+	}
+	return crawled, nil
+}
+
+// normalizeFuturesContracts fills in the underlying, contract month and, when
+// the provider leaves it out, the usual last trading date of contracts,
+// dropping those whose code is not a futures code
+func normalizeFuturesContracts(listed []models.FuturesContract, underlying string, updatedAt primitive.DateTime) []models.FuturesContract {
+	contracts := make([]models.FuturesContract, 0, len(listed))
+	for _, contract := range listed {
+		codeUnderlying, month, ok := models.ParseFuturesCode(contract.Code)
+		if !ok {
+			continue
+		}
+		if contract.Underlying == "" {
+			contract.Underlying = codeUnderlying
+		}
+		if !strings.EqualFold(contract.Underlying, underlying) {
+			continue
+		}
+		contract.Underlying = strings.ToUpper(contract.Underlying)
+		contract.ContractMonth = month
+		if contract.LastTradingDate == "" {
+			contract.LastTradingDate, _ = models.ExpiryDate(month)
+		}
+		contract.UpdatedAt = updatedAt
+		contracts = append(contracts, contract)
+	}
+	return contracts
+}
+
+// saveContracts upserts contracts by code
+func (s *DerivativesService) saveContracts(ctx context.Context, contracts []models.FuturesContract) error {
+	if len(contracts) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, 0, len(contracts))
+	for _, contract := range contracts {
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": contract.Code}).
+			SetReplacement(contract).
+			SetUpsert(true))
+	}
+	if _, err := s.contractsCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to save futures contracts: %w", err)
+	}
+	return nil
+}
+
+// crawlPrices fetches the full history of a contract the first time, its
+// newest candles afterwards, and merges them into the stored history
+func (s *DerivativesService) crawlPrices(ctx context.Context, futures FuturesSource, contract models.FuturesContract) error {
+	var stored models.FuturesPrices
+	err := s.pricesCollection.FindOne(ctx, bson.M{"_id": contract.Code}).Decode(&stored)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to fetch stored prices: %w", err)
+	}
+	sessions := vndirectHistorySessions
+	if len(stored.History) > 0 {
+		sessions = futuresRefreshSessions
+	}
+
+	fetched, err := futures.FetchFuturesPrices(contract.Code, sessions)
+	if err != nil {
+		return err
+	}
+	prices := models.FuturesPrices{
+		Code:       contract.Code,
+		Underlying: contract.Underlying,
+		History:    models.MergeFuturesCandles(stored.History, fetched),
+	}
+	_, err = s.pricesCollection.ReplaceOne(ctx, bson.M{"_id": contract.Code}, prices, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save prices: %w", err)
+	}
+	return nil
+}
+
+// FuturesContractsQuery filters the listed contracts
+type FuturesContractsQuery struct {
+	Underlying string
+	Status     string // models.FuturesStatusActive or FuturesStatusExpired; "" for both
+}
+
+// ParseFuturesContractsQuery reads a FuturesContractsQuery from request
+// query parameters
+func ParseFuturesContractsQuery(query func(string) string) (FuturesContractsQuery, error) {
+	q := FuturesContractsQuery{
+		Underlying: strings.ToUpper(strings.TrimSpace(query("underlying"))),
+		Status:     strings.ToLower(strings.TrimSpace(query("status"))),
+	}
+	if q.Underlying == "" {
+		q.Underlying = models.DefaultFuturesUnderlying
+	}
+	if q.Status != "" && !models.ValidFuturesStatus(q.Status) {
+		return q, fmt.Errorf("status: expected %s or %s", models.FuturesStatusActive, models.FuturesStatusExpired)
+	}
+	return q, nil
+}
+
+// Contracts returns the stored contracts on q.Underlying with their status
+// today, nearest expiry first. Results are cached in the prices namespace.
+func (s *DerivativesService) Contracts(ctx context.Context, q FuturesContractsQuery) ([]models.FuturesContract, error) {
+	stored, err := s.contracts(ctx, q.Underlying)
+	if err != nil {
+		return nil, err
+	}
+	today := markettime.Today()
+	contracts := make([]models.FuturesContract, 0, len(stored))
+	for _, contract := range stored {
+		contract.Status = contract.StatusOn(today)
+		if q.Status == "" || contract.Status == q.Status {
+			contracts = append(contracts, contract)
+		}
+	}
+	return contracts, nil
+}
+
+// contracts returns the stored contracts on underlying by last trading date
+func (s *DerivativesService) contracts(ctx context.Context, underlying string) ([]models.FuturesContract, error) {
+	return cache.Fetch(ctx, s.readCache, cache.NamespacePrices, "futures-contracts:"+underlying, func(ctx context.Context) ([]models.FuturesContract, error) {
+		opts := options.Find().SetSort(bson.D{{Key: "lastTradingDate", Value: 1}})
+		cursor, err := s.contractsCollection.Find(ctx, bson.M{"underlying": underlying}, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch futures contracts: %w", err)
+		}
+		contracts := []models.FuturesContract{}
+		if err := cursor.All(ctx, &contracts); err != nil {
+			return nil, fmt.Errorf("failed to decode futures contracts: %w", err)
+		}
+		return contracts, nil
+	})
+}
+
+// FuturesCandlesQuery selects the candles of a contract
+type FuturesCandlesQuery struct {
+	Code string // Contract code, or a rolling code such as VN30F1M
+	From string // YYYY-MM-DD; "" for the first candle
+	To   string // YYYY-MM-DD; "" for the last candle
+}
+
+// ParseFuturesCandlesQuery reads a FuturesCandlesQuery from the contract
+// code of the path and request query parameters
+func ParseFuturesCandlesQuery(code string, query func(string) string) (FuturesCandlesQuery, error) {
+	q := FuturesCandlesQuery{
+		Code: strings.ToUpper(strings.TrimSpace(code)),
+		From: strings.TrimSpace(query("from")),
+		To:   strings.TrimSpace(query("to")),
+	}
+	_, _, isContract := models.ParseFuturesCode(q.Code)
+	_, _, isAlias := models.ParseFuturesAlias(q.Code)
+	if !isContract && !isAlias {
+		return q, fmt.Errorf("code: expected a futures code such as VN30F2611 or VN30F1M")
+	}
+	for param, value := range map[string]string{"from": q.From, "to": q.To} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return q, fmt.Errorf("%s: expected YYYY-MM-DD", param)
+		}
+	}
+	if q.From != "" && q.To != "" && q.From > q.To {
+		return q, fmt.Errorf("from must not be after to")
+	}
+	return q, nil
+}
+
+// FuturesCandles is a contract with its candles in the requested range
+type FuturesCandles struct {
+	Contract models.FuturesContract `json:"contract"`
+	Candles  []models.FuturesCandle `json:"candles"` // Oldest first
+}
+
+// Candles returns the contract of q.Code, a rolling code resolving to the
+// contract it stands for today, with its candles between q.From and q.To,
+// or nil when the contract is unknown
+func (s *DerivativesService) Candles(ctx context.Context, q FuturesCandlesQuery) (*FuturesCandles, error) {
+	underlying, _, ok := models.ParseFuturesCode(q.Code)
+	if !ok {
+		underlying, _, _ = models.ParseFuturesAlias(q.Code)
+	}
+	contracts, err := s.contracts(ctx, underlying)
+	if err != nil {
+		return nil, err
+	}
+	today := markettime.Today()
+	contract, found := futuresContractFor(contracts, q.Code, today)
+	if !found {
+		return nil, nil
+	}
+	contract.Status = contract.StatusOn(today)
+
+	prices, err := cache.Fetch(ctx, s.readCache, cache.NamespacePrices, "futures-prices:"+contract.Code, func(ctx context.Context) (*models.FuturesPrices, error) {
+		var prices models.FuturesPrices
+		err := s.pricesCollection.FindOne(ctx, bson.M{"_id": contract.Code}).Decode(&prices)
+		if err == mongo.ErrNoDocuments {
+			return &prices, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch futures prices: %w", err)
+		}
+		return &prices, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candles := []models.FuturesCandle{}
+	for _, candle := range prices.History {
+		if (q.From == "" || candle.D >= q.From) && (q.To == "" || candle.D <= q.To) {
+			candles = append(candles, candle)
+		}
+	}
+	return &FuturesCandles{Contract: contract, Candles: candles}, nil
+}
+
+// futuresContractFor returns the contract of code among contracts, a rolling
+// code standing for the nearest contracts trading on date
+func futuresContractFor(contracts []models.FuturesContract, code, date string) (models.FuturesContract, bool) {
+	if _, position, ok := models.ParseFuturesAlias(code); ok {
+		return models.NearestContract(contracts, date, position)
+	}
+	for _, contract := range contracts {
+		if contract.Code == code {
+			return contract, true
+		}
+	}
+	return models.FuturesContract{}, false
+}
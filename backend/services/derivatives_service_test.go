@@ -0,0 +1,80 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestNormalizeFuturesContracts(t *testing.T) {
+	listed := []models.FuturesContract{
+		{Code: "VN30F2611", LastTradingDate: "2026-11-19"},
+		{Code: "VN30F2612"},
+		{Code: "VN100F2611", Underlying: "VN100"},
+		{Code: "GB05F2612", Underlying: "GB05"},
+		{Code: "VN30"},
+	}
+	contracts := normalizeFuturesContracts(listed, "VN30", primitive.NewDateTimeFromTime(time.Now()))
+	if len(contracts) != 2 {
+		t.Fatalf("normalizeFuturesContracts() = %+v; want the 2 VN30 contracts", contracts)
+	}
+	if contracts[0].Underlying != "VN30" || contracts[0].ContractMonth != "2026-11" || contracts[0].LastTradingDate != "2026-11-19" {
+		t.Errorf("contracts[0] = %+v; want VN30, 2026-11 and the published last trading date", contracts[0])
+	}
+	if contracts[1].LastTradingDate != "2026-12-17" {
+		t.Errorf("contracts[1].LastTradingDate = %q; want the third Thursday 2026-12-17", contracts[1].LastTradingDate)
+	}
+}
+
+func TestParseFuturesContractsQuery(t *testing.T) {
+	q, err := ParseFuturesContractsQuery(url.Values{}.Get)
+	if err != nil || q.Underlying != models.DefaultFuturesUnderlying || q.Status != "" {
+		t.Errorf("ParseFuturesContractsQuery() = %+v, %v; want VN30 in any status", q, err)
+	}
+	q, err = ParseFuturesContractsQuery(url.Values{"underlying": {"vn100"}, "status": {"Active"}}.Get)
+	if err != nil || q.Underlying != "VN100" || q.Status != models.FuturesStatusActive {
+		t.Errorf("ParseFuturesContractsQuery(vn100, Active) = %+v, %v; want VN100, active", q, err)
+	}
+	if _, err := ParseFuturesContractsQuery(url.Values{"status": {"listed"}}.Get); err == nil {
+		t.Error("ParseFuturesContractsQuery(status=listed) succeeded; want an error")
+	}
+}
+
+func TestParseFuturesCandlesQuery(t *testing.T) {
+	q, err := ParseFuturesCandlesQuery("vn30f1m", url.Values{"from": {"2026-10-01"}}.Get)
+	if err != nil || q.Code != "VN30F1M" || q.From != "2026-10-01" || q.To != "" {
+		t.Errorf("ParseFuturesCandlesQuery(vn30f1m) = %+v, %v; want VN30F1M from 2026-10-01", q, err)
+	}
+	tests := []struct {
+		code  string
+		query url.Values
+	}{
+		{"HPG", nil},
+		{"VN30F2611", url.Values{"from": {"01/10/2026"}}},
+		{"VN30F2611", url.Values{"from": {"2026-10-02"}, "to": {"2026-10-01"}}},
+	}
+	for _, tt := range tests {
+		if _, err := ParseFuturesCandlesQuery(tt.code, tt.query.Get); err == nil {
+			t.Errorf("ParseFuturesCandlesQuery(%s, %v) succeeded; want an error", tt.code, tt.query)
+		}
+	}
+}
+
+func TestFuturesContractFor(t *testing.T) {
+	contracts := []models.FuturesContract{
+		{Code: "VN30F2610", LastTradingDate: "2026-10-15"},
+		{Code: "VN30F2611", LastTradingDate: "2026-11-19"},
+	}
+	if contract, ok := futuresContractFor(contracts, "VN30F1M", "2026-10-16"); !ok || contract.Code != "VN30F2611" {
+		t.Errorf("futuresContractFor(VN30F1M) after expiry = %+v; want VN30F2611", contract)
+	}
+	if contract, ok := futuresContractFor(contracts, "VN30F2610", "2026-10-16"); !ok || contract.Code != "VN30F2610" {
+		t.Errorf("futuresContractFor(VN30F2610) = %+v; want the expired contract", contract)
+	}
+	if _, ok := futuresContractFor(contracts, "VN30F2703", "2026-10-16"); ok {
+		t.Error("futuresContractFor(VN30F2703) succeeded; want an unknown contract")
+	}
+}
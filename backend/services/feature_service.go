@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"gorm.io/gorm"
+)
+
+// featureTierTTL is how long a member's membership tier is cached for flag
+// checks, so an upgrade turns tier-targeted features on within a minute
+const featureTierTTL = time.Minute
+
+// maxFeatureTiers is the cache size above which expired tiers are pruned
+const maxFeatureTiers = 10000
+
+// featureTier is a cached membership tier
+type featureTier struct {
+	tier      string
+	fetchedAt time.Time
+}
+
+// FeatureService answers feature flag checks. Flags are feature.* settings
+// held in the runtime configuration (stored in app_settings and reloaded with
+// it); the membership tier of members is looked up in profiles and cached
+// in memory for featureTierTTL.
+type FeatureService struct {
+	mu    sync.Mutex
+	tiers map[string]featureTier
+	now   func() time.Time
+}
+
+// NewFeatureService creates a new FeatureService instance
+func NewFeatureService() *FeatureService {
+	return &FeatureService{
+		tiers: make(map[string]featureTier),
+		now:   time.Now,
+	}
+}
+
+// Enabled reports whether the feature is on for profileID, or for a caller
+// without a membership tier when profileID is empty
+func (s *FeatureService) Enabled(ctx context.Context, name, profileID string) (bool, error) {
+	tier, err := s.Tier(ctx, profileID)
+	if err != nil {
+		return false, err
+	}
+	return config.Runtime().FeatureEnabledFor(name, tier), nil
+}
+
+// EnabledFeatures returns the features on for profileID and its membership
+// tier
+func (s *FeatureService) EnabledFeatures(ctx context.Context, profileID string) ([]string, string, error) {
+	tier, err := s.Tier(ctx, profileID)
+	if err != nil {
+		return nil, "", err
+	}
+	return config.Runtime().EnabledFeatures(tier), tier, nil
+}
+
+// Tier returns the membership tier in effect for profileID ("" when empty).
+// Unknown profiles are free.
+func (s *FeatureService) Tier(ctx context.Context, profileID string) (string, error) {
+	if profileID == "" {
+		return "", nil
+	}
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.tiers[profileID]
+	s.mu.Unlock()
+	if ok && now.Sub(cached.fetchedAt) < featureTierTTL {
+		return cached.tier, nil
+	}
+
+	var profile models.Profile
+	err := config.GetDBWithContext(ctx).Select("membership", "membership_expires_at").First(&profile, "id = ?", profileID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("failed to look up membership: %w", err)
+	}
+	tier := profile.Tier(now)
+
+	s.mu.Lock()
+	if len(s.tiers) >= maxFeatureTiers {
+		for id, cached := range s.tiers {
+			if now.Sub(cached.fetchedAt) >= featureTierTTL {
+				delete(s.tiers, id)
+			}
+		}
+	}
+	s.tiers[profileID] = featureTier{tier: tier, fetchedAt: now}
+	s.mu.Unlock()
+	return tier, nil
+}
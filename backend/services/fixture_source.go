@@ -0,0 +1,556 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// Crawler modes, selected with CRAWLER_MODE
+const (
+	CrawlerModeLive   = "live"    // Crawl the providers (default)
+	CrawlerModeRecord = "record"  // Crawl the providers and save their responses as fixtures
+	CrawlerModeDryRun = "dry-run" // Crawl the recorded fixtures into sandbox collections
+)
+
+// SandboxCollectionPrefix prefixes every MongoDB collection in dry-run mode,
+// so neither the crawl nor the API touch the real stocks and prices
+const SandboxCollectionPrefix = "sandbox_"
+
+// DefaultFixturesDir holds the recorded fixtures shipped with the backend
+const DefaultFixturesDir = "testdata/fixtures"
+
+var crawlerDryRun atomic.Bool
+
+// DryRun reports whether the crawler runs on fixtures. Dry runs send no
+// webhook events, run summaries or member notifications.
+func DryRun() bool {
+	return crawlerDryRun.Load()
+}
+
+// UseCrawlerMode applies a CRAWLER_MODE to the registered data sources and
+// returns the mode in effect. It must be called before services open their
+// collections. In dry-run mode every source is replaced by the fixtures
+// recorded for it in dir; in record mode every source saves what it
+// returns to dir.
+func UseCrawlerMode(mode, dir string) (string, error) {
+	if dir == "" {
+		dir = DefaultFixturesDir
+	}
+	switch mode {
+	case "", CrawlerModeLive:
+		return CrawlerModeLive, nil
+	case CrawlerModeDryRun:
+		config.CollectionPrefix = SandboxCollectionPrefix
+		for _, source := range MarketDataSources() {
+			RegisterMarketDataSource(NewFixtureSource(source.Name(), dir))
+		}
+		crawlerDryRun.Store(true)
+	case CrawlerModeRecord:
+		for _, source := range MarketDataSources() {
+			RegisterMarketDataSource(NewRecordingSource(source, dir))
+		}
+	default:
+		return "", fmt.Errorf("unknown crawler mode %q (supported: %s, %s, %s)", mode, CrawlerModeLive, CrawlerModeRecord, CrawlerModeDryRun)
+	}
+	return mode, nil
+}
+
+// fixtureStock is a listed symbol as recorded in symbols.json
+type fixtureStock struct {
+	Code          string  `json:"code"`
+	CompanyName   string  `json:"companyName"`
+	CompanyNameEn string  `json:"companyNameEn,omitempty"`
+	Exchange      string  `json:"exchange"`
+	Type          string  `json:"type"` // Stocks when empty
+	Status        string  `json:"status"`
+	Underlying    string  `json:"underlying,omitempty"`
+	Issuer        string  `json:"issuer,omitempty"`
+	ExercisePrice float64 `json:"exercisePrice,omitempty"`
+	ExerciseRatio string  `json:"exerciseRatio,omitempty"`
+	MaturityDate  string  `json:"maturityDate,omitempty"`
+	CouponRate    float64 `json:"couponRate,omitempty"`
+}
+
+// newFixtureStock records stock
+func newFixtureStock(stock models.Stock) fixtureStock {
+	return fixtureStock{
+		Code:          stock.Code,
+		CompanyName:   stock.CompanyName,
+		CompanyNameEn: stock.CompanyNameEn,
+		Exchange:      stock.Exchange,
+		Type:          stock.Type,
+		Status:        stock.Status,
+		Underlying:    stock.Underlying,
+		Issuer:        stock.Issuer,
+		ExercisePrice: stock.ExercisePrice,
+		ExerciseRatio: stock.ExerciseRatio,
+		MaturityDate:  stock.MaturityDate,
+		CouponRate:    stock.CouponRate,
+	}
+}
+
+// stock returns the recorded symbol
+func (f fixtureStock) stock() models.Stock {
+	return models.Stock{
+		Code:          f.Code,
+		CompanyName:   f.CompanyName,
+		CompanyNameEn: f.CompanyNameEn,
+		Exchange:      f.Exchange,
+		Type:          f.instrumentType(),
+		Status:        f.Status,
+		Underlying:    f.Underlying,
+		Issuer:        f.Issuer,
+		ExercisePrice: f.ExercisePrice,
+		ExerciseRatio: f.ExerciseRatio,
+		MaturityDate:  f.MaturityDate,
+		CouponRate:    f.CouponRate,
+	}
+}
+
+// instrumentType returns the recorded type, stocks for fixtures recorded
+// without one
+func (f fixtureStock) instrumentType() string {
+	if f.Type == "" {
+		return models.InstrumentStock
+	}
+	return strings.ToLower(f.Type)
+}
+
+// Fixture files of a data source, relative to <dir>/<source name>
+const (
+	fixtureSymbolsFile    = "symbols.json"    // []fixtureStock
+	fixtureSectorsFile    = "sectors.json"    // Code -> sector
+	fixtureIndustriesFile = "industries.json" // Code -> models.IndustryClassification
+	fixtureSharesFile     = "shares.json"     // Code -> shares outstanding
+	fixtureFuturesFile    = "futures.json"    // []models.FuturesContract
+	fixturePricesDir      = "prices"          // <CODE>.json: []models.CandleData, oldest first (indexes too)
+)
+
+// FixtureSource serves recorded provider responses from disk in place of
+// the data source of the same name
+type FixtureSource struct {
+	name string
+	dir  string
+}
+
+// NewFixtureSource creates a data source reading the fixtures of source name
+// under dir
+func NewFixtureSource(name, dir string) *FixtureSource {
+	return &FixtureSource{name: name, dir: filepath.Join(dir, name)}
+}
+
+// Name implements MarketDataSource
+func (s *FixtureSource) Name() string {
+	return s.name
+}
+
+// FetchSymbols implements MarketDataSource with the recorded stocks of exchanges
+func (s *FixtureSource) FetchSymbols(exchanges []string) ([]models.Stock, error) {
+	return s.FetchInstruments(exchanges, []string{models.InstrumentStock})
+}
+
+// FetchInstruments implements InstrumentLister with the recorded symbols of
+// exchanges and types
+func (s *FixtureSource) FetchInstruments(exchanges, types []string) ([]models.Stock, error) {
+	var recorded []fixtureStock
+	if err := readFixture(filepath.Join(s.dir, fixtureSymbolsFile), &recorded); err != nil {
+		return nil, err
+	}
+	stocks := make([]models.Stock, 0, len(recorded))
+	for _, stock := range recorded {
+		if containsFold(exchanges, stock.Exchange) && containsFold(types, stock.instrumentType()) {
+			stocks = append(stocks, stock.stock())
+		}
+	}
+	return stocks, nil
+}
+
+// FetchPrices implements MarketDataSource with every recorded candle
+func (s *FixtureSource) FetchPrices(stock models.Stock) ([]models.CandleData, error) {
+	return s.FetchRecentPrices(stock, 0)
+}
+
+// FetchRecentPrices implements RecentPriceFetcher with the last sessions
+// recorded candles, newest first like the providers return them. A symbol
+// without fixture fails like a provider error.
+func (s *FixtureSource) FetchRecentPrices(stock models.Stock, sessions int) ([]models.CandleData, error) {
+	var candles []models.CandleData
+	if err := readFixture(s.pricesPath(stock.Code), &candles); err != nil {
+		return nil, err
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].D > candles[j].D })
+	if sessions > 0 && len(candles) > sessions {
+		candles = candles[:sessions]
+	}
+	return candles, nil
+}
+
+// FetchIndexPrices implements IndexPriceFetcher with the last sessions
+// recorded levels of an index, stored like a symbol's candles
+func (s *FixtureSource) FetchIndexPrices(code string, sessions int) ([]models.CandleData, error) {
+	return s.FetchRecentPrices(models.Stock{Code: code}, sessions)
+}
+
+// FetchSectors implements SectorClassifier with the recorded sectors
+func (s *FixtureSource) FetchSectors() (map[string]string, error) {
+	sectors := make(map[string]string)
+	if err := readFixture(filepath.Join(s.dir, fixtureSectorsFile), &sectors); err != nil {
+		return nil, err
+	}
+	return sectors, nil
+}
+
+// FetchClassification implements IndustryClassifier with the recorded
+// classification, or the recorded sectors alone for fixtures taken before it
+// was recorded
+func (s *FixtureSource) FetchClassification() (map[string]models.IndustryClassification, error) {
+	classification := make(map[string]models.IndustryClassification)
+	err := readFixture(filepath.Join(s.dir, fixtureIndustriesFile), &classification)
+	if err == nil {
+		return classification, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	sectors, err := s.FetchSectors()
+	if err != nil {
+		return nil, err
+	}
+	for code, sector := range sectors {
+		classification[code] = models.IndustryClassification{Sector: sector}
+	}
+	return classification, nil
+}
+
+// FetchSharesOutstanding implements ShareCountFetcher with the recorded
+// share counts
+func (s *FixtureSource) FetchSharesOutstanding() (map[string]int64, error) {
+	shares := make(map[string]int64)
+	if err := readFixture(filepath.Join(s.dir, fixtureSharesFile), &shares); err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// FetchFuturesContracts implements FuturesSource with the recorded
+// contracts on underlying
+func (s *FixtureSource) FetchFuturesContracts(underlying string) ([]models.FuturesContract, error) {
+	var recorded []models.FuturesContract
+	if err := readFixture(filepath.Join(s.dir, fixtureFuturesFile), &recorded); err != nil {
+		return nil, err
+	}
+	contracts := make([]models.FuturesContract, 0, len(recorded))
+	for _, contract := range recorded {
+		if strings.EqualFold(contract.Underlying, underlying) {
+			contracts = append(contracts, contract)
+		}
+	}
+	return contracts, nil
+}
+
+// FetchFuturesPrices implements FuturesSource with the last sessions
+// recorded candles of a contract, stored like a symbol's but with open
+// interest, newest first
+func (s *FixtureSource) FetchFuturesPrices(code string, sessions int) ([]models.FuturesCandle, error) {
+	var candles []models.FuturesCandle
+	if err := readFixture(s.pricesPath(code), &candles); err != nil {
+		return nil, err
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].D > candles[j].D })
+	if sessions > 0 && len(candles) > sessions {
+		candles = candles[:sessions]
+	}
+	return candles, nil
+}
+
+// HealthCheck implements ProviderHealthChecker: the symbols must have been recorded
+func (s *FixtureSource) HealthCheck(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(s.dir, fixtureSymbolsFile)); err != nil {
+		return fmt.Errorf("no %s fixtures: %w", s.name, err)
+	}
+	return nil
+}
+
+func (s *FixtureSource) pricesPath(code string) string {
+	return filepath.Join(s.dir, fixturePricesDir, strings.ToUpper(code)+".json")
+}
+
+// readFixture decodes a fixture file into v
+func readFixture(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("no fixture %s: %w", path, os.ErrNotExist)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read fixture: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("invalid fixture %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeFixture encodes v into a fixture file, creating its directory
+func writeFixture(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// RecordingSource wraps a data source and saves what it returns as the
+// fixtures a FixtureSource of the same name serves. Recorded candles are
+// merged with the ones already on disk, so refreshes extend the history.
+// Failing to record is logged and never fails the crawl.
+type RecordingSource struct {
+	source   MarketDataSource
+	fixtures *FixtureSource
+	mu       sync.Mutex // Serializes writes of the shared symbol, classification and share files
+}
+
+// NewRecordingSource creates a data source recording source's responses under dir
+func NewRecordingSource(source MarketDataSource, dir string) *RecordingSource {
+	return &RecordingSource{source: source, fixtures: NewFixtureSource(source.Name(), dir)}
+}
+
+// Name implements MarketDataSource
+func (s *RecordingSource) Name() string {
+	return s.source.Name()
+}
+
+// FetchSymbols implements MarketDataSource, recording the stocks of the
+// requested exchanges in place of the ones recorded before
+func (s *RecordingSource) FetchSymbols(exchanges []string) ([]models.Stock, error) {
+	return s.FetchInstruments(exchanges, []string{models.InstrumentStock})
+}
+
+// FetchInstruments implements InstrumentLister, recording the symbols of the
+// requested exchanges and types in place of the ones recorded before
+func (s *RecordingSource) FetchInstruments(exchanges, types []string) ([]models.Stock, error) {
+	stocks, err := fetchInstruments(s.source, exchanges, types)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	path := filepath.Join(s.fixtures.dir, fixtureSymbolsFile)
+	var recorded []fixtureStock
+	if err := readFixture(path, &recorded); err != nil {
+		recorded = nil
+	}
+	kept := make([]fixtureStock, 0, len(recorded)+len(stocks))
+	for _, stock := range recorded {
+		if !containsFold(exchanges, stock.Exchange) || !containsFold(types, stock.instrumentType()) {
+			kept = append(kept, stock)
+		}
+	}
+	for _, stock := range stocks {
+		kept = append(kept, newFixtureStock(stock))
+	}
+	sort.Slice(kept, func(i, j int) bool { return kept[i].Code < kept[j].Code })
+	s.record(path, kept)
+	return stocks, nil
+}
+
+// FetchPrices implements MarketDataSource
+func (s *RecordingSource) FetchPrices(stock models.Stock) ([]models.CandleData, error) {
+	candles, err := s.source.FetchPrices(stock)
+	if err != nil {
+		return nil, err
+	}
+	s.recordPrices(stock.Code, candles)
+	return candles, nil
+}
+
+// FetchRecentPrices implements RecentPriceFetcher, falling back to the full
+// history when the wrapped source cannot fetch recent prices
+func (s *RecordingSource) FetchRecentPrices(stock models.Stock, sessions int) ([]models.CandleData, error) {
+	fetcher, ok := s.source.(RecentPriceFetcher)
+	if !ok {
+		return s.FetchPrices(stock)
+	}
+	candles, err := fetcher.FetchRecentPrices(stock, sessions)
+	if err != nil {
+		return nil, err
+	}
+	s.recordPrices(stock.Code, candles)
+	return candles, nil
+}
+
+// FetchIndexPrices implements IndexPriceFetcher when the wrapped source does
+func (s *RecordingSource) FetchIndexPrices(code string, sessions int) ([]models.CandleData, error) {
+	fetcher, ok := s.source.(IndexPriceFetcher)
+	if !ok {
+		return nil, fmt.Errorf("%s does not publish index prices", s.source.Name())
+	}
+	candles, err := fetcher.FetchIndexPrices(code, sessions)
+	if err != nil {
+		return nil, err
+	}
+	s.recordPrices(code, candles)
+	return candles, nil
+}
+
+// FetchSectors implements SectorClassifier when the wrapped source does
+func (s *RecordingSource) FetchSectors() (map[string]string, error) {
+	classifier, ok := s.source.(SectorClassifier)
+	if !ok {
+		return nil, fmt.Errorf("%s does not classify sectors", s.source.Name())
+	}
+	sectors, err := classifier.FetchSectors()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record(filepath.Join(s.fixtures.dir, fixtureSectorsFile), sectors)
+	return sectors, nil
+}
+
+// FetchClassification implements IndustryClassifier when the wrapped source
+// does, recording the sectors too
+func (s *RecordingSource) FetchClassification() (map[string]models.IndustryClassification, error) {
+	classifier, ok := s.source.(IndustryClassifier)
+	if !ok {
+		return nil, fmt.Errorf("%s does not classify industries", s.source.Name())
+	}
+	classification, err := classifier.FetchClassification()
+	if err != nil {
+		return nil, err
+	}
+	sectors := make(map[string]string, len(classification))
+	for code, c := range classification {
+		sectors[code] = c.Sector
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record(filepath.Join(s.fixtures.dir, fixtureIndustriesFile), classification)
+	s.record(filepath.Join(s.fixtures.dir, fixtureSectorsFile), sectors)
+	return classification, nil
+}
+
+// FetchSharesOutstanding implements ShareCountFetcher when the wrapped
+// source does
+func (s *RecordingSource) FetchSharesOutstanding() (map[string]int64, error) {
+	fetcher, ok := s.source.(ShareCountFetcher)
+	if !ok {
+		return nil, fmt.Errorf("%s does not publish share counts", s.source.Name())
+	}
+	shares, err := fetcher.FetchSharesOutstanding()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record(filepath.Join(s.fixtures.dir, fixtureSharesFile), shares)
+	return shares, nil
+}
+
+// FetchFuturesContracts implements FuturesSource when the wrapped source
+// does. Each underlying's contracts replace its recorded ones.
+func (s *RecordingSource) FetchFuturesContracts(underlying string) ([]models.FuturesContract, error) {
+	futures, ok := s.source.(FuturesSource)
+	if !ok {
+		return nil, fmt.Errorf("%s does not publish futures", s.source.Name())
+	}
+	contracts, err := futures.FetchFuturesContracts(underlying)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(s.fixtures.dir, fixtureFuturesFile)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var recorded []models.FuturesContract
+	if err := readFixture(path, &recorded); err != nil {
+		recorded = nil
+	}
+	merged := make([]models.FuturesContract, 0, len(recorded)+len(contracts))
+	for _, contract := range recorded {
+		if !strings.EqualFold(contract.Underlying, underlying) {
+			merged = append(merged, contract)
+		}
+	}
+	merged = append(merged, contracts...)
+	s.record(path, merged)
+	return contracts, nil
+}
+
+// FetchFuturesPrices implements FuturesSource when the wrapped source does
+func (s *RecordingSource) FetchFuturesPrices(code string, sessions int) ([]models.FuturesCandle, error) {
+	futures, ok := s.source.(FuturesSource)
+	if !ok {
+		return nil, fmt.Errorf("%s does not publish futures", s.source.Name())
+	}
+	candles, err := futures.FetchFuturesPrices(code, sessions)
+	if err != nil {
+		return nil, err
+	}
+	path := s.fixtures.pricesPath(code)
+	var recorded []models.FuturesCandle
+	if err := readFixture(path, &recorded); err != nil {
+		recorded = nil
+	}
+	s.record(path, models.MergeFuturesCandles(recorded, candles))
+	return candles, nil
+}
+
+// UseCredentials implements CredentialedSource when the wrapped source does
+func (s *RecordingSource) UseCredentials(lookup CredentialLookup) {
+	if credentialed, ok := s.source.(CredentialedSource); ok {
+		credentialed.UseCredentials(lookup)
+	}
+}
+
+// HealthCheck implements ProviderHealthChecker when the wrapped source does
+func (s *RecordingSource) HealthCheck(ctx context.Context) error {
+	if checker, ok := s.source.(ProviderHealthChecker); ok {
+		return checker.HealthCheck(ctx)
+	}
+	return nil
+}
+
+// recordPrices merges candles into the recorded candles of code; a
+// re-fetched date replaces the recorded candle
+func (s *RecordingSource) recordPrices(code string, candles []models.CandleData) {
+	path := s.fixtures.pricesPath(code)
+	var recorded []models.CandleData
+	if err := readFixture(path, &recorded); err != nil {
+		recorded = nil
+	}
+	byDate := make(map[string]models.CandleData, len(recorded)+len(candles))
+	for _, candle := range recorded {
+		byDate[candle.D] = candle
+	}
+	for _, candle := range candles {
+		byDate[candle.D] = candle
+	}
+	merged := make([]models.CandleData, 0, len(byDate))
+	for _, candle := range byDate {
+		merged = append(merged, candle)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].D < merged[j].D })
+	s.record(path, merged)
+}
+
+func (s *RecordingSource) record(path string, v interface{}) {
+	if err := writeFixture(path, v); err != nil {
+		crawlLog.Warn("Failed to record fixture", "path", path, logging.FieldError, err)
+	}
+}
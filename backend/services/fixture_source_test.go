@@ -0,0 +1,140 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestFixtureSourceServesShippedFixtures(t *testing.T) {
+	source := NewFixtureSource(models.DataSourceVNDirect, filepath.Join("..", DefaultFixturesDir))
+
+	stocks, err := source.FetchSymbols([]string{"HNX"})
+	if err != nil {
+		t.Fatalf("FetchSymbols: %v", err)
+	}
+	if len(stocks) != 1 || stocks[0].Code != "SHS" {
+		t.Errorf("FetchSymbols(HNX) = %+v; want only SHS", stocks)
+	}
+
+	instruments, err := source.FetchInstruments([]string{"HOSE"}, []string{models.InstrumentETF, models.InstrumentCoveredWarrant})
+	if err != nil || len(instruments) != 2 || instruments[1].Code != "CHPG2401" || instruments[1].Underlying != "HPG" || instruments[1].ExercisePrice == 0 {
+		t.Errorf("FetchInstruments(HOSE, etf, cw) = %+v, %v; want E1VFVN30 and CHPG2401 with its terms", instruments, err)
+	}
+	if stocks, _ := source.FetchSymbols([]string{"HOSE"}); len(stocks) != 4 {
+		t.Errorf("FetchSymbols(HOSE) = %+v; want the 4 stocks only", stocks)
+	}
+
+	contracts, err := source.FetchFuturesContracts("VN30")
+	if err != nil || len(contracts) != 4 || contracts[0].LastTradingDate == "" {
+		t.Errorf("FetchFuturesContracts(VN30) = %+v, %v; want the 4 recorded contracts", contracts, err)
+	}
+	futures, err := source.FetchFuturesPrices("VN30F2611", 3)
+	if err != nil || len(futures) != 3 || futures[0].D <= futures[2].D || futures[0].OI == 0 {
+		t.Errorf("FetchFuturesPrices(VN30F2611, 3) = %+v, %v; want the 3 newest candles with open interest", futures, err)
+	}
+
+	candles, err := source.FetchRecentPrices(models.Stock{Code: "HPG", Exchange: "HOSE"}, 5)
+	if err != nil {
+		t.Fatalf("FetchRecentPrices: %v", err)
+	}
+	if len(candles) != 5 || candles[0].D <= candles[4].D {
+		t.Errorf("FetchRecentPrices(HPG, 5) = %+v; want the 5 newest candles, newest first", candles)
+	}
+	if _, err := source.FetchPrices(models.Stock{Code: "XXX", Exchange: "HOSE"}); err == nil {
+		t.Error("FetchPrices of a symbol without fixture succeeded; want an error")
+	}
+
+	sectors, err := source.FetchSectors()
+	if err != nil || sectors["HPG"] == "" {
+		t.Errorf("FetchSectors() = %v, %v; want the sector of HPG", sectors, err)
+	}
+	classification, err := source.FetchClassification()
+	if err != nil || classification["HPG"].Sector != sectors["HPG"] || classification["HPG"].Industry == "" || classification["HPG"].ICBCode == "" {
+		t.Errorf("FetchClassification() = %v, %v; want the industry, sector and code of HPG", classification, err)
+	}
+	if shares, err := source.FetchSharesOutstanding(); err != nil || shares["HPG"] <= 0 {
+		t.Errorf("FetchSharesOutstanding() = %v, %v; want the share count of HPG", shares, err)
+	}
+}
+
+func TestFixtureSourceClassifiesFromSectors(t *testing.T) {
+	dir := t.TempDir()
+	if err := writeFixture(filepath.Join(dir, "old", fixtureSectorsFile), map[string]string{"HPG": "Basic Resources"}); err != nil {
+		t.Fatal(err)
+	}
+	classification, err := NewFixtureSource("old", dir).FetchClassification()
+	if err != nil || classification["HPG"] != (models.IndustryClassification{Sector: "Basic Resources"}) {
+		t.Errorf("FetchClassification() without industries.json = %v, %v; want the recorded sector", classification, err)
+	}
+	if _, err := NewFixtureSource("missing", dir).FetchClassification(); err == nil {
+		t.Error("FetchClassification() without fixtures succeeded; want an error")
+	}
+}
+
+// stubSource returns fixed symbols and candles
+type stubSource struct {
+	stocks  []models.Stock
+	candles []models.CandleData
+}
+
+func (s *stubSource) Name() string { return "stub" }
+
+func (s *stubSource) FetchSymbols(exchanges []string) ([]models.Stock, error) {
+	return s.stocks, nil
+}
+
+func (s *stubSource) FetchPrices(stock models.Stock) ([]models.CandleData, error) {
+	return s.candles, nil
+}
+
+func TestRecordingSourceRecordsFixtures(t *testing.T) {
+	dir := t.TempDir()
+	stub := &stubSource{
+		stocks: []models.Stock{{Code: "AAA", CompanyName: "A", Exchange: "HOSE", Type: "stock", Status: "listed"}},
+		candles: []models.CandleData{
+			{D: "2024-06-11", O: 10.5, H: 11, L: 10, C: 10.8, V: 200},
+			{D: "2024-06-10", O: 10, H: 11, L: 9, C: 10.5, V: 100},
+		},
+	}
+	recorder := NewRecordingSource(stub, dir)
+	stock := stub.stocks[0]
+
+	if _, err := recorder.FetchSymbols([]string{"HOSE"}); err != nil {
+		t.Fatalf("FetchSymbols: %v", err)
+	}
+	if _, err := recorder.FetchRecentPrices(stock, 20); err != nil {
+		t.Fatalf("FetchRecentPrices: %v", err)
+	}
+	// A later fetch extends the history and replaces re-fetched dates
+	stub.candles = []models.CandleData{
+		{D: "2024-06-12", O: 10.8, H: 11.2, L: 10.6, C: 11, V: 300},
+		{D: "2024-06-11", O: 10.5, H: 11, L: 10, C: 10.9, V: 250},
+	}
+	if _, err := recorder.FetchPrices(stock); err != nil {
+		t.Fatalf("FetchPrices: %v", err)
+	}
+
+	fixtures := NewFixtureSource("stub", dir)
+	stocks, err := fixtures.FetchSymbols([]string{"HOSE"})
+	if err != nil || len(stocks) != 1 || stocks[0].Code != "AAA" {
+		t.Fatalf("recorded symbols = %+v, %v; want AAA", stocks, err)
+	}
+	candles, err := fixtures.FetchPrices(stock)
+	if err != nil {
+		t.Fatalf("recorded prices: %v", err)
+	}
+	if len(candles) != 3 || candles[0].D != "2024-06-12" || candles[1].C != 10.9 || candles[2].D != "2024-06-10" {
+		t.Errorf("recorded prices = %+v; want 3 merged candles with the re-fetched 2024-06-11", candles)
+	}
+}
+
+func TestUseCrawlerModeRejectsUnknownModes(t *testing.T) {
+	if mode, err := UseCrawlerMode("", ""); err != nil || mode != CrawlerModeLive {
+		t.Errorf("UseCrawlerMode(\"\") = %q, %v; want live", mode, err)
+	}
+	if _, err := UseCrawlerMode("mock", ""); err == nil {
+		t.Error("UseCrawlerMode(mock) succeeded; want an error")
+	}
+}
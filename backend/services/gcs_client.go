@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	gcsAPIBase    = "https://storage.googleapis.com/storage/v1"
+	gcsUploadBase = "https://storage.googleapis.com/upload/storage/v1"
+	// gcsMetadataTokenURL serves the access token of the Cloud Run service account
+	gcsMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// GCSClient is a minimal Google Cloud Storage JSON API client for one bucket.
+// It authenticates with GCS_ACCESS_TOKEN when set (local runs) and otherwise
+// with the service account of the Cloud Run instance.
+type GCSClient struct {
+	bucket string
+	client *resty.Client
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewGCSClient creates a client for the given bucket
+func NewGCSClient(bucket string) *GCSClient {
+	client := newRestyClient()
+	client.SetTimeout(5 * time.Minute)
+	client.SetRetryCount(2)
+	client.SetRetryWaitTime(2 * time.Second)
+
+	return &GCSClient{bucket: bucket, client: client}
+}
+
+// Bucket returns the bucket name
+func (g *GCSClient) Bucket() string {
+	return g.bucket
+}
+
+// Upload stores data under the object name
+func (g *GCSClient) Upload(ctx context.Context, object, contentType string, data []byte) error {
+	req, err := g.request(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := req.
+		SetQueryParams(map[string]string{"uploadType": "media", "name": object}).
+		SetHeader("Content-Type", contentType).
+		SetBody(data).
+		Post(fmt.Sprintf("%s/b/%s/o", gcsUploadBase, url.PathEscape(g.bucket)))
+	if err != nil {
+		return fmt.Errorf("failed to upload %s: %w", object, err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("upload of %s returned status %d: %s", object, resp.StatusCode(), resp.String())
+	}
+	return nil
+}
+
+// Download returns the content of an object
+func (g *GCSClient) Download(ctx context.Context, object string) ([]byte, error) {
+	req, err := g.request(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := req.
+		SetQueryParam("alt", "media").
+		Get(fmt.Sprintf("%s/b/%s/o/%s", gcsAPIBase, url.PathEscape(g.bucket), url.PathEscape(object)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", object, err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("download of %s returned status %d", object, resp.StatusCode())
+	}
+	return resp.Body(), nil
+}
+
+// ListPrefixes returns the "folders" directly under prefix (names ending in /)
+func (g *GCSClient) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	var prefixes []string
+	pageToken := ""
+	for {
+		req, err := g.request(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Prefixes      []string `json:"prefixes"`
+			NextPageToken string   `json:"nextPageToken"`
+		}
+		params := map[string]string{"prefix": prefix, "delimiter": "/", "fields": "prefixes,nextPageToken"}
+		if pageToken != "" {
+			params["pageToken"] = pageToken
+		}
+		resp, err := req.SetQueryParams(params).SetResult(&page).
+			Get(fmt.Sprintf("%s/b/%s/o", gcsAPIBase, url.PathEscape(g.bucket)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		if resp.IsError() {
+			return nil, fmt.Errorf("listing %s returned status %d", prefix, resp.StatusCode())
+		}
+		prefixes = append(prefixes, page.Prefixes...)
+		if page.NextPageToken == "" {
+			return prefixes, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// ListObjects returns the names of the objects under prefix
+func (g *GCSClient) ListObjects(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	pageToken := ""
+	for {
+		req, err := g.request(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		params := map[string]string{"prefix": prefix, "fields": "items(name),nextPageToken"}
+		if pageToken != "" {
+			params["pageToken"] = pageToken
+		}
+		resp, err := req.SetQueryParams(params).SetResult(&page).
+			Get(fmt.Sprintf("%s/b/%s/o", gcsAPIBase, url.PathEscape(g.bucket)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", prefix, err)
+		}
+		if resp.IsError() {
+			return nil, fmt.Errorf("listing %s returned status %d", prefix, resp.StatusCode())
+		}
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		if page.NextPageToken == "" {
+			return names, nil
+		}
+		pageToken = page.NextPageToken
+	}
+}
+
+// Delete removes an object
+func (g *GCSClient) Delete(ctx context.Context, object string) error {
+	req, err := g.request(ctx)
+	if err != nil {
+		return err
+	}
+	resp, err := req.Delete(fmt.Sprintf("%s/b/%s/o/%s", gcsAPIBase, url.PathEscape(g.bucket), url.PathEscape(object)))
+	if err != nil {
+		return fmt.Errorf("failed to delete %s: %w", object, err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("delete of %s returned status %d", object, resp.StatusCode())
+	}
+	return nil
+}
+
+// request returns an authenticated request
+func (g *GCSClient) request(ctx context.Context) (*resty.Request, error) {
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return g.client.R().SetContext(ctx).SetAuthToken(token), nil
+}
+
+// accessToken returns GCS_ACCESS_TOKEN or a cached service account token
+func (g *GCSClient) accessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv("GCS_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.token != "" && time.Now().Before(g.tokenExpiry) {
+		return g.token, nil
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	resp, err := g.client.R().
+		SetContext(ctx).
+		SetHeader("Metadata-Flavor", "Google").
+		SetResult(&result).
+		Get(gcsMetadataTokenURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to get service account token (set GCS_ACCESS_TOKEN outside Google Cloud): %w", err)
+	}
+	if resp.IsError() || result.AccessToken == "" {
+		return "", fmt.Errorf("metadata server returned status %d for the service account token", resp.StatusCode())
+	}
+
+	// Refresh a minute before the token expires
+	g.token = result.AccessToken
+	g.tokenExpiry = time.Now().Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return g.token, nil
+}
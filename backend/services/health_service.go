@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+// States of one dependency in a deep health check
+const (
+	HealthOK            = "ok"
+	HealthDown          = "down"
+	HealthStale         = "stale"          // Reachable, but its data is older than allowed
+	HealthNotConfigured = "not_configured" // Not connected in this deployment; does not degrade health
+)
+
+const (
+	// healthCheckTimeout bounds each dependency check
+	healthCheckTimeout = 3 * time.Second
+	// DeepHealthCacheTTL is how long a deep check result is served, so probes
+	// and curious clients cannot turn the endpoint into database load
+	DeepHealthCacheTTL = 10 * time.Second
+)
+
+// DependencyHealth is the result of checking one dependency. The endpoint
+// is public, so only the name and status are serialized; the details are
+// logged by Deep.
+type DependencyHealth struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	LatencyMS  int64  `json:"-"`
+	Error      string `json:"-"`
+	AgeSeconds *int64 `json:"-"` // Last successful crawl only
+}
+
+// DeepHealth is the payload of GET /health?deep=true
+type DeepHealth struct {
+	Status    string             `json:"status"` // healthy or degraded
+	Checks    []DependencyHealth `json:"checks"`
+	CheckedAt time.Time          `json:"checked_at"`
+}
+
+// Healthy reports whether every configured dependency is ok
+func (h *DeepHealth) Healthy() bool {
+	return h.Status == "healthy"
+}
+
+// HealthService checks the databases, crawl freshness and upstream data
+// sources on demand
+type HealthService struct {
+	alertService *AlertService
+
+	mu       sync.Mutex // Held while checking, so concurrent misses share one check
+	cached   *DeepHealth
+	cachedAt time.Time
+}
+
+// NewHealthService creates a new HealthService instance
+func NewHealthService(alertService *AlertService) *HealthService {
+	return &HealthService{alertService: alertService}
+}
+
+// Deep runs every dependency check in parallel, serving a result younger
+// than DeepHealthCacheTTL instead when there is one
+func (s *HealthService) Deep(ctx context.Context) *DeepHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	if s.cached != nil && now.Sub(s.cachedAt) < DeepHealthCacheTTL {
+		return s.cached
+	}
+
+	type namedCheck struct {
+		name  string
+		check func(ctx context.Context) DependencyHealth
+	}
+	checks := []namedCheck{
+		{"postgres", checkPostgres},
+		{"mongodb", checkMongo},
+		{"crawl", s.checkCrawl},
+	}
+	for _, source := range MarketDataSources() {
+		if checker, ok := source.(ProviderHealthChecker); ok {
+			checks = append(checks, namedCheck{"upstream:" + source.Name(), func(ctx context.Context) DependencyHealth {
+				return timedCheck(func() error { return checker.HealthCheck(ctx) })
+			}})
+		}
+	}
+
+	results := make([]DependencyHealth, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			results[i] = check.check(checkCtx)
+			results[i].Name = check.name
+		}()
+	}
+	wg.Wait()
+
+	logger := logging.FromContext(ctx)
+	for _, result := range results {
+		if result.Status == HealthDown || result.Status == HealthStale {
+			logger.Warn("Deep health check failed", "check", result.Name, "health", result.Status,
+				"latency_ms", result.LatencyMS, logging.FieldError, result.Error)
+		}
+	}
+
+	health := &DeepHealth{Status: overallHealth(results), Checks: results, CheckedAt: now}
+	s.cached, s.cachedAt = health, now
+	return health
+}
+
+// checkPostgres pings the Supabase connection pool
+func checkPostgres(ctx context.Context) DependencyHealth {
+	if config.PostgresDB == nil {
+		return DependencyHealth{Status: HealthNotConfigured}
+	}
+	return timedCheck(func() error {
+		sqlDB, err := config.PostgresDB.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// checkMongo pings the MongoDB primary
+func checkMongo(ctx context.Context) DependencyHealth {
+	if config.MongoClient == nil {
+		return DependencyHealth{Status: HealthNotConfigured}
+	}
+	return timedCheck(func() error { return config.MongoClient.Ping(ctx, nil) })
+}
+
+// checkCrawl reports the age of the last successful full crawl, which is
+// stale once older than the health.max_crawl_age setting
+func (s *HealthService) checkCrawl(ctx context.Context) DependencyHealth {
+	if config.MongoClient == nil {
+		return DependencyHealth{Status: HealthNotConfigured}
+	}
+	var finishedAt *time.Time
+	result := timedCheck(func() error {
+		metrics, err := s.alertService.CollectMetrics(ctx)
+		finishedAt = metrics.LastSuccessfulCrawlAt
+		return err
+	})
+	if result.Status != HealthOK {
+		return result
+	}
+	return crawlAgeHealth(result, finishedAt, config.Runtime().HealthMaxCrawlAge, time.Now())
+}
+
+// crawlAgeHealth classifies the age of the last successful crawl
+func crawlAgeHealth(result DependencyHealth, finishedAt *time.Time, maxAge time.Duration, now time.Time) DependencyHealth {
+	if finishedAt == nil {
+		result.Status = HealthStale
+		result.Error = "no successful crawl recorded"
+		return result
+	}
+	age := int64(now.Sub(*finishedAt).Seconds())
+	result.AgeSeconds = &age
+	if maxAge > 0 && now.Sub(*finishedAt) > maxAge {
+		result.Status = HealthStale
+		result.Error = fmt.Sprintf("last successful crawl is older than %s", maxAge)
+	}
+	return result
+}
+
+// timedCheck runs check and reports its outcome and latency
+func timedCheck(check func() error) DependencyHealth {
+	started := time.Now()
+	err := check()
+	result := DependencyHealth{Status: HealthOK, LatencyMS: time.Since(started).Milliseconds()}
+	if err != nil {
+		result.Status = HealthDown
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// overallHealth is healthy when every configured dependency is ok
+func overallHealth(checks []DependencyHealth) string {
+	for _, check := range checks {
+		if check.Status != HealthOK && check.Status != HealthNotConfigured {
+			return "degraded"
+		}
+	}
+	return "healthy"
+}
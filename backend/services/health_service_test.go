@@ -0,0 +1,52 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCrawlAgeHealth(t *testing.T) {
+	now := time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC)
+	recent := now.Add(-20 * time.Hour)
+	old := now.Add(-80 * time.Hour)
+
+	tests := []struct {
+		name       string
+		finishedAt *time.Time
+		wantStatus string
+		wantAge    int64
+	}{
+		{"recent", &recent, HealthOK, 72000},
+		{"older than allowed", &old, HealthStale, 288000},
+		{"never crawled", nil, HealthStale, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := crawlAgeHealth(DependencyHealth{Status: HealthOK}, tt.finishedAt, 72*time.Hour, now)
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %s; want %s", got.Status, tt.wantStatus)
+			}
+			if tt.wantAge < 0 {
+				if got.AgeSeconds != nil || got.Error == "" {
+					t.Errorf("AgeSeconds = %v, Error = %q; want no age and an error", got.AgeSeconds, got.Error)
+				}
+			} else if got.AgeSeconds == nil || *got.AgeSeconds != tt.wantAge {
+				t.Errorf("AgeSeconds = %v; want %d", got.AgeSeconds, tt.wantAge)
+			}
+		})
+	}
+}
+
+func TestOverallHealth(t *testing.T) {
+	healthy := []DependencyHealth{{Status: HealthOK}, {Status: HealthNotConfigured}}
+	if got := overallHealth(healthy); got != "healthy" {
+		t.Errorf("overallHealth(ok, not configured) = %s; want healthy", got)
+	}
+	for _, status := range []string{HealthDown, HealthStale} {
+		checks := append([]DependencyHealth{}, healthy...)
+		checks = append(checks, DependencyHealth{Status: status})
+		if got := overallHealth(checks); got != "degraded" {
+			t.Errorf("overallHealth(%s) = %s; want degraded", status, got)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package services
+
+import (
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/go-resty/resty/v2"
+)
+
+// newRestyClient creates the resty client of an outbound integration. Calls
+// made with a request's context forward its X-Request-ID, so upstream logs
+// can be correlated with ours.
+func newRestyClient() *resty.Client {
+	client := resty.New()
+	client.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		if id := logging.RequestID(req.Context()); id != "" {
+			req.SetHeader(logging.RequestIDHeader, id)
+		}
+		return nil
+	})
+	return client
+}
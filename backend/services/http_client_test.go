@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+func TestRestyClientForwardsRequestID(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get(logging.RequestIDHeader))
+	}))
+	defer server.Close()
+
+	client := newRestyClient()
+	ctx := logging.WithRequestID(context.Background(), "req-42")
+	if _, err := client.R().SetContext(ctx).Get(server.URL); err != nil {
+		t.Fatalf("request with ID: %v", err)
+	}
+	if _, err := client.R().SetContext(context.Background()).Get(server.URL); err != nil {
+		t.Fatalf("request without ID: %v", err)
+	}
+
+	if len(received) != 2 || received[0] != "req-42" || received[1] != "" {
+		t.Errorf("received request IDs %q; want [req-42, none]", received)
+	}
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// integrityLog logs price integrity verification
+var integrityLog = logging.Component("integrity")
+
+// Bucket integrity statuses
+const (
+	IntegrityStatusOK              = "ok"
+	IntegrityStatusMismatch        = "mismatch"         // Stored checksum differs from the data
+	IntegrityStatusMissingChecksum = "missing_checksum" // Bucket written before checksums existed
+	IntegrityStatusDuplicateDates  = "duplicate_dates"  // Same trading date stored more than once
+	IntegrityStatusReplicaDiverged = "replica_diverged" // Secondary holds different data
+	IntegrityStatusReplicaMissing  = "replica_missing"  // Bucket absent on the secondary
+)
+
+// maxIntegrityIssues caps the issues listed in a report; counters stay exact
+const maxIntegrityIssues = 500
+
+// BucketChecksum is the checksum of one price bucket
+type BucketChecksum struct {
+	ID       string `json:"id"`
+	Year     int    `json:"year"`
+	Candles  int    `json:"candles"`
+	Checksum string `json:"checksum"` // Computed from the stored candles
+	Stored   string `json:"stored"`   // Checksum saved with the bucket (empty if never signed)
+	Status   string `json:"status"`
+}
+
+// IntegrityIssue describes one problem found by verification
+type IntegrityIssue struct {
+	BucketID string `json:"bucket_id"`
+	Status   string `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// IntegrityReport summarizes a verification run
+type IntegrityReport struct {
+	StartedAt       time.Time        `json:"started_at"`
+	FinishedAt      time.Time        `json:"finished_at"`
+	Checked         int              `json:"checked"`
+	OK              int              `json:"ok"`
+	Mismatched      int              `json:"mismatched"`
+	MissingChecksum int              `json:"missing_checksum"`
+	DuplicateDates  int              `json:"duplicate_dates"`
+	Repaired        int              `json:"repaired"`
+	ReplicaChecked  bool             `json:"replica_checked"`
+	ReplicaDiverged int              `json:"replica_diverged"`
+	Issues          []IntegrityIssue `json:"issues"`
+	Truncated       bool             `json:"truncated"` // More issues than listed
+}
+
+// HasProblems reports whether the run found corruption or divergence.
+// Missing checksums alone are not a problem; they are expected for buckets
+// written before checksums were introduced.
+func (r *IntegrityReport) HasProblems() bool {
+	return r.Mismatched > 0 || r.DuplicateDates > 0 || r.ReplicaDiverged > 0
+}
+
+func (r *IntegrityReport) addIssue(bucketID, status, detail string) {
+	if len(r.Issues) >= maxIntegrityIssues {
+		r.Truncated = true
+		return
+	}
+	r.Issues = append(r.Issues, IntegrityIssue{BucketID: bucketID, Status: status, Detail: detail})
+}
+
+// VerifyOptions selects the buckets to verify and optional actions
+type VerifyOptions struct {
+	Code           string `json:"code"`            // Only buckets of this stock (optional)
+	Year           int    `json:"year"`            // Only buckets of this year (optional)
+	RepairMissing  bool   `json:"repair_missing"`  // Sign buckets that have no checksum yet
+	CompareReplica bool   `json:"compare_replica"` // Also read from a secondary and compare
+}
+
+// IntegrityService computes and verifies price bucket checksums
+type IntegrityService struct {
+	priceCollection *mongo.Collection
+	notifications   *NotificationService
+}
+
+// NewIntegrityService creates a new IntegrityService instance
+func NewIntegrityService(notifications *NotificationService) *IntegrityService {
+	return &IntegrityService{
+		priceCollection: config.GetCollection("stock_prices"),
+		notifications:   notifications,
+	}
+}
+
+// GetChecksums returns the checksum of every bucket of a stock, so external
+// mirrors can compare their copy without downloading the candles
+func (s *IntegrityService) GetChecksums(ctx context.Context, code string) ([]BucketChecksum, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "year", Value: 1}})
+	cursor, err := s.priceCollection.Find(ctx, bson.M{"code": strings.ToUpper(code)}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price buckets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	checksums := make([]BucketChecksum, 0)
+	for cursor.Next(ctx) {
+		var bucket models.PriceBucket
+		if err := cursor.Decode(&bucket); err != nil {
+			return nil, fmt.Errorf("failed to decode price bucket: %w", err)
+		}
+		computed := models.ComputeChecksum(bucket.History)
+		checksums = append(checksums, BucketChecksum{
+			ID:       bucket.ID,
+			Year:     bucket.Year,
+			Candles:  len(bucket.History),
+			Checksum: computed,
+			Stored:   bucket.Checksum,
+			Status:   bucketStatus(&bucket, computed),
+		})
+	}
+	return checksums, cursor.Err()
+}
+
+// Verify recomputes the checksum of every selected bucket and compares it
+// with the stored one, optionally backfilling missing checksums and
+// comparing against a secondary replica
+func (s *IntegrityService) Verify(ctx context.Context, opts VerifyOptions) (*IntegrityReport, error) {
+	report := &IntegrityReport{StartedAt: time.Now().UTC(), Issues: make([]IntegrityIssue, 0)}
+
+	filter := bson.M{}
+	if opts.Code != "" {
+		filter["code"] = strings.ToUpper(opts.Code)
+	}
+	if opts.Year != 0 {
+		filter["year"] = opts.Year
+	}
+
+	// Read the replica first so the primary pass can compare in one sweep
+	var replica map[string]string
+	if opts.CompareReplica {
+		var err error
+		if replica, err = s.replicaChecksums(ctx, filter); err != nil {
+			return nil, err
+		}
+		report.ReplicaChecked = true
+	}
+
+	cursor, err := s.priceCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price buckets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var bucket models.PriceBucket
+		if err := cursor.Decode(&bucket); err != nil {
+			return nil, fmt.Errorf("failed to decode price bucket: %w", err)
+		}
+		report.Checked++
+		computed := models.ComputeChecksum(bucket.History)
+		healthy := true
+
+		switch {
+		case bucket.Checksum == "":
+			report.MissingChecksum++
+			if opts.RepairMissing {
+				if err := s.sign(ctx, bucket.ID, computed); err != nil {
+					return nil, err
+				}
+				report.Repaired++
+			}
+		case bucket.Checksum != computed:
+			report.Mismatched++
+			healthy = false
+			report.addIssue(bucket.ID, IntegrityStatusMismatch,
+				fmt.Sprintf("stored %s, computed %s", bucket.Checksum, computed))
+		}
+
+		if duplicates := bucket.DuplicateDates(); len(duplicates) > 0 {
+			report.DuplicateDates++
+			healthy = false
+			report.addIssue(bucket.ID, IntegrityStatusDuplicateDates, strings.Join(duplicates, ", "))
+		}
+
+		if replica != nil {
+			replicaChecksum, found := replica[bucket.ID]
+			switch {
+			case !found:
+				report.ReplicaDiverged++
+				healthy = false
+				report.addIssue(bucket.ID, IntegrityStatusReplicaMissing, "")
+			case replicaChecksum != computed:
+				report.ReplicaDiverged++
+				healthy = false
+				report.addIssue(bucket.ID, IntegrityStatusReplicaDiverged,
+					fmt.Sprintf("primary %s, secondary %s", computed, replicaChecksum))
+			}
+		}
+
+		if healthy {
+			report.OK++
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read price buckets: %w", err)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
+
+// replicaChecksums computes bucket checksums from a secondary member. On a
+// standalone server this reads the primary, so no divergence is reported.
+func (s *IntegrityService) replicaChecksums(ctx context.Context, filter bson.M) (map[string]string, error) {
+	secondary, err := s.priceCollection.Clone(options.Collection().SetReadPreference(readpref.SecondaryPreferred()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open secondary: %w", err)
+	}
+
+	cursor, err := secondary.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query secondary: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	checksums := make(map[string]string)
+	for cursor.Next(ctx) {
+		var bucket models.PriceBucket
+		if err := cursor.Decode(&bucket); err != nil {
+			return nil, fmt.Errorf("failed to decode secondary bucket: %w", err)
+		}
+		checksums[bucket.ID] = models.ComputeChecksum(bucket.History)
+	}
+	return checksums, cursor.Err()
+}
+
+// sign stores the checksum of a bucket that has none yet
+func (s *IntegrityService) sign(ctx context.Context, bucketID, checksum string) error {
+	filter := bson.M{"_id": bucketID, "checksum": bson.M{"$exists": false}}
+	if _, err := s.priceCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"checksum": checksum}}); err != nil {
+		return fmt.Errorf("failed to store checksum for %s: %w", bucketID, err)
+	}
+	return nil
+}
+
+// StartVerificationJob verifies all buckets until ctx is cancelled, backfilling
+// missing checksums and notifying every registered channel when corruption
+// or divergence is found. The interval is re-read from the runtime
+// configuration after every run.
+func (s *IntegrityService) StartVerificationJob(ctx context.Context) {
+	go func() {
+		interval := config.Runtime().IntegrityCheckInterval
+		integrityLog.Info("Price integrity verification scheduled", "interval", interval.String())
+		timer := time.NewTimer(interval)
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+				runCtx, cancel := context.WithTimeout(ctx, time.Hour)
+				s.runVerification(runCtx)
+				cancel()
+
+				interval = config.Runtime().IntegrityCheckInterval
+				timer.Reset(interval)
+			}
+		}
+	}()
+}
+
+func (s *IntegrityService) runVerification(ctx context.Context) {
+	report, err := s.Verify(ctx, VerifyOptions{RepairMissing: true, CompareReplica: true})
+	if err != nil {
+		integrityLog.Warn("Price integrity verification failed", logging.FieldError, err)
+		return
+	}
+
+	integrityLog.Info("Price integrity verified", "checked", report.Checked, "ok", report.OK, "mismatched", report.Mismatched,
+		"duplicate_dates", report.DuplicateDates, "replica_diverged", report.ReplicaDiverged, "backfilled", report.Repaired)
+	if !report.HasProblems() {
+		return
+	}
+
+	err = s.notifications.Send(ctx, s.notifications.Channels(), Notification{
+		Title:    "Price data integrity check failed",
+		Message:  fmt.Sprintf("%d of %d price buckets failed verification", report.Checked-report.OK, report.Checked),
+		Severity: SeverityCritical,
+		Source:   "integrity",
+		Fields: map[string]interface{}{
+			"mismatched":       report.Mismatched,
+			"duplicate_dates":  report.DuplicateDates,
+			"replica_diverged": report.ReplicaDiverged,
+		},
+	})
+	if err != nil {
+		integrityLog.Warn("Failed to send integrity notification", logging.FieldError, err)
+	}
+}
+
+// bucketStatus classifies a bucket from its stored and computed checksums
+func bucketStatus(bucket *models.PriceBucket, computed string) string {
+	switch {
+	case len(bucket.DuplicateDates()) > 0:
+		return IntegrityStatusDuplicateDates
+	case bucket.Checksum == "":
+		return IntegrityStatusMissingChecksum
+	case bucket.Checksum != computed:
+		return IntegrityStatusMismatch
+	default:
+		return IntegrityStatusOK
+	}
+}
@@ -0,0 +1,565 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// jobLog logs the job queue
+var jobLog = logging.Component("jobs")
+
+const (
+	// jobPollInterval is how often due jobs are looked for
+	jobPollInterval = 5 * time.Second
+	// jobLease keeps a claimed job from being picked up by another instance;
+	// it is renewed every jobLease/3 while the job runs
+	jobLease = 2 * time.Minute
+	// maxJobListLimit caps the jobs returned by one listing
+	maxJobListLimit = 500
+	// maxJobError caps the error message kept on a job
+	maxJobError = 2000
+)
+
+var (
+	// ErrJobNotFound is returned when a job ID does not exist
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobDuplicate is returned when an unfinished job with the same unique key exists
+	ErrJobDuplicate = errors.New("an unfinished job with the same key exists")
+	// ErrJobState is returned when a job cannot be retried or cancelled in its state
+	ErrJobState = errors.New("job cannot be changed in its current state")
+)
+
+// JobHandler runs one job. Returning an error retries the job with backoff
+// until it runs out of attempts; DeferJob postpones it without using one.
+// ctx is cancelled when the instance shuts down, after which the job is
+// put back in the queue for another instance.
+type JobHandler func(ctx context.Context, job *models.Job) error
+
+// JobOptions adjusts a queued job
+type JobOptions struct {
+	Priority    int       // models.JobPriority*; higher runs first
+	MaxAttempts int       // Default models.DefaultJobMaxAttempts
+	RunAt       time.Time // Default now
+	UniqueKey   string    // Rejects the job while another with this key is queued or running
+	CreatedBy   string
+}
+
+// jobDeferral postpones a job without counting the attempt
+type jobDeferral struct {
+	after  time.Duration
+	reason error
+}
+
+func (d *jobDeferral) Error() string { return d.reason.Error() }
+func (d *jobDeferral) Unwrap() error { return d.reason }
+
+// DeferJob returns an error that puts the job back in the queue for after,
+// without using one of its attempts, e.g. while a shared resource is busy
+func DeferJob(after time.Duration, reason error) error {
+	return &jobDeferral{after: after, reason: reason}
+}
+
+// JobFilter selects jobs of the listing
+type JobFilter struct {
+	Kind   string
+	Status string
+	Limit  int
+}
+
+// JobQueue is a durable queue of background work kept in the Supabase jobs
+// table. Every instance runs workers that claim due jobs, so queued work
+// survives restarts and deploys. Without Postgres, jobs run right away in
+// this instance and are lost if it stops.
+type JobQueue struct {
+	instance string
+	handlers map[string]JobHandler
+	wake     chan struct{} // Signals the workers that jobs are queued
+	ctx      context.Context
+
+	mu          sync.Mutex
+	workers     int                // Set by Start
+	stopWorkers context.CancelFunc // Stops the polling workers, for Restart
+	active      map[string]bool    // Unique keys of jobs running without Postgres
+	running     sync.WaitGroup     // Jobs being run by this instance
+	inFlight    atomic.Int64       // Number of jobs in running, for load shedding
+}
+
+// NewJobQueue creates a new JobQueue instance
+func NewJobQueue() *JobQueue {
+	host, _ := os.Hostname()
+	return &JobQueue{
+		instance: fmt.Sprintf("%s/%s", host, uuid.NewString()[:8]),
+		handlers: make(map[string]JobHandler),
+		wake:     make(chan struct{}, 1),
+		ctx:      context.Background(),
+		active:   make(map[string]bool),
+	}
+}
+
+// Handle registers the handler of a job kind. Handlers must be registered
+// before Start.
+func (q *JobQueue) Handle(kind string, handler JobHandler) {
+	q.handlers[kind] = handler
+}
+
+// Enqueue queues a job of kind with payload encoded as JSON
+func (q *JobQueue) Enqueue(ctx context.Context, kind string, payload interface{}, opts JobOptions) (*models.Job, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
+	now := time.Now().UTC()
+	job := &models.Job{
+		ID:          uuid.New(),
+		Kind:        kind,
+		Payload:     string(body),
+		Priority:    opts.Priority,
+		Status:      models.JobStatusQueued,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt.UTC(),
+		CreatedBy:   optionalString(opts.CreatedBy),
+		UniqueKey:   optionalString(opts.UniqueKey),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = models.DefaultJobMaxAttempts
+	}
+	if opts.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	if config.PostgresDB == nil {
+		// Without Postgres work still runs, but only on this instance
+		return job, q.runLocal(job)
+	}
+	if !config.PostgresAvailable() {
+		// Running the job here could duplicate work another instance queued
+		// before the outage, so it is rejected until Postgres is back
+		return nil, fmt.Errorf("failed to queue job: %w: %s", config.ErrStoreUnavailable, config.StorePostgres)
+	}
+
+	result := config.GetDBWithContext(ctx).Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "unique_key"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "unique_key IS NOT NULL AND status IN ('queued', 'running')"}}},
+		DoNothing:   true,
+	}).Create(job)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to queue job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrJobDuplicate
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// runLocal runs a job in the background of this instance when Postgres is
+// not configured, retrying it like a worker would until the instance stops
+func (q *JobQueue) runLocal(job *models.Job) error {
+	handler, ok := q.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for job kind %q", job.Kind)
+	}
+	key := ""
+	if job.UniqueKey != nil {
+		key = *job.UniqueKey
+		q.mu.Lock()
+		if q.active[key] {
+			q.mu.Unlock()
+			return ErrJobDuplicate
+		}
+		q.active[key] = true
+		q.mu.Unlock()
+	}
+
+	q.running.Add(1)
+	q.inFlight.Add(1)
+	go func() {
+		defer q.running.Done()
+		defer q.inFlight.Add(-1)
+		defer func() {
+			q.mu.Lock()
+			delete(q.active, key)
+			q.mu.Unlock()
+		}()
+		for {
+			if wait := time.Until(job.RunAt); wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-q.ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+			}
+			job.Attempts++
+			err := handler(q.ctx, job)
+			var deferral *jobDeferral
+			switch {
+			case err == nil:
+				return
+			case q.ctx.Err() != nil:
+				jobLog.Warn("Job lost on shutdown: no jobs table without Postgres", "job_id", job.ID.String(), "kind", job.Kind)
+				return
+			case errors.As(err, &deferral):
+				job.Attempts--
+				job.RunAt = time.Now().Add(deferral.after)
+			case job.Attempts >= job.MaxAttempts:
+				jobLog.Error("Job failed", "job_id", job.ID.String(), "kind", job.Kind, "attempts", job.Attempts, logging.FieldError, err)
+				return
+			default:
+				job.RunAt = time.Now().Add(models.JobRetryDelay(job.Attempts))
+			}
+		}
+	}()
+	return nil
+}
+
+// Start runs workers that claim and run due jobs until ctx is cancelled.
+// Jobs queued without Postgres also stop with ctx.
+func (q *JobQueue) Start(ctx context.Context, workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	q.mu.Lock()
+	q.ctx, q.workers = ctx, workers
+	q.mu.Unlock()
+	if config.PostgresDB == nil {
+		jobLog.Warn("Supabase not connected: background jobs run in-process and do not survive restarts")
+		return
+	}
+	q.startWorkers()
+	jobLog.Info("Job queue started", "workers", workers, "instance", q.instance)
+}
+
+// Restart stops the workers polling for jobs and starts new ones, e.g. once
+// Supabase is reachable again after the instance started without it. Jobs
+// being run keep running. It returns the number of workers started.
+func (q *JobQueue) Restart() (int, error) {
+	q.mu.Lock()
+	workers := q.workers
+	q.mu.Unlock()
+	if workers == 0 {
+		return 0, errors.New("job queue was not started")
+	}
+	if config.PostgresDB == nil {
+		return 0, fmt.Errorf("%w: %s", config.ErrStoreUnavailable, config.StorePostgres)
+	}
+	q.startWorkers()
+	jobLog.Info("Job queue restarted", "workers", workers, "instance", q.instance)
+	return workers, nil
+}
+
+// startWorkers replaces the polling workers. They stop polling when Start's
+// ctx is cancelled or the next startWorkers, but the jobs they claimed run
+// with Start's ctx so a restart does not interrupt them.
+func (q *JobQueue) startWorkers() {
+	q.mu.Lock()
+	if q.stopWorkers != nil {
+		q.stopWorkers()
+	}
+	parent, workers := q.ctx, q.workers
+	ctx, stop := context.WithCancel(parent)
+	q.stopWorkers = stop
+	q.mu.Unlock()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			ticker := time.NewTicker(jobPollInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				case <-q.wake:
+				}
+				// Run due jobs back to back before waiting again; Postgres
+				// outages are waited out without polling
+				for ctx.Err() == nil && config.PostgresAvailable() {
+					job, err := q.claim(ctx)
+					if err != nil {
+						jobLog.Warn("Failed to claim a job", logging.FieldError, err)
+					}
+					if job == nil {
+						break
+					}
+					q.running.Add(1)
+					q.inFlight.Add(1)
+					q.run(parent, job)
+					q.inFlight.Add(-1)
+					q.running.Done()
+				}
+			}
+		}()
+	}
+}
+
+// RunningJobs returns how many jobs this instance is running, including
+// jobs waiting in-process for a retry while Postgres is down
+func (q *JobQueue) RunningJobs() int {
+	return int(q.inFlight.Load())
+}
+
+// Wait blocks until the jobs this instance runs have stopped and recorded
+// their outcome, or ctx expires. Start's ctx must be cancelled first. A job
+// still running when the instance exits is taken over by another instance
+// once its lock expires.
+func (q *JobQueue) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background jobs still running: %w", ctx.Err())
+	}
+}
+
+// claim locks the next due job for this instance: the highest priority
+// queued job whose time has come, or a running job whose instance stopped
+// renewing its lock. It returns nil when there is none.
+func (q *JobQueue) claim(ctx context.Context) (*models.Job, error) {
+	now := time.Now().UTC()
+	var job models.Job
+	err := config.GetDBWithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)",
+				models.JobStatusQueued, now, models.JobStatusRunning, now).
+			Order("priority DESC, run_at ASC").
+			Limit(1).
+			Find(&job).Error
+		if err != nil || job.ID == uuid.Nil {
+			return err
+		}
+
+		lockedUntil := now.Add(jobLease)
+		job.Status = models.JobStatusRunning
+		job.Attempts++
+		job.LockedBy = &q.instance
+		job.LockedUntil = &lockedUntil
+		job.UpdatedAt = now
+		return tx.Model(&job).
+			Select("status", "attempts", "locked_by", "locked_until", "updated_at").
+			Updates(&job).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", err)
+	}
+	if job.ID == uuid.Nil {
+		return nil, nil
+	}
+	return &job, nil
+}
+
+// run runs a claimed job while renewing its lock, then records the outcome
+func (q *JobQueue) run(ctx context.Context, job *models.Job) {
+	claimedAttempts := job.Attempts
+	handler, ok := q.handlers[job.Kind]
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler for job kind %q", job.Kind)
+		job.Attempts = job.MaxAttempts
+	} else {
+		stopRenewing := q.renewLock(ctx, job.ID)
+		err = handler(ctx, job)
+		stopRenewing()
+	}
+
+	now := time.Now().UTC()
+	job.UpdatedAt = now
+	job.LockedBy, job.LockedUntil = nil, nil
+	var deferral *jobDeferral
+	switch {
+	case err == nil:
+		job.Status = models.JobStatusSucceeded
+		job.FinishedAt = &now
+		job.LastError = nil
+	case ctx.Err() != nil:
+		// Shutting down: give the attempt back and let another instance run it
+		job.Status = models.JobStatusQueued
+		job.Attempts--
+		job.RunAt = now
+	case errors.As(err, &deferral):
+		job.Status = models.JobStatusQueued
+		job.Attempts--
+		job.RunAt = now.Add(deferral.after)
+		job.LastError = optionalString(truncateJobError(err))
+	case job.Attempts >= job.MaxAttempts:
+		job.Status = models.JobStatusFailed
+		job.FinishedAt = &now
+		job.LastError = optionalString(truncateJobError(err))
+		jobLog.Error("Job failed", "job_id", job.ID.String(), "kind", job.Kind, "attempts", job.Attempts, logging.FieldError, err)
+	default:
+		job.Status = models.JobStatusQueued
+		job.RunAt = now.Add(models.JobRetryDelay(job.Attempts))
+		job.LastError = optionalString(truncateJobError(err))
+	}
+
+	// Record the outcome even when ctx is cancelled by a shutdown, unless the
+	// lock expired and the job was claimed again meanwhile (by another
+	// instance, or by this one for a later attempt): that run owns the row now
+	saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result := config.GetDBWithContext(saveCtx).Model(job).
+		Where("locked_by = ? AND attempts = ?", q.instance, claimedAttempts).
+		Select("status", "attempts", "run_at", "locked_by", "locked_until", "last_error", "finished_at", "updated_at").
+		Updates(job)
+	switch {
+	case result.Error != nil:
+		jobLog.Warn("Failed to save job", "job_id", job.ID.String(), logging.FieldError, result.Error)
+	case result.RowsAffected == 0:
+		jobLog.Warn("Job lock was lost before the outcome was saved, outcome dropped",
+			"job_id", job.ID.String(), "kind", job.Kind, "job_status", job.Status)
+	}
+}
+
+// renewLock extends the lock of a running job until the returned function is called
+func (q *JobQueue) renewLock(ctx context.Context, id uuid.UUID) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(jobLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := config.GetDBWithContext(ctx).Model(&models.Job{}).
+					Where("id = ? AND locked_by = ?", id, q.instance).
+					Update("locked_until", time.Now().UTC().Add(jobLease)).Error
+				if err != nil {
+					jobLog.Warn("Failed to renew job lock", "job_id", id.String(), logging.FieldError, err)
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// List returns jobs, newest first
+func (q *JobQueue) List(ctx context.Context, filter JobFilter) ([]models.Job, error) {
+	if filter.Limit <= 0 || filter.Limit > maxJobListLimit {
+		filter.Limit = maxJobListLimit
+	}
+
+	query := config.GetDBWithContext(ctx).Order("created_at DESC").Limit(filter.Limit)
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var jobs []models.Job
+	if err := query.Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// Get returns one job
+func (q *JobQueue) Get(ctx context.Context, id string) (*models.Job, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrJobNotFound
+	}
+	var job models.Job
+	err := config.GetDBWithContext(ctx).First(&job, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch job: %w", err)
+	}
+	return &job, nil
+}
+
+// Retry queues a failed or cancelled job again with a fresh set of attempts
+func (q *JobQueue) Retry(ctx context.Context, id string) (*models.Job, error) {
+	job, err := q.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != models.JobStatusFailed && job.Status != models.JobStatusCancelled {
+		return nil, ErrJobState
+	}
+
+	now := time.Now().UTC()
+	job.Status = models.JobStatusQueued
+	job.Attempts = 0
+	job.RunAt = now
+	job.FinishedAt = nil
+	job.UpdatedAt = now
+	result := config.GetDBWithContext(ctx).Model(job).
+		Where("status IN ?", []string{models.JobStatusFailed, models.JobStatusCancelled}).
+		Select("status", "attempts", "run_at", "finished_at", "updated_at").
+		Updates(job)
+	if result.Error != nil {
+		// The unique index rejects a retry while the same work is queued again
+		return nil, fmt.Errorf("failed to requeue job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrJobState
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return job, nil
+}
+
+// Cancel cancels a queued job. Running jobs cannot be cancelled.
+func (q *JobQueue) Cancel(ctx context.Context, id string) (*models.Job, error) {
+	job, err := q.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	job.Status = models.JobStatusCancelled
+	job.FinishedAt = &now
+	job.UpdatedAt = now
+	result := config.GetDBWithContext(ctx).Model(job).
+		Where("status = ?", models.JobStatusQueued).
+		Select("status", "finished_at", "updated_at").
+		Updates(job)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel job: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrJobState
+	}
+	return job, nil
+}
+
+// truncateJobError returns the error message kept on a job
+func truncateJobError(err error) string {
+	message := err.Error()
+	if len(message) > maxJobError {
+		message = message[:maxJobError]
+	}
+	return message
+}
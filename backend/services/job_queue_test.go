@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestJobQueueRunsInProcessWithoutPostgres(t *testing.T) {
+	q := NewJobQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	q.Start(ctx, 1)
+
+	release := make(chan struct{})
+	var attempts []int
+	q.Handle("test", func(ctx context.Context, job *models.Job) error {
+		attempts = append(attempts, job.Attempts)
+		if len(attempts) == 1 {
+			// Deferred runs do not use an attempt
+			return DeferJob(0, errors.New("busy"))
+		}
+		<-release
+		return nil
+	})
+
+	if _, err := q.Enqueue(ctx, "test", map[string]string{"a": "b"}, JobOptions{UniqueKey: "only-one"}); err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+	if _, err := q.Enqueue(ctx, "test", nil, JobOptions{UniqueKey: "only-one"}); !errors.Is(err, ErrJobDuplicate) {
+		t.Errorf("second Enqueue() error = %v; want ErrJobDuplicate", err)
+	}
+	if _, err := q.Enqueue(ctx, "unknown", nil, JobOptions{}); err == nil {
+		t.Error("expected an error for a kind without handler")
+	}
+
+	close(release)
+	waitCtx, cancelWait := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelWait()
+	if err := q.Wait(waitCtx); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 1 {
+		t.Errorf("attempts = %v; want [1 1]", attempts)
+	}
+	if _, err := q.Enqueue(ctx, "test", nil, JobOptions{UniqueKey: "only-one"}); err != nil {
+		t.Errorf("Enqueue() after the job finished: %v", err)
+	}
+}
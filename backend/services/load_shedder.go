@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"runtime/debug"
+	"runtime/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+// shedLog logs load shedding transitions
+var shedLog = logging.Component("load_shedding")
+
+const (
+	// loadCheckInterval is how often memory and queue depths are sampled
+	loadCheckInterval = 2 * time.Second
+	// loadRecoveryRatio is the share of a threshold usage must fall below
+	// before shedding stops, so the instance does not flap at the threshold
+	loadRecoveryRatio = 0.9
+)
+
+// cgroupMemoryLimitFiles hold the container memory limit (cgroup v2, then v1)
+var cgroupMemoryLimitFiles = []string{
+	"/sys/fs/cgroup/memory.max",
+	"/sys/fs/cgroup/memory/memory.limit_in_bytes",
+}
+
+// LoadState is the pressure of the instance at the last check
+type LoadState struct {
+	Shedding         bool           `json:"shedding"`
+	Reason           string         `json:"reason,omitempty"`
+	Since            *time.Time     `json:"since,omitempty"` // When shedding started
+	MemoryBytes      uint64         `json:"memory_bytes"`    // Memory held by the Go runtime
+	MemoryLimitBytes uint64         `json:"memory_limit_bytes,omitempty"`
+	QueueDepths      map[string]int `json:"queue_depths"`
+	CheckedAt        time.Time      `json:"checked_at"`
+}
+
+// LoadShedder watches the memory usage and queue depths of the instance and
+// tells expensive routes to reject requests while either is above its
+// threshold (load_shedding.* settings), so big crawls do not get the
+// instance killed for running out of memory. Cheap reads keep working.
+type LoadShedder struct {
+	memoryLimit uint64 // 0 when the instance has no known limit
+	queues      map[string]func() int
+	readMemory  func() uint64
+
+	mu    sync.RWMutex
+	state LoadState
+}
+
+// NewLoadShedder creates a load shedder for the memory limit of the instance
+func NewLoadShedder() *LoadShedder {
+	limit := memoryLimit()
+	if limit == 0 {
+		shedLog.Warn("No memory limit found (GOMEMLIMIT or cgroup): load shedding only watches queue depths")
+	}
+	return &LoadShedder{
+		memoryLimit: limit,
+		queues:      make(map[string]func() int),
+		readMemory:  runtimeMemory,
+	}
+}
+
+// TrackQueue adds a queue whose depth counts towards
+// load_shedding.queue_depth. Queues must be tracked before StartMonitor.
+func (s *LoadShedder) TrackQueue(name string, depth func() int) {
+	s.queues[name] = depth
+}
+
+// StartMonitor samples the load every loadCheckInterval until ctx is cancelled
+func (s *LoadShedder) StartMonitor(ctx context.Context) {
+	s.Check()
+	go func() {
+		ticker := time.NewTicker(loadCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Check()
+			}
+		}
+	}()
+}
+
+// Check samples memory and queue depths, updates the state and returns it
+func (s *LoadShedder) Check() LoadState {
+	cfg := config.Runtime()
+	now := time.Now().UTC()
+	state := LoadState{
+		MemoryBytes:      s.readMemory(),
+		MemoryLimitBytes: s.memoryLimit,
+		QueueDepths:      make(map[string]int, len(s.queues)),
+		CheckedAt:        now,
+	}
+	depth := 0
+	for name, read := range s.queues {
+		state.QueueDepths[name] = read()
+		depth += state.QueueDepths[name]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	wasShedding := s.state.Shedding
+
+	// While shedding, usage must fall clearly below a threshold to stop
+	ratio := 1.0
+	if wasShedding {
+		ratio = loadRecoveryRatio
+	}
+	var reasons []string
+	if cfg.LoadShedMemoryPercent > 0 && s.memoryLimit > 0 {
+		threshold := float64(s.memoryLimit) * float64(cfg.LoadShedMemoryPercent) / 100
+		if float64(state.MemoryBytes) > threshold*ratio {
+			reasons = append(reasons, fmt.Sprintf("memory at %.0f%% of the limit",
+				float64(state.MemoryBytes)/float64(s.memoryLimit)*100))
+		}
+	}
+	if cfg.LoadShedQueueDepth > 0 && float64(depth) > float64(cfg.LoadShedQueueDepth)*ratio {
+		reasons = append(reasons, fmt.Sprintf("%d queued crawl symbols and jobs", depth))
+	}
+
+	state.Shedding = len(reasons) > 0
+	state.Reason = strings.Join(reasons, ", ")
+	switch {
+	case state.Shedding && !wasShedding:
+		state.Since = &now
+		shedLog.Warn("Load shedding started, expensive requests answer 503", "reason", state.Reason)
+	case state.Shedding:
+		state.Since = s.state.Since
+	case wasShedding:
+		shedLog.Info("Load shedding stopped", "after", now.Sub(*s.state.Since).Round(time.Second).String())
+	}
+	s.state = state
+	return state
+}
+
+// State returns the load at the last check
+func (s *LoadShedder) State() LoadState {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state
+}
+
+// Shedding reports whether expensive requests should be rejected, and why
+func (s *LoadShedder) Shedding() (bool, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.state.Shedding, s.state.Reason
+}
+
+// runtimeMemory returns the memory the Go runtime holds from the OS: what
+// counts against the container limit
+func runtimeMemory() uint64 {
+	samples := []metrics.Sample{
+		{Name: "/memory/classes/total:bytes"},
+		{Name: "/memory/classes/heap/released:bytes"},
+	}
+	metrics.Read(samples)
+	total, released := samples[0].Value.Uint64(), samples[1].Value.Uint64()
+	if released > total {
+		return 0
+	}
+	return total - released
+}
+
+// memoryLimit returns GOMEMLIMIT when set, otherwise the container memory
+// limit, or 0 when neither is known
+func memoryLimit() uint64 {
+	if limit := debug.SetMemoryLimit(-1); limit > 0 && limit < math.MaxInt64 {
+		return uint64(limit)
+	}
+	for _, path := range cgroupMemoryLimitFiles {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if limit, ok := parseCgroupMemoryLimit(string(raw)); ok {
+			return limit
+		}
+	}
+	return 0
+}
+
+// parseCgroupMemoryLimit parses a cgroup memory limit file; "max" and the
+// near-infinite v1 default mean no limit
+func parseCgroupMemoryLimit(raw string) (uint64, bool) {
+	limit, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+	if err != nil || limit == 0 || limit >= 1<<60 {
+		return 0, false
+	}
+	return limit, true
+}
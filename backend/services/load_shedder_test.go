@@ -0,0 +1,112 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/config"
+)
+
+// useLoadShedding sets the load_shedding.* thresholds for the test
+func useLoadShedding(t *testing.T, memoryPercent, queueDepth string) {
+	t.Helper()
+	previous := config.Runtime()
+	t.Cleanup(func() { config.SetRuntime(previous) })
+	cfg, err := config.LoadRuntimeConfig(map[string]string{
+		"load_shedding.memory_percent": memoryPercent,
+		"load_shedding.queue_depth":    queueDepth,
+	})
+	if err != nil {
+		t.Fatalf("LoadRuntimeConfig() unexpected error: %v", err)
+	}
+	config.SetRuntime(cfg)
+}
+
+func TestLoadShedderMemory(t *testing.T) {
+	useLoadShedding(t, "80", "0")
+	memory := uint64(700)
+	shedder := &LoadShedder{
+		memoryLimit: 1000,
+		queues:      map[string]func() int{},
+		readMemory:  func() uint64 { return memory },
+	}
+
+	if state := shedder.Check(); state.Shedding {
+		t.Fatalf("Check() at 70%% = shedding (%s); want serving", state.Reason)
+	}
+
+	memory = 850
+	state := shedder.Check()
+	if !state.Shedding || state.Since == nil {
+		t.Fatalf("Check() at 85%% = %+v; want shedding", state)
+	}
+	if shedding, reason := shedder.Shedding(); !shedding || reason != "memory at 85% of the limit" {
+		t.Errorf("Shedding() = %v, %q; want true, memory at 85%% of the limit", shedding, reason)
+	}
+
+	// Shedding goes on until usage is below 90% of the threshold (72%)
+	memory = 750
+	if state := shedder.Check(); !state.Shedding {
+		t.Error("Check() at 75% after shedding = serving; want shedding until below 72%")
+	}
+	memory = 700
+	if state := shedder.Check(); state.Shedding || state.Since != nil {
+		t.Errorf("Check() at 70%% after shedding = %+v; want serving", state)
+	}
+}
+
+func TestLoadShedderQueueDepth(t *testing.T) {
+	useLoadShedding(t, "80", "100")
+	crawl, jobs := 60, 30
+	shedder := &LoadShedder{
+		queues: map[string]func() int{
+			"crawl": func() int { return crawl },
+			"jobs":  func() int { return jobs },
+		},
+		readMemory: func() uint64 { return 1 << 40 },
+	}
+
+	// Without a known memory limit only the queues count
+	state := shedder.Check()
+	if state.Shedding {
+		t.Fatalf("Check() with 90 queued = shedding (%s); want serving", state.Reason)
+	}
+	if state.QueueDepths["crawl"] != 60 || state.QueueDepths["jobs"] != 30 {
+		t.Errorf("QueueDepths = %v; want crawl 60, jobs 30", state.QueueDepths)
+	}
+
+	crawl = 80
+	if state := shedder.Check(); !state.Shedding || state.Reason != "110 queued crawl symbols and jobs" {
+		t.Errorf("Check() with 110 queued = %+v; want shedding", state)
+	}
+}
+
+func TestLoadShedderDisabled(t *testing.T) {
+	useLoadShedding(t, "0", "0")
+	shedder := &LoadShedder{
+		memoryLimit: 1000,
+		queues:      map[string]func() int{"crawl": func() int { return 1 << 20 }},
+		readMemory:  func() uint64 { return 999 },
+	}
+	if state := shedder.Check(); state.Shedding {
+		t.Errorf("Check() with thresholds disabled = shedding (%s)", state.Reason)
+	}
+}
+
+func TestParseCgroupMemoryLimit(t *testing.T) {
+	tests := []struct {
+		raw   string
+		limit uint64
+		ok    bool
+	}{
+		{"536870912\n", 536870912, true},
+		{"max\n", 0, false},
+		{"9223372036854771712\n", 0, false}, // cgroup v1 without a limit
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		limit, ok := parseCgroupMemoryLimit(tt.raw)
+		if limit != tt.limit || ok != tt.ok {
+			t.Errorf("parseCgroupMemoryLimit(%q) = %d, %v; want %d, %v", tt.raw, limit, ok, tt.limit, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// loginFailureTTL is how long failed logins are remembered for throttling
+const loginFailureTTL = time.Hour
+
+// Login channels recorded in the audit log
+const (
+	LoginChannelDashboard = "dashboard" // POST /admin/login
+	LoginChannelToken     = "token"     // POST /api/auth/token
+)
+
+// LoginThrottledError is returned when a login is attempted before the wait
+// imposed by earlier failures has passed
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry in %s", e.RetryAfter.Round(time.Second))
+}
+
+// LoginAttempt is one admin login request
+type LoginAttempt struct {
+	Identifier string // Username or email
+	Password   string
+	IP         string
+	UserAgent  string
+	Channel    string // One of the LoginChannel* values
+}
+
+// loginFailures tracks recent failures for one username or IP
+type loginFailures struct {
+	count       int
+	lastFailure time.Time
+}
+
+// LoginService authenticates admins with brute-force protection: failures
+// are tracked per username and per IP with exponentially increasing waits,
+// accounts are locked after login.max_failures consecutive failures, and
+// every attempt is written to the audit log. Waits are tracked per instance;
+// lockouts are stored in the database and apply everywhere.
+type LoginService struct {
+	authService  *AuthService
+	auditService *AuditService
+
+	mu        sync.Mutex
+	failures  map[string]*loginFailures
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewLoginService creates a new LoginService instance
+func NewLoginService(authService *AuthService, auditService *AuditService) *LoginService {
+	return &LoginService{
+		authService:  authService,
+		auditService: auditService,
+		failures:     make(map[string]*loginFailures),
+		now:          time.Now,
+	}
+}
+
+// Login verifies an admin's credentials. Besides the errors of
+// AuthService.Authenticate it returns *LoginThrottledError while a wait is in
+// effect for the username or IP.
+func (s *LoginService) Login(ctx context.Context, attempt LoginAttempt) (*models.AdminUser, error) {
+	attempt.Identifier = strings.TrimSpace(attempt.Identifier)
+	keys := loginThrottleKeys(attempt)
+	audit := AuditEntry{
+		Actor:     attempt.Identifier,
+		Action:    models.AuditActionLogin,
+		IP:        attempt.IP,
+		UserAgent: attempt.UserAgent,
+		Details:   map[string]interface{}{"channel": attempt.Channel},
+	}
+
+	if wait := s.retryAfter(keys); wait > 0 {
+		audit.Outcome = models.AuditOutcomeThrottled
+		audit.Details["retry_after_seconds"] = math.Ceil(wait.Seconds())
+		s.auditService.Record(ctx, audit)
+		return nil, &LoginThrottledError{RetryAfter: wait}
+	}
+
+	adminUser, err := s.authService.Authenticate(attempt.Identifier, attempt.Password)
+	switch {
+	case err == nil:
+		s.clearFailures(keys)
+		if recordErr := s.authService.RecordLogin(adminUser, attempt.IP, attempt.UserAgent, attempt.Channel); recordErr != nil {
+			logging.FromContext(ctx).Warn("Failed to record login attempt", logging.FieldError, recordErr)
+		}
+		audit.Outcome = models.AuditOutcomeSuccess
+		audit.EntityType, audit.EntityID = "admin_user", adminUser.ID.String()
+		s.auditService.Record(ctx, audit)
+		return adminUser, nil
+
+	case errors.Is(err, ErrAccountLocked):
+		audit.Outcome = models.AuditOutcomeLocked
+		s.auditService.Record(ctx, audit)
+		return nil, err
+
+	case errors.Is(err, ErrInvalidCredentials):
+		s.recordFailure(keys)
+		audit.Outcome = models.AuditOutcomeFailure
+
+		runtime := config.Runtime()
+		failed, recordErr := s.authService.RecordLoginFailure(attempt.Identifier, runtime.LoginMaxFailures, runtime.LoginLockoutDuration)
+		if recordErr != nil {
+			logging.FromContext(ctx).Warn("Failed to record login attempt", logging.FieldError, recordErr)
+		}
+		if failed != nil {
+			audit.EntityType, audit.EntityID = "admin_user", failed.ID.String()
+			audit.Details["failed_attempts"] = failed.FailedLoginAttempts
+			if failed.Locked() && failed.FailedLoginAttempts == runtime.LoginMaxFailures {
+				audit.Outcome = models.AuditOutcomeLocked
+				logging.FromContext(ctx).Warn("Admin account locked after failed logins", "admin_user_id", failed.ID.String(),
+					"failed_attempts", failed.FailedLoginAttempts, "lockout", runtime.LoginLockoutDuration.String(), "ip", attempt.IP)
+			}
+		}
+		s.auditService.Record(ctx, audit)
+		return nil, err
+	}
+
+	return nil, err
+}
+
+// Unlock clears an admin's lockout and the throttling state of their
+// username and email, recording the action in the audit log
+func (s *LoginService) Unlock(ctx context.Context, id, actor, ip string) (*models.AdminUser, error) {
+	adminUser, err := s.authService.UnlockAdmin(id)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{"user:" + strings.ToLower(adminUser.Email)}
+	if adminUser.Username != nil {
+		keys = append(keys, "user:"+strings.ToLower(*adminUser.Username))
+	}
+	s.clearFailures(keys)
+
+	s.auditService.Record(ctx, AuditEntry{
+		Actor:      actor,
+		Action:     models.AuditActionAdminUnlock,
+		Outcome:    models.AuditOutcomeSuccess,
+		EntityType: "admin_user",
+		EntityID:   adminUser.ID.String(),
+		IP:         ip,
+	})
+	return adminUser, nil
+}
+
+// loginThrottleKeys returns the throttling keys of an attempt
+func loginThrottleKeys(attempt LoginAttempt) []string {
+	keys := make([]string, 0, 2)
+	if attempt.Identifier != "" {
+		keys = append(keys, "user:"+strings.ToLower(attempt.Identifier))
+	}
+	if attempt.IP != "" {
+		keys = append(keys, "ip:"+attempt.IP)
+	}
+	return keys
+}
+
+// retryAfter returns how long the attempt must still wait (0 when allowed)
+func (s *LoginService) retryAfter(keys []string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg := config.Runtime()
+	now := s.now()
+	var wait time.Duration
+	for _, key := range keys {
+		if f, ok := s.failures[key]; ok {
+			if remaining := f.lastFailure.Add(loginDelay(f.count, cfg.LoginDelayBase, cfg.LoginDelayMax)).Sub(now); remaining > wait {
+				wait = remaining
+			}
+		}
+	}
+	return wait
+}
+
+// recordFailure counts a failed attempt for each key
+func (s *LoginService) recordFailure(keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= time.Minute {
+		for key, f := range s.failures {
+			if now.Sub(f.lastFailure) >= loginFailureTTL {
+				delete(s.failures, key)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	for _, key := range keys {
+		f, ok := s.failures[key]
+		if !ok || now.Sub(f.lastFailure) >= loginFailureTTL {
+			f = &loginFailures{}
+			s.failures[key] = f
+		}
+		f.count++
+		f.lastFailure = now
+	}
+}
+
+// clearFailures forgets the failures of each key
+func (s *LoginService) clearFailures(keys []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.failures, key)
+	}
+}
+
+// loginDelay returns the wait imposed after the given number of consecutive
+// failures: base after the first, doubling with each further one, up to max
+func loginDelay(failures int, base, max time.Duration) time.Duration {
+	if failures <= 0 || base <= 0 {
+		return 0
+	}
+	delay := base
+	for i := 1; i < failures; i++ {
+		if delay >= max {
+			break
+		}
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
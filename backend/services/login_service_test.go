@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, 0},
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{9, 256 * time.Second},
+		{10, 5 * time.Minute},
+		{1000, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := loginDelay(tt.failures, time.Second, 5*time.Minute); got != tt.expected {
+			t.Errorf("loginDelay(%d) = %s; want %s", tt.failures, got, tt.expected)
+		}
+	}
+}
+
+func TestLoginServiceThrottle(t *testing.T) {
+	s := NewLoginService(nil, nil)
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return start }
+
+	attacker := loginThrottleKeys(LoginAttempt{Identifier: "Admin", IP: "10.0.0.1"})
+	otherIP := loginThrottleKeys(LoginAttempt{Identifier: "admin", IP: "10.0.0.2"})
+	otherUser := loginThrottleKeys(LoginAttempt{Identifier: "viewer", IP: "10.0.0.3"})
+
+	s.recordFailure(attacker)
+	s.recordFailure(attacker)
+	if wait := s.retryAfter(attacker); wait != 2*time.Second {
+		t.Errorf("retryAfter after 2 failures = %s; want 2s", wait)
+	}
+	if wait := s.retryAfter(otherIP); wait != 2*time.Second {
+		t.Errorf("retryAfter for same username from another IP = %s; want 2s", wait)
+	}
+	if wait := s.retryAfter(otherUser); wait != 0 {
+		t.Errorf("retryAfter for unrelated username and IP = %s; want 0", wait)
+	}
+
+	s.now = func() time.Time { return start.Add(2 * time.Second) }
+	if wait := s.retryAfter(attacker); wait != 0 {
+		t.Errorf("retryAfter once the delay passed = %s; want 0", wait)
+	}
+
+	s.clearFailures(attacker)
+	if wait := s.retryAfter(otherIP); wait != 0 {
+		t.Errorf("retryAfter after clearing = %s; want 0", wait)
+	}
+}
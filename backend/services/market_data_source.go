@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// ErrProviderThrottled is returned when a data provider rejects a request
+// for exceeding its rate limit (HTTP 429)
+var ErrProviderThrottled = errors.New("data provider is throttling requests")
+
+// MarketDataSource lists the symbols of one or more exchanges and fetches
+// their daily prices. Each registered exchange names the source that serves
+// it, so supporting a new market means registering its Exchange rules and a
+// MarketDataSource; the crawler core stays unchanged.
+type MarketDataSource interface {
+	// Name is the source name referenced by models.Exchange.Source
+	Name() string
+	// FetchSymbols returns the listed symbols of the given exchanges
+	FetchSymbols(exchanges []string) ([]models.Stock, error)
+	// FetchPrices returns the recent daily candles of a symbol
+	FetchPrices(stock models.Stock) ([]models.CandleData, error)
+}
+
+// InstrumentLister is implemented by data sources that list instruments
+// other than stocks, such as ETFs and covered warrants
+type InstrumentLister interface {
+	// FetchInstruments returns the listed symbols of the given exchanges and
+	// instrument types (models.InstrumentTypes)
+	FetchInstruments(exchanges, types []string) ([]models.Stock, error)
+}
+
+// fetchInstruments returns the listed symbols of types on exchanges from
+// source. A source that only lists stocks serves them when types include
+// stocks, and nothing otherwise.
+func fetchInstruments(source MarketDataSource, exchanges, types []string) ([]models.Stock, error) {
+	if lister, ok := source.(InstrumentLister); ok {
+		return lister.FetchInstruments(exchanges, types)
+	}
+	for _, instrumentType := range types {
+		if instrumentType == models.InstrumentStock {
+			return source.FetchSymbols(exchanges)
+		}
+	}
+	return nil, nil
+}
+
+// RecentPriceFetcher is implemented by data sources that can fetch only the
+// newest candles of a symbol, which is all a daily refresh needs
+type RecentPriceFetcher interface {
+	// FetchRecentPrices returns the candles of the last sessions of a symbol
+	FetchRecentPrices(stock models.Stock, sessions int) ([]models.CandleData, error)
+}
+
+// IndexPriceFetcher is implemented by data sources that publish the daily
+// levels of their exchanges' market indexes (models.Exchange.Indexes)
+type IndexPriceFetcher interface {
+	// FetchIndexPrices returns the candles of the last sessions of an index
+	FetchIndexPrices(code string, sessions int) ([]models.CandleData, error)
+}
+
+// SectorClassifier is implemented by data sources that publish an industry
+// classification of their symbols
+type SectorClassifier interface {
+	// FetchSectors returns the sector of each classified symbol, by code
+	FetchSectors() (map[string]string, error)
+}
+
+// IndustryClassifier is implemented by data sources that publish the full
+// ICB classification of their symbols, industry and sector code included.
+// The crawler prefers it to SectorClassifier.
+type IndustryClassifier interface {
+	// FetchClassification returns the classification of each classified
+	// symbol, by code
+	FetchClassification() (map[string]models.IndustryClassification, error)
+}
+
+// ShareCountFetcher is implemented by data sources that publish the listed
+// share count of their symbols
+type ShareCountFetcher interface {
+	// FetchSharesOutstanding returns the shares outstanding of each symbol
+	// publishing it, by code
+	FetchSharesOutstanding() (map[string]int64, error)
+}
+
+// FuturesSource is implemented by data sources that publish the futures
+// contracts of models.DerivativesExchange
+type FuturesSource interface {
+	// FetchFuturesContracts returns the listed contracts on underlying (e.g.
+	// VN30)
+	FetchFuturesContracts(underlying string) ([]models.FuturesContract, error)
+	// FetchFuturesPrices returns the candles of the last sessions of a
+	// contract, open interest included
+	FetchFuturesPrices(code string, sessions int) ([]models.FuturesCandle, error)
+}
+
+// QuoteStreamer is implemented by data sources that push trades as they
+// happen, which the realtime quote feed (REALTIME_SOURCE) relays
+type QuoteStreamer interface {
+	// StreamQuotes passes the session candle of a symbol of codes (none:
+	// every symbol) to emit after each of its trades, until ctx is cancelled
+	// (nil) or the stream fails
+	StreamQuotes(ctx context.Context, codes []string, emit func(code string, candle models.CandleData)) error
+}
+
+// CredentialLookup returns one of the source's provider credentials by name,
+// or "" when it is not set
+type CredentialLookup func(ctx context.Context, name string) (string, error)
+
+// CredentialedSource is implemented by data sources that authenticate with
+// provider credentials. The lookup is called per request, so a rotated
+// credential is used without a restart.
+type CredentialedSource interface {
+	UseCredentials(lookup CredentialLookup)
+}
+
+// ProviderHealthChecker is implemented by data sources that can verify their
+// connectivity and credentials with a cheap request
+type ProviderHealthChecker interface {
+	HealthCheck(ctx context.Context) error
+}
+
+var (
+	dataSourcesMu sync.RWMutex
+	dataSources   = map[string]MarketDataSource{}
+)
+
+func init() {
+	RegisterMarketDataSource(NewVNDirectSource())
+	RegisterMarketDataSource(NewSSISource())
+}
+
+// RegisterMarketDataSource adds or replaces a data source by name
+func RegisterMarketDataSource(source MarketDataSource) {
+	dataSourcesMu.Lock()
+	defer dataSourcesMu.Unlock()
+	dataSources[source.Name()] = source
+}
+
+// MarketDataSources returns the registered data sources in name order
+func MarketDataSources() []MarketDataSource {
+	dataSourcesMu.RLock()
+	defer dataSourcesMu.RUnlock()
+	sources := make([]MarketDataSource, 0, len(dataSources))
+	for _, source := range dataSources {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name() < sources[j].Name() })
+	return sources
+}
+
+// LookupMarketDataSource returns a registered data source by name
+func LookupMarketDataSource(name string) (MarketDataSource, bool) {
+	dataSourcesMu.RLock()
+	defer dataSourcesMu.RUnlock()
+	source, ok := dataSources[name]
+	return source, ok
+}
+
+// MarketDataSourceFor returns the data source serving an exchange:
+// crawler.sources when it names one, else the exchange's own
+func MarketDataSourceFor(exchangeCode string) (MarketDataSource, error) {
+	exchange, ok := models.LookupExchange(exchangeCode)
+	if !ok {
+		return nil, fmt.Errorf("unknown exchange %q", exchangeCode)
+	}
+	name := exchange.Source
+	if configured, ok := config.Runtime().CrawlerExchangeSources[exchange.Code]; ok {
+		name = configured
+	}
+
+	dataSourcesMu.RLock()
+	defer dataSourcesMu.RUnlock()
+	source, ok := dataSources[name]
+	if !ok {
+		return nil, fmt.Errorf("exchange %s uses unregistered data source %q", exchange.Code, name)
+	}
+	return source, nil
+}
+
+// sourceExchanges is the set of exchanges one data source is asked for
+type sourceExchanges struct {
+	source    MarketDataSource
+	exchanges []string
+}
+
+// groupExchangesBySource groups exchanges by the data source serving them,
+// in source name order
+func groupExchangesBySource(exchangeCodes []string) ([]sourceExchanges, error) {
+	byName := make(map[string]*sourceExchanges)
+	for _, code := range exchangeCodes {
+		source, err := MarketDataSourceFor(code)
+		if err != nil {
+			return nil, err
+		}
+		group, ok := byName[source.Name()]
+		if !ok {
+			group = &sourceExchanges{source: source}
+			byName[source.Name()] = group
+		}
+		group.exchanges = append(group.exchanges, code)
+	}
+
+	groups := make([]sourceExchanges, 0, len(byName))
+	for _, group := range byName {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].source.Name() < groups[j].source.Name() })
+	return groups, nil
+}
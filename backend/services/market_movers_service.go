@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+const (
+	// defaultMoversLimit and maxMoversLimit bound the symbols per ranking
+	defaultMoversLimit = 10
+	maxMoversLimit     = 50
+	// moversBatch caps the symbols whose candles are read at once
+	moversBatch = 200
+)
+
+// MoversQuery selects the movers of a trading day
+type MoversQuery struct {
+	Date      string   // YYYY-MM-DD; empty for the newest day with candles
+	By        string   // models.MoversByChange (default) or models.MoversByVolume
+	Exchanges []string // Empty for every exchange
+	Limit     int
+}
+
+// ParseMoversQuery reads a movers query from the date, by, exchange (one or
+// more comma-separated) and limit query parameters
+func ParseMoversQuery(query func(string) string) (MoversQuery, error) {
+	q := MoversQuery{Date: query("date"), By: query("by"), Limit: defaultMoversLimit}
+	if q.Date != "" {
+		if _, err := time.Parse("2006-01-02", q.Date); err != nil {
+			return q, fmt.Errorf("date: expected YYYY-MM-DD")
+		}
+	}
+	switch q.By {
+	case "":
+		q.By = models.MoversByChange
+	case models.MoversByChange, models.MoversByVolume:
+	default:
+		return q, fmt.Errorf("by: expected %s or %s", models.MoversByChange, models.MoversByVolume)
+	}
+	for _, code := range strings.Split(query("exchange"), ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code == "" {
+			continue
+		}
+		if _, ok := models.LookupExchange(code); !ok {
+			return q, fmt.Errorf("exchange: unknown exchange %q", code)
+		}
+		q.Exchanges = append(q.Exchanges, code)
+	}
+	if raw := query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxMoversLimit {
+			return q, fmt.Errorf("limit: expected 1 to %d", maxMoversLimit)
+		}
+		q.Limit = limit
+	}
+	return q, nil
+}
+
+// MarketMoversService ranks the gainers, losers and most traded symbols of
+// a trading day from the stored candles
+type MarketMoversService struct {
+	stockService PriceStore
+	readCache    *cache.Cache // Shared with the stock service
+}
+
+// NewMarketMoversService creates a new MarketMoversService instance
+func NewMarketMoversService(stockService *StockService) *MarketMoversService {
+	return &MarketMoversService{
+		stockService: stockService,
+		readCache:    stockService.readCache,
+	}
+}
+
+// Movers returns the movers of q. Results are cached with the candles, so a
+// crawl run bringing new candles drops them.
+func (s *MarketMoversService) Movers(ctx context.Context, q MoversQuery) (*models.MarketMovers, error) {
+	key := fmt.Sprintf("movers:%s:%s:%s:%d", q.Date, q.By, strings.Join(q.Exchanges, ","), q.Limit)
+	return cache.Fetch(ctx, s.readCache, cache.NamespacePrices, key, func(ctx context.Context) (*models.MarketMovers, error) {
+		return s.compute(ctx, q)
+	})
+}
+
+// compute ranks the movers of q from the last two candles of every stock of
+// the selected exchanges as of the date
+func (s *MarketMoversService) compute(ctx context.Context, q MoversQuery) (*models.MarketMovers, error) {
+	stocks, err := s.stockService.GetStockMetadata(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	exchanges := make(map[string]string, len(stocks.Stocks))
+	codes := make([]string, 0, len(stocks.Stocks))
+	for _, stock := range stocks.Stocks {
+		if len(q.Exchanges) > 0 && !containsFold(q.Exchanges, stock.Exchange) {
+			continue
+		}
+		exchanges[stock.Code] = stock.Exchange
+		codes = append(codes, stock.Code)
+	}
+	sort.Strings(codes)
+
+	asOf := q.Date
+	if asOf == "" {
+		asOf = markettime.Today()
+	}
+	candles := make(map[string][]models.CandleData, len(codes))
+	for start := 0; start < len(codes); start += moversBatch {
+		end := start + moversBatch
+		if end > len(codes) {
+			end = len(codes)
+		}
+		batch, err := s.stockService.CandlesAsOf(ctx, codes[start:end], asOf, 2)
+		if err != nil {
+			return nil, err
+		}
+		for code, history := range batch {
+			candles[code] = history
+		}
+	}
+
+	date := q.Date
+	if date == "" {
+		for _, history := range candles {
+			if n := len(history); n > 0 && history[n-1].D > date {
+				date = history[n-1].D
+			}
+		}
+	}
+	movers := models.ComputeMarketMovers(date, q.By, exchanges, candles, q.Limit)
+	movers.Exchanges = q.Exchanges
+	return &movers, nil
+}
+
+// containsFold reports whether values holds value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
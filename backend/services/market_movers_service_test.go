@@ -0,0 +1,34 @@
+package services
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestParseMoversQuery(t *testing.T) {
+	q, err := ParseMoversQuery(url.Values{}.Get)
+	if err != nil || q.By != models.MoversByChange || q.Limit != defaultMoversLimit || q.Date != "" || q.Exchanges != nil {
+		t.Errorf("ParseMoversQuery() = %+v, %v; want the latest day by change", q, err)
+	}
+
+	q, err = ParseMoversQuery(url.Values{"date": {"2026-03-03"}, "by": {"volume"}, "exchange": {"hose, hnx"}, "limit": {"5"}}.Get)
+	want := MoversQuery{Date: "2026-03-03", By: models.MoversByVolume, Exchanges: []string{"HOSE", "HNX"}, Limit: 5}
+	if err != nil || !reflect.DeepEqual(q, want) {
+		t.Errorf("ParseMoversQuery() = %+v, %v; want %+v", q, err, want)
+	}
+
+	for _, bad := range []url.Values{
+		{"date": {"03/03/2026"}},
+		{"by": {"value"}},
+		{"exchange": {"NYSE"}},
+		{"limit": {"0"}},
+		{"limit": {"51"}},
+	} {
+		if _, err := ParseMoversQuery(bad.Get); err == nil {
+			t.Errorf("ParseMoversQuery(%v) succeeded; want error", bad)
+		}
+	}
+}
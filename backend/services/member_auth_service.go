@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// supabaseAudience is the "aud" claim of access tokens issued to signed-in users
+const supabaseAudience = "authenticated"
+
+// MemberClaims are the Supabase access token claims used by the backend
+type MemberClaims struct {
+	Subject     string `json:"sub"` // Profile ID (auth.users.id)
+	Email       string `json:"email"`
+	Audience    string `json:"aud"`
+	ExpiresAt   int64  `json:"exp"`
+	AppMetadata struct {
+		Role       string `json:"role"`
+		Membership string `json:"membership"`
+	} `json:"app_metadata"` // Custom claims injected by custom_access_token_hook
+}
+
+// MemberAuthService verifies access tokens issued by Supabase Auth to members
+type MemberAuthService struct {
+	jwtSecret []byte
+	profiles  ProfileStatus
+	now       func() time.Time
+}
+
+// NewMemberAuthService creates a MemberAuthService for the given Supabase JWT
+// secret, checking members against profiles
+func NewMemberAuthService(jwtSecret []byte, profiles ProfileStatus) *MemberAuthService {
+	return &MemberAuthService{jwtSecret: jwtSecret, profiles: profiles, now: time.Now}
+}
+
+// NewMemberAuthServiceFromEnv creates a MemberAuthService configured from
+// SUPABASE_JWT_SECRET (Project Settings → API → JWT Secret)
+func NewMemberAuthServiceFromEnv(profiles ProfileStatus) (*MemberAuthService, error) {
+	secret := os.Getenv("SUPABASE_JWT_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("SUPABASE_JWT_SECRET environment variable not set")
+	}
+	return NewMemberAuthService([]byte(secret), profiles), nil
+}
+
+// Verify checks a member's Supabase access token and returns its claims
+func (s *MemberAuthService) Verify(token string) (*MemberClaims, error) {
+	var claims MemberClaims
+	if err := verifyHS256(token, s.jwtSecret, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Audience != supabaseAudience || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+// CheckActive returns ErrProfileInactive when the profile of a verified
+// member was deactivated. A token outlives the deactivation until it
+// expires, so every request is checked. Members without a profile pass;
+// the routes needing one report it.
+func (s *MemberAuthService) CheckActive(ctx context.Context, claims *MemberClaims) error {
+	profileID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	active, err := s.profiles.ProfileActive(ctx, profileID)
+	if errors.Is(err, ErrProfileNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !active {
+		return ErrProfileInactive
+	}
+	return nil
+}
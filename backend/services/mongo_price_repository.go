@@ -0,0 +1,491 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoPriceRepository is the PriceRepository of the stock_prices
+// collection, which keeps the candles of each code and year in one
+// PriceBucket
+type MongoPriceRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoPriceRepository creates a new MongoPriceRepository
+func NewMongoPriceRepository() *MongoPriceRepository {
+	return &MongoPriceRepository{collection: config.GetCollection("stock_prices")}
+}
+
+// Store implements PriceRepository
+func (r *MongoPriceRepository) Store() string {
+	return config.StoreMongo
+}
+
+// SaveCandles implements PriceRepository: candles are merged into the
+// yearly bucket of their date, written only while the bucket is as read
+// (ErrBucketConflict otherwise) and in the encoding the runtime settings
+// select for new writes
+func (r *MongoPriceRepository) SaveCandles(ctx context.Context, code string, candles []models.CandleData) (string, error) {
+	// Group candles by year
+	bucketsByYear := make(map[int][]models.CandleData)
+	for _, candle := range candles {
+		year, err := models.GetYearFromDate(candle.D)
+		if err != nil {
+			crawlLog.Warn("Invalid candle date", logging.FieldSymbol, code, "date", candle.D)
+			continue
+		}
+		bucketsByYear[year] = append(bucketsByYear[year], candle)
+	}
+
+	// Save each year's data to its bucket
+	newest := ""
+	for year, yearCandles := range bucketsByYear {
+		bucketID := models.GenerateBucketID(code, year)
+
+		// Check if bucket exists
+		filter := bson.M{"_id": bucketID}
+		var existingBucket models.PriceBucket
+		err := r.collection.FindOne(ctx, filter).Decode(&existingBucket)
+
+		if err == mongo.ErrNoDocuments {
+			// Create new bucket
+			newBucket := models.PriceBucket{
+				ID:       bucketID,
+				Code:     code,
+				Year:     year,
+				History:  yearCandles,
+				Checksum: models.ComputeChecksum(yearCandles),
+				Encoding: config.Runtime().PriceStorageEncoding,
+			}
+
+			_, err := r.collection.InsertOne(ctx, newBucket)
+			if mongo.IsDuplicateKeyError(err) {
+				return "", fmt.Errorf("%w: %s was created by another writer", ErrBucketConflict, bucketID)
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to insert new bucket: %w", err)
+			}
+			newest = newestCandleDate(newest, yearCandles)
+		} else if err == nil {
+			// Bucket exists - merge data without duplicates
+			existingDates := make(map[string]bool)
+			for _, candle := range existingBucket.History {
+				existingDates[candle.D] = true
+			}
+
+			// Add only new candles
+			newCandles := make([]models.CandleData, 0)
+			for _, candle := range yearCandles {
+				if !existingDates[candle.D] {
+					newCandles = append(newCandles, candle)
+				}
+			}
+
+			newest = newestCandleDate(newest, newCandles)
+
+			// Re-sign the bucket only when its current content is intact, so
+			// that existing corruption stays detectable by verification
+			intact := existingBucket.Checksum == "" || existingBucket.Checksum == models.ComputeChecksum(existingBucket.History)
+			if len(newCandles) > 0 && !intact {
+				crawlLog.Warn("Checksum mismatch, leaving checksum unchanged for verification", logging.FieldSymbol, code, "bucket", bucketID)
+			}
+
+			// Writes only apply while the bucket is as read
+			guard := bucketUnchangedFilter(existingBucket)
+			encoding := config.Runtime().PriceStorageEncoding
+			if len(newCandles) > 0 && (existingBucket.Encoding != models.BucketEncodingPlain || encoding != models.BucketEncodingPlain) {
+				// Packed history cannot be appended to in place: rewrite the bucket
+				existingBucket.History = append(existingBucket.History, newCandles...)
+				existingBucket.Encoding = encoding
+				if intact {
+					existingBucket.Checksum = models.ComputeChecksum(existingBucket.History)
+				}
+				result, err := r.collection.ReplaceOne(ctx, guard, existingBucket)
+				if err != nil {
+					return "", fmt.Errorf("failed to rewrite bucket: %w", err)
+				}
+				if result.MatchedCount == 0 {
+					return "", fmt.Errorf("%w: %s", ErrBucketConflict, bucketID)
+				}
+			} else if len(newCandles) > 0 {
+				update := bson.M{
+					"$push": bson.M{
+						"history": bson.M{
+							"$each": newCandles,
+						},
+					},
+				}
+
+				if intact {
+					merged := append(existingBucket.History, newCandles...)
+					update["$set"] = bson.M{"checksum": models.ComputeChecksum(merged)}
+				}
+
+				result, err := r.collection.UpdateOne(ctx, guard, update)
+				if err != nil {
+					return "", fmt.Errorf("failed to update bucket: %w", err)
+				}
+				if result.MatchedCount == 0 {
+					return "", fmt.Errorf("%w: %s", ErrBucketConflict, bucketID)
+				}
+			}
+		} else {
+			return "", fmt.Errorf("failed to check bucket existence: %w", err)
+		}
+	}
+
+	return newest, nil
+}
+
+// SaveSessionCandle implements PriceRepository: the candle replaces the one
+// stored for its date in the yearly bucket, which is rewritten only while it
+// is as read (ErrBucketConflict otherwise). A date not stored yet is added
+// like SaveCandles adds it.
+func (r *MongoPriceRepository) SaveSessionCandle(ctx context.Context, code string, candle models.CandleData) (bool, error) {
+	year, err := models.GetYearFromDate(candle.D)
+	if err != nil {
+		return false, fmt.Errorf("invalid candle date %q: %w", candle.D, err)
+	}
+	bucketID := models.GenerateBucketID(code, year)
+
+	var bucket models.PriceBucket
+	err = r.collection.FindOne(ctx, bson.M{"_id": bucketID}).Decode(&bucket)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return false, fmt.Errorf("failed to check bucket existence: %w", err)
+	}
+	index := -1
+	if err == nil {
+		for i, stored := range bucket.History {
+			if stored.D == candle.D {
+				index = i
+				break
+			}
+		}
+	}
+	if index < 0 {
+		newest, err := r.SaveCandles(ctx, code, []models.CandleData{candle})
+		return newest != "", err
+	}
+	if bucket.History[index] == candle {
+		return false, nil
+	}
+
+	// As in SaveCandles, a bucket that fails verification keeps its checksum
+	intact := bucket.Checksum == "" || bucket.Checksum == models.ComputeChecksum(bucket.History)
+	if !intact {
+		crawlLog.Warn("Checksum mismatch, leaving checksum unchanged for verification", logging.FieldSymbol, code, "bucket", bucketID)
+	}
+	guard := bucketUnchangedFilter(bucket)
+	now := time.Now().UTC()
+	bucket.History[index] = candle
+	bucket.Encoding = config.Runtime().PriceStorageEncoding
+	bucket.UpdatedAt = &now
+	if intact {
+		bucket.Checksum = models.ComputeChecksum(bucket.History)
+	}
+	result, err := r.collection.ReplaceOne(ctx, guard, bucket)
+	if err != nil {
+		return false, fmt.Errorf("failed to rewrite bucket: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return false, fmt.Errorf("%w: %s", ErrBucketConflict, bucketID)
+	}
+	return false, nil
+}
+
+// LatestCandleDates implements PriceRepository: the newest date is read from
+// the buckets of the current and previous year
+func (r *MongoPriceRepository) LatestCandleDates(ctx context.Context) (map[string]string, error) {
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"year": bson.M{"$gte": time.Now().Year() - 1}}},
+		bson.M{"$project": bson.M{"code": 1, "last": bson.M{"$ifNull": bson.A{"$lastDate", bson.M{"$max": "$history.d"}}}}},
+		bson.M{"$group": bson.M{"_id": "$code", "last": bson.M{"$max": "$last"}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate latest candle dates: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Code string `bson:"_id"`
+		Last string `bson:"last"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode latest candle dates: %w", err)
+	}
+	latest := make(map[string]string, len(rows))
+	for _, row := range rows {
+		latest[row.Code] = row.Last
+	}
+	return latest, nil
+}
+
+// Candles implements PriceRepository, reading the whole buckets of the years
+// in range
+func (r *MongoPriceRepository) Candles(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error) {
+	return r.queryCandles(ctx, codes, from, to, false)
+}
+
+// CandlesFilteredInDB returns the same candles as Candles but applies the
+// date range in MongoDB, so plain buckets send only the candles in range
+// instead of their whole year
+func (r *MongoPriceRepository) CandlesFilteredInDB(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error) {
+	return r.queryCandles(ctx, codes, from, to, true)
+}
+
+// queryCandles reads the candles of codes between from and to. With
+// rangeInDB, plain buckets are trimmed to the date range by a projection;
+// columnar buckets are always decoded whole and trimmed here.
+func (r *MongoPriceRepository) queryCandles(ctx context.Context, codes []string, from, to time.Time, rangeInDB bool) ([]models.CandleData, error) {
+	priority, codeList := lineagePriority(codes)
+
+	filter := bson.M{
+		"code": bson.M{"$in": codeList},
+		"year": bson.M{"$gte": from.Year(), "$lte": to.Year()},
+	}
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+	opts := options.Find()
+	if rangeInDB {
+		opts.SetProjection(bson.M{
+			"code":   1,
+			"year":   1,
+			"enc":    1,
+			"packed": 1,
+			"history": bson.M{"$filter": bson.M{
+				"input": "$history",
+				"as":    "candle",
+				"cond": bson.M{"$and": bson.A{
+					bson.M{"$gte": bson.A{"$$candle.d", fromDate}},
+					bson.M{"$lte": bson.A{"$$candle.d", toDate}},
+				}},
+			}},
+		})
+	}
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price buckets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []models.PriceBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to decode price buckets: %w", err)
+	}
+	return mergeBucketCandles(buckets, priority, fromDate, toDate), nil
+}
+
+// StreamCandles implements PriceRepository, reading one year of buckets at a
+// time
+func (r *MongoPriceRepository) StreamCandles(ctx context.Context, codes []string, from, to time.Time, emit func(models.CandleData) error) (int, error) {
+	priority, codeList := lineagePriority(codes)
+
+	filter := bson.M{
+		"code": bson.M{"$in": codeList},
+		"year": bson.M{"$gte": from.Year(), "$lte": to.Year()},
+	}
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "year", Value: 1}}))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query price buckets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	emitted := 0
+	var year []models.PriceBucket
+	flush := func() error {
+		for _, candle := range mergeBucketCandles(year, priority, fromDate, toDate) {
+			if err := emit(candle); err != nil {
+				return err
+			}
+			emitted++
+		}
+		year = year[:0]
+		return nil
+	}
+	for cursor.Next(ctx) {
+		var bucket models.PriceBucket
+		if err := cursor.Decode(&bucket); err != nil {
+			return emitted, fmt.Errorf("failed to decode price bucket: %w", err)
+		}
+		if len(year) > 0 && year[0].Year != bucket.Year {
+			if err := flush(); err != nil {
+				return emitted, err
+			}
+		}
+		year = append(year, bucket)
+	}
+	if err := cursor.Err(); err != nil {
+		return emitted, fmt.Errorf("failed to read price buckets: %w", err)
+	}
+	return emitted, flush()
+}
+
+// mergeBucketCandles returns the candles of buckets between fromDate and
+// toDate ordered by date, leaving out those outside their code's lineage
+// windows. Where buckets of several codes of a lineage hold the same date,
+// the code ranked first in priority wins.
+func mergeBucketCandles(buckets []models.PriceBucket, priority map[string][]lineageSource, fromDate, toDate string) []models.CandleData {
+	byDate := make(map[string]models.CandleData)
+	sourceRank := make(map[string]int)
+	for _, bucket := range buckets {
+		for _, candle := range bucket.History {
+			if candle.D < fromDate || candle.D > toDate {
+				continue
+			}
+			rank, ok := lineageRank(priority, bucket.Code, candle.D)
+			if !ok {
+				continue
+			}
+			if existing, ok := sourceRank[candle.D]; ok && existing <= rank {
+				continue
+			}
+			byDate[candle.D] = candle
+			sourceRank[candle.D] = rank
+		}
+	}
+
+	candles := make([]models.CandleData, 0, len(byDate))
+	for _, candle := range byDate {
+		candles = append(candles, candle)
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].D < candles[j].D })
+	return candles
+}
+
+// CandlesTag implements PriceRepository with the checksum, candle count and
+// last in-place update of each yearly bucket in range, read without decoding
+// candles. Appends change the count; replacing the session candle changes
+// the checksum and, as a bucket failing verification keeps its checksum,
+// the update time. It returns "" when a bucket has no checksum yet.
+func (r *MongoPriceRepository) CandlesTag(ctx context.Context, codes []string, from, to time.Time) (string, error) {
+	_, codeList := lineagePriority(codes)
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{
+			"code": bson.M{"$in": codeList},
+			"year": bson.M{"$gte": from.Year(), "$lte": to.Year()},
+		}},
+		bson.M{"$project": bson.M{
+			"checksum":  1,
+			"updatedAt": 1,
+			"candles": bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$candles", 0}},
+				bson.M{"$size": bson.M{"$ifNull": bson.A{"$history", bson.A{}}}},
+			}},
+		}},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return "", fmt.Errorf("failed to query bucket checksums: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID        string    `bson:"_id"`
+		Checksum  string    `bson:"checksum"`
+		Candles   int       `bson:"candles"`
+		UpdatedAt time.Time `bson:"updatedAt"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return "", fmt.Errorf("failed to decode bucket checksums: %w", err)
+	}
+
+	parts := make([]string, 0, len(rows)+3)
+	parts = append(parts, lineageKey(codes), from.Format("2006-01-02"), to.Format("2006-01-02"))
+	for _, row := range rows {
+		if row.Checksum == "" {
+			return "", nil
+		}
+		parts = append(parts, fmt.Sprintf("%s:%s:%d:%d", row.ID, row.Checksum, row.Candles, row.UpdatedAt.UnixNano()))
+	}
+	return models.EntityTag(parts...), nil
+}
+
+// LatestCandles implements PriceRepository, reading this year's and last
+// year's buckets of all codes at once
+func (r *MongoPriceRepository) LatestCandles(ctx context.Context, codes []string, n int) (map[string][]models.CandleData, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"code": bson.M{"$in": codes},
+		"year": bson.M{"$gte": time.Now().Year() - 1},
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price buckets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []models.PriceBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to decode price buckets: %w", err)
+	}
+
+	byCode := make(map[string][]models.CandleData, len(codes))
+	for _, bucket := range buckets {
+		byCode[bucket.Code] = append(byCode[bucket.Code], bucket.History...)
+	}
+	for code, candles := range byCode {
+		sort.Slice(candles, func(i, j int) bool { return candles[i].D < candles[j].D })
+		if len(candles) > n {
+			candles = candles[len(candles)-n:]
+		}
+		byCode[code] = candles
+	}
+	return byCode, nil
+}
+
+// CandlesAsOf implements PriceRepository, reading the buckets of date's year
+// and the year before
+func (r *MongoPriceRepository) CandlesAsOf(ctx context.Context, codes []string, date string, n int) (map[string][]models.CandleData, error) {
+	year, err := models.GetYearFromDate(date)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"code": bson.M{"$in": codes},
+		"year": bson.M{"$gte": year - 1, "$lte": year},
+	}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price buckets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var buckets []models.PriceBucket
+	if err := cursor.All(ctx, &buckets); err != nil {
+		return nil, fmt.Errorf("failed to decode price buckets: %w", err)
+	}
+
+	byCode := make(map[string][]models.CandleData, len(codes))
+	for _, bucket := range buckets {
+		for _, candle := range bucket.History {
+			if candle.D <= date {
+				byCode[bucket.Code] = append(byCode[bucket.Code], candle)
+			}
+		}
+	}
+	for code, candles := range byCode {
+		sort.Slice(candles, func(i, j int) bool { return candles[i].D < candles[j].D })
+		if len(candles) > n {
+			candles = candles[len(candles)-n:]
+		}
+		byCode[code] = candles
+	}
+	return byCode, nil
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestMergeBucketCandles(t *testing.T) {
+	buckets := []models.PriceBucket{
+		{Code: "OLD", Year: 2024, History: []models.CandleData{{D: "2024-03-01", C: 9}, {D: "2024-03-04", C: 10}}},
+		{Code: "NEW", Year: 2024, History: []models.CandleData{{D: "2024-03-06", C: 12}, {D: "2024-03-04", C: 11}, {D: "2024-02-28", C: 8}}},
+	}
+	priority, _ := lineagePriority([]string{"NEW", "OLD"})
+
+	candles := mergeBucketCandles(buckets, priority, "2024-03-01", "2024-03-31")
+	if len(candles) != 3 {
+		t.Fatalf("mergeBucketCandles() = %v; want 3 candles in range", candles)
+	}
+	if candles[0].D != "2024-03-01" || candles[1].D != "2024-03-04" || candles[2].D != "2024-03-06" {
+		t.Errorf("mergeBucketCandles() = %v; want ordered by date", candles)
+	}
+	if candles[1].C != 11 {
+		t.Errorf("candle of 2024-03-04 = %v; want the current code's", candles[1])
+	}
+}
+
+func TestMergeBucketCandlesWindows(t *testing.T) {
+	// ABC was renamed to NEW on 2024-03-05 and the ABC ticker was later
+	// reused by another company, whose candles must not leak into NEW.
+	buckets := []models.PriceBucket{
+		{Code: "ABC", Year: 2024, History: []models.CandleData{{D: "2024-03-04", C: 10}, {D: "2024-03-06", C: 99}}},
+		{Code: "NEW", Year: 2024, History: []models.CandleData{{D: "2024-03-05", C: 11}, {D: "2024-03-06", C: 12}}},
+	}
+	priority, codes := lineagePriority([]string{"NEW@2024-03-05..", "ABC@..2024-03-05"})
+	if len(codes) != 2 {
+		t.Fatalf("lineagePriority() codes = %v; want NEW and ABC", codes)
+	}
+
+	candles := mergeBucketCandles(buckets, priority, "2024-03-01", "2024-03-31")
+	if len(candles) != 3 {
+		t.Fatalf("mergeBucketCandles() = %v; want 3 candles", candles)
+	}
+	if candles[0].C != 10 || candles[1].C != 11 || candles[2].C != 12 {
+		t.Errorf("mergeBucketCandles() = %v; want ABC before the rename and NEW after", candles)
+	}
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// movingAverageLog logs the moving averages computed after crawl runs
+var movingAverageLog = logging.Component("moving_averages")
+
+// movingAverageBatch caps the symbols whose candles are read at once
+const movingAverageBatch = 100
+
+// MovingAverageService maintains the moving_averages collection: the MA10,
+// MA20, MA50 and MA200 series of every symbol, so charts do not recompute
+// them from the candles on every request
+type MovingAverageService struct {
+	seriesCollection *mongo.Collection
+	stockService     PriceStore
+	readCache        *cache.Cache // Shared with the stock service
+}
+
+// NewMovingAverageService creates a new MovingAverageService instance
+func NewMovingAverageService(stockService *StockService) *MovingAverageService {
+	return &MovingAverageService{
+		seriesCollection: config.GetCollection("moving_averages"),
+		stockService:     stockService,
+		readCache:        stockService.readCache,
+	}
+}
+
+// ComputeRun rebuilds the series of the symbols that gained candles in a
+// finished crawl run. It is registered as a crawl run listener.
+func (s *MovingAverageService) ComputeRun(run *models.CrawlRun, newDates map[string]string) {
+	if len(newDates) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	codes := make([]string, 0, len(newDates))
+	for code := range newDates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	computed, err := s.Compute(ctx, codes)
+	s.readCache.Invalidate(ctx, cache.NamespaceIndicators)
+	if err != nil {
+		movingAverageLog.Warn("Failed to compute moving averages", "run_id", run.ID.Hex(), logging.FieldError, err)
+		return
+	}
+	movingAverageLog.Info("Moving averages computed", "symbols", computed)
+}
+
+// Compute rebuilds and stores the series of codes from their newest candles
+// and returns how many were stored
+func (s *MovingAverageService) Compute(ctx context.Context, codes []string) (int, error) {
+	updatedAt := primitive.NewDateTimeFromTime(time.Now())
+	computed := 0
+	for start := 0; start < len(codes); start += movingAverageBatch {
+		end := start + movingAverageBatch
+		if end > len(codes) {
+			end = len(codes)
+		}
+		batch := codes[start:end]
+		candles, err := s.stockService.LatestCandles(ctx, batch, models.MovingAverageSessions)
+		if err != nil {
+			return computed, err
+		}
+
+		writes := make([]mongo.WriteModel, 0, len(batch))
+		for _, code := range batch {
+			series := models.NewMovingAverageSeries(code, candles[code], updatedAt)
+			if series == nil {
+				continue
+			}
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": code}).
+				SetReplacement(series).
+				SetUpsert(true))
+		}
+		if len(writes) == 0 {
+			continue
+		}
+		if _, err := s.seriesCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return computed, fmt.Errorf("failed to save moving averages: %w", err)
+		}
+		computed += len(writes)
+	}
+	return computed, nil
+}
+
+// Get returns the stored series of a symbol, or nil when none is stored.
+// Results are cached in the indicators namespace.
+func (s *MovingAverageService) Get(ctx context.Context, code string) (*models.MovingAverageSeries, error) {
+	code = strings.ToUpper(code)
+	return cache.Fetch(ctx, s.readCache, cache.NamespaceIndicators, "moving-averages:"+code, func(ctx context.Context) (*models.MovingAverageSeries, error) {
+		var series models.MovingAverageSeries
+		err := s.seriesCollection.FindOne(ctx, bson.M{"_id": code}).Decode(&series)
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch moving averages: %w", err)
+		}
+		return &series, nil
+	})
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+)
+
+// notifyLog is the output of the log notification channel
+var notifyLog = logging.Component("notifications")
+
+// Notification severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+	SeverityResolved = "resolved"
+)
+
+// Notification is a message routed to one or more notification channels
+type Notification struct {
+	Title    string                 `json:"title"`
+	Message  string                 `json:"message"`
+	Severity string                 `json:"severity"`
+	Source   string                 `json:"source"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	SentAt   time.Time              `json:"sent_at"`
+}
+
+// Notifier delivers notifications to a single channel
+type Notifier interface {
+	// Name is the channel name referenced by alert rules (e.g. "log", "webhook")
+	Name() string
+	// Notify delivers the notification
+	Notify(ctx context.Context, n Notification) error
+}
+
+// MemberNotifier is implemented by channels that can also reach an
+// individual member (price alerts) rather than the operations team
+type MemberNotifier interface {
+	NotifyMember(ctx context.Context, profileID uuid.UUID, n Notification) error
+}
+
+// NotificationService routes notifications to the registered channels
+type NotificationService struct {
+	notifiers map[string]Notifier
+	jobs      *JobQueue // Delivers SendInBackground notifications with retries
+}
+
+// notificationJobPayload is the payload of a notification job
+type notificationJobPayload struct {
+	Channel      string       `json:"channel"`
+	Notification Notification `json:"notification"`
+}
+
+// NewNotificationService creates a NotificationService with the built-in
+// channels. The "log" channel is always available; other channels are
+// registered only when their configuration is present in the environment.
+func NewNotificationService() *NotificationService {
+	ns := &NotificationService{notifiers: make(map[string]Notifier)}
+	ns.Register(&LogNotifier{})
+
+	if url := os.Getenv("ALERT_WEBHOOK_URL"); url != "" {
+		ns.Register(NewWebhookNotifier(url))
+	}
+
+	return ns
+}
+
+// Register adds or replaces a notifier for its channel name
+func (ns *NotificationService) Register(n Notifier) {
+	ns.notifiers[n.Name()] = n
+}
+
+// Channels returns the names of all registered channels
+func (ns *NotificationService) Channels() []string {
+	names := make([]string, 0, len(ns.notifiers))
+	for name := range ns.notifiers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MemberChannels returns the names of the registered channels that can reach members
+func (ns *NotificationService) MemberChannels() []string {
+	names := make([]string, 0, len(ns.notifiers))
+	for name, notifier := range ns.notifiers {
+		if _, ok := notifier.(MemberNotifier); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Send delivers the notification to every listed channel. Unknown channels and
+// delivery failures are collected into the returned error; delivery to the
+// remaining channels continues regardless.
+func (ns *NotificationService) Send(ctx context.Context, channels []string, n Notification) error {
+	if n.SentAt.IsZero() {
+		n.SentAt = time.Now().UTC()
+	}
+
+	var failures []string
+	for _, channel := range channels {
+		notifier, ok := ns.notifiers[channel]
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s: channel not configured", channel))
+			continue
+		}
+		if err := notifier.Notify(ctx, n); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", channel, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("notification delivery failed: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// UseJobQueue delivers SendInBackground notifications through jobs, so a
+// channel outage is retried instead of dropping the notification
+func (ns *NotificationService) UseJobQueue(jobs *JobQueue) {
+	ns.jobs = jobs
+	jobs.Handle(models.JobKindNotification, func(ctx context.Context, job *models.Job) error {
+		var payload notificationJobPayload
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return fmt.Errorf("invalid notification job payload: %w", err)
+		}
+		return ns.Send(ctx, []string{payload.Channel}, payload.Notification)
+	})
+}
+
+// SendInBackground queues one notification job per channel, retried
+// independently. Without a job queue it sends right away like Send.
+func (ns *NotificationService) SendInBackground(ctx context.Context, channels []string, n Notification) error {
+	if ns.jobs == nil {
+		return ns.Send(ctx, channels, n)
+	}
+	if n.SentAt.IsZero() {
+		n.SentAt = time.Now().UTC()
+	}
+
+	var failures []string
+	for _, channel := range channels {
+		payload := notificationJobPayload{Channel: channel, Notification: n}
+		if _, err := ns.jobs.Enqueue(ctx, models.JobKindNotification, payload, JobOptions{Priority: models.JobPriorityLow}); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", channel, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to queue notification: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// SendToMember delivers the notification to a member on every listed
+// channel, collecting failures like Send
+func (ns *NotificationService) SendToMember(ctx context.Context, channels []string, profileID uuid.UUID, n Notification) error {
+	if n.SentAt.IsZero() {
+		n.SentAt = time.Now().UTC()
+	}
+
+	var failures []string
+	for _, channel := range channels {
+		notifier, ok := ns.notifiers[channel].(MemberNotifier)
+		if !ok {
+			failures = append(failures, fmt.Sprintf("%s: channel cannot reach members", channel))
+			continue
+		}
+		if err := notifier.NotifyMember(ctx, profileID, n); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", channel, err))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("notification delivery failed: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// LogNotifier writes notifications to the application log
+type LogNotifier struct{}
+
+// Name returns the channel name
+func (LogNotifier) Name() string { return "log" }
+
+// Notify logs the notification
+func (LogNotifier) Notify(ctx context.Context, n Notification) error {
+	notifyLog.Info(n.Title, "severity", n.Severity, "message", n.Message)
+	return nil
+}
+
+// NotifyMember logs the notification with the member it is meant for
+func (LogNotifier) NotifyMember(ctx context.Context, profileID uuid.UUID, n Notification) error {
+	notifyLog.Info(n.Title, "severity", n.Severity, "profile_id", profileID.String(), "message", n.Message)
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to a fixed URL
+type WebhookNotifier struct {
+	url    string
+	client *resty.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier for the given URL
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	client := newRestyClient()
+	client.SetTimeout(10 * time.Second)
+	client.SetRetryCount(2)
+	client.SetRetryWaitTime(time.Second)
+
+	return &WebhookNotifier{url: url, client: client}
+}
+
+// Name returns the channel name
+func (w *WebhookNotifier) Name() string { return "webhook" }
+
+// Notify posts the notification to the webhook URL
+func (w *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	resp, err := w.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(n).
+		Post(w.url)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	if resp.IsError() {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode())
+	}
+	return nil
+}
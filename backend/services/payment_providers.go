@@ -0,0 +1,189 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+const (
+	// PaymentProviderVNPay signs its IPN query with vnp_SecureHash
+	PaymentProviderVNPay = "vnpay"
+	// PaymentProviderMoMo signs its IPN body with a signature field
+	PaymentProviderMoMo = "momo"
+)
+
+// decodePaymentNotification verifies a webhook with the scheme of its
+// provider and returns the notification together with the raw payload to
+// store. VNPay and MoMo use their own signatures; every other provider uses
+// the X-Payment-Signature scheme of verifyPaymentSignature.
+func decodePaymentNotification(provider string, secret []byte, signature string, body []byte, query url.Values, now time.Time) (PaymentNotification, string, error) {
+	switch provider {
+	case PaymentProviderVNPay:
+		if err := verifyVNPaySignature(secret, query); err != nil {
+			return PaymentNotification{}, "", err
+		}
+		n, err := parseVNPayNotification(query)
+		return n, query.Encode(), err
+	case PaymentProviderMoMo:
+		n, err := parseMoMoNotification(secret, body)
+		return n, string(body), err
+	default:
+		if err := verifyPaymentSignature(secret, signature, body, now); err != nil {
+			return PaymentNotification{}, "", err
+		}
+		n, err := parsePaymentNotification(body)
+		return n, string(body), err
+	}
+}
+
+// verifyVNPaySignature checks vnp_SecureHash, the hex HMAC-SHA512 under the
+// merchant's hash secret of every other vnp_ parameter, sorted by name and
+// URL-encoded as key=value pairs joined by "&"
+func verifyVNPaySignature(secret []byte, query url.Values) error {
+	signature := query.Get("vnp_SecureHash")
+	if signature == "" {
+		return fmt.Errorf("%w: missing vnp_SecureHash", ErrInvalidPaymentSignature)
+	}
+	signed := url.Values{}
+	for key, values := range query {
+		if strings.HasPrefix(key, "vnp_") && key != "vnp_SecureHash" && key != "vnp_SecureHashType" && len(values) > 0 {
+			signed.Set(key, values[0])
+		}
+	}
+
+	mac := hmac.New(sha512.New, secret)
+	mac.Write([]byte(signed.Encode()))
+	actual, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac.Sum(nil), actual) {
+		return ErrInvalidPaymentSignature
+	}
+	return nil
+}
+
+// parseVNPayNotification maps a verified VNPay IPN to a notification.
+// vnp_TxnRef is the merchant's order reference, vnp_Amount is in đồng x 100
+// and vnp_OrderInfo carries "<profile_id> <months>" set at checkout.
+func parseVNPayNotification(query url.Values) (PaymentNotification, error) {
+	n := PaymentNotification{
+		TransactionID: query.Get("vnp_TxnRef"),
+		Currency:      "VND",
+	}
+	switch {
+	case query.Get("vnp_ResponseCode") == "00" && query.Get("vnp_TransactionStatus") == "00":
+		n.Status = models.PaymentStatusSucceeded
+	case query.Get("vnp_TransactionStatus") == "01":
+		n.Status = models.PaymentStatusPending
+	default:
+		n.Status = models.PaymentStatusFailed
+	}
+
+	amount, err := strconv.ParseInt(query.Get("vnp_Amount"), 10, 64)
+	if err != nil || amount%100 != 0 {
+		return n, fmt.Errorf("%w: malformed vnp_Amount", ErrInvalidPayment)
+	}
+	n.Amount = amount / 100
+
+	info := strings.Fields(query.Get("vnp_OrderInfo"))
+	if len(info) != 2 {
+		return n, fmt.Errorf("%w: vnp_OrderInfo must be \"<profile_id> <months>\"", ErrInvalidPayment)
+	}
+	n.ProfileID = info[0]
+	if n.Months, err = strconv.Atoi(info[1]); err != nil {
+		return n, fmt.Errorf("%w: malformed months in vnp_OrderInfo", ErrInvalidPayment)
+	}
+	return validatePaymentNotification(n)
+}
+
+// momoNotification is the body of a MoMo IPN (payment gateway v2)
+type momoNotification struct {
+	PartnerCode  string      `json:"partnerCode"`
+	OrderID      string      `json:"orderId"`
+	RequestID    string      `json:"requestId"`
+	Amount       json.Number `json:"amount"`
+	OrderInfo    string      `json:"orderInfo"`
+	OrderType    string      `json:"orderType"`
+	TransID      json.Number `json:"transId"`
+	ResultCode   json.Number `json:"resultCode"`
+	Message      string      `json:"message"`
+	PayType      string      `json:"payType"`
+	ResponseTime json.Number `json:"responseTime"`
+	ExtraData    string      `json:"extraData"`
+	Signature    string      `json:"signature"`
+}
+
+// momoExtraData is the base64 JSON the checkout puts in extraData
+type momoExtraData struct {
+	ProfileID string `json:"profile_id"`
+	Months    int    `json:"months"`
+}
+
+// parseMoMoNotification verifies and maps a MoMo IPN. The secret is
+// "<accessKey>:<secretKey>"; signature is the hex HMAC-SHA256 under the
+// secret key of the IPN fields in alphabetical order, accessKey included.
+func parseMoMoNotification(secret []byte, body []byte) (PaymentNotification, error) {
+	accessKey, secretKey, _ := bytes.Cut(secret, []byte(":"))
+
+	var m momoNotification
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&m); err != nil {
+		return PaymentNotification{}, fmt.Errorf("%w: %v", ErrInvalidPaymentSignature, err)
+	}
+	if m.Signature == "" {
+		return PaymentNotification{}, fmt.Errorf("%w: missing signature", ErrInvalidPaymentSignature)
+	}
+
+	raw := "accessKey=" + string(accessKey) +
+		"&amount=" + m.Amount.String() +
+		"&extraData=" + m.ExtraData +
+		"&message=" + m.Message +
+		"&orderId=" + m.OrderID +
+		"&orderInfo=" + m.OrderInfo +
+		"&orderType=" + m.OrderType +
+		"&partnerCode=" + m.PartnerCode +
+		"&payType=" + m.PayType +
+		"&requestId=" + m.RequestID +
+		"&responseTime=" + m.ResponseTime.String() +
+		"&resultCode=" + m.ResultCode.String() +
+		"&transId=" + m.TransID.String()
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(raw))
+	actual, err := hex.DecodeString(m.Signature)
+	if err != nil || !hmac.Equal(mac.Sum(nil), actual) {
+		return PaymentNotification{}, ErrInvalidPaymentSignature
+	}
+
+	n := PaymentNotification{TransactionID: m.OrderID, Currency: "VND"}
+	switch m.ResultCode.String() {
+	case "0":
+		n.Status = models.PaymentStatusSucceeded
+	case "1000", "7000", "7002", "9000":
+		n.Status = models.PaymentStatusPending
+	default:
+		n.Status = models.PaymentStatusFailed
+	}
+	if n.Amount, err = m.Amount.Int64(); err != nil {
+		return n, fmt.Errorf("%w: malformed amount", ErrInvalidPayment)
+	}
+
+	extra, err := base64.StdEncoding.DecodeString(m.ExtraData)
+	var data momoExtraData
+	if err != nil || json.Unmarshal(extra, &data) != nil {
+		return n, fmt.Errorf("%w: extraData must be base64 JSON with profile_id and months", ErrInvalidPayment)
+	}
+	n.ProfileID = data.ProfileID
+	n.Months = data.Months
+	return validatePaymentNotification(n)
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+const testPaymentProfile = "0b6f1c9e-3a52-4c4e-9a57-3f1d2a6c8e11"
+
+func signVNPay(secret string, query url.Values) url.Values {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write([]byte(query.Encode()))
+	signed := url.Values{}
+	for key, values := range query {
+		signed[key] = values
+	}
+	signed.Set("vnp_SecureHashType", "HmacSHA512")
+	signed.Set("vnp_SecureHash", hex.EncodeToString(mac.Sum(nil)))
+	return signed
+}
+
+func TestVNPayNotification(t *testing.T) {
+	query := url.Values{
+		"vnp_Amount":            {"19900000"},
+		"vnp_OrderInfo":         {testPaymentProfile + " 1"},
+		"vnp_ResponseCode":      {"00"},
+		"vnp_TransactionStatus": {"00"},
+		"vnp_TxnRef":            {"order-1"},
+		"vnp_TmnCode":           {"CPLS0001"},
+	}
+	signed := signVNPay("hash-secret", query)
+
+	n, payload, err := decodePaymentNotification(PaymentProviderVNPay, []byte("hash-secret"), "", nil, signed, time.Now())
+	if err != nil {
+		t.Fatalf("decodePaymentNotification() error = %v", err)
+	}
+	want := PaymentNotification{TransactionID: "order-1", ProfileID: testPaymentProfile,
+		Status: models.PaymentStatusSucceeded, Amount: 199000, Currency: "VND", Months: 1}
+	if n != want {
+		t.Errorf("decodePaymentNotification() = %+v; want %+v", n, want)
+	}
+	if payload != signed.Encode() {
+		t.Errorf("payload = %q; want the signed query", payload)
+	}
+
+	tampered := signVNPay("hash-secret", query)
+	tampered.Set("vnp_Amount", "100")
+	for name, q := range map[string]url.Values{
+		"wrong secret": signVNPay("other", query),
+		"tampered":     tampered,
+		"unsigned":     query,
+	} {
+		if err := verifyVNPaySignature([]byte("hash-secret"), q); !errors.Is(err, ErrInvalidPaymentSignature) {
+			t.Errorf("%s: verifyVNPaySignature() error = %v; want ErrInvalidPaymentSignature", name, err)
+		}
+	}
+}
+
+func momoBody(secret, accessKey, resultCode, extraData string) string {
+	raw := "accessKey=" + accessKey + "&amount=199000&extraData=" + extraData +
+		"&message=Successful.&orderId=order-1&orderInfo=Premium&orderType=momo_wallet&partnerCode=CPLS" +
+		"&payType=qr&requestId=req-1&responseTime=1769767200000&resultCode=" + resultCode + "&transId=4088878653"
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(raw))
+	return `{"partnerCode":"CPLS","orderId":"order-1","requestId":"req-1","amount":199000,` +
+		`"orderInfo":"Premium","orderType":"momo_wallet","transId":4088878653,"resultCode":` + resultCode + `,` +
+		`"message":"Successful.","payType":"qr","responseTime":1769767200000,"extraData":"` + extraData + `",` +
+		`"signature":"` + hex.EncodeToString(mac.Sum(nil)) + `"}`
+}
+
+func TestMoMoNotification(t *testing.T) {
+	extra := base64.StdEncoding.EncodeToString([]byte(`{"profile_id":"` + testPaymentProfile + `","months":3}`))
+	secret := []byte("access:secret")
+
+	n, err := parseMoMoNotification(secret, []byte(momoBody("secret", "access", "0", extra)))
+	if err != nil {
+		t.Fatalf("parseMoMoNotification() error = %v", err)
+	}
+	want := PaymentNotification{TransactionID: "order-1", ProfileID: testPaymentProfile,
+		Status: models.PaymentStatusSucceeded, Amount: 199000, Currency: "VND", Months: 3}
+	if n != want {
+		t.Errorf("parseMoMoNotification() = %+v; want %+v", n, want)
+	}
+
+	if n, err := parseMoMoNotification(secret, []byte(momoBody("secret", "access", "1006", extra))); err != nil || n.Status != models.PaymentStatusFailed {
+		t.Errorf("declined payment = %+v, %v; want failed", n, err)
+	}
+
+	for name, body := range map[string]string{
+		"wrong secret":     momoBody("other", "access", "0", extra),
+		"wrong access key": momoBody("secret", "other", "0", extra),
+		"tampered":         strings.Replace(momoBody("secret", "access", "0", extra), `"amount":199000`, `"amount":1990000`, 1),
+		"unsigned":         `{"orderId":"order-1"}`,
+	} {
+		if _, err := parseMoMoNotification(secret, []byte(body)); !errors.Is(err, ErrInvalidPaymentSignature) {
+			t.Errorf("%s: parseMoMoNotification() error = %v; want ErrInvalidPaymentSignature", name, err)
+		}
+	}
+
+	if _, err := parseMoMoNotification(secret, []byte(momoBody("secret", "access", "0", "bm90IGpzb24="))); !errors.Is(err, ErrInvalidPayment) {
+		t.Errorf("bad extraData: parseMoMoNotification() error = %v; want ErrInvalidPayment", err)
+	}
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// paymentSignatureTolerance is how old a signed notification may be, so a
+	// captured request cannot be replayed later
+	paymentSignatureTolerance = 5 * time.Minute
+	// maxPaymentMonths caps the membership period a single payment may buy
+	maxPaymentMonths = 36
+)
+
+var (
+	// ErrPaymentsDisabled is returned when no provider secrets are configured
+	ErrPaymentsDisabled = errors.New("payment webhooks are not configured")
+	// ErrInvalidPaymentSignature is returned when a notification is unsigned,
+	// wrongly signed, expired or from an unknown provider
+	ErrInvalidPaymentSignature = errors.New("invalid payment signature")
+	// ErrInvalidPayment is returned when a signed notification is malformed
+	ErrInvalidPayment = errors.New("invalid payment notification")
+)
+
+// PaymentNotification is the body a payment provider posts to the webhook
+type PaymentNotification struct {
+	TransactionID string `json:"transaction_id"` // Provider's transaction ID; retries reuse it
+	ProfileID     string `json:"profile_id"`     // Member who paid
+	Status        string `json:"status"`         // pending, succeeded or failed
+	Amount        int64  `json:"amount"`         // Smallest currency unit
+	Currency      string `json:"currency"`
+	Months        int    `json:"months"` // Premium months bought
+}
+
+// PaymentResult is the outcome of one webhook delivery
+type PaymentResult struct {
+	Payment   *models.Payment `json:"payment"`
+	Duplicate bool            `json:"duplicate"` // Already processed; nothing changed
+}
+
+// PaymentService verifies payment provider webhooks and upgrades the
+// memberships they pay for
+type PaymentService struct {
+	secrets      map[string][]byte // Webhook signing secret per provider
+	auditService *AuditService
+	now          func() time.Time
+}
+
+// NewPaymentService creates a PaymentService with explicit provider secrets
+func NewPaymentService(secrets map[string][]byte, auditService *AuditService) *PaymentService {
+	return &PaymentService{
+		secrets:      secrets,
+		auditService: auditService,
+		now:          time.Now,
+	}
+}
+
+// NewPaymentServiceFromEnv creates a PaymentService configured from
+// PAYMENT_WEBHOOK_SECRETS, a comma-separated list of provider=secret pairs
+// (e.g. "stripe=whsec_...,vnpay=<hash secret>,momo=<accessKey>:<secretKey>").
+// Without it every webhook is rejected.
+func NewPaymentServiceFromEnv(auditService *AuditService) (*PaymentService, error) {
+	secrets, err := parsePaymentSecrets(os.Getenv("PAYMENT_WEBHOOK_SECRETS"))
+	if err != nil {
+		return nil, err
+	}
+	return NewPaymentService(secrets, auditService), nil
+}
+
+// parsePaymentSecrets parses "provider=secret,provider=secret"
+func parsePaymentSecrets(raw string) (map[string][]byte, error) {
+	secrets := make(map[string][]byte)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		provider, secret, ok := strings.Cut(entry, "=")
+		provider = strings.ToLower(strings.TrimSpace(provider))
+		if !ok || provider == "" || strings.TrimSpace(secret) == "" {
+			return nil, fmt.Errorf("invalid PAYMENT_WEBHOOK_SECRETS entry %q: expected provider=secret", entry)
+		}
+		if provider == PaymentProviderMoMo && !strings.Contains(secret, ":") {
+			return nil, fmt.Errorf("invalid PAYMENT_WEBHOOK_SECRETS entry for %s: expected momo=<accessKey>:<secretKey>", provider)
+		}
+		secrets[provider] = []byte(strings.TrimSpace(secret))
+	}
+	return secrets, nil
+}
+
+// Providers returns the providers whose webhooks are accepted, sorted
+func (s *PaymentService) Providers() []string {
+	providers := make([]string, 0, len(s.secrets))
+	for provider := range s.secrets {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// HandleWebhook verifies a provider notification, records the payment and,
+// when it succeeded, upgrades the member to premium in the same transaction.
+// Notifications for a transaction that already succeeded are acknowledged
+// without changing anything, so provider retries are safe.
+func (s *PaymentService) HandleWebhook(ctx context.Context, provider, signature string, body []byte, query url.Values, ip string) (*PaymentResult, error) {
+	if len(s.secrets) == 0 {
+		return nil, ErrPaymentsDisabled
+	}
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	secret, ok := s.secrets[provider]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown provider %q", ErrInvalidPaymentSignature, provider)
+	}
+	now := s.now().UTC()
+	notification, payload, err := decodePaymentNotification(provider, secret, signature, body, query, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var result PaymentResult
+	var previous *time.Time
+	err = config.GetDBWithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&payment, "provider = ? AND transaction_id = ?", provider, notification.TransactionID).Error
+		exists := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to look up payment: %w", err)
+		}
+		if exists {
+			if payment.ProfileID.String() != notification.ProfileID || payment.Amount != notification.Amount ||
+				payment.Months != notification.Months {
+				return fmt.Errorf("%w: transaction %s was already recorded with different details", ErrInvalidPayment, notification.TransactionID)
+			}
+			if payment.Status == models.PaymentStatusSucceeded || payment.Status == notification.Status {
+				result = PaymentResult{Payment: &payment, Duplicate: true}
+				return nil
+			}
+		}
+
+		var profile models.Profile
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&profile, "id = ?", notification.ProfileID).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrProfileNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up profile: %w", err)
+		}
+
+		if !exists {
+			payment = models.Payment{
+				ID:            uuid.New(),
+				Provider:      provider,
+				TransactionID: notification.TransactionID,
+				ProfileID:     profile.ID,
+				Amount:        notification.Amount,
+				Currency:      notification.Currency,
+				Months:        notification.Months,
+				CreatedAt:     now,
+			}
+		}
+		payment.Status = notification.Status
+		payment.Payload = payload
+		payment.UpdatedAt = now
+
+		if payment.Status == models.PaymentStatusSucceeded {
+			previous = profile.MembershipExpiresAt
+			expiresAt := extendMembership(&profile, payment.Months, now)
+			err := tx.Model(&models.Profile{}).Where("id = ?", profile.ID).Updates(map[string]interface{}{
+				"membership":            models.MembershipPremium,
+				"membership_expires_at": expiresAt,
+				"updated_at":            now,
+			}).Error
+			if err != nil {
+				return fmt.Errorf("failed to upgrade membership: %w", err)
+			}
+			payment.MembershipExpiresAt = expiresAt
+		}
+
+		if exists {
+			err = tx.Save(&payment).Error
+		} else {
+			err = tx.Create(&payment).Error
+		}
+		if err != nil {
+			return fmt.Errorf("failed to record payment: %w", err)
+		}
+		result = PaymentResult{Payment: &payment}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Duplicate {
+		details := map[string]interface{}{
+			"provider":       provider,
+			"transaction_id": notification.TransactionID,
+			"status":         notification.Status,
+			"amount":         notification.Amount,
+			"currency":       notification.Currency,
+			"months":         notification.Months,
+		}
+		if result.Payment.MembershipExpiresAt != nil {
+			details["membership_expires_at"] = map[string]interface{}{"from": previous, "to": result.Payment.MembershipExpiresAt}
+		}
+		s.auditService.Record(ctx, AuditEntry{
+			Actor:      "payment:" + provider,
+			Action:     models.AuditActionPayment,
+			Outcome:    models.AuditOutcomeSuccess,
+			EntityType: "profile",
+			EntityID:   notification.ProfileID,
+			IP:         ip,
+			Details:    details,
+		})
+	}
+	return &result, nil
+}
+
+// verifyPaymentSignature checks a "t=<unix seconds>,v1=<hex>" signature
+// header, where v1 is the HMAC-SHA256 of "<t>.<body>" under the provider's
+// secret. Several v1 values may be present while a secret is rotated.
+func verifyPaymentSignature(secret []byte, header string, body []byte, now time.Time) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("%w: missing timestamp or signature", ErrInvalidPaymentSignature)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidPaymentSignature)
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > paymentSignatureTolerance || age < -paymentSignatureTolerance {
+		return fmt.Errorf("%w: timestamp outside the %s tolerance", ErrInvalidPaymentSignature, paymentSignatureTolerance)
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	expected := mac.Sum(nil)
+	for _, signature := range signatures {
+		if actual, err := hex.DecodeString(signature); err == nil && hmac.Equal(expected, actual) {
+			return nil
+		}
+	}
+	return ErrInvalidPaymentSignature
+}
+
+// parsePaymentNotification decodes and validates a webhook body
+func parsePaymentNotification(body []byte) (PaymentNotification, error) {
+	var n PaymentNotification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return n, fmt.Errorf("%w: %v", ErrInvalidPayment, err)
+	}
+	return validatePaymentNotification(n)
+}
+
+// validatePaymentNotification normalizes a decoded notification and checks
+// the fields every provider must supply
+func validatePaymentNotification(n PaymentNotification) (PaymentNotification, error) {
+	n.TransactionID = strings.TrimSpace(n.TransactionID)
+	n.Status = strings.ToLower(strings.TrimSpace(n.Status))
+	n.Currency = strings.ToUpper(strings.TrimSpace(n.Currency))
+
+	switch {
+	case n.TransactionID == "":
+		return n, fmt.Errorf("%w: transaction_id is required", ErrInvalidPayment)
+	case !models.ValidPaymentStatus(n.Status):
+		return n, fmt.Errorf("%w: status must be %s, %s or %s", ErrInvalidPayment,
+			models.PaymentStatusPending, models.PaymentStatusSucceeded, models.PaymentStatusFailed)
+	case n.Amount < 0 || (n.Status == models.PaymentStatusSucceeded && n.Amount == 0):
+		return n, fmt.Errorf("%w: amount must be positive", ErrInvalidPayment)
+	case n.Currency == "":
+		return n, fmt.Errorf("%w: currency is required", ErrInvalidPayment)
+	case n.Months < 1 || n.Months > maxPaymentMonths:
+		return n, fmt.Errorf("%w: months must be between 1 and %d", ErrInvalidPayment, maxPaymentMonths)
+	}
+	id, err := uuid.Parse(n.ProfileID)
+	if err != nil {
+		return n, fmt.Errorf("%w: profile_id must be a UUID", ErrInvalidPayment)
+	}
+	n.ProfileID = id.String()
+	return n, nil
+}
+
+// extendMembership returns the expiry after adding months of premium: paid
+// time still remaining is kept, otherwise the period starts now. Premium
+// members without an expiry keep their unlimited membership (nil).
+func extendMembership(profile *models.Profile, months int, now time.Time) *time.Time {
+	start := now
+	if profile.Membership == models.MembershipPremium {
+		if profile.MembershipExpiresAt == nil {
+			return nil
+		}
+		if profile.MembershipExpiresAt.After(now) {
+			start = *profile.MembershipExpiresAt
+		}
+	}
+	expiresAt := start.AddDate(0, months, 0).UTC()
+	return &expiresAt
+}
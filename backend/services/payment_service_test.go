@@ -0,0 +1,128 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func paymentSignature(secret, body string, at time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(at.Unix(), 10) + "." + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func signPayment(secret, body string, at time.Time) string {
+	return "t=" + strconv.FormatInt(at.Unix(), 10) + ",v1=" + paymentSignature(secret, body, at)
+}
+
+func TestVerifyPaymentSignature(t *testing.T) {
+	now := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	body := `{"transaction_id":"tx-1"}`
+
+	tests := []struct {
+		name   string
+		header string
+		valid  bool
+	}{
+		{"valid", signPayment("secret", body, now), true},
+		{"slightly old", signPayment("secret", body, now.Add(-4*time.Minute)), true},
+		{"rotated secret", signPayment("old", body, now) + ",v1=" + paymentSignature("secret", body, now), true},
+		{"wrong secret", signPayment("other", body, now), false},
+		{"tampered body", signPayment("secret", body+" ", now), false},
+		{"expired", signPayment("secret", body, now.Add(-10*time.Minute)), false},
+		{"from the future", signPayment("secret", body, now.Add(10*time.Minute)), false},
+		{"missing signature", "t=" + strconv.FormatInt(now.Unix(), 10), false},
+		{"empty", "", false},
+		{"malformed hex", "t=" + strconv.FormatInt(now.Unix(), 10) + ",v1=zz", false},
+	}
+
+	for _, tt := range tests {
+		err := verifyPaymentSignature([]byte("secret"), tt.header, []byte(body), now)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: verifyPaymentSignature() error = %v; want valid %v", tt.name, err, tt.valid)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidPaymentSignature) {
+			t.Errorf("%s: error %v is not ErrInvalidPaymentSignature", tt.name, err)
+		}
+	}
+}
+
+func TestParsePaymentNotification(t *testing.T) {
+	const profile = "0b6f1c9e-3a52-4c4e-9a57-3f1d2a6c8e11"
+	valid := `{"transaction_id":"tx-1","profile_id":"` + profile + `","status":"Succeeded","amount":199000,"currency":"vnd","months":1}`
+
+	n, err := parsePaymentNotification([]byte(valid))
+	if err != nil {
+		t.Fatalf("parsePaymentNotification() error = %v", err)
+	}
+	if n.Status != models.PaymentStatusSucceeded || n.Currency != "VND" || n.ProfileID != profile {
+		t.Errorf("parsePaymentNotification() = %+v; want normalized status, currency and profile", n)
+	}
+
+	invalid := map[string]string{
+		"not JSON":        `transaction_id=tx-1`,
+		"no transaction":  `{"profile_id":"` + profile + `","status":"succeeded","amount":1,"currency":"VND","months":1}`,
+		"bad profile":     `{"transaction_id":"tx-1","profile_id":"42","status":"succeeded","amount":1,"currency":"VND","months":1}`,
+		"unknown status":  `{"transaction_id":"tx-1","profile_id":"` + profile + `","status":"refunded","amount":1,"currency":"VND","months":1}`,
+		"free success":    `{"transaction_id":"tx-1","profile_id":"` + profile + `","status":"succeeded","amount":0,"currency":"VND","months":1}`,
+		"no currency":     `{"transaction_id":"tx-1","profile_id":"` + profile + `","status":"succeeded","amount":1,"months":1}`,
+		"no months":       `{"transaction_id":"tx-1","profile_id":"` + profile + `","status":"succeeded","amount":1,"currency":"VND"}`,
+		"too many months": `{"transaction_id":"tx-1","profile_id":"` + profile + `","status":"succeeded","amount":1,"currency":"VND","months":37}`,
+		"negative amount": `{"transaction_id":"tx-1","profile_id":"` + profile + `","status":"failed","amount":-1,"currency":"VND","months":1}`,
+	}
+	for name, body := range invalid {
+		if _, err := parsePaymentNotification([]byte(body)); !errors.Is(err, ErrInvalidPayment) {
+			t.Errorf("%s: parsePaymentNotification() error = %v; want ErrInvalidPayment", name, err)
+		}
+	}
+}
+
+func TestExtendMembership(t *testing.T) {
+	now := time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC)
+	future := now.AddDate(0, 0, 10)
+	past := now.AddDate(0, 0, -10)
+
+	tests := []struct {
+		name    string
+		profile models.Profile
+		want    *time.Time
+	}{
+		{"free member starts now", models.Profile{Membership: models.MembershipFree}, ptrTime(now.AddDate(0, 2, 0))},
+		{"active premium is extended", models.Profile{Membership: models.MembershipPremium, MembershipExpiresAt: &future}, ptrTime(future.AddDate(0, 2, 0))},
+		{"lapsed premium starts now", models.Profile{Membership: models.MembershipPremium, MembershipExpiresAt: &past}, ptrTime(now.AddDate(0, 2, 0))},
+		{"unlimited premium stays unlimited", models.Profile{Membership: models.MembershipPremium}, nil},
+	}
+
+	for _, tt := range tests {
+		if got := extendMembership(&tt.profile, 2, now); !sameTime(got, tt.want) {
+			t.Errorf("%s: extendMembership() = %v; want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParsePaymentSecrets(t *testing.T) {
+	secrets, err := parsePaymentSecrets(" Stripe=whsec_1 , momo=access:abc=def,")
+	if err != nil {
+		t.Fatalf("parsePaymentSecrets() error = %v", err)
+	}
+	if string(secrets["stripe"]) != "whsec_1" || string(secrets["momo"]) != "access:abc=def" || len(secrets) != 2 {
+		t.Errorf("parsePaymentSecrets() = %v", secrets)
+	}
+
+	for _, raw := range []string{"stripe", "=secret", "stripe=", "momo=secret-only"} {
+		if _, err := parsePaymentSecrets(raw); err == nil {
+			t.Errorf("parsePaymentSecrets(%q) succeeded; want error", raw)
+		}
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// personalTokenPrefix distinguishes member tokens from JWTs and partner API keys
+	personalTokenPrefix = "cpls_pat_"
+	// maxActivePersonalTokens limits how many live tokens a member can hold
+	maxActivePersonalTokens = 10
+)
+
+var (
+	// ErrInvalidPersonalToken is returned when a token does not exist, was revoked or expired
+	ErrInvalidPersonalToken = errors.New("invalid, revoked or expired personal access token")
+	// ErrPersonalTokenNotFound is returned when a member's token ID does not exist
+	ErrPersonalTokenNotFound = errors.New("personal access token not found")
+	// ErrPersonalTokenLimit is returned when a member already has the maximum number of active tokens
+	ErrPersonalTokenLimit = fmt.Errorf("at most %d active personal access tokens are allowed", maxActivePersonalTokens)
+	// ErrProfileNotFound is returned when the authenticated member has no profile
+	ErrProfileNotFound = errors.New("profile not found")
+	// ErrProfileInactive is returned when the member's profile was deactivated
+	ErrProfileInactive = errors.New("profile is deactivated")
+)
+
+// PersonalTokenService manages members' personal access tokens. Tokens of
+// deactivated profiles cannot be created or used.
+type PersonalTokenService struct {
+	profiles ProfileStatus
+}
+
+// NewPersonalTokenService creates a new PersonalTokenService instance
+func NewPersonalTokenService(profiles ProfileStatus) *PersonalTokenService {
+	return &PersonalTokenService{profiles: profiles}
+}
+
+// IsPersonalToken reports whether a bearer token looks like a personal access token
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, personalTokenPrefix)
+}
+
+// CreateToken generates a personal access token for a member. The plaintext
+// token is returned only here. A zero ttl creates a token without expiry.
+func (s *PersonalTokenService) CreateToken(ctx context.Context, profileID uuid.UUID, name string, scopes []string, ttl time.Duration) (*models.PersonalAccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("name is required")
+	}
+	normalized, err := models.NormalizePersonalTokenScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	profileActive, err := s.profiles.ProfileActive(ctx, profileID)
+	if err != nil {
+		return nil, "", err
+	}
+	if !profileActive {
+		return nil, "", ErrProfileInactive
+	}
+
+	db := config.GetDBWithContext(ctx)
+	now := time.Now().UTC()
+	var active int64
+	err = db.Model(&models.PersonalAccessToken{}).
+		Where("profile_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", profileID, now).
+		Count(&active).Error
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to count personal access tokens: %w", err)
+	}
+	if active >= maxActivePersonalTokens {
+		return nil, "", ErrPersonalTokenLimit
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate personal access token: %w", err)
+	}
+	plaintext := personalTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	token := &models.PersonalAccessToken{
+		ID:        uuid.New(),
+		ProfileID: profileID,
+		Name:      name,
+		Prefix:    plaintext[:len(personalTokenPrefix)+6],
+		TokenHash: hashAPIKey(plaintext),
+		Scopes:    normalized,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := db.Create(token).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create personal access token: %w", err)
+	}
+	return token, plaintext, nil
+}
+
+// ListTokens returns a member's tokens, newest first
+func (s *PersonalTokenService) ListTokens(ctx context.Context, profileID uuid.UUID) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := config.GetDBWithContext(ctx).
+		Where("profile_id = ?", profileID).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch personal access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken revokes one of the member's own tokens
+func (s *PersonalTokenService) RevokeToken(ctx context.Context, profileID uuid.UUID, id string) (*models.PersonalAccessToken, error) {
+	db := config.GetDBWithContext(ctx)
+
+	var token models.PersonalAccessToken
+	err := db.First(&token, "id = ? AND profile_id = ?", id, profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPersonalTokenNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch personal access token: %w", err)
+	}
+
+	if token.RevokedAt == nil {
+		now := time.Now().UTC()
+		if err := db.Model(&token).UpdateColumn("revoked_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to revoke personal access token: %w", err)
+		}
+		token.RevokedAt = &now
+	}
+	return &token, nil
+}
+
+// Authenticate resolves a plaintext token to an active personal access token
+func (s *PersonalTokenService) Authenticate(plaintext string) (*models.PersonalAccessToken, error) {
+	if !IsPersonalToken(plaintext) {
+		return nil, ErrInvalidPersonalToken
+	}
+
+	var token models.PersonalAccessToken
+	err := config.GetDB().Where("token_hash = ?", hashAPIKey(plaintext)).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidPersonalToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up personal access token: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if err := s.checkUsable(context.Background(), &token, now); err != nil {
+		return nil, err
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiKeyTouchInterval {
+		if err := config.GetDB().Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+			authLog.Warn("Failed to update last_used_at of personal token", "prefix", token.Prefix, logging.FieldError, err)
+		}
+	}
+
+	return &token, nil
+}
+
+// checkUsable returns ErrInvalidPersonalToken unless token is active at now
+// and its member's profile exists and is active
+func (s *PersonalTokenService) checkUsable(ctx context.Context, token *models.PersonalAccessToken, now time.Time) error {
+	if !token.Active(now) {
+		return ErrInvalidPersonalToken
+	}
+	active, err := s.profiles.ProfileActive(ctx, token.ProfileID)
+	if errors.Is(err, ErrProfileNotFound) {
+		return fmt.Errorf("%w: %v", ErrInvalidPersonalToken, err)
+	}
+	if err != nil {
+		return err
+	}
+	if !active {
+		return fmt.Errorf("%w: %v", ErrInvalidPersonalToken, ErrProfileInactive)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+)
+
+func TestCreateTokenRequiresActiveProfile(t *testing.T) {
+	active, inactive := uuid.New(), uuid.New()
+	s := NewPersonalTokenService(profileStatuses{active: true, inactive: false})
+
+	if _, _, err := s.CreateToken(context.Background(), inactive, "ci", []string{"read_prices"}, 0); !errors.Is(err, ErrProfileInactive) {
+		t.Errorf("CreateToken() for a deactivated profile error = %v; want ErrProfileInactive", err)
+	}
+	if _, _, err := s.CreateToken(context.Background(), uuid.New(), "ci", []string{"read_prices"}, 0); !errors.Is(err, ErrProfileNotFound) {
+		t.Errorf("CreateToken() without a profile error = %v; want ErrProfileNotFound", err)
+	}
+}
+
+func TestPersonalTokenCheckUsable(t *testing.T) {
+	active, inactive := uuid.New(), uuid.New()
+	s := NewPersonalTokenService(profileStatuses{active: true, inactive: false})
+	now := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Hour)
+
+	tests := []struct {
+		name  string
+		token models.PersonalAccessToken
+		valid bool
+	}{
+		{"active profile", models.PersonalAccessToken{ProfileID: active}, true},
+		{"deactivated profile", models.PersonalAccessToken{ProfileID: inactive}, false},
+		{"missing profile", models.PersonalAccessToken{ProfileID: uuid.New()}, false},
+		{"expired token", models.PersonalAccessToken{ProfileID: active, ExpiresAt: &expired}, false},
+	}
+	for _, tt := range tests {
+		err := s.checkUsable(context.Background(), &tt.token, now)
+		if tt.valid && err != nil {
+			t.Errorf("%s: checkUsable() unexpected error: %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidPersonalToken) {
+			t.Errorf("%s: checkUsable() error = %v; want ErrInvalidPersonalToken", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxPortfolioTrades caps the trades one member can record
+const maxPortfolioTrades = 5000
+
+var (
+	// ErrPortfolioTradeNotFound is returned when a trade does not exist or belongs to another member
+	ErrPortfolioTradeNotFound = errors.New("portfolio trade not found")
+	// ErrInvalidPortfolioTrade is returned when a trade fails validation
+	ErrInvalidPortfolioTrade = errors.New("invalid portfolio trade")
+	// ErrPortfolioTradeLimit is returned when a member has recorded the maximum number of trades
+	ErrPortfolioTradeLimit = errors.New("portfolio trade limit reached")
+	// ErrInvalidPerformanceQuery is returned for an unknown performance period or benchmark
+	ErrInvalidPerformanceQuery = errors.New("invalid performance query")
+)
+
+// performanceMarkDays is how many days before a period's base date candles
+// are loaded, so holdings bought earlier are marked with a close at its start
+const performanceMarkDays = 30
+
+// PortfolioService records members' trades and values their holdings with
+// the stored candles
+type PortfolioService struct {
+	stockService PriceStore
+}
+
+// NewPortfolioService creates a new PortfolioService instance
+func NewPortfolioService(stockService PriceStore) *PortfolioService {
+	return &PortfolioService{stockService: stockService}
+}
+
+// ListTrades returns a member's trades, optionally of one code, newest first
+func (s *PortfolioService) ListTrades(ctx context.Context, profileID uuid.UUID, code string) ([]models.PortfolioTrade, error) {
+	query := config.GetDBWithContext(ctx).Where("profile_id = ?", profileID)
+	if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+		query = query.Where("code = ?", code)
+	}
+	trades := make([]models.PortfolioTrade, 0)
+	if err := query.Order("trade_date DESC, created_at DESC").Find(&trades).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch portfolio trades: %w", err)
+	}
+	return trades, nil
+}
+
+// RecordTrade stores a member's trade. Sells are rejected when they exceed
+// the quantity held on their date.
+func (s *PortfolioService) RecordTrade(ctx context.Context, trade *models.PortfolioTrade) error {
+	trade.Code = strings.ToUpper(strings.TrimSpace(trade.Code))
+	trade.Side = strings.ToLower(strings.TrimSpace(trade.Side))
+	if err := trade.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPortfolioTrade, err)
+	}
+	if _, err := s.stockService.GetStock(ctx, trade.Code); err != nil {
+		if errors.Is(err, ErrStockNotFound) {
+			return fmt.Errorf("%w: unknown stock code %s", ErrInvalidPortfolioTrade, trade.Code)
+		}
+		return err
+	}
+
+	db := config.GetDBWithContext(ctx)
+	var count int64
+	if err := db.Model(&models.PortfolioTrade{}).Where("profile_id = ?", trade.ProfileID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count portfolio trades: %w", err)
+	}
+	if count >= maxPortfolioTrades {
+		return ErrPortfolioTradeLimit
+	}
+
+	if trade.Side == models.TradeSideSell {
+		trades, err := s.orderedTrades(ctx, trade.ProfileID, trade.Code)
+		if err != nil {
+			return err
+		}
+		if _, err := models.BuildHoldings(append(trades, *trade)); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPortfolioTrade, err)
+		}
+	}
+
+	trade.ID = uuid.New()
+	trade.CreatedAt = time.Now().UTC()
+	if err := db.Create(trade).Error; err != nil {
+		return fmt.Errorf("failed to record portfolio trade: %w", err)
+	}
+	return nil
+}
+
+// DeleteTrade removes one of a member's trades. Deleting a buy that later
+// sells depend on is rejected.
+func (s *PortfolioService) DeleteTrade(ctx context.Context, profileID uuid.UUID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrPortfolioTradeNotFound
+	}
+	db := config.GetDBWithContext(ctx)
+	var trade models.PortfolioTrade
+	err := db.First(&trade, "id = ? AND profile_id = ?", id, profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrPortfolioTradeNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch portfolio trade: %w", err)
+	}
+
+	if trade.Side == models.TradeSideBuy {
+		trades, err := s.orderedTrades(ctx, profileID, trade.Code)
+		if err != nil {
+			return err
+		}
+		remaining := make([]models.PortfolioTrade, 0, len(trades))
+		for _, other := range trades {
+			if other.ID != trade.ID {
+				remaining = append(remaining, other)
+			}
+		}
+		if _, err := models.BuildHoldings(remaining); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidPortfolioTrade, err)
+		}
+	}
+
+	if err := db.Delete(&trade).Error; err != nil {
+		return fmt.Errorf("failed to delete portfolio trade: %w", err)
+	}
+	return nil
+}
+
+// Valuation builds a member's holdings from their trades and marks them to
+// market with the latest stored candle of each code
+func (s *PortfolioService) Valuation(ctx context.Context, profileID uuid.UUID) (*models.PortfolioValuation, error) {
+	trades, err := s.orderedTrades(ctx, profileID, "")
+	if err != nil {
+		return nil, err
+	}
+	holdings, err := models.BuildHoldings(trades)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build holdings: %w", err)
+	}
+
+	codes := make([]string, 0, len(holdings))
+	for _, holding := range holdings {
+		if holding.Quantity > 0 {
+			codes = append(codes, holding.Code)
+		}
+	}
+	latest := make(map[string]models.CandleData, len(codes))
+	if len(codes) > 0 {
+		candles, err := s.stockService.LatestCandles(ctx, codes, 1)
+		if err != nil {
+			return nil, err
+		}
+		for code, recent := range candles {
+			if len(recent) > 0 {
+				latest[code] = recent[len(recent)-1]
+			}
+		}
+	}
+
+	valuation := models.ValuePortfolio(holdings, latest)
+	return &valuation, nil
+}
+
+// Performance computes the time-weighted return and drawdown of a member's
+// portfolio over period (models.PerformancePeriod*) ending today, valued
+// with the stored candles, and compares it with the benchmark index
+// (default VNINDEX). Benchmark figures are null until the crawler has
+// stored the index's candles.
+func (s *PortfolioService) Performance(ctx context.Context, profileID uuid.UUID, period, benchmark string) (*models.PortfolioPerformance, error) {
+	if period == "" {
+		period = models.PerformancePeriod1Y
+	}
+	benchmark = strings.ToUpper(strings.TrimSpace(benchmark))
+	if benchmark == "" {
+		benchmark = models.DefaultBenchmark
+	}
+	if _, ok := models.IndexExchange(benchmark); !ok {
+		return nil, fmt.Errorf("%w: unknown benchmark index %s", ErrInvalidPerformanceQuery, benchmark)
+	}
+
+	trades, err := s.orderedTrades(ctx, profileID, "")
+	if err != nil {
+		return nil, err
+	}
+	firstTrade := ""
+	if len(trades) > 0 {
+		firstTrade = trades[0].TradeDate
+	}
+	now := markettime.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if period == models.PerformancePeriodAll && firstTrade == "" {
+		firstTrade = to.Format("2006-01-02")
+	}
+	since, err := models.PerformanceSince(period, to, firstTrade)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPerformanceQuery, err)
+	}
+	sinceDate, _ := time.Parse("2006-01-02", since)
+	from := sinceDate.AddDate(0, 0, -performanceMarkDays)
+
+	closes := make(map[string][]models.CandleData)
+	for _, trade := range trades {
+		if _, ok := closes[trade.Code]; ok {
+			continue
+		}
+		candles, err := s.stockService.GetCandles(ctx, []string{trade.Code}, from, to)
+		if err != nil {
+			return nil, err
+		}
+		closes[trade.Code] = candles
+	}
+	benchmarkCandles, err := s.stockService.GetCandles(ctx, []string{benchmark}, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	performance := models.ComputePerformance(trades, closes, benchmarkCandles, since, to.Format("2006-01-02"))
+	performance.Period, performance.Benchmark = period, benchmark
+	return &performance, nil
+}
+
+// orderedTrades returns a member's trades, optionally of one code, in the
+// order they are replayed: by trade date, then by recording time
+func (s *PortfolioService) orderedTrades(ctx context.Context, profileID uuid.UUID, code string) ([]models.PortfolioTrade, error) {
+	query := config.GetDBWithContext(ctx).Where("profile_id = ?", profileID)
+	if code != "" {
+		query = query.Where("code = ?", code)
+	}
+	var trades []models.PortfolioTrade
+	if err := query.Order("trade_date ASC, created_at ASC").Find(&trades).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch portfolio trades: %w", err)
+	}
+	return trades, nil
+}
@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// postgresCandleBatch is how many candles one INSERT writes
+const postgresCandleBatch = 500
+
+// PostgresPriceRepository is the PriceRepository of the stock_candles table
+// in Supabase, one row per code and date
+type PostgresPriceRepository struct{}
+
+// NewPostgresPriceRepository creates a new PostgresPriceRepository
+func NewPostgresPriceRepository() *PostgresPriceRepository {
+	return &PostgresPriceRepository{}
+}
+
+// Store implements PriceRepository
+func (r *PostgresPriceRepository) Store() string {
+	return config.StorePostgres
+}
+
+// db returns the Supabase connection bound to ctx
+func (r *PostgresPriceRepository) db(ctx context.Context) (*gorm.DB, error) {
+	if config.PostgresDB == nil {
+		return nil, fmt.Errorf("%w: %s", config.ErrStoreUnavailable, config.StorePostgres)
+	}
+	return config.GetDBWithContext(ctx), nil
+}
+
+// SaveCandles implements PriceRepository. Dates stored meanwhile by another
+// writer are skipped by the insert itself.
+func (r *PostgresPriceRepository) SaveCandles(ctx context.Context, code string, candles []models.CandleData) (string, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return "", err
+	}
+	rows := make([]models.StockCandle, 0, len(candles))
+	for _, candle := range candles {
+		row, err := models.NewStockCandle(code, candle)
+		if err != nil {
+			crawlLog.Warn("Invalid candle date", logging.FieldSymbol, code, "date", candle.D)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		return "", nil
+	}
+
+	first, last := rows[0].Date, rows[0].Date
+	for _, row := range rows {
+		if row.Date.Before(first) {
+			first = row.Date
+		}
+		if row.Date.After(last) {
+			last = row.Date
+		}
+	}
+	var storedDates []time.Time
+	if err := db.Model(&models.StockCandle{}).
+		Where("code = ? AND date BETWEEN ? AND ?", code, first.Format("2006-01-02"), last.Format("2006-01-02")).
+		Pluck("date", &storedDates).Error; err != nil {
+		return "", fmt.Errorf("failed to query stored candle dates: %w", err)
+	}
+	stored := make(map[string]bool, len(storedDates))
+	for _, date := range storedDates {
+		stored[date.Format("2006-01-02")] = true
+	}
+
+	newRows := make([]models.StockCandle, 0, len(rows))
+	newest := ""
+	for _, row := range rows {
+		date := row.Date.Format("2006-01-02")
+		if stored[date] {
+			continue
+		}
+		stored[date] = true
+		newRows = append(newRows, row)
+		if date > newest {
+			newest = date
+		}
+	}
+	if len(newRows) == 0 {
+		return "", nil
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(newRows, postgresCandleBatch).Error; err != nil {
+		return "", fmt.Errorf("failed to insert candles: %w", err)
+	}
+	return newest, nil
+}
+
+// SaveSessionCandle implements PriceRepository with an upsert of the row of
+// the candle's date, which moves its updated_at for CandlesTag
+func (r *PostgresPriceRepository) SaveSessionCandle(ctx context.Context, code string, candle models.CandleData) (bool, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return false, err
+	}
+	row, err := models.NewStockCandle(code, candle)
+	if err != nil {
+		return false, fmt.Errorf("invalid candle date %q: %w", candle.D, err)
+	}
+	var stored int64
+	if err := db.Model(&models.StockCandle{}).
+		Where("code = ? AND date = ?", code, candle.D).
+		Count(&stored).Error; err != nil {
+		return false, fmt.Errorf("failed to query stored candle: %w", err)
+	}
+	row.UpdatedAt = time.Now().UTC()
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"open", "high", "low", "close", "volume", "updated_at"}),
+	}).Create(&row).Error; err != nil {
+		return false, fmt.Errorf("failed to upsert candle: %w", err)
+	}
+	return stored == 0, nil
+}
+
+// LatestCandleDates implements PriceRepository
+func (r *PostgresPriceRepository) LatestCandleDates(ctx context.Context) (map[string]string, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Date(time.Now().Year()-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var rows []struct {
+		Code string
+		Last time.Time
+	}
+	if err := db.Model(&models.StockCandle{}).
+		Select("code, max(date) AS last").
+		Where("date >= ?", since.Format("2006-01-02")).
+		Group("code").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query latest candle dates: %w", err)
+	}
+	latest := make(map[string]string, len(rows))
+	for _, row := range rows {
+		latest[row.Code] = row.Last.Format("2006-01-02")
+	}
+	return latest, nil
+}
+
+// Candles implements PriceRepository
+func (r *PostgresPriceRepository) Candles(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	priority, codeList := lineagePriority(codes)
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+	var rows []models.StockCandle
+	if err := db.
+		Where("code IN ? AND date BETWEEN ? AND ?", codeList, fromDate, toDate).
+		Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query candles: %w", err)
+	}
+
+	// One bucket per code, so lineages merge like MongoDB's buckets
+	buckets := make([]models.PriceBucket, len(codeList))
+	index := make(map[string]int, len(codeList))
+	for i, code := range codeList {
+		buckets[i].Code = code
+		index[code] = i
+	}
+	for _, row := range rows {
+		i := index[row.Code]
+		buckets[i].History = append(buckets[i].History, row.Candle())
+	}
+	return mergeBucketCandles(buckets, priority, fromDate, toDate), nil
+}
+
+// StreamCandles implements PriceRepository, reading the rows in date order
+// through a cursor
+func (r *PostgresPriceRepository) StreamCandles(ctx context.Context, codes []string, from, to time.Time, emit func(models.CandleData) error) (int, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return 0, err
+	}
+	priority, codeList := lineagePriority(codes)
+	rows, err := db.Model(&models.StockCandle{}).
+		Where("code IN ? AND date BETWEEN ? AND ?", codeList, from.Format("2006-01-02"), to.Format("2006-01-02")).
+		Order("date").
+		Rows()
+	if err != nil {
+		return 0, fmt.Errorf("failed to query candles: %w", err)
+	}
+	defer rows.Close()
+
+	// Rows of one date arrive together; the best ranked code's is emitted
+	// once the next date starts
+	emitted := 0
+	var pending *models.CandleData
+	pendingRank := 0
+	for rows.Next() {
+		var row models.StockCandle
+		if err := db.ScanRows(rows, &row); err != nil {
+			return emitted, fmt.Errorf("failed to decode candle: %w", err)
+		}
+		candle := row.Candle()
+		rank, ok := lineageRank(priority, row.Code, candle.D)
+		if !ok {
+			continue
+		}
+		if pending != nil && pending.D == candle.D {
+			if rank < pendingRank {
+				pending, pendingRank = &candle, rank
+			}
+			continue
+		}
+		if pending != nil {
+			if err := emit(*pending); err != nil {
+				return emitted, err
+			}
+			emitted++
+		}
+		pending, pendingRank = &candle, rank
+	}
+	if err := rows.Err(); err != nil {
+		return emitted, fmt.Errorf("failed to read candles: %w", err)
+	}
+	if pending != nil {
+		if err := emit(*pending); err != nil {
+			return emitted, err
+		}
+		emitted++
+	}
+	return emitted, nil
+}
+
+// CandlesTag implements PriceRepository with the number of rows in range and
+// when the last of them was inserted or updated: additions change the count
+// and replacing the session candle moves its updated_at
+func (r *PostgresPriceRepository) CandlesTag(ctx context.Context, codes []string, from, to time.Time) (string, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return "", err
+	}
+	_, codeList := lineagePriority(codes)
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+	var summary struct {
+		Candles int64
+		Latest  *time.Time
+	}
+	if err := db.Model(&models.StockCandle{}).
+		Select("count(*) AS candles, max(greatest(created_at, updated_at)) AS latest").
+		Where("code IN ? AND date BETWEEN ? AND ?", codeList, fromDate, toDate).
+		Scan(&summary).Error; err != nil {
+		return "", fmt.Errorf("failed to summarize candles: %w", err)
+	}
+	latest := ""
+	if summary.Latest != nil {
+		latest = summary.Latest.UTC().Format(time.RFC3339Nano)
+	}
+	return models.EntityTag(lineageKey(codes), fromDate, toDate, strconv.FormatInt(summary.Candles, 10), latest), nil
+}
+
+// LatestCandles implements PriceRepository, like MongoDB reading no further
+// back than the start of the previous year
+func (r *PostgresPriceRepository) LatestCandles(ctx context.Context, codes []string, n int) (map[string][]models.CandleData, error) {
+	db, err := r.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Date(time.Now().Year()-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var rows []models.StockCandle
+	if err := db.Raw(`
+		SELECT code, date, open, high, low, close, volume, created_at FROM (
+			SELECT *, row_number() OVER (PARTITION BY code ORDER BY date DESC) AS recency
+			FROM public.stock_candles
+			WHERE code IN ? AND date >= ?
+		) latest
+		WHERE recency <= ?
+		ORDER BY code, date`, codes, since.Format("2006-01-02"), n).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query latest candles: %w", err)
+	}
+	byCode := make(map[string][]models.CandleData, len(codes))
+	for _, row := range rows {
+		byCode[row.Code] = append(byCode[row.Code], row.Candle())
+	}
+	return byCode, nil
+}
+
+// CandlesAsOf implements PriceRepository, like MongoDB reading no further
+// back than the start of the year before date
+func (r *PostgresPriceRepository) CandlesAsOf(ctx context.Context, codes []string, date string, n int) (map[string][]models.CandleData, error) {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q: %w", date, err)
+	}
+	db, err := r.db(ctx)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Date(day.Year()-1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var rows []models.StockCandle
+	if err := db.Raw(`
+		SELECT code, date, open, high, low, close, volume, created_at FROM (
+			SELECT *, row_number() OVER (PARTITION BY code ORDER BY date DESC) AS recency
+			FROM public.stock_candles
+			WHERE code IN ? AND date >= ? AND date <= ?
+		) latest
+		WHERE recency <= ?
+		ORDER BY code, date`, codes, since.Format("2006-01-02"), date, n).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to query candles as of %s: %w", date, err)
+	}
+	byCode := make(map[string][]models.CandleData, len(codes))
+	for _, row := range rows {
+		byCode[row.Code] = append(byCode[row.Code], row.Candle())
+	}
+	return byCode, nil
+}
+
+// lineageSource is the rank of a code of a lineage and the dates it
+// contributes candles for
+type lineageSource struct {
+	rank   int
+	window models.LineageCode
+}
+
+// lineagePriority ranks the codes of a lineage, formatted like
+// models.LineageCode, by their position and returns the upper-cased codes
+// without repeats, in order. A code renamed back to has several windows.
+func lineagePriority(codes []string) (map[string][]lineageSource, []string) {
+	priority := make(map[string][]lineageSource, len(codes))
+	codeList := make([]string, 0, len(codes))
+	for rank, code := range codes {
+		window := models.ParseLineageCode(code)
+		if _, ok := priority[window.Code]; !ok {
+			codeList = append(codeList, window.Code)
+		}
+		priority[window.Code] = append(priority[window.Code], lineageSource{rank: rank, window: window})
+	}
+	return priority, codeList
+}
+
+// lineageRank returns the rank of code's candle of date in a lineage, or
+// false when no window of code covers the date
+func lineageRank(priority map[string][]lineageSource, code, date string) (int, bool) {
+	for _, source := range priority[code] {
+		if source.window.Covers(date) {
+			return source.rank, true
+		}
+	}
+	return 0, false
+}
+
+// lineageKey identifies the codes and windows of a lineage in validators
+func lineageKey(codes []string) string {
+	keys := make([]string, len(codes))
+	for i, code := range codes {
+		keys[i] = models.ParseLineageCode(code).String()
+	}
+	return strings.Join(keys, ",")
+}
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// priceAlertLog logs price alert evaluation after crawl runs
+var priceAlertLog = logging.Component("price_alerts")
+
+const (
+	// PriceAlertSource is the Notification.Source of triggered price alerts
+	PriceAlertSource = "price_alert"
+	// maxPriceAlertsPerMember caps the alerts one member can keep
+	maxPriceAlertsPerMember = 50
+	// maxPriceAlertEventLimit caps the history rows returned by one query
+	maxPriceAlertEventLimit = 500
+	// priceAlertHistoryDays is the candle history loaded to evaluate conditions
+	// (enough trading days for a smoothed RSI)
+	priceAlertHistoryDays = 180
+)
+
+var (
+	// ErrPriceAlertNotFound is returned when an alert does not exist or belongs to another member
+	ErrPriceAlertNotFound = errors.New("price alert not found")
+	// ErrInvalidPriceAlert is returned when an alert's code, condition or channels are rejected
+	ErrInvalidPriceAlert = errors.New("invalid price alert")
+	// ErrPriceAlertLimit is returned when a member already has the maximum number of alerts
+	ErrPriceAlertLimit = fmt.Errorf("a member can keep at most %d price alerts", maxPriceAlertsPerMember)
+)
+
+// PriceAlertEventFilter selects a member's alert history
+type PriceAlertEventFilter struct {
+	AlertID string
+	Limit   int
+}
+
+// PriceAlertService manages members' price alerts and evaluates them after
+// every crawl that stores new candles
+type PriceAlertService struct {
+	notifications *NotificationService
+	stockService  PriceStore
+}
+
+// NewPriceAlertService creates a new PriceAlertService instance
+func NewPriceAlertService(notifications *NotificationService, stockService PriceStore) *PriceAlertService {
+	return &PriceAlertService{
+		notifications: notifications,
+		stockService:  stockService,
+	}
+}
+
+// Channels returns the notification channels alerts can be delivered to
+func (s *PriceAlertService) Channels() []string {
+	return s.notifications.MemberChannels()
+}
+
+// ListAlerts returns a member's alerts ordered by creation time
+func (s *PriceAlertService) ListAlerts(ctx context.Context, profileID uuid.UUID) ([]models.PriceAlert, error) {
+	alerts := make([]models.PriceAlert, 0)
+	err := config.GetDBWithContext(ctx).
+		Where("profile_id = ?", profileID).
+		Order("created_at ASC").
+		Find(&alerts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price alerts: %w", err)
+	}
+	return alerts, nil
+}
+
+// CreateAlert validates and stores a new alert for alert.ProfileID
+func (s *PriceAlertService) CreateAlert(ctx context.Context, alert *models.PriceAlert) error {
+	if err := s.normalize(ctx, alert); err != nil {
+		return err
+	}
+
+	db := config.GetDBWithContext(ctx)
+	var count int64
+	if err := db.Model(&models.PriceAlert{}).Where("profile_id = ?", alert.ProfileID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count price alerts: %w", err)
+	}
+	if count >= maxPriceAlertsPerMember {
+		return ErrPriceAlertLimit
+	}
+
+	alert.ID = uuid.New()
+	alert.State = models.PriceAlertStateOK
+	if err := db.Create(alert).Error; err != nil {
+		return fmt.Errorf("failed to create price alert: %w", err)
+	}
+	return nil
+}
+
+// UpdateAlert replaces the editable fields of a member's alert. A changed
+// condition starts over in the ok state.
+func (s *PriceAlertService) UpdateAlert(ctx context.Context, profileID uuid.UUID, id string, changes models.PriceAlert) (*models.PriceAlert, error) {
+	alert, err := s.getAlert(ctx, profileID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.normalize(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	if changes.Code != alert.Code || changes.Condition != alert.Condition {
+		alert.State = models.PriceAlertStateOK
+		alert.LastValue = nil
+	}
+	alert.Code = changes.Code
+	alert.Condition = changes.Condition
+	alert.Channels = changes.Channels
+	alert.Note = changes.Note
+	alert.Enabled = changes.Enabled
+	alert.UpdatedAt = time.Now().UTC()
+	if err := config.GetDBWithContext(ctx).Model(alert).
+		Select("code", "condition", "channels", "note", "enabled", "state", "last_value", "updated_at").
+		Updates(alert).Error; err != nil {
+		return nil, fmt.Errorf("failed to update price alert: %w", err)
+	}
+	return alert, nil
+}
+
+// DeleteAlert removes a member's alert and its history
+func (s *PriceAlertService) DeleteAlert(ctx context.Context, profileID uuid.UUID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrPriceAlertNotFound
+	}
+	result := config.GetDBWithContext(ctx).Delete(&models.PriceAlert{}, "id = ? AND profile_id = ?", id, profileID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete price alert: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPriceAlertNotFound
+	}
+	return nil
+}
+
+// ListEvents returns a member's alert history, newest first
+func (s *PriceAlertService) ListEvents(ctx context.Context, profileID uuid.UUID, filter PriceAlertEventFilter) ([]models.PriceAlertEvent, error) {
+	if filter.Limit <= 0 || filter.Limit > maxPriceAlertEventLimit {
+		filter.Limit = maxPriceAlertEventLimit
+	}
+
+	query := config.GetDBWithContext(ctx).Where("profile_id = ?", profileID)
+	if filter.AlertID != "" {
+		if _, err := uuid.Parse(filter.AlertID); err != nil {
+			return nil, ErrPriceAlertNotFound
+		}
+		query = query.Where("alert_id = ?", filter.AlertID)
+	}
+
+	events := make([]models.PriceAlertEvent, 0)
+	if err := query.Order("created_at DESC").Limit(filter.Limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch price alert history: %w", err)
+	}
+	return events, nil
+}
+
+// normalize validates an alert, upper-cases its code, rewrites its condition
+// in canonical form and checks that the stock and channels exist
+func (s *PriceAlertService) normalize(ctx context.Context, alert *models.PriceAlert) error {
+	if err := alert.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPriceAlert, err)
+	}
+	condition, _ := alert.ParsedCondition()
+	alert.Condition = condition.String()
+	alert.Code = strings.ToUpper(strings.TrimSpace(alert.Code))
+	alert.Channels = strings.Join(alert.ChannelList(), ",")
+
+	available := make(map[string]bool)
+	for _, channel := range s.Channels() {
+		available[channel] = true
+	}
+	for _, channel := range alert.ChannelList() {
+		if !available[channel] {
+			return fmt.Errorf("%w: channel %q is not available (available: %s)",
+				ErrInvalidPriceAlert, channel, strings.Join(s.Channels(), ", "))
+		}
+	}
+
+	if _, err := s.stockService.GetStock(ctx, alert.Code); err != nil {
+		if errors.Is(err, ErrStockNotFound) {
+			return fmt.Errorf("%w: unknown stock code %q", ErrInvalidPriceAlert, alert.Code)
+		}
+		return err
+	}
+	return nil
+}
+
+// getAlert loads one of a member's alerts by ID
+func (s *PriceAlertService) getAlert(ctx context.Context, profileID uuid.UUID, id string) (*models.PriceAlert, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrPriceAlertNotFound
+	}
+	var alert models.PriceAlert
+	err := config.GetDBWithContext(ctx).First(&alert, "id = ? AND profile_id = ?", id, profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPriceAlertNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price alert: %w", err)
+	}
+	return &alert, nil
+}
+
+// EvaluateRun evaluates the enabled alerts on every symbol that gained
+// candles in a finished crawl run. It is registered as a crawl run listener.
+func (s *PriceAlertService) EvaluateRun(run *models.CrawlRun, newDates map[string]string) {
+	if len(newDates) == 0 || DryRun() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	codes := make([]string, 0, len(newDates))
+	for code := range newDates {
+		codes = append(codes, code)
+	}
+	var alerts []models.PriceAlert
+	if err := config.GetDBWithContext(ctx).Where("enabled = ? AND code IN ?", true, codes).Find(&alerts).Error; err != nil {
+		priceAlertLog.Error("Failed to load price alerts", "run_id", run.ID.Hex(), logging.FieldError, err)
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	byCode := make(map[string][]models.PriceAlert)
+	for _, alert := range alerts {
+		byCode[alert.Code] = append(byCode[alert.Code], alert)
+	}
+
+	triggered := 0
+	for code, codeAlerts := range byCode {
+		to, err := time.Parse("2006-01-02", newDates[code])
+		if err != nil {
+			continue
+		}
+		candles, err := s.stockService.GetCandles(ctx, []string{code}, to.AddDate(0, 0, -priceAlertHistoryDays), to)
+		if err != nil {
+			priceAlertLog.Warn("Failed to load candles for price alerts", logging.FieldSymbol, code, logging.FieldError, err)
+			continue
+		}
+		for i := range codeAlerts {
+			if s.evaluate(ctx, &codeAlerts[i], candles) {
+				triggered++
+			}
+		}
+	}
+	priceAlertLog.Info("Price alerts evaluated", "alerts", len(alerts), "symbols", len(byCode), "triggered", triggered)
+}
+
+// evaluate checks one alert against its stock's candles and notifies the
+// member when the condition starts to hold. It reports whether the alert triggered.
+func (s *PriceAlertService) evaluate(ctx context.Context, alert *models.PriceAlert, candles []models.CandleData) bool {
+	condition, err := alert.ParsedCondition()
+	if err != nil {
+		priceAlertLog.Warn("Skipping price alert", "alert_id", alert.ID.String(), logging.FieldError, err)
+		return false
+	}
+	value, ok := condition.Value(candles)
+	if !ok {
+		return false
+	}
+
+	now := time.Now().UTC()
+	holds := condition.Holds(value)
+	fire := holds && alert.State != models.PriceAlertStateTriggered
+	columns := map[string]interface{}{
+		"last_value":        value,
+		"last_evaluated_at": now,
+		"state":             models.PriceAlertStateOK,
+	}
+	if holds {
+		columns["state"] = models.PriceAlertStateTriggered
+	}
+	if fire {
+		columns["last_triggered_at"] = now
+	}
+	db := config.GetDBWithContext(ctx)
+	if err := db.Model(&models.PriceAlert{}).Where("id = ?", alert.ID).Updates(columns).Error; err != nil {
+		priceAlertLog.Warn("Failed to save price alert state", "alert_id", alert.ID.String(), logging.FieldError, err)
+	}
+	if !fire {
+		return false
+	}
+
+	latest := candles[len(candles)-1]
+	event := models.PriceAlertEvent{
+		ID:         uuid.New(),
+		AlertID:    alert.ID,
+		ProfileID:  alert.ProfileID,
+		Code:       alert.Code,
+		Condition:  alert.Condition,
+		Value:      value,
+		CandleDate: latest.D,
+		Close:      latest.C,
+		Channels:   alert.Channels,
+		CreatedAt:  now,
+	}
+	if channels := alert.ChannelList(); len(channels) > 0 {
+		if err := s.notifications.SendToMember(ctx, channels, alert.ProfileID, priceAlertNotification(*alert, condition, value, latest)); err != nil {
+			event.Error = optionalString(err.Error())
+		}
+	}
+	if err := db.Create(&event).Error; err != nil {
+		priceAlertLog.Warn("Failed to record price alert trigger", "alert_id", alert.ID.String(), logging.FieldError, err)
+	}
+	return true
+}
+
+// priceAlertNotification describes a triggered alert. Fields match the
+// placeholders of the price_alert Zalo template.
+func priceAlertNotification(alert models.PriceAlert, condition models.PriceAlertCondition, value float64, latest models.CandleData) Notification {
+	message := fmt.Sprintf("%s is %.2f, close %g on %s", condition.Metric, value, latest.C, latest.D)
+	if alert.Note != "" {
+		message += "\n" + alert.Note
+	}
+	return Notification{
+		Title:    fmt.Sprintf("%s: %s", alert.Code, alert.Condition),
+		Message:  message,
+		Severity: SeverityInfo,
+		Source:   PriceAlertSource,
+		Fields: map[string]interface{}{
+			"Code":      alert.Code,
+			"Condition": alert.Condition,
+			"Close":     latest.C,
+			"Date":      latest.D,
+		},
+	}
+}
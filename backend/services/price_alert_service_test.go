@@ -0,0 +1,31 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestPriceAlertNotificationRendersZaloTemplate(t *testing.T) {
+	alert := models.PriceAlert{Code: "HPG", Condition: "close > 30", Note: "Chốt lời"}
+	condition, _ := alert.ParsedCondition()
+	latest := models.CandleData{D: "2026-02-03", C: 30.5}
+
+	n := priceAlertNotification(alert, condition, 30.5, latest)
+	if n.Source != PriceAlertSource || n.Title != "HPG: close > 30" {
+		t.Errorf("notification = %+v; want a price alert titled with code and condition", n)
+	}
+	if !strings.Contains(n.Message, "Chốt lời") {
+		t.Errorf("message %q does not include the note", n.Message)
+	}
+
+	text, err := RenderZaloTemplate("price_alert", n.Fields)
+	if err != nil {
+		t.Fatalf("RenderZaloTemplate() error = %v", err)
+	}
+	want := "🔔 HPG: close > 30\nGiá đóng cửa 30.5 ngày 2026-02-03"
+	if text != want {
+		t.Errorf("zalo text = %q; want %q", text, want)
+	}
+}
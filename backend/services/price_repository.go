@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// PriceRepository stores the daily candles of stocks and market indexes.
+// The crawler writes candles and StockService reads them through it, so
+// either can run on MongoDB (MongoPriceRepository, yearly buckets) or
+// Supabase (PostgresPriceRepository, one row per candle) as PRICE_STORES
+// selects. Candles are only ever added: a date already stored for a code
+// keeps its candle, except the current session's, which SaveSessionCandle
+// replaces until the session closes.
+//
+// Reads take the codes of a symbol's lineage, current code first; a date
+// stored under several codes is taken from the earliest in the list.
+type PriceRepository interface {
+	// Store names the data store holding the candles (config.StoreMongo or
+	// config.StorePostgres)
+	Store() string
+	// SaveCandles adds the candles of code and returns the date of the newest
+	// candle that was not stored yet ("" if none)
+	SaveCandles(ctx context.Context, code string, candles []models.CandleData) (string, error)
+	// SaveSessionCandle stores the candle of the session in progress of code,
+	// replacing the candle stored for its date, and reports whether the date
+	// was not stored yet
+	SaveSessionCandle(ctx context.Context, code string, candle models.CandleData) (bool, error)
+	// LatestCandleDates returns the newest candle date of every code with
+	// candles in the current or previous year
+	LatestCandleDates(ctx context.Context) (map[string]string, error)
+	// Candles returns the candles of codes between from and to, by date
+	Candles(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error)
+	// StreamCandles passes the same candles as Candles to emit in date order
+	// without holding them all in memory, stopping at the first error of emit
+	StreamCandles(ctx context.Context, codes []string, from, to time.Time, emit func(models.CandleData) error) (int, error)
+	// CandlesTag returns a validator that changes whenever Candles would
+	// return other candles, including when SaveSessionCandle replaced one in
+	// place, or "" when the store cannot tell
+	CandlesTag(ctx context.Context, codes []string, from, to time.Time) (string, error)
+	// LatestCandles returns up to n of the newest candles of each code, by date
+	LatestCandles(ctx context.Context, codes []string, n int) (map[string][]models.CandleData, error)
+	// CandlesAsOf returns up to n of the candles of each code up to date
+	// (YYYY-MM-DD), by date: its newest candles as they stood that day
+	CandlesAsOf(ctx context.Context, codes []string, date string, n int) (map[string][]models.CandleData, error)
+}
+
+// rangeFilteringRepository is implemented by repositories that can read
+// candles with the date range applied in the store rather than after
+// reading, the candidate of the "candles" canary route
+type rangeFilteringRepository interface {
+	CandlesFilteredInDB(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error)
+}
+
+// NewPriceRepository returns the repository of stores (see
+// config.PriceStoresFromEnv): reads use the first store, writes go to all
+func NewPriceRepository(stores []string) (PriceRepository, error) {
+	repositories := make([]PriceRepository, 0, len(stores))
+	for _, store := range stores {
+		switch store {
+		case config.StoreMongo:
+			repositories = append(repositories, NewMongoPriceRepository())
+		case config.StorePostgres:
+			repositories = append(repositories, NewPostgresPriceRepository())
+		default:
+			return nil, fmt.Errorf("unknown price store %q", store)
+		}
+	}
+	switch len(repositories) {
+	case 0:
+		return NewMongoPriceRepository(), nil
+	case 1:
+		return repositories[0], nil
+	}
+	return &MirroredPriceRepository{PriceRepository: repositories[0], mirrors: repositories[1:]}, nil
+}
+
+// MirroredPriceRepository reads from one repository and writes to it and to
+// mirrors, so a store being migrated to receives every new candle while the
+// API keeps reading the current one. A failed mirror write is logged and
+// does not fail the crawl; the mirror catches up with copy-prices.
+type MirroredPriceRepository struct {
+	PriceRepository
+	mirrors []PriceRepository
+}
+
+// SaveCandles saves candles to the primary repository, then to the mirrors
+func (r *MirroredPriceRepository) SaveCandles(ctx context.Context, code string, candles []models.CandleData) (string, error) {
+	newest, err := r.PriceRepository.SaveCandles(ctx, code, candles)
+	if err != nil {
+		return "", err
+	}
+	for _, mirror := range r.mirrors {
+		if _, err := mirror.SaveCandles(ctx, code, candles); err != nil {
+			crawlLog.Warn("Failed to mirror candles", logging.FieldSymbol, code, "store", mirror.Store(), logging.FieldError, err)
+		}
+	}
+	return newest, nil
+}
+
+// SaveSessionCandle saves the candle to the primary repository, then to the
+// mirrors
+func (r *MirroredPriceRepository) SaveSessionCandle(ctx context.Context, code string, candle models.CandleData) (bool, error) {
+	added, err := r.PriceRepository.SaveSessionCandle(ctx, code, candle)
+	if err != nil {
+		return false, err
+	}
+	for _, mirror := range r.mirrors {
+		if _, err := mirror.SaveSessionCandle(ctx, code, candle); err != nil {
+			crawlLog.Warn("Failed to mirror candles", logging.FieldSymbol, code, "store", mirror.Store(), logging.FieldError, err)
+		}
+	}
+	return added, nil
+}
+
+// CandlesFilteredInDB reads with the primary repository's range filtering,
+// or like Candles when it has none
+func (r *MirroredPriceRepository) CandlesFilteredInDB(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error) {
+	if filtering, ok := r.PriceRepository.(rangeFilteringRepository); ok {
+		return filtering.CandlesFilteredInDB(ctx, codes, from, to)
+	}
+	return r.PriceRepository.Candles(ctx, codes, from, to)
+}
+
+// CopyCandles saves the candles of code between from and to of source in
+// target and returns how many were read. Candles target already has for a
+// date are kept.
+func CopyCandles(ctx context.Context, source, target PriceRepository, code string, from, to time.Time) (int, error) {
+	candles, err := source.Candles(ctx, []string{code}, from, to)
+	if err != nil || len(candles) == 0 {
+		return 0, err
+	}
+	if _, err := target.SaveCandles(ctx, code, candles); err != nil {
+		return 0, err
+	}
+	return len(candles), nil
+}
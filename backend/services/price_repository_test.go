@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// memoryPriceRepository keeps candles by code, for repository tests
+type memoryPriceRepository struct {
+	store   string
+	candles map[string][]models.CandleData
+	err     error
+}
+
+func (r *memoryPriceRepository) Store() string { return r.store }
+
+func (r *memoryPriceRepository) SaveCandles(ctx context.Context, code string, candles []models.CandleData) (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	newest := ""
+	for _, candle := range candles {
+		stored := false
+		for _, existing := range r.candles[code] {
+			stored = stored || existing.D == candle.D
+		}
+		if !stored {
+			r.candles[code] = append(r.candles[code], candle)
+			newest = newestCandleDate(newest, []models.CandleData{candle})
+		}
+	}
+	return newest, nil
+}
+
+func (r *memoryPriceRepository) SaveSessionCandle(ctx context.Context, code string, candle models.CandleData) (bool, error) {
+	if r.err != nil {
+		return false, r.err
+	}
+	for i, existing := range r.candles[code] {
+		if existing.D == candle.D {
+			r.candles[code][i] = candle
+			return false, nil
+		}
+	}
+	r.candles[code] = append(r.candles[code], candle)
+	return true, nil
+}
+
+func (r *memoryPriceRepository) LatestCandleDates(ctx context.Context) (map[string]string, error) {
+	return nil, r.err
+}
+
+func (r *memoryPriceRepository) Candles(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error) {
+	priority, _ := lineagePriority(codes)
+	var buckets []models.PriceBucket
+	for code := range priority {
+		buckets = append(buckets, models.PriceBucket{Code: code, History: r.candles[code]})
+	}
+	return mergeBucketCandles(buckets, priority, from.Format("2006-01-02"), to.Format("2006-01-02")), r.err
+}
+
+func (r *memoryPriceRepository) StreamCandles(ctx context.Context, codes []string, from, to time.Time, emit func(models.CandleData) error) (int, error) {
+	return 0, r.err
+}
+
+func (r *memoryPriceRepository) CandlesTag(ctx context.Context, codes []string, from, to time.Time) (string, error) {
+	return "", r.err
+}
+
+func (r *memoryPriceRepository) LatestCandles(ctx context.Context, codes []string, n int) (map[string][]models.CandleData, error) {
+	return nil, r.err
+}
+
+func (r *memoryPriceRepository) CandlesAsOf(ctx context.Context, codes []string, date string, n int) (map[string][]models.CandleData, error) {
+	return nil, r.err
+}
+
+func TestMirroredPriceRepository(t *testing.T) {
+	primary := &memoryPriceRepository{store: config.StoreMongo, candles: map[string][]models.CandleData{
+		"HPG": {{D: "2026-03-02", C: 25}},
+	}}
+	mirror := &memoryPriceRepository{store: config.StorePostgres, candles: map[string][]models.CandleData{}}
+	prices := &MirroredPriceRepository{PriceRepository: primary, mirrors: []PriceRepository{mirror}}
+
+	candles := []models.CandleData{{D: "2026-03-02", C: 25}, {D: "2026-03-03", C: 26}}
+	newest, err := prices.SaveCandles(context.Background(), "HPG", candles)
+	if err != nil || newest != "2026-03-03" {
+		t.Fatalf("SaveCandles() = %q, %v; want the primary's newest new date 2026-03-03", newest, err)
+	}
+	if len(mirror.candles["HPG"]) != 2 {
+		t.Errorf("mirror candles = %v; want both candles", mirror.candles["HPG"])
+	}
+
+	// A failing mirror does not fail the write
+	mirror.err = errors.New("connection refused")
+	if _, err := prices.SaveCandles(context.Background(), "HPG", []models.CandleData{{D: "2026-03-04", C: 27}}); err != nil {
+		t.Errorf("SaveCandles() with a failing mirror = %v; want nil", err)
+	}
+	if prices.Store() != config.StoreMongo {
+		t.Errorf("Store() = %q; want the primary's", prices.Store())
+	}
+}
+
+func TestCopyCandles(t *testing.T) {
+	source := &memoryPriceRepository{candles: map[string][]models.CandleData{
+		"FPT": {{D: "2026-03-02", C: 90}, {D: "2026-03-03", C: 91}},
+	}}
+	target := &memoryPriceRepository{candles: map[string][]models.CandleData{
+		"FPT": {{D: "2026-03-02", C: 89}},
+	}}
+	from, to := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	n, err := CopyCandles(context.Background(), source, target, "FPT", from, to)
+	if err != nil || n != 2 {
+		t.Fatalf("CopyCandles() = %d, %v; want 2 candles read", n, err)
+	}
+	copied, _ := target.Candles(context.Background(), []string{"FPT"}, from, to)
+	if len(copied) != 2 || copied[0].C != 89 || copied[1].C != 91 {
+		t.Errorf("target candles = %v; want the stored candle kept and the missing one added", copied)
+	}
+}
+
+func TestNewPriceRepository(t *testing.T) {
+	prices, err := NewPriceRepository([]string{config.StorePostgres})
+	if _, ok := prices.(*PostgresPriceRepository); err != nil || !ok {
+		t.Errorf("NewPriceRepository(postgres) = %#v, %v; want the Postgres repository alone", prices, err)
+	}
+	if _, err := NewPriceRepository([]string{"redis"}); err == nil {
+		t.Error("NewPriceRepository(redis) expected an error")
+	}
+}
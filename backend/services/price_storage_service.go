@@ -0,0 +1,200 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrInvalidPriceEncoding is returned for an unknown target encoding
+	ErrInvalidPriceEncoding = errors.New("encoding must be plain or columnar")
+	// ErrBucketConflict is returned when a price bucket was changed by another
+	// writer between being read and being written back
+	ErrBucketConflict = errors.New("price bucket was modified concurrently")
+)
+
+// bucketUnchangedFilter matches bucket only while it still has the checksum
+// it was read with, guarding read-modify-write updates
+func bucketUnchangedFilter(bucket models.PriceBucket) bson.M {
+	filter := bson.M{"_id": bucket.ID, "checksum": bucket.Checksum}
+	if bucket.Checksum == "" {
+		filter["checksum"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+// PriceEncodingNames maps the names used by the API to bucket encodings
+var PriceEncodingNames = map[string]string{
+	"plain":    models.BucketEncodingPlain,
+	"columnar": models.BucketEncodingColumnar,
+}
+
+// priceEncodingName returns the API name of a bucket encoding
+func priceEncodingName(encoding string) string {
+	for name, value := range PriceEncodingNames {
+		if value == encoding {
+			return name
+		}
+	}
+	return encoding
+}
+
+// PriceEncodingStats is the storage used by the buckets of one encoding
+type PriceEncodingStats struct {
+	Encoding string `json:"encoding"`
+	Buckets  int64  `json:"buckets"`
+	Bytes    int64  `json:"bytes"` // Total BSON size of the documents
+}
+
+// PriceStorageStats summarizes how price buckets are stored
+type PriceStorageStats struct {
+	DefaultEncoding string               `json:"default_encoding"` // Used by the crawler for new writes
+	Encodings       []PriceEncodingStats `json:"encodings"`
+}
+
+// ConvertOptions selects the buckets to re-encode
+type ConvertOptions struct {
+	Encoding string `json:"encoding" binding:"required"` // plain or columnar
+	Code     string `json:"code"`                        // Only buckets of this stock (optional)
+	Year     int    `json:"year"`                        // Only buckets of this year (optional)
+}
+
+// ConvertReport summarizes a conversion run
+type ConvertReport struct {
+	Checked     int      `json:"checked"`
+	Converted   int      `json:"converted"`
+	Unchanged   int      `json:"unchanged"` // Already in the target encoding
+	BytesBefore int64    `json:"bytes_before"`
+	BytesAfter  int64    `json:"bytes_after"`
+	Failed      []string `json:"failed"` // Buckets left untouched because the round-trip check failed
+}
+
+// PriceStorageService reports and converts the storage encoding of price buckets
+type PriceStorageService struct {
+	priceCollection *mongo.Collection
+}
+
+// NewPriceStorageService creates a new PriceStorageService instance
+func NewPriceStorageService() *PriceStorageService {
+	return &PriceStorageService{
+		priceCollection: config.GetCollection("stock_prices"),
+	}
+}
+
+// Stats returns the bucket count and stored size per encoding
+func (s *PriceStorageService) Stats(ctx context.Context) (*PriceStorageStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":     bson.M{"$ifNull": bson.A{"$enc", models.BucketEncodingPlain}},
+			"buckets": bson.M{"$sum": 1},
+			"bytes":   bson.M{"$sum": bson.M{"$bsonSize": "$$ROOT"}},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+	cursor, err := s.priceCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate price storage: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	stats := &PriceStorageStats{
+		DefaultEncoding: priceEncodingName(config.Runtime().PriceStorageEncoding),
+		Encodings:       make([]PriceEncodingStats, 0),
+	}
+	for cursor.Next(ctx) {
+		var row struct {
+			Encoding string `bson:"_id"`
+			Buckets  int64  `bson:"buckets"`
+			Bytes    int64  `bson:"bytes"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode price storage stats: %w", err)
+		}
+		stats.Encodings = append(stats.Encodings, PriceEncodingStats{
+			Encoding: priceEncodingName(row.Encoding),
+			Buckets:  row.Buckets,
+			Bytes:    row.Bytes,
+		})
+	}
+	return stats, cursor.Err()
+}
+
+// Convert re-encodes the selected buckets. Each bucket is only replaced when
+// its candles survive the round-trip with an identical checksum.
+func (s *PriceStorageService) Convert(ctx context.Context, opts ConvertOptions) (*ConvertReport, error) {
+	encoding, ok := PriceEncodingNames[strings.ToLower(opts.Encoding)]
+	if !ok {
+		return nil, ErrInvalidPriceEncoding
+	}
+
+	filter := bson.M{}
+	if opts.Code != "" {
+		filter["code"] = strings.ToUpper(opts.Code)
+	}
+	if opts.Year != 0 {
+		filter["year"] = opts.Year
+	}
+
+	cursor, err := s.priceCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query price buckets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	report := &ConvertReport{Failed: make([]string, 0)}
+	for cursor.Next(ctx) {
+		var bucket models.PriceBucket
+		if err := cursor.Decode(&bucket); err != nil {
+			return nil, fmt.Errorf("failed to decode price bucket: %w", err)
+		}
+		report.Checked++
+		before := int64(len(cursor.Current))
+		if bucket.Encoding == encoding {
+			report.Unchanged++
+			report.BytesBefore += before
+			report.BytesAfter += before
+			continue
+		}
+
+		bucket.Encoding = encoding
+		raw, err := bson.Marshal(bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode bucket %s: %w", bucket.ID, err)
+		}
+		var decoded models.PriceBucket
+		if err := bson.Unmarshal(raw, &decoded); err != nil ||
+			models.ComputeChecksum(decoded.History) != models.ComputeChecksum(bucket.History) {
+			report.Failed = append(report.Failed, bucket.ID)
+			report.BytesBefore += before
+			report.BytesAfter += before
+			continue
+		}
+
+		result, err := s.priceCollection.ReplaceOne(ctx, bucketUnchangedFilter(bucket), bucket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store bucket %s: %w", bucket.ID, err)
+		}
+		if result.MatchedCount == 0 {
+			return report, fmt.Errorf("%w: %s changed during conversion, retry", ErrBucketConflict, bucket.ID)
+		}
+		report.Converted++
+		report.BytesBefore += before
+		report.BytesAfter += int64(len(raw))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read price buckets: %w", err)
+	}
+	return report, nil
+}
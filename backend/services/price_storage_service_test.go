@@ -0,0 +1,22 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestBucketUnchangedFilter(t *testing.T) {
+	signed := models.PriceBucket{ID: "HPG_2024", Checksum: "abc"}
+	if got, want := bucketUnchangedFilter(signed), (bson.M{"_id": "HPG_2024", "checksum": "abc"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("bucketUnchangedFilter(signed) = %v; want %v", got, want)
+	}
+
+	unsigned := models.PriceBucket{ID: "HPG_2023"}
+	want := bson.M{"_id": "HPG_2023", "checksum": bson.M{"$exists": false}}
+	if got := bucketUnchangedFilter(unsigned); !reflect.DeepEqual(got, want) {
+		t.Errorf("bucketUnchangedFilter(unsigned) = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// streamLog logs the change stream feeding real-time price push
+var streamLog = logging.Component("price_stream")
+
+const (
+	// priceSubscriberBuffer is how many updates a slow client may lag behind
+	// before further updates to it are dropped
+	priceSubscriberBuffer = 64
+	// changeStreamRetryMax caps the wait before reopening a failed change stream
+	changeStreamRetryMax = time.Minute
+)
+
+// PriceUpdate is pushed to subscribers when a symbol's candles change
+type PriceUpdate struct {
+	Code   string            `json:"code"`
+	Year   int               `json:"year"`
+	Candle models.CandleData `json:"candle"` // Latest candle of the bucket after the write
+	At     time.Time         `json:"at"`
+}
+
+// PriceSubscription receives the updates of the symbols it is subscribed to
+type PriceSubscription struct {
+	Updates <-chan PriceUpdate
+
+	updates chan PriceUpdate
+	mu      sync.RWMutex
+	codes   map[string]bool // Empty: every symbol
+	dropped atomic.Int64
+}
+
+// Subscribe adds symbols to the subscription
+func (s *PriceSubscription) Subscribe(codes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range codes {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			s.codes[code] = true
+		}
+	}
+}
+
+// Unsubscribe removes symbols from the subscription
+func (s *PriceSubscription) Unsubscribe(codes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, code := range codes {
+		delete(s.codes, strings.ToUpper(strings.TrimSpace(code)))
+	}
+}
+
+// Codes returns the subscribed symbols, sorted (empty: every symbol)
+func (s *PriceSubscription) Codes() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	codes := make([]string, 0, len(s.codes))
+	for code := range s.codes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Dropped returns how many updates were skipped because the client was too slow
+func (s *PriceSubscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func (s *PriceSubscription) wants(code string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.codes) == 0 || s.codes[code]
+}
+
+// PriceStreamService fans out price bucket writes to WebSocket and SSE
+// clients. Writes are observed through a MongoDB change stream, so the
+// crawler never waits on real-time delivery and updates made by other
+// instances are pushed too.
+type PriceStreamService struct {
+	priceCollection *mongo.Collection
+
+	mu          sync.RWMutex
+	subscribers map[*PriceSubscription]struct{}
+	watching    atomic.Bool
+	fed         atomic.Bool // A realtime quote source publishes directly
+}
+
+// NewPriceStreamService creates a new PriceStreamService instance
+func NewPriceStreamService() *PriceStreamService {
+	return &PriceStreamService{
+		priceCollection: config.GetCollection("stock_prices"),
+		subscribers:     make(map[*PriceSubscription]struct{}),
+	}
+}
+
+// Subscribe registers a client for the given symbols (none: every symbol).
+// Call Unsubscribe when the client disconnects.
+func (s *PriceStreamService) Subscribe(codes ...string) *PriceSubscription {
+	updates := make(chan PriceUpdate, priceSubscriberBuffer)
+	sub := &PriceSubscription{Updates: updates, updates: updates, codes: make(map[string]bool)}
+	sub.Subscribe(codes...)
+
+	s.mu.Lock()
+	s.subscribers[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe removes a client and closes its update channel
+func (s *PriceStreamService) Unsubscribe(sub *PriceSubscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscribers[sub]; ok {
+		delete(s.subscribers, sub)
+		close(sub.updates)
+	}
+}
+
+// CloseAll disconnects every client by closing its update channel, so open
+// SSE and WebSocket streams end and clients reconnect to another instance.
+// It runs when the server starts shutting down, so draining requests does
+// not wait on streams that never finish.
+func (s *PriceStreamService) CloseAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for sub := range s.subscribers {
+		delete(s.subscribers, sub)
+		close(sub.updates)
+	}
+}
+
+// Available reports whether updates are being delivered: the change stream
+// is open or a realtime quote source feeds the stream
+func (s *PriceStreamService) Available() bool {
+	return s.watching.Load() || s.fed.Load()
+}
+
+// MarkFed records that a realtime quote source publishes to the stream, so
+// clients are served without the change stream
+func (s *PriceStreamService) MarkFed() {
+	s.fed.Store(true)
+}
+
+// Subscribers returns the number of connected clients
+func (s *PriceStreamService) Subscribers() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.subscribers)
+}
+
+// Publish delivers an update to every interested subscriber without
+// blocking: updates to clients whose buffer is full are dropped
+func (s *PriceStreamService) Publish(update PriceUpdate) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for sub := range s.subscribers {
+		if !sub.wants(update.Code) {
+			continue
+		}
+		select {
+		case sub.updates <- update:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// StartWatching consumes the price bucket change stream until ctx is
+// cancelled, reopening it after errors from the last seen resume token.
+// Change streams need a replica set; on a standalone server the watcher
+// logs a warning and stops.
+func (s *PriceStreamService) StartWatching(ctx context.Context) {
+	go func() {
+		var resumeToken bson.Raw
+		wait := time.Second
+		for {
+			token, err := s.watch(ctx, resumeToken)
+			if token != nil {
+				resumeToken = token
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			var cmdErr mongo.CommandError
+			if errors.As(err, &cmdErr) && (cmdErr.Code == 40573 || cmdErr.Name == "IllegalOperation") {
+				streamLog.Warn("Real-time price push disabled: change streams need a MongoDB replica set", logging.FieldError, err)
+				return
+			}
+			if errors.As(err, &cmdErr) && cmdErr.Code == 286 {
+				// ChangeStreamHistoryLost: the resume token fell off the oplog
+				resumeToken = nil
+			}
+			streamLog.Warn("Price change stream interrupted", "retry_in", wait.String(), logging.FieldError, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			if wait *= 2; wait > changeStreamRetryMax {
+				wait = changeStreamRetryMax
+			}
+		}
+	}()
+}
+
+// watch streams changes until an error occurs, returning the last resume token
+func (s *PriceStreamService) watch(ctx context.Context, resumeAfter bson.Raw) (bson.Raw, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace"}}}}},
+	}
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeAfter != nil {
+		opts.SetResumeAfter(resumeAfter)
+	}
+
+	stream, err := s.priceCollection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close(context.Background())
+
+	s.watching.Store(true)
+	defer s.watching.Store(false)
+	streamLog.Info("Watching price buckets for real-time push")
+
+	for stream.Next(ctx) {
+		var event struct {
+			FullDocument *models.PriceBucket `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&event); err != nil {
+			streamLog.Warn("Failed to decode price change event", logging.FieldError, err)
+			continue
+		}
+		if event.FullDocument != nil {
+			if update, ok := latestPriceUpdate(event.FullDocument); ok {
+				s.Publish(update)
+			}
+		}
+	}
+	return stream.ResumeToken(), stream.Err()
+}
+
+// latestPriceUpdate returns the newest candle of a bucket as an update
+func latestPriceUpdate(bucket *models.PriceBucket) (PriceUpdate, bool) {
+	if len(bucket.History) == 0 {
+		return PriceUpdate{}, false
+	}
+	latest := bucket.History[0]
+	for _, candle := range bucket.History[1:] {
+		if candle.D > latest.D {
+			latest = candle
+		}
+	}
+	return PriceUpdate{
+		Code:   bucket.Code,
+		Year:   bucket.Year,
+		Candle: latest,
+		At:     time.Now().UTC(),
+	}, true
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestPriceStreamPublish(t *testing.T) {
+	s := &PriceStreamService{subscribers: make(map[*PriceSubscription]struct{})}
+	all := s.Subscribe()
+	hpg := s.Subscribe("hpg")
+
+	s.Publish(PriceUpdate{Code: "VNM"})
+	s.Publish(PriceUpdate{Code: "HPG"})
+
+	if got := len(all.Updates); got != 2 {
+		t.Errorf("unfiltered subscriber received %d updates; want 2", got)
+	}
+	if got := len(hpg.Updates); got != 1 {
+		t.Errorf("HPG subscriber received %d updates; want 1", got)
+	}
+
+	hpg.Unsubscribe("HPG")
+	hpg.Subscribe("VNM")
+	s.Publish(PriceUpdate{Code: "HPG"})
+	if got := len(hpg.Updates); got != 1 {
+		t.Errorf("HPG update delivered after unsubscribing (%d queued)", got)
+	}
+
+	// A full buffer drops updates instead of blocking the publisher
+	for i := 0; i < priceSubscriberBuffer+5; i++ {
+		s.Publish(PriceUpdate{Code: "VNM"})
+	}
+	if all.Dropped() == 0 {
+		t.Error("expected updates to a full subscriber to be dropped")
+	}
+
+	s.Unsubscribe(all)
+	s.Unsubscribe(all)
+	if s.Subscribers() != 1 {
+		t.Errorf("Subscribers() = %d; want 1", s.Subscribers())
+	}
+}
+
+func TestLatestPriceUpdate(t *testing.T) {
+	bucket := &models.PriceBucket{Code: "HPG", Year: 2024, History: []models.CandleData{
+		{D: "2024-01-03", C: 27.1},
+		{D: "2024-01-05", C: 27.6},
+		{D: "2024-01-04", C: 27.3},
+	}}
+	update, ok := latestPriceUpdate(bucket)
+	if !ok || update.Candle.D != "2024-01-05" || update.Code != "HPG" {
+		t.Errorf("latestPriceUpdate() = %+v, %v; want the 2024-01-05 candle", update, ok)
+	}
+	if _, ok := latestPriceUpdate(&models.PriceBucket{}); ok {
+		t.Error("latestPriceUpdate() of an empty bucket should report no update")
+	}
+}
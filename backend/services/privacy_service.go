@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxDataErasures caps the rows returned by one erasure log query
+const maxDataErasures = 500
+
+var (
+	// ErrProfileErased is returned when a profile's data was already erased
+	ErrProfileErased = errors.New("profile data already erased")
+	// ErrErasureNotConfirmed is returned when a member's erasure request does not repeat their email
+	ErrErasureNotConfirmed = errors.New("erasure must be confirmed with the profile's email")
+)
+
+// ProfileDataExport is everything stored about one member
+type ProfileDataExport struct {
+	ExportedAt   time.Time                    `json:"exported_at"`
+	Profile      models.Profile               `json:"profile"`
+	Tokens       []models.PersonalAccessToken `json:"personal_access_tokens"`
+	Alerts       []models.PriceAlert          `json:"price_alerts"`
+	AlertEvents  []models.PriceAlertEvent     `json:"price_alert_events"`
+	Watchlists   []models.Watchlist           `json:"watchlists"`
+	Screens      []models.ScreenerPreset      `json:"screener_presets"`
+	Trades       []models.PortfolioTrade      `json:"portfolio_trades"`
+	Payments     []models.Payment             `json:"payments"`
+	ZaloMessages []models.ZaloMessage         `json:"zalo_messages"`
+	AuditLogs    []ExportedAuditLog           `json:"audit_logs"` // Admin actions on the profile
+}
+
+// ExportedAuditLog is an audit log entry about the profile as exported: what
+// was done and when, without the admin, IP, user agent or details
+type ExportedAuditLog struct {
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// exportedAuditLogs redacts audit log entries for a data export
+func exportedAuditLogs(logs []models.AuditLog) []ExportedAuditLog {
+	exported := make([]ExportedAuditLog, 0, len(logs))
+	for _, entry := range logs {
+		exported = append(exported, ExportedAuditLog{
+			Action:    entry.Action,
+			Outcome:   entry.Outcome,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+	return exported
+}
+
+// PrivacyService exports and erases the data held about members
+type PrivacyService struct {
+	auditService *AuditService
+}
+
+// NewPrivacyService creates a new PrivacyService instance
+func NewPrivacyService(auditService *AuditService) *PrivacyService {
+	return &PrivacyService{auditService: auditService}
+}
+
+// Export collects all data held about a profile. The stored TCBS API key is
+// left out; the export only tells whether one is connected. Audit log
+// entries keep only their action, outcome and time.
+func (s *PrivacyService) Export(ctx context.Context, profileID uuid.UUID, actor, ip string) (*ProfileDataExport, error) {
+	db := config.GetDBWithContext(ctx)
+
+	export := ProfileDataExport{ExportedAt: time.Now().UTC()}
+	err := db.First(&export.Profile, "id = ?", profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up profile: %w", err)
+	}
+	export.Profile.TCBSAPIKey = nil
+
+	owned := []struct {
+		name string
+		dest interface{}
+	}{
+		{"personal access tokens", &export.Tokens},
+		{"price alerts", &export.Alerts},
+		{"price alert events", &export.AlertEvents},
+		{"watchlists", &export.Watchlists},
+		{"screener presets", &export.Screens},
+		{"portfolio trades", &export.Trades},
+		{"payments", &export.Payments},
+		{"zalo messages", &export.ZaloMessages},
+	}
+	for _, table := range owned {
+		if err := db.Where("profile_id = ?", profileID).Order("created_at").Find(table.dest).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", table.name, err)
+		}
+	}
+	for i := range export.Watchlists {
+		var codes []string
+		err := db.Model(&models.WatchlistSymbol{}).
+			Where("watchlist_id = ?", export.Watchlists[i].ID).
+			Order("position").
+			Pluck("code", &codes).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch watchlist symbols: %w", err)
+		}
+		export.Watchlists[i].Codes = codes
+	}
+	var auditLogs []models.AuditLog
+	err = db.Where("entity_type = ? AND entity_id = ?", "profile", profileID.String()).
+		Order("created_at").
+		Find(&auditLogs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch audit logs: %w", err)
+	}
+	export.AuditLogs = exportedAuditLogs(auditLogs)
+
+	s.auditService.Record(ctx, AuditEntry{
+		Actor:      actor,
+		Action:     models.AuditActionDataExport,
+		Outcome:    models.AuditOutcomeSuccess,
+		EntityType: "profile",
+		EntityID:   profileID.String(),
+		IP:         ip,
+	})
+	return &export, nil
+}
+
+// Erase deletes a member's tokens, alerts, watchlists, screens, trades and Zalo
+// messages, anonymizes the profile row and payment records, and strips the
+// IP, user agent and details from audit logs about the profile, all in one
+// transaction. Payments keep their amounts for accounting; the profile row
+// stays because it is keyed by the Supabase Auth user, which has to be
+// deleted in Supabase. The erasure is logged in data_erasures.
+func (s *PrivacyService) Erase(ctx context.Context, profileID uuid.UUID, requestedBy, reason, ip string) (*models.DataErasure, error) {
+	db := config.GetDBWithContext(ctx)
+
+	var profile models.Profile
+	err := db.First(&profile, "id = ?", profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up profile: %w", err)
+	}
+	var erased int64
+	if err := db.Model(&models.DataErasure{}).Where("profile_id = ?", profileID).Count(&erased).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up data erasures: %w", err)
+	}
+	if erased > 0 {
+		return nil, ErrProfileErased
+	}
+
+	now := time.Now().UTC()
+	rows := make(map[string]int64)
+	erasure := models.DataErasure{
+		ID:          uuid.New(),
+		ProfileID:   profileID,
+		EmailHash:   models.ErasureEmailHash(profile.Email),
+		RequestedBy: requestedBy,
+		Reason:      optionalString(strings.TrimSpace(reason)),
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Alert events before alerts and symbols before watchlists, in case
+		// the foreign keys do not cascade
+		deletes := []struct {
+			table string
+			model interface{}
+			where string
+			arg   interface{}
+		}{
+			{"personal_access_tokens", &models.PersonalAccessToken{}, "profile_id = ?", profileID},
+			{"price_alert_events", &models.PriceAlertEvent{}, "profile_id = ?", profileID},
+			{"price_alerts", &models.PriceAlert{}, "profile_id = ?", profileID},
+			{"watchlist_symbols", &models.WatchlistSymbol{}, "watchlist_id IN (?)", tx.Model(&models.Watchlist{}).Select("id").Where("profile_id = ?", profileID)},
+			{"watchlists", &models.Watchlist{}, "profile_id = ?", profileID},
+			{"screener_presets", &models.ScreenerPreset{}, "profile_id = ?", profileID},
+			{"portfolio_trades", &models.PortfolioTrade{}, "profile_id = ?", profileID},
+			{"zalo_messages", &models.ZaloMessage{}, "profile_id = ?", profileID},
+		}
+		for _, d := range deletes {
+			result := tx.Where(d.where, d.arg).Delete(d.model)
+			if result.Error != nil {
+				return fmt.Errorf("failed to delete %s: %w", d.table, result.Error)
+			}
+			rows[d.table] = result.RowsAffected
+		}
+
+		result := tx.Model(&models.Payment{}).Where("profile_id = ?", profileID).
+			Updates(map[string]interface{}{"payload": "{}", "updated_at": now})
+		if result.Error != nil {
+			return fmt.Errorf("failed to anonymize payments: %w", result.Error)
+		}
+		rows["payments"] = result.RowsAffected
+
+		result = tx.Model(&models.AuditLog{}).
+			Where("entity_type = ? AND entity_id = ?", "profile", profileID.String()).
+			Updates(map[string]interface{}{"ip": nil, "user_agent": nil, "details": nil})
+		if result.Error != nil {
+			return fmt.Errorf("failed to anonymize audit logs: %w", result.Error)
+		}
+		rows["audit_logs"] = result.RowsAffected
+
+		result = tx.Model(&models.Profile{}).Where("id = ?", profileID).Updates(erasedProfileColumns(profileID, now))
+		if result.Error != nil {
+			return fmt.Errorf("failed to anonymize profile: %w", result.Error)
+		}
+		rows["profiles"] = result.RowsAffected
+
+		counts, err := json.Marshal(rows)
+		if err != nil {
+			return fmt.Errorf("failed to encode erasure counts: %w", err)
+		}
+		erasure.Rows = string(counts)
+		if err := tx.Create(&erasure).Error; err != nil {
+			return fmt.Errorf("failed to log data erasure: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Recorded after the audit logs were stripped, so this row survives
+	s.auditService.Record(ctx, AuditEntry{
+		Actor:      requestedBy,
+		Action:     models.AuditActionDataErase,
+		Outcome:    models.AuditOutcomeSuccess,
+		EntityType: "profile",
+		EntityID:   profileID.String(),
+		IP:         ip,
+		Details:    map[string]interface{}{"erasure_id": erasure.ID.String()},
+	})
+	return &erasure, nil
+}
+
+// EraseOwn erases the data of the requesting member after checking that
+// confirm repeats the profile's email. The member's IP is not recorded.
+func (s *PrivacyService) EraseOwn(ctx context.Context, profileID uuid.UUID, confirm string) (*models.DataErasure, error) {
+	var profile models.Profile
+	err := config.GetDBWithContext(ctx).Select("email").First(&profile, "id = ?", profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up profile: %w", err)
+	}
+	if !strings.EqualFold(strings.TrimSpace(confirm), profile.Email) {
+		return nil, ErrErasureNotConfirmed
+	}
+	return s.Erase(ctx, profileID, models.ErasureRequestedByMember, "", "")
+}
+
+// ListErasures returns the erasure log, newest first. An email filter is
+// matched by its hash.
+func (s *PrivacyService) ListErasures(ctx context.Context, email string, limit int) ([]models.DataErasure, error) {
+	if limit <= 0 || limit > maxDataErasures {
+		limit = maxDataErasures
+	}
+	query := config.GetDBWithContext(ctx).Order("created_at DESC").Limit(limit)
+	if email = strings.TrimSpace(email); email != "" {
+		query = query.Where("email_hash = ?", models.ErasureEmailHash(email))
+	}
+
+	erasures := make([]models.DataErasure, 0)
+	if err := query.Find(&erasures).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch data erasures: %w", err)
+	}
+	return erasures, nil
+}
+
+// erasedProfileColumns returns the profile columns written by an erasure:
+// personal fields cleared, the unique email replaced by a placeholder and
+// the profile deactivated. Membership is kept, as paid-for state.
+func erasedProfileColumns(profileID uuid.UUID, now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"email":                models.ErasedEmail(profileID),
+		"phone_number":         "",
+		"full_name":            nil,
+		"nickname":             nil,
+		"stock_account_number": nil,
+		"avatar_url":           nil,
+		"zalo_id":              nil,
+		"birthday":             nil,
+		"gender":               nil,
+		"tcbs_api_key":         nil,
+		"tcbs_connected_at":    nil,
+		"active":               false,
+		"deactivated_at":       now,
+		"updated_at":           now,
+	}
+}
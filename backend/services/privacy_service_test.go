@@ -0,0 +1,75 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+)
+
+func TestErasedProfileColumns(t *testing.T) {
+	id := uuid.MustParse("7b0f7d7e-3f3c-4a43-9a55-0c1f0d6b2a11")
+	now := time.Date(2026, 2, 9, 3, 0, 0, 0, time.UTC)
+	columns := erasedProfileColumns(id, now)
+
+	if columns["email"] != "erased-7b0f7d7e-3f3c-4a43-9a55-0c1f0d6b2a11@erased.invalid" {
+		t.Errorf("email = %v", columns["email"])
+	}
+	if columns["active"] != false || columns["deactivated_at"] != now {
+		t.Errorf("profile not deactivated: %v", columns)
+	}
+	for _, column := range []string{"full_name", "nickname", "stock_account_number", "avatar_url", "zalo_id", "birthday", "gender", "tcbs_api_key"} {
+		value, ok := columns[column]
+		if !ok || value != nil {
+			t.Errorf("%s = %v, want cleared", column, value)
+		}
+	}
+	if _, ok := columns["membership"]; ok {
+		t.Error("membership should be kept")
+	}
+}
+
+func TestErasureEmailHashNormalizes(t *testing.T) {
+	a := models.ErasureEmailHash("Member@Example.com ")
+	b := models.ErasureEmailHash("member@example.com")
+	if a != b {
+		t.Errorf("hashes differ: %s vs %s", a, b)
+	}
+	if len(a) != 64 || strings.Contains(a, "example") {
+		t.Errorf("unexpected hash %q", a)
+	}
+}
+
+func TestExportedAuditLogsRedact(t *testing.T) {
+	str := func(s string) *string { return &s }
+	at := time.Date(2026, 2, 9, 3, 0, 0, 0, time.UTC)
+	logs := []models.AuditLog{{
+		ID:         uuid.New(),
+		Actor:      "ops-admin",
+		Action:     models.AuditActionDataExport,
+		Outcome:    models.AuditOutcomeSuccess,
+		EntityType: str("profile"),
+		EntityID:   str("7b0f7d7e-3f3c-4a43-9a55-0c1f0d6b2a11"),
+		IP:         str("203.0.113.7"),
+		UserAgent:  str("Mozilla/5.0"),
+		Details:    str(`{"membership":{"from":"free","to":"premium"}}`),
+		CreatedAt:  at,
+	}}
+
+	exported := exportedAuditLogs(logs)
+	if len(exported) != 1 || exported[0].Action != models.AuditActionDataExport || exported[0].Outcome != models.AuditOutcomeSuccess || !exported[0].CreatedAt.Equal(at) {
+		t.Fatalf("exportedAuditLogs() = %+v; want the action, outcome and time", exported)
+	}
+	body, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	for _, leaked := range []string{"ops-admin", "203.0.113.7", "Mozilla", "membership", "actor", "ip", "user_agent", "details"} {
+		if strings.Contains(string(body), `"`+leaked) {
+			t.Errorf("export %s contains %q", body, leaked)
+		}
+	}
+}
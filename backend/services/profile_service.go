@@ -0,0 +1,238 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidProfileUpdate is returned when a profile update fails validation
+	ErrInvalidProfileUpdate = errors.New("invalid profile update")
+	// ErrInvalidProfileLookup is returned when a batch lookup lists malformed or too many IDs
+	ErrInvalidProfileLookup = errors.New("invalid profile lookup")
+)
+
+// MaxProfileLookup caps the IDs of one batch profile lookup
+const MaxProfileLookup = 500
+
+// ProfileUpdate lists the profile fields an admin may change; nil fields are
+// left unchanged
+type ProfileUpdate struct {
+	Membership *string `json:"membership"` // free or premium
+	// MembershipExpiresAt is an RFC 3339 timestamp or YYYY-MM-DD date (end of
+	// that day, Vietnam time); an empty string removes the expiry
+	MembershipExpiresAt *string `json:"membership_expires_at"`
+	Active              *bool   `json:"active"`
+}
+
+// ProfileService applies admin changes to member profiles
+type ProfileService struct {
+	auditService *AuditService
+}
+
+// NewProfileService creates a new ProfileService instance
+func NewProfileService(auditService *AuditService) *ProfileService {
+	return &ProfileService{auditService: auditService}
+}
+
+// LookupProfiles returns the profiles with the given IDs in one query, in
+// the order first requested, and the IDs that match no profile. Subsystems
+// acting on many members (alerts, billing) use it instead of a query per
+// member.
+func (s *ProfileService) LookupProfiles(ctx context.Context, ids []string) ([]models.Profile, []string, error) {
+	parsed, err := parseProfileIDs(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	profiles := make([]models.Profile, 0, len(parsed))
+	if len(parsed) == 0 {
+		return profiles, []string{}, nil
+	}
+
+	var found []models.Profile
+	if err := config.GetDBWithContext(ctx).Where("id IN ?", parsed).Find(&found).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to look up profiles: %w", err)
+	}
+	byID := make(map[uuid.UUID]models.Profile, len(found))
+	for _, profile := range found {
+		byID[profile.ID] = profile
+	}
+
+	missing := make([]string, 0)
+	for _, id := range parsed {
+		if profile, ok := byID[id]; ok {
+			profiles = append(profiles, profile)
+		} else {
+			missing = append(missing, id.String())
+		}
+	}
+	return profiles, missing, nil
+}
+
+// parseProfileIDs parses and deduplicates the IDs of a batch lookup,
+// keeping their order
+func parseProfileIDs(ids []string) ([]uuid.UUID, error) {
+	if len(ids) > MaxProfileLookup {
+		return nil, fmt.Errorf("%w: at most %d IDs per lookup", ErrInvalidProfileLookup, MaxProfileLookup)
+	}
+	parsed := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, raw := range ids {
+		id, err := uuid.Parse(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not a UUID", ErrInvalidProfileLookup, raw)
+		}
+		if !seen[id] {
+			seen[id] = true
+			parsed = append(parsed, id)
+		}
+	}
+	return parsed, nil
+}
+
+// UpdateProfile changes a profile's membership tier, membership expiry or
+// active state and records the changes in the audit log. Deactivating a
+// profile also revokes its personal access tokens.
+func (s *ProfileService) UpdateProfile(ctx context.Context, id string, update ProfileUpdate, actor, ip string) (*models.Profile, error) {
+	db := config.GetDBWithContext(ctx)
+
+	var profile models.Profile
+	err := db.First(&profile, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up profile: %w", err)
+	}
+
+	columns, changes, err := profileChanges(&profile, update, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return &profile, nil
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Profile{}).Where("id = ?", profile.ID).Updates(columns).Error; err != nil {
+			return fmt.Errorf("failed to update profile: %w", err)
+		}
+		if update.Active != nil && !*update.Active {
+			err := tx.Model(&models.PersonalAccessToken{}).
+				Where("profile_id = ? AND revoked_at IS NULL", profile.ID).
+				UpdateColumn("revoked_at", columns["deactivated_at"]).Error
+			if err != nil {
+				return fmt.Errorf("failed to revoke personal access tokens: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, AuditEntry{
+		Actor:      actor,
+		Action:     models.AuditActionProfileEdit,
+		Outcome:    models.AuditOutcomeSuccess,
+		EntityType: "profile",
+		EntityID:   profile.ID.String(),
+		IP:         ip,
+		Details:    changes,
+	})
+	return &profile, nil
+}
+
+// profileChanges validates update against profile, applies it to profile and
+// returns the columns to write and a from/to description of each change
+func profileChanges(profile *models.Profile, update ProfileUpdate, now time.Time) (map[string]interface{}, map[string]interface{}, error) {
+	columns := make(map[string]interface{})
+	changes := make(map[string]interface{})
+
+	if update.Membership != nil {
+		tier := strings.ToLower(strings.TrimSpace(*update.Membership))
+		if !models.ValidMembership(tier) {
+			return nil, nil, fmt.Errorf("%w: membership must be %q or %q", ErrInvalidProfileUpdate, models.MembershipFree, models.MembershipPremium)
+		}
+		if tier != profile.Membership {
+			changes["membership"] = map[string]interface{}{"from": profile.Membership, "to": tier}
+			columns["membership"] = tier
+			profile.Membership = tier
+		}
+	}
+
+	if update.MembershipExpiresAt != nil {
+		expiresAt, err := parseMembershipExpiry(*update.MembershipExpiresAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !sameTime(expiresAt, profile.MembershipExpiresAt) {
+			changes["membership_expires_at"] = map[string]interface{}{"from": profile.MembershipExpiresAt, "to": expiresAt}
+			columns["membership_expires_at"] = expiresAt
+			profile.MembershipExpiresAt = expiresAt
+		}
+	} else if columns["membership"] == models.MembershipFree && profile.MembershipExpiresAt != nil {
+		// A downgrade ends the paid period
+		changes["membership_expires_at"] = map[string]interface{}{"from": profile.MembershipExpiresAt, "to": nil}
+		columns["membership_expires_at"] = nil
+		profile.MembershipExpiresAt = nil
+	}
+
+	if profile.Membership == models.MembershipFree && profile.MembershipExpiresAt != nil {
+		return nil, nil, fmt.Errorf("%w: membership_expires_at only applies to %q members", ErrInvalidProfileUpdate, models.MembershipPremium)
+	}
+
+	if update.Active != nil && *update.Active != profile.Active {
+		changes["active"] = map[string]interface{}{"from": profile.Active, "to": *update.Active}
+		columns["active"] = *update.Active
+		if *update.Active {
+			columns["deactivated_at"] = nil
+			profile.DeactivatedAt = nil
+		} else {
+			columns["deactivated_at"] = now
+			profile.DeactivatedAt = &now
+		}
+		profile.Active = *update.Active
+	}
+
+	if len(columns) > 0 {
+		columns["updated_at"] = now
+		profile.UpdatedAt = now
+	}
+	return columns, changes, nil
+}
+
+// parseMembershipExpiry parses an RFC 3339 timestamp or a YYYY-MM-DD date,
+// which expires at the end of that day in Vietnam; "" means no expiry
+func parseMembershipExpiry(raw string) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		utc := t.UTC()
+		return &utc, nil
+	}
+	if end, err := markettime.EndOfDate(raw); err == nil {
+		end = end.UTC()
+		return &end, nil
+	}
+	return nil, fmt.Errorf("%w: membership_expires_at must be an RFC 3339 timestamp or YYYY-MM-DD date", ErrInvalidProfileUpdate)
+}
+
+// sameTime reports whether two optional timestamps are equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
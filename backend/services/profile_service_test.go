@@ -0,0 +1,91 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestProfileChanges(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	expiry := time.Date(2024, 6, 30, 16, 59, 59, 0, time.UTC)
+	str := func(s string) *string { return &s }
+	boolean := func(b bool) *bool { return &b }
+
+	tests := []struct {
+		name    string
+		profile models.Profile
+		update  ProfileUpdate
+		columns []string
+		wantErr bool
+	}{
+		{"upgrade with expiry", models.Profile{Membership: "free", Active: true},
+			ProfileUpdate{Membership: str("Premium"), MembershipExpiresAt: str("2024-06-30")},
+			[]string{"membership", "membership_expires_at", "updated_at"}, false},
+		{"downgrade clears expiry", models.Profile{Membership: "premium", MembershipExpiresAt: &expiry, Active: true},
+			ProfileUpdate{Membership: str("free")},
+			[]string{"membership", "membership_expires_at", "updated_at"}, false},
+		{"deactivate", models.Profile{Membership: "free", Active: true},
+			ProfileUpdate{Active: boolean(false)},
+			[]string{"active", "deactivated_at", "updated_at"}, false},
+		{"no change", models.Profile{Membership: "premium", MembershipExpiresAt: &expiry, Active: true},
+			ProfileUpdate{Membership: str("premium"), MembershipExpiresAt: str("2024-06-30T16:59:59Z"), Active: boolean(true)},
+			nil, false},
+		{"unknown tier", models.Profile{Membership: "free", Active: true},
+			ProfileUpdate{Membership: str("gold")}, nil, true},
+		{"expiry on free member", models.Profile{Membership: "free", Active: true},
+			ProfileUpdate{MembershipExpiresAt: str("2024-06-30")}, nil, true},
+		{"invalid expiry", models.Profile{Membership: "premium", Active: true},
+			ProfileUpdate{MembershipExpiresAt: str("next month")}, nil, true},
+	}
+
+	for _, tt := range tests {
+		profile := tt.profile
+		columns, _, err := profileChanges(&profile, tt.update, now)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidProfileUpdate) {
+				t.Errorf("%s: error = %v; want ErrInvalidProfileUpdate", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+			continue
+		}
+		if len(columns) != len(tt.columns) {
+			t.Errorf("%s: columns = %v; want %v", tt.name, columns, tt.columns)
+			continue
+		}
+		for _, column := range tt.columns {
+			if _, ok := columns[column]; !ok {
+				t.Errorf("%s: column %s not updated", tt.name, column)
+			}
+		}
+	}
+}
+
+func TestParseProfileIDs(t *testing.T) {
+	first := "3f2c8f0e-4b7a-4c55-9a51-0c8e2a1d9b10"
+	second := "a1b2c3d4-e5f6-4711-8899-aabbccddeeff"
+
+	ids, err := parseProfileIDs([]string{second, " " + first + " ", second})
+	if err != nil {
+		t.Fatalf("parseProfileIDs failed: %v", err)
+	}
+	if len(ids) != 2 || ids[0].String() != second || ids[1].String() != first {
+		t.Errorf("parseProfileIDs = %v; want [%s %s] in request order without duplicates", ids, second, first)
+	}
+
+	if _, err := parseProfileIDs([]string{first, "not-a-uuid"}); !errors.Is(err, ErrInvalidProfileLookup) {
+		t.Errorf("malformed ID error = %v; want ErrInvalidProfileLookup", err)
+	}
+	tooMany := make([]string, MaxProfileLookup+1)
+	for i := range tooMany {
+		tooMany[i] = first
+	}
+	if _, err := parseProfileIDs(tooMany); !errors.Is(err, ErrInvalidProfileLookup) {
+		t.Errorf("oversized lookup error = %v; want ErrInvalidProfileLookup", err)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"sync"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"golang.org/x/sync/singleflight"
+)
+
+// ProviderCacheStats reports the provider response cache of the active runs
+type ProviderCacheStats struct {
+	ActiveRuns int   `json:"active_runs"`
+	Entries    int   `json:"entries"`
+	Bytes      int64 `json:"bytes"`
+	Hits       int64 `json:"hits"`   // Requests answered without calling the provider
+	Misses     int64 `json:"misses"` // Requests sent to the provider
+}
+
+// ProviderResponseCache keeps the successful provider responses fetched while
+// crawl runs are active, keyed by URL, so a run never fetches the same
+// payload twice: symbols retried within the run, sources asked for the same
+// list twice and overlapping windows all reuse the first response.
+// Concurrent identical requests share one call. Entries live until the last
+// active run ends; once crawler.provider_cache_mb is used up, new responses
+// are no longer kept.
+type ProviderResponseCache struct {
+	mu      sync.Mutex
+	group   singleflight.Group
+	runs    int
+	entries map[string][]byte
+	bytes   int64
+	hits    int64
+	misses  int64
+}
+
+var providerCache = &ProviderResponseCache{}
+
+// ProviderCache returns the process-wide provider response cache
+func ProviderCache() *ProviderResponseCache {
+	return providerCache
+}
+
+// BeginRun activates the cache for a crawl run and returns the function
+// ending it. The cache starts empty with the first active run and is dropped
+// when the last one ends.
+func (c *ProviderResponseCache) BeginRun() (end func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.runs == 0 {
+		c.entries, c.bytes, c.hits, c.misses = make(map[string][]byte), 0, 0, 0
+	}
+	c.runs++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.runs--
+			if c.runs == 0 {
+				c.entries, c.bytes = nil, 0
+			}
+		})
+	}
+}
+
+// Fetch returns the cached response for url or calls fetch, keeping its
+// result when it succeeds. Outside crawl runs fetch is always called.
+func (c *ProviderResponseCache) Fetch(url string, fetch func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	if c.runs == 0 {
+		c.mu.Unlock()
+		return fetch()
+	}
+	if body, ok := c.entries[url]; ok {
+		c.hits++
+		c.mu.Unlock()
+		return body, nil
+	}
+	c.mu.Unlock()
+
+	leader := false
+	body, err, _ := c.group.Do(url, func() (interface{}, error) {
+		leader = true
+		body, err := fetch()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.misses++
+		if err == nil && c.entries != nil {
+			limit := int64(config.Runtime().ProviderCacheMB) << 20
+			if c.bytes+int64(len(body)) <= limit {
+				c.entries[url] = body
+				c.bytes += int64(len(body))
+			}
+		}
+		return body, err
+	})
+	if !leader {
+		// Answered by the identical request in flight
+		c.mu.Lock()
+		c.hits++
+		c.mu.Unlock()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return body.([]byte), nil
+}
+
+// Flush drops the cached responses of the active runs, which fetch from the
+// providers again; the runs stay active
+func (c *ProviderResponseCache) Flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	flushed := len(c.entries)
+	if c.entries != nil {
+		c.entries = make(map[string][]byte)
+	}
+	c.bytes = 0
+	return flushed
+}
+
+// Stats returns the cache counters of the active runs
+func (c *ProviderResponseCache) Stats() ProviderCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ProviderCacheStats{
+		ActiveRuns: c.runs,
+		Entries:    len(c.entries),
+		Bytes:      c.bytes,
+		Hits:       c.hits,
+		Misses:     c.misses,
+	}
+}
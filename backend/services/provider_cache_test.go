@@ -0,0 +1,132 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+)
+
+// useProviderCacheMB sets crawler.provider_cache_mb for the test
+func useProviderCacheMB(t *testing.T, mb string) {
+	t.Helper()
+	previous := config.Runtime()
+	t.Cleanup(func() { config.SetRuntime(previous) })
+	cfg, err := config.LoadRuntimeConfig(map[string]string{"crawler.provider_cache_mb": mb})
+	if err != nil {
+		t.Fatalf("LoadRuntimeConfig() unexpected error: %v", err)
+	}
+	config.SetRuntime(cfg)
+}
+
+func TestProviderCacheWithinRun(t *testing.T) {
+	useProviderCacheMB(t, "1")
+	cache := &ProviderResponseCache{}
+	calls := 0
+	fetch := func() ([]byte, error) {
+		calls++
+		return []byte(`{"data":[]}`), nil
+	}
+
+	// Outside a run every request reaches the provider
+	cache.Fetch("https://example.test/a", fetch)
+	cache.Fetch("https://example.test/a", fetch)
+	if calls != 2 {
+		t.Fatalf("calls outside a run = %d; want 2", calls)
+	}
+
+	end := cache.BeginRun()
+	calls = 0
+	for i := 0; i < 3; i++ {
+		body, err := cache.Fetch("https://example.test/a", fetch)
+		if err != nil || string(body) != `{"data":[]}` {
+			t.Fatalf("Fetch() = %q, %v", body, err)
+		}
+	}
+	cache.Fetch("https://example.test/b", fetch)
+	if calls != 2 {
+		t.Errorf("calls within a run = %d; want 2 (one per URL)", calls)
+	}
+	if stats := cache.Stats(); stats.Hits != 2 || stats.Misses != 2 || stats.Entries != 2 {
+		t.Errorf("Stats() = %+v; want 2 hits, 2 misses, 2 entries", stats)
+	}
+
+	// A flush keeps the run active, so responses are cached again
+	if flushed := cache.Flush(); flushed != 2 {
+		t.Errorf("Flush() = %d; want 2", flushed)
+	}
+	cache.Fetch("https://example.test/a", fetch)
+	cache.Fetch("https://example.test/a", fetch)
+	if calls != 3 || cache.Stats().Entries != 1 {
+		t.Errorf("calls after a flush = %d, entries = %d; want 3, 1", calls, cache.Stats().Entries)
+	}
+
+	end()
+	end() // Ending twice must not end another run
+	if stats := cache.Stats(); stats.ActiveRuns != 0 || stats.Entries != 0 {
+		t.Errorf("Stats() after the run = %+v; want no runs or entries", stats)
+	}
+}
+
+func TestProviderCacheSkipsErrorsAndFullCache(t *testing.T) {
+	useProviderCacheMB(t, "0")
+	cache := &ProviderResponseCache{}
+	defer cache.BeginRun()()
+
+	calls := 0
+	failing := func() ([]byte, error) {
+		calls++
+		return nil, errors.New("status 502")
+	}
+	cache.Fetch("https://example.test/a", failing)
+	if _, err := cache.Fetch("https://example.test/a", failing); err == nil || calls != 2 {
+		t.Errorf("failed fetches: err %v, calls %d; want the error and 2 calls", err, calls)
+	}
+
+	ok := func() ([]byte, error) {
+		calls++
+		return []byte("x"), nil
+	}
+	calls = 0
+	cache.Fetch("https://example.test/b", ok)
+	cache.Fetch("https://example.test/b", ok)
+	if calls != 2 {
+		t.Errorf("calls with a 0 MB cache = %d; want 2", calls)
+	}
+}
+
+func TestProviderCacheSharesConcurrentFetches(t *testing.T) {
+	useProviderCacheMB(t, "1")
+	cache := &ProviderResponseCache{}
+	defer cache.BeginRun()()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	fetch := func() ([]byte, error) {
+		calls.Add(1)
+		<-release
+		return []byte("x"), nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Fetch("https://example.test/a", fetch)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("provider calls = %d; want 1", calls.Load())
+	}
+	if stats := cache.Stats(); stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v; want 3 hits, 1 miss", stats)
+	}
+}
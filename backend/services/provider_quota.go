@@ -0,0 +1,200 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/markettime"
+)
+
+// ErrProviderQuotaExhausted is recorded for the symbols a crawl skipped
+// because their provider's quota was nearly used up
+var ErrProviderQuotaExhausted = errors.New("not crawled: provider quota exhausted")
+
+// Windows counted for every provider, so usage is visible before quotas are configured
+var defaultQuotaWindows = []time.Duration{time.Hour, 24 * time.Hour}
+
+// ProviderQuotaUsage is the request count of one provider in its current window
+type ProviderQuotaUsage struct {
+	Window    string    `json:"window"`
+	Requests  int       `json:"requests"`
+	Limit     int       `json:"limit,omitempty"`     // 0 when the window has no quota
+	Remaining *int      `json:"remaining,omitempty"` // Requests left before the ceiling
+	Paused    bool      `json:"paused"`              // Crawling waits for the window to reset
+	ResetAt   time.Time `json:"reset_at"`
+}
+
+// ProviderQuotaTracker counts the requests made to each upstream provider in
+// fixed windows aligned to midnight Vietnam time, which is when providers
+// reset their daily quotas. Counts are kept per instance.
+type ProviderQuotaTracker struct {
+	mu     sync.Mutex
+	counts map[string]map[time.Duration]*quotaWindow // Provider -> window length -> current window
+	now    func() time.Time
+}
+
+type quotaWindow struct {
+	start time.Time
+	count int
+}
+
+// NewProviderQuotaTracker creates an empty quota tracker
+func NewProviderQuotaTracker() *ProviderQuotaTracker {
+	return &ProviderQuotaTracker{
+		counts: make(map[string]map[time.Duration]*quotaWindow),
+		now:    time.Now,
+	}
+}
+
+var providerQuotas = NewProviderQuotaTracker()
+
+// ProviderQuotas returns the tracker shared by every data source
+func ProviderQuotas() *ProviderQuotaTracker {
+	return providerQuotas
+}
+
+// Record counts one request (or retry) made to provider
+func (t *ProviderQuotaTracker) Record(provider string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, length := range quotaWindows(provider) {
+		t.window(provider, length, now).count++
+	}
+}
+
+// Reset forgets the requests counted in the current windows, resuming
+// providers paused for their quota, e.g. once a provider raised it
+func (t *ProviderQuotaTracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts = make(map[string]map[time.Duration]*quotaWindow)
+}
+
+// quotaCountingTransport counts every request sent through it against the
+// quota of provider, so retries made by the provider's client count too
+type quotaCountingTransport struct {
+	provider string
+	next     http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t quotaCountingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ProviderQuotas().Record(t.provider)
+	return t.next.RoundTrip(req)
+}
+
+// Exhausted reports whether a window of provider has no more than the
+// configured reserve left, and when the latest such window resets
+func (t *ProviderQuotaTracker) Exhausted(provider string) (bool, time.Time) {
+	cfg := config.Runtime()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	exhausted, resumeAt := false, time.Time{}
+	for _, quota := range cfg.ProviderQuotas[provider] {
+		w := t.window(provider, quota.Window, now)
+		if quotaPaused(w.count, quota.Requests, cfg.ProviderQuotaReserve) {
+			exhausted = true
+			if resetAt := w.start.Add(quota.Window); resetAt.After(resumeAt) {
+				resumeAt = resetAt
+			}
+		}
+	}
+	return exhausted, resumeAt
+}
+
+// Usage returns every counted provider's usage, by provider name
+func (t *ProviderQuotaTracker) Usage() map[string][]ProviderQuotaUsage {
+	cfg := config.Runtime()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	providers := make(map[string]bool, len(t.counts)+len(cfg.ProviderQuotas))
+	for provider := range t.counts {
+		providers[provider] = true
+	}
+	for provider := range cfg.ProviderQuotas {
+		providers[provider] = true
+	}
+
+	now := t.now()
+	usage := make(map[string][]ProviderQuotaUsage, len(providers))
+	for provider := range providers {
+		limits := make(map[time.Duration]int)
+		for _, quota := range cfg.ProviderQuotas[provider] {
+			limits[quota.Window] = quota.Requests
+		}
+		for _, length := range quotaWindows(provider) {
+			w := t.window(provider, length, now)
+			entry := ProviderQuotaUsage{
+				Window:   length.String(),
+				Requests: w.count,
+				ResetAt:  w.start.Add(length),
+			}
+			if limit, ok := limits[length]; ok {
+				remaining := limit - w.count
+				if remaining < 0 {
+					remaining = 0
+				}
+				entry.Limit, entry.Remaining = limit, &remaining
+				entry.Paused = quotaPaused(w.count, limit, cfg.ProviderQuotaReserve)
+			}
+			usage[provider] = append(usage[provider], entry)
+		}
+	}
+	return usage
+}
+
+// window returns provider's current window of length, starting a new one
+// when the stored window has ended. Callers hold t.mu.
+func (t *ProviderQuotaTracker) window(provider string, length time.Duration, now time.Time) *quotaWindow {
+	windows, ok := t.counts[provider]
+	if !ok {
+		windows = make(map[time.Duration]*quotaWindow)
+		t.counts[provider] = windows
+	}
+	start := quotaWindowStart(now, length)
+	w, ok := windows[length]
+	if !ok || !w.start.Equal(start) {
+		w = &quotaWindow{start: start}
+		windows[length] = w
+	}
+	return w
+}
+
+// quotaWindows returns the window lengths counted for provider: the default
+// hour and day plus any other configured window, shortest first
+func quotaWindows(provider string) []time.Duration {
+	lengths := append([]time.Duration{}, defaultQuotaWindows...)
+	for _, quota := range config.Runtime().ProviderQuotas[provider] {
+		known := false
+		for _, length := range lengths {
+			known = known || length == quota.Window
+		}
+		if !known {
+			lengths = append(lengths, quota.Window)
+		}
+	}
+	sort.Slice(lengths, func(i, j int) bool { return lengths[i] < lengths[j] })
+	return lengths
+}
+
+// quotaWindowStart returns the start of the window of length containing
+// now, with windows counted from midnight Vietnam time
+func quotaWindowStart(now time.Time, length time.Duration) time.Time {
+	local := now.In(markettime.Location())
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, local.Location())
+	return midnight.Add(local.Sub(midnight) / length * length)
+}
+
+// quotaPaused reports whether count leaves no more than reserve percent of limit
+func quotaPaused(count, limit, reserve int) bool {
+	return limit-count <= limit*reserve/100
+}
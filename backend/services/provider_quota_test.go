@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+)
+
+func TestQuotaWindowStart(t *testing.T) {
+	// 16:40 UTC is 23:40 in Vietnam
+	now := time.Date(2026, 3, 9, 16, 40, 0, 0, time.UTC)
+	tests := []struct {
+		length time.Duration
+		want   time.Time
+	}{
+		{time.Hour, time.Date(2026, 3, 9, 16, 0, 0, 0, time.UTC)},
+		{6 * time.Hour, time.Date(2026, 3, 9, 11, 0, 0, 0, time.UTC)},
+		{24 * time.Hour, time.Date(2026, 3, 8, 17, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := quotaWindowStart(now, tt.length); !got.Equal(tt.want) {
+			t.Errorf("quotaWindowStart(%s) = %s; want %s", tt.length, got.UTC(), tt.want)
+		}
+	}
+}
+
+func TestProviderQuotaTracker(t *testing.T) {
+	previous := config.Runtime()
+	t.Cleanup(func() { config.SetRuntime(previous) })
+	cfg, err := config.LoadRuntimeConfig(map[string]string{
+		"crawler.provider_quotas":        "vndirect=100/1h,vndirect=1000/24h",
+		"crawler.provider_quota_reserve": "10",
+	})
+	if err != nil {
+		t.Fatalf("LoadRuntimeConfig() unexpected error: %v", err)
+	}
+	config.SetRuntime(cfg)
+
+	now := time.Date(2026, 3, 9, 2, 15, 0, 0, time.UTC)
+	tracker := NewProviderQuotaTracker()
+	tracker.now = func() time.Time { return now }
+
+	for i := 0; i < 89; i++ {
+		tracker.Record("vndirect")
+	}
+	if exhausted, _ := tracker.Exhausted("vndirect"); exhausted {
+		t.Fatalf("Exhausted() after 89/100 = true; want false")
+	}
+	tracker.Record("vndirect")
+	exhausted, resumeAt := tracker.Exhausted("vndirect")
+	if want := time.Date(2026, 3, 9, 3, 0, 0, 0, time.UTC); !exhausted || !resumeAt.Equal(want) {
+		t.Errorf("Exhausted() after 90/100 = %v, %s; want true, %s", exhausted, resumeAt.UTC(), want)
+	}
+
+	usage := tracker.Usage()["vndirect"]
+	if len(usage) != 2 || usage[0].Window != "1h0m0s" || usage[1].Window != "24h0m0s" {
+		t.Fatalf("Usage() = %+v; want the hour then the day", usage)
+	}
+	if !usage[0].Paused || *usage[0].Remaining != 10 || usage[1].Paused || *usage[1].Remaining != 910 {
+		t.Errorf("Usage() = %+v; want the hour paused with 10 left and the day with 910", usage)
+	}
+
+	// A new hour starts a new window; the day keeps counting
+	now = now.Add(time.Hour)
+	tracker.Record("vndirect")
+	usage = tracker.Usage()["vndirect"]
+	if usage[0].Requests != 1 || usage[1].Requests != 91 {
+		t.Errorf("Usage() next hour = %d/%d requests; want 1/91", usage[0].Requests, usage[1].Requests)
+	}
+	if exhausted, _ := tracker.Exhausted("vndirect"); exhausted {
+		t.Errorf("Exhausted() next hour = true; want false")
+	}
+
+	// Reset resumes a provider paused for its daily window
+	for i := 0; i < 900; i++ {
+		tracker.Record("vndirect")
+	}
+	if exhausted, _ := tracker.Exhausted("vndirect"); !exhausted {
+		t.Fatalf("Exhausted() after 991/1000 = false; want true")
+	}
+	tracker.Reset()
+	if exhausted, _ := tracker.Exhausted("vndirect"); exhausted {
+		t.Errorf("Exhausted() after Reset() = true; want false")
+	}
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+// Triggers a Pub/Sub message can carry
+const (
+	PubSubTriggerCrawl  = "crawl"  // Queue a full crawl
+	PubSubTriggerBackup = "backup" // Queue a backup to Google Cloud Storage
+	PubSubTriggerDigest = "digest" // Finalize today's data digest
+)
+
+// PubSubTriggers lists the supported triggers
+var PubSubTriggers = []string{PubSubTriggerCrawl, PubSubTriggerBackup, PubSubTriggerDigest}
+
+const (
+	googleCertsURL = "https://www.googleapis.com/oauth2/v3/certs"
+	// googleCertsTTL is how long Google's signing keys are cached; keys
+	// rotate over days and an unknown key ID refreshes them early
+	googleCertsTTL = time.Hour
+	// googleCertsMinRefresh keeps unknown key IDs from refetching the keys on every request
+	googleCertsMinRefresh = time.Minute
+	// pubSubSeenTTL is how long delivered message IDs are remembered, so
+	// that redeliveries of a handled message are acknowledged without rerunning it
+	pubSubSeenTTL = 10 * time.Minute
+	// maxPubSubSeen is the number of remembered IDs above which expired ones are pruned
+	maxPubSubSeen = 1000
+)
+
+var (
+	// ErrPubSubNotConfigured is returned when neither an audience nor a verification token is set
+	ErrPubSubNotConfigured = errors.New("pub/sub push is not configured")
+	// ErrInvalidPubSubPush is returned when a push request fails authentication
+	ErrInvalidPubSubPush = errors.New("invalid pub/sub push authentication")
+	// ErrInvalidPubSubMessage is returned for malformed messages and unknown triggers
+	ErrInvalidPubSubMessage = errors.New("invalid pub/sub message")
+)
+
+// PubSubConfig holds how push requests are authenticated. Push
+// subscriptions should send an OIDC token; the verification token suits
+// setups where the subscription cannot (it travels in the URL).
+type PubSubConfig struct {
+	Audience          string // Audience of the subscription's OIDC token (usually the push URL)
+	ServiceAccount    string // Email of the service account the OIDC token is issued for
+	VerificationToken string // Shared secret in the push URL's ?token=
+}
+
+// PubSubConfigFromEnv reads PUBSUB_AUDIENCE, PUBSUB_SERVICE_ACCOUNT and
+// PUBSUB_VERIFICATION_TOKEN. An audience requires the service account: any
+// Google account can get an ID token for it.
+func PubSubConfigFromEnv() (PubSubConfig, error) {
+	cfg := PubSubConfig{
+		Audience:          strings.TrimSpace(os.Getenv("PUBSUB_AUDIENCE")),
+		ServiceAccount:    strings.TrimSpace(os.Getenv("PUBSUB_SERVICE_ACCOUNT")),
+		VerificationToken: os.Getenv("PUBSUB_VERIFICATION_TOKEN"),
+	}
+	if cfg.Audience != "" && cfg.ServiceAccount == "" {
+		return PubSubConfig{}, errors.New("PUBSUB_AUDIENCE requires PUBSUB_SERVICE_ACCOUNT")
+	}
+	return cfg, nil
+}
+
+// Configured reports whether push requests can be authenticated
+func (c PubSubConfig) Configured() bool {
+	return c.Audience != "" || c.VerificationToken != ""
+}
+
+// pubSubPush is the body of a Pub/Sub push request
+type pubSubPush struct {
+	Message struct {
+		Data       string            `json:"data"` // Base64
+		Attributes map[string]string `json:"attributes"`
+		MessageID  string            `json:"messageId"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+}
+
+// googleIDClaims are the claims of a Google-signed OIDC token
+type googleIDClaims struct {
+	Issuer        string `json:"iss"`
+	Audience      string `json:"aud"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	ExpiresAt     int64  `json:"exp"`
+}
+
+// PubSubPushService runs crawls and other jobs on messages pushed by a
+// Pub/Sub subscription, e.g. published by Cloud Scheduler, so schedules can
+// live in GCP instead of timers in every instance
+type PubSubPushService struct {
+	cfg            PubSubConfig
+	crawlerService *CrawlerService
+	backupService  *BackupService
+	digestService  *DataDigestService
+	keys           func(ctx context.Context, kid string) (*rsa.PublicKey, error)
+	now            func() time.Time
+
+	mu   sync.Mutex
+	seen map[string]time.Time // Message ID -> handled at
+}
+
+// NewPubSubPushService creates a PubSubPushService mapping triggers to the
+// crawler, backup and data digest services
+func NewPubSubPushService(cfg PubSubConfig, crawlerService *CrawlerService, backupService *BackupService, digestService *DataDigestService) *PubSubPushService {
+	certs := &googleCerts{client: newRestyClient().SetTimeout(10 * time.Second)}
+	return &PubSubPushService{
+		cfg:            cfg,
+		crawlerService: crawlerService,
+		backupService:  backupService,
+		digestService:  digestService,
+		keys:           certs.key,
+		now:            time.Now,
+		seen:           make(map[string]time.Time),
+	}
+}
+
+// Configured reports whether push requests are accepted
+func (s *PubSubPushService) Configured() bool {
+	return s.cfg.Configured()
+}
+
+// HandlePush authenticates a push request and runs the trigger of its
+// message, returning the trigger. authorization is the Authorization header
+// and token the ?token= query parameter.
+func (s *PubSubPushService) HandlePush(ctx context.Context, authorization, token string, body []byte) (string, error) {
+	if !s.Configured() {
+		return "", ErrPubSubNotConfigured
+	}
+	if err := s.authenticate(ctx, authorization, token); err != nil {
+		return "", err
+	}
+
+	var push pubSubPush
+	if err := json.Unmarshal(body, &push); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPubSubMessage, err)
+	}
+	trigger, err := pubSubTrigger(push)
+	if err != nil {
+		return "", err
+	}
+	if push.Message.MessageID != "" && s.alreadyHandled(push.Message.MessageID) {
+		return trigger, nil
+	}
+
+	logging.FromContext(ctx).Info("Pub/Sub trigger received", "trigger", trigger, "message_id", push.Message.MessageID, "subscription", push.Subscription)
+	if err := s.run(ctx, trigger); err != nil {
+		s.forget(push.Message.MessageID)
+		return trigger, err
+	}
+	return trigger, nil
+}
+
+// run starts a trigger. Work already queued or running counts as started.
+func (s *PubSubPushService) run(ctx context.Context, trigger string) error {
+	switch trigger {
+	case PubSubTriggerCrawl:
+		if err := s.crawlerService.StartCrawling(); err != nil && !errors.Is(err, ErrCrawlInProgress) {
+			return err
+		}
+	case PubSubTriggerBackup:
+		err := s.backupService.Trigger()
+		if errors.Is(err, ErrBackupNotConfigured) {
+			return fmt.Errorf("%w: %v", ErrInvalidPubSubMessage, err)
+		}
+		if err != nil && !errors.Is(err, ErrBackupRunning) {
+			return err
+		}
+	case PubSubTriggerDigest:
+		s.digestService.FinalizeToday(ctx)
+	}
+	return nil
+}
+
+// authenticate accepts a valid OIDC token when an audience is configured,
+// or else the verification token
+func (s *PubSubPushService) authenticate(ctx context.Context, authorization, token string) error {
+	if s.cfg.Audience != "" {
+		bearer, ok := strings.CutPrefix(authorization, "Bearer ")
+		if ok {
+			return s.verifyIDToken(ctx, strings.TrimSpace(bearer))
+		}
+	}
+	if s.cfg.VerificationToken != "" && token != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.VerificationToken)) == 1 {
+		return nil
+	}
+	return ErrInvalidPubSubPush
+}
+
+// verifyIDToken checks a Google-signed OIDC token: RS256 signature by one
+// of Google's current keys, issuer, audience, expiry and service account
+func (s *PubSubPushService) verifyIDToken(ctx context.Context, token string) error {
+	var claims googleIDClaims
+	if err := verifyRS256(ctx, token, s.keys, &claims); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPubSubPush, err)
+	}
+	switch {
+	case claims.Issuer != "accounts.google.com" && claims.Issuer != "https://accounts.google.com":
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidPubSubPush, claims.Issuer)
+	case claims.Audience != s.cfg.Audience:
+		return fmt.Errorf("%w: unexpected audience %q", ErrInvalidPubSubPush, claims.Audience)
+	case s.now().Unix() >= claims.ExpiresAt:
+		return fmt.Errorf("%w: token has expired", ErrInvalidPubSubPush)
+	case s.cfg.ServiceAccount == "" || claims.Email != s.cfg.ServiceAccount || !claims.EmailVerified:
+		return fmt.Errorf("%w: unexpected service account %q", ErrInvalidPubSubPush, claims.Email)
+	}
+	return nil
+}
+
+// alreadyHandled records a message ID and reports whether it was seen recently
+func (s *PubSubPushService) alreadyHandled(id string) bool {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if at, ok := s.seen[id]; ok && now.Sub(at) < pubSubSeenTTL {
+		return true
+	}
+	if len(s.seen) >= maxPubSubSeen {
+		for seenID, at := range s.seen {
+			if now.Sub(at) >= pubSubSeenTTL {
+				delete(s.seen, seenID)
+			}
+		}
+	}
+	s.seen[id] = now
+	return false
+}
+
+// forget lets a redelivery of a failed message run again
+func (s *PubSubPushService) forget(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.seen, id)
+}
+
+// pubSubTrigger returns the trigger of a message: its "trigger" attribute,
+// or its data as {"trigger": "..."} or plain text
+func pubSubTrigger(push pubSubPush) (string, error) {
+	trigger := push.Message.Attributes["trigger"]
+	if trigger == "" && push.Message.Data != "" {
+		data, err := base64.StdEncoding.DecodeString(push.Message.Data)
+		if err != nil {
+			return "", fmt.Errorf("%w: data is not base64", ErrInvalidPubSubMessage)
+		}
+		var payload struct {
+			Trigger string `json:"trigger"`
+		}
+		if json.Unmarshal(data, &payload) == nil {
+			trigger = payload.Trigger
+		} else {
+			trigger = string(data)
+		}
+	}
+
+	trigger = strings.ToLower(strings.TrimSpace(trigger))
+	for _, supported := range PubSubTriggers {
+		if trigger == supported {
+			return trigger, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown trigger %q (supported: %s)", ErrInvalidPubSubMessage, trigger, strings.Join(PubSubTriggers, ", "))
+}
+
+// verifyRS256 checks the signature of an RS256 JWT with the key named by
+// its header and decodes its claims. Claim contents are left to the caller.
+func verifyRS256(ctx context.Context, token string, keys func(ctx context.Context, kid string) (*rsa.PublicKey, error), claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "RS256" {
+		return ErrInvalidToken
+	}
+	key, err := keys(ctx, header.Kid)
+	if err != nil {
+		return err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return ErrInvalidToken
+	}
+
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// googleCerts caches the public keys Google signs OIDC tokens with
+type googleCerts struct {
+	client *resty.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// key returns the key with ID kid, fetching the key set when it is stale
+// or does not contain kid
+func (g *googleCerts) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	age := time.Since(g.fetchedAt)
+	key, ok := g.keys[kid]
+	if ok && age < googleCertsTTL {
+		return key, nil
+	}
+	if !ok && age < googleCertsMinRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	resp, err := g.client.R().SetContext(ctx).SetResult(&set).Get(googleCertsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Google signing keys: %w", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("failed to fetch Google signing keys: status %d", resp.StatusCode())
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+		e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+		if jwk.Kty != "RSA" || errN != nil || errE != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	g.keys, g.fetchedAt = keys, time.Now()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func signTestIDToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("SignPKCS1v15() unexpected error: %v", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestPubSubVerifyIDToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey() unexpected error: %v", err)
+	}
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	now := time.Date(2026, 2, 12, 11, 0, 0, 0, time.UTC)
+
+	s := &PubSubPushService{
+		cfg: PubSubConfig{Audience: "https://cpls.example/api/pubsub/push", ServiceAccount: "scheduler@cpls.iam.gserviceaccount.com"},
+		keys: func(_ context.Context, kid string) (*rsa.PublicKey, error) {
+			if kid != "k1" {
+				return nil, fmt.Errorf("unknown signing key %q", kid)
+			}
+			return &key.PublicKey, nil
+		},
+		now: func() time.Time { return now },
+	}
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"iss":            "https://accounts.google.com",
+			"aud":            "https://cpls.example/api/pubsub/push",
+			"email":          "scheduler@cpls.iam.gserviceaccount.com",
+			"email_verified": true,
+			"exp":            now.Add(time.Hour).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		key    *rsa.PrivateKey
+		kid    string
+		modify func(map[string]interface{})
+		valid  bool
+	}{
+		{"valid", key, "k1", func(map[string]interface{}) {}, true},
+		{"wrong key", other, "k1", func(map[string]interface{}) {}, false},
+		{"unknown kid", key, "k2", func(map[string]interface{}) {}, false},
+		{"wrong issuer", key, "k1", func(c map[string]interface{}) { c["iss"] = "https://evil.example" }, false},
+		{"wrong audience", key, "k1", func(c map[string]interface{}) { c["aud"] = "https://other.example" }, false},
+		{"expired", key, "k1", func(c map[string]interface{}) { c["exp"] = now.Add(-time.Minute).Unix() }, false},
+		{"other account", key, "k1", func(c map[string]interface{}) { c["email"] = "someone@example.com" }, false},
+		{"unverified email", key, "k1", func(c map[string]interface{}) { c["email_verified"] = false }, false},
+	}
+	for _, tt := range tests {
+		claims := valid()
+		tt.modify(claims)
+		err := s.verifyIDToken(context.Background(), signTestIDToken(t, tt.key, tt.kid, claims))
+		if tt.valid && err != nil {
+			t.Errorf("%s: verifyIDToken() unexpected error: %v", tt.name, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidPubSubPush) {
+			t.Errorf("%s: verifyIDToken() error = %v; want ErrInvalidPubSubPush", tt.name, err)
+		}
+	}
+
+	if err := s.authenticate(context.Background(), "", ""); !errors.Is(err, ErrInvalidPubSubPush) {
+		t.Errorf("authenticate() without credentials error = %v; want ErrInvalidPubSubPush", err)
+	}
+
+	// Without a service account no token identifies the scheduler
+	s.cfg.ServiceAccount = ""
+	if err := s.verifyIDToken(context.Background(), signTestIDToken(t, key, "k1", valid())); !errors.Is(err, ErrInvalidPubSubPush) {
+		t.Errorf("verifyIDToken() without a service account error = %v; want ErrInvalidPubSubPush", err)
+	}
+}
+
+func TestPubSubConfigFromEnv(t *testing.T) {
+	t.Setenv("PUBSUB_AUDIENCE", "https://cpls.example/api/pubsub/push")
+	t.Setenv("PUBSUB_SERVICE_ACCOUNT", "")
+	t.Setenv("PUBSUB_VERIFICATION_TOKEN", "")
+	if _, err := PubSubConfigFromEnv(); err == nil {
+		t.Error("PubSubConfigFromEnv() with an audience but no service account should fail")
+	}
+
+	t.Setenv("PUBSUB_SERVICE_ACCOUNT", "scheduler@cpls.iam.gserviceaccount.com")
+	if cfg, err := PubSubConfigFromEnv(); err != nil || !cfg.Configured() {
+		t.Errorf("PubSubConfigFromEnv() = %+v, %v; want a configured audience", cfg, err)
+	}
+}
+
+func TestPubSubVerificationToken(t *testing.T) {
+	s := &PubSubPushService{cfg: PubSubConfig{VerificationToken: "s3cret"}}
+
+	if err := s.authenticate(context.Background(), "", "s3cret"); err != nil {
+		t.Errorf("authenticate() with the token unexpected error: %v", err)
+	}
+	if err := s.authenticate(context.Background(), "", "wrong"); !errors.Is(err, ErrInvalidPubSubPush) {
+		t.Errorf("authenticate() with a wrong token error = %v; want ErrInvalidPubSubPush", err)
+	}
+	if err := s.authenticate(context.Background(), "Bearer x.y.z", ""); !errors.Is(err, ErrInvalidPubSubPush) {
+		t.Errorf("authenticate() with a bearer token only error = %v; want ErrInvalidPubSubPush", err)
+	}
+}
+
+func TestPubSubTrigger(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name       string
+		data       string
+		attributes map[string]string
+		want       string
+	}{
+		{"attribute", "", map[string]string{"trigger": "backup"}, "backup"},
+		{"json data", encode(`{"trigger":"crawl"}`), nil, "crawl"},
+		{"text data", encode(" Digest\n"), nil, "digest"},
+		{"unknown", encode("reboot"), nil, ""},
+		{"empty", "", nil, ""},
+		{"not base64", "%%%", nil, ""},
+	}
+	for _, tt := range tests {
+		var push pubSubPush
+		push.Message.Data = tt.data
+		push.Message.Attributes = tt.attributes
+
+		got, err := pubSubTrigger(push)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidPubSubMessage) {
+				t.Errorf("%s: pubSubTrigger() error = %v; want ErrInvalidPubSubMessage", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: pubSubTrigger() = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestPubSubAlreadyHandled(t *testing.T) {
+	now := time.Date(2026, 2, 12, 11, 0, 0, 0, time.UTC)
+	s := &PubSubPushService{seen: make(map[string]time.Time), now: func() time.Time { return now }}
+
+	if s.alreadyHandled("m1") {
+		t.Error("alreadyHandled(m1) = true on first delivery")
+	}
+	if !s.alreadyHandled("m1") {
+		t.Error("alreadyHandled(m1) = false on redelivery")
+	}
+	s.forget("m1")
+	if s.alreadyHandled("m1") {
+		t.Error("alreadyHandled(m1) = true after forget")
+	}
+
+	now = now.Add(pubSubSeenTTL)
+	if s.alreadyHandled("m1") {
+		t.Error("alreadyHandled(m1) = true after the TTL")
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimitResult is the outcome of one rate limit check
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	ResetAt   time.Time // When the current window ends
+}
+
+// RateLimiter counts requests per key in fixed windows
+type RateLimiter interface {
+	// Allow records one request for key and reports whether it is within limit
+	Allow(ctx context.Context, key string, limit config.RateLimit) (RateLimitResult, error)
+}
+
+// NewRateLimiter returns a Redis-backed limiter shared by all instances when
+// Redis is configured, or an in-memory limiter per instance otherwise
+func NewRateLimiter() RateLimiter {
+	if config.Redis != nil {
+		return NewRedisRateLimiter(config.Redis)
+	}
+	return NewMemoryRateLimiter()
+}
+
+// rateLimitResult builds a result from the request count in the current window
+func rateLimitResult(count int, limit config.RateLimit, resetAt time.Time) RateLimitResult {
+	remaining := limit.Requests - count
+	if remaining < 0 {
+		remaining = 0
+	}
+	return RateLimitResult{
+		Allowed:   count <= limit.Requests,
+		Limit:     limit.Requests,
+		Remaining: remaining,
+		ResetAt:   resetAt,
+	}
+}
+
+// MemoryRateLimiter keeps counters in process memory. Limits apply per
+// instance, so with N instances a client can make up to N times the limit.
+type MemoryRateLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type rateWindow struct {
+	count   int
+	resetAt time.Time
+}
+
+// NewMemoryRateLimiter creates an in-memory rate limiter
+func NewMemoryRateLimiter() *MemoryRateLimiter {
+	return &MemoryRateLimiter{
+		windows: make(map[string]*rateWindow),
+		now:     time.Now,
+	}
+}
+
+// Allow records one request for key
+func (l *MemoryRateLimiter) Allow(_ context.Context, key string, limit config.RateLimit) (RateLimitResult, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= time.Minute {
+		for k, w := range l.windows {
+			if !now.Before(w.resetAt) {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &rateWindow{resetAt: now.Add(limit.Window)}
+		l.windows[key] = w
+	}
+	w.count++
+	return rateLimitResult(w.count, limit, w.resetAt), nil
+}
+
+// redisRateLimitScript increments the window counter, starts the window on
+// the first request and returns the count with the window's remaining TTL
+var redisRateLimitScript = redis.NewScript(`
+local count = redis.call('INCR', KEYS[1])
+if count == 1 then
+  redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return {count, redis.call('PTTL', KEYS[1])}`)
+
+// RedisRateLimiter keeps counters in Redis so limits are shared by all instances
+type RedisRateLimiter struct {
+	client redis.Scripter
+}
+
+// NewRedisRateLimiter creates a rate limiter backed by Redis
+func NewRedisRateLimiter(client redis.Scripter) *RedisRateLimiter {
+	return &RedisRateLimiter{client: client}
+}
+
+// Allow records one request for key
+func (l *RedisRateLimiter) Allow(ctx context.Context, key string, limit config.RateLimit) (RateLimitResult, error) {
+	values, err := redisRateLimitScript.Run(ctx, l.client, []string{"ratelimit:" + key}, limit.Window.Milliseconds()).Int64Slice()
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("rate limit check failed: %w", err)
+	}
+	if len(values) != 2 {
+		return RateLimitResult{}, fmt.Errorf("rate limit check failed: unexpected reply %v", values)
+	}
+	count, ttl := values[0], values[1]
+	if ttl < 0 {
+		ttl = limit.Window.Milliseconds()
+	}
+
+	return rateLimitResult(int(count), limit, time.Now().Add(time.Duration(ttl)*time.Millisecond)), nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+)
+
+func TestMemoryRateLimiter(t *testing.T) {
+	limiter := NewMemoryRateLimiter()
+	start := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return start }
+	limit := config.RateLimit{Requests: 2, Window: time.Minute}
+	ctx := context.Background()
+
+	for i, expectedRemaining := range []int{1, 0} {
+		result, _ := limiter.Allow(ctx, "ip:10.0.0.1", limit)
+		if !result.Allowed || result.Remaining != expectedRemaining {
+			t.Errorf("request %d = %+v; want allowed with %d remaining", i+1, result, expectedRemaining)
+		}
+	}
+
+	result, _ := limiter.Allow(ctx, "ip:10.0.0.1", limit)
+	if result.Allowed || !result.ResetAt.Equal(start.Add(time.Minute)) {
+		t.Errorf("request 3 = %+v; want rejected until %s", result, start.Add(time.Minute))
+	}
+
+	if other, _ := limiter.Allow(ctx, "ip:10.0.0.2", limit); !other.Allowed {
+		t.Errorf("other client = %+v; want allowed (separate counter)", other)
+	}
+
+	limiter.now = func() time.Time { return start.Add(time.Minute) }
+	if result, _ := limiter.Allow(ctx, "ip:10.0.0.1", limit); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("request in next window = %+v; want allowed with 1 remaining", result)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ReadCoalescer collapses identical concurrent reads into one computation.
+// After market close hundreds of clients ask for the same candles within
+// seconds; the first caller for a key runs the query and everyone arriving
+// while it is in flight shares its result. Nothing is cached: a request
+// that arrives after the query finished runs a new one.
+type ReadCoalescer struct {
+	group   singleflight.Group
+	timeout time.Duration // Deadline of the shared computation
+
+	calls  atomic.Int64 // Reads requested
+	shared atomic.Int64 // Reads answered by a computation that served several callers
+}
+
+// ReadCoalescerStats counts how many reads were answered by a shared computation
+type ReadCoalescerStats struct {
+	Calls  int64 `json:"calls"`
+	Shared int64 `json:"shared"`
+}
+
+// NewReadCoalescer creates a coalescer whose shared computations run for at most timeout
+func NewReadCoalescer(timeout time.Duration) *ReadCoalescer {
+	return &ReadCoalescer{timeout: timeout}
+}
+
+// Stats returns the call counters since startup
+func (r *ReadCoalescer) Stats() ReadCoalescerStats {
+	return ReadCoalescerStats{Calls: r.calls.Load(), Shared: r.shared.Load()}
+}
+
+// Coalesce returns fn's result for key, sharing one call of fn among all
+// callers that ask for the same key while it runs. fn runs detached from the
+// caller's cancellation (bounded by the coalescer's timeout) so that one
+// client disconnecting does not fail the others; a caller whose own context
+// ends stops waiting and gets its error. The result is shared between
+// callers and must not be modified.
+func Coalesce[T any](r *ReadCoalescer, ctx context.Context, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	result := r.group.DoChan(key, func() (interface{}, error) {
+		sharedCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.timeout)
+		defer cancel()
+		return fn(sharedCtx)
+	})
+	// Counted once the call has joined (or started) the computation
+	r.calls.Add(1)
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-result:
+		if res.Shared {
+			r.shared.Add(1)
+		}
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCoalesceSharesConcurrentReads(t *testing.T) {
+	reads := NewReadCoalescer(time.Second)
+	var queries atomic.Int32
+	release := make(chan struct{})
+
+	const callers = 50
+	var wg sync.WaitGroup
+	results := make([]int, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], _ = Coalesce(reads, context.Background(), "candles:FPT", func(ctx context.Context) (int, error) {
+				queries.Add(1)
+				<-release
+				return 42, nil
+			})
+		}(i)
+	}
+	// Let every caller join the in-flight query before it finishes
+	for reads.Stats().Calls < callers {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := queries.Load(); got != 1 {
+		t.Errorf("query ran %d times; want 1", got)
+	}
+	for i, result := range results {
+		if result != 42 {
+			t.Fatalf("caller %d got %d; want 42", i, result)
+		}
+	}
+	if stats := reads.Stats(); stats.Shared != callers {
+		t.Errorf("Stats().Shared = %d; want %d", stats.Shared, callers)
+	}
+
+	// Once the query finished, the next read runs a new one
+	Coalesce(reads, context.Background(), "candles:FPT", func(ctx context.Context) (int, error) {
+		queries.Add(1)
+		return 42, nil
+	})
+	if got := queries.Load(); got != 2 {
+		t.Errorf("query ran %d times after the first finished; want 2", got)
+	}
+}
+
+func TestCoalesceCallerCancellation(t *testing.T) {
+	reads := NewReadCoalescer(time.Second)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	queryErr := make(chan error, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := Coalesce(reads, ctx, "metadata:full", func(ctx context.Context) (string, error) {
+			close(started)
+			<-release
+			queryErr <- ctx.Err()
+			return "stocks", nil
+		})
+		done <- err
+	}()
+	<-started
+
+	// A second caller joins, then the first one goes away
+	second := make(chan string, 1)
+	go func() {
+		value, _ := Coalesce(reads, context.Background(), "metadata:full", func(ctx context.Context) (string, error) {
+			return "second query", nil
+		})
+		second <- value
+	}()
+	for reads.Stats().Calls < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("cancelled caller error = %v; want context.Canceled", err)
+	}
+
+	close(release)
+	if err := <-queryErr; err != nil {
+		t.Errorf("shared query context error = %v; want it to outlive the cancelled caller", err)
+	}
+	if got := <-second; got != "stocks" {
+		t.Errorf("second caller got %q; want the shared result", got)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+const (
+	// realtimeFlushInterval is how often the latest session candles received
+	// are written to the price store
+	realtimeFlushInterval = time.Minute
+	// realtimeRetryMax caps the wait before reconnecting a failed stream
+	realtimeRetryMax = time.Minute
+)
+
+// RealtimeQuoteService relays the trades a QuoteStreamer pushes. Every quote
+// is published to the price stream subscribers (WebSocket and SSE) as it
+// arrives, and the latest session candle of each symbol is written to the
+// price store every realtimeFlushInterval, like an intraday poll would. The
+// change stream publishes those writes again, with the same candle.
+type RealtimeQuoteService struct {
+	source MarketDataSource
+	stocks *StockService
+	stream *PriceStreamService
+
+	mu       sync.Mutex
+	pending  map[string]models.CandleData // Candles received since the last flush, by code
+	received int
+}
+
+// NewRealtimeQuoteService creates a RealtimeQuoteService relaying the
+// registered data source sourceName, which must implement QuoteStreamer,
+// into the price store of stocks
+func NewRealtimeQuoteService(sourceName string, stocks *StockService, stream *PriceStreamService) (*RealtimeQuoteService, error) {
+	source, ok := LookupMarketDataSource(sourceName)
+	if !ok {
+		return nil, fmt.Errorf("unknown data source %q", sourceName)
+	}
+	if _, ok := source.(QuoteStreamer); !ok {
+		return nil, fmt.Errorf("data source %q does not stream quotes", sourceName)
+	}
+	return &RealtimeQuoteService{
+		source:  source,
+		stocks:  stocks,
+		stream:  stream,
+		pending: make(map[string]models.CandleData),
+	}, nil
+}
+
+// Start streams the crawler.intraday_symbols (empty: every symbol) until
+// ctx is cancelled, reconnecting after errors, and flushes the received
+// candles in the background
+func (s *RealtimeQuoteService) Start(ctx context.Context) {
+	streamer := s.source.(QuoteStreamer)
+	s.stream.MarkFed()
+	go func() {
+		wait := time.Second
+		for {
+			s.mu.Lock()
+			s.received = 0
+			s.mu.Unlock()
+			err := streamer.StreamQuotes(ctx, config.Runtime().CrawlerIntradaySymbols, s.receive)
+			if ctx.Err() != nil {
+				return
+			}
+
+			// A stream that delivered quotes was healthy; retry it promptly
+			s.mu.Lock()
+			if s.received > 0 {
+				wait = time.Second
+			}
+			s.mu.Unlock()
+			crawlLog.Warn("Realtime quotes interrupted", "source", s.source.Name(), "retry_in", wait.String(), logging.FieldError, err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+			if wait *= 2; wait > realtimeRetryMax {
+				wait = realtimeRetryMax
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(realtimeFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				// Keep what arrived since the last flush
+				flushCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				s.flush(flushCtx)
+				cancel()
+				return
+			case <-ticker.C:
+				s.flush(ctx)
+			}
+		}
+	}()
+	crawlLog.Info("Relaying realtime quotes", "source", s.source.Name())
+}
+
+// receive publishes a quote and queues its candle for the next flush
+func (s *RealtimeQuoteService) receive(code string, candle models.CandleData) {
+	year, err := models.GetYearFromDate(candle.D)
+	if err != nil {
+		return
+	}
+	s.stream.Publish(PriceUpdate{Code: code, Year: year, Candle: candle, At: time.Now().UTC()})
+
+	s.mu.Lock()
+	s.pending[code] = candle
+	s.received++
+	s.mu.Unlock()
+}
+
+// flush writes the queued candles to the price store, replacing the stored
+// candle of their session. A candle that fails is queued again unless a
+// newer one arrived meanwhile.
+func (s *RealtimeQuoteService) flush(ctx context.Context) {
+	s.mu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]models.CandleData, len(pending))
+	s.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	written := 0
+	for code, candle := range pending {
+		if _, err := s.stocks.prices.SaveSessionCandle(ctx, code, candle); err != nil {
+			crawlLog.Warn("Failed to store realtime candle", logging.FieldSymbol, code, logging.FieldError, err)
+			s.mu.Lock()
+			if _, ok := s.pending[code]; !ok {
+				s.pending[code] = candle
+			}
+			s.mu.Unlock()
+			continue
+		}
+		written++
+	}
+	if written > 0 {
+		s.stocks.InvalidatePrices(ctx)
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestRealtimeQuoteFlush(t *testing.T) {
+	repo := &memoryPriceRepository{candles: map[string][]models.CandleData{}}
+	stream := &PriceStreamService{subscribers: make(map[*PriceSubscription]struct{})}
+	sub := stream.Subscribe("HPG")
+	s := &RealtimeQuoteService{
+		stocks:  &StockService{prices: repo, readCache: cache.New(cache.NewStore())},
+		stream:  stream,
+		pending: make(map[string]models.CandleData),
+	}
+
+	s.receive("HPG", models.CandleData{D: "2026-10-15", O: 25.6, H: 25.6, L: 25.6, C: 25.6, V: 1000})
+	s.receive("HPG", models.CandleData{D: "2026-10-15", O: 25.6, H: 25.9, L: 25.6, C: 25.9, V: 3000})
+	if got := len(sub.Updates); got != 2 {
+		t.Errorf("subscriber received %d updates; want every quote", got)
+	}
+
+	// A failed write is retried at the next flush
+	repo.err = errors.New("store down")
+	s.flush(context.Background())
+	repo.err = nil
+	s.flush(context.Background())
+	if stored := repo.candles["HPG"]; len(stored) != 1 || stored[0].C != 25.9 {
+		t.Errorf("stored candles = %v; want only the latest HPG candle", stored)
+	}
+	if len(s.pending) != 0 {
+		t.Errorf("pending = %v after a successful flush; want none", s.pending)
+	}
+
+	// The next flush replaces the session candle stored by the previous one
+	s.receive("HPG", models.CandleData{D: "2026-10-15", O: 25.6, H: 26.1, L: 25.6, C: 26.1, V: 4200})
+	s.flush(context.Background())
+	if stored := repo.candles["HPG"]; len(stored) != 1 || stored[0].C != 26.1 || stored[0].V != 4200 {
+		t.Errorf("stored candles = %v after a second flush; want the newer HPG candle", stored)
+	}
+}
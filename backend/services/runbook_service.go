@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// Runbook actions
+const (
+	RunbookFlushCaches        = "flush-caches"
+	RunbookResetBreakers      = "reset-breakers"
+	RunbookReconnectDatabases = "reconnect-databases"
+	RunbookRestartScheduler   = "restart-scheduler"
+)
+
+const (
+	// runbookCooldown is the least time between two runs of an action
+	runbookCooldown = 30 * time.Second
+	// runbookTimeout bounds one run
+	runbookTimeout = time.Minute
+)
+
+var (
+	// ErrUnknownRunbookAction is returned for an action that does not exist
+	ErrUnknownRunbookAction = errors.New("unknown runbook action")
+	// ErrRunbookBusy is returned while another action is running
+	ErrRunbookBusy = errors.New("another runbook action is running")
+	// ErrRunbookCooldown is returned when the action ran less than runbookCooldown ago
+	ErrRunbookCooldown = errors.New("runbook action ran too recently")
+)
+
+// RunbookStep is the outcome of one step of a runbook action
+type RunbookStep struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// RunbookResult is the outcome of a runbook action; it succeeded when every
+// step did
+type RunbookResult struct {
+	Action     string        `json:"action"`
+	Actor      string        `json:"actor"`
+	OK         bool          `json:"ok"`
+	Steps      []RunbookStep `json:"steps"`
+	StartedAt  time.Time     `json:"started_at"`
+	DurationMS int64         `json:"duration_ms"`
+}
+
+// RunbookAction describes a runbook action and its last run on this instance
+type RunbookAction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	LastRun     *RunbookResult `json:"last_run,omitempty"`
+}
+
+// runbookProcedure is an action's description and its steps
+type runbookProcedure struct {
+	description string
+	run         func(ctx context.Context) []RunbookStep
+}
+
+// RunbookService runs the recovery procedures on-call would otherwise run
+// from a shell in the container, as single actions of the admin API. One
+// action runs at a time, an action cannot run again within runbookCooldown,
+// and every run is recorded in the audit log. Actions act on the instance
+// serving the request only.
+type RunbookService struct {
+	stocks       *StockService
+	jobs         *JobQueue
+	loadShedder  *LoadShedder
+	auditService *AuditService
+	procedures   map[string]runbookProcedure
+	names        []string // Actions in listing order
+
+	running sync.Mutex // Held while an action runs
+	mu      sync.Mutex
+	lastRun map[string]*RunbookResult
+	now     func() time.Time
+}
+
+// NewRunbookService creates a new RunbookService
+func NewRunbookService(stocks *StockService, jobs *JobQueue, loadShedder *LoadShedder, auditService *AuditService) *RunbookService {
+	s := &RunbookService{
+		stocks:       stocks,
+		jobs:         jobs,
+		loadShedder:  loadShedder,
+		auditService: auditService,
+		lastRun:      make(map[string]*RunbookResult),
+		now:          time.Now,
+	}
+	s.procedures = map[string]runbookProcedure{
+		RunbookFlushCaches: {
+			description: "Drop the cached API responses (stocks, prices, indicators) and the provider responses of active crawl runs",
+			run:         s.flushCaches,
+		},
+		RunbookResetBreakers: {
+			description: "Resume providers paused for their quota, re-check store availability and re-evaluate load shedding",
+			run:         s.resetBreakers,
+		},
+		RunbookReconnectDatabases: {
+			description: "Replace the Supabase connection pool and ping MongoDB",
+			run:         s.reconnectDatabases,
+		},
+		RunbookRestartScheduler: {
+			description: "Restart the job queue workers; running jobs are not interrupted",
+			run:         s.restartScheduler,
+		},
+	}
+	s.names = []string{RunbookFlushCaches, RunbookResetBreakers, RunbookReconnectDatabases, RunbookRestartScheduler}
+	return s
+}
+
+// Actions returns the runbook actions with their last run
+func (s *RunbookService) Actions() []RunbookAction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	actions := make([]RunbookAction, 0, len(s.names))
+	for _, name := range s.names {
+		actions = append(actions, RunbookAction{
+			Name:        name,
+			Description: s.procedures[name].description,
+			LastRun:     s.lastRun[name],
+		})
+	}
+	return actions
+}
+
+// Run runs action for actor and records it in the audit log. Rejected runs
+// are audited too.
+func (s *RunbookService) Run(ctx context.Context, action, actor, ip, userAgent string) (*RunbookResult, error) {
+	procedure, ok := s.procedures[action]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownRunbookAction, action)
+	}
+	entry := AuditEntry{
+		Actor:      actor,
+		Action:     models.AuditActionRunbook,
+		EntityType: "runbook",
+		EntityID:   action,
+		IP:         ip,
+		UserAgent:  userAgent,
+	}
+
+	if !s.running.TryLock() {
+		entry.Outcome, entry.Details = models.AuditOutcomeThrottled, map[string]interface{}{"reason": ErrRunbookBusy.Error()}
+		s.auditService.Record(ctx, entry)
+		return nil, ErrRunbookBusy
+	}
+	defer s.running.Unlock()
+
+	startedAt := s.now()
+	s.mu.Lock()
+	last := s.lastRun[action]
+	s.mu.Unlock()
+	if last != nil && startedAt.Sub(last.StartedAt) < runbookCooldown {
+		retryAt := last.StartedAt.Add(runbookCooldown)
+		entry.Outcome, entry.Details = models.AuditOutcomeThrottled, map[string]interface{}{"reason": ErrRunbookCooldown.Error()}
+		s.auditService.Record(ctx, entry)
+		return nil, fmt.Errorf("%w: retry after %s", ErrRunbookCooldown, retryAt.UTC().Format(time.RFC3339))
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, runbookTimeout)
+	defer cancel()
+	result := &RunbookResult{
+		Action:    action,
+		Actor:     actor,
+		OK:        true,
+		Steps:     procedure.run(runCtx),
+		StartedAt: startedAt.UTC(),
+	}
+	result.DurationMS = s.now().Sub(startedAt).Milliseconds()
+	var failed []string
+	for _, step := range result.Steps {
+		if !step.OK {
+			result.OK = false
+			failed = append(failed, step.Name)
+		}
+	}
+
+	s.mu.Lock()
+	s.lastRun[action] = result
+	s.mu.Unlock()
+
+	entry.Outcome = models.AuditOutcomeSuccess
+	entry.Details = map[string]interface{}{"steps": result.Steps, "duration_ms": result.DurationMS}
+	if !result.OK {
+		entry.Outcome = models.AuditOutcomeFailure
+		entry.Details["failed"] = strings.Join(failed, ",")
+	}
+	// The run's context may have ended with a slow step; the audit row must
+	// still be written
+	auditCtx, cancelAudit := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancelAudit()
+	s.auditService.Record(auditCtx, entry)
+	return result, nil
+}
+
+// flushCaches drops the read cache and the provider response cache
+func (s *RunbookService) flushCaches(ctx context.Context) []RunbookStep {
+	s.stocks.FlushCache(ctx)
+	flushed := ProviderCache().Flush()
+	return []RunbookStep{
+		{Name: "read_cache", OK: true, Detail: "namespaces " + strings.Join(cache.Namespaces, ", ") + " invalidated"},
+		{Name: "provider_cache", OK: true, Detail: fmt.Sprintf("%d cached provider responses dropped", flushed)},
+	}
+}
+
+// resetBreakers clears the states that make the instance refuse work:
+// provider quota pauses, stores marked unavailable and load shedding
+func (s *RunbookService) resetBreakers(ctx context.Context) []RunbookStep {
+	ProviderQuotas().Reset()
+	steps := []RunbookStep{{Name: "provider_quotas", OK: true, Detail: "request counts of the current windows cleared"}}
+
+	config.CheckStores(ctx)
+	var unavailable []string
+	for _, status := range config.StoreStatuses() {
+		if !status.Available {
+			unavailable = append(unavailable, status.Name)
+		}
+	}
+	stores := RunbookStep{Name: "store_availability", OK: len(unavailable) == 0, Detail: "every connected store is available"}
+	if len(unavailable) > 0 {
+		stores.Detail, stores.Error = "", "still unavailable: "+strings.Join(unavailable, ", ")
+	}
+	steps = append(steps, stores)
+
+	state := s.loadShedder.Check()
+	shedding := RunbookStep{Name: "load_shedding", OK: !state.Shedding, Detail: "not shedding"}
+	if state.Shedding {
+		shedding.Detail, shedding.Error = "", "still shedding: "+state.Reason
+	}
+	return append(steps, shedding)
+}
+
+// reconnectDatabases replaces the Supabase pool and pings MongoDB
+func (s *RunbookService) reconnectDatabases(ctx context.Context) []RunbookStep {
+	postgres := RunbookStep{Name: config.StorePostgres, OK: true, Detail: "connection pool replaced"}
+	if err := config.ReconnectPostgres(); err != nil {
+		postgres.OK, postgres.Detail, postgres.Error = false, "", err.Error()
+	}
+	mongo := RunbookStep{Name: config.StoreMongo, OK: true, Detail: "ping succeeded"}
+	if err := config.ReconnectMongo(ctx); err != nil {
+		mongo.OK, mongo.Detail, mongo.Error = false, "", err.Error()
+	}
+	return []RunbookStep{postgres, mongo}
+}
+
+// restartScheduler restarts the job queue workers
+func (s *RunbookService) restartScheduler(ctx context.Context) []RunbookStep {
+	step := RunbookStep{Name: "job_workers", OK: true}
+	workers, err := s.jobs.Restart()
+	if err != nil {
+		step.OK, step.Error = false, err.Error()
+	} else {
+		step.Detail = fmt.Sprintf("%d workers started, %d jobs still running", workers, s.jobs.RunningJobs())
+	}
+	return []RunbookStep{step}
+}
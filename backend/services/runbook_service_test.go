@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunbookServiceGuards(t *testing.T) {
+	now := time.Date(2026, 3, 9, 2, 0, 0, 0, time.UTC)
+	runbook := NewRunbookService(nil, NewJobQueue(), nil, NewAuditService())
+	runbook.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if _, err := runbook.Run(ctx, "drop-tables", "admin", "", ""); !errors.Is(err, ErrUnknownRunbookAction) {
+		t.Errorf("Run(drop-tables) error = %v; want ErrUnknownRunbookAction", err)
+	}
+
+	// The queue was never started, so the step fails but the run is recorded
+	result, err := runbook.Run(ctx, RunbookRestartScheduler, "admin", "", "")
+	if err != nil {
+		t.Fatalf("Run(restart-scheduler) unexpected error: %v", err)
+	}
+	if result.OK || len(result.Steps) != 1 || result.Steps[0].Error == "" {
+		t.Errorf("Run(restart-scheduler) = %+v; want one failed step", result)
+	}
+
+	now = now.Add(10 * time.Second)
+	if _, err := runbook.Run(ctx, RunbookRestartScheduler, "admin", "", ""); !errors.Is(err, ErrRunbookCooldown) {
+		t.Errorf("Run() again after 10s error = %v; want ErrRunbookCooldown", err)
+	}
+
+	runbook.running.Lock()
+	now = now.Add(time.Minute)
+	if _, err := runbook.Run(ctx, RunbookRestartScheduler, "admin", "", ""); !errors.Is(err, ErrRunbookBusy) {
+		t.Errorf("Run() while another action runs error = %v; want ErrRunbookBusy", err)
+	}
+	runbook.running.Unlock()
+
+	actions := runbook.Actions()
+	if len(actions) != 4 || actions[3].Name != RunbookRestartScheduler || actions[3].LastRun != result {
+		t.Errorf("Actions() = %+v; want the four actions with restart-scheduler's last run", actions)
+	}
+}
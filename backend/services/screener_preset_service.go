@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// screenerLog logs screener preset evaluation after crawl runs
+var screenerLog = logging.Component("screener")
+
+// ScreenerPresetSource is the Notification.Source of screener preset matches
+const ScreenerPresetSource = "screener_preset"
+
+var (
+	// ErrScreenerPresetNotFound is returned when a preset does not exist or is not visible to the caller
+	ErrScreenerPresetNotFound = errors.New("screener preset not found")
+	// ErrInvalidScreenerPreset is returned when a preset's name, criteria or channels are rejected
+	ErrInvalidScreenerPreset = errors.New("invalid screener preset")
+	// ErrScreenerPresetLimit is returned when a member already has the maximum number of presets
+	ErrScreenerPresetLimit = fmt.Errorf("a member can keep at most %d screener presets", models.MaxScreenerPresetsPerProfile)
+)
+
+// ScreenerPresetService manages saved screens: members' own presets and the
+// presets admins share with every member. Members' presets with notify are
+// re-run after every crawl.
+//
+// Methods take the owner of the presets they act on: a member's profile ID,
+// or nil for the admin API, which manages the shared presets. Members can
+// read and run shared presets but not change them.
+type ScreenerPresetService struct {
+	metricsService *StockMetricsService
+	notifications  *NotificationService
+}
+
+// NewScreenerPresetService creates a new ScreenerPresetService instance
+func NewScreenerPresetService(metricsService *StockMetricsService, notifications *NotificationService) *ScreenerPresetService {
+	return &ScreenerPresetService{
+		metricsService: metricsService,
+		notifications:  notifications,
+	}
+}
+
+// Channels returns the notification channels matches can be delivered to
+func (s *ScreenerPresetService) Channels() []string {
+	return s.notifications.MemberChannels()
+}
+
+// ListPresets returns the presets visible to owner, ordered by creation
+// time: a member's own followed by the shared ones, or only the shared
+// ones for the admin API
+func (s *ScreenerPresetService) ListPresets(ctx context.Context, owner *uuid.UUID) ([]models.ScreenerPreset, error) {
+	query := config.GetDBWithContext(ctx).Where("profile_id IS NULL")
+	if owner != nil {
+		query = config.GetDBWithContext(ctx).Where("profile_id = ? OR profile_id IS NULL", *owner)
+	}
+
+	presets := make([]models.ScreenerPreset, 0)
+	if err := query.Order("profile_id IS NULL, created_at ASC").Find(&presets).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch screener presets: %w", err)
+	}
+	return presets, nil
+}
+
+// CreatePreset validates and stores a new preset of preset.ProfileID
+func (s *ScreenerPresetService) CreatePreset(ctx context.Context, preset *models.ScreenerPreset) error {
+	if err := s.normalize(preset); err != nil {
+		return err
+	}
+
+	db := config.GetDBWithContext(ctx)
+	if preset.ProfileID != nil {
+		var count int64
+		if err := db.Model(&models.ScreenerPreset{}).Where("profile_id = ?", *preset.ProfileID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count screener presets: %w", err)
+		}
+		if count >= models.MaxScreenerPresetsPerProfile {
+			return ErrScreenerPresetLimit
+		}
+	}
+
+	preset.ID = uuid.New()
+	preset.MatchedCodes = nil
+	if err := db.Create(preset).Error; err != nil {
+		return fmt.Errorf("failed to create screener preset: %w", err)
+	}
+	return nil
+}
+
+// UpdatePreset replaces the editable fields of one of owner's presets.
+// Changed criteria start over without previous matches.
+func (s *ScreenerPresetService) UpdatePreset(ctx context.Context, owner *uuid.UUID, id string, changes models.ScreenerPreset) (*models.ScreenerPreset, error) {
+	preset, err := s.getOwnedPreset(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	changes.ProfileID = owner
+	if err := s.normalize(&changes); err != nil {
+		return nil, err
+	}
+
+	if changes.Criteria != preset.Criteria {
+		preset.MatchedCodes = nil
+	}
+	preset.Name = changes.Name
+	preset.Criteria = changes.Criteria
+	preset.Notify = changes.Notify
+	preset.Channels = changes.Channels
+	preset.UpdatedAt = time.Now().UTC()
+	if err := config.GetDBWithContext(ctx).Model(preset).
+		Select("name", "criteria", "notify", "channels", "matched_codes", "updated_at").
+		Updates(preset).Error; err != nil {
+		return nil, fmt.Errorf("failed to update screener preset: %w", err)
+	}
+	return preset, nil
+}
+
+// DeletePreset removes one of owner's presets
+func (s *ScreenerPresetService) DeletePreset(ctx context.Context, owner *uuid.UUID, id string) error {
+	preset, err := s.getOwnedPreset(ctx, owner, id)
+	if err != nil {
+		return err
+	}
+	if err := config.GetDBWithContext(ctx).Delete(preset).Error; err != nil {
+		return fmt.Errorf("failed to delete screener preset: %w", err)
+	}
+	return nil
+}
+
+// RunPreset runs a preset visible to owner and returns it with its matches
+func (s *ScreenerPresetService) RunPreset(ctx context.Context, owner *uuid.UUID, id string) (*models.ScreenerPreset, []models.LiquidityMetrics, error) {
+	preset, err := s.getPreset(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !preset.Shared() && (owner == nil || *preset.ProfileID != *owner) {
+		return nil, nil, ErrScreenerPresetNotFound
+	}
+
+	filter, err := presetFilter(*preset)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := s.metricsService.Screen(ctx, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	return preset, rows, nil
+}
+
+// normalize validates a preset, rewrites its criteria as the canonical
+// screener query string and checks that its channels exist
+func (s *ScreenerPresetService) normalize(preset *models.ScreenerPreset) error {
+	preset.Name = strings.TrimSpace(preset.Name)
+	preset.Channels = strings.Join(preset.ChannelList(), ",")
+	if err := preset.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidScreenerPreset, err)
+	}
+
+	query, err := url.ParseQuery(strings.TrimPrefix(strings.TrimSpace(preset.Criteria), "?"))
+	if err != nil {
+		return fmt.Errorf("%w: criteria: %v", ErrInvalidScreenerPreset, err)
+	}
+	filter, err := ParseScreenerFilter(query.Get)
+	if err != nil {
+		return fmt.Errorf("%w: criteria: %v", ErrInvalidScreenerPreset, err)
+	}
+	preset.Criteria = EncodeScreenerFilter(filter)
+
+	available := make(map[string]bool)
+	for _, channel := range s.Channels() {
+		available[channel] = true
+	}
+	for _, channel := range preset.ChannelList() {
+		if !available[channel] {
+			return fmt.Errorf("%w: channel %q is not available (available: %s)",
+				ErrInvalidScreenerPreset, channel, strings.Join(s.Channels(), ", "))
+		}
+	}
+	return nil
+}
+
+// getPreset loads a preset by ID
+func (s *ScreenerPresetService) getPreset(ctx context.Context, id string) (*models.ScreenerPreset, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrScreenerPresetNotFound
+	}
+	var preset models.ScreenerPreset
+	err := config.GetDBWithContext(ctx).First(&preset, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrScreenerPresetNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch screener preset: %w", err)
+	}
+	return &preset, nil
+}
+
+// getOwnedPreset loads a preset owner may change: one of the member's, or
+// a shared one for the admin API
+func (s *ScreenerPresetService) getOwnedPreset(ctx context.Context, owner *uuid.UUID, id string) (*models.ScreenerPreset, error) {
+	preset, err := s.getPreset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if preset.Shared() != (owner == nil) || (owner != nil && *preset.ProfileID != *owner) {
+		return nil, ErrScreenerPresetNotFound
+	}
+	return preset, nil
+}
+
+// presetFilter parses a stored preset's criteria
+func presetFilter(preset models.ScreenerPreset) (ScreenerFilter, error) {
+	query, err := url.ParseQuery(preset.Criteria)
+	if err != nil {
+		return ScreenerFilter{}, fmt.Errorf("%w: criteria: %v", ErrInvalidScreenerPreset, err)
+	}
+	filter, err := ParseScreenerFilter(query.Get)
+	if err != nil {
+		return ScreenerFilter{}, fmt.Errorf("%w: criteria: %v", ErrInvalidScreenerPreset, err)
+	}
+	return filter, nil
+}
+
+// EvaluateRun re-runs the notifying presets once a crawl run stored new
+// candles and notifies members of the symbols that started matching. The
+// first evaluation of a preset only records its matches. It is registered
+// as a crawl run listener after the liquidity metrics are recomputed.
+func (s *ScreenerPresetService) EvaluateRun(run *models.CrawlRun, newDates map[string]string) {
+	if len(newDates) == 0 || DryRun() {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	var presets []models.ScreenerPreset
+	if err := config.GetDBWithContext(ctx).Where("notify = ? AND profile_id IS NOT NULL", true).Find(&presets).Error; err != nil {
+		screenerLog.Error("Failed to load screener presets", "run_id", run.ID.Hex(), logging.FieldError, err)
+		return
+	}
+
+	notified := 0
+	for i := range presets {
+		if s.evaluate(ctx, &presets[i]) {
+			notified++
+		}
+	}
+	if len(presets) > 0 {
+		screenerLog.Info("Screener presets evaluated", "presets", len(presets), "with_new_matches", notified)
+	}
+}
+
+// evaluate runs one preset, stores its matches and notifies its member of
+// new ones. It reports whether the member was notified.
+func (s *ScreenerPresetService) evaluate(ctx context.Context, preset *models.ScreenerPreset) bool {
+	filter, err := presetFilter(*preset)
+	if err != nil {
+		screenerLog.Warn("Skipping screener preset", "preset_id", preset.ID.String(), logging.FieldError, err)
+		return false
+	}
+	rows, err := s.metricsService.Screen(ctx, filter)
+	if err != nil {
+		screenerLog.Warn("Failed to run screener preset", "preset_id", preset.ID.String(), logging.FieldError, err)
+		return false
+	}
+
+	codes := make([]string, len(rows))
+	for i, row := range rows {
+		codes[i] = row.Code
+	}
+	added := preset.NewMatches(codes)
+	notify := preset.MatchedCodes != nil && len(added) > 0
+
+	now := time.Now().UTC()
+	columns := map[string]interface{}{
+		"matched_codes": strings.Join(codes, ","),
+		"last_run_at":   now,
+	}
+	if err := config.GetDBWithContext(ctx).Model(&models.ScreenerPreset{}).Where("id = ?", preset.ID).Updates(columns).Error; err != nil {
+		screenerLog.Warn("Failed to save screener preset matches", "preset_id", preset.ID.String(), logging.FieldError, err)
+	}
+	if !notify {
+		return false
+	}
+
+	if err := s.notifications.SendToMember(ctx, preset.ChannelList(), *preset.ProfileID, screenerPresetNotification(*preset, added)); err != nil {
+		screenerLog.Warn("Failed to notify screener preset matches", "preset_id", preset.ID.String(), logging.FieldError, err)
+	}
+	return true
+}
+
+// screenerPresetNotification describes the symbols that started matching a preset
+func screenerPresetNotification(preset models.ScreenerPreset, added []string) Notification {
+	return Notification{
+		Title:    fmt.Sprintf("%s: %d new matches", preset.Name, len(added)),
+		Message:  strings.Join(added, ", "),
+		Severity: SeverityInfo,
+		Source:   ScreenerPresetSource,
+		Fields: map[string]interface{}{
+			"Preset": preset.Name,
+			"Codes":  strings.Join(added, ","),
+		},
+	}
+}
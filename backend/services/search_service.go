@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Result groups of an admin search, in display order
+const (
+	SearchGroupAdminUsers = "admin_users"
+	SearchGroupProfiles   = "profiles"
+	SearchGroupStocks     = "stocks"
+	SearchGroupCrawlRuns  = "crawl_runs"
+)
+
+const (
+	// MinSearchQueryLength is the shortest query searched
+	MinSearchQueryLength = 2
+	// defaultSearchLimit and maxSearchLimit bound the hits of each group
+	defaultSearchLimit = 5
+	maxSearchLimit     = 20
+	// minPhoneSearchDigits is how many digits a query needs to match phone numbers
+	minPhoneSearchDigits = 4
+)
+
+// ErrSearchQueryTooShort is returned for queries shorter than MinSearchQueryLength
+var ErrSearchQueryTooShort = fmt.Errorf("query must be at least %d characters", MinSearchQueryLength)
+
+// SearchHit is one result of an admin search
+type SearchHit struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle,omitempty"`
+	Link     string `json:"link,omitempty"` // Admin page showing the entity
+}
+
+// SearchGroup holds the hits of one entity type. A group whose query failed
+// reports its error while the other groups are still returned.
+type SearchGroup struct {
+	Type  string      `json:"type"`
+	Hits  []SearchHit `json:"hits"`
+	Error string      `json:"error,omitempty"`
+}
+
+// SearchService searches admin users, profiles, stocks and crawl runs at once
+// for the dashboard's global search box
+type SearchService struct {
+	stockCollection *mongo.Collection
+	runCollection   *mongo.Collection
+}
+
+// NewSearchService creates a new SearchService instance
+func NewSearchService() *SearchService {
+	return &SearchService{
+		stockCollection: config.GetCollection("stocks"),
+		runCollection:   config.GetCollection("crawl_runs"),
+	}
+}
+
+// Search runs query against every entity type in parallel and returns one
+// group per type, each with at most limit hits
+func (s *SearchService) Search(ctx context.Context, query string, limit int) ([]SearchGroup, error) {
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < MinSearchQueryLength {
+		return nil, ErrSearchQueryTooShort
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	searches := []struct {
+		group  string
+		search func(ctx context.Context, query string, limit int) ([]SearchHit, error)
+	}{
+		{SearchGroupAdminUsers, s.searchAdminUsers},
+		{SearchGroupProfiles, s.searchProfiles},
+		{SearchGroupStocks, s.searchStocks},
+		{SearchGroupCrawlRuns, s.searchCrawlRuns},
+	}
+	groups := make([]SearchGroup, len(searches))
+	var wg sync.WaitGroup
+	for i, search := range searches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			hits, err := search.search(ctx, query, limit)
+			groups[i] = SearchGroup{Type: search.group, Hits: hits}
+			if err != nil {
+				groups[i].Hits = []SearchHit{}
+				groups[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return groups, nil
+}
+
+// searchAdminUsers matches the email, username or full name of admin users
+func (s *SearchService) searchAdminUsers(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	pattern := containsPattern(query)
+	var users []models.AdminUser
+	err := config.GetDBWithContext(ctx).
+		Where("email ILIKE ? OR username ILIKE ? OR full_name ILIKE ?", pattern, pattern, pattern).
+		Order("email").Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to search admin users: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(users))
+	for _, user := range users {
+		hit := SearchHit{ID: user.ID.String(), Title: user.Email, Subtitle: user.Role, Link: "/admin/users"}
+		if user.FullName != nil && *user.FullName != "" {
+			hit.Subtitle = *user.FullName + " · " + user.Role
+		}
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+// searchProfiles matches the email or full name of member profiles, and
+// their phone number ignoring formatting when the query has enough digits
+func (s *SearchService) searchProfiles(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	pattern := containsPattern(query)
+	db := config.GetDBWithContext(ctx).Where("email ILIKE ? OR full_name ILIKE ?", pattern, pattern)
+	for _, digits := range phoneSearchDigits(query) {
+		db = db.Or("regexp_replace(phone_number, '\\D', '', 'g') LIKE ?", "%"+digits+"%")
+	}
+
+	var profiles []models.Profile
+	if err := db.Order("email").Limit(limit).Find(&profiles).Error; err != nil {
+		return nil, fmt.Errorf("failed to search profiles: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(profiles))
+	for _, profile := range profiles {
+		subtitle := profile.PhoneNumber + " · " + profile.Membership
+		if profile.FullName != nil && *profile.FullName != "" {
+			subtitle = *profile.FullName + " · " + subtitle
+		}
+		hits = append(hits, SearchHit{ID: profile.ID.String(), Title: profile.Email, Subtitle: subtitle, Link: "/admin/users"})
+	}
+	return hits, nil
+}
+
+// searchStocks matches symbols starting with the query and Vietnamese or
+// English company names containing it; symbol matches come first
+func (s *SearchService) searchStocks(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	opts := options.Find().SetSort(bson.M{"code": 1}).SetLimit(int64(maxSearchLimit * 5))
+	cursor, err := s.stockCollection.Find(ctx, stockSearchFilter(query), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search stocks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stocks []models.Stock
+	if err := cursor.All(ctx, &stocks); err != nil {
+		return nil, fmt.Errorf("failed to decode stocks: %w", err)
+	}
+	return rankStockHits(stocks, query, limit), nil
+}
+
+// rankStockHits orders stocks whose code starts with query before those
+// matched by company name only, keeping at most limit
+func rankStockHits(stocks []models.Stock, query string, limit int) []SearchHit {
+	ranked := rankStocks(stocks, query, limit)
+	hits := make([]SearchHit, 0, len(ranked))
+	for _, stock := range ranked {
+		subtitle := stock.CompanyName + " · " + stock.Exchange
+		if stock.CompanyNameEn != "" {
+			subtitle = stock.CompanyName + " (" + stock.CompanyNameEn + ") · " + stock.Exchange
+		}
+		hits = append(hits, SearchHit{ID: stock.Code, Title: stock.Code, Subtitle: subtitle})
+	}
+	return hits
+}
+
+// searchCrawlRuns matches a run ID, a run status, or the symbols that
+// failed in a run, newest run first
+func (s *SearchService) searchCrawlRuns(ctx context.Context, query string, limit int) ([]SearchHit, error) {
+	conditions := bson.A{
+		bson.M{"status": strings.ToLower(query)},
+		bson.M{"errors.code": strings.ToUpper(query)},
+	}
+	if id, err := primitive.ObjectIDFromHex(query); err == nil {
+		conditions = append(conditions, bson.M{"_id": id})
+	}
+	opts := options.Find().
+		SetSort(bson.M{"startedAt": -1}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"errors": 0})
+	cursor, err := s.runCollection.Find(ctx, bson.M{"$or": conditions}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search crawl runs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var runs []models.CrawlRun
+	if err := cursor.All(ctx, &runs); err != nil {
+		return nil, fmt.Errorf("failed to decode crawl runs: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(runs))
+	for _, run := range runs {
+		kind := run.Kind
+		if kind == "" {
+			kind = models.CrawlRunKindFull
+		}
+		hits = append(hits, SearchHit{
+			ID:    run.ID.Hex(),
+			Title: fmt.Sprintf("%s %s run, %s", run.StartedAt.Time().In(markettime.Location()).Format("2006-01-02 15:04"), kind, run.Status),
+			Subtitle: fmt.Sprintf("%d/%d symbols succeeded, %d failed",
+				run.SucceededSymbols, run.TotalSymbols, run.FailedSymbols),
+			Link: "/admin/crawl-errors?run_id=" + run.ID.Hex(),
+		})
+	}
+	return hits, nil
+}
+
+// containsPattern returns an ILIKE pattern matching values containing query
+// literally
+func containsPattern(query string) string {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + escaper.Replace(query) + "%"
+}
+
+// phoneSearchDigits returns the digit strings to look for in phone numbers
+// when query looks like (part of) one: its digits, plus the local form of an
+// international +84 number. It returns nothing for other queries.
+func phoneSearchDigits(query string) []string {
+	digits := make([]rune, 0, len(query))
+	for _, r := range query {
+		switch {
+		case unicode.IsDigit(r):
+			digits = append(digits, r)
+		case r == '+' || r == ' ' || r == '.' || r == '-' || r == '(' || r == ')':
+		default:
+			return nil
+		}
+	}
+	if len(digits) < minPhoneSearchDigits {
+		return nil
+	}
+	result := []string{string(digits)}
+	if local, ok := strings.CutPrefix(string(digits), "84"); ok && len(local) >= minPhoneSearchDigits-1 {
+		result = append(result, "0"+local)
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestContainsPattern(t *testing.T) {
+	tests := map[string]string{
+		"nguyen":     "%nguyen%",
+		"50%_off":    `%50\%\_off%`,
+		`back\slash`: `%back\\slash%`,
+	}
+	for query, want := range tests {
+		if got := containsPattern(query); got != want {
+			t.Errorf("containsPattern(%q) = %q; want %q", query, got, want)
+		}
+	}
+}
+
+func TestPhoneSearchDigits(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"0912 345", []string{"0912345"}},
+		{"+84 912.345", []string{"84912345", "0912345"}},
+		{"091", nil},
+		{"nguyen", nil},
+		{"a1234", nil},
+	}
+	for _, tt := range tests {
+		if got := phoneSearchDigits(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("phoneSearchDigits(%q) = %v; want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestRankStockHits(t *testing.T) {
+	stocks := []models.Stock{
+		{Code: "ACB", CompanyName: "Thep HPS A Chau", Exchange: "HOSE"},
+		{Code: "HPG", CompanyName: "Tap doan Hoa Phat", Exchange: "HOSE"},
+		{Code: "HPX", CompanyName: "Dau tu Hai Phat", Exchange: "HOSE"},
+		{Code: "PHR", CompanyName: "Cao su HP Phuoc Hoa", Exchange: "HOSE"},
+	}
+	hits := rankStockHits(stocks, "hp", 3)
+	got := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		got = append(got, hit.ID)
+	}
+	if want := []string{"HPG", "HPX", "ACB"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("rankStockHits() = %v; want %v", got, want)
+	}
+}
+
+func TestSearchRejectsShortQuery(t *testing.T) {
+	if _, err := (&SearchService{}).Search(context.Background(), " a ", 5); err != ErrSearchQueryTooShort {
+		t.Errorf("Search(\" a \") = %v; want ErrSearchQueryTooShort", err)
+	}
+}
+
+func TestStockSearchFilterMatchesFoldedNames(t *testing.T) {
+	filter := stockSearchFilter("Hòa  Phát")
+	conditions, ok := filter["$or"].(bson.A)
+	if !ok || len(conditions) != 4 {
+		t.Fatalf("stockSearchFilter() = %v; want 4 alternatives", filter)
+	}
+	want := bson.M{"searchNames": bson.M{"$regex": "hoa phat"}}
+	if !reflect.DeepEqual(conditions[3], want) {
+		t.Errorf("folded condition = %v; want %v", conditions[3], want)
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sectorBreadthLog logs the sector breadth computed after crawl runs
+var sectorBreadthLog = logging.Component("sector_breadth")
+
+const (
+	// sectorBreadthBatch caps the symbols whose candles are read at once
+	sectorBreadthBatch = 200
+	// MaxSectorBreadthDays caps the date range of one sector breadth query
+	MaxSectorBreadthDays = 366
+)
+
+// SectorBreadthService computes the daily breadth of every sector after
+// each crawl and keeps its history in the sector_breadth collection
+type SectorBreadthService struct {
+	breadthCollection *mongo.Collection
+	stockService      PriceStore
+}
+
+// NewSectorBreadthService creates a new SectorBreadthService instance
+func NewSectorBreadthService(stockService PriceStore) *SectorBreadthService {
+	return &SectorBreadthService{
+		breadthCollection: config.GetCollection("sector_breadth"),
+		stockService:      stockService,
+	}
+}
+
+// ComputeRun computes the sector breadth of the newest trading day of a
+// finished crawl run. It is registered as a crawl run listener.
+func (s *SectorBreadthService) ComputeRun(run *models.CrawlRun, newDates map[string]string) {
+	date := ""
+	for _, newDate := range newDates {
+		if newDate > date {
+			date = newDate
+		}
+	}
+	if date == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	snapshot, err := s.Compute(ctx, date)
+	if err != nil {
+		sectorBreadthLog.Warn("Failed to compute sector breadth", "run_id", run.ID.Hex(), logging.FieldError, err)
+		return
+	}
+	sectorBreadthLog.Info("Sector breadth computed", "date", snapshot.Date, "sectors", len(snapshot.Sectors))
+}
+
+// Compute computes and stores the breadth of every sector on date,
+// replacing an earlier computation of the same date
+func (s *SectorBreadthService) Compute(ctx context.Context, date string) (*models.SectorBreadthSnapshot, error) {
+	sectors, err := s.stockService.Sectors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(sectors) == 0 {
+		return nil, fmt.Errorf("no stock has a sector yet")
+	}
+
+	codes := make([]string, 0, len(sectors))
+	for code := range sectors {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	candles := make(map[string][]models.CandleData, len(codes))
+	shares := make(map[string]int64, len(codes))
+	for start := 0; start < len(codes); start += sectorBreadthBatch {
+		end := start + sectorBreadthBatch
+		if end > len(codes) {
+			end = len(codes)
+		}
+		batch, err := s.stockService.LatestCandles(ctx, codes[start:end], models.BreadthMAPeriod)
+		if err != nil {
+			return nil, err
+		}
+		for code, history := range batch {
+			candles[code] = history
+		}
+		stocks, err := s.stockService.GetStocks(ctx, codes[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for code, stock := range stocks {
+			shares[code] = stock.SharesOutstanding
+		}
+	}
+
+	snapshot := &models.SectorBreadthSnapshot{
+		Date:       date,
+		Sectors:    models.ComputeSectorBreadth(date, sectors, shares, candles),
+		ComputedAt: primitive.NewDateTimeFromTime(time.Now()),
+	}
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.breadthCollection.ReplaceOne(ctx, bson.M{"_id": date}, snapshot, opts); err != nil {
+		return nil, fmt.Errorf("failed to save sector breadth: %w", err)
+	}
+	return snapshot, nil
+}
+
+// History returns the stored breadth from one date to another (inclusive),
+// oldest first, optionally of one sector only
+func (s *SectorBreadthService) History(ctx context.Context, from, to, sector string) ([]models.SectorBreadthSnapshot, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": bson.M{"$gte": from, "$lte": to}}
+	cursor, err := s.breadthCollection.Find(ctx, filter, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sector breadth: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := make([]models.SectorBreadthSnapshot, 0)
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to decode sector breadth: %w", err)
+	}
+	if sector != "" {
+		for i := range snapshots {
+			kept := make([]models.SectorBreadth, 0, 1)
+			for _, breadth := range snapshots[i].Sectors {
+				if breadth.Sector == sector {
+					kept = append(kept, breadth)
+				}
+			}
+			snapshots[i].Sectors = kept
+		}
+	}
+	return snapshots, nil
+}
+
+// ParseBreadthRange resolves the ?from= and ?to= dates of a breadth query:
+// to defaults to today in Vietnam and from to 30 days before to
+func ParseBreadthRange(rawFrom, rawTo string, now time.Time) (string, string, error) {
+	to := now.In(markettime.Location())
+	if rawTo != "" {
+		parsed, err := time.Parse("2006-01-02", rawTo)
+		if err != nil {
+			return "", "", fmt.Errorf("to: expected a date in YYYY-MM-DD format")
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -30)
+	if rawFrom != "" {
+		parsed, err := time.Parse("2006-01-02", rawFrom)
+		if err != nil {
+			return "", "", fmt.Errorf("from: expected a date in YYYY-MM-DD format")
+		}
+		from = parsed
+	}
+
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+	if fromDate > toDate {
+		return "", "", fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) > MaxSectorBreadthDays*24*time.Hour {
+		return "", "", fmt.Errorf("at most %d days per request", MaxSectorBreadthDays)
+	}
+	return fromDate, toDate, nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/vndirect"
+)
+
+func TestParseBreadthRange(t *testing.T) {
+	now := time.Date(2026, 2, 9, 18, 30, 0, 0, time.UTC) // 2026-02-10 in Vietnam
+
+	from, to, err := ParseBreadthRange("", "", now)
+	if err != nil || from != "2026-01-11" || to != "2026-02-10" {
+		t.Errorf("defaults = %s..%s, %v; want 2026-01-11..2026-02-10", from, to, err)
+	}
+	from, to, err = ParseBreadthRange("2026-01-02", "2026-01-31", now)
+	if err != nil || from != "2026-01-02" || to != "2026-01-31" {
+		t.Errorf("explicit = %s..%s, %v", from, to, err)
+	}
+
+	invalid := [][2]string{
+		{"2026-02-01", "2026-01-01"}, // Reversed
+		{"2024-01-01", "2026-01-01"}, // Too long
+		{"02/01/2026", ""},
+		{"", "yesterday"},
+	}
+	for _, r := range invalid {
+		if _, _, err := ParseBreadthRange(r[0], r[1], now); err == nil {
+			t.Errorf("ParseBreadthRange(%q, %q): expected an error", r[0], r[1])
+		}
+	}
+}
+
+func TestIndustrySectors(t *testing.T) {
+	sectors := industrySectors([]vndirect.Industry{
+		{IndustryCode: "1700", IndustryLevel: "2", EnglishName: "Basic Resources", CodeList: "HPG, hsg,,NKG"},
+		{IndustryCode: "8300", IndustryLevel: "2", VietnameseName: "Ngân hàng", CodeList: "VCB"},
+	})
+	if len(sectors) != 4 || sectors["HSG"] != "Basic Resources" || sectors["VCB"] != "Ngân hàng" {
+		t.Errorf("sectors = %v; want HPG, HSG and NKG in Basic Resources and VCB by its Vietnamese name", sectors)
+	}
+}
+
+func TestIndustryClassification(t *testing.T) {
+	classification := industryClassification(
+		[]vndirect.Industry{
+			{IndustryCode: "1000", IndustryLevel: "1", EnglishName: "Basic Materials", CodeList: "HPG,HSG,DPM"},
+		},
+		[]vndirect.Industry{
+			{IndustryCode: "1700", IndustryLevel: "2", EnglishName: "Basic Resources", CodeList: "HPG,HSG"},
+			{IndustryCode: "8300", IndustryLevel: "2", EnglishName: "Banks", CodeList: "VCB"},
+		},
+	)
+	want := models.IndustryClassification{Industry: "Basic Materials", Sector: "Basic Resources", ICBCode: "1700"}
+	if len(classification) != 3 || classification["HSG"] != want {
+		t.Errorf("classification = %v; want HPG and HSG in %+v and VCB", classification, want)
+	}
+	if _, ok := classification["DPM"]; ok {
+		t.Error("DPM has no sector and should be left out")
+	}
+	if got := classification["VCB"]; got.Sector != "Banks" || got.Industry != "" {
+		t.Errorf("VCB = %+v; want Banks without an industry", got)
+	}
+}
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"gorm.io/gorm/clause"
+)
+
+// seedStock is a stock created by the development seed with the price its
+// synthetic history starts from (thousands of đồng) and its usual volume
+type seedStock struct {
+	Code, CompanyName, CompanyNameEn, Exchange, Sector string
+	StartPrice                                         float64
+	Volume                                             int64
+}
+
+// seedStocks are a handful of well-known listings across the three exchanges
+var seedStocks = []seedStock{
+	{"HPG", "Công ty Cổ phần Tập đoàn Hòa Phát", "Hoa Phat Group", "HOSE", "Basic Resources", 25, 20_000_000},
+	{"VNM", "Công ty Cổ phần Sữa Việt Nam", "Vietnam Dairy Products", "HOSE", "Food & Beverage", 70, 3_000_000},
+	{"FPT", "Công ty Cổ phần FPT", "FPT Corporation", "HOSE", "Technology", 95, 4_000_000},
+	{"VCB", "Ngân hàng TMCP Ngoại thương Việt Nam", "Vietcombank", "HOSE", "Banks", 90, 2_000_000},
+	{"SHS", "Công ty Cổ phần Chứng khoán Sài Gòn - Hà Nội", "Saigon - Hanoi Securities", "HNX", "Financial Services", 15, 10_000_000},
+	{"ACV", "Tổng Công ty Cảng hàng không Việt Nam", "Airports Corporation of Vietnam", "UPCOM", "Industrial Goods & Services", 90, 500_000},
+}
+
+// seedProfile is a sample member profile created by the development seed
+type seedProfile struct {
+	Email, Phone, FullName, Membership string
+	ExpiresInDays                      int // Premium expiry relative to now; negative for lapsed, 0 for none
+}
+
+var seedProfiles = []seedProfile{
+	{"free.member@cpls.local", "0901000001", "Nguyễn Văn An", models.MembershipFree, 0},
+	{"premium.member@cpls.local", "0901000002", "Trần Thị Bình", models.MembershipPremium, 180},
+	{"lapsed.member@cpls.local", "0901000003", "Lê Minh Châu", models.MembershipPremium, -30},
+}
+
+// SeedOptions configures SeedDevelopment
+type SeedOptions struct {
+	Years         int // Years of candle history per stock
+	AdminEmail    string
+	AdminUsername string
+	AdminPassword string
+}
+
+// SeedReport describes what SeedDevelopment created
+type SeedReport struct {
+	Stocks        int      `json:"stocks"`
+	SkippedStocks []string `json:"skipped_stocks"` // Already present; their candles were left alone
+	Candles       int      `json:"candles"`
+	Admin         string   `json:"admin,omitempty"`
+	AdminCreated  bool     `json:"admin_created"`
+	Profiles      int      `json:"profiles"`
+}
+
+// SeedDevelopment populates a development environment: a few stocks with
+// synthetic candle history in MongoDB, and an admin user and sample member
+// profiles in PostgreSQL, so the dashboard and APIs work without crawling
+// VNDirect. Stores that are not connected are skipped. It is idempotent:
+// existing stocks, candles and profiles are kept, and the admin's password
+// is reset to AdminPassword.
+func SeedDevelopment(ctx context.Context, opts SeedOptions) (*SeedReport, error) {
+	if opts.Years <= 0 {
+		opts.Years = 2
+	}
+	report := &SeedReport{SkippedStocks: []string{}}
+	now := time.Now().UTC()
+
+	if config.MongoClient != nil {
+		if err := seedStocksAndCandles(ctx, opts.Years, now, report); err != nil {
+			return report, err
+		}
+	}
+	if config.PostgresDB != nil {
+		adminUser, created, err := NewAuthService().BootstrapAdmin(opts.AdminEmail, opts.AdminUsername, opts.AdminPassword)
+		if err != nil {
+			return report, fmt.Errorf("failed to seed admin: %w", err)
+		}
+		report.Admin, report.AdminCreated = adminUser.Email, created
+
+		if err := seedMemberProfiles(ctx, now, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// seedStocksAndCandles inserts the seed stocks that do not exist yet, each
+// with Years of synthetic candles
+func seedStocksAndCandles(ctx context.Context, years int, now time.Time, report *SeedReport) error {
+	stocks := config.GetCollection("stocks")
+	prices := config.GetCollection("stock_prices")
+	timestamp := primitive.NewDateTimeFromTime(now)
+
+	for _, seed := range seedStocks {
+		exchange, ok := models.LookupExchange(seed.Exchange)
+		if !ok {
+			return fmt.Errorf("seed stock %s has unknown exchange %s", seed.Code, seed.Exchange)
+		}
+		stock := models.Stock{
+			Code:          seed.Code,
+			CompanyName:   seed.CompanyName,
+			CompanyNameEn: seed.CompanyNameEn,
+			Exchange:      seed.Exchange,
+			Type:          "stock",
+			Status:        "listed",
+			Sector:        seed.Sector,
+			SearchNames:   models.StockSearchNames(seed.CompanyName, seed.CompanyNameEn),
+			CreatedAt:     timestamp,
+			UpdatedAt:     timestamp,
+		}
+		result, err := stocks.UpdateOne(ctx, bson.M{"code": seed.Code}, bson.M{"$setOnInsert": stock}, options.Update().SetUpsert(true))
+		if err != nil {
+			return fmt.Errorf("failed to seed stock %s: %w", seed.Code, err)
+		}
+		if result.UpsertedCount == 0 {
+			report.SkippedStocks = append(report.SkippedStocks, seed.Code)
+			continue
+		}
+		report.Stocks++
+
+		candles := syntheticCandles(seed, exchange, now.AddDate(-years, 0, 0), now)
+		byYear := make(map[int][]models.CandleData)
+		for _, candle := range candles {
+			year, _ := models.GetYearFromDate(candle.D)
+			byYear[year] = append(byYear[year], candle)
+		}
+		for year, history := range byYear {
+			bucket := models.PriceBucket{
+				ID:       models.GenerateBucketID(seed.Code, year),
+				Code:     seed.Code,
+				Year:     year,
+				History:  history,
+				Checksum: models.ComputeChecksum(history),
+				Encoding: config.Runtime().PriceStorageEncoding,
+			}
+			if _, err := prices.InsertOne(ctx, bucket); err != nil && !mongo.IsDuplicateKeyError(err) {
+				return fmt.Errorf("failed to seed candles of %s: %w", seed.Code, err)
+			}
+		}
+		report.Candles += len(candles)
+	}
+	return nil
+}
+
+// syntheticCandles returns a random walk of daily candles between from and
+// to on the exchange's trading days. Every candle stays within the price
+// band of the previous close and the walk is the same for a code on every
+// run.
+func syntheticCandles(seed seedStock, exchange models.Exchange, from, to time.Time) []models.CandleData {
+	hash := fnv.New64a()
+	hash.Write([]byte(seed.Code))
+	rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	roundTick := func(price float64) float64 {
+		tick := exchange.PriceBand.Tick(price)
+		if tick <= 0 {
+			tick = 0.01
+		}
+		return math.Round(math.Round(price/tick)*tick*100) / 100
+	}
+
+	var candles []models.CandleData
+	previous := seed.StartPrice
+	location := exchange.Location()
+	for day := from.In(location); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if !exchange.IsTradingDay(day) {
+			continue
+		}
+		floor, ceiling := exchange.PriceLimits(previous, false)
+		clamp := func(price float64) float64 {
+			return roundTick(math.Min(ceiling, math.Max(floor, price)))
+		}
+
+		openPrice := clamp(previous * (1 + rng.NormFloat64()*0.005))
+		closePrice := clamp(previous * (1 + 0.0003 + rng.NormFloat64()*0.018))
+		high := clamp(math.Max(openPrice, closePrice) * (1 + math.Abs(rng.NormFloat64())*0.008))
+		low := clamp(math.Min(openPrice, closePrice) * (1 - math.Abs(rng.NormFloat64())*0.008))
+		volume := int64(float64(seed.Volume) * math.Exp(rng.NormFloat64()*0.4))
+
+		candles = append(candles, models.CandleData{
+			D: day.Format("2006-01-02"),
+			O: openPrice,
+			H: high,
+			L: low,
+			C: closePrice,
+			V: volume,
+		})
+		previous = closePrice
+	}
+	return candles
+}
+
+// seedMemberProfiles creates the sample member profiles that do not exist
+// yet. On Supabase profiles reference auth.users, so a matching auth user
+// without password is created first; it cannot sign in.
+func seedMemberProfiles(ctx context.Context, now time.Time, report *SeedReport) error {
+	db := config.GetDBWithContext(ctx)
+
+	var hasAuthUsers bool
+	if err := db.Raw("SELECT to_regclass('auth.users') IS NOT NULL").Scan(&hasAuthUsers).Error; err != nil {
+		return fmt.Errorf("failed to look up auth.users: %w", err)
+	}
+
+	for _, seed := range seedProfiles {
+		id := uuid.NewSHA1(uuid.NameSpaceURL, []byte("cpls-seed:"+seed.Email))
+		if hasAuthUsers {
+			err := db.Exec(`INSERT INTO auth.users (id, instance_id, aud, role, email, created_at, updated_at)
+				VALUES (?, '00000000-0000-0000-0000-000000000000', 'authenticated', 'authenticated', ?, now(), now())
+				ON CONFLICT (id) DO NOTHING`, id, seed.Email).Error
+			if err != nil {
+				return fmt.Errorf("failed to seed auth user %s: %w", seed.Email, err)
+			}
+		}
+
+		fullName := seed.FullName
+		profile := models.Profile{
+			ID:          id,
+			Email:       seed.Email,
+			PhoneNumber: seed.Phone,
+			FullName:    &fullName,
+			Membership:  seed.Membership,
+			Active:      true,
+		}
+		if seed.ExpiresInDays != 0 {
+			expires := now.AddDate(0, 0, seed.ExpiresInDays)
+			profile.MembershipExpiresAt = &expires
+		}
+		// A signup trigger may already have created a bare profile for the auth user
+		result := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"phone_number", "full_name", "membership", "membership_expires_at"}),
+			Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "profiles.full_name IS NULL"}}},
+		}).Create(&profile)
+		if result.Error != nil {
+			return fmt.Errorf("failed to seed profile %s: %w", seed.Email, result.Error)
+		}
+		report.Profiles += int(result.RowsAffected)
+	}
+	return nil
+}
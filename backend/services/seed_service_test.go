@@ -0,0 +1,44 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestSyntheticCandles(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	for _, seed := range seedStocks {
+		exchange, ok := models.LookupExchange(seed.Exchange)
+		if !ok {
+			t.Fatalf("%s: unknown exchange %s", seed.Code, seed.Exchange)
+		}
+		candles := syntheticCandles(seed, exchange, from, to)
+		if len(candles) < 400 {
+			t.Fatalf("%s: %d candles over two years; want about 500", seed.Code, len(candles))
+		}
+		if !reflect.DeepEqual(candles, syntheticCandles(seed, exchange, from, to)) {
+			t.Errorf("%s: candles differ between runs", seed.Code)
+		}
+
+		previous := seed.StartPrice
+		for _, candle := range candles {
+			day, _ := time.ParseInLocation("2006-01-02", candle.D, exchange.Location())
+			if !exchange.IsTradingDay(day) {
+				t.Errorf("%s: candle on non-trading day %s", seed.Code, candle.D)
+			}
+			if candle.H < candle.O || candle.H < candle.C || candle.L > candle.O || candle.L > candle.C || candle.V <= 0 {
+				t.Errorf("%s %s: inconsistent candle %+v", seed.Code, candle.D, candle)
+			}
+			floor, ceiling := exchange.PriceLimits(previous, false)
+			if candle.L < floor-1e-9 || candle.H > ceiling+1e-9 {
+				t.Errorf("%s %s: %+v outside band [%v, %v]", seed.Code, candle.D, candle, floor, ceiling)
+			}
+			previous = candle.C
+		}
+	}
+}
@@ -0,0 +1,384 @@
+// Package servicestest provides in-memory implementations of the service
+// interfaces (services.UserStore, services.ProfileStatus and
+// services.AdminStatus,
+// services.PriceStore and
+// services.MarketDataSource), so controllers and services can be tested
+// without Supabase, MongoDB or a data provider.
+package servicestest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/google/uuid"
+)
+
+var (
+	_ services.UserStore          = (*UserStore)(nil)
+	_ services.ProfileStatus      = (*UserStore)(nil)
+	_ services.AdminStatus        = (*UserStore)(nil)
+	_ services.PriceStore         = (*PriceStore)(nil)
+	_ services.MarketDataSource   = (*DataSource)(nil)
+	_ services.RecentPriceFetcher = (*DataSource)(nil)
+)
+
+// UserStore is an in-memory services.UserStore
+type UserStore struct {
+	AdminUsers   []models.AdminUser
+	Profiles     []models.Profile
+	LoginHistory map[string][]models.LoginHistory // By admin user ID, newest first
+	Err          error                            // Returned by every method when set
+}
+
+// GetAdminUsers implements services.UserStore
+func (s *UserStore) GetAdminUsers(ctx context.Context) ([]models.AdminUser, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return s.AdminUsers, nil
+}
+
+// GetAdminUsersWithPagination implements services.UserStore
+func (s *UserStore) GetAdminUsersWithPagination(ctx context.Context, page, pageSize int) ([]models.AdminUser, int64, error) {
+	if s.Err != nil {
+		return nil, 0, s.Err
+	}
+	return paginate(s.AdminUsers, page, pageSize), int64(len(s.AdminUsers)), nil
+}
+
+// GetProfiles implements services.UserStore
+func (s *UserStore) GetProfiles(ctx context.Context) ([]models.Profile, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return s.Profiles, nil
+}
+
+// GetProfilesWithPagination implements services.UserStore
+func (s *UserStore) GetProfilesWithPagination(ctx context.Context, page, pageSize int) ([]models.Profile, int64, error) {
+	if s.Err != nil {
+		return nil, 0, s.Err
+	}
+	return paginate(s.Profiles, page, pageSize), int64(len(s.Profiles)), nil
+}
+
+// ProfileActive implements services.ProfileStatus, returning
+// services.ErrProfileNotFound for IDs not in Profiles
+func (s *UserStore) ProfileActive(ctx context.Context, profileID uuid.UUID) (bool, error) {
+	if s.Err != nil {
+		return false, s.Err
+	}
+	for _, profile := range s.Profiles {
+		if profile.ID == profileID {
+			return profile.Active, nil
+		}
+	}
+	return false, services.ErrProfileNotFound
+}
+
+// AdminActive implements services.AdminStatus, returning
+// services.ErrAdminUserNotFound for IDs not in AdminUsers
+func (s *UserStore) AdminActive(ctx context.Context, adminUserID uuid.UUID) (bool, error) {
+	if s.Err != nil {
+		return false, s.Err
+	}
+	for _, user := range s.AdminUsers {
+		if user.ID == adminUserID {
+			return user.Active, nil
+		}
+	}
+	return false, services.ErrAdminUserNotFound
+}
+
+// GetLoginHistory implements services.UserStore, returning
+// services.ErrAdminUserNotFound for IDs not in AdminUsers
+func (s *UserStore) GetLoginHistory(ctx context.Context, adminUserID string, limit int) ([]models.LoginHistory, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	found := false
+	for _, user := range s.AdminUsers {
+		if user.ID.String() == adminUserID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, services.ErrAdminUserNotFound
+	}
+	history := s.LoginHistory[adminUserID]
+	if limit > 0 && len(history) > limit {
+		history = history[:limit]
+	}
+	return history, nil
+}
+
+// paginate returns page (1-based) of items
+func paginate[T any](items []T, page, pageSize int) []T {
+	start := (page - 1) * pageSize
+	if start < 0 || start >= len(items) {
+		return []T{}
+	}
+	end := start + pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[start:end]
+}
+
+// PriceStore is an in-memory services.PriceStore
+type PriceStore struct {
+	Stocks  []models.Stock
+	Candles map[string][]models.CandleData // By code, oldest first
+	Err     error                          // Returned by every method when set
+}
+
+// GetStockMetadata implements services.PriceStore
+func (s *PriceStore) GetStockMetadata(ctx context.Context, since *time.Time) (*services.StockMetadataResult, error) {
+	next := services.MetadataCursor(since)
+	var stocks []models.Stock
+	if _, err := s.StreamStockMetadata(ctx, since, func(stock models.Stock) error {
+		stocks = append(stocks, stock)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	mode := "full"
+	if since != nil {
+		mode = "delta"
+	}
+	return &services.StockMetadataResult{Mode: mode, Since: since, NextSince: next, Count: len(stocks), Stocks: stocks}, nil
+}
+
+// StreamStockMetadata implements services.PriceStore
+func (s *PriceStore) StreamStockMetadata(ctx context.Context, since *time.Time, emit func(models.Stock) error) (int, error) {
+	if s.Err != nil {
+		return 0, s.Err
+	}
+	emitted := 0
+	for _, stock := range s.Stocks {
+		if since != nil && !stock.UpdatedAt.Time().After(*since) {
+			continue
+		}
+		if err := emit(stock); err != nil {
+			return emitted, err
+		}
+		emitted++
+	}
+	return emitted, nil
+}
+
+// SearchStocks implements services.PriceStore with codes starting with query
+// and company names containing it, ignoring case only
+func (s *PriceStore) SearchStocks(ctx context.Context, query string, limit int) ([]models.Stock, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	query = strings.ToLower(strings.TrimSpace(query))
+	if len([]rune(query)) < services.MinSearchQueryLength {
+		return nil, services.ErrSearchQueryTooShort
+	}
+	matches := []models.Stock{}
+	for _, stock := range s.Stocks {
+		if limit > 0 && len(matches) == limit {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(stock.Code), query) || strings.Contains(strings.ToLower(stock.CompanyName), query) {
+			matches = append(matches, stock)
+		}
+	}
+	return matches, nil
+}
+
+// GetStock implements services.PriceStore
+func (s *PriceStore) GetStock(ctx context.Context, code string) (*models.Stock, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	for _, stock := range s.Stocks {
+		if strings.EqualFold(stock.Code, code) {
+			return &stock, nil
+		}
+	}
+	return nil, services.ErrStockNotFound
+}
+
+// GetStocks implements services.PriceStore
+func (s *PriceStore) GetStocks(ctx context.Context, codes []string) (map[string]models.Stock, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	stocks := make(map[string]models.Stock, len(codes))
+	for _, code := range codes {
+		if stock, err := s.GetStock(ctx, code); err == nil {
+			stocks[stock.Code] = *stock
+		}
+	}
+	return stocks, nil
+}
+
+// Sectors implements services.PriceStore
+func (s *PriceStore) Sectors(ctx context.Context) (map[string]string, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	sectors := make(map[string]string)
+	for _, stock := range s.Stocks {
+		if stock.Sector != "" {
+			sectors[stock.Code] = stock.Sector
+		}
+	}
+	return sectors, nil
+}
+
+// GetCandles implements services.PriceStore: the candles of codes between
+// from and to by date, a date held by several codes taken from the first
+func (s *PriceStore) GetCandles(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	fromDate, toDate := from.Format("2006-01-02"), to.Format("2006-01-02")
+	byDate := make(map[string]models.CandleData)
+	for i := len(codes) - 1; i >= 0; i-- {
+		for _, candle := range s.Candles[strings.ToUpper(codes[i])] {
+			if candle.D >= fromDate && candle.D <= toDate {
+				byDate[candle.D] = candle
+			}
+		}
+	}
+	candles := make([]models.CandleData, 0, len(byDate))
+	for _, candle := range byDate {
+		candles = append(candles, candle)
+	}
+	sort.Slice(candles, func(i, j int) bool { return candles[i].D < candles[j].D })
+	return candles, nil
+}
+
+// GetCandlesFilteredInDB implements services.PriceStore like GetCandles
+func (s *PriceStore) GetCandlesFilteredInDB(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error) {
+	return s.GetCandles(ctx, codes, from, to)
+}
+
+// CandlesETag implements services.PriceStore with a hash of the candles
+func (s *PriceStore) CandlesETag(ctx context.Context, codes []string, from, to time.Time) (string, error) {
+	candles, err := s.GetCandles(ctx, codes, from, to)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprint(codes, candles)))
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// StreamCandles implements services.PriceStore
+func (s *PriceStore) StreamCandles(ctx context.Context, codes []string, from, to time.Time, emit func(models.CandleData) error) (int, error) {
+	candles, err := s.GetCandles(ctx, codes, from, to)
+	if err != nil {
+		return 0, err
+	}
+	for i, candle := range candles {
+		if err := emit(candle); err != nil {
+			return i, err
+		}
+	}
+	return len(candles), nil
+}
+
+// LatestCandles implements services.PriceStore
+func (s *PriceStore) LatestCandles(ctx context.Context, codes []string, n int) (map[string][]models.CandleData, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	latest := make(map[string][]models.CandleData, len(codes))
+	for _, code := range codes {
+		candles := s.Candles[strings.ToUpper(code)]
+		if len(candles) == 0 {
+			continue
+		}
+		if len(candles) > n {
+			candles = candles[len(candles)-n:]
+		}
+		latest[strings.ToUpper(code)] = candles
+	}
+	return latest, nil
+}
+
+// CandlesAsOf implements services.PriceStore
+func (s *PriceStore) CandlesAsOf(ctx context.Context, codes []string, date string, n int) (map[string][]models.CandleData, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	asOf := make(map[string][]models.CandleData, len(codes))
+	for _, code := range codes {
+		var candles []models.CandleData
+		for _, candle := range s.Candles[strings.ToUpper(code)] {
+			if candle.D <= date {
+				candles = append(candles, candle)
+			}
+		}
+		if len(candles) == 0 {
+			continue
+		}
+		if len(candles) > n {
+			candles = candles[len(candles)-n:]
+		}
+		asOf[strings.ToUpper(code)] = candles
+	}
+	return asOf, nil
+}
+
+// DataSource is an in-memory services.MarketDataSource
+type DataSource struct {
+	SourceName string
+	Stocks     []models.Stock
+	Candles    map[string][]models.CandleData // By code, newest first like the providers
+	Err        error                          // Returned by every fetch when set
+}
+
+// Name implements services.MarketDataSource
+func (s *DataSource) Name() string {
+	return s.SourceName
+}
+
+// FetchSymbols implements services.MarketDataSource
+func (s *DataSource) FetchSymbols(exchanges []string) ([]models.Stock, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	var stocks []models.Stock
+	for _, stock := range s.Stocks {
+		for _, exchange := range exchanges {
+			if strings.EqualFold(stock.Exchange, exchange) {
+				stocks = append(stocks, stock)
+				break
+			}
+		}
+	}
+	return stocks, nil
+}
+
+// FetchPrices implements services.MarketDataSource
+func (s *DataSource) FetchPrices(stock models.Stock) ([]models.CandleData, error) {
+	return s.FetchRecentPrices(stock, 0)
+}
+
+// FetchRecentPrices implements services.RecentPriceFetcher
+func (s *DataSource) FetchRecentPrices(stock models.Stock, sessions int) ([]models.CandleData, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	candles, ok := s.Candles[stock.Code]
+	if !ok {
+		return nil, fmt.Errorf("no prices for %s", stock.Code)
+	}
+	if sessions > 0 && len(candles) > sessions {
+		candles = candles[:sessions]
+	}
+	return candles, nil
+}
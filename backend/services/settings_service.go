@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"gorm.io/gorm/clause"
+)
+
+// settingsLog logs runtime config reloads
+var settingsLog = logging.Component("settings")
+
+// ErrSettingNotFound is returned when deleting a setting that is not stored
+var ErrSettingNotFound = errors.New("setting not found")
+
+// SettingsService manages stored runtime settings and reloads the runtime configuration
+type SettingsService struct{}
+
+// NewSettingsService creates a new SettingsService instance
+func NewSettingsService() *SettingsService {
+	return &SettingsService{}
+}
+
+// ListStored returns the settings stored in app_settings, keyed by setting key
+func (s *SettingsService) ListStored(ctx context.Context) (map[string]models.AppSetting, error) {
+	var settings []models.AppSetting
+	if err := config.GetDBWithContext(ctx).Order("key ASC").Find(&settings).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch settings: %w", err)
+	}
+
+	byKey := make(map[string]models.AppSetting, len(settings))
+	for _, setting := range settings {
+		byKey[setting.Key] = setting
+	}
+	return byKey, nil
+}
+
+// storedValues returns the stored settings as a key -> value map
+func (s *SettingsService) storedValues(ctx context.Context) (map[string]string, error) {
+	stored, err := s.ListStored(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(stored))
+	for key, setting := range stored {
+		values[key] = setting.Value
+	}
+	return values, nil
+}
+
+// Set validates and stores a setting. The change takes effect on this
+// instance immediately and on other instances at their next reload.
+func (s *SettingsService) Set(ctx context.Context, key, value, updatedBy string) (*config.RuntimeConfig, error) {
+	return s.SetMany(ctx, map[string]string{key: value}, updatedBy)
+}
+
+// SetMany validates and stores several settings at once: either all of
+// them are stored or, when any is invalid, none is
+func (s *SettingsService) SetMany(ctx context.Context, changes map[string]string, updatedBy string) (*config.RuntimeConfig, error) {
+	values, err := s.storedValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings := make([]models.AppSetting, 0, len(changes))
+	now := time.Now().UTC()
+	for key, value := range changes {
+		key = strings.TrimSpace(key)
+		if !config.IsRuntimeSetting(key) {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+		values[key] = value
+		setting := models.AppSetting{Key: key, Value: value, UpdatedAt: now}
+		if updatedBy != "" {
+			setting.UpdatedBy = &updatedBy
+		}
+		settings = append(settings, setting)
+	}
+	if len(settings) == 0 {
+		return config.Runtime(), nil
+	}
+	if _, err := config.LoadRuntimeConfig(values); err != nil {
+		return nil, err
+	}
+
+	err = config.GetDBWithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(&settings).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save settings: %w", err)
+	}
+
+	return s.Reload(ctx)
+}
+
+// Delete removes a stored setting so the environment or default value applies again
+func (s *SettingsService) Delete(ctx context.Context, key string) (*config.RuntimeConfig, error) {
+	result := config.GetDBWithContext(ctx).Delete(&models.AppSetting{}, "key = ?", key)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to delete setting: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrSettingNotFound
+	}
+	return s.Reload(ctx)
+}
+
+// Reload rebuilds the runtime configuration from the environment and the
+// settings store and swaps it in atomically. On error the current
+// configuration is kept.
+func (s *SettingsService) Reload(ctx context.Context) (*config.RuntimeConfig, error) {
+	values, err := s.storedValues(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.LoadRuntimeConfig(values)
+	if err != nil {
+		return nil, err
+	}
+
+	config.SetRuntime(cfg)
+	return cfg, nil
+}
+
+// StartAutoReload reloads the runtime configuration every interval until ctx
+// is cancelled, so settings changed on one instance reach all instances
+func (s *SettingsService) StartAutoReload(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				reloadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+				if _, err := s.Reload(reloadCtx); err != nil {
+					settingsLog.Warn("Runtime config auto-reload failed, keeping the current config", logging.FieldError, err)
+				}
+				cancel()
+			}
+		}
+	}()
+}
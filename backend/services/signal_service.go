@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// signalLog logs signal detection after crawl runs
+var signalLog = logging.Component("signals")
+
+const (
+	// signalHistoryDays is the calendar window read to detect signals: a year
+	// for the 52-week high plus room for holidays
+	signalHistoryDays = 400
+	// signalWriteBatch caps the writes of one bulk write
+	signalWriteBatch = 500
+	// defaultPatternSessions and maxPatternSessions bound the sessions scanned
+	// for candlestick patterns
+	defaultPatternSessions = 20
+	maxPatternSessions     = 250
+	// patternLookback is how many sessions before the scanned ones a pattern
+	// may need
+	patternLookback = 5
+)
+
+// PatternQuery selects the candlestick patterns of a symbol
+type PatternQuery struct {
+	Code     string
+	Sessions int    // Newest sessions scanned
+	Pattern  string // Only this pattern when set
+}
+
+// SignalFilter selects stored signals (empty fields match everything)
+type SignalFilter struct {
+	Date string // YYYY-MM-DD
+	Type string
+	Code string
+}
+
+// SignalService computes trading signals from the stored candles after each
+// crawl and keeps them in the signals collection
+type SignalService struct {
+	signalCollection *mongo.Collection
+	stockService     *StockService
+	readCache        *cache.Cache // Shared with the stock service
+}
+
+// NewSignalService creates a new SignalService instance
+func NewSignalService(stockService *StockService) *SignalService {
+	return &SignalService{
+		signalCollection: config.GetCollection("signals"),
+		stockService:     stockService,
+		readCache:        stockService.readCache,
+	}
+}
+
+// GenerateRun detects the signals fired by the new candles of every symbol
+// in a finished crawl run. A symbol's earlier signals for the same date are
+// replaced, so a repeated crawl does not leave stale ones. It is registered
+// as a crawl run listener.
+func (s *SignalService) GenerateRun(run *models.CrawlRun, newDates map[string]string) {
+	cfg := config.Runtime().Signals()
+	if len(newDates) == 0 || len(cfg.Types) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
+	defer cancel()
+
+	historyDays := signalHistoryDays
+	if days := cfg.SlowMA*3/2 + 30; days > historyDays {
+		historyDays = days
+	}
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	writes := make([]mongo.WriteModel, 0, signalWriteBatch)
+	fired := 0
+	flush := func() {
+		if len(writes) == 0 {
+			return
+		}
+		if _, err := s.signalCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			signalLog.Warn("Failed to save signals", logging.FieldError, err)
+		}
+		writes = writes[:0]
+	}
+
+	for code, date := range newDates {
+		to, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+		candles, err := s.stockService.GetCandles(ctx, []string{code}, to.AddDate(0, 0, -historyDays), to)
+		if err != nil {
+			signalLog.Warn("Failed to load candles for signals", logging.FieldSymbol, code, logging.FieldError, err)
+			continue
+		}
+		if len(candles) == 0 || candles[len(candles)-1].D != date {
+			continue
+		}
+
+		signals := models.DetectSignals(code, candles, cfg, now)
+		kept := make([]string, 0, len(signals))
+		for _, signal := range signals {
+			kept = append(kept, signal.Type)
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": signal.ID}).
+				SetReplacement(signal).
+				SetUpsert(true))
+		}
+		writes = append(writes, mongo.NewDeleteManyModel().
+			SetFilter(bson.M{"code": code, "date": date, "type": bson.M{"$nin": kept}}))
+		fired += len(signals)
+		if len(writes) >= signalWriteBatch {
+			flush()
+		}
+	}
+	flush()
+	s.readCache.Invalidate(ctx, cache.NamespaceIndicators)
+	signalLog.Info("Signals detected", "signals", fired, "symbols", len(newDates), "run_id", run.ID.Hex())
+}
+
+// List returns stored signals matching filter, by type then code. Results
+// are cached in the indicators namespace.
+func (s *SignalService) List(ctx context.Context, filter SignalFilter) ([]models.Signal, error) {
+	key := fmt.Sprintf("signals:%s:%s:%s", filter.Date, filter.Type, strings.ToUpper(filter.Code))
+	return cache.Fetch(ctx, s.readCache, cache.NamespaceIndicators, key, func(ctx context.Context) ([]models.Signal, error) {
+		return s.list(ctx, filter)
+	})
+}
+
+// list runs the List query
+func (s *SignalService) list(ctx context.Context, filter SignalFilter) ([]models.Signal, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	query := bson.M{}
+	if filter.Date != "" {
+		query["date"] = filter.Date
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.Code != "" {
+		query["code"] = strings.ToUpper(filter.Code)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "type", Value: 1}, {Key: "code", Value: 1}})
+	cursor, err := s.signalCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query signals: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	signals := make([]models.Signal, 0)
+	if err := cursor.All(ctx, &signals); err != nil {
+		return nil, fmt.Errorf("failed to decode signals: %w", err)
+	}
+	return signals, nil
+}
+
+// Patterns scans the newest sessions of a symbol for candlestick patterns,
+// newest first. Results are cached with the candles.
+func (s *SignalService) Patterns(ctx context.Context, query PatternQuery) ([]models.CandlePattern, error) {
+	key := fmt.Sprintf("patterns:%s:%d", query.Code, query.Sessions)
+	patterns, err := cache.Fetch(ctx, s.readCache, cache.NamespacePrices, key, func(ctx context.Context) ([]models.CandlePattern, error) {
+		candles, err := s.stockService.LatestCandles(ctx, []string{query.Code}, query.Sessions+patternLookback)
+		if err != nil {
+			return nil, err
+		}
+		return models.ScanCandlePatterns(query.Code, candles[query.Code], query.Sessions), nil
+	})
+	if err != nil || query.Pattern == "" {
+		return patterns, err
+	}
+	matching := make([]models.CandlePattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern.Pattern == query.Pattern {
+			matching = append(matching, pattern)
+		}
+	}
+	return matching, nil
+}
+
+// ParsePatternQuery reads a pattern query from query parameters: code
+// (required), sessions (default 20, max 250) and pattern
+func ParsePatternQuery(query func(string) string) (PatternQuery, error) {
+	q := PatternQuery{
+		Code:     strings.ToUpper(strings.TrimSpace(query("code"))),
+		Sessions: defaultPatternSessions,
+		Pattern:  strings.ToLower(strings.TrimSpace(query("pattern"))),
+	}
+	if q.Code == "" {
+		return q, fmt.Errorf("code is required")
+	}
+	if raw := query("sessions"); raw != "" {
+		sessions, err := strconv.Atoi(raw)
+		if err != nil || sessions <= 0 || sessions > maxPatternSessions {
+			return q, fmt.Errorf("sessions: expected 1 to %d", maxPatternSessions)
+		}
+		q.Sessions = sessions
+	}
+	if q.Pattern != "" && !models.ValidCandlePattern(q.Pattern) {
+		return q, fmt.Errorf("pattern: unknown pattern %q", q.Pattern)
+	}
+	return q, nil
+}
+
+// ParseSignalDate resolves a ?date= value: "today" (or empty) is the current
+// date in Vietnam, otherwise a YYYY-MM-DD date
+func ParseSignalDate(raw string, now time.Time) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.EqualFold(raw, "today") {
+		return markettime.Date(now), nil
+	}
+	if _, err := time.Parse("2006-01-02", raw); err != nil {
+		return "", fmt.Errorf("expected today or a date in YYYY-MM-DD format")
+	}
+	return raw, nil
+}
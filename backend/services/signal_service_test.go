@@ -0,0 +1,51 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseSignalDate(t *testing.T) {
+	// 18:30 UTC is already the next day in Vietnam
+	now := time.Date(2026, 2, 9, 18, 30, 0, 0, time.UTC)
+
+	tests := map[string]string{
+		"":           "2026-02-10",
+		"today":      "2026-02-10",
+		"TODAY":      "2026-02-10",
+		"2026-01-30": "2026-01-30",
+	}
+	for raw, want := range tests {
+		got, err := ParseSignalDate(raw, now)
+		if err != nil || got != want {
+			t.Errorf("ParseSignalDate(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{"yesterday", "2026-13-01", "10/02/2026"} {
+		if _, err := ParseSignalDate(raw, now); err == nil {
+			t.Errorf("ParseSignalDate(%q): expected an error", raw)
+		}
+	}
+}
+
+func TestParsePatternQuery(t *testing.T) {
+	query, err := ParsePatternQuery(url.Values{"code": {" hpg"}, "sessions": {"60"}, "pattern": {"Hammer"}}.Get)
+	if err != nil || query != (PatternQuery{Code: "HPG", Sessions: 60, Pattern: "hammer"}) {
+		t.Errorf("ParsePatternQuery() = %+v, %v; want HPG, 60 sessions, hammer", query, err)
+	}
+	if query, err := ParsePatternQuery(url.Values{"code": {"HPG"}}.Get); err != nil || query.Sessions != defaultPatternSessions {
+		t.Errorf("ParsePatternQuery(code) = %+v, %v; want the default sessions", query, err)
+	}
+	for _, bad := range []url.Values{
+		{},
+		{"code": {"HPG"}, "sessions": {"0"}},
+		{"code": {"HPG"}, "sessions": {"251"}},
+		{"code": {"HPG"}, "pattern": {"cup_and_handle"}},
+	} {
+		if _, err := ParsePatternQuery(bad.Get); err == nil {
+			t.Errorf("ParsePatternQuery(%v) succeeded; want error", bad)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sparklineLog logs sparkline updates after crawl runs
+var sparklineLog = logging.Component("sparklines")
+
+const (
+	// MaxSparklineCodes caps the symbols of one sparklines request
+	MaxSparklineCodes = 100
+	// sparklineHistoryDays is the calendar window read to collect
+	// SparklineLength trading days, with room for holidays
+	sparklineHistoryDays = 60
+	// sparklineWriteBatch caps the upserts of one bulk write
+	sparklineWriteBatch = 500
+)
+
+// SparklineService maintains the sparklines collection: the last closes of
+// every symbol, small enough to send a whole watchlist in one response
+type SparklineService struct {
+	sparklineCollection *mongo.Collection
+	stockService        PriceStore
+}
+
+// NewSparklineService creates a new SparklineService instance
+func NewSparklineService(stockService PriceStore) *SparklineService {
+	return &SparklineService{
+		sparklineCollection: config.GetCollection("sparklines"),
+		stockService:        stockService,
+	}
+}
+
+// GetSparklines returns the sparklines of the given codes in request order.
+// Codes without a sparkline are left out.
+func (s *SparklineService) GetSparklines(ctx context.Context, codes []string) ([]models.Sparkline, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.sparklineCollection.Find(ctx, bson.M{"_id": bson.M{"$in": codes}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sparklines: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var found []models.Sparkline
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, fmt.Errorf("failed to decode sparklines: %w", err)
+	}
+	byCode := make(map[string]models.Sparkline, len(found))
+	for _, sparkline := range found {
+		byCode[sparkline.Code] = sparkline
+	}
+
+	sparklines := make([]models.Sparkline, 0, len(found))
+	for _, code := range codes {
+		if sparkline, ok := byCode[code]; ok {
+			sparklines = append(sparklines, sparkline)
+		}
+	}
+	return sparklines, nil
+}
+
+// UpdateRun rebuilds the sparklines of every symbol that gained candles in a
+// finished crawl run. It is registered as a crawl run listener.
+func (s *SparklineService) UpdateRun(run *models.CrawlRun, newDates map[string]string) {
+	if len(newDates) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	now := primitive.NewDateTimeFromTime(time.Now())
+	writes := make([]mongo.WriteModel, 0, sparklineWriteBatch)
+	updated := 0
+	flush := func() {
+		if len(writes) == 0 {
+			return
+		}
+		if _, err := s.sparklineCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			sparklineLog.Warn("Failed to save sparklines", logging.FieldError, err)
+		} else {
+			updated += len(writes)
+		}
+		writes = writes[:0]
+	}
+
+	for code, date := range newDates {
+		to, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			continue
+		}
+		candles, err := s.stockService.GetCandles(ctx, []string{code}, to.AddDate(0, 0, -sparklineHistoryDays), to)
+		if err != nil {
+			sparklineLog.Warn("Failed to load candles for sparkline", logging.FieldSymbol, code, logging.FieldError, err)
+			continue
+		}
+		sparkline := models.NewSparkline(code, candles, now)
+		if sparkline == nil {
+			continue
+		}
+		writes = append(writes, mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": code}).
+			SetReplacement(sparkline).
+			SetUpsert(true))
+		if len(writes) == sparklineWriteBatch {
+			flush()
+		}
+	}
+	flush()
+	sparklineLog.Info("Sparklines updated", "sparklines", updated, "run_id", run.ID.Hex())
+}
+
+// ParseSparklineCodes splits a comma-separated ?codes= value into upper-case,
+// de-duplicated codes
+func ParseSparklineCodes(raw string) ([]string, error) {
+	seen := make(map[string]bool)
+	codes := make([]string, 0)
+	for _, code := range strings.Split(raw, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, code)
+	}
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("codes is required (comma-separated stock codes)")
+	}
+	if len(codes) > MaxSparklineCodes {
+		return nil, fmt.Errorf("at most %d codes per request", MaxSparklineCodes)
+	}
+	return codes, nil
+}
@@ -0,0 +1,29 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSparklineCodes(t *testing.T) {
+	codes, err := ParseSparklineCodes(" hpg,FPT,,hpg , vnm")
+	if err != nil {
+		t.Fatalf("ParseSparklineCodes() error = %v", err)
+	}
+	if want := []string{"HPG", "FPT", "VNM"}; !reflect.DeepEqual(codes, want) {
+		t.Errorf("ParseSparklineCodes() = %v; want %v", codes, want)
+	}
+
+	if _, err := ParseSparklineCodes(" , "); err == nil {
+		t.Error("ParseSparklineCodes(empty) succeeded; want an error")
+	}
+	many := make([]string, MaxSparklineCodes+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("S%03d", i)
+	}
+	if _, err := ParseSparklineCodes(strings.Join(many, ",")); err == nil {
+		t.Errorf("ParseSparklineCodes(%d codes) succeeded; want an error", len(many))
+	}
+}
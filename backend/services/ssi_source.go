@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/ssi"
+)
+
+const (
+	// ssiHistoryDays is how far back a full fetch of daily candles reaches
+	ssiHistoryDays = 400
+	// ssiTokenTTL is how long an access token is reused
+	ssiTokenTTL = time.Hour
+	// ssiPriceUnit converts SSI prices (VND) to the candles' unit (thousand VND)
+	ssiPriceUnit = 1000
+)
+
+// SSISource fetches Vietnamese listings and daily candles from SSI
+// FastConnect Data and streams their trades for the realtime quote feed. It
+// authenticates with the consumer_id and consumer_secret provider
+// credentials (SSI_CONSUMER_ID and SSI_CONSUMER_SECRET).
+type SSISource struct {
+	client *ssi.Client
+
+	mu        sync.Mutex
+	lookup    CredentialLookup
+	token     string
+	tokenFor  string // Consumer ID the token was issued for
+	expiresAt time.Time
+}
+
+// NewSSISource creates a new SSI data source
+func NewSSISource() *SSISource {
+	return NewSSISourceWithConfig(ssi.DefaultConfig())
+}
+
+// NewSSISourceWithConfig creates an SSI data source with a client configured
+// by cfg. Every REST attempt, retries included, counts against the provider
+// quota.
+func NewSSISourceWithConfig(cfg ssi.Config) *SSISource {
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	cfg.Transport = quotaCountingTransport{provider: models.DataSourceSSI, next: cfg.Transport}
+	return &SSISource{client: ssi.New(cfg)}
+}
+
+// Name implements MarketDataSource
+func (s *SSISource) Name() string {
+	return models.DataSourceSSI
+}
+
+// UseCredentials implements CredentialedSource
+func (s *SSISource) UseCredentials(lookup CredentialLookup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lookup = lookup
+	s.token = ""
+}
+
+// FetchSymbols fetches the listed stocks of the given exchanges
+func (s *SSISource) FetchSymbols(exchanges []string) ([]models.Stock, error) {
+	ctx := context.Background()
+	var stocks []models.Stock
+	for _, exchange := range exchanges {
+		var securities []ssi.Security
+		err := s.withToken(ctx, func(token string) (err error) {
+			securities, err = s.client.Securities(ctx, token, exchange)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, security := range securities {
+			stocks = append(stocks, models.Stock{
+				Code:          strings.ToUpper(strings.TrimSpace(security.Symbol)),
+				CompanyName:   strings.TrimSpace(security.StockName),
+				CompanyNameEn: strings.TrimSpace(security.StockEnName),
+				Exchange:      strings.ToUpper(security.Market),
+				Type:          models.InstrumentStock,
+				Status:        "listed",
+			})
+		}
+	}
+	return stocks, nil
+}
+
+// FetchPrices fetches the daily candles of the last ~400 days of a stock
+func (s *SSISource) FetchPrices(stock models.Stock) ([]models.CandleData, error) {
+	return s.fetchPrices(stock, ssiHistoryDays)
+}
+
+// FetchRecentPrices implements RecentPriceFetcher. SSI selects candles by
+// date, so the range covers sessions with room for weekends and holidays.
+func (s *SSISource) FetchRecentPrices(stock models.Stock, sessions int) ([]models.CandleData, error) {
+	candles, err := s.fetchPrices(stock, sessions*2+7)
+	if len(candles) > sessions {
+		candles = candles[len(candles)-sessions:]
+	}
+	return candles, err
+}
+
+// fetchPrices fetches the daily candles of the last days of a stock
+func (s *SSISource) fetchPrices(stock models.Stock, days int) ([]models.CandleData, error) {
+	ctx := context.Background()
+	to := time.Now()
+	var rows []ssi.OHLC
+	err := s.withToken(ctx, func(token string) (err error) {
+		rows, err = s.client.DailyOHLC(ctx, token, stock.Code, to.AddDate(0, 0, -days), to)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	candles := make([]models.CandleData, 0, len(rows))
+	for _, row := range rows {
+		date, err := ssiDate(row.TradingDate)
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, models.CandleData{
+			D: date,
+			O: ssiPrice(row.Open),
+			H: ssiPrice(row.High),
+			L: ssiPrice(row.Low),
+			C: ssiPrice(row.Close),
+			V: int64(row.Volume),
+		})
+	}
+	return candles, nil
+}
+
+// StreamQuotes implements QuoteStreamer with the trade channel of codes
+func (s *SSISource) StreamQuotes(ctx context.Context, codes []string, emit func(code string, candle models.CandleData)) error {
+	token, err := s.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	err = s.client.StreamTrades(ctx, token, ssi.TradeChannel(codes), func(trade ssi.Trade) {
+		code, candle, err := ssiTradeCandle(trade)
+		if err != nil {
+			crawlLog.Warn("Skipping SSI trade", logging.FieldSymbol, trade.Symbol, logging.FieldError, err)
+			return
+		}
+		emit(code, candle)
+	})
+	if errors.Is(err, ssi.ErrInvalidCredentials) {
+		s.forgetToken()
+	}
+	return ssiError(err)
+}
+
+// ssiTradeCandle returns the session candle of the symbol of a trade
+func ssiTradeCandle(trade ssi.Trade) (string, models.CandleData, error) {
+	code := strings.ToUpper(strings.TrimSpace(trade.Symbol))
+	date, err := ssiDate(trade.TradingDate)
+	if err != nil {
+		return "", models.CandleData{}, err
+	}
+	if code == "" || trade.LastPrice <= 0 {
+		return "", models.CandleData{}, fmt.Errorf("SSI trade of %q has no price", trade.Symbol)
+	}
+	candle := models.CandleData{
+		D: date,
+		O: ssiPrice(trade.Open),
+		H: ssiPrice(trade.High),
+		L: ssiPrice(trade.Low),
+		C: ssiPrice(trade.LastPrice),
+		V: int64(trade.TotalVol),
+	}
+	// The first trade of a session may come before its open and range
+	if candle.O == 0 {
+		candle.O = candle.C
+	}
+	candle.H = math.Max(candle.H, candle.C)
+	if candle.L == 0 || candle.L > candle.C {
+		candle.L = candle.C
+	}
+	return code, candle, nil
+}
+
+// HealthCheck implements ProviderHealthChecker by exchanging the current
+// credentials for a new access token
+func (s *SSISource) HealthCheck(ctx context.Context) error {
+	s.forgetToken()
+	_, err := s.accessToken(ctx)
+	return err
+}
+
+// withToken calls fn with an access token. A cached token that SSI rejects
+// is renewed once.
+func (s *SSISource) withToken(ctx context.Context, fn func(token string) error) error {
+	for attempt := 0; ; attempt++ {
+		token, err := s.accessToken(ctx)
+		if err != nil {
+			return err
+		}
+		err = fn(token)
+		if !errors.Is(err, ssi.ErrInvalidCredentials) || attempt == 1 {
+			return ssiError(err)
+		}
+		s.forgetToken()
+	}
+}
+
+// accessToken returns the cached access token, or exchanges the current
+// credentials for a new one
+func (s *SSISource) accessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	lookup := s.lookup
+	s.mu.Unlock()
+	consumerID, err := s.credential(ctx, lookup, "consumer_id")
+	if err != nil {
+		return "", err
+	}
+	consumerSecret, err := s.credential(ctx, lookup, "consumer_secret")
+	if err != nil {
+		return "", err
+	}
+	if consumerID == "" || consumerSecret == "" {
+		return "", fmt.Errorf("SSI credentials are not set (consumer_id and consumer_secret)")
+	}
+
+	s.mu.Lock()
+	if s.token != "" && s.tokenFor == consumerID && time.Now().Before(s.expiresAt) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+	s.mu.Unlock()
+
+	token, err := s.client.AccessToken(ctx, consumerID, consumerSecret)
+	if err != nil {
+		return "", ssiError(err)
+	}
+	s.mu.Lock()
+	s.token, s.tokenFor, s.expiresAt = token, consumerID, time.Now().Add(ssiTokenTTL)
+	s.mu.Unlock()
+	return token, nil
+}
+
+// credential returns a provider credential, from the environment when no
+// lookup is attached
+func (s *SSISource) credential(ctx context.Context, lookup CredentialLookup, name string) (string, error) {
+	if lookup == nil {
+		return os.Getenv("SSI_" + strings.ToUpper(name)), nil
+	}
+	return lookup(ctx, name)
+}
+
+// forgetToken makes the next call exchange the credentials again
+func (s *SSISource) forgetToken() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.token = ""
+}
+
+// ssiError reports an SSI failure as ErrProviderThrottled when it was rate
+// limited
+func ssiError(err error) error {
+	var status *ssi.StatusError
+	if errors.As(err, &status) && status.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %v", ErrProviderThrottled, err)
+	}
+	return err
+}
+
+// ssiDate converts an SSI date (DD/MM/YYYY) to the candles' YYYY-MM-DD
+func ssiDate(date string) (string, error) {
+	parsed, err := time.Parse(ssi.DateLayout, strings.TrimSpace(date))
+	if err != nil {
+		return "", fmt.Errorf("invalid SSI trading date %q", date)
+	}
+	return parsed.Format("2006-01-02"), nil
+}
+
+// ssiPrice converts an SSI price to thousand VND
+func ssiPrice(price ssi.Number) float64 {
+	return math.Round(float64(price)/ssiPriceUnit*1000) / 1000
+}
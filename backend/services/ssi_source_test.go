@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/ssi"
+)
+
+func TestSSISourcePrices(t *testing.T) {
+	var tokens atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/Market/AccessToken":
+			tokens.Add(1)
+			w.Write([]byte(`{"status": 200, "data": {"accessToken": "jwt"}}`))
+		case "/api/v2/Market/DailyOhlc":
+			rows := ""
+			for day := 1; day <= 3; day++ {
+				if rows != "" {
+					rows += ","
+				}
+				rows += fmt.Sprintf(`{"Symbol": "HPG", "TradingDate": "%02d/10/2026", "Open": "25600", "High": "26050", "Low": "25500", "Close": "25950", "Volume": "1200300"}`, day)
+			}
+			fmt.Fprintf(w, `{"status": "Success", "totalRecord": 3, "data": [%s]}`, rows)
+		}
+	}))
+	defer server.Close()
+	source := NewSSISourceWithConfig(ssi.Config{BaseURL: server.URL})
+	source.UseCredentials(func(ctx context.Context, name string) (string, error) { return name + "-value", nil })
+
+	candles, err := source.FetchRecentPrices(models.Stock{Code: "HPG"}, 2)
+	if err != nil {
+		t.Fatalf("FetchRecentPrices() unexpected error: %v", err)
+	}
+	want := models.CandleData{D: "2026-10-03", O: 25.6, H: 26.05, L: 25.5, C: 25.95, V: 1200300}
+	if len(candles) != 2 || candles[1] != want {
+		t.Errorf("FetchRecentPrices() = %v; want the last 2 sessions ending with %v", candles, want)
+	}
+	if _, err := source.FetchPrices(models.Stock{Code: "HPG"}); err != nil || tokens.Load() != 1 {
+		t.Errorf("FetchPrices() = %v after %d token exchanges; want the cached token reused", err, tokens.Load())
+	}
+}
+
+func TestSSITradeCandle(t *testing.T) {
+	// The first trade of the session, before SSI reports its open and range
+	code, candle, err := ssiTradeCandle(ssi.Trade{Symbol: "hpg", TradingDate: "15/10/2026", LastPrice: 25900, TotalVol: 1000})
+	want := models.CandleData{D: "2026-10-15", O: 25.9, H: 25.9, L: 25.9, C: 25.9, V: 1000}
+	if code != "HPG" || candle != want || err != nil {
+		t.Errorf("ssiTradeCandle() = %s, %v, %v; want HPG, %v", code, candle, err, want)
+	}
+	if _, _, err := ssiTradeCandle(ssi.Trade{Symbol: "HPG", TradingDate: "15/10/2026"}); err == nil {
+		t.Error("ssiTradeCandle() of a trade without a price should fail")
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Overall states of the public status page
+const (
+	StatusOperational = "operational"
+	StatusDegraded    = "degraded" // An incident is open, an exchange's data is stale or a data store is down
+	StatusUnknown     = "unknown"  // Status could not be determined
+)
+
+// StatusCacheTTL is how long a computed status is served before it is
+// recomputed; the page is public, so the queries behind it must not run per request
+const StatusCacheTTL = 30 * time.Second
+
+// maxStatusTradingDayLookback bounds the search for the previous trading day
+const maxStatusTradingDayLookback = 14
+
+// ExchangeFreshness reports how current the stored candles of one exchange are
+type ExchangeFreshness struct {
+	Exchange         string `json:"exchange"`
+	LatestCandleDate string `json:"latest_candle_date,omitempty"` // Newest stored candle of any symbol on the exchange
+	ExpectedDate     string `json:"expected_date"`                // Previous trading day in exchange time
+	Fresh            bool   `json:"fresh"`
+}
+
+// StatusIncident is the public summary of firing alert rules affecting one
+// component. Rule names and alert messages are internal and never exposed.
+type StatusIncident struct {
+	Component string     `json:"component"`
+	Message   string     `json:"message"`
+	Since     *time.Time `json:"since,omitempty"` // When the incident was last notified
+}
+
+// Public components an incident can affect
+const (
+	StatusComponentMarketData = "market_data"
+	StatusComponentAPI        = "api"
+)
+
+// publicIncidents maps alert rule types to what the status page says about
+// them; rule types not listed are reported as a generic API incident
+var publicIncidents = map[string]StatusIncident{
+	models.AlertRuleNoSuccessfulCrawl:   {Component: StatusComponentMarketData, Message: "Market data updates are delayed"},
+	models.AlertRuleFailedSymbolsRatio:  {Component: StatusComponentMarketData, Message: "Some symbols are not being updated"},
+	models.AlertRuleStaleCandles:        {Component: StatusComponentMarketData, Message: "Price history may be out of date"},
+	models.AlertRuleSampleMismatchRatio: {Component: StatusComponentMarketData, Message: "Some prices are being corrected"},
+}
+
+// PublicStatus is the payload of the public status page
+type PublicStatus struct {
+	Status                string              `json:"status"`
+	StartedAt             time.Time           `json:"started_at"`
+	UptimeSeconds         int64               `json:"uptime_seconds"`
+	LastSuccessfulCrawlAt *time.Time          `json:"last_successful_crawl_at,omitempty"`
+	Exchanges             []ExchangeFreshness `json:"exchanges"`
+	Incidents             []StatusIncident    `json:"incidents"`
+	UnavailableStores     []string            `json:"unavailable_stores,omitempty"` // Data stores that cannot be reached right now
+	GeneratedAt           time.Time           `json:"generated_at"`
+}
+
+// StatusService computes the public service status and caches it
+type StatusService struct {
+	alertService    *AlertService
+	priceCollection *mongo.Collection
+	startedAt       time.Time
+
+	mu       sync.Mutex // Held while computing, so concurrent misses share one computation
+	cached   *PublicStatus
+	cachedAt time.Time
+}
+
+// NewStatusService creates a StatusService; uptime is counted from now
+func NewStatusService(alertService *AlertService) *StatusService {
+	return &StatusService{
+		alertService:    alertService,
+		priceCollection: config.GetCollection("stock_prices"),
+		startedAt:       time.Now().UTC(),
+	}
+}
+
+// Status returns the cached status, recomputing it once it is older than
+// StatusCacheTTL. When recomputing fails the previous status is served.
+func (s *StatusService) Status(ctx context.Context) (*PublicStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	if s.cached != nil && now.Sub(s.cachedAt) < StatusCacheTTL {
+		return s.current(*s.cached, now), nil
+	}
+
+	// While a data store is down the queries would only wait for a timeout
+	if len(config.UnavailableStores(config.Stores...)) > 0 {
+		if s.cached != nil {
+			return s.current(*s.cached, now), nil
+		}
+		return s.current(PublicStatus{
+			StartedAt:   s.startedAt,
+			Exchanges:   []ExchangeFreshness{},
+			Incidents:   []StatusIncident{},
+			GeneratedAt: now,
+		}, now), nil
+	}
+
+	status, err := s.compute(ctx, now)
+	if err != nil {
+		if s.cached != nil {
+			logging.FromContext(ctx).Warn("Serving cached status", logging.FieldError, err)
+			return s.current(*s.cached, now), nil
+		}
+		return nil, err
+	}
+	s.cached, s.cachedAt = status, now
+	return s.current(*status, now), nil
+}
+
+// current returns a copy of status with the uptime and data store
+// availability as of now
+func (s *StatusService) current(status PublicStatus, now time.Time) *PublicStatus {
+	status.UptimeSeconds = int64(now.Sub(s.startedAt).Seconds())
+	if down := config.UnavailableStores(config.Stores...); len(down) > 0 {
+		status.Status = StatusDegraded
+		status.UnavailableStores = down
+	}
+	return &status
+}
+
+// compute gathers crawl, freshness and incident data
+func (s *StatusService) compute(ctx context.Context, now time.Time) (*PublicStatus, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	status := &PublicStatus{
+		StartedAt:   s.startedAt,
+		Exchanges:   []ExchangeFreshness{},
+		Incidents:   []StatusIncident{},
+		GeneratedAt: now,
+	}
+
+	metrics, err := s.alertService.CollectMetrics(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.LastSuccessfulCrawlAt = metrics.LastSuccessfulCrawlAt
+
+	latest, err := s.latestCandleByExchange(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range config.Runtime().CrawlerExchanges {
+		exchange, ok := models.LookupExchange(code)
+		if !ok {
+			continue
+		}
+		expected := expectedCandleDate(exchange, now)
+		status.Exchanges = append(status.Exchanges, ExchangeFreshness{
+			Exchange:         exchange.Code,
+			LatestCandleDate: latest[exchange.Code],
+			ExpectedDate:     expected,
+			Fresh:            latest[exchange.Code] != "" && latest[exchange.Code] >= expected,
+		})
+	}
+
+	firing, err := s.alertService.FiringRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Incidents = statusIncidents(firing)
+
+	status.Status = overallStatus(status.Exchanges, status.Incidents)
+	return status, nil
+}
+
+// latestCandleByExchange returns the newest candle date stored for each
+// exchange, joining price buckets of the latest year to their stocks
+func (s *StatusService) latestCandleByExchange(ctx context.Context) (map[string]string, error) {
+	year := time.Now().Year()
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"year": bson.M{"$gte": year - 1}}},
+		bson.M{"$project": bson.M{"code": 1, "last": bson.M{"$ifNull": bson.A{"$lastDate", bson.M{"$max": "$history.d"}}}}},
+		bson.M{"$group": bson.M{"_id": "$code", "last": bson.M{"$max": "$last"}}},
+		bson.M{"$lookup": bson.M{"from": "stocks", "localField": "_id", "foreignField": "code", "as": "stock"}},
+		bson.M{"$unwind": "$stock"},
+		bson.M{"$group": bson.M{"_id": "$stock.exchange", "last": bson.M{"$max": "$last"}}},
+	}
+	cursor, err := s.priceCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate freshness per exchange: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Exchange string `bson:"_id"`
+		Last     string `bson:"last"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode freshness per exchange: %w", err)
+	}
+
+	latest := make(map[string]string, len(rows))
+	for _, row := range rows {
+		latest[row.Exchange] = row.Last
+	}
+	return latest, nil
+}
+
+// expectedCandleDate returns the most recent trading day before now's date
+// in exchange time: the daily crawl runs after the close, so until then the
+// previous session is the newest candle that can be stored
+func expectedCandleDate(exchange models.Exchange, now time.Time) string {
+	day := now.In(exchange.Location())
+	for i := 0; i < maxStatusTradingDayLookback; i++ {
+		day = day.AddDate(0, 0, -1)
+		if exchange.IsTradingDay(day) {
+			break
+		}
+	}
+	return day.Format("2006-01-02")
+}
+
+// statusIncidents maps firing rules to public incidents, one per distinct
+// message, each dated by the earliest notification among its rules
+func statusIncidents(firing []models.AlertRule) []StatusIncident {
+	incidents := []StatusIncident{}
+	index := make(map[StatusIncident]int)
+	for _, rule := range firing {
+		incident, ok := publicIncidents[rule.Type]
+		if !ok {
+			incident = StatusIncident{Component: StatusComponentAPI, Message: "We are investigating an issue"}
+		}
+		if i, seen := index[incident]; seen {
+			if since := incidents[i].Since; since == nil || (rule.LastNotifiedAt != nil && rule.LastNotifiedAt.Before(*since)) {
+				incidents[i].Since = rule.LastNotifiedAt
+			}
+			continue
+		}
+		index[incident] = len(incidents)
+		incident.Since = rule.LastNotifiedAt
+		incidents = append(incidents, incident)
+	}
+	return incidents
+}
+
+// overallStatus summarizes exchange freshness and open incidents
+func overallStatus(exchanges []ExchangeFreshness, incidents []StatusIncident) string {
+	if len(incidents) > 0 {
+		return StatusDegraded
+	}
+	for _, exchange := range exchanges {
+		if !exchange.Fresh {
+			return StatusDegraded
+		}
+	}
+	return StatusOperational
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestExpectedCandleDate(t *testing.T) {
+	hose, ok := models.LookupExchange("HOSE")
+	if !ok {
+		t.Fatal("HOSE is not registered")
+	}
+	vietnam := hose.Location()
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"tuesday", time.Date(2026, 2, 3, 10, 0, 0, 0, vietnam), "2026-02-02"},
+		{"monday skips the weekend", time.Date(2026, 2, 2, 10, 0, 0, 0, vietnam), "2026-01-30"},
+		{"sunday", time.Date(2026, 2, 1, 10, 0, 0, 0, vietnam), "2026-01-30"},
+		// 01:00 in Vietnam is still the previous day in UTC
+		{"uses exchange time", time.Date(2026, 2, 3, 1, 0, 0, 0, vietnam).UTC(), "2026-02-02"},
+	}
+	for _, tt := range tests {
+		if got := expectedCandleDate(hose, tt.now); got != tt.want {
+			t.Errorf("%s: expectedCandleDate() = %s; want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOverallStatus(t *testing.T) {
+	fresh := []ExchangeFreshness{{Exchange: "HOSE", Fresh: true}, {Exchange: "HNX", Fresh: true}}
+	if got := overallStatus(fresh, nil); got != StatusOperational {
+		t.Errorf("all fresh = %s; want %s", got, StatusOperational)
+	}
+
+	stale := []ExchangeFreshness{{Exchange: "HOSE", Fresh: true}, {Exchange: "HNX", Fresh: false}}
+	if got := overallStatus(stale, nil); got != StatusDegraded {
+		t.Errorf("stale exchange = %s; want %s", got, StatusDegraded)
+	}
+
+	if got := overallStatus(fresh, []StatusIncident{{Component: StatusComponentMarketData}}); got != StatusDegraded {
+		t.Errorf("open incident = %s; want %s", got, StatusDegraded)
+	}
+}
+
+func TestStatusIncidents(t *testing.T) {
+	early := time.Date(2026, 2, 3, 1, 0, 0, 0, time.UTC)
+	late := early.Add(time.Hour)
+	message := "Crawler vndirect failed: dial tcp 10.0.0.5:443: connection refused"
+	firing := []models.AlertRule{
+		{Name: "Stale HOSE", Type: models.AlertRuleStaleCandles, LastMessage: &message, LastNotifiedAt: &late},
+		{Name: "Stale HNX", Type: models.AlertRuleStaleCandles, LastNotifiedAt: &early},
+		{Name: "Crawl overdue", Type: models.AlertRuleNoSuccessfulCrawl, LastMessage: &message},
+		{Name: "Internal", Type: "future_rule"},
+	}
+
+	incidents := statusIncidents(firing)
+	if len(incidents) != 3 {
+		t.Fatalf("statusIncidents() = %+v; want 3 incidents", incidents)
+	}
+	if incidents[0].Message != publicIncidents[models.AlertRuleStaleCandles].Message || !sameTime(incidents[0].Since, &early) {
+		t.Errorf("stale candles incident = %+v; want the public message since %v", incidents[0], early)
+	}
+	if incidents[2].Component != StatusComponentAPI {
+		t.Errorf("unknown rule type incident = %+v; want component %s", incidents[2], StatusComponentAPI)
+	}
+	for _, incident := range incidents {
+		if strings.Contains(incident.Message, "10.0.0.5") || strings.Contains(incident.Message, "HOSE") {
+			t.Errorf("incident %+v exposes an internal alert message or rule name", incident)
+		}
+	}
+}
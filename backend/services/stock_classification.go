@@ -0,0 +1,125 @@
+package services
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+// StockFilter selects stocks of the stock list by exchange and ICB
+// classification. Empty fields match every stock; names match ignoring case.
+type StockFilter struct {
+	Exchange string
+	Industry string // ICB level 1
+	Sector   string // ICB level 2
+	Type     string // Instrument type (models.InstrumentTypes)
+}
+
+// ParseStockFilter reads a stock filter from the exchange, industry, sector
+// and type query parameters
+func ParseStockFilter(query func(string) string) StockFilter {
+	return StockFilter{
+		Exchange: strings.TrimSpace(query("exchange")),
+		Industry: strings.TrimSpace(query("industry")),
+		Sector:   strings.TrimSpace(query("sector")),
+		Type:     strings.TrimSpace(query("type")),
+	}
+}
+
+// IsZero reports whether the filter matches every stock
+func (f StockFilter) IsZero() bool {
+	return f == StockFilter{}
+}
+
+// Matches reports whether stock passes the filter
+func (f StockFilter) Matches(stock models.Stock) bool {
+	return (f.Exchange == "" || strings.EqualFold(stock.Exchange, f.Exchange)) &&
+		(f.Industry == "" || strings.EqualFold(stock.Industry, f.Industry)) &&
+		(f.Sector == "" || strings.EqualFold(stock.Sector, f.Sector)) &&
+		(f.Type == "" || strings.EqualFold(stockType(stock), f.Type))
+}
+
+// stockType returns the instrument type of stock, stocks for those stored
+// without one
+func stockType(stock models.Stock) string {
+	if stock.Type == "" {
+		return models.InstrumentStock
+	}
+	return stock.Type
+}
+
+// FilterStockMetadata returns a copy of result holding only the stocks
+// matching filter. The cursor is kept, so filtered mirrors resume like
+// unfiltered ones.
+func FilterStockMetadata(result *StockMetadataResult, filter StockFilter) *StockMetadataResult {
+	if filter.IsZero() {
+		return result
+	}
+	filtered := *result
+	filtered.Stocks = make([]models.Stock, 0)
+	for _, stock := range result.Stocks {
+		if filter.Matches(stock) {
+			filtered.Stocks = append(filtered.Stocks, stock)
+		}
+	}
+	filtered.Count = len(filtered.Stocks)
+	return &filtered
+}
+
+// SectorSummary is one ICB sector and how many stocks it classifies
+type SectorSummary struct {
+	Sector  string `json:"sector"`
+	ICBCode string `json:"icb_code,omitempty"`
+	Stocks  int    `json:"stocks"`
+}
+
+// IndustrySummary is one ICB industry with its sectors
+type IndustrySummary struct {
+	Industry string          `json:"industry"` // Empty for sectors stored before industries were crawled
+	Stocks   int             `json:"stocks"`
+	Sectors  []SectorSummary `json:"sectors"`
+}
+
+// SummarizeClassification groups the classified stocks by industry and
+// sector, both by name, for the values the stock list and screener filter on
+func SummarizeClassification(stocks []models.Stock) []IndustrySummary {
+	byIndustry := make(map[string]*IndustrySummary)
+	bySector := make(map[[2]string]*SectorSummary)
+	var sectorKeys [][2]string
+	for _, stock := range stocks {
+		if stock.Sector == "" {
+			continue
+		}
+		industry, ok := byIndustry[stock.Industry]
+		if !ok {
+			industry = &IndustrySummary{Industry: stock.Industry}
+			byIndustry[stock.Industry] = industry
+		}
+		industry.Stocks++
+
+		key := [2]string{stock.Industry, stock.Sector}
+		sector, ok := bySector[key]
+		if !ok {
+			sector = &SectorSummary{Sector: stock.Sector}
+			bySector[key] = sector
+			sectorKeys = append(sectorKeys, key)
+		}
+		if sector.ICBCode == "" {
+			sector.ICBCode = stock.ICBCode
+		}
+		sector.Stocks++
+	}
+
+	sort.Slice(sectorKeys, func(i, j int) bool { return sectorKeys[i][1] < sectorKeys[j][1] })
+	for _, key := range sectorKeys {
+		industry := byIndustry[key[0]]
+		industry.Sectors = append(industry.Sectors, *bySector[key])
+	}
+	summaries := make([]IndustrySummary, 0, len(byIndustry))
+	for _, industry := range byIndustry {
+		summaries = append(summaries, *industry)
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Industry < summaries[j].Industry })
+	return summaries
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+var classifiedStocks = []models.Stock{
+	{Code: "HPG", Exchange: "HOSE", Industry: "Basic Materials", Sector: "Basic Resources", ICBCode: "1700"},
+	{Code: "HSG", Exchange: "HOSE", Industry: "Basic Materials", Sector: "Basic Resources", ICBCode: "1700"},
+	{Code: "DPM", Exchange: "HOSE", Industry: "Basic Materials", Sector: "Chemicals", ICBCode: "1300"},
+	{Code: "SHS", Exchange: "HNX", Industry: "Financials", Sector: "Financial Services", ICBCode: "8700"},
+	{Code: "VCB", Exchange: "HOSE", Sector: "Banks"},
+	{Code: "XYZ", Exchange: "UPCOM"},
+}
+
+func TestStockFilter(t *testing.T) {
+	filter := ParseStockFilter(url.Values{"exchange": {"hose"}, "industry": {" basic materials "}}.Get)
+	result := FilterStockMetadata(&StockMetadataResult{Mode: "full", Count: len(classifiedStocks), Stocks: classifiedStocks}, filter)
+	if result.Count != 3 || result.Stocks[2].Code != "DPM" || result.Mode != "full" {
+		t.Errorf("FilterStockMetadata(%+v) = %+v; want HPG, HSG and DPM", filter, result)
+	}
+
+	filter = ParseStockFilter(url.Values{"sector": {"Banks"}}.Get)
+	if !filter.Matches(classifiedStocks[4]) || filter.Matches(classifiedStocks[0]) {
+		t.Errorf("%+v should match VCB only", filter)
+	}
+
+	filter = ParseStockFilter(url.Values{"type": {"cw"}}.Get)
+	warrant := models.Stock{Code: "CHPG2401", Exchange: "HOSE", Type: models.InstrumentCoveredWarrant}
+	if !filter.Matches(warrant) || filter.Matches(classifiedStocks[0]) {
+		t.Errorf("%+v should match the covered warrant only", filter)
+	}
+	if !ParseStockFilter(url.Values{"type": {"stock"}}.Get).Matches(classifiedStocks[0]) {
+		t.Error("type=stock should match stocks stored without a type")
+	}
+
+	unfiltered := &StockMetadataResult{Count: 1, Stocks: classifiedStocks[:1]}
+	if FilterStockMetadata(unfiltered, ParseStockFilter(url.Values{}.Get)) != unfiltered {
+		t.Error("an empty filter should return the result as is")
+	}
+}
+
+func TestSummarizeClassification(t *testing.T) {
+	summaries := SummarizeClassification(classifiedStocks)
+	if len(summaries) != 3 {
+		t.Fatalf("summaries = %+v; want the unnamed industry, Basic Materials and Financials", summaries)
+	}
+	if summaries[0].Industry != "" || summaries[0].Sectors[0].Sector != "Banks" {
+		t.Errorf("summaries[0] = %+v; want Banks without an industry first", summaries[0])
+	}
+	materials := summaries[1]
+	if materials.Industry != "Basic Materials" || materials.Stocks != 3 || len(materials.Sectors) != 2 {
+		t.Fatalf("summaries[1] = %+v; want Basic Materials with 3 stocks in 2 sectors", materials)
+	}
+	if got := materials.Sectors[0]; got != (SectorSummary{Sector: "Basic Resources", ICBCode: "1700", Stocks: 2}) {
+		t.Errorf("Basic Materials sectors[0] = %+v; want Basic Resources (1700) with 2 stocks", got)
+	}
+}
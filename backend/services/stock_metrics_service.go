@@ -0,0 +1,504 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// metricsLog logs the liquidity metrics computed after crawl runs
+var metricsLog = logging.Component("stock_metrics")
+
+const (
+	// stockMetricsBatch caps the symbols whose candles are read at once
+	stockMetricsBatch = 200
+	// defaultScreenerLimit and maxScreenerLimit bound the rows of a screen
+	defaultScreenerLimit = 50
+	maxScreenerLimit     = 500
+	// defaultNewHighsLowsLimit and maxNewHighsLowsLimit bound the symbols of
+	// each side of the new highs and lows
+	defaultNewHighsLowsLimit = 50
+	maxNewHighsLowsLimit     = 500
+)
+
+// ScreenerSortFields maps the ?sort= values of the screener to stored fields
+var ScreenerSortFields = map[string]string{
+	"code":                 "_id",
+	"avg_value20":          "avgValue20",
+	"avg_volume20":         "avgVolume20",
+	"relative_volume":      "relativeVolume",
+	"volatility20":         "volatility20",
+	"amihud20":             "amihud20",
+	"max_daily_position":   "maxDailyPosition",
+	"zero_volume_sessions": "zeroVolumeSessions",
+}
+
+// ScreenerFilter selects and orders stored liquidity metrics. Zero values
+// leave a bound unset.
+type ScreenerFilter struct {
+	Exchange          string
+	Industry          string // ICB level 1
+	Sector            string // ICB level 2
+	MinAvgValue       float64
+	MinAvgVolume      float64
+	MinRelativeVolume float64
+	MaxVolatility     float64
+	MaxAmihud         float64
+	AboveMA           int    // Close above the moving average of these sessions (one of models.MovingAveragePeriods)
+	BelowMA           int    // Close below it
+	Sort              string // A key of ScreenerSortFields; default avg_value20
+	Ascending         bool   // Descending by default
+	Limit             int
+}
+
+// NewHighsLowsQuery selects the new 52-week highs and lows
+type NewHighsLowsQuery struct {
+	Exchange string // All exchanges when empty
+	Limit    int    // Symbols of each side
+}
+
+// StockMetricsService recomputes the derived liquidity metrics of every
+// symbol that gained candles after each crawl, with their 52-week range, and
+// serves them to the stock detail, the screener and the new highs and lows
+type StockMetricsService struct {
+	metricsCollection *mongo.Collection
+	stockService      *StockService
+	readCache         *cache.Cache // Shared with the stock service
+}
+
+// NewStockMetricsService creates a new StockMetricsService instance
+func NewStockMetricsService(stockService *StockService) *StockMetricsService {
+	return &StockMetricsService{
+		metricsCollection: config.GetCollection("stock_metrics"),
+		stockService:      stockService,
+		readCache:         stockService.readCache,
+	}
+}
+
+// ComputeRun recomputes the metrics of the symbols that gained candles in a
+// finished crawl run. It is registered as a crawl run listener.
+func (s *StockMetricsService) ComputeRun(run *models.CrawlRun, newDates map[string]string) {
+	if len(newDates) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	codes := make([]string, 0, len(newDates))
+	for code := range newDates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	computed, err := s.Compute(ctx, codes)
+	s.readCache.Invalidate(ctx, cache.NamespaceIndicators)
+	if err != nil {
+		metricsLog.Warn("Failed to compute stock metrics", "run_id", run.ID.Hex(), logging.FieldError, err)
+		return
+	}
+	metricsLog.Info("Liquidity metrics computed", "symbols", computed)
+}
+
+// Compute recomputes and stores the metrics of codes and returns how many
+// were stored
+func (s *StockMetricsService) Compute(ctx context.Context, codes []string) (int, error) {
+	computedAt := primitive.NewDateTimeFromTime(time.Now())
+	computed := 0
+	for start := 0; start < len(codes); start += stockMetricsBatch {
+		end := start + stockMetricsBatch
+		if end > len(codes) {
+			end = len(codes)
+		}
+		batch := codes[start:end]
+		candles, err := s.stockService.LatestCandles(ctx, batch, models.Range52WSessions)
+		if err != nil {
+			return computed, err
+		}
+		stocks, err := s.stockService.GetStocks(ctx, batch)
+		if err != nil {
+			return computed, err
+		}
+
+		writes := make([]mongo.WriteModel, 0, len(batch))
+		for _, code := range batch {
+			metrics, ok := models.ComputeLiquidityMetrics(code, candles[code], computedAt)
+			if !ok {
+				continue
+			}
+			stock := stocks[code]
+			metrics.Exchange, metrics.Industry, metrics.Sector = stock.Exchange, stock.Industry, stock.Sector
+			metrics.SharesOutstanding = stock.SharesOutstanding
+			metrics.MarketCap = models.MarketCap(metrics.Close, stock.SharesOutstanding)
+			if r, ok := models.ComputeRange52W(candles[code]); ok {
+				metrics.Range52W = &r
+			}
+			if ma, ok := models.LatestMovingAverages(candles[code]); ok {
+				metrics.MovingAverages = &ma
+			}
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.M{"_id": code}).
+				SetReplacement(metrics).
+				SetUpsert(true))
+		}
+		if len(writes) == 0 {
+			continue
+		}
+		if _, err := s.metricsCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return computed, fmt.Errorf("failed to save stock metrics: %w", err)
+		}
+		computed += len(writes)
+	}
+	return computed, nil
+}
+
+// Get returns the stored metrics of a symbol, or nil when none are stored.
+// Results are cached in the indicators namespace.
+func (s *StockMetricsService) Get(ctx context.Context, code string) (*models.LiquidityMetrics, error) {
+	code = strings.ToUpper(code)
+	return cache.Fetch(ctx, s.readCache, cache.NamespaceIndicators, "metrics:"+code, func(ctx context.Context) (*models.LiquidityMetrics, error) {
+		return s.get(ctx, code)
+	})
+}
+
+// get reads the stored metrics of a symbol
+func (s *StockMetricsService) get(ctx context.Context, code string) (*models.LiquidityMetrics, error) {
+	var metrics models.LiquidityMetrics
+	err := s.metricsCollection.FindOne(ctx, bson.M{"_id": strings.ToUpper(code)}).Decode(&metrics)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stock metrics: %w", err)
+	}
+	return &metrics, nil
+}
+
+// NewHighsLows returns the symbols whose newest session made a new 52-week
+// high or low, as of the newest date of the stored metrics of the exchange.
+// Each side is ordered by average traded value, most liquid first. Results
+// are cached in the indicators namespace.
+func (s *StockMetricsService) NewHighsLows(ctx context.Context, query NewHighsLowsQuery) (*models.NewHighsLows, error) {
+	key := fmt.Sprintf("new-highs-lows:%+v", query)
+	return cache.Fetch(ctx, s.readCache, cache.NamespaceIndicators, key, func(ctx context.Context) (*models.NewHighsLows, error) {
+		return s.newHighsLows(ctx, query)
+	})
+}
+
+// newHighsLows runs the NewHighsLows queries
+func (s *StockMetricsService) newHighsLows(ctx context.Context, query NewHighsLowsQuery) (*models.NewHighsLows, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	result := &models.NewHighsLows{
+		Exchange: query.Exchange,
+		Highs:    make([]models.LiquidityMetrics, 0),
+		Lows:     make([]models.LiquidityMetrics, 0),
+	}
+	var newest models.LiquidityMetrics
+	err := s.metricsCollection.FindOne(ctx, newHighsLowsQuery(query.Exchange, "", ""),
+		options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}}).SetProjection(bson.M{"date": 1})).Decode(&newest)
+	if err == mongo.ErrNoDocuments {
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the newest stock metrics: %w", err)
+	}
+	result.Date = newest.Date
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultNewHighsLowsLimit
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "avgValue20", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	for _, side := range []struct {
+		flag string
+		rows *[]models.LiquidityMetrics
+	}{
+		{"newHigh", &result.Highs},
+		{"newLow", &result.Lows},
+	} {
+		cursor, err := s.metricsCollection.Find(ctx, newHighsLowsQuery(query.Exchange, result.Date, side.flag), opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find 52-week %s: %w", side.flag, err)
+		}
+		err = cursor.All(ctx, side.rows)
+		cursor.Close(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode stock metrics: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// newHighsLowsQuery builds the Mongo filter of the metrics of exchange (all
+// when empty) on date (any when empty) whose 52-week range has flag
+// (newHigh or newLow; any when empty) set
+func newHighsLowsQuery(exchange, date, flag string) bson.M {
+	query := bson.M{}
+	if exchange != "" {
+		query["exchange"] = strings.ToUpper(exchange)
+	}
+	if date != "" {
+		query["date"] = date
+	}
+	if flag != "" {
+		query["range52w."+flag] = true
+	}
+	return query
+}
+
+// ParseNewHighsLowsQuery reads the new highs and lows query from query
+// parameters: exchange and limit (each side, default 50, max 500)
+func ParseNewHighsLowsQuery(query func(string) string) (NewHighsLowsQuery, error) {
+	q := NewHighsLowsQuery{Exchange: strings.ToUpper(strings.TrimSpace(query("exchange"))), Limit: defaultNewHighsLowsLimit}
+	if q.Exchange != "" {
+		if _, ok := models.LookupExchange(q.Exchange); !ok {
+			return q, fmt.Errorf("exchange: unknown exchange %q", q.Exchange)
+		}
+	}
+	if raw := query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxNewHighsLowsLimit {
+			return q, fmt.Errorf("limit: expected 1 to %d", maxNewHighsLowsLimit)
+		}
+		q.Limit = limit
+	}
+	return q, nil
+}
+
+// Screen returns the stored metrics matching filter. Results are cached in
+// the indicators namespace.
+func (s *StockMetricsService) Screen(ctx context.Context, filter ScreenerFilter) ([]models.LiquidityMetrics, error) {
+	key := fmt.Sprintf("screener:%+v", filter)
+	return cache.Fetch(ctx, s.readCache, cache.NamespaceIndicators, key, func(ctx context.Context) ([]models.LiquidityMetrics, error) {
+		return s.screen(ctx, filter)
+	})
+}
+
+// screen runs the Screen query
+func (s *StockMetricsService) screen(ctx context.Context, filter ScreenerFilter) ([]models.LiquidityMetrics, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	field, ok := ScreenerSortFields[filter.Sort]
+	if !ok {
+		field = ScreenerSortFields["avg_value20"]
+	}
+	order := -1
+	if filter.Ascending {
+		order = 1
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultScreenerLimit
+	}
+	if limit > maxScreenerLimit {
+		limit = maxScreenerLimit
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: order}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := s.metricsCollection.Find(ctx, screenerQuery(filter), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to screen stock metrics: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	rows := make([]models.LiquidityMetrics, 0)
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode stock metrics: %w", err)
+	}
+	return rows, nil
+}
+
+// screenerQuery builds the Mongo filter of a screen
+func screenerQuery(filter ScreenerFilter) bson.M {
+	query := bson.M{}
+	if filter.Exchange != "" {
+		query["exchange"] = strings.ToUpper(filter.Exchange)
+	}
+	if filter.Industry != "" {
+		query["industry"] = filter.Industry
+	}
+	if filter.Sector != "" {
+		query["sector"] = filter.Sector
+	}
+	bounds := []struct {
+		field string
+		op    string
+		value float64
+	}{
+		{"avgValue20", "$gte", filter.MinAvgValue},
+		{"avgVolume20", "$gte", filter.MinAvgVolume},
+		{"relativeVolume", "$gte", filter.MinRelativeVolume},
+		{"volatility20", "$lte", filter.MaxVolatility},
+		{"amihud20", "$lte", filter.MaxAmihud},
+	}
+	for _, bound := range bounds {
+		if bound.value > 0 {
+			query[bound.field] = bson.M{bound.op: bound.value}
+		}
+	}
+	var versusMA bson.A
+	for _, ma := range []struct {
+		op     string
+		period int
+	}{
+		{"$gt", filter.AboveMA},
+		{"$lt", filter.BelowMA},
+	} {
+		if ma.period <= 0 {
+			continue
+		}
+		// A symbol without the average yet matches neither
+		field := fmt.Sprintf("ma.ma%d", ma.period)
+		query[field] = bson.M{"$exists": true}
+		versusMA = append(versusMA, bson.M{ma.op: bson.A{"$close", "$" + field}})
+	}
+	switch len(versusMA) {
+	case 1:
+		query["$expr"] = versusMA[0]
+	case 2:
+		query["$expr"] = bson.M{"$and": versusMA}
+	}
+	return query
+}
+
+// ParseScreenerFilter reads a screen from query parameters: exchange,
+// industry, sector, min_avg_value, min_avg_volume, min_relative_volume,
+// max_volatility, max_amihud, above_ma and below_ma (10, 20, 50 or 200),
+// sort (a field, ascending, or "-field", descending; default -avg_value20)
+// and limit
+func ParseScreenerFilter(query func(string) string) (ScreenerFilter, error) {
+	filter := ScreenerFilter{
+		Exchange: query("exchange"),
+		Industry: query("industry"),
+		Sector:   query("sector"),
+	}
+	numbers := []struct {
+		name   string
+		target *float64
+	}{
+		{"min_avg_value", &filter.MinAvgValue},
+		{"min_avg_volume", &filter.MinAvgVolume},
+		{"min_relative_volume", &filter.MinRelativeVolume},
+		{"max_volatility", &filter.MaxVolatility},
+		{"max_amihud", &filter.MaxAmihud},
+	}
+	for _, number := range numbers {
+		raw := query(number.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 {
+			return filter, fmt.Errorf("%s: expected a non-negative number", number.name)
+		}
+		*number.target = value
+	}
+	periods := []struct {
+		name   string
+		target *int
+	}{
+		{"above_ma", &filter.AboveMA},
+		{"below_ma", &filter.BelowMA},
+	}
+	for _, period := range periods {
+		raw := query(period.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || !validMAPeriod(value) {
+			return filter, fmt.Errorf("%s: expected one of %v", period.name, models.MovingAveragePeriods)
+		}
+		*period.target = value
+	}
+
+	if sortBy := query("sort"); sortBy != "" {
+		field, descending := strings.CutPrefix(sortBy, "-")
+		if _, ok := ScreenerSortFields[field]; !ok {
+			return filter, fmt.Errorf("sort: unknown field %q", field)
+		}
+		filter.Sort, filter.Ascending = field, !descending
+	}
+	if raw := query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return filter, fmt.Errorf("limit: expected a positive number")
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// EncodeScreenerFilter returns filter as the canonical query string
+// ParseScreenerFilter reads, with unset bounds left out
+func EncodeScreenerFilter(filter ScreenerFilter) string {
+	values := url.Values{}
+	if filter.Exchange != "" {
+		values.Set("exchange", strings.ToUpper(filter.Exchange))
+	}
+	if filter.Industry != "" {
+		values.Set("industry", filter.Industry)
+	}
+	if filter.Sector != "" {
+		values.Set("sector", filter.Sector)
+	}
+	for _, number := range []struct {
+		name  string
+		value float64
+	}{
+		{"min_avg_value", filter.MinAvgValue},
+		{"min_avg_volume", filter.MinAvgVolume},
+		{"min_relative_volume", filter.MinRelativeVolume},
+		{"max_volatility", filter.MaxVolatility},
+		{"max_amihud", filter.MaxAmihud},
+	} {
+		if number.value > 0 {
+			values.Set(number.name, strconv.FormatFloat(number.value, 'f', -1, 64))
+		}
+	}
+	if filter.AboveMA > 0 {
+		values.Set("above_ma", strconv.Itoa(filter.AboveMA))
+	}
+	if filter.BelowMA > 0 {
+		values.Set("below_ma", strconv.Itoa(filter.BelowMA))
+	}
+	if filter.Sort != "" {
+		if filter.Ascending {
+			values.Set("sort", filter.Sort)
+		} else {
+			values.Set("sort", "-"+filter.Sort)
+		}
+	}
+	if filter.Limit > 0 {
+		values.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	return values.Encode()
+}
+
+// validMAPeriod reports whether period is one of models.MovingAveragePeriods
+func validMAPeriod(period int) bool {
+	for _, p := range models.MovingAveragePeriods {
+		if p == period {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseScreenerFilter(t *testing.T) {
+	query := url.Values{
+		"exchange":       {"hose"},
+		"min_avg_value":  {"1e9"},
+		"max_volatility": {"3.5"},
+		"sort":           {"relative_volume"},
+		"limit":          {"20"},
+	}
+	filter, err := ParseScreenerFilter(query.Get)
+	if err != nil {
+		t.Fatalf("ParseScreenerFilter() error = %v", err)
+	}
+	want := ScreenerFilter{Exchange: "hose", MinAvgValue: 1e9, MaxVolatility: 3.5, Sort: "relative_volume", Ascending: true, Limit: 20}
+	if filter != want {
+		t.Errorf("ParseScreenerFilter() = %+v; want %+v", filter, want)
+	}
+
+	filter, err = ParseScreenerFilter(url.Values{"sort": {"-amihud20"}}.Get)
+	if err != nil || filter.Sort != "amihud20" || filter.Ascending {
+		t.Errorf("ParseScreenerFilter(sort=-amihud20) = %+v, %v; want amihud20 descending", filter, err)
+	}
+
+	for _, bad := range []url.Values{
+		{"min_avg_volume": {"-1"}},
+		{"max_amihud": {"abc"}},
+		{"sort": {"price"}},
+		{"limit": {"0"}},
+	} {
+		if _, err := ParseScreenerFilter(bad.Get); err == nil {
+			t.Errorf("ParseScreenerFilter(%v) succeeded; want error", bad)
+		}
+	}
+}
+
+func TestScreenerQuery(t *testing.T) {
+	got := screenerQuery(ScreenerFilter{Exchange: "hnx", Industry: "Financials", Sector: "Banks", MinRelativeVolume: 2, MaxAmihud: 0.5})
+	want := bson.M{
+		"exchange":       "HNX",
+		"industry":       "Financials",
+		"sector":         "Banks",
+		"relativeVolume": bson.M{"$gte": 2.0},
+		"amihud20":       bson.M{"$lte": 0.5},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("screenerQuery() = %v; want %v", got, want)
+	}
+}
+
+func TestEncodeScreenerFilter(t *testing.T) {
+	filter := ScreenerFilter{Exchange: "hose", Industry: "Financials", MinAvgValue: 1e9, MaxVolatility: 3.5, Sort: "relative_volume", Limit: 20}
+	encoded := EncodeScreenerFilter(filter)
+	if encoded != "exchange=HOSE&industry=Financials&limit=20&max_volatility=3.5&min_avg_value=1000000000&sort=-relative_volume" {
+		t.Errorf("EncodeScreenerFilter() = %q", encoded)
+	}
+
+	query, _ := url.ParseQuery(encoded)
+	decoded, err := ParseScreenerFilter(query.Get)
+	filter.Exchange = "HOSE"
+	if err != nil || decoded != filter {
+		t.Errorf("ParseScreenerFilter(EncodeScreenerFilter()) = %+v, %v; want %+v", decoded, err, filter)
+	}
+	if encoded := EncodeScreenerFilter(ScreenerFilter{}); encoded != "" {
+		t.Errorf("EncodeScreenerFilter(empty) = %q; want empty", encoded)
+	}
+}
+
+func TestParseNewHighsLowsQuery(t *testing.T) {
+	query, err := ParseNewHighsLowsQuery(url.Values{"exchange": {"hose"}, "limit": {"20"}}.Get)
+	if err != nil || query != (NewHighsLowsQuery{Exchange: "HOSE", Limit: 20}) {
+		t.Errorf("ParseNewHighsLowsQuery() = %+v, %v; want HOSE, 20", query, err)
+	}
+	if query, err := ParseNewHighsLowsQuery(url.Values{}.Get); err != nil || query.Limit != defaultNewHighsLowsLimit {
+		t.Errorf("ParseNewHighsLowsQuery(empty) = %+v, %v; want the default limit", query, err)
+	}
+	for _, bad := range []url.Values{
+		{"exchange": {"NYSE"}},
+		{"limit": {"0"}},
+		{"limit": {"501"}},
+	} {
+		if _, err := ParseNewHighsLowsQuery(bad.Get); err == nil {
+			t.Errorf("ParseNewHighsLowsQuery(%v) succeeded; want error", bad)
+		}
+	}
+}
+
+func TestNewHighsLowsQuery(t *testing.T) {
+	got := newHighsLowsQuery("hnx", "2026-03-03", "newLow")
+	want := bson.M{"exchange": "HNX", "date": "2026-03-03", "range52w.newLow": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("newHighsLowsQuery() = %v; want %v", got, want)
+	}
+	if got := newHighsLowsQuery("", "", ""); len(got) != 0 {
+		t.Errorf("newHighsLowsQuery(all) = %v; want no filter", got)
+	}
+}
+
+func TestScreenerMovingAverages(t *testing.T) {
+	filter, err := ParseScreenerFilter(url.Values{"above_ma": {"50"}, "below_ma": {"10"}}.Get)
+	if err != nil || filter.AboveMA != 50 || filter.BelowMA != 10 {
+		t.Fatalf("ParseScreenerFilter(above_ma, below_ma) = %+v, %v; want 50 and 10", filter, err)
+	}
+	if _, err := ParseScreenerFilter(url.Values{"above_ma": {"30"}}.Get); err == nil {
+		t.Error("ParseScreenerFilter(above_ma=30) succeeded; want error")
+	}
+	if encoded := EncodeScreenerFilter(filter); encoded != "above_ma=50&below_ma=10" {
+		t.Errorf("EncodeScreenerFilter() = %q", encoded)
+	}
+
+	got := screenerQuery(ScreenerFilter{AboveMA: 200})
+	want := bson.M{
+		"ma.ma200": bson.M{"$exists": true},
+		"$expr":    bson.M{"$gt": bson.A{"$close", "$ma.ma200"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("screenerQuery(above_ma) = %v; want %v", got, want)
+	}
+	got = screenerQuery(filter)
+	if and, ok := got["$expr"].(bson.M)["$and"].(bson.A); !ok || len(and) != 2 {
+		t.Errorf("screenerQuery(above_ma, below_ma) = %v; want both conditions", got)
+	}
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrStockNotFound is returned for codes that are neither listed nor a
+// former ticker
+var ErrStockNotFound = errors.New("stock not found")
+
+// StockService handles read access to the stock universe
+type StockService struct {
+	stockCollection *mongo.Collection
+	prices          PriceRepository
+	reads           *ReadCoalescer
+	readCache       *cache.Cache // Stock list, search and candle responses
+}
+
+// NewStockService creates a new StockService instance reading candles from
+// prices
+func NewStockService(prices PriceRepository, readCache *cache.Cache) *StockService {
+	return &StockService{
+		stockCollection: config.GetCollection("stocks"),
+		prices:          prices,
+		reads:           NewReadCoalescer(15 * time.Second),
+		readCache:       readCache,
+	}
+}
+
+// InvalidateRun drops the cached stock list and candles once a crawl run
+// finished. It is registered as the first crawl run listener, so listeners
+// reading candles see the new ones.
+func (s *StockService) InvalidateRun(run *models.CrawlRun, newDates map[string]string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s.readCache.Invalidate(ctx, cache.NamespaceStocks, cache.NamespacePrices)
+}
+
+// InvalidatePrices drops the cached candles after candles were written
+// outside a crawl run, such as by the realtime quote feed
+func (s *StockService) InvalidatePrices(ctx context.Context) {
+	s.readCache.Invalidate(ctx, cache.NamespacePrices)
+}
+
+// FlushCache drops every cached response of the read cache shared with the
+// signal and metrics services
+func (s *StockService) FlushCache(ctx context.Context) {
+	s.readCache.Invalidate(ctx, cache.Namespaces...)
+}
+
+// StockMetadataResult is the payload returned by GetStockMetadata
+type StockMetadataResult struct {
+	Mode      string         `json:"mode"`            // "full" or "delta"
+	Since     *time.Time     `json:"since,omitempty"` // Lower bound requested by the client
+	NextSince time.Time      `json:"next_since"`      // Cursor to pass as ?since= on the next call
+	Count     int            `json:"count"`           // Number of stocks returned
+	Stocks    []models.Stock `json:"stocks"`          // Stocks ordered by updatedAt ascending
+}
+
+// GetStockMetadata returns the full stock list, or only the stocks changed
+// after since when it is non-nil. Results are ordered by updatedAt so that
+// mirrors can resume from NextSince without missing changes. Identical
+// concurrent calls share one query, and the full list is cached in the
+// stocks namespace (pre-loaded by the warm-up routine). Mirrors that missed
+// a change while it was cached pick it up on their next delta call, since
+// NextSince is captured before the list was queried.
+func (s *StockService) GetStockMetadata(ctx context.Context, since *time.Time) (*StockMetadataResult, error) {
+	if since != nil {
+		key := "metadata:" + since.UTC().Format(time.RFC3339Nano)
+		return Coalesce(s.reads, ctx, key, func(ctx context.Context) (*StockMetadataResult, error) {
+			return s.queryStockMetadata(ctx, since)
+		})
+	}
+
+	return cache.Fetch(ctx, s.readCache, cache.NamespaceStocks, "metadata:full", func(ctx context.Context) (*StockMetadataResult, error) {
+		return Coalesce(s.reads, ctx, "metadata:full", func(ctx context.Context) (*StockMetadataResult, error) {
+			return s.queryStockMetadata(ctx, nil)
+		})
+	})
+}
+
+// queryStockMetadata runs the GetStockMetadata query
+func (s *StockService) queryStockMetadata(ctx context.Context, since *time.Time) (*StockMetadataResult, error) {
+
+	// Capture the cursor before querying so that writes landing during the
+	// query are picked up by the next delta call instead of being skipped
+	queryStartedAt := time.Now().UTC()
+
+	filter := bson.M{}
+	mode := "full"
+	if since != nil {
+		filter["updatedAt"] = bson.M{"$gt": primitive.NewDateTimeFromTime(*since)}
+		mode = "delta"
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: 1}, {Key: "code", Value: 1}})
+	cursor, err := s.stockCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stocks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	stocks := make([]models.Stock, 0)
+	if err := cursor.All(ctx, &stocks); err != nil {
+		return nil, fmt.Errorf("failed to decode stocks: %w", err)
+	}
+
+	nextSince := queryStartedAt
+	if since != nil && len(stocks) == 0 && since.After(nextSince) {
+		nextSince = *since
+	}
+
+	return &StockMetadataResult{
+		Mode:      mode,
+		Since:     since,
+		NextSince: nextSince,
+		Count:     len(stocks),
+		Stocks:    stocks,
+	}, nil
+}
+
+// MetadataCursor returns the next_since cursor of a stock metadata read
+// starting now: taken before the query, like GetStockMetadata's, and never
+// earlier than since
+func MetadataCursor(since *time.Time) time.Time {
+	next := time.Now().UTC()
+	if since != nil && since.After(next) {
+		return *since
+	}
+	return next
+}
+
+// StreamStockMetadata reads the same stocks as GetStockMetadata, uncached,
+// and passes them to emit one at a time. Take the next_since cursor with
+// MetadataCursor before calling it. It returns the number of stocks emitted
+// and stops at the first error of emit.
+func (s *StockService) StreamStockMetadata(ctx context.Context, since *time.Time, emit func(models.Stock) error) (int, error) {
+	filter := bson.M{}
+	if since != nil {
+		filter["updatedAt"] = bson.M{"$gt": primitive.NewDateTimeFromTime(*since)}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "updatedAt", Value: 1}, {Key: "code", Value: 1}})
+	cursor, err := s.stockCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query stocks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	emitted := 0
+	for cursor.Next(ctx) {
+		var stock models.Stock
+		if err := cursor.Decode(&stock); err != nil {
+			return emitted, fmt.Errorf("failed to decode stock: %w", err)
+		}
+		if err := emit(stock); err != nil {
+			return emitted, err
+		}
+		emitted++
+	}
+	if err := cursor.Err(); err != nil {
+		return emitted, fmt.Errorf("failed to read stocks: %w", err)
+	}
+	return emitted, nil
+}
+
+// GetStock returns the stock with the given code, or ErrStockNotFound when
+// it is not listed
+func (s *StockService) GetStock(ctx context.Context, code string) (*models.Stock, error) {
+	var stock models.Stock
+	err := s.stockCollection.FindOne(ctx, bson.M{"code": strings.ToUpper(code)}).Decode(&stock)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %s", ErrStockNotFound, strings.ToUpper(code))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stock %s: %w", code, err)
+	}
+	return &stock, nil
+}
+
+// GetCandles returns the daily candles stored under codes between from and
+// to (inclusive), ordered by date, from the price repository. Pass a symbol lineage (current code first) to stitch history across ticker
+// renames; a date stored under several codes is taken from the earliest
+// code in the list. Results are cached in the prices namespace and
+// identical concurrent calls share one query, so the returned slice must
+// not be modified.
+func (s *StockService) GetCandles(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error) {
+	key := fmt.Sprintf("candles:%s:%s:%s", strings.ToUpper(strings.Join(codes, ",")),
+		from.Format("2006-01-02"), to.Format("2006-01-02"))
+	return cache.Fetch(ctx, s.readCache, cache.NamespacePrices, key, func(ctx context.Context) ([]models.CandleData, error) {
+		return Coalesce(s.reads, ctx, key, func(ctx context.Context) ([]models.CandleData, error) {
+			return s.prices.Candles(ctx, codes, from, to)
+		})
+	})
+}
+
+// GetCandlesFilteredInDB returns the same candles as GetCandles but applies
+// the date range in MongoDB, so plain buckets send only the candles in range
+// instead of their whole year (other stores read like GetCandles). It is the
+// candidate implementation of the "candles" canary route.
+func (s *StockService) GetCandlesFilteredInDB(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error) {
+	key := fmt.Sprintf("candles-filtered:%s:%s:%s", strings.ToUpper(strings.Join(codes, ",")),
+		from.Format("2006-01-02"), to.Format("2006-01-02"))
+	return cache.Fetch(ctx, s.readCache, cache.NamespacePrices, key, func(ctx context.Context) ([]models.CandleData, error) {
+		return Coalesce(s.reads, ctx, key, func(ctx context.Context) ([]models.CandleData, error) {
+			if filtering, ok := s.prices.(rangeFilteringRepository); ok {
+				return filtering.CandlesFilteredInDB(ctx, codes, from, to)
+			}
+			return s.prices.Candles(ctx, codes, from, to)
+		})
+	})
+}
+
+// CandlesETag returns a validator of the candles GetCandles returns for
+// codes between from and to, read from the price repository without the
+// candles (PriceRepository.CandlesTag). It returns "" when the repository
+// cannot tell; callers then go without one.
+func (s *StockService) CandlesETag(ctx context.Context, codes []string, from, to time.Time) (string, error) {
+	key := fmt.Sprintf("etag:%s:%s:%s", strings.ToUpper(strings.Join(codes, ",")),
+		from.Format("2006-01-02"), to.Format("2006-01-02"))
+	return cache.Fetch(ctx, s.readCache, cache.NamespacePrices, key, func(ctx context.Context) (string, error) {
+		return s.prices.CandlesTag(ctx, codes, from, to)
+	})
+}
+
+// GetStocks returns the listed stocks among codes, keyed by code
+func (s *StockService) GetStocks(ctx context.Context, codes []string) (map[string]models.Stock, error) {
+	cursor, err := s.stockCollection.Find(ctx, bson.M{"code": bson.M{"$in": codes}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stocks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stocks []models.Stock
+	if err := cursor.All(ctx, &stocks); err != nil {
+		return nil, fmt.Errorf("failed to decode stocks: %w", err)
+	}
+	byCode := make(map[string]models.Stock, len(stocks))
+	for _, stock := range stocks {
+		byCode[stock.Code] = stock
+	}
+	return byCode, nil
+}
+
+// SearchStocks returns up to limit stocks whose code starts with query or
+// whose Vietnamese or English company name contains it, ignoring case and
+// diacritics ("hoa phat group" finds HPG). Code matches come first.
+// Results are cached in the stocks namespace.
+func (s *StockService) SearchStocks(ctx context.Context, query string, limit int) ([]models.Stock, error) {
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < MinSearchQueryLength {
+		return nil, ErrSearchQueryTooShort
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	key := fmt.Sprintf("search:%d:%s", limit, strings.ToLower(query))
+	return cache.Fetch(ctx, s.readCache, cache.NamespaceStocks, key, func(ctx context.Context) ([]models.Stock, error) {
+		return s.searchStocks(ctx, query, limit)
+	})
+}
+
+// searchStocks runs the SearchStocks query
+func (s *StockService) searchStocks(ctx context.Context, query string, limit int) ([]models.Stock, error) {
+	opts := options.Find().SetSort(bson.M{"code": 1}).SetLimit(int64(maxSearchLimit * 5))
+	cursor, err := s.stockCollection.Find(ctx, stockSearchFilter(query), opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search stocks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stocks []models.Stock
+	if err := cursor.All(ctx, &stocks); err != nil {
+		return nil, fmt.Errorf("failed to decode stocks: %w", err)
+	}
+	return rankStocks(stocks, query, limit), nil
+}
+
+// stockSearchFilter matches codes starting with query and company names
+// containing it, as typed or folded (see models.FoldName)
+func stockSearchFilter(query string) bson.M {
+	pattern := regexp.QuoteMeta(query)
+	conditions := bson.A{
+		bson.M{"code": bson.M{"$regex": "^" + regexp.QuoteMeta(strings.ToUpper(query))}},
+		bson.M{"companyName": bson.M{"$regex": pattern, "$options": "i"}},
+		bson.M{"companyNameEn": bson.M{"$regex": pattern, "$options": "i"}},
+	}
+	if folded := models.FoldName(query); folded != "" {
+		conditions = append(conditions, bson.M{"searchNames": bson.M{"$regex": regexp.QuoteMeta(folded)}})
+	}
+	return bson.M{"$or": conditions}
+}
+
+// rankStocks orders stocks whose code starts with query before those matched
+// by company name only, keeping at most limit
+func rankStocks(stocks []models.Stock, query string, limit int) []models.Stock {
+	prefix := strings.ToUpper(query)
+	byCode, byName := make([]models.Stock, 0, limit), make([]models.Stock, 0, limit)
+	for _, stock := range stocks {
+		if strings.HasPrefix(stock.Code, prefix) {
+			byCode = append(byCode, stock)
+		} else {
+			byName = append(byName, stock)
+		}
+	}
+	ranked := append(byCode, byName...)
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+	return ranked
+}
+
+// Sectors returns the sector of every classified stock, by code
+func (s *StockService) Sectors(ctx context.Context) (map[string]string, error) {
+	opts := options.Find().SetProjection(bson.M{"code": 1, "sector": 1})
+	cursor, err := s.stockCollection.Find(ctx, bson.M{"sector": bson.M{"$nin": bson.A{nil, ""}}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stock sectors: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var stocks []models.Stock
+	if err := cursor.All(ctx, &stocks); err != nil {
+		return nil, fmt.Errorf("failed to decode stock sectors: %w", err)
+	}
+	sectors := make(map[string]string, len(stocks))
+	for _, stock := range stocks {
+		sectors[stock.Code] = stock.Sector
+	}
+	return sectors, nil
+}
+
+// StreamCandles reads the same candles as GetCandles and passes them to emit
+// in date order without holding long histories in memory at once (one year
+// of buckets at a time in MongoDB). It returns the number of candles emitted
+// and stops at the first error of emit.
+func (s *StockService) StreamCandles(ctx context.Context, codes []string, from, to time.Time, emit func(models.CandleData) error) (int, error) {
+	return s.prices.StreamCandles(ctx, codes, from, to, emit)
+}
+
+// LatestCandles returns up to n of the newest candles of each code, ordered
+// by date
+func (s *StockService) LatestCandles(ctx context.Context, codes []string, n int) (map[string][]models.CandleData, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	return s.prices.LatestCandles(ctx, codes, n)
+}
+
+// CandlesAsOf returns up to n of the candles of each code up to date, by date
+func (s *StockService) CandlesAsOf(ctx context.Context, codes []string, date string, n int) (map[string][]models.CandleData, error) {
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+	return s.prices.CandlesAsOf(ctx, codes, date, n)
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+)
+
+// UserStore reads the admin users, member profiles and login history listed
+// in the admin dashboard. UserService implements it over Supabase;
+// controllers take the interface so handler tests can pass a fake.
+type UserStore interface {
+	GetAdminUsers(ctx context.Context) ([]models.AdminUser, error)
+	GetAdminUsersWithPagination(ctx context.Context, page, pageSize int) ([]models.AdminUser, int64, error)
+	GetProfiles(ctx context.Context) ([]models.Profile, error)
+	GetProfilesWithPagination(ctx context.Context, page, pageSize int) ([]models.Profile, int64, error)
+	GetLoginHistory(ctx context.Context, adminUserID string, limit int) ([]models.LoginHistory, error)
+}
+
+// ProfileStatus reports whether members' profiles are active. Member access
+// tokens and personal access tokens are checked against it so that a
+// deactivated member loses access at once; UserService implements it over
+// Supabase.
+type ProfileStatus interface {
+	// ProfileActive reports whether the profile is active, or returns
+	// ErrProfileNotFound
+	ProfileActive(ctx context.Context, profileID uuid.UUID) (bool, error)
+}
+
+// AdminStatus reports whether admin users are active. Admin access tokens
+// are checked against it so that a deactivated admin loses API access at
+// once; UserService implements it over Supabase.
+type AdminStatus interface {
+	// AdminActive reports whether the admin user is active, or returns
+	// ErrAdminUserNotFound
+	AdminActive(ctx context.Context, adminUserID uuid.UUID) (bool, error)
+}
+
+// PriceStore reads the stored stocks and their daily candles. StockService
+// implements it over MongoDB with the read cache; controllers and the
+// services computing on candles take the interface so they can be tested
+// without a database. The crawler's side is MarketDataSource.
+type PriceStore interface {
+	GetStockMetadata(ctx context.Context, since *time.Time) (*StockMetadataResult, error)
+	StreamStockMetadata(ctx context.Context, since *time.Time, emit func(models.Stock) error) (int, error)
+	SearchStocks(ctx context.Context, query string, limit int) ([]models.Stock, error)
+	GetStock(ctx context.Context, code string) (*models.Stock, error)
+	GetStocks(ctx context.Context, codes []string) (map[string]models.Stock, error)
+	Sectors(ctx context.Context) (map[string]string, error)
+	GetCandles(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error)
+	GetCandlesFilteredInDB(ctx context.Context, codes []string, from, to time.Time) ([]models.CandleData, error)
+	CandlesETag(ctx context.Context, codes []string, from, to time.Time) (string, error)
+	StreamCandles(ctx context.Context, codes []string, from, to time.Time, emit func(models.CandleData) error) (int, error)
+	LatestCandles(ctx context.Context, codes []string, n int) (map[string][]models.CandleData, error)
+	CandlesAsOf(ctx context.Context, codes []string, date string, n int) (map[string][]models.CandleData, error)
+}
+
+var (
+	_ UserStore  = (*UserService)(nil)
+	_ PriceStore = (*StockService)(nil)
+)
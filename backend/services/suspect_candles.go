@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrSuspectCandleNotFound is returned when a suspect candle ID does not exist
+	ErrSuspectCandleNotFound = errors.New("suspect candle not found")
+	// ErrSuspectCandleResolved is returned when a suspect candle was already reviewed
+	ErrSuspectCandleResolved = errors.New("suspect candle already resolved")
+)
+
+// priceBandLookbackDays is how far before the first fetched candle stored
+// candles are loaded as references; it covers RelistingGapDays
+const priceBandLookbackDays = 60
+
+// maxSuspectCandles caps the suspect candles listed at once
+const maxSuspectCandles = 500
+
+// screenPriceBands checks new candles of stock against its exchange's price
+// band (see models.CheckPriceBands) and stores the violations in the
+// suspect_candles collection. It returns the candles to save and the number
+// of newly detected suspects. When stored candles cannot be loaded the
+// candles are saved unchecked.
+func (cs *CrawlerService) screenPriceBands(stock models.Stock, exchange models.Exchange, candles []models.CandleData, transferDates []string) ([]models.CandleData, int) {
+	if len(candles) == 0 || cs.suspectCollection == nil {
+		return candles, 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	from, _ := time.Parse("2006-01-02", candles[0].D)
+	stored, err := cs.storedCandles(ctx, stock.Code, from.AddDate(0, 0, -priceBandLookbackDays).Format("2006-01-02"), candles[len(candles)-1].D)
+	if err != nil {
+		crawlLog.Warn("Failed to load stored candles, saving without price band check", logging.FieldSymbol, stock.Code, logging.FieldError, err)
+		return candles, 0
+	}
+
+	valid, suspects := models.CheckPriceBands(stock.Code, exchange, candles, stored, firstSessions(transferDates, stored, candles))
+	detected := 0
+	for _, suspect := range suspects {
+		suspect.DetectedAt = primitive.NewDateTimeFromTime(time.Now())
+		// Reviewed suspects keep their status when a later crawl fetches them again
+		result, err := cs.suspectCollection.UpdateOne(ctx,
+			bson.M{"_id": suspect.ID},
+			bson.M{"$setOnInsert": suspect},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			crawlLog.Warn("Failed to store suspect candle", logging.FieldSymbol, stock.Code, "date", suspect.Candle.D, logging.FieldError, err)
+			continue
+		}
+		if result.UpsertedCount > 0 {
+			detected++
+			crawlLog.Warn("Candle outside price band held for review", logging.FieldSymbol, stock.Code,
+				"date", suspect.Candle.D, "reason", suspect.Reason, "first_day", suspect.FirstDay)
+		}
+	}
+	return valid, detected
+}
+
+// storedCandles returns the stored candles of code between from and to
+// (YYYY-MM-DD, inclusive), sorted by date
+func (cs *CrawlerService) storedCandles(ctx context.Context, code, from, to string) ([]models.CandleData, error) {
+	fromDate, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, err
+	}
+	toDate, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, err
+	}
+	return cs.prices.Candles(ctx, []string{code}, fromDate, toDate)
+}
+
+// firstSessions returns the first session on or after each transfer date,
+// the session a listing opened on its new exchange. Transfers are recorded
+// when detected, which may be before the first session there.
+func firstSessions(transferDates []string, stored, candles []models.CandleData) map[string]bool {
+	if len(transferDates) == 0 {
+		return nil
+	}
+	first := make(map[string]bool, len(transferDates))
+	for _, date := range transferDates {
+		session := ""
+		for _, list := range [][]models.CandleData{stored, candles} {
+			i := sort.Search(len(list), func(i int) bool { return list[i].D >= date })
+			if i < len(list) && (session == "" || list[i].D < session) {
+				session = list[i].D
+			}
+		}
+		if session != "" {
+			first[session] = true
+		}
+	}
+	return first
+}
+
+// ListSuspectCandles returns suspect candles with status (all when empty),
+// newest first
+func (cs *CrawlerService) ListSuspectCandles(ctx context.Context, status string, limit int) ([]models.SuspectCandle, error) {
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	if limit <= 0 || limit > maxSuspectCandles {
+		limit = maxSuspectCandles
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "candle.d", Value: -1}, {Key: "code", Value: 1}}).SetLimit(int64(limit))
+	cursor, err := cs.suspectCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query suspect candles: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	suspects := make([]models.SuspectCandle, 0)
+	if err := cursor.All(ctx, &suspects); err != nil {
+		return nil, fmt.Errorf("failed to decode suspect candles: %w", err)
+	}
+	return suspects, nil
+}
+
+// ResolveSuspectCandle records an admin's review of a pending suspect
+// candle. An accepted candle is saved to the price buckets as regular data;
+// a rejected one stays out of them.
+func (cs *CrawlerService) ResolveSuspectCandle(ctx context.Context, id string, accept bool, admin string) (*models.SuspectCandle, error) {
+	var suspect models.SuspectCandle
+	err := cs.suspectCollection.FindOne(ctx, bson.M{"_id": strings.ToUpper(id)}).Decode(&suspect)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSuspectCandleNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load suspect candle: %w", err)
+	}
+	if suspect.Status != models.SuspectCandleStatusPending {
+		return nil, ErrSuspectCandleResolved
+	}
+
+	if accept {
+		if _, err := cs.saveCandles(suspect.Code, []models.CandleData{suspect.Candle}); err != nil {
+			return nil, fmt.Errorf("failed to save accepted candle: %w", err)
+		}
+		suspect.Status = models.SuspectCandleStatusAccepted
+	} else {
+		suspect.Status = models.SuspectCandleStatusRejected
+	}
+	resolvedAt := primitive.NewDateTimeFromTime(time.Now())
+	suspect.ResolvedBy = admin
+	suspect.ResolvedAt = &resolvedAt
+
+	update := bson.M{"$set": bson.M{"status": suspect.Status, "resolvedBy": admin, "resolvedAt": resolvedAt}}
+	result, err := cs.suspectCollection.UpdateOne(ctx, bson.M{"_id": suspect.ID, "status": models.SuspectCandleStatusPending}, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update suspect candle: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrSuspectCandleResolved
+	}
+	crawlLog.Info("Suspect candle resolved", logging.FieldSymbol, suspect.Code, "date", suspect.Candle.D, "status", suspect.Status, "by", admin)
+	return &suspect, nil
+}
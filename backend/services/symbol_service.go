@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrSymbolChangeNotFound is returned when a symbol change ID does not exist
+var ErrSymbolChangeNotFound = errors.New("symbol change not found")
+
+// SymbolResolution maps a requested code to the current ticker
+type SymbolResolution struct {
+	Requested string                `json:"requested"`
+	Code      string                `json:"code"`    // Current ticker
+	Lineage   []string              `json:"lineage"` // Current code first, then former codes
+	Windows   []models.LineageCode  `json:"windows"` // Lineage codes with the dates their candles belong to the symbol
+	Changes   []models.SymbolChange `json:"changes"` // Renames and transfers of the lineage, oldest first
+}
+
+// ReadCodes returns the lineage codes with their windows, for price reads
+func (r *SymbolResolution) ReadCodes() []string {
+	codes := make([]string, len(r.Windows))
+	for i, window := range r.Windows {
+		codes[i] = window.String()
+	}
+	return codes
+}
+
+// Aliased reports whether the requested code is a former ticker
+func (r *SymbolResolution) Aliased() bool {
+	return r.Requested != r.Code
+}
+
+// SymbolService tracks ticker renames and exchange transfers
+type SymbolService struct {
+	changeCollection *mongo.Collection
+	stockCollection  *mongo.Collection
+}
+
+// NewSymbolService creates a new SymbolService instance
+func NewSymbolService() *SymbolService {
+	return &SymbolService{
+		changeCollection: config.GetCollection("symbol_changes"),
+		stockCollection:  config.GetCollection("stocks"),
+	}
+}
+
+// ListChanges returns symbol changes ordered by effective date, optionally
+// only those involving code
+func (s *SymbolService) ListChanges(ctx context.Context, code string) ([]models.SymbolChange, error) {
+	filter := bson.M{}
+	if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+		filter["$or"] = bson.A{bson.M{"oldCode": code}, bson.M{"newCode": code}}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "effectiveDate", Value: 1}, {Key: "createdAt", Value: 1}})
+	cursor, err := s.changeCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query symbol changes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	changes := make([]models.SymbolChange, 0)
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, fmt.Errorf("failed to decode symbol changes: %w", err)
+	}
+	return changes, nil
+}
+
+// CreateChange validates and stores a symbol change
+func (s *SymbolService) CreateChange(ctx context.Context, change *models.SymbolChange) error {
+	if err := change.Normalize(); err != nil {
+		return err
+	}
+	if change.Source == "" {
+		change.Source = models.SymbolChangeSourceAdmin
+	}
+	change.ID = primitive.NewObjectID()
+	change.CreatedAt = primitive.NewDateTimeFromTime(time.Now().UTC())
+
+	if _, err := s.changeCollection.InsertOne(ctx, change); err != nil {
+		return fmt.Errorf("failed to save symbol change: %w", err)
+	}
+	return nil
+}
+
+// DeleteChange removes a symbol change recorded by mistake
+func (s *SymbolService) DeleteChange(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrSymbolChangeNotFound
+	}
+
+	result, err := s.changeCollection.DeleteOne(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return fmt.Errorf("failed to delete symbol change: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return ErrSymbolChangeNotFound
+	}
+	return nil
+}
+
+// Resolve maps a possibly former ticker to the current one as of today,
+// like ResolveAsOf
+func (s *SymbolService) Resolve(ctx context.Context, code string) (*SymbolResolution, error) {
+	return s.ResolveAsOf(ctx, code, markettime.Today())
+}
+
+// ResolveAsOf maps a code as it was on date (YYYY-MM-DD) to the current
+// ticker and lists the codes whose history belongs to it, each with the
+// dates it does. Codes that are neither listed nor part of a symbol change
+// return ErrStockNotFound.
+func (s *SymbolService) ResolveAsOf(ctx context.Context, code, date string) (*SymbolResolution, error) {
+	changes, err := s.ListChanges(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	requested := strings.ToUpper(strings.TrimSpace(code))
+	listed, err := s.stockCollection.CountDocuments(ctx, bson.M{"code": requested}, options.Count().SetLimit(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up stock %s: %w", requested, err)
+	}
+	current, windows := models.ResolveSymbol(changes, requested, date, listed > 0)
+
+	inLineage := make(map[string]bool, len(windows))
+	lineage := make([]string, 0, len(windows))
+	for _, window := range windows {
+		if !inLineage[window.Code] {
+			inLineage[window.Code] = true
+			lineage = append(lineage, window.Code)
+		}
+	}
+	related := make([]models.SymbolChange, 0)
+	for _, change := range changes {
+		if inLineage[change.OldCode] || inLineage[change.NewCode] {
+			related = append(related, change)
+		}
+	}
+	if len(related) == 0 && listed == 0 {
+		// Not a former ticker: the code must be listed
+		return nil, fmt.Errorf("%w: %s", ErrStockNotFound, requested)
+	}
+
+	return &SymbolResolution{
+		Requested: requested,
+		Code:      current,
+		Lineage:   lineage,
+		Windows:   windows,
+		Changes:   related,
+	}, nil
+}
+
+// ExchangeTransferDates returns the effective dates of the exchange
+// transfers of every symbol that moved, oldest first
+func (s *SymbolService) ExchangeTransferDates(ctx context.Context) (map[string][]string, error) {
+	filter := bson.M{"type": models.SymbolChangeExchangeTransfer}
+	opts := options.Find().SetSort(bson.D{{Key: "effectiveDate", Value: 1}}).SetProjection(bson.M{"newCode": 1, "effectiveDate": 1})
+	cursor, err := s.changeCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query exchange transfers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var changes []models.SymbolChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, fmt.Errorf("failed to decode exchange transfers: %w", err)
+	}
+	dates := make(map[string][]string)
+	for _, change := range changes {
+		dates[change.NewCode] = append(dates[change.NewCode], change.EffectiveDate)
+	}
+	return dates, nil
+}
@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/tcbs"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// tcbsTokenTTL is how long an access token is reused when TCBS does not
+	// say when it expires
+	tcbsTokenTTL = 30 * time.Minute
+	// tcbsTokenMargin renews access tokens this long before they expire
+	tcbsTokenMargin = time.Minute
+	// tcbsEncryptedPrefix marks a tcbs_api_key encrypted with
+	// PROVIDER_CREDENTIALS_KEY; other values are keys the frontend wrote in
+	// plain text before the connect endpoint existed
+	tcbsEncryptedPrefix = "enc:"
+)
+
+var (
+	// ErrTCBSNotConnected is returned when the member has no TCBS API key
+	ErrTCBSNotConnected = errors.New("TCBS account is not connected")
+	// ErrTCBSKeyRejected is returned when TCBS rejects the member's API key
+	ErrTCBSKeyRejected = errors.New("TCBS rejected the API key")
+)
+
+// tcbsToken is a cached access token of a member
+type tcbsToken struct {
+	value     string
+	key       [sha256.Size]byte // Fingerprint of the API key it was issued for
+	expiresAt time.Time
+}
+
+// TCBSService connects members' TCBS accounts and reads them with the API
+// key stored, encrypted, on their profile. Access tokens are cached in memory
+// per member until shortly before they expire.
+type TCBSService struct {
+	client *tcbs.Client
+	aead   cipher.AEAD // nil when PROVIDER_CREDENTIALS_KEY is not set
+	mu     sync.Mutex
+	tokens map[uuid.UUID]tcbsToken
+	now    func() time.Time
+}
+
+// NewTCBSService creates a TCBSService calling TCBS through client and
+// encrypting API keys with aead (nil: keys cannot be connected)
+func NewTCBSService(client *tcbs.Client, aead cipher.AEAD) *TCBSService {
+	return &TCBSService{
+		client: client,
+		aead:   aead,
+		tokens: make(map[uuid.UUID]tcbsToken),
+		now:    time.Now,
+	}
+}
+
+// NewTCBSServiceFromEnv creates a TCBSService calling TCBS_BASE_URL (default
+// the TCBS Open API) and encrypting API keys with PROVIDER_CREDENTIALS_KEY
+func NewTCBSServiceFromEnv() (*TCBSService, error) {
+	cfg := tcbs.DefaultConfig()
+	if baseURL := os.Getenv("TCBS_BASE_URL"); baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	var aead cipher.AEAD
+	if raw := strings.TrimSpace(os.Getenv("PROVIDER_CREDENTIALS_KEY")); raw != "" {
+		var err error
+		if aead, err = newCredentialCipher(raw); err != nil {
+			return nil, fmt.Errorf("invalid PROVIDER_CREDENTIALS_KEY: %w", err)
+		}
+	}
+	return NewTCBSService(tcbs.New(cfg), aead), nil
+}
+
+// Connect validates apiKey against TCBS and stores it encrypted on the
+// member's profile with tcbs_connected_at. A rejected key returns
+// ErrTCBSKeyRejected and leaves the profile unchanged.
+func (s *TCBSService) Connect(ctx context.Context, profileID uuid.UUID, apiKey string) (time.Time, error) {
+	if s.aead == nil {
+		return time.Time{}, ErrCredentialsKeyMissing
+	}
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return time.Time{}, ErrTCBSKeyRejected
+	}
+	s.forgetToken(profileID)
+	if _, err := s.token(ctx, profileID, apiKey); err != nil {
+		return time.Time{}, err
+	}
+
+	encrypted, err := s.encryptKey(profileID, apiKey)
+	if err != nil {
+		return time.Time{}, err
+	}
+	connectedAt := s.now().UTC()
+	result := config.GetDBWithContext(ctx).Model(&models.Profile{}).Where("id = ?", profileID).
+		Updates(map[string]interface{}{"tcbs_api_key": encrypted, "tcbs_connected_at": connectedAt, "updated_at": connectedAt})
+	if result.Error != nil {
+		return time.Time{}, fmt.Errorf("failed to save TCBS API key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return time.Time{}, ErrProfileNotFound
+	}
+	return connectedAt, nil
+}
+
+// Disconnect clears the TCBS API key and tcbs_connected_at of the member
+func (s *TCBSService) Disconnect(ctx context.Context, profileID uuid.UUID) error {
+	s.forgetToken(profileID)
+	result := config.GetDBWithContext(ctx).Model(&models.Profile{}).Where("id = ?", profileID).
+		Updates(map[string]interface{}{"tcbs_api_key": nil, "tcbs_connected_at": nil, "updated_at": s.now().UTC()})
+	if result.Error != nil {
+		return fmt.Errorf("failed to clear TCBS API key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrProfileNotFound
+	}
+	return nil
+}
+
+// Portfolio returns the positions and cash balances of every TCBS account of
+// the member. A plain text key stored without going through Connect is
+// validated here, then encrypted and its tcbs_connected_at set.
+func (s *TCBSService) Portfolio(ctx context.Context, profileID uuid.UUID) (*models.TCBSPortfolio, error) {
+	var profile models.Profile
+	db := config.GetDBWithContext(ctx)
+	err := db.Select("id", "tcbs_api_key", "tcbs_connected_at").First(&profile, "id = ?", profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up profile: %w", err)
+	}
+	if profile.TCBSAPIKey == nil || strings.TrimSpace(*profile.TCBSAPIKey) == "" {
+		return nil, ErrTCBSNotConnected
+	}
+	apiKey, err := s.decryptKey(profileID, strings.TrimSpace(*profile.TCBSAPIKey))
+	if err != nil {
+		return nil, err
+	}
+
+	var accounts []models.TCBSAccount
+	err = s.withToken(ctx, profileID, apiKey, func(token string) error {
+		accounts, err = s.fetchAccounts(ctx, token)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if profile.TCBSConnectedAt == nil {
+		connectedAt := s.now().UTC()
+		updates["tcbs_connected_at"] = connectedAt
+		profile.TCBSConnectedAt = &connectedAt
+	}
+	if s.aead != nil && !strings.HasPrefix(*profile.TCBSAPIKey, tcbsEncryptedPrefix) {
+		if updates["tcbs_api_key"], err = s.encryptKey(profileID, apiKey); err != nil {
+			return nil, err
+		}
+	}
+	if len(updates) > 0 {
+		if err := db.Model(&profile).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to record TCBS connection: %w", err)
+		}
+	}
+
+	portfolio := models.SummarizeTCBSPortfolio(accounts, s.now().UTC())
+	portfolio.ConnectedAt = profile.TCBSConnectedAt
+	return &portfolio, nil
+}
+
+// encryptKey returns the stored form of apiKey, bound to the member so that
+// it does not decrypt when copied to another profile
+func (s *TCBSService) encryptKey(profileID uuid.UUID, apiKey string) (string, error) {
+	encrypted, err := encryptCredential(s.aead, "tcbs", profileID.String(), apiKey)
+	if err != nil {
+		return "", err
+	}
+	return tcbsEncryptedPrefix + encrypted, nil
+}
+
+// decryptKey reverses encryptKey; plain text keys are returned as they are
+func (s *TCBSService) decryptKey(profileID uuid.UUID, stored string) (string, error) {
+	encrypted, ok := strings.CutPrefix(stored, tcbsEncryptedPrefix)
+	if !ok {
+		return stored, nil
+	}
+	if s.aead == nil {
+		return "", ErrCredentialsKeyMissing
+	}
+	apiKey, err := decryptCredential(s.aead, "tcbs", profileID.String(), encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to read TCBS API key: %w", err)
+	}
+	return apiKey, nil
+}
+
+// fetchAccounts reads the positions and balance of every account of token
+func (s *TCBSService) fetchAccounts(ctx context.Context, token string) ([]models.TCBSAccount, error) {
+	subAccounts, err := s.client.Accounts(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]models.TCBSAccount, 0, len(subAccounts))
+	for _, subAccount := range subAccounts {
+		holdings, err := s.client.Holdings(ctx, token, subAccount.AccountNo)
+		if err != nil {
+			return nil, err
+		}
+		balance, err := s.client.Balance(ctx, token, subAccount.AccountNo)
+		if err != nil {
+			return nil, err
+		}
+
+		account := models.TCBSAccount{
+			AccountNo: subAccount.AccountNo,
+			Type:      subAccount.Type,
+			Positions: make([]models.TCBSPosition, 0, len(holdings)),
+			Balance: models.TCBSBalance{
+				Cash:        balance.Cash,
+				Available:   balance.Available,
+				BuyingPower: balance.BuyingPower,
+				Debt:        balance.Debt,
+				TotalAsset:  balance.TotalAsset,
+			},
+		}
+		for _, holding := range holdings {
+			account.Positions = append(account.Positions, models.NewTCBSPosition(strings.ToUpper(holding.Symbol),
+				holding.Quantity, holding.AvailableQuantity, holding.CostPrice, holding.MarketPrice))
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, nil
+}
+
+// withToken calls fn with an access token of the member. A cached token
+// that TCBS rejects is renewed once before the key counts as rejected.
+func (s *TCBSService) withToken(ctx context.Context, profileID uuid.UUID, apiKey string, fn func(token string) error) error {
+	for attempt := 0; ; attempt++ {
+		token, err := s.token(ctx, profileID, apiKey)
+		if err != nil {
+			return err
+		}
+		err = fn(token)
+		if !errors.Is(err, tcbs.ErrInvalidAPIKey) {
+			return err
+		}
+		s.forgetToken(profileID)
+		if attempt == 1 {
+			return ErrTCBSKeyRejected
+		}
+	}
+}
+
+// token returns a cached access token of the member for apiKey, or
+// exchanges the key for a new one
+func (s *TCBSService) token(ctx context.Context, profileID uuid.UUID, apiKey string) (string, error) {
+	fingerprint := sha256.Sum256([]byte(apiKey))
+	now := s.now()
+	s.mu.Lock()
+	cached, ok := s.tokens[profileID]
+	s.mu.Unlock()
+	if ok && cached.key == fingerprint && now.Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	issued, err := s.client.Token(ctx, apiKey)
+	if errors.Is(err, tcbs.ErrInvalidAPIKey) {
+		return "", ErrTCBSKeyRejected
+	}
+	if err != nil {
+		return "", err
+	}
+	expiresAt := now.Add(tcbsTokenTTL)
+	if !issued.ExpiresAt.IsZero() {
+		expiresAt = issued.ExpiresAt.Add(-tcbsTokenMargin)
+	}
+
+	s.mu.Lock()
+	s.tokens[profileID] = tcbsToken{value: issued.Value, key: fingerprint, expiresAt: expiresAt}
+	s.mu.Unlock()
+	return issued.Value, nil
+}
+
+// forgetToken drops the cached access token of the member
+func (s *TCBSService) forgetToken(profileID uuid.UUID) {
+	s.mu.Lock()
+	delete(s.tokens, profileID)
+	s.mu.Unlock()
+}
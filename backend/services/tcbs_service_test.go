@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/tcbs"
+	"github.com/google/uuid"
+)
+
+func TestTCBSTokens(t *testing.T) {
+	var issued atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gaia/v1/oauth2/openapi/token":
+			fmt.Fprintf(w, `{"token": "jwt-%d", "expiresIn": 3600}`, issued.Add(1))
+		case "/aion/v1/accounts":
+			// Only the latest token is accepted, as after a revocation
+			if r.Header.Get("Authorization") != fmt.Sprintf("Bearer jwt-%d", issued.Load()) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"data": []}`))
+		}
+	}))
+	defer server.Close()
+	s := NewTCBSService(tcbs.New(tcbs.Config{BaseURL: server.URL}), nil)
+	ctx, profileID := context.Background(), uuid.New()
+
+	first, err := s.token(ctx, profileID, "key")
+	if err != nil {
+		t.Fatalf("token() unexpected error: %v", err)
+	}
+	if again, _ := s.token(ctx, profileID, "key"); again != first || issued.Load() != 1 {
+		t.Errorf("token() again = %q after %d exchanges; want the cached %q", again, issued.Load(), first)
+	}
+	if rotated, _ := s.token(ctx, profileID, "new key"); rotated == first {
+		t.Error("token() for a new API key reused the token of the old one")
+	}
+
+	// A cached token that TCBS stopped accepting is renewed once
+	s.tokens[profileID] = tcbsToken{value: "jwt-old", key: s.tokens[profileID].key, expiresAt: s.tokens[profileID].expiresAt}
+	calls := 0
+	err = s.withToken(ctx, profileID, "new key", func(token string) error {
+		calls++
+		_, err := s.client.Accounts(ctx, token)
+		return err
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("withToken() with a revoked cached token = %v after %d calls; want success on the renewed token", err, calls)
+	}
+
+	err = s.withToken(ctx, profileID, "new key", func(string) error { return &tcbs.StatusError{Endpoint: "holdings", StatusCode: 403} })
+	if !errors.Is(err, ErrTCBSKeyRejected) {
+		t.Errorf("withToken() rejected twice = %v; want ErrTCBSKeyRejected", err)
+	}
+}
+
+func TestTCBSKeyEncryption(t *testing.T) {
+	aead, err := newCredentialCipher(strings.Repeat("ab", 32))
+	if err != nil {
+		t.Fatalf("newCredentialCipher() unexpected error: %v", err)
+	}
+	s := NewTCBSService(nil, aead)
+	profileID := uuid.New()
+
+	stored, err := s.encryptKey(profileID, "secret-key")
+	if err != nil || !strings.HasPrefix(stored, tcbsEncryptedPrefix) || strings.Contains(stored, "secret-key") {
+		t.Fatalf("encryptKey() = %q, %v; want an encrypted value", stored, err)
+	}
+	if apiKey, err := s.decryptKey(profileID, stored); apiKey != "secret-key" || err != nil {
+		t.Errorf("decryptKey() = %q, %v; want the original key", apiKey, err)
+	}
+	if _, err := s.decryptKey(uuid.New(), stored); err == nil {
+		t.Error("decryptKey() of a key copied to another profile should fail")
+	}
+	if apiKey, _ := s.decryptKey(profileID, "plain-key"); apiKey != "plain-key" {
+		t.Errorf("decryptKey() of a plain text key = %q; want it unchanged", apiKey)
+	}
+	if _, err := NewTCBSService(nil, nil).decryptKey(profileID, stored); !errors.Is(err, ErrCredentialsKeyMissing) {
+		t.Errorf("decryptKey() without PROVIDER_CREDENTIALS_KEY = %v; want ErrCredentialsKeyMissing", err)
+	}
+}
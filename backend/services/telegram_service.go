@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+const (
+	telegramAPIURL = "https://api.telegram.org"
+	// telegramMaxTextLength is the longest message Telegram accepts
+	telegramMaxTextLength = 4096
+)
+
+var (
+	// ErrTelegramNotConfigured is returned when the bot token or chat is missing
+	ErrTelegramNotConfigured = errors.New("telegram bot is not configured")
+	// ErrInvalidTelegramUpdate is returned when a webhook update lacks the configured secret token
+	ErrInvalidTelegramUpdate = errors.New("invalid telegram webhook secret")
+)
+
+// TelegramConfig holds the bot credentials and the operations chat
+type TelegramConfig struct {
+	BotToken      string
+	ChatID        string // Chat (group or user) that receives notifications and may send commands
+	WebhookSecret string // secret_token given to setWebhook; commands are disabled without it
+}
+
+// TelegramConfigFromEnv reads TELEGRAM_BOT_TOKEN, TELEGRAM_CHAT_ID and TELEGRAM_WEBHOOK_SECRET
+func TelegramConfigFromEnv() TelegramConfig {
+	return TelegramConfig{
+		BotToken:      os.Getenv("TELEGRAM_BOT_TOKEN"),
+		ChatID:        strings.TrimSpace(os.Getenv("TELEGRAM_CHAT_ID")),
+		WebhookSecret: os.Getenv("TELEGRAM_WEBHOOK_SECRET"),
+	}
+}
+
+// TelegramService posts messages to the operations chat through the Bot API
+type TelegramService struct {
+	cfg    TelegramConfig
+	client *resty.Client
+}
+
+// NewTelegramService creates a TelegramService for the given bot
+func NewTelegramService(cfg TelegramConfig) *TelegramService {
+	client := newRestyClient()
+	client.SetTimeout(10 * time.Second)
+	client.SetRetryCount(2)
+	client.SetRetryWaitTime(time.Second)
+
+	return &TelegramService{cfg: cfg, client: client}
+}
+
+// Configured reports whether messages can be sent to the operations chat
+func (s *TelegramService) Configured() bool {
+	return s.cfg.BotToken != "" && s.cfg.ChatID != ""
+}
+
+// CommandsEnabled reports whether the command webhook is accepted
+func (s *TelegramService) CommandsEnabled() bool {
+	return s.Configured() && s.cfg.WebhookSecret != ""
+}
+
+// SendMessage posts plain text to a chat
+func (s *TelegramService) SendMessage(ctx context.Context, chatID, text string) error {
+	if !s.Configured() {
+		return ErrTelegramNotConfigured
+	}
+	if runes := []rune(text); len(runes) > telegramMaxTextLength {
+		text = string(runes[:telegramMaxTextLength-1]) + "…"
+	}
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetBody(map[string]interface{}{
+			"chat_id":                  chatID,
+			"text":                     text,
+			"disable_web_page_preview": true,
+		}).
+		SetResult(&result).
+		SetError(&result).
+		Post(telegramAPIURL + "/bot" + s.cfg.BotToken + "/sendMessage")
+	if err != nil {
+		// The request URL contains the bot token, so only the cause is reported
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("failed to call telegram API: %w", err)
+	}
+	if resp.IsError() || !result.OK {
+		return fmt.Errorf("telegram API returned status %d: %s", resp.StatusCode(), result.Description)
+	}
+	return nil
+}
+
+// TelegramNotifier delivers notifications to the operations chat
+type TelegramNotifier struct {
+	telegram *TelegramService
+}
+
+// NewTelegramNotifier creates the "telegram" notification channel
+func NewTelegramNotifier(telegram *TelegramService) *TelegramNotifier {
+	return &TelegramNotifier{telegram: telegram}
+}
+
+// Name returns the channel name
+func (t *TelegramNotifier) Name() string { return "telegram" }
+
+// Notify posts the notification to the operations chat
+func (t *TelegramNotifier) Notify(ctx context.Context, n Notification) error {
+	return t.telegram.SendMessage(ctx, t.telegram.cfg.ChatID, formatTelegramNotification(n))
+}
+
+// formatTelegramNotification renders a notification as plain text
+func formatTelegramNotification(n Notification) string {
+	icon := "ℹ️"
+	switch n.Severity {
+	case SeverityWarning:
+		icon = "⚠️"
+	case SeverityCritical:
+		icon = "🚨"
+	case SeverityResolved:
+		icon = "✅"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", icon, n.Title)
+	if n.Message != "" {
+		b.WriteString("\n" + n.Message)
+	}
+	keys := make([]string, 0, len(n.Fields))
+	for key := range n.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&b, "\n%s: %v", key, n.Fields[key])
+	}
+	return b.String()
+}
+
+// telegramUpdate is the part of a Bot API update used for commands
+type telegramUpdate struct {
+	Message *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		From struct {
+			Username string `json:"username"`
+		} `json:"from"`
+	} `json:"message"`
+}
+
+// parseTelegramCommand returns the command of a message ("/crawl@cpls_bot now"
+// gives "crawl"), or "" when the text is not a command
+func parseTelegramCommand(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	command, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+	return strings.ToLower(command)
+}
+
+// TelegramBot answers commands sent to the bot from the operations chat
+type TelegramBot struct {
+	telegram       *TelegramService
+	crawlerService *CrawlerService
+	statusService  *StatusService
+}
+
+// NewTelegramBot creates a bot mapping /crawl and /status to the crawler and status services
+func NewTelegramBot(telegram *TelegramService, crawlerService *CrawlerService, statusService *StatusService) *TelegramBot {
+	return &TelegramBot{
+		telegram:       telegram,
+		crawlerService: crawlerService,
+		statusService:  statusService,
+	}
+}
+
+// HandleUpdate verifies a webhook update and answers its command. Messages
+// from other chats and non-command messages are ignored.
+func (b *TelegramBot) HandleUpdate(ctx context.Context, secretToken string, body []byte) error {
+	if !b.telegram.CommandsEnabled() {
+		return ErrTelegramNotConfigured
+	}
+	if subtle.ConstantTimeCompare([]byte(secretToken), []byte(b.telegram.cfg.WebhookSecret)) != 1 {
+		return ErrInvalidTelegramUpdate
+	}
+
+	var update telegramUpdate
+	if err := json.Unmarshal(body, &update); err != nil {
+		return fmt.Errorf("failed to decode telegram update: %w", err)
+	}
+	if update.Message == nil || strconv.FormatInt(update.Message.Chat.ID, 10) != b.telegram.cfg.ChatID {
+		return nil
+	}
+	command := parseTelegramCommand(update.Message.Text)
+	if command == "" {
+		return nil
+	}
+
+	logging.FromContext(ctx).Info("Telegram command received", "command", command, "from", update.Message.From.Username)
+	return b.telegram.SendMessage(ctx, b.telegram.cfg.ChatID, b.answer(ctx, command))
+}
+
+// answer runs a command and returns the reply
+func (b *TelegramBot) answer(ctx context.Context, command string) string {
+	switch command {
+	case "crawl":
+		if err := b.crawlerService.StartCrawling(); err != nil {
+			return fmt.Sprintf("❌ Failed to start crawling: %v", err)
+		}
+		return "🚀 Crawl started. A summary is posted here when it finishes if CRAWLER_SUMMARY_CHANNELS includes telegram."
+	case "status":
+		status, err := b.statusService.Status(ctx)
+		if err != nil {
+			return fmt.Sprintf("❌ Failed to get status: %v", err)
+		}
+		return formatTelegramStatus(status)
+	default:
+		return "Commands:\n/status - service status, data freshness and incidents\n/crawl - start a full crawl"
+	}
+}
+
+// formatTelegramStatus renders the public status as plain text
+func formatTelegramStatus(status *PublicStatus) string {
+	var b strings.Builder
+	icon := "✅"
+	if status.Status != StatusOperational {
+		icon = "⚠️"
+	}
+	fmt.Fprintf(&b, "%s Status: %s\nUptime: %s", icon, status.Status,
+		(time.Duration(status.UptimeSeconds) * time.Second).String())
+	if status.LastSuccessfulCrawlAt != nil {
+		fmt.Fprintf(&b, "\nLast successful crawl: %s", status.LastSuccessfulCrawlAt.Format(time.RFC3339))
+	} else {
+		b.WriteString("\nLast successful crawl: never")
+	}
+	for _, exchange := range status.Exchanges {
+		state := "fresh"
+		if !exchange.Fresh {
+			state = "stale"
+		}
+		latest := exchange.LatestCandleDate
+		if latest == "" {
+			latest = "none"
+		}
+		fmt.Fprintf(&b, "\n%s: %s (latest %s, expected %s)", exchange.Exchange, state, latest, exchange.ExpectedDate)
+	}
+	for _, incident := range status.Incidents {
+		fmt.Fprintf(&b, "\n🚨 %s: %s", incident.Component, incident.Message)
+	}
+	return b.String()
+}
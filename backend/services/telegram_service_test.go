@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseTelegramCommand(t *testing.T) {
+	tests := map[string]string{
+		"/status":             "status",
+		"/crawl@cpls_ops_bot": "crawl",
+		"/CRAWL now":          "crawl",
+		"  /help":             "help",
+		"status":              "",
+		"":                    "",
+	}
+	for text, want := range tests {
+		if got := parseTelegramCommand(text); got != want {
+			t.Errorf("parseTelegramCommand(%q) = %q; want %q", text, got, want)
+		}
+	}
+}
+
+func TestFormatTelegramNotification(t *testing.T) {
+	text := formatTelegramNotification(Notification{
+		Title:    "Alert: Failed symbols",
+		Message:  "12% of symbols failed",
+		Severity: SeverityCritical,
+		Fields:   map[string]interface{}{"threshold": 5, "rule_type": "failed_symbols_ratio"},
+	})
+	want := "🚨 Alert: Failed symbols\n12% of symbols failed\nrule_type: failed_symbols_ratio\nthreshold: 5"
+	if text != want {
+		t.Errorf("formatTelegramNotification() = %q; want %q", text, want)
+	}
+}
+
+func TestCrawlRunSummary(t *testing.T) {
+	started := time.Date(2026, 2, 3, 11, 0, 0, 0, time.UTC)
+	finished := primitive.NewDateTimeFromTime(started.Add(95 * time.Second))
+	run := &models.CrawlRun{
+		ID:               primitive.NewObjectID(),
+		Kind:             models.CrawlRunKindFull,
+		Status:           models.CrawlRunStatusSuccess,
+		StartedAt:        primitive.NewDateTimeFromTime(started),
+		FinishedAt:       &finished,
+		TotalSymbols:     1600,
+		SucceededSymbols: 1598,
+		FailedSymbols:    2,
+		Errors:           []models.CrawlSymbolError{{Code: "AAA"}, {Code: "BBB"}},
+	}
+
+	n := crawlRunSummary(run)
+	if n.Severity != SeverityWarning || n.Message != "1598/1600 symbols succeeded, 2 failed in 1m35s" {
+		t.Errorf("summary = %q (%s)", n.Message, n.Severity)
+	}
+	if n.Fields["failed"] != "AAA, BBB" {
+		t.Errorf("failed field = %v", n.Fields["failed"])
+	}
+
+	run.Status, run.Message = models.CrawlRunStatusFailed, "failed to fetch stock list"
+	if n := crawlRunSummary(run); n.Severity != SeverityCritical || !strings.Contains(n.Title, "failed") {
+		t.Errorf("failed run summary = %+v", n)
+	}
+}
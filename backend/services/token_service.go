@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+)
+
+// Token types carried in the "typ" claim
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+
+	tokenIssuer = "cpls-backend"
+)
+
+var (
+	// ErrInvalidToken is returned when a token is malformed, has a bad signature or wrong type
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrExpiredToken is returned when a token's exp claim is in the past
+	ErrExpiredToken = errors.New("token has expired")
+	// ErrAdminInactive is returned when the admin user of a token was deactivated
+	ErrAdminInactive = errors.New("admin user is deactivated")
+)
+
+// TokenClaims are the JWT claims issued for API access
+type TokenClaims struct {
+	Subject   string `json:"sub"`  // Admin user ID
+	Name      string `json:"name"` // Username or email
+	Role      string `json:"role"`
+	Type      string `json:"typ"` // access or refresh
+	Issuer    string `json:"iss"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+	ID        string `json:"jti"`
+}
+
+// TokenPair is returned when tokens are issued or refreshed
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// TokenService issues and verifies HS256 JSON Web Tokens
+type TokenService struct {
+	signingKey []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	admins     AdminStatus
+	now        func() time.Time
+}
+
+// NewTokenService creates a TokenService with an explicit key and lifetimes,
+// checking token holders against admins
+func NewTokenService(signingKey []byte, accessTTL, refreshTTL time.Duration, admins AdminStatus) *TokenService {
+	return &TokenService{
+		signingKey: signingKey,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		admins:     admins,
+		now:        time.Now,
+	}
+}
+
+// NewTokenServiceFromEnv creates a TokenService configured from JWT_SECRET,
+// JWT_ACCESS_TTL (default 15m) and JWT_REFRESH_TTL (default 168h)
+func NewTokenServiceFromEnv(admins AdminStatus) (*TokenService, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("JWT_SECRET environment variable not set")
+	}
+
+	accessTTL, err := durationFromEnv("JWT_ACCESS_TTL", 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	refreshTTL, err := durationFromEnv("JWT_REFRESH_TTL", 7*24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTokenService([]byte(secret), accessTTL, refreshTTL, admins), nil
+}
+
+// durationFromEnv parses a positive duration from an environment variable
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a positive duration like 15m or 24h", name, raw)
+	}
+	return d, nil
+}
+
+// IssueTokens creates a new access/refresh token pair for an admin user
+func (s *TokenService) IssueTokens(adminUser *models.AdminUser) (*TokenPair, error) {
+	name := adminUser.Email
+	if adminUser.Username != nil && *adminUser.Username != "" {
+		name = *adminUser.Username
+	}
+
+	access, err := s.sign(adminUser.ID.String(), name, adminUser.Role, TokenTypeAccess, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := s.sign(adminUser.ID.String(), name, adminUser.Role, TokenTypeRefresh, s.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+	}, nil
+}
+
+// Verify checks the signature, expiry and type of a token and returns its claims
+func (s *TokenService) Verify(token, expectedType string) (*TokenClaims, error) {
+	var claims TokenClaims
+	if err := verifyHS256(token, s.signingKey, &claims); err != nil {
+		return nil, err
+	}
+	if claims.Issuer != tokenIssuer || claims.Type != expectedType || claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+
+	return &claims, nil
+}
+
+// CheckActive returns ErrAdminInactive when the admin user of a verified
+// token was deactivated, and ErrInvalidToken when it no longer exists. An
+// access token outlives the deactivation until it expires, so every request
+// is checked.
+func (s *TokenService) CheckActive(ctx context.Context, claims *TokenClaims) error {
+	adminUserID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return ErrInvalidToken
+	}
+	active, err := s.admins.AdminActive(ctx, adminUserID)
+	if errors.Is(err, ErrAdminUserNotFound) {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if !active {
+		return ErrAdminInactive
+	}
+	return nil
+}
+
+// sign builds and signs a token with the given claims
+func (s *TokenService) sign(subject, name, role, tokenType string, ttl time.Duration) (string, error) {
+	now := s.now()
+	claims := TokenClaims{
+		Subject:   subject,
+		Name:      name,
+		Role:      role,
+		Type:      tokenType,
+		Issuer:    tokenIssuer,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+		ID:        uuid.NewString(),
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token header: %w", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(hs256(s.signingKey, unsigned)), nil
+}
+
+// verifyHS256 checks the signature of an HS256 JWT and decodes its claims.
+// Expiry and claim contents are left to the caller.
+func verifyHS256(token string, key []byte, claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return ErrInvalidToken
+	}
+
+	expected := hs256(key, parts[0]+"."+parts[1])
+	actual, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(expected, actual) {
+		return ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return ErrInvalidToken
+	}
+
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// hs256 computes the HMAC-SHA256 of the signing input
+func hs256(key []byte, input string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(input))
+	return mac.Sum(nil)
+}
+
+// decodeSegment base64url-decodes and unmarshals a JWT segment
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+)
+
+func testAdminUser() *models.AdminUser {
+	username := "admin"
+	return &models.AdminUser{
+		ID:       uuid.New(),
+		Email:    "admin@cpls.com",
+		Username: &username,
+		Role:     "super_admin",
+	}
+}
+
+func TestTokenServiceIssueAndVerify(t *testing.T) {
+	ts := NewTokenService([]byte("test-secret"), 15*time.Minute, time.Hour, nil)
+	admin := testAdminUser()
+
+	pair, err := ts.IssueTokens(admin)
+	if err != nil {
+		t.Fatalf("IssueTokens() unexpected error: %v", err)
+	}
+	if pair.TokenType != "Bearer" || pair.ExpiresIn != 900 {
+		t.Errorf("IssueTokens() = %+v; want Bearer token expiring in 900s", pair)
+	}
+
+	claims, err := ts.Verify(pair.AccessToken, TokenTypeAccess)
+	if err != nil {
+		t.Fatalf("Verify(access) unexpected error: %v", err)
+	}
+	if claims.Subject != admin.ID.String() || claims.Name != "admin" || claims.Role != "super_admin" {
+		t.Errorf("Verify(access) claims = %+v; want subject %s, name admin, role super_admin", claims, admin.ID)
+	}
+
+	if _, err := ts.Verify(pair.RefreshToken, TokenTypeAccess); err != ErrInvalidToken {
+		t.Errorf("Verify(refresh as access) error = %v; want ErrInvalidToken", err)
+	}
+	if _, err := ts.Verify(pair.RefreshToken, TokenTypeRefresh); err != nil {
+		t.Errorf("Verify(refresh) unexpected error: %v", err)
+	}
+}
+
+func TestTokenServiceRejectsTampering(t *testing.T) {
+	ts := NewTokenService([]byte("test-secret"), 15*time.Minute, time.Hour, nil)
+	pair, _ := ts.IssueTokens(testAdminUser())
+
+	other := NewTokenService([]byte("other-secret"), 15*time.Minute, time.Hour, nil)
+	if _, err := other.Verify(pair.AccessToken, TokenTypeAccess); err != ErrInvalidToken {
+		t.Errorf("Verify with wrong key error = %v; want ErrInvalidToken", err)
+	}
+
+	parts := strings.Split(pair.AccessToken, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	if _, err := ts.Verify(tampered, TokenTypeAccess); err != ErrInvalidToken {
+		t.Errorf("Verify(tampered) error = %v; want ErrInvalidToken", err)
+	}
+
+	for _, token := range []string{"", "abc", "a.b", "a.b.c"} {
+		if _, err := ts.Verify(token, TokenTypeAccess); err != ErrInvalidToken {
+			t.Errorf("Verify(%q) error = %v; want ErrInvalidToken", token, err)
+		}
+	}
+}
+
+func TestTokenServiceExpiry(t *testing.T) {
+	ts := NewTokenService([]byte("test-secret"), 15*time.Minute, time.Hour, nil)
+	issuedAt := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	ts.now = func() time.Time { return issuedAt }
+
+	pair, _ := ts.IssueTokens(testAdminUser())
+
+	ts.now = func() time.Time { return issuedAt.Add(16 * time.Minute) }
+	if _, err := ts.Verify(pair.AccessToken, TokenTypeAccess); err != ErrExpiredToken {
+		t.Errorf("Verify(expired access) error = %v; want ErrExpiredToken", err)
+	}
+	if _, err := ts.Verify(pair.RefreshToken, TokenTypeRefresh); err != nil {
+		t.Errorf("Verify(refresh) unexpected error: %v", err)
+	}
+}
+
+// signTestToken signs arbitrary claims the way Supabase Auth does (HS256)
+func signTestToken(t *testing.T, key []byte, claims map[string]interface{}) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(hs256(key, unsigned))
+}
+
+// profileStatuses is a ProfileStatus of the active state of profiles by ID
+type profileStatuses map[uuid.UUID]bool
+
+func (p profileStatuses) ProfileActive(ctx context.Context, profileID uuid.UUID) (bool, error) {
+	active, ok := p[profileID]
+	if !ok {
+		return false, ErrProfileNotFound
+	}
+	return active, nil
+}
+
+func TestMemberAuthServiceVerify(t *testing.T) {
+	secret := []byte("supabase-secret")
+	ms := NewMemberAuthService(secret, profileStatuses{})
+	now := time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)
+	ms.now = func() time.Time { return now }
+
+	profileID := uuid.NewString()
+	token := signTestToken(t, secret, map[string]interface{}{
+		"sub":          profileID,
+		"email":        "member@example.com",
+		"aud":          "authenticated",
+		"exp":          now.Add(time.Hour).Unix(),
+		"app_metadata": map[string]interface{}{"role": "user", "membership": "premium"},
+	})
+
+	claims, err := ms.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify() unexpected error: %v", err)
+	}
+	if claims.Subject != profileID || claims.AppMetadata.Membership != "premium" {
+		t.Errorf("Verify() claims = %+v; want subject %s with premium membership", claims, profileID)
+	}
+
+	ms.now = func() time.Time { return now.Add(2 * time.Hour) }
+	if _, err := ms.Verify(token); err != ErrExpiredToken {
+		t.Errorf("Verify(expired) error = %v; want ErrExpiredToken", err)
+	}
+
+	// Admin API tokens signed with the same secret are not member tokens
+	ts := NewTokenService(secret, 15*time.Minute, time.Hour, nil)
+	pair, _ := ts.IssueTokens(testAdminUser())
+	if _, err := ms.Verify(pair.AccessToken); err != ErrInvalidToken {
+		t.Errorf("Verify(admin token) error = %v; want ErrInvalidToken", err)
+	}
+}
+
+func TestMemberAuthServiceCheckActive(t *testing.T) {
+	active, inactive := uuid.New(), uuid.New()
+	ms := NewMemberAuthService([]byte("supabase-secret"), profileStatuses{active: true, inactive: false})
+
+	tests := []struct {
+		subject string
+		want    error
+	}{
+		{active.String(), nil},
+		{inactive.String(), ErrProfileInactive},
+		{uuid.NewString(), nil}, // No profile yet
+		{"not-a-uuid", ErrInvalidToken},
+	}
+	for _, tt := range tests {
+		if err := ms.CheckActive(context.Background(), &MemberClaims{Subject: tt.subject}); !errors.Is(err, tt.want) {
+			t.Errorf("CheckActive(%s) error = %v; want %v", tt.subject, err, tt.want)
+		}
+	}
+}
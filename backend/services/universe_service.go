@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/markettime"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrUniverseSnapshotNotFound is returned when no snapshot exists on or before a requested date
+	ErrUniverseSnapshotNotFound = errors.New("no universe snapshot on or before the requested date")
+	// ErrInvalidUniverseRange is returned for malformed or reversed diff dates
+	ErrInvalidUniverseRange = errors.New("invalid universe diff range")
+)
+
+// UniverseService keeps daily snapshots of the stock universe and compares them
+type UniverseService struct {
+	snapshotCollection *mongo.Collection
+}
+
+// NewUniverseService creates a new UniverseService instance
+func NewUniverseService() *UniverseService {
+	return &UniverseService{
+		snapshotCollection: config.GetCollection("universe_snapshots"),
+	}
+}
+
+// universeDate returns the market date of t as YYYY-MM-DD
+func universeDate(t time.Time) string {
+	return markettime.Date(t)
+}
+
+// SaveSnapshot stores the crawled stock list as the snapshot of the day it
+// was taken, replacing an earlier snapshot of the same day
+func (s *UniverseService) SaveSnapshot(ctx context.Context, stocks []models.Stock, takenAt time.Time) (*models.UniverseSnapshot, error) {
+	snapshot := models.NewUniverseSnapshot(universeDate(takenAt), primitive.NewDateTimeFromTime(takenAt), stocks)
+
+	opts := options.Replace().SetUpsert(true)
+	if _, err := s.snapshotCollection.ReplaceOne(ctx, bson.M{"_id": snapshot.Date}, snapshot, opts); err != nil {
+		return nil, fmt.Errorf("failed to save universe snapshot: %w", err)
+	}
+	return snapshot, nil
+}
+
+// ListSnapshots returns the dates and sizes of the stored snapshots, newest first
+func (s *UniverseService) ListSnapshots(ctx context.Context) ([]models.UniverseSnapshot, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetProjection(bson.M{"stocks": 0})
+	cursor, err := s.snapshotCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query universe snapshots: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	snapshots := make([]models.UniverseSnapshot, 0)
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to decode universe snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// Diff compares the universe on two dates (YYYY-MM-DD). Each date uses the
+// latest snapshot taken on or before it, so weekends and holidays resolve to
+// the previous crawl.
+func (s *UniverseService) Diff(ctx context.Context, from, to string) (*models.UniverseDiff, error) {
+	fromDate, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("%w: from must be YYYY-MM-DD", ErrInvalidUniverseRange)
+	}
+	toDate, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("%w: to must be YYYY-MM-DD", ErrInvalidUniverseRange)
+	}
+	if toDate.Before(fromDate) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidUniverseRange)
+	}
+
+	before, err := s.snapshotOnOrBefore(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.snapshotOnOrBefore(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := models.DiffUniverse(before.Stocks, after.Stocks)
+	diff.From, diff.To = from, to
+	diff.FromSnapshot, diff.ToSnapshot = before.Date, after.Date
+	return diff, nil
+}
+
+// snapshotOnOrBefore returns the latest snapshot taken on or before date
+func (s *UniverseService) snapshotOnOrBefore(ctx context.Context, date string) (*models.UniverseSnapshot, error) {
+	var snapshot models.UniverseSnapshot
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})
+	err := s.snapshotCollection.FindOne(ctx, bson.M{"_id": bson.M{"$lte": date}}, opts).Decode(&snapshot)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %s", ErrUniverseSnapshotNotFound, date)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch universe snapshot for %s: %w", date, err)
+	}
+	return &snapshot, nil
+}
@@ -1,13 +1,27 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 
 	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
 	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+// userLog logs lookups made outside of a request
+var userLog = logging.Component("users")
+
+// emptyTableHint explains why a listing of a table that should have rows
+// came back empty
+const emptyTableHint = "the table is empty, RLS is blocking access, or the connection uses the wrong database or schema"
+
+// maxLoginHistoryLimit caps the logins returned by one history query
+const maxLoginHistoryLimit = 200
+
 // UserService handles user-related business logic
 type UserService struct{}
 
@@ -16,146 +30,105 @@ func NewUserService() *UserService {
 	return &UserService{}
 }
 
-// GetAdminUsers retrieves all admin users from the admin_users table
-// This function includes detailed logging for debugging purposes
-func (s *UserService) GetAdminUsers() ([]models.AdminUser, error) {
-	log.Println("=== GetAdminUsers: Starting query ===")
-
+// GetAdminUsers retrieves all admin users from the admin_users table,
+// warning when none are found
+func (s *UserService) GetAdminUsers(ctx context.Context) ([]models.AdminUser, error) {
+	logger := logging.FromContext(ctx)
 	var adminUsers []models.AdminUser
-
-	// Get database instance with debug mode enabled
-	db := config.GetDB()
-
-	// Execute query with detailed logging
-	result := db.Find(&adminUsers)
-
-	// Check for errors
-	if result.Error != nil {
-		log.Printf("❌ GetAdminUsers: Database error: %v", result.Error)
-		return nil, fmt.Errorf("failed to fetch admin users: %w", result.Error)
-	}
-
-	// Log results
-	log.Printf("✓ GetAdminUsers: Found %d admin users", result.RowsAffected)
-	for i, user := range adminUsers {
-		username := "N/A"
-		if user.Username != nil {
-			username = *user.Username
-		}
-		fullName := "N/A"
-		if user.FullName != nil {
-			fullName = *user.FullName
-		}
-		log.Printf("  [%d] ID: %s, Email: %s, Username: %s, FullName: %s, Role: %s, Active: %v",
-			i+1, user.ID, user.Email, username, fullName, user.Role, user.Active)
+	if err := config.GetDBWithContext(ctx).Find(&adminUsers).Error; err != nil {
+		logger.Error("Failed to fetch admin users", logging.FieldError, err)
+		return nil, fmt.Errorf("failed to fetch admin users: %w", err)
 	}
 
+	logger.Debug("Fetched admin users", "count", len(adminUsers))
 	if len(adminUsers) == 0 {
-		log.Println("⚠ GetAdminUsers: No admin users found in database")
-		log.Println("  Possible reasons:")
-		log.Println("  1. The admin_users table is empty")
-		log.Println("  2. RLS (Row Level Security) is blocking access")
-		log.Println("  3. Schema or table name is incorrect")
-		log.Println("  4. Database connection is using wrong database/schema")
-		log.Println("  Check the SQL query above to see what was executed.")
+		logger.Warn("No admin users found", "hint", emptyTableHint)
 	}
-
 	return adminUsers, nil
 }
 
-// GetProfiles retrieves all user profiles from the profiles table
-// This function includes detailed logging for debugging purposes
-func (s *UserService) GetProfiles() ([]models.Profile, error) {
-	log.Println("=== GetProfiles: Starting query ===")
-
-	var profiles []models.Profile
-
-	// Get database instance with debug mode enabled
-	db := config.GetDB()
-
-	// Execute query with detailed logging
-	result := db.Find(&profiles)
+// ProfileActive implements ProfileStatus with the profile's active column
+func (s *UserService) ProfileActive(ctx context.Context, profileID uuid.UUID) (bool, error) {
+	if config.PostgresDB == nil {
+		return false, fmt.Errorf("%w: %s", config.ErrStoreUnavailable, config.StorePostgres)
+	}
+	var profile models.Profile
+	err := config.GetDBWithContext(ctx).Select("id", "active").First(&profile, "id = ?", profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, ErrProfileNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up profile: %w", err)
+	}
+	return profile.Active, nil
+}
 
-	// Check for errors
-	if result.Error != nil {
-		log.Printf("❌ GetProfiles: Database error: %v", result.Error)
-		return nil, fmt.Errorf("failed to fetch profiles: %w", result.Error)
+// AdminActive implements AdminStatus with the admin user's active column.
+// Lockouts are not considered: they stop new logins, while failed logins
+// by anyone must not end an admin's running sessions.
+func (s *UserService) AdminActive(ctx context.Context, adminUserID uuid.UUID) (bool, error) {
+	if config.PostgresDB == nil {
+		return false, fmt.Errorf("%w: %s", config.ErrStoreUnavailable, config.StorePostgres)
+	}
+	var adminUser models.AdminUser
+	err := config.GetDBWithContext(ctx).Select("id", "active").First(&adminUser, "id = ?", adminUserID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, ErrAdminUserNotFound
 	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up admin user: %w", err)
+	}
+	return adminUser.Active, nil
+}
 
-	// Log results
-	log.Printf("✓ GetProfiles: Found %d profiles", result.RowsAffected)
-	for i, profile := range profiles {
-		fullName := ""
-		if profile.FullName != nil {
-			fullName = *profile.FullName
-		}
-		nickname := ""
-		if profile.Nickname != nil {
-			nickname = *profile.Nickname
-		}
-		log.Printf("  [%d] ID: %s, Email: %s, Phone: %s, Name: %s, Nickname: %s, Membership: %s",
-			i+1, profile.ID, profile.Email, profile.PhoneNumber, fullName, nickname, profile.Membership)
+// GetProfiles retrieves all user profiles from the profiles table, warning
+// when none are found
+func (s *UserService) GetProfiles(ctx context.Context) ([]models.Profile, error) {
+	logger := logging.FromContext(ctx)
+	var profiles []models.Profile
+	if err := config.GetDBWithContext(ctx).Find(&profiles).Error; err != nil {
+		logger.Error("Failed to fetch profiles", logging.FieldError, err)
+		return nil, fmt.Errorf("failed to fetch profiles: %w", err)
 	}
 
+	logger.Debug("Fetched profiles", "count", len(profiles))
 	if len(profiles) == 0 {
-		log.Println("⚠ GetProfiles: No profiles found in database")
-		log.Println("  Possible reasons:")
-		log.Println("  1. The profiles table is empty")
-		log.Println("  2. RLS (Row Level Security) is blocking access")
-		log.Println("  3. Schema or table name is incorrect")
-		log.Println("  4. Database connection is using wrong database/schema")
-		log.Println("  Check the SQL query above to see what was executed.")
+		logger.Warn("No profiles found", "hint", emptyTableHint)
 	}
-
 	return profiles, nil
 }
 
 // GetAdminUserByID retrieves a single admin user by ID
 func (s *UserService) GetAdminUserByID(id string) (*models.AdminUser, error) {
-	log.Printf("=== GetAdminUserByID: Looking for ID: %s ===", id)
-
 	var adminUser models.AdminUser
-	db := config.GetDB()
-
-	result := db.First(&adminUser, "id = ?", id)
-	if result.Error != nil {
-		log.Printf("❌ GetAdminUserByID: Error: %v", result.Error)
-		return nil, fmt.Errorf("failed to fetch admin user: %w", result.Error)
+	if err := config.GetDB().First(&adminUser, "id = ?", id).Error; err != nil {
+		userLog.Warn("Failed to fetch admin user", "admin_user_id", id, logging.FieldError, err)
+		return nil, fmt.Errorf("failed to fetch admin user: %w", err)
 	}
-
-	log.Printf("✓ GetAdminUserByID: Found user: %s (%s)", adminUser.Email, adminUser.Username)
 	return &adminUser, nil
 }
 
 // GetProfileByID retrieves a single profile by ID
 func (s *UserService) GetProfileByID(id string) (*models.Profile, error) {
-	log.Printf("=== GetProfileByID: Looking for ID: %s ===", id)
-
 	var profile models.Profile
-	db := config.GetDB()
-
-	result := db.First(&profile, "id = ?", id)
-	if result.Error != nil {
-		log.Printf("❌ GetProfileByID: Error: %v", result.Error)
-		return nil, fmt.Errorf("failed to fetch profile: %w", result.Error)
+	if err := config.GetDB().First(&profile, "id = ?", id).Error; err != nil {
+		userLog.Warn("Failed to fetch profile", "profile_id", id, logging.FieldError, err)
+		return nil, fmt.Errorf("failed to fetch profile: %w", err)
 	}
-
-	log.Printf("✓ GetProfileByID: Found profile: %s", profile.Email)
 	return &profile, nil
 }
 
 // GetProfilesWithPagination retrieves profiles with pagination support
-func (s *UserService) GetProfilesWithPagination(page, pageSize int) ([]models.Profile, int64, error) {
-	log.Printf("=== GetProfilesWithPagination: Page %d, PageSize %d ===", page, pageSize)
-
+func (s *UserService) GetProfilesWithPagination(ctx context.Context, page, pageSize int) ([]models.Profile, int64, error) {
+	logger := logging.FromContext(ctx)
 	var profiles []models.Profile
 	var total int64
 
-	db := config.GetDB()
+	db := config.GetDBWithContext(ctx)
 
 	// Get total count
 	if err := db.Model(&models.Profile{}).Count(&total).Error; err != nil {
-		log.Printf("❌ GetProfilesWithPagination: Count error: %v", err)
+		logger.Error("Failed to count profiles", logging.FieldError, err)
 		return nil, 0, fmt.Errorf("failed to count profiles: %w", err)
 	}
 
@@ -165,26 +138,25 @@ func (s *UserService) GetProfilesWithPagination(page, pageSize int) ([]models.Pr
 	// Get paginated results
 	result := db.Offset(offset).Limit(pageSize).Find(&profiles)
 	if result.Error != nil {
-		log.Printf("❌ GetProfilesWithPagination: Query error: %v", result.Error)
+		logger.Error("Failed to fetch profiles", "page", page, "page_size", pageSize, logging.FieldError, result.Error)
 		return nil, 0, fmt.Errorf("failed to fetch profiles: %w", result.Error)
 	}
 
-	log.Printf("✓ GetProfilesWithPagination: Found %d of %d total profiles", len(profiles), total)
+	logger.Debug("Fetched profiles", "page", page, "count", len(profiles), "total", total)
 	return profiles, total, nil
 }
 
 // GetAdminUsersWithPagination retrieves admin users with pagination support
-func (s *UserService) GetAdminUsersWithPagination(page, pageSize int) ([]models.AdminUser, int64, error) {
-	log.Printf("=== GetAdminUsersWithPagination: Page %d, PageSize %d ===", page, pageSize)
-
+func (s *UserService) GetAdminUsersWithPagination(ctx context.Context, page, pageSize int) ([]models.AdminUser, int64, error) {
+	logger := logging.FromContext(ctx)
 	var adminUsers []models.AdminUser
 	var total int64
 
-	db := config.GetDB()
+	db := config.GetDBWithContext(ctx)
 
 	// Get total count
 	if err := db.Model(&models.AdminUser{}).Count(&total).Error; err != nil {
-		log.Printf("❌ GetAdminUsersWithPagination: Count error: %v", err)
+		logger.Error("Failed to count admin users", logging.FieldError, err)
 		return nil, 0, fmt.Errorf("failed to count admin users: %w", err)
 	}
 
@@ -194,10 +166,37 @@ func (s *UserService) GetAdminUsersWithPagination(page, pageSize int) ([]models.
 	// Get paginated results
 	result := db.Offset(offset).Limit(pageSize).Find(&adminUsers)
 	if result.Error != nil {
-		log.Printf("❌ GetAdminUsersWithPagination: Query error: %v", result.Error)
+		logger.Error("Failed to fetch admin users", "page", page, "page_size", pageSize, logging.FieldError, result.Error)
 		return nil, 0, fmt.Errorf("failed to fetch admin users: %w", result.Error)
 	}
 
-	log.Printf("✓ GetAdminUsersWithPagination: Found %d of %d total admin users", len(adminUsers), total)
+	logger.Debug("Fetched admin users", "page", page, "count", len(adminUsers), "total", total)
 	return adminUsers, total, nil
 }
+
+// GetLoginHistory returns the most recent successful logins of an admin,
+// newest first, or ErrAdminUserNotFound when the admin does not exist
+func (s *UserService) GetLoginHistory(ctx context.Context, adminUserID string, limit int) ([]models.LoginHistory, error) {
+	if limit <= 0 || limit > maxLoginHistoryLimit {
+		limit = maxLoginHistoryLimit
+	}
+	if _, err := uuid.Parse(adminUserID); err != nil {
+		return nil, ErrAdminUserNotFound
+	}
+	db := config.GetDBWithContext(ctx)
+
+	var count int64
+	if err := db.Model(&models.AdminUser{}).Where("id = ?", adminUserID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up admin user: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrAdminUserNotFound
+	}
+
+	var history []models.LoginHistory
+	err := db.Where("admin_user_id = ?", adminUserID).Order("created_at DESC").Limit(limit).Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch login history: %w", err)
+	}
+	return history, nil
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/vndirect"
+)
+
+// VNDirectSource fetches Vietnamese listings (HOSE, HNX, UPCOM) from the
+// VNDirect finfo API
+type VNDirectSource struct {
+	client *vndirect.Client
+}
+
+// NewVNDirectSource creates a new VNDirect data source
+func NewVNDirectSource() *VNDirectSource {
+	return NewVNDirectSourceWithConfig(vndirect.DefaultConfig())
+}
+
+// NewVNDirectSourceWithConfig creates a VNDirect data source with a client
+// configured by cfg. Every attempt, retries included, counts against the
+// provider quota, and responses are shared through the provider response
+// cache.
+func NewVNDirectSourceWithConfig(cfg vndirect.Config) *VNDirectSource {
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	cfg.Transport = quotaCountingTransport{provider: models.DataSourceVNDirect, next: cfg.Transport}
+	cfg.ResponseCache = ProviderCache().Fetch
+	return &VNDirectSource{client: vndirect.New(cfg)}
+}
+
+// Name implements MarketDataSource
+func (s *VNDirectSource) Name() string {
+	return models.DataSourceVNDirect
+}
+
+// FetchSymbols fetches the listed stocks of the given exchanges
+func (s *VNDirectSource) FetchSymbols(exchanges []string) ([]models.Stock, error) {
+	return s.FetchInstruments(exchanges, []string{models.InstrumentStock})
+}
+
+// FetchInstruments implements InstrumentLister. The instrument types are
+// named alike in the finfo API.
+func (s *VNDirectSource) FetchInstruments(exchanges, types []string) ([]models.Stock, error) {
+	rows, err := s.client.Listed(context.Background(), types, exchanges, 0)
+	if err != nil {
+		return nil, vndirectError(err)
+	}
+
+	stocks := make([]models.Stock, 0, len(rows))
+	for _, item := range rows {
+		stocks = append(stocks, models.Stock{
+			Code:          item.Code,
+			CompanyName:   item.CompanyName,
+			CompanyNameEn: strings.TrimSpace(item.CompanyNameEng),
+			Exchange:      item.Exchange,
+			Type:          strings.ToLower(item.Type),
+			Status:        item.Status,
+			Underlying:    strings.ToUpper(strings.TrimSpace(item.UnderlyingSymbol)),
+			Issuer:        strings.TrimSpace(item.IssuerName),
+			ExercisePrice: item.ExercisePrice,
+			ExerciseRatio: strings.TrimSpace(item.ExerciseRatio),
+			MaturityDate:  item.MaturityDate,
+			CouponRate:    item.CouponRate,
+		})
+	}
+
+	return stocks, nil
+}
+
+// vndirectHistorySessions is how many daily candles a full fetch returns
+const vndirectHistorySessions = 270
+
+// FetchPrices fetches the last ~270 daily candles of a stock
+func (s *VNDirectSource) FetchPrices(stock models.Stock) ([]models.CandleData, error) {
+	return s.fetchPrices(stock, vndirectHistorySessions)
+}
+
+// FetchRecentPrices implements RecentPriceFetcher
+func (s *VNDirectSource) FetchRecentPrices(stock models.Stock, sessions int) ([]models.CandleData, error) {
+	return s.fetchPrices(stock, sessions)
+}
+
+// fetchPrices fetches the newest daily candles of a stock
+func (s *VNDirectSource) fetchPrices(stock models.Stock, sessions int) ([]models.CandleData, error) {
+	rows, err := s.client.StockPrices(context.Background(), stock.Code, sessions)
+	if err != nil {
+		return nil, vndirectError(err)
+	}
+
+	candles := make([]models.CandleData, 0, len(rows))
+	for _, item := range rows {
+		candle := models.CandleData{
+			D: item.Date,
+			O: item.Open,
+			H: item.High,
+			L: item.Low,
+			C: item.Close,
+			V: item.Volume,
+		}
+		candles = append(candles, candle)
+	}
+
+	return candles, nil
+}
+
+// FetchIndexPrices implements IndexPriceFetcher
+func (s *VNDirectSource) FetchIndexPrices(code string, sessions int) ([]models.CandleData, error) {
+	rows, err := s.client.IndexPrices(context.Background(), code, sessions)
+	if err != nil {
+		return nil, vndirectError(err)
+	}
+
+	candles := make([]models.CandleData, 0, len(rows))
+	for _, item := range rows {
+		candles = append(candles, models.CandleData{
+			D: item.Date,
+			O: item.Open,
+			H: item.High,
+			L: item.Low,
+			C: item.Close,
+			V: int64(item.AccumulatedVol),
+		})
+	}
+	return candles, nil
+}
+
+// FetchSectors implements SectorClassifier with the ICB level 2 sectors
+func (s *VNDirectSource) FetchSectors() (map[string]string, error) {
+	industries, err := s.client.Industries(context.Background(), 2)
+	if err != nil {
+		return nil, vndirectError(err)
+	}
+	return industrySectors(industries), nil
+}
+
+// FetchClassification implements IndustryClassifier with the ICB level 1
+// industries and level 2 sectors
+func (s *VNDirectSource) FetchClassification() (map[string]models.IndustryClassification, error) {
+	ctx := context.Background()
+	industries, err := s.client.Industries(ctx, 1)
+	if err != nil {
+		return nil, vndirectError(err)
+	}
+	sectors, err := s.client.Industries(ctx, 2)
+	if err != nil {
+		return nil, vndirectError(err)
+	}
+	return industryClassification(industries, sectors), nil
+}
+
+// FetchSharesOutstanding implements ShareCountFetcher with the latest
+// outstanding shares ratio
+func (s *VNDirectSource) FetchSharesOutstanding() (map[string]int64, error) {
+	ratios, err := s.client.LatestRatios(context.Background(), vndirect.RatioOutstandingShares)
+	if err != nil {
+		return nil, vndirectError(err)
+	}
+	shares := make(map[string]int64, len(ratios))
+	for _, ratio := range ratios {
+		if code := strings.ToUpper(strings.TrimSpace(ratio.Code)); code != "" && ratio.Value > 0 {
+			shares[code] = int64(ratio.Value)
+		}
+	}
+	return shares, nil
+}
+
+// FetchFuturesContracts implements FuturesSource
+func (s *VNDirectSource) FetchFuturesContracts(underlying string) ([]models.FuturesContract, error) {
+	rows, err := s.client.FuturesContracts(context.Background(), underlying)
+	if err != nil {
+		return nil, vndirectError(err)
+	}
+	contracts := make([]models.FuturesContract, 0, len(rows))
+	for _, item := range rows {
+		contracts = append(contracts, models.FuturesContract{
+			Code:             strings.ToUpper(strings.TrimSpace(item.Code)),
+			Underlying:       strings.ToUpper(strings.TrimSpace(item.UnderlyingSymbol)),
+			FirstTradingDate: item.FirstTradingDate,
+			LastTradingDate:  item.LastTradingDate,
+			Multiplier:       item.Multiplier,
+		})
+	}
+	return contracts, nil
+}
+
+// FetchFuturesPrices implements FuturesSource
+func (s *VNDirectSource) FetchFuturesPrices(code string, sessions int) ([]models.FuturesCandle, error) {
+	rows, err := s.client.FuturesPrices(context.Background(), code, sessions)
+	if err != nil {
+		return nil, vndirectError(err)
+	}
+	candles := make([]models.FuturesCandle, 0, len(rows))
+	for _, item := range rows {
+		candles = append(candles, models.FuturesCandle{
+			D:  item.Date,
+			O:  item.Open,
+			H:  item.High,
+			L:  item.Low,
+			C:  item.Close,
+			V:  item.Volume,
+			OI: item.OpenInterest,
+		})
+	}
+	return candles, nil
+}
+
+// vndirectError reports a VNDirect failure as ErrProviderThrottled when it
+// was rate limited
+func vndirectError(err error) error {
+	if errors.Is(err, vndirect.ErrThrottled) {
+		return fmt.Errorf("%w: %v", ErrProviderThrottled, err)
+	}
+	return err
+}
+
+// industrySectors maps every member code to its sector's English name
+func industrySectors(industries []vndirect.Industry) map[string]string {
+	sectors := make(map[string]string)
+	for _, industry := range industries {
+		name := industryName(industry)
+		if name == "" {
+			continue
+		}
+		for _, code := range industry.Members() {
+			sectors[code] = name
+		}
+	}
+	return sectors
+}
+
+// industryClassification maps every member code of the level 2 sectors to
+// its sector, sector code and the level 1 industry listing it. Codes only in
+// an industry are left out: the sector is what the stock list filters on.
+func industryClassification(industries, sectors []vndirect.Industry) map[string]models.IndustryClassification {
+	industryOf := industrySectors(industries)
+	classification := make(map[string]models.IndustryClassification)
+	for _, sector := range sectors {
+		name := industryName(sector)
+		if name == "" {
+			continue
+		}
+		for _, code := range sector.Members() {
+			classification[code] = models.IndustryClassification{
+				Industry: industryOf[code],
+				Sector:   name,
+				ICBCode:  strings.TrimSpace(sector.IndustryCode),
+			}
+		}
+	}
+	return classification
+}
+
+// industryName returns the English name of an industry, or the Vietnamese
+// one when it has none
+func industryName(industry vndirect.Industry) string {
+	if name := strings.TrimSpace(industry.EnglishName); name != "" {
+		return name
+	}
+	return strings.TrimSpace(industry.VietnameseName)
+}
+
+// HealthCheck implements ProviderHealthChecker by listing a single HOSE
+// stock, bypassing the provider response cache
+func (s *VNDirectSource) HealthCheck(ctx context.Context) error {
+	stocks, err := s.client.Uncached().ListedStocks(ctx, []string{"HOSE"}, 1)
+	if err != nil {
+		var status *vndirect.StatusError
+		if errors.As(err, &status) {
+			return vndirectError(err)
+		}
+		return fmt.Errorf("failed to reach VNDirect: %w", err)
+	}
+	if len(stocks) == 0 {
+		return fmt.Errorf("VNDirect returned no stocks")
+	}
+	return nil
+}
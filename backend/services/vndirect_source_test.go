@@ -0,0 +1,41 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/datvt88/CPLS/backend/vndirect"
+)
+
+func TestVNDirectSourceErrors(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(`{"data": [{"code": "HPG", "date": "2026-03-02", "close": 25.5, "volume": 1000}]}`))
+	}))
+	defer server.Close()
+	source := NewVNDirectSourceWithConfig(vndirect.Config{BaseURL: server.URL})
+
+	tests := []struct {
+		status    int
+		wantErr   bool
+		throttled bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusTooManyRequests, true, true},
+		{http.StatusBadGateway, true, false},
+	}
+	for _, tt := range tests {
+		status = tt.status
+		candles, err := source.FetchRecentPrices(models.Stock{Code: "HPG"}, 5)
+		if (err != nil) != tt.wantErr || errors.Is(err, ErrProviderThrottled) != tt.throttled {
+			t.Errorf("FetchRecentPrices() with status %d = %v; want error %v, throttled %v", tt.status, err, tt.wantErr, tt.throttled)
+		}
+		if err == nil && (len(candles) != 1 || candles[0].C != 25.5 || candles[0].V != 1000) {
+			t.Errorf("FetchRecentPrices() = %v; want the HPG candle", candles)
+		}
+	}
+}
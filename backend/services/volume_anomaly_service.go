@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// anomalyLog logs volume anomaly detection after crawl runs
+var anomalyLog = logging.Component("volume_anomalies")
+
+const (
+	// anomalyBatch caps the symbols whose candles are read at once
+	anomalyBatch = 200
+	// defaultAnomalyLimit and maxAnomalyLimit bound the anomalies listed
+	defaultAnomalyLimit = 100
+	maxAnomalyLimit     = 500
+)
+
+// VolumeAnomalyFilter selects stored volume anomalies (empty fields match
+// everything)
+type VolumeAnomalyFilter struct {
+	From     string // YYYY-MM-DD, inclusive
+	To       string
+	Code     string
+	Exchange string
+	MinRatio float64
+	Limit    int
+}
+
+// ParseVolumeAnomalyFilter reads a filter from the from, to (as for sector
+// breadth: to defaults to today and from to 30 days before), code, exchange,
+// min_ratio and limit query parameters
+func ParseVolumeAnomalyFilter(query func(string) string, now time.Time) (VolumeAnomalyFilter, error) {
+	filter := VolumeAnomalyFilter{
+		Code:     strings.ToUpper(strings.TrimSpace(query("code"))),
+		Exchange: strings.ToUpper(strings.TrimSpace(query("exchange"))),
+		Limit:    defaultAnomalyLimit,
+	}
+	var err error
+	if filter.From, filter.To, err = ParseBreadthRange(query("from"), query("to"), now); err != nil {
+		return filter, err
+	}
+	if raw := query("min_ratio"); raw != "" {
+		ratio, err := strconv.ParseFloat(raw, 64)
+		if err != nil || ratio < 0 {
+			return filter, fmt.Errorf("min_ratio: expected a non-negative number")
+		}
+		filter.MinRatio = ratio
+	}
+	if raw := query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 || limit > maxAnomalyLimit {
+			return filter, fmt.Errorf("limit: expected 1 to %d", maxAnomalyLimit)
+		}
+		filter.Limit = limit
+	}
+	return filter, nil
+}
+
+// VolumeAnomalyService flags the sessions of unusual volume after each
+// crawl and keeps them in the volume_anomalies collection
+type VolumeAnomalyService struct {
+	anomalyCollection *mongo.Collection
+	stockService      PriceStore
+	readCache         *cache.Cache // Shared with the stock service
+}
+
+// NewVolumeAnomalyService creates a new VolumeAnomalyService instance
+func NewVolumeAnomalyService(stockService *StockService) *VolumeAnomalyService {
+	return &VolumeAnomalyService{
+		anomalyCollection: config.GetCollection("volume_anomalies"),
+		stockService:      stockService,
+		readCache:         stockService.readCache,
+	}
+}
+
+// DetectRun checks the new candle of every symbol in a finished crawl run.
+// A symbol's earlier anomaly of the same date is replaced, or removed when
+// the re-crawled candle is no longer one. It is registered as a crawl run
+// listener.
+func (s *VolumeAnomalyService) DetectRun(run *models.CrawlRun, newDates map[string]string) {
+	if len(newDates) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	detected, err := s.Detect(ctx, newDates)
+	s.readCache.Invalidate(ctx, cache.NamespaceIndicators)
+	if err != nil {
+		anomalyLog.Warn("Failed to detect volume anomalies", "run_id", run.ID.Hex(), logging.FieldError, err)
+		return
+	}
+	anomalyLog.Info("Volume anomalies detected", "anomalies", detected, "symbols", len(newDates), "run_id", run.ID.Hex())
+}
+
+// Detect checks the candle of each code on its date (code -> date) and
+// stores the anomalies. It returns how many were found.
+func (s *VolumeAnomalyService) Detect(ctx context.Context, dates map[string]string) (int, error) {
+	cfg := config.Runtime()
+	byDate := make(map[string][]string)
+	for code, date := range dates {
+		byDate[date] = append(byDate[date], code)
+	}
+
+	detectedAt := primitive.NewDateTimeFromTime(time.Now())
+	detected := 0
+	for date, codes := range byDate {
+		sort.Strings(codes)
+		for start := 0; start < len(codes); start += anomalyBatch {
+			end := start + anomalyBatch
+			if end > len(codes) {
+				end = len(codes)
+			}
+			batch := codes[start:end]
+			candles, err := s.stockService.CandlesAsOf(ctx, batch, date, models.VolumeSpikeWindow+1)
+			if err != nil {
+				return detected, err
+			}
+			stocks, err := s.stockService.GetStocks(ctx, batch)
+			if err != nil {
+				return detected, err
+			}
+
+			writes := make([]mongo.WriteModel, 0, len(batch))
+			for _, code := range batch {
+				history := candles[code]
+				if len(history) == 0 || history[len(history)-1].D != date {
+					continue
+				}
+				anomaly, ok := models.DetectVolumeAnomaly(code, history, cfg.AnomalyVolumeMultiple, cfg.AnomalyMinAvgVolume, detectedAt)
+				if !ok {
+					writes = append(writes, mongo.NewDeleteOneModel().SetFilter(bson.M{"_id": code + "_" + date}))
+					continue
+				}
+				anomaly.Exchange, anomaly.Sector = stocks[code].Exchange, stocks[code].Sector
+				writes = append(writes, mongo.NewReplaceOneModel().
+					SetFilter(bson.M{"_id": anomaly.ID}).
+					SetReplacement(anomaly).
+					SetUpsert(true))
+				detected++
+			}
+			if len(writes) == 0 {
+				continue
+			}
+			if _, err := s.anomalyCollection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+				return detected, fmt.Errorf("failed to save volume anomalies: %w", err)
+			}
+		}
+	}
+	return detected, nil
+}
+
+// List returns the stored anomalies matching filter, newest date first and
+// by ratio within a date. Results are cached in the indicators namespace.
+func (s *VolumeAnomalyService) List(ctx context.Context, filter VolumeAnomalyFilter) ([]models.VolumeAnomaly, error) {
+	key := fmt.Sprintf("anomalies:%s:%s:%s:%s:%g:%d", filter.From, filter.To, filter.Code, filter.Exchange, filter.MinRatio, filter.Limit)
+	return cache.Fetch(ctx, s.readCache, cache.NamespaceIndicators, key, func(ctx context.Context) ([]models.VolumeAnomaly, error) {
+		return s.list(ctx, filter)
+	})
+}
+
+// list runs the List query
+func (s *VolumeAnomalyService) list(ctx context.Context, filter VolumeAnomalyFilter) ([]models.VolumeAnomaly, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	cursor, err := s.anomalyCollection.Find(ctx, volumeAnomalyQuery(filter), options.Find().
+		SetSort(bson.D{{Key: "date", Value: -1}, {Key: "ratio", Value: -1}}).
+		SetLimit(int64(filter.Limit)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query volume anomalies: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	anomalies := make([]models.VolumeAnomaly, 0)
+	if err := cursor.All(ctx, &anomalies); err != nil {
+		return nil, fmt.Errorf("failed to decode volume anomalies: %w", err)
+	}
+	return anomalies, nil
+}
+
+// volumeAnomalyQuery builds the Mongo filter of a volume anomaly listing
+func volumeAnomalyQuery(filter VolumeAnomalyFilter) bson.M {
+	query := bson.M{"date": bson.M{"$gte": filter.From, "$lte": filter.To}}
+	if filter.Code != "" {
+		query["code"] = filter.Code
+	}
+	if filter.Exchange != "" {
+		query["exchange"] = filter.Exchange
+	}
+	if filter.MinRatio > 0 {
+		query["ratio"] = bson.M{"$gte": filter.MinRatio}
+	}
+	return query
+}
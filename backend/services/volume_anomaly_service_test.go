@@ -0,0 +1,41 @@
+package services
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseVolumeAnomalyFilter(t *testing.T) {
+	now := time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC)
+	filter, err := ParseVolumeAnomalyFilter(url.Values{"code": {"hpg"}, "exchange": {"hose"}, "min_ratio": {"4"}}.Get, now)
+	want := VolumeAnomalyFilter{From: "2026-02-01", To: "2026-03-03", Code: "HPG", Exchange: "HOSE", MinRatio: 4, Limit: defaultAnomalyLimit}
+	if err != nil || filter != want {
+		t.Errorf("ParseVolumeAnomalyFilter() = %+v, %v; want %+v", filter, err, want)
+	}
+
+	for _, bad := range []url.Values{
+		{"from": {"yesterday"}},
+		{"min_ratio": {"-1"}},
+		{"limit": {"501"}},
+	} {
+		if _, err := ParseVolumeAnomalyFilter(bad.Get, now); err == nil {
+			t.Errorf("ParseVolumeAnomalyFilter(%v) succeeded; want error", bad)
+		}
+	}
+}
+
+func TestVolumeAnomalyQuery(t *testing.T) {
+	got := volumeAnomalyQuery(VolumeAnomalyFilter{From: "2026-03-01", To: "2026-03-03", Exchange: "HNX", MinRatio: 5})
+	want := bson.M{
+		"date":     bson.M{"$gte": "2026-03-01", "$lte": "2026-03-03"},
+		"exchange": "HNX",
+		"ratio":    bson.M{"$gte": 5.0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("volumeAnomalyQuery() = %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+)
+
+// warmupLog logs the startup warm-up steps
+var warmupLog = logging.Component("warmup")
+
+// Outcomes of a warm-up step
+const (
+	WarmupOK      = "ok"
+	WarmupFailed  = "failed"
+	WarmupSkipped = "skipped"
+)
+
+// warmupAttempts and warmupBackoff bound the retries of a failing step: the
+// waits double from warmupBackoff between attempts
+const (
+	warmupAttempts = 5
+	warmupBackoff  = 500 * time.Millisecond
+)
+
+// WarmupStep is one task run on instance start before the server accepts
+// requests. Run returning errWarmupSkipped reports the step as skipped.
+type WarmupStep struct {
+	Name  string
+	Retry bool // Retry failures up to warmupAttempts times
+	Run   func(ctx context.Context) error
+}
+
+// WarmupResult is the outcome of one step
+type WarmupResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // ok, failed or skipped
+	Attempts   int    `json:"attempts"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WarmupReport is the outcome of the warm-up routine
+type WarmupReport struct {
+	Steps      []WarmupResult `json:"steps"`
+	DurationMs int64          `json:"duration_ms"`
+}
+
+// Failed returns the names of the steps that failed
+func (r *WarmupReport) Failed() []string {
+	var failed []string
+	for _, step := range r.Steps {
+		if step.Status == WarmupFailed {
+			failed = append(failed, step.Name)
+		}
+	}
+	return failed
+}
+
+// errWarmupSkipped is returned by steps whose dependency is not configured
+var errWarmupSkipped = errors.New("not configured")
+
+// Warmup runs steps in order, retrying those marked Retry with doubling
+// waits, until each succeeds or ctx ends. A failed step is logged and the
+// next one still runs: the instance starts degraded rather than not at all.
+func Warmup(ctx context.Context, steps []WarmupStep) *WarmupReport {
+	return warmup(ctx, steps, warmupBackoff)
+}
+
+func warmup(ctx context.Context, steps []WarmupStep, backoff time.Duration) *WarmupReport {
+	started := time.Now()
+	report := &WarmupReport{Steps: make([]WarmupResult, 0, len(steps))}
+	for _, step := range steps {
+		stepStarted := time.Now()
+		result := WarmupResult{Name: step.Name, Status: WarmupOK}
+		attempts := 1
+		if step.Retry {
+			attempts = warmupAttempts
+		}
+		wait := backoff
+		var err error
+		for {
+			result.Attempts++
+			err = step.Run(ctx)
+			if err == nil || errors.Is(err, errWarmupSkipped) || result.Attempts >= attempts {
+				break
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				err = fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+			case <-timer.C:
+				wait *= 2
+				continue
+			}
+			break
+		}
+		switch {
+		case errors.Is(err, errWarmupSkipped):
+			result.Status = WarmupSkipped
+		case err != nil:
+			result.Status, result.Error = WarmupFailed, err.Error()
+		}
+		result.DurationMs = time.Since(stepStarted).Milliseconds()
+		report.Steps = append(report.Steps, result)
+
+		switch result.Status {
+		case WarmupOK:
+			warmupLog.Info("Warm-up step done", "step", step.Name, "duration_ms", result.DurationMs)
+		case WarmupSkipped:
+			warmupLog.Info("Warm-up step skipped", "step", step.Name, "reason", err.Error())
+		default:
+			warmupLog.Warn("Warm-up step failed", "step", step.Name, "attempts", result.Attempts, logging.FieldError, err)
+		}
+	}
+	report.DurationMs = time.Since(started).Milliseconds()
+	return report
+}
+
+// PostgresWarmupStep verifies the Supabase connection pool
+func PostgresWarmupStep() WarmupStep {
+	return WarmupStep{Name: "postgres", Retry: true, Run: func(ctx context.Context) error {
+		if config.PostgresDB == nil {
+			return errWarmupSkipped
+		}
+		sqlDB, err := config.PostgresDB.DB()
+		if err != nil {
+			return err
+		}
+		return pingWithTimeout(ctx, sqlDB.PingContext)
+	}}
+}
+
+// MongoWarmupStep verifies the MongoDB connection
+func MongoWarmupStep() WarmupStep {
+	return WarmupStep{Name: "mongodb", Retry: true, Run: func(ctx context.Context) error {
+		if config.MongoClient == nil {
+			return errWarmupSkipped
+		}
+		return pingWithTimeout(ctx, func(ctx context.Context) error { return config.MongoClient.Ping(ctx, nil) })
+	}}
+}
+
+// StockListWarmupStep pre-loads the cached stock list served by
+// GET /api/stocks/metadata
+func StockListWarmupStep(stockService *StockService) WarmupStep {
+	return WarmupStep{Name: "stock_list", Retry: true, Run: func(ctx context.Context) error {
+		if config.MongoClient == nil {
+			return errWarmupSkipped
+		}
+		_, err := stockService.GetStockMetadata(ctx, nil)
+		return err
+	}}
+}
+
+// pingWithTimeout bounds one ping attempt so that a hung connection is retried
+func pingWithTimeout(ctx context.Context, ping func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return ping(ctx)
+}
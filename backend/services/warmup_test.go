@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWarmupRetriesAndContinues(t *testing.T) {
+	calls := map[string]int{}
+	flaky := func(ctx context.Context) error {
+		if calls["flaky"]++; calls["flaky"] < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	steps := []WarmupStep{
+		{Name: "flaky", Retry: true, Run: flaky},
+		{Name: "broken", Retry: true, Run: func(context.Context) error { calls["broken"]++; return errors.New("down") }},
+		{Name: "once", Run: func(context.Context) error { calls["once"]++; return errors.New("bad template") }},
+		{Name: "absent", Retry: true, Run: func(context.Context) error { calls["absent"]++; return errWarmupSkipped }},
+	}
+
+	report := warmup(context.Background(), steps, time.Millisecond)
+
+	statuses := make([]string, 0, len(report.Steps))
+	for _, step := range report.Steps {
+		statuses = append(statuses, step.Status)
+	}
+	if want := []string{WarmupOK, WarmupFailed, WarmupFailed, WarmupSkipped}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v; want %v", statuses, want)
+	}
+	if want := map[string]int{"flaky": 3, "broken": warmupAttempts, "once": 1, "absent": 1}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v; want %v", calls, want)
+	}
+	if got := report.Failed(); !reflect.DeepEqual(got, []string{"broken", "once"}) {
+		t.Errorf("Failed() = %v; want [broken once]", got)
+	}
+}
+
+func TestWarmupStopsRetryingWhenContextEnds(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	steps := []WarmupStep{{Name: "slow", Retry: true, Run: func(context.Context) error { return errors.New("timeout") }}}
+
+	started := time.Now()
+	report := warmup(ctx, steps, time.Hour)
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("warmup() took %s; want it to stop at the context deadline", elapsed)
+	}
+	if step := report.Steps[0]; step.Status != WarmupFailed || step.Attempts != 1 {
+		t.Errorf("step = %+v; want failed after 1 attempt", step)
+	}
+}
@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrWatchlistNotFound is returned when a watchlist does not exist or belongs to another member
+	ErrWatchlistNotFound = errors.New("watchlist not found")
+	// ErrInvalidWatchlist is returned when a watchlist name or its symbols are rejected
+	ErrInvalidWatchlist = errors.New("invalid watchlist")
+	// ErrWatchlistLimit is returned when a member or a list is at its maximum size
+	ErrWatchlistLimit = errors.New("watchlist limit reached")
+)
+
+// WatchlistService manages members' watchlists and their quotes
+type WatchlistService struct {
+	stockService PriceStore
+}
+
+// NewWatchlistService creates a new WatchlistService instance
+func NewWatchlistService(stockService PriceStore) *WatchlistService {
+	return &WatchlistService{stockService: stockService}
+}
+
+// ListWatchlists returns a member's watchlists with their codes, ordered by creation time
+func (s *WatchlistService) ListWatchlists(ctx context.Context, profileID uuid.UUID) ([]models.Watchlist, error) {
+	db := config.GetDBWithContext(ctx)
+	watchlists := make([]models.Watchlist, 0)
+	if err := db.Where("profile_id = ?", profileID).Order("created_at ASC").Find(&watchlists).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch watchlists: %w", err)
+	}
+	if len(watchlists) == 0 {
+		return watchlists, nil
+	}
+
+	ids := make([]uuid.UUID, len(watchlists))
+	for i, watchlist := range watchlists {
+		ids[i] = watchlist.ID
+	}
+	var symbols []models.WatchlistSymbol
+	if err := db.Where("watchlist_id IN ?", ids).Order("position ASC").Find(&symbols).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch watchlist symbols: %w", err)
+	}
+	codes := make(map[uuid.UUID][]string, len(watchlists))
+	for _, symbol := range symbols {
+		codes[symbol.WatchlistID] = append(codes[symbol.WatchlistID], symbol.Code)
+	}
+	for i := range watchlists {
+		watchlists[i].Codes = codes[watchlists[i].ID]
+		if watchlists[i].Codes == nil {
+			watchlists[i].Codes = []string{}
+		}
+	}
+	return watchlists, nil
+}
+
+// GetWatchlist returns one of a member's watchlists with its codes
+func (s *WatchlistService) GetWatchlist(ctx context.Context, profileID uuid.UUID, id string) (*models.Watchlist, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrWatchlistNotFound
+	}
+	db := config.GetDBWithContext(ctx)
+	var watchlist models.Watchlist
+	err := db.First(&watchlist, "id = ? AND profile_id = ?", id, profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWatchlistNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch watchlist: %w", err)
+	}
+
+	watchlist.Codes = []string{}
+	if err := db.Model(&models.WatchlistSymbol{}).
+		Where("watchlist_id = ?", watchlist.ID).
+		Order("position ASC").
+		Pluck("code", &watchlist.Codes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch watchlist symbols: %w", err)
+	}
+	return &watchlist, nil
+}
+
+// CreateWatchlist stores a new watchlist for a member, optionally with codes
+func (s *WatchlistService) CreateWatchlist(ctx context.Context, profileID uuid.UUID, name string, codes []string) (*models.Watchlist, error) {
+	name, err := models.NormalizeWatchlistName(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWatchlist, err)
+	}
+	codes, err = s.checkCodes(ctx, codes, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	db := config.GetDBWithContext(ctx)
+	var count int64
+	if err := db.Model(&models.Watchlist{}).Where("profile_id = ?", profileID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to count watchlists: %w", err)
+	}
+	if count >= models.MaxWatchlistsPerProfile {
+		return nil, fmt.Errorf("%w: a member can keep at most %d watchlists", ErrWatchlistLimit, models.MaxWatchlistsPerProfile)
+	}
+
+	now := time.Now().UTC()
+	watchlist := &models.Watchlist{
+		ID:        uuid.New(),
+		ProfileID: profileID,
+		Name:      name,
+		Codes:     codes,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(watchlist).Error; err != nil {
+			return fmt.Errorf("failed to create watchlist: %w", err)
+		}
+		return appendWatchlistSymbols(tx, watchlist.ID, codes, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return watchlist, nil
+}
+
+// RenameWatchlist changes the name of a member's watchlist
+func (s *WatchlistService) RenameWatchlist(ctx context.Context, profileID uuid.UUID, id, name string) (*models.Watchlist, error) {
+	name, err := models.NormalizeWatchlistName(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWatchlist, err)
+	}
+	watchlist, err := s.GetWatchlist(ctx, profileID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	watchlist.Name = name
+	watchlist.UpdatedAt = time.Now().UTC()
+	if err := config.GetDBWithContext(ctx).Model(watchlist).
+		Select("name", "updated_at").
+		Updates(watchlist).Error; err != nil {
+		return nil, fmt.Errorf("failed to rename watchlist: %w", err)
+	}
+	return watchlist, nil
+}
+
+// DeleteWatchlist removes a member's watchlist and its symbols
+func (s *WatchlistService) DeleteWatchlist(ctx context.Context, profileID uuid.UUID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrWatchlistNotFound
+	}
+	result := config.GetDBWithContext(ctx).Delete(&models.Watchlist{}, "id = ? AND profile_id = ?", id, profileID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete watchlist: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWatchlistNotFound
+	}
+	return nil
+}
+
+// AddSymbols appends codes to the end of a member's watchlist; codes already
+// on the list keep their position
+func (s *WatchlistService) AddSymbols(ctx context.Context, profileID uuid.UUID, id string, codes []string) (*models.Watchlist, error) {
+	watchlist, err := s.GetWatchlist(ctx, profileID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	onList := make(map[string]bool, len(watchlist.Codes))
+	for _, code := range watchlist.Codes {
+		onList[code] = true
+	}
+	added := make([]string, 0, len(codes))
+	for _, code := range models.NormalizeStockCodes(codes) {
+		if !onList[code] {
+			added = append(added, code)
+		}
+	}
+	if added, err = s.checkCodes(ctx, added, len(watchlist.Codes)); err != nil {
+		return nil, err
+	}
+	if len(added) == 0 {
+		return watchlist, nil
+	}
+
+	now := time.Now().UTC()
+	err = config.GetDBWithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := appendWatchlistSymbols(tx, watchlist.ID, added, now); err != nil {
+			return err
+		}
+		return tx.Model(watchlist).UpdateColumn("updated_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	watchlist.Codes = append(watchlist.Codes, added...)
+	watchlist.UpdatedAt = now
+	return watchlist, nil
+}
+
+// RemoveSymbol removes a code from a member's watchlist
+func (s *WatchlistService) RemoveSymbol(ctx context.Context, profileID uuid.UUID, id, code string) (*models.Watchlist, error) {
+	watchlist, err := s.GetWatchlist(ctx, profileID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	code = strings.ToUpper(strings.TrimSpace(code))
+	result := config.GetDBWithContext(ctx).Delete(&models.WatchlistSymbol{}, "watchlist_id = ? AND code = ?", watchlist.ID, code)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to remove watchlist symbol: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: %s is not on the watchlist", ErrInvalidWatchlist, code)
+	}
+
+	remaining := make([]string, 0, len(watchlist.Codes))
+	for _, listed := range watchlist.Codes {
+		if listed != code {
+			remaining = append(remaining, listed)
+		}
+	}
+	watchlist.Codes = remaining
+	return watchlist, nil
+}
+
+// Quotes returns the latest candle and day change of every symbol on a
+// member's watchlist, in list order
+func (s *WatchlistService) Quotes(ctx context.Context, profileID uuid.UUID, id string) (*models.Watchlist, []models.Quote, error) {
+	watchlist, err := s.GetWatchlist(ctx, profileID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	quotes := make([]models.Quote, 0, len(watchlist.Codes))
+	if len(watchlist.Codes) == 0 {
+		return watchlist, quotes, nil
+	}
+
+	stocks, err := s.stockService.GetStocks(ctx, watchlist.Codes)
+	if err != nil {
+		return nil, nil, err
+	}
+	candles, err := s.stockService.LatestCandles(ctx, watchlist.Codes, 2)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, code := range watchlist.Codes {
+		var stock *models.Stock
+		if listed, ok := stocks[code]; ok {
+			stock = &listed
+		}
+		quotes = append(quotes, models.NewQuote(code, stock, candles[code]))
+	}
+	return watchlist, quotes, nil
+}
+
+// checkCodes normalizes codes, checks that a list holding existing codes has
+// room for them and that every code is a listed stock
+func (s *WatchlistService) checkCodes(ctx context.Context, codes []string, existing int) ([]string, error) {
+	codes = models.NormalizeStockCodes(codes)
+	if existing+len(codes) > models.MaxWatchlistSymbols {
+		return nil, fmt.Errorf("%w: a watchlist holds at most %d symbols", ErrWatchlistLimit, models.MaxWatchlistSymbols)
+	}
+	if len(codes) == 0 {
+		return codes, nil
+	}
+
+	stocks, err := s.stockService.GetStocks(ctx, codes)
+	if err != nil {
+		return nil, err
+	}
+	var unknown []string
+	for _, code := range codes {
+		if _, ok := stocks[code]; !ok {
+			unknown = append(unknown, code)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: unknown stock codes %s", ErrInvalidWatchlist, strings.Join(unknown, ", "))
+	}
+	return codes, nil
+}
+
+// appendWatchlistSymbols adds codes after the last symbol of a watchlist
+func appendWatchlistSymbols(tx *gorm.DB, watchlistID uuid.UUID, codes []string, now time.Time) error {
+	if len(codes) == 0 {
+		return nil
+	}
+	var first int
+	if err := tx.Model(&models.WatchlistSymbol{}).
+		Where("watchlist_id = ?", watchlistID).
+		Select("COALESCE(MAX(position) + 1, 0)").
+		Scan(&first).Error; err != nil {
+		return fmt.Errorf("failed to read watchlist positions: %w", err)
+	}
+	symbols := make([]models.WatchlistSymbol, len(codes))
+	for i, code := range codes {
+		symbols[i] = models.WatchlistSymbol{WatchlistID: watchlistID, Code: code, Position: first + i, AddedAt: now}
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&symbols).Error; err != nil {
+		return fmt.Errorf("failed to add watchlist symbols: %w", err)
+	}
+	return nil
+}
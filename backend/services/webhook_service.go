@@ -0,0 +1,470 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// webhookLog logs webhook dispatching
+var webhookLog = logging.Component("webhooks")
+
+const (
+	webhookSecretPrefix = "whsec_"
+	// webhookDispatchInterval is how often due deliveries are looked for
+	webhookDispatchInterval = 10 * time.Second
+	// webhookDispatchBatch caps the deliveries claimed per pass
+	webhookDispatchBatch = 20
+	// webhookRequestTimeout bounds one delivery request, connecting included
+	webhookRequestTimeout = 10 * time.Second
+	// webhookClaimLease keeps claimed deliveries from being picked up by
+	// another instance while they are sent one after another: it covers a
+	// batch of requests that all time out, plus a margin for saving them
+	webhookClaimLease       = webhookDispatchBatch*webhookRequestTimeout + time.Minute
+	maxWebhookDeliveryLimit = 500
+)
+
+var (
+	// ErrWebhookNotFound is returned when a webhook endpoint ID does not exist
+	ErrWebhookNotFound = errors.New("webhook endpoint not found")
+	// ErrWebhookDeliveryNotFound is returned when a delivery ID does not exist
+	ErrWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+// WebhookPayload is the JSON body posted to endpoints
+type WebhookPayload struct {
+	ID        string      `json:"id"` // Event ID, shared by the deliveries of one event
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDeliveryFilter selects deliveries of the log
+type WebhookDeliveryFilter struct {
+	EndpointID string
+	Status     string
+	Event      string
+	Limit      int
+}
+
+// WebhookService manages outbound webhook endpoints and delivers events to
+// them with retries
+type WebhookService struct {
+	client *resty.Client
+	wake   chan struct{} // Signals the dispatcher that new deliveries are queued
+}
+
+// NewWebhookService creates a new WebhookService instance. Endpoint URLs
+// are partner-controlled, so the client only dials public addresses after
+// DNS resolution and does not follow redirects.
+func NewWebhookService() *WebhookService {
+	client := newRestyClient()
+	client.SetTransport(newWebhookTransport())
+	client.SetRedirectPolicy(resty.RedirectPolicyFunc(func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}))
+	client.SetTimeout(webhookRequestTimeout)
+	client.SetHeader("User-Agent", "CPLS-Webhooks/1.0")
+
+	return &WebhookService{client: client, wake: make(chan struct{}, 1)}
+}
+
+// ListEndpoints returns all endpoints ordered by creation time
+func (s *WebhookService) ListEndpoints(ctx context.Context) ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	if err := config.GetDBWithContext(ctx).Order("created_at ASC").Find(&endpoints).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook endpoints: %w", err)
+	}
+	return endpoints, nil
+}
+
+// CreateEndpoint validates and stores a new endpoint with a generated
+// signing secret, which is returned in plaintext
+func (s *WebhookService) CreateEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) (string, error) {
+	if err := endpoint.Validate(); err != nil {
+		return "", err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	endpoint.ID = uuid.New()
+	endpoint.Secret = webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	if err := config.GetDBWithContext(ctx).Create(endpoint).Error; err != nil {
+		return "", fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return endpoint.Secret, nil
+}
+
+// UpdateEndpoint replaces the editable fields of an existing endpoint
+func (s *WebhookService) UpdateEndpoint(ctx context.Context, id string, changes models.WebhookEndpoint) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.getEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.URL = changes.URL
+	endpoint.Description = changes.Description
+	endpoint.Events = changes.Events
+	endpoint.Enabled = changes.Enabled
+	if err := endpoint.Validate(); err != nil {
+		return nil, err
+	}
+
+	endpoint.UpdatedAt = time.Now().UTC()
+	if err := config.GetDBWithContext(ctx).Model(endpoint).
+		Select("url", "description", "events", "enabled", "updated_at").
+		Updates(endpoint).Error; err != nil {
+		return nil, fmt.Errorf("failed to update webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+// DeleteEndpoint removes an endpoint and its delivery log
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, id string) error {
+	result := config.GetDBWithContext(ctx).Delete(&models.WebhookEndpoint{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete webhook endpoint: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// getEndpoint loads an endpoint by ID
+func (s *WebhookService) getEndpoint(ctx context.Context, id string) (*models.WebhookEndpoint, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	var endpoint models.WebhookEndpoint
+	err := config.GetDBWithContext(ctx).First(&endpoint, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook endpoint: %w", err)
+	}
+	return &endpoint, nil
+}
+
+// Publish queues an event for every enabled endpoint subscribed to it. The
+// dispatcher delivers it in the background.
+func (s *WebhookService) Publish(ctx context.Context, event string, data interface{}) error {
+	var endpoints []models.WebhookEndpoint
+	if err := config.GetDBWithContext(ctx).Where("enabled = ?", true).Find(&endpoints).Error; err != nil {
+		return fmt.Errorf("failed to fetch webhook endpoints: %w", err)
+	}
+
+	subscribed := make([]models.WebhookEndpoint, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		if endpoint.Subscribes(event) {
+			subscribed = append(subscribed, endpoint)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+	_, err := s.enqueue(ctx, event, data, subscribed)
+	return err
+}
+
+// Ping queues a test event for one endpoint, whether or not it is enabled
+func (s *WebhookService) Ping(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	endpoint, err := s.getEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := s.enqueue(ctx, models.WebhookEventPing, map[string]string{"endpoint_id": endpoint.ID.String()},
+		[]models.WebhookEndpoint{*endpoint})
+	if err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// enqueue stores one pending delivery of the event per endpoint
+func (s *WebhookService) enqueue(ctx context.Context, event string, data interface{}, endpoints []models.WebhookEndpoint) ([]models.WebhookDelivery, error) {
+	now := time.Now().UTC()
+	body, err := json.Marshal(WebhookPayload{ID: uuid.NewString(), Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	deliveries := make([]models.WebhookDelivery, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:            uuid.New(),
+			EndpointID:    endpoint.ID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+	}
+	if err := config.GetDBWithContext(ctx).Create(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return deliveries, nil
+}
+
+// ListDeliveries returns the delivery log, newest first
+func (s *WebhookService) ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	if filter.Limit <= 0 || filter.Limit > maxWebhookDeliveryLimit {
+		filter.Limit = maxWebhookDeliveryLimit
+	}
+
+	query := config.GetDBWithContext(ctx).Order("created_at DESC").Limit(filter.Limit)
+	if filter.EndpointID != "" {
+		if _, err := s.getEndpoint(ctx, filter.EndpointID); err != nil {
+			return nil, err
+		}
+		query = query.Where("endpoint_id = ?", filter.EndpointID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver queues a delivery again with a fresh set of attempts
+func (s *WebhookService) Redeliver(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	var delivery models.WebhookDelivery
+	err := config.GetDBWithContext(ctx).First(&delivery, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch webhook delivery: %w", err)
+	}
+
+	now := time.Now().UTC()
+	delivery.Status = models.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+	delivery.UpdatedAt = now
+	if err := config.GetDBWithContext(ctx).Model(&delivery).
+		Select("status", "attempts", "next_attempt_at", "updated_at").
+		Updates(&delivery).Error; err != nil {
+		return nil, fmt.Errorf("failed to requeue webhook delivery: %w", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return &delivery, nil
+}
+
+// StartDispatcher delivers queued events until ctx is cancelled
+func (s *WebhookService) StartDispatcher(ctx context.Context) {
+	go func() {
+		webhookLog.Info("Webhook dispatcher started", "interval", webhookDispatchInterval.String())
+		ticker := time.NewTicker(webhookDispatchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				webhookLog.Info("Webhook dispatcher stopped")
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+			// Drain full batches before waiting again
+			for {
+				claimed, err := s.dispatchDue(ctx)
+				if err != nil {
+					webhookLog.Warn("Webhook dispatch pass failed", logging.FieldError, err)
+				}
+				if err != nil || claimed < webhookDispatchBatch {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// dispatchDue claims the deliveries that are due and sends them, returning
+// how many were claimed
+func (s *WebhookService) dispatchDue(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	var due []models.WebhookDelivery
+	err := config.GetDBWithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Order("next_attempt_at ASC").
+			Limit(webhookDispatchBatch).
+			Find(&due).Error
+		if err != nil || len(due) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, 0, len(due))
+		for _, delivery := range due {
+			ids = append(ids, delivery.ID)
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(webhookClaimLease)).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	endpoints := make(map[uuid.UUID]*models.WebhookEndpoint)
+	for i := range due {
+		delivery := &due[i]
+		endpoint, ok := endpoints[delivery.EndpointID]
+		if !ok {
+			endpoint, err = s.getEndpoint(ctx, delivery.EndpointID.String())
+			if err != nil && !errors.Is(err, ErrWebhookNotFound) {
+				return len(due), err
+			}
+			endpoints[delivery.EndpointID] = endpoint
+		}
+		s.attempt(ctx, delivery, endpoint)
+	}
+	return len(due), nil
+}
+
+// attempt sends one delivery and records the outcome, scheduling a retry
+// with backoff on failure
+func (s *WebhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery, endpoint *models.WebhookEndpoint) {
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.UpdatedAt = now
+
+	var statusCode int
+	var sendErr error
+	switch {
+	case endpoint == nil:
+		sendErr = ErrWebhookNotFound
+	case !endpoint.Enabled && delivery.Event != models.WebhookEventPing:
+		sendErr = errors.New("endpoint is disabled")
+	default:
+		statusCode, sendErr = s.send(ctx, endpoint, delivery, now)
+	}
+
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = nil
+	case delivery.Attempts >= models.MaxWebhookAttempts || endpoint == nil || !endpoint.Enabled:
+		delivery.Status = models.WebhookDeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = optionalString(sendErr.Error())
+		webhookLog.Error("Webhook delivery failed", "delivery_id", delivery.ID.String(), "event", delivery.Event, "attempts", delivery.Attempts, logging.FieldError, sendErr)
+	default:
+		next := now.Add(models.WebhookRetryDelay(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+		delivery.LastError = optionalString(sendErr.Error())
+	}
+
+	err := config.GetDBWithContext(ctx).Model(delivery).
+		Select("status", "attempts", "next_attempt_at", "last_status_code", "last_error", "delivered_at", "updated_at").
+		Updates(delivery).Error
+	if err != nil {
+		webhookLog.Warn("Failed to save webhook delivery", "delivery_id", delivery.ID.String(), logging.FieldError, err)
+	}
+}
+
+// send posts the signed payload and returns the response status
+func (s *WebhookService) send(ctx context.Context, endpoint *models.WebhookEndpoint, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetHeader("X-CPLS-Event", delivery.Event).
+		SetHeader("X-CPLS-Delivery", delivery.ID.String()).
+		SetHeader("X-CPLS-Signature", signWebhookPayload([]byte(endpoint.Secret), []byte(delivery.Payload), now)).
+		SetBody(delivery.Payload).
+		Post(endpoint.URL)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.IsError() || resp.StatusCode() >= 300 {
+		// Only the status is kept: the body is the partner's and may echo
+		// anything back into the delivery log
+		return resp.StatusCode(), fmt.Errorf("endpoint returned status %d", resp.StatusCode())
+	}
+	return resp.StatusCode(), nil
+}
+
+// newWebhookTransport returns a transport whose connections are refused
+// unless the resolved address is public, so a host name pointing at an
+// internal service is caught after DNS resolution. Proxies are not used, as
+// the dial would then check the proxy instead of the endpoint.
+func newWebhookTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   webhookRequestTimeout,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("webhook address %q is not allowed", address)
+			}
+			if !models.IsPublicWebhookAddr(addrPort.Addr()) {
+				return fmt.Errorf("webhook address %s is not public", addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+// signWebhookPayload returns the X-CPLS-Signature header: "t=<unix
+// seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">", the same scheme the
+// payment webhook verifies
+func signWebhookPayload(secret, body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	now := time.Date(2026, 2, 3, 10, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":"evt","event":"crawl.completed"}`)
+	header := signWebhookPayload([]byte("whsec_test"), body, now)
+
+	// Receivers verify with the same scheme as the payment webhook
+	if err := verifyPaymentSignature([]byte("whsec_test"), header, body, now.Add(time.Minute)); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+	if err := verifyPaymentSignature([]byte("other"), header, body, now); err == nil {
+		t.Error("signature verified with the wrong secret")
+	}
+	if err := verifyPaymentSignature([]byte("whsec_test"), header, append(body, ' '), now); err == nil {
+		t.Error("signature verified a modified body")
+	}
+}
+
+func TestWebhookSendRefusesInternalAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	// The dialer checks the resolved address, whatever the URL's host is
+	endpoint := &models.WebhookEndpoint{URL: server.URL, Secret: "whsec_test", Enabled: true}
+	delivery := &models.WebhookDelivery{Event: models.WebhookEventPing, Payload: "{}"}
+	status, err := NewWebhookService().send(context.Background(), endpoint, delivery, time.Now())
+	if err == nil || status != 0 {
+		t.Errorf("send() = %d, %v; want the dial refused", status, err)
+	}
+	if called {
+		t.Error("the internal endpoint was reached")
+	}
+}
+
+func TestNewestCandleDate(t *testing.T) {
+	got := newestCandleDate("2026-01-30", []models.CandleData{{D: "2026-02-02"}, {D: "2026-01-29"}})
+	if got != "2026-02-02" {
+		t.Errorf("newestCandleDate() = %s; want 2026-02-02", got)
+	}
+	if got := newestCandleDate("", nil); got != "" {
+		t.Errorf("newestCandleDate() of nothing = %q", got)
+	}
+}
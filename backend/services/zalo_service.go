@@ -0,0 +1,525 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/datvt88/CPLS/backend/models"
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// zaloLog logs Zalo OA messaging and token refreshes
+var zaloLog = logging.Component("zalo")
+
+const (
+	zaloMessageURL = "https://openapi.zalo.me/v3.0/oa/message/cs"
+	zaloTokenURL   = "https://oauth.zaloapp.com/v4/oa/access_token"
+	// zaloTokenProvider is the oauth_tokens row holding the OA tokens
+	zaloTokenProvider = "zalo_oa"
+	// zaloErrInvalidToken is the API error code for an expired or revoked access token
+	zaloErrInvalidToken = -216
+	// zaloTokenRefreshMargin refreshes the access token this long before it expires
+	zaloTokenRefreshMargin = 5 * time.Minute
+	// zaloEventTolerance is how far an OA event's timestamp may be from now,
+	// so a captured event cannot be replayed later; processed events are
+	// remembered twice as long to catch replays within the window
+	zaloEventTolerance = 5 * time.Minute
+	// zaloMaxTextLength is the longest text message the OA API accepts
+	zaloMaxTextLength   = 2000
+	maxZaloMessageLimit = 500
+)
+
+var (
+	// ErrZaloNotConfigured is returned when no Official Account credentials are set
+	ErrZaloNotConfigured = errors.New("zalo official account is not configured")
+	// ErrUnknownZaloTemplate is returned for a template name not in ZaloTemplates
+	ErrUnknownZaloTemplate = errors.New("unknown zalo message template")
+	// ErrInvalidZaloEvent is returned when an OA webhook event is unsigned,
+	// wrongly signed or too old
+	ErrInvalidZaloEvent = errors.New("invalid zalo event signature")
+)
+
+// ZaloTemplates are the message templates (text/template syntax) that can be
+// sent through the Official Account. Member-facing templates are Vietnamese.
+var ZaloTemplates = map[string]string{
+	"alert":       "{{if eq .Severity \"resolved\"}}✅{{else}}⚠️{{end}} {{.Title}}\n{{.Message}}",
+	"price_alert": "🔔 {{.Code}}: {{.Condition}}\nGiá đóng cửa {{.Close}} ngày {{.Date}}",
+	"membership":  "Tài khoản CPLS của bạn đã được nâng cấp lên gói {{.Membership}}{{if .ExpiresAt}} đến hết ngày {{.ExpiresAt}}{{end}}.",
+	"test":        "Tin nhắn kiểm tra từ CPLS ({{.Actor}}).",
+}
+
+var zaloTemplates = func() map[string]*template.Template {
+	parsed := make(map[string]*template.Template, len(ZaloTemplates))
+	for name, text := range ZaloTemplates {
+		parsed[name] = template.Must(template.New(name).Option("missingkey=zero").Parse(text))
+	}
+	return parsed
+}()
+
+// RenderZaloTemplate renders a message template with data, truncated to
+// the longest text the OA API accepts
+func RenderZaloTemplate(name string, data interface{}) (string, error) {
+	tmpl, ok := zaloTemplates[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownZaloTemplate, name)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render zalo template %s: %w", name, err)
+	}
+	text := buf.String()
+	if runes := []rune(text); len(runes) > zaloMaxTextLength {
+		text = string(runes[:zaloMaxTextLength-1]) + "…"
+	}
+	return text, nil
+}
+
+// ZaloConfig holds the Official Account credentials
+type ZaloConfig struct {
+	AppID        string
+	AppSecret    string   // Used to refresh the access token
+	OASecretKey  string   // Verifies OA webhook events
+	AccessToken  string   // Initial token; later tokens are kept in oauth_tokens
+	RefreshToken string   // Initial refresh token
+	AdminUserIDs []string // Zalo user IDs (followers of the OA) that receive alerts
+}
+
+// ZaloConfigFromEnv reads ZALO_APP_ID, ZALO_APP_SECRET, ZALO_OA_SECRET_KEY,
+// ZALO_OA_ACCESS_TOKEN, ZALO_OA_REFRESH_TOKEN and ZALO_ADMIN_USER_IDS
+func ZaloConfigFromEnv() ZaloConfig {
+	return ZaloConfig{
+		AppID:        os.Getenv("ZALO_APP_ID"),
+		AppSecret:    os.Getenv("ZALO_APP_SECRET"),
+		OASecretKey:  os.Getenv("ZALO_OA_SECRET_KEY"),
+		AccessToken:  os.Getenv("ZALO_OA_ACCESS_TOKEN"),
+		RefreshToken: os.Getenv("ZALO_OA_REFRESH_TOKEN"),
+		AdminUserIDs: models.SplitList(os.Getenv("ZALO_ADMIN_USER_IDS")),
+	}
+}
+
+// ZaloMessageFilter selects tracked messages
+type ZaloMessageFilter struct {
+	Status    string
+	ProfileID string
+	Limit     int
+}
+
+// ZaloService sends messages through the Zalo Official Account API and
+// tracks their delivery
+type ZaloService struct {
+	cfg    ZaloConfig
+	client *resty.Client
+
+	mu    sync.Mutex
+	token *models.OAuthToken // Loaded lazily from oauth_tokens or the environment
+	now   func() time.Time
+}
+
+// NewZaloService creates a ZaloService for the given credentials
+func NewZaloService(cfg ZaloConfig) *ZaloService {
+	// No retries: a resent message POST can reach the user twice, and a
+	// resent token refresh spends the single-use refresh token
+	client := newRestyClient()
+	client.SetTimeout(10 * time.Second)
+
+	return &ZaloService{cfg: cfg, client: client, now: time.Now}
+}
+
+// Configured reports whether messages can be sent
+func (s *ZaloService) Configured() bool {
+	return s.cfg.AccessToken != "" || (s.cfg.RefreshToken != "" && s.cfg.AppID != "" && s.cfg.AppSecret != "")
+}
+
+// AdminUserIDs returns the Zalo user IDs that receive operational alerts
+func (s *ZaloService) AdminUserIDs() []string {
+	return s.cfg.AdminUserIDs
+}
+
+// SendToProfile renders a template and sends it to a member's linked Zalo
+// account. Members without a Zalo ID are recorded as no_zalo_id.
+func (s *ZaloService) SendToProfile(ctx context.Context, profileID uuid.UUID, templateName string, data interface{}) (*models.ZaloMessage, error) {
+	var profile models.Profile
+	err := config.GetDBWithContext(ctx).Select("id", "zalo_id").First(&profile, "id = ?", profileID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrProfileNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up profile: %w", err)
+	}
+
+	zaloID := ""
+	if profile.ZaloID != nil {
+		zaloID = strings.TrimSpace(*profile.ZaloID)
+	}
+	return s.Send(ctx, zaloID, &profile.ID, templateName, data)
+}
+
+// Send renders a template, sends it to a Zalo user and records the outcome
+// in zalo_messages. Delivery failures are recorded on the returned message
+// as well as returned.
+func (s *ZaloService) Send(ctx context.Context, zaloUserID string, profileID *uuid.UUID, templateName string, data interface{}) (*models.ZaloMessage, error) {
+	if !s.Configured() {
+		return nil, ErrZaloNotConfigured
+	}
+	text, err := RenderZaloTemplate(templateName, data)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	message := &models.ZaloMessage{
+		ID:         uuid.New(),
+		ProfileID:  profileID,
+		ZaloUserID: zaloUserID,
+		Template:   templateName,
+		Text:       text,
+		Status:     models.ZaloMessagePending,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	db := config.GetDBWithContext(ctx)
+
+	if zaloUserID == "" {
+		message.Status = models.ZaloMessageNoZaloID
+	}
+	if err := db.Create(message).Error; err != nil {
+		return nil, fmt.Errorf("failed to record zalo message: %w", err)
+	}
+	if message.Status == models.ZaloMessageNoZaloID {
+		return message, nil
+	}
+
+	messageID, sendErr := s.deliver(ctx, zaloUserID, text)
+	message.UpdatedAt = s.now().UTC()
+	if sendErr != nil {
+		message.Status = models.ZaloMessageFailed
+		message.Error = optionalString(sendErr.Error())
+	} else {
+		message.Status = models.ZaloMessageSent
+		message.MessageID = optionalString(messageID)
+		message.SentAt = &message.UpdatedAt
+	}
+	if err := db.Model(message).Select("status", "message_id", "error", "sent_at", "updated_at").Updates(message).Error; err != nil {
+		zaloLog.Warn("Failed to save zalo message status", "message_id", message.ID.String(), logging.FieldError, err)
+	}
+	return message, sendErr
+}
+
+// ListMessages returns tracked messages, newest first
+func (s *ZaloService) ListMessages(ctx context.Context, filter ZaloMessageFilter) ([]models.ZaloMessage, error) {
+	if filter.Limit <= 0 || filter.Limit > maxZaloMessageLimit {
+		filter.Limit = maxZaloMessageLimit
+	}
+
+	query := config.GetDBWithContext(ctx).Order("created_at DESC").Limit(filter.Limit)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.ProfileID != "" {
+		query = query.Where("profile_id = ?", filter.ProfileID)
+	}
+
+	var messages []models.ZaloMessage
+	if err := query.Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch zalo messages: %w", err)
+	}
+	return messages, nil
+}
+
+// zaloEvent is the part of an OA webhook event used for delivery tracking
+type zaloEvent struct {
+	AppID     string `json:"app_id"`
+	EventName string `json:"event_name"`
+	Timestamp string `json:"timestamp"`
+	Message   struct {
+		MsgID  string   `json:"msg_id"`
+		MsgIDs []string `json:"msg_ids"`
+	} `json:"message"`
+}
+
+// HandleEvent verifies an OA webhook event and applies delivery and read
+// receipts to the tracked messages. Other events, and events already
+// processed, are ignored.
+func (s *ZaloService) HandleEvent(ctx context.Context, body []byte, signature string) error {
+	if s.cfg.OASecretKey == "" {
+		return ErrZaloNotConfigured
+	}
+	var event zaloEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidZaloEvent, err)
+	}
+	if !verifyZaloEventSignature(s.cfg.AppID, s.cfg.OASecretKey, event.Timestamp, body, signature) {
+		return ErrInvalidZaloEvent
+	}
+	now := s.now().UTC()
+	if err := checkZaloEventTime(event.Timestamp, now); err != nil {
+		return err
+	}
+
+	status, ids, advancesFrom := zaloReceipt(event)
+	if status == "" || len(ids) == 0 {
+		return nil
+	}
+	db := config.GetDBWithContext(ctx)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		seen := models.ZaloEvent{
+			EventID:    strings.ToLower(strings.TrimPrefix(strings.TrimSpace(signature), "mac=")),
+			EventName:  event.EventName,
+			ReceivedAt: now,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seen)
+		if result.Error != nil {
+			return fmt.Errorf("failed to record zalo event: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		err := tx.Model(&models.ZaloMessage{}).
+			Where("message_id IN ? AND status IN ?", ids, advancesFrom).
+			Updates(map[string]interface{}{"status": status, "updated_at": now}).Error
+		if err != nil {
+			return fmt.Errorf("failed to update zalo message status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Older events are rejected by their timestamp, so their IDs can go
+	if err := db.Where("received_at < ?", now.Add(-2*zaloEventTolerance)).Delete(&models.ZaloEvent{}).Error; err != nil {
+		zaloLog.Warn("Failed to prune processed zalo events", logging.FieldError, err)
+	}
+	return nil
+}
+
+// checkZaloEventTime rejects an event whose timestamp (Unix milliseconds)
+// is more than zaloEventTolerance away from now
+func checkZaloEventTime(timestamp string, now time.Time) error {
+	millis, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidZaloEvent)
+	}
+	if age := now.Sub(time.UnixMilli(millis)); age > zaloEventTolerance || age < -zaloEventTolerance {
+		return fmt.Errorf("%w: timestamp outside the %s tolerance", ErrInvalidZaloEvent, zaloEventTolerance)
+	}
+	return nil
+}
+
+// zaloReceipt maps a receipt event to the status it sets, the Zalo message
+// IDs it covers and the statuses it may advance from (never backwards)
+func zaloReceipt(event zaloEvent) (string, []string, []string) {
+	ids := event.Message.MsgIDs
+	if event.Message.MsgID != "" {
+		ids = append(ids, event.Message.MsgID)
+	}
+	switch event.EventName {
+	case "user_received_message":
+		return models.ZaloMessageReceived, ids, []string{models.ZaloMessageSent}
+	case "user_seen_message":
+		return models.ZaloMessageSeen, ids, []string{models.ZaloMessageSent, models.ZaloMessageReceived}
+	}
+	return "", nil, nil
+}
+
+// verifyZaloEventSignature checks the X-ZEvent-Signature header, which is
+// "mac=" followed by the hex SHA-256 of app ID + body + timestamp + OA secret key
+func verifyZaloEventSignature(appID, secretKey, timestamp string, body []byte, signature string) bool {
+	mac := strings.TrimPrefix(strings.TrimSpace(signature), "mac=")
+	if mac == "" || timestamp == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(appID + string(body) + timestamp + secretKey))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(mac))) == 1
+}
+
+// zaloResponse is the envelope of OA API responses; a non-zero Error is a
+// failure even when the HTTP status is 200
+type zaloResponse struct {
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+	Data    struct {
+		MessageID string `json:"message_id"`
+	} `json:"data"`
+}
+
+// deliver posts a text message, refreshing the access token once if Zalo
+// reports it expired
+func (s *ZaloService) deliver(ctx context.Context, zaloUserID, text string) (string, error) {
+	for attempt := 0; ; attempt++ {
+		token, err := s.accessToken(ctx, attempt > 0)
+		if err != nil {
+			return "", err
+		}
+
+		var result zaloResponse
+		resp, err := s.client.R().
+			SetContext(ctx).
+			SetHeader("access_token", token).
+			SetBody(map[string]interface{}{
+				"recipient": map[string]string{"user_id": zaloUserID},
+				"message":   map[string]string{"text": text},
+			}).
+			SetResult(&result).
+			Post(zaloMessageURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to call zalo OA API: %w", err)
+		}
+		if resp.IsError() {
+			return "", fmt.Errorf("zalo OA API returned status %d", resp.StatusCode())
+		}
+		if result.Error == zaloErrInvalidToken && attempt == 0 {
+			continue
+		}
+		if result.Error != 0 {
+			return "", fmt.Errorf("zalo OA API error %d: %s", result.Error, result.Message)
+		}
+		return result.Data.MessageID, nil
+	}
+}
+
+// accessToken returns a usable access token, refreshing it when it is about
+// to expire or when force is set
+func (s *ZaloService) accessToken(ctx context.Context, force bool) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == nil {
+		var stored models.OAuthToken
+		err := config.GetDBWithContext(ctx).First(&stored, "provider = ?", zaloTokenProvider).Error
+		switch {
+		case err == nil:
+			s.token = &stored
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// Expiry unknown: used until Zalo rejects it
+			s.token = &models.OAuthToken{Provider: zaloTokenProvider, AccessToken: s.cfg.AccessToken, RefreshToken: s.cfg.RefreshToken}
+		default:
+			return "", fmt.Errorf("failed to load zalo tokens: %w", err)
+		}
+	}
+
+	expiring := !s.token.ExpiresAt.IsZero() && s.now().Add(zaloTokenRefreshMargin).After(s.token.ExpiresAt)
+	if force || expiring || s.token.AccessToken == "" {
+		if err := s.refresh(ctx); err != nil {
+			return "", err
+		}
+	}
+	return s.token.AccessToken, nil
+}
+
+// refresh exchanges the refresh token for a new token pair and stores it.
+// Zalo refresh tokens are single-use, so the new pair must be persisted.
+func (s *ZaloService) refresh(ctx context.Context) error {
+	if s.token.RefreshToken == "" || s.cfg.AppID == "" || s.cfg.AppSecret == "" {
+		return fmt.Errorf("zalo access token expired and no refresh token, ZALO_APP_ID or ZALO_APP_SECRET is configured")
+	}
+
+	var result struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    string `json:"expires_in"` // Seconds, sent as a string
+		Error        int    `json:"error"`
+		Message      string `json:"message"`
+	}
+	resp, err := s.client.R().
+		SetContext(ctx).
+		SetHeader("secret_key", s.cfg.AppSecret).
+		SetFormData(map[string]string{
+			"app_id":        s.cfg.AppID,
+			"refresh_token": s.token.RefreshToken,
+			"grant_type":    "refresh_token",
+		}).
+		SetResult(&result).
+		Post(zaloTokenURL)
+	if err != nil {
+		return fmt.Errorf("failed to refresh zalo access token: %w", err)
+	}
+	if resp.IsError() || result.Error != 0 || result.AccessToken == "" {
+		return fmt.Errorf("failed to refresh zalo access token: status %d, error %d %s", resp.StatusCode(), result.Error, result.Message)
+	}
+
+	seconds, err := strconv.Atoi(result.ExpiresIn)
+	if err != nil || seconds <= 0 {
+		seconds = 3600
+	}
+	now := s.now().UTC()
+	token := models.OAuthToken{
+		Provider:     zaloTokenProvider,
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+		ExpiresAt:    now.Add(time.Duration(seconds) * time.Second),
+		UpdatedAt:    now,
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = s.token.RefreshToken
+	}
+	s.token = &token
+
+	err = config.GetDBWithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}},
+		DoUpdates: clause.AssignmentColumns([]string{"access_token", "refresh_token", "expires_at", "updated_at"}),
+	}).Create(&token).Error
+	if err != nil {
+		// The new pair still works for this instance; the old refresh token
+		// is already spent, so a restart would need new tokens from Zalo
+		zaloLog.Error("Failed to store refreshed zalo tokens", logging.FieldError, err)
+	}
+	zaloLog.Info("Zalo OA access token refreshed", "expires_at", token.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// ZaloNotifier delivers notifications to the admins following the Official Account
+type ZaloNotifier struct {
+	zalo *ZaloService
+}
+
+// NewZaloNotifier creates the "zalo" notification channel
+func NewZaloNotifier(zalo *ZaloService) *ZaloNotifier {
+	return &ZaloNotifier{zalo: zalo}
+}
+
+// Name returns the channel name
+func (z *ZaloNotifier) Name() string { return "zalo" }
+
+// Notify sends the notification to every admin Zalo user ID
+func (z *ZaloNotifier) Notify(ctx context.Context, n Notification) error {
+	var failures []string
+	for _, userID := range z.zalo.AdminUserIDs() {
+		if _, err := z.zalo.Send(ctx, userID, nil, "alert", n); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", userID, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to send zalo messages: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// NotifyMember sends the notification to a member's linked Zalo account.
+// Price alerts use the price_alert template with the notification's fields.
+func (z *ZaloNotifier) NotifyMember(ctx context.Context, profileID uuid.UUID, n Notification) error {
+	var err error
+	if n.Source == PriceAlertSource {
+		_, err = z.zalo.SendToProfile(ctx, profileID, "price_alert", n.Fields)
+	} else {
+		_, err = z.zalo.SendToProfile(ctx, profileID, "alert", n)
+	}
+	return err
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/datvt88/CPLS/backend/models"
+)
+
+func TestRenderZaloTemplate(t *testing.T) {
+	text, err := RenderZaloTemplate("alert", Notification{Title: "Crawl failed", Message: "12 symbols failed", Severity: "critical"})
+	if err != nil {
+		t.Fatalf("RenderZaloTemplate() error = %v", err)
+	}
+	if text != "⚠️ Crawl failed\n12 symbols failed" {
+		t.Errorf("alert = %q", text)
+	}
+
+	text, err = RenderZaloTemplate("alert", Notification{Title: "Crawl recovered", Severity: "resolved"})
+	if err != nil || !strings.HasPrefix(text, "✅") {
+		t.Errorf("resolved alert = %q, %v", text, err)
+	}
+
+	text, err = RenderZaloTemplate("price_alert", map[string]interface{}{"Code": "VNM", "Condition": "giá vượt 70.000", "Close": 70500, "Date": "2026-02-02"})
+	if err != nil || text != "🔔 VNM: giá vượt 70.000\nGiá đóng cửa 70500 ngày 2026-02-02" {
+		t.Errorf("price_alert = %q, %v", text, err)
+	}
+
+	if _, err := RenderZaloTemplate("missing", nil); !errors.Is(err, ErrUnknownZaloTemplate) {
+		t.Errorf("unknown template error = %v; want ErrUnknownZaloTemplate", err)
+	}
+}
+
+func TestRenderZaloTemplateTruncates(t *testing.T) {
+	text, err := RenderZaloTemplate("alert", Notification{Title: "Long", Message: strings.Repeat("ả", 3000)})
+	if err != nil {
+		t.Fatalf("RenderZaloTemplate() error = %v", err)
+	}
+	if n := utf8.RuneCountInString(text); n != zaloMaxTextLength {
+		t.Errorf("rendered %d characters; want %d", n, zaloMaxTextLength)
+	}
+	if !strings.HasSuffix(text, "…") {
+		t.Errorf("truncated text does not end with an ellipsis")
+	}
+}
+
+func TestVerifyZaloEventSignature(t *testing.T) {
+	body := []byte(`{"app_id":"123","event_name":"user_seen_message","timestamp":"1738500000000"}`)
+	sum := sha256.Sum256([]byte("123" + string(body) + "1738500000000" + "oa-secret"))
+	mac := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name      string
+		signature string
+		timestamp string
+		valid     bool
+	}{
+		{"valid", "mac=" + mac, "1738500000000", true},
+		{"uppercase hex", "mac=" + strings.ToUpper(mac), "1738500000000", true},
+		{"wrong timestamp", "mac=" + mac, "1738500000001", false},
+		{"missing", "", "1738500000000", false},
+		{"no timestamp", "mac=" + mac, "", false},
+		{"garbage", "mac=abc", "1738500000000", false},
+	}
+	for _, tt := range tests {
+		if got := verifyZaloEventSignature("123", "oa-secret", tt.timestamp, body, tt.signature); got != tt.valid {
+			t.Errorf("%s: verifyZaloEventSignature() = %v; want %v", tt.name, got, tt.valid)
+		}
+	}
+}
+
+func TestCheckZaloEventTime(t *testing.T) {
+	now := time.UnixMilli(1738500000000)
+
+	tests := []struct {
+		name      string
+		timestamp string
+		valid     bool
+	}{
+		{"now", "1738500000000", true},
+		{"slightly old", "1738499760000", true},
+		{"stale", "1738499000000", false},
+		{"from the future", "1738501000000", false},
+		{"seconds instead of milliseconds", "1738500000", false},
+		{"malformed", "yesterday", false},
+	}
+	for _, tt := range tests {
+		err := checkZaloEventTime(tt.timestamp, now)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: checkZaloEventTime() error = %v; want valid %v", tt.name, err, tt.valid)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidZaloEvent) {
+			t.Errorf("%s: error %v is not ErrInvalidZaloEvent", tt.name, err)
+		}
+	}
+}
+
+func TestZaloReceipt(t *testing.T) {
+	var received zaloEvent
+	received.EventName = "user_received_message"
+	received.Message.MsgID = "m1"
+	status, ids, from := zaloReceipt(received)
+	if status != models.ZaloMessageReceived || !reflect.DeepEqual(ids, []string{"m1"}) || !reflect.DeepEqual(from, []string{models.ZaloMessageSent}) {
+		t.Errorf("received: got %q %v %v", status, ids, from)
+	}
+
+	var seen zaloEvent
+	seen.EventName = "user_seen_message"
+	seen.Message.MsgIDs = []string{"m1", "m2"}
+	status, ids, from = zaloReceipt(seen)
+	if status != models.ZaloMessageSeen || len(ids) != 2 || len(from) != 2 {
+		t.Errorf("seen: got %q %v %v", status, ids, from)
+	}
+
+	if status, _, _ := zaloReceipt(zaloEvent{EventName: "follow"}); status != "" {
+		t.Errorf("follow event set status %q", status)
+	}
+}
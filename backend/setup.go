@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/datvt88/CPLS/backend/cache"
+	"github.com/datvt88/CPLS/backend/config"
+	"github.com/datvt88/CPLS/backend/services"
+	"github.com/datvt88/CPLS/backend/ssi"
+	"github.com/datvt88/CPLS/backend/vndirect"
+)
+
+// openStores connects the data stores, applies CRAWLER_MODE and loads the
+// runtime settings. Every command but migrate starts with it; the returned
+// function disconnects the stores.
+func openStores() (*services.SettingsService, func()) {
+	// Stores listed in REQUIRED_STORES must be reachable to start. Others may
+	// be down: the routes using them answer 503 until they are back.
+	requiredStores, err := config.RequiredStoresFromEnv()
+	if err != nil {
+		log.Fatalf("FATAL: Invalid REQUIRED_STORES: %v", err)
+	}
+
+	// Connect to PostgreSQL (Supabase)
+	connectStore("PostgreSQL", config.StorePostgres, config.ConnectPostgres, requiredStores)
+
+	// Connect to MongoDB (stocks, prices and crawl runs)
+	connectStore("MongoDB", config.StoreMongo, config.ConnectMongoDB, requiredStores)
+
+	// VNDIRECT_BASE_URL points the VNDirect source at a mirror or a stub server
+	if baseURL := os.Getenv("VNDIRECT_BASE_URL"); baseURL != "" {
+		cfg := vndirect.DefaultConfig()
+		cfg.BaseURL = baseURL
+		services.RegisterMarketDataSource(services.NewVNDirectSourceWithConfig(cfg))
+	}
+	// SSI_BASE_URL and SSI_STREAM_URL point the SSI source at other FastConnect endpoints
+	if baseURL, streamURL := os.Getenv("SSI_BASE_URL"), os.Getenv("SSI_STREAM_URL"); baseURL != "" || streamURL != "" {
+		cfg := ssi.DefaultConfig()
+		if baseURL != "" {
+			cfg.BaseURL = baseURL
+		}
+		if streamURL != "" {
+			cfg.StreamURL = streamURL
+		}
+		services.RegisterMarketDataSource(services.NewSSISourceWithConfig(cfg))
+	}
+
+	// CRAWLER_MODE=dry-run crawls the fixtures recorded in CRAWLER_FIXTURES_DIR into
+	// sandbox_ collections instead of calling the providers; record saves them
+	crawlerMode, err := services.UseCrawlerMode(os.Getenv("CRAWLER_MODE"), os.Getenv("CRAWLER_FIXTURES_DIR"))
+	if err != nil {
+		log.Fatalf("FATAL: Invalid CRAWLER_MODE: %v", err)
+	}
+	if crawlerMode == services.CrawlerModeDryRun && os.Getenv("ENV") == "production" {
+		log.Fatal("FATAL: CRAWLER_MODE=dry-run cannot be used in production")
+	}
+	if crawlerMode != services.CrawlerModeLive {
+		log.Printf("⚠️  Crawler mode %s", crawlerMode)
+	}
+
+	// Connect to Redis (optional: shared rate limit counters and response cache across instances)
+	if err := config.ConnectRedis(); err != nil {
+		log.Printf("Warning: Failed to connect to Redis, using in-memory rate limits and cache: %v", err)
+	}
+
+	// Load reloadable settings (rate limits, schedules, feature flags, symbol filters)
+	settingsService := services.NewSettingsService()
+	if _, err := settingsService.Reload(context.Background()); err != nil {
+		log.Printf("Warning: Failed to load stored settings, using environment/defaults: %v", err)
+	}
+
+	return settingsService, func() {
+		config.DisconnectRedis()
+		config.DisconnectMongoDB()
+		config.DisconnectPostgres()
+	}
+}
+
+// newNotificationService registers the configured notification channels
+// for alert rules and crawl run summaries
+func newNotificationService(jobQueue *services.JobQueue) (*services.NotificationService, *services.ZaloService, *services.TelegramService) {
+	notificationService := services.NewNotificationService()
+	notificationService.UseJobQueue(jobQueue)
+	zaloService := services.NewZaloService(services.ZaloConfigFromEnv())
+	if zaloService.Configured() {
+		// Alert rules can route to "zalo" to message the admins following the Official Account
+		notificationService.Register(services.NewZaloNotifier(zaloService))
+	} else {
+		log.Println("Warning: ZALO_OA_ACCESS_TOKEN not set. Zalo notifications are disabled")
+	}
+	telegramService := services.NewTelegramService(services.TelegramConfigFromEnv())
+	if telegramService.Configured() {
+		// Alert rules and crawl summaries can route to "telegram" (the operations chat)
+		notificationService.Register(services.NewTelegramNotifier(telegramService))
+	} else {
+		log.Println("Warning: TELEGRAM_BOT_TOKEN or TELEGRAM_CHAT_ID not set. Telegram notifications are disabled")
+	}
+	return notificationService, zaloService, telegramService
+}
+
+// attachProviderCredentials hands the data provider credentials (encrypted
+// in Supabase, rotated from the admin API) to the registered data sources
+func attachProviderCredentials(auditService *services.AuditService) *services.CredentialService {
+	credentialService, err := services.NewCredentialServiceFromEnv(auditService)
+	if err != nil {
+		log.Fatalf("Failed to configure provider credentials: %v", err)
+	}
+	if !credentialService.Configured() {
+		log.Println("Warning: PROVIDER_CREDENTIALS_KEY not set. Provider credentials are read from environment variables only")
+	}
+	credentialService.AttachSources()
+	return credentialService
+}
+
+// newTCBSService creates the TCBS service, exiting on an invalid
+// PROVIDER_CREDENTIALS_KEY
+func newTCBSService() *services.TCBSService {
+	tcbsService, err := services.NewTCBSServiceFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure TCBS: %v", err)
+	}
+	return tcbsService
+}
+
+// startRealtimeQuotes relays the quotes of REALTIME_SOURCE (e.g. ssi), when
+// set, to the price stream and the price store until ctx is cancelled
+func startRealtimeQuotes(ctx context.Context, stocks *services.StockService, priceStream *services.PriceStreamService) {
+	name := os.Getenv("REALTIME_SOURCE")
+	if name == "" {
+		return
+	}
+	realtimeService, err := services.NewRealtimeQuoteService(name, stocks, priceStream)
+	if err != nil {
+		log.Printf("Warning: Realtime quotes are disabled: %v", err)
+		return
+	}
+	realtimeService.Start(ctx)
+}
+
+// crawlPipeline is the crawler with the services its run listeners keep up
+// to date, shared by the server and the crawl command
+type crawlPipeline struct {
+	crawler         *services.CrawlerService
+	stocks          *services.StockService
+	sparklines      *services.SparklineService
+	movingAverages  *services.MovingAverageService
+	derivatives     *services.DerivativesService
+	signals         *services.SignalService
+	sectorBreadth   *services.SectorBreadthService
+	volumeAnomalies *services.VolumeAnomalyService
+	stockMetrics    *services.StockMetricsService
+	screenerPresets *services.ScreenerPresetService
+	dataDigest      *services.DataDigestService
+	priceAlerts     *services.PriceAlertService
+}
+
+// newPriceRepository returns the repository of the stores listed in
+// PRICE_STORES, which the crawler writes candles to and the API reads them from
+func newPriceRepository() services.PriceRepository {
+	stores, err := config.PriceStoresFromEnv()
+	if err != nil {
+		log.Fatalf("FATAL: Invalid PRICE_STORES: %v", err)
+	}
+	prices, err := services.NewPriceRepository(stores)
+	if err != nil {
+		log.Fatalf("FATAL: Invalid PRICE_STORES: %v", err)
+	}
+	if len(stores) > 1 || stores[0] != config.StoreMongo {
+		log.Printf("✓ Candles are read from %s and written to %s", stores[0], strings.Join(stores, ", "))
+	}
+	return prices
+}
+
+// newCrawlPipeline creates the crawler and registers its run listeners in
+// the order they run
+func newCrawlPipeline(notificationService *services.NotificationService, webhookService *services.WebhookService, jobQueue *services.JobQueue) *crawlPipeline {
+	prices := newPriceRepository()
+	p := &crawlPipeline{crawler: services.NewCrawlerService(prices, notificationService, webhookService, jobQueue)}
+
+	// Read API responses (stock list, candles, indicators) are cached in Redis,
+	// or in memory without it, and invalidated before the other crawl listeners run
+	p.stocks = services.NewStockService(prices, cache.New(cache.NewStore()))
+	p.crawler.OnRunFinished(p.stocks.InvalidateRun)
+	// Watchlist sparklines are rebuilt after every crawl run that stores new candles
+	p.sparklines = services.NewSparklineService(p.stocks)
+	p.crawler.OnRunFinished(p.sparklines.UpdateRun)
+	// MA10/20/50/200 chart series, rebuilt after every crawl run that stores new candles
+	p.movingAverages = services.NewMovingAverageService(p.stocks)
+	p.crawler.OnRunFinished(p.movingAverages.ComputeRun)
+	// Futures of the crawler.derivatives underlyings, crawled after every full crawl run
+	p.derivatives = services.NewDerivativesService(p.stocks)
+	p.crawler.OnRunFinished(p.derivatives.CrawlRun)
+
+	// Trading signals are computed after every crawl run that stores new candles
+	p.signals = services.NewSignalService(p.stocks)
+	p.crawler.OnRunFinished(p.signals.GenerateRun)
+
+	// Sessions of unusual volume, flagged after every crawl run that stores new candles
+	p.volumeAnomalies = services.NewVolumeAnomalyService(p.stocks)
+	p.crawler.OnRunFinished(p.volumeAnomalies.DetectRun)
+
+	// Sector breadth history for the sector rotation dashboard, computed after every crawl run
+	p.sectorBreadth = services.NewSectorBreadthService(p.stocks)
+	p.crawler.OnRunFinished(p.sectorBreadth.ComputeRun)
+
+	// Liquidity metrics for the stock detail and the screener, recomputed after every crawl run
+	p.stockMetrics = services.NewStockMetricsService(p.stocks)
+	p.crawler.OnRunFinished(p.stockMetrics.ComputeRun)
+	// Saved screens; notifying ones are re-run once the metrics are recomputed
+	p.screenerPresets = services.NewScreenerPresetService(p.stockMetrics, notificationService)
+	p.crawler.OnRunFinished(p.screenerPresets.EvaluateRun)
+
+	// Partner digest of each day's end-of-day data, finalized by the first full crawl after digest.time
+	p.dataDigest = services.NewDataDigestService(webhookService)
+	p.crawler.OnRunFinished(p.dataDigest.FinalizeRun)
+
+	// Member price alerts, evaluated after every crawl run that stores new candles
+	p.priceAlerts = services.NewPriceAlertService(notificationService, p.stocks)
+	p.crawler.OnRunFinished(p.priceAlerts.EvaluateRun)
+	// A random sample of symbols is re-fetched after every full crawl run and
+	// compared with the stored candles (sample_mismatch_ratio alert rules)
+	p.crawler.OnRunFinished(services.NewCrawlVerificationService().VerifyRun)
+	return p
+}
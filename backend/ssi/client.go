@@ -0,0 +1,258 @@
+// Package ssi is a client of SSI FastConnect Data: the REST API listing
+// securities and their daily candles, and the streaming hub pushing trades
+// as they happen. It only speaks HTTP and SignalR and decodes messages:
+// credentials, reconnection and the mapping to candles belong to its callers.
+package ssi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// DefaultBaseURL is the REST API the client calls unless configured otherwise
+	DefaultBaseURL = "https://fc-data.ssi.com.vn"
+	// DefaultStreamURL is the streaming hub the client connects to unless
+	// configured otherwise
+	DefaultStreamURL = "https://fc-datahub.ssi.com.vn"
+	// DefaultPageSize is the page size of list endpoints (the API maximum)
+	DefaultPageSize = 1000
+	// DateLayout is the date format of request parameters and responses
+	DateLayout = "02/01/2006"
+)
+
+// ErrInvalidCredentials is returned when SSI rejects the consumer ID and
+// secret or the access token issued for them
+var ErrInvalidCredentials = errors.New("SSI rejected the FastConnect credentials")
+
+// Config configures a Client
+type Config struct {
+	BaseURL   string            // Default DefaultBaseURL
+	StreamURL string            // Default DefaultStreamURL
+	Transport http.RoundTripper // Default http.DefaultTransport
+	Timeout   time.Duration     // Per attempt; 0 for none
+	Retries   int               // Attempts after a failed one
+	PageSize  int               // Default DefaultPageSize
+}
+
+// DefaultConfig returns the settings used against the real API
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:   DefaultBaseURL,
+		StreamURL: DefaultStreamURL,
+		Timeout:   15 * time.Second,
+		Retries:   1,
+		PageSize:  DefaultPageSize,
+	}
+}
+
+// Client calls SSI FastConnect Data. It is safe for concurrent use.
+type Client struct {
+	http      *resty.Client
+	baseURL   string
+	streamURL string
+	pageSize  int
+}
+
+// New creates a Client with cfg
+func New(cfg Config) *Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.StreamURL == "" {
+		cfg.StreamURL = DefaultStreamURL
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	if cfg.PageSize <= 0 {
+		cfg.PageSize = DefaultPageSize
+	}
+
+	client := resty.New().
+		SetTransport(cfg.Transport).
+		SetTimeout(cfg.Timeout).
+		SetRetryCount(cfg.Retries)
+	// Calls made with a request's context forward its X-Request-ID
+	client.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		if id := logging.RequestID(req.Context()); id != "" {
+			req.SetHeader(logging.RequestIDHeader, id)
+		}
+		return nil
+	})
+
+	return &Client{
+		http:      client,
+		baseURL:   strings.TrimRight(cfg.BaseURL, "/"),
+		streamURL: strings.TrimRight(cfg.StreamURL, "/"),
+		pageSize:  cfg.PageSize,
+	}
+}
+
+// StatusError is an error response of the API. SSI reports errors in the
+// body's status as well as in the HTTP status.
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("SSI %s returned status %d: %s", e.Endpoint, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("SSI %s returned status %d", e.Endpoint, e.StatusCode)
+}
+
+// Is makes 401 and 403 responses match ErrInvalidCredentials
+func (e *StatusError) Is(target error) bool {
+	return target == ErrInvalidCredentials && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden)
+}
+
+// Number is a price or volume, sent as a JSON number or a numeric string
+type Number float64
+
+// UnmarshalJSON accepts numbers, numeric strings and empty strings (0)
+func (n *Number) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		*n = 0
+		return nil
+	}
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %s", data)
+	}
+	*n = Number(value)
+	return nil
+}
+
+// Security is a listed symbol
+type Security struct {
+	Market      string `json:"Market"` // HOSE, HNX, UPCOM
+	Symbol      string `json:"Symbol"`
+	StockName   string `json:"StockName"`
+	StockEnName string `json:"StockEnName"`
+}
+
+// OHLC is a daily candle; prices are in VND
+type OHLC struct {
+	Symbol      string `json:"Symbol"`
+	TradingDate string `json:"TradingDate"` // DateLayout
+	Open        Number `json:"Open"`
+	High        Number `json:"High"`
+	Low         Number `json:"Low"`
+	Close       Number `json:"Close"`
+	Volume      Number `json:"Volume"`
+	Value       Number `json:"Value"`
+}
+
+// AccessToken exchanges the consumer ID and secret for an access token. A
+// rejected pair returns an error matching ErrInvalidCredentials.
+func (c *Client) AccessToken(ctx context.Context, consumerID, consumerSecret string) (string, error) {
+	var body struct {
+		Data struct {
+			AccessToken string `json:"accessToken"`
+		} `json:"data"`
+	}
+	resp, err := c.http.R().SetContext(ctx).
+		SetBody(map[string]string{"consumerID": consumerID, "consumerSecret": consumerSecret}).
+		Post(c.baseURL + "/api/v2/Market/AccessToken")
+	if err := c.decode(resp, err, "access token", &body); err != nil {
+		return "", err
+	}
+	if body.Data.AccessToken == "" {
+		return "", fmt.Errorf("SSI access token response has no token")
+	}
+	return body.Data.AccessToken, nil
+}
+
+// Securities returns the listed symbols of market, across pages
+func (c *Client) Securities(ctx context.Context, token, market string) ([]Security, error) {
+	var securities []Security
+	for page := 1; ; page++ {
+		var body struct {
+			Data        []Security `json:"data"`
+			TotalRecord int        `json:"totalRecord"`
+		}
+		err := c.get(ctx, token, "securities", "/api/v2/Market/Securities", map[string]string{
+			"market":    market,
+			"pageIndex": strconv.Itoa(page),
+			"pageSize":  strconv.Itoa(c.pageSize),
+		}, &body)
+		if err != nil {
+			return nil, err
+		}
+		securities = append(securities, body.Data...)
+		if len(body.Data) < c.pageSize || len(securities) >= body.TotalRecord {
+			return securities, nil
+		}
+	}
+}
+
+// DailyOHLC returns the daily candles of symbol between from and to, oldest
+// first, across pages
+func (c *Client) DailyOHLC(ctx context.Context, token, symbol string, from, to time.Time) ([]OHLC, error) {
+	var candles []OHLC
+	for page := 1; ; page++ {
+		var body struct {
+			Data        []OHLC `json:"data"`
+			TotalRecord int    `json:"totalRecord"`
+		}
+		err := c.get(ctx, token, "daily OHLC", "/api/v2/Market/DailyOhlc", map[string]string{
+			"symbol":    symbol,
+			"fromDate":  from.Format(DateLayout),
+			"toDate":    to.Format(DateLayout),
+			"pageIndex": strconv.Itoa(page),
+			"pageSize":  strconv.Itoa(c.pageSize),
+			"ascending": "true",
+		}, &body)
+		if err != nil {
+			return nil, err
+		}
+		candles = append(candles, body.Data...)
+		if len(body.Data) < c.pageSize || len(candles) >= body.TotalRecord {
+			return candles, nil
+		}
+	}
+}
+
+// get reads path with the access token into v
+func (c *Client) get(ctx context.Context, token, endpoint, path string, params map[string]string, v interface{}) error {
+	resp, err := c.http.R().SetContext(ctx).SetAuthToken(token).SetQueryParams(params).Get(c.baseURL + path)
+	return c.decode(resp, err, endpoint, v)
+}
+
+// decode checks the response of endpoint and parses its body into v
+func (c *Client) decode(resp *resty.Response, err error, endpoint string, v interface{}) error {
+	if err != nil {
+		return fmt.Errorf("failed to call SSI %s: %w", endpoint, err)
+	}
+	if resp.IsError() {
+		return &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode()}
+	}
+	var envelope struct {
+		Status  json.RawMessage `json:"status"` // 200 or "Success"
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(resp.Body(), &envelope); err != nil {
+		return fmt.Errorf("failed to parse SSI %s response: %w", endpoint, err)
+	}
+	if status := strings.Trim(string(envelope.Status), `"`); status != "" && status != "200" && !strings.EqualFold(status, "success") {
+		code, _ := strconv.Atoi(status)
+		return &StatusError{Endpoint: endpoint, StatusCode: code, Message: envelope.Message}
+	}
+	if err := json.Unmarshal(resp.Body(), v); err != nil {
+		return fmt.Errorf("failed to parse SSI %s response: %w", endpoint, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package ssi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestAccessToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Rejected credentials are reported in the body of a 200 response
+		if r.URL.Path != "/api/v2/Market/AccessToken" {
+			http.NotFound(w, r)
+			return
+		}
+		var body struct{ ConsumerSecret string }
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.ConsumerSecret != "secret" {
+			w.Write([]byte(`{"message": "Invalid consumer secret", "status": 401, "data": null}`))
+			return
+		}
+		w.Write([]byte(`{"message": "Success", "status": 200, "data": {"accessToken": "jwt"}}`))
+	}))
+	defer server.Close()
+	client := New(Config{BaseURL: server.URL})
+
+	if token, err := client.AccessToken(context.Background(), "id", "secret"); token != "jwt" || err != nil {
+		t.Errorf("AccessToken() = %q, %v; want jwt", token, err)
+	}
+	if _, err := client.AccessToken(context.Background(), "id", "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("AccessToken() with a wrong secret = %v; want ErrInvalidCredentials", err)
+	}
+}
+
+func TestDailyOHLCPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Header.Get("Authorization") != "Bearer jwt" || query.Get("fromDate") != "01/10/2026" || query.Get("toDate") != "15/10/2026" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		page, _ := strconv.Atoi(query.Get("pageIndex"))
+		rows := ""
+		for day := (page-1)*2 + 1; day <= page*2 && day <= 3; day++ {
+			if rows != "" {
+				rows += ","
+			}
+			// Prices are numeric strings
+			rows += fmt.Sprintf(`{"Symbol": "HPG", "TradingDate": "%02d/10/2026", "Open": "25600", "High": "26000", "Low": "25500", "Close": "25900", "Volume": "1200300"}`, day)
+		}
+		fmt.Fprintf(w, `{"message": "Success", "status": "Success", "totalRecord": 3, "data": [%s]}`, rows)
+	}))
+	defer server.Close()
+	client := New(Config{BaseURL: server.URL, PageSize: 2})
+
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	candles, err := client.DailyOHLC(context.Background(), "jwt", "HPG", from, from.AddDate(0, 0, 14))
+	if err != nil {
+		t.Fatalf("DailyOHLC() unexpected error: %v", err)
+	}
+	if len(candles) != 3 || candles[2].TradingDate != "03/10/2026" || candles[2].Close != 25900 || candles[2].Volume != 1200300 {
+		t.Errorf("DailyOHLC() = %+v; want 3 candles across pages with parsed prices", candles)
+	}
+}
+
+func TestStreamTrades(t *testing.T) {
+	const broadcast = `{"C": "d-1", "M": [{"H": "FcMarketDataV2Hub", "M": "Broadcast", "A": ["{\"DataType\":\"X-TRADE\",\"Content\":\"{\\\"Symbol\\\":\\\"HPG\\\",\\\"TradingDate\\\":\\\"15/10/2026\\\",\\\"Open\\\":25600,\\\"High\\\":26000,\\\"Low\\\":25500,\\\"LastPrice\\\":25900,\\\"TotalVol\\\":1200300}\"}"]}]}`
+	subscribed := make(chan string, 1)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2.0/signalr/negotiate", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ConnectionToken": "conn-1", "KeepAliveTimeout": 20.0}`))
+	})
+	mux.HandleFunc("/v2.0/signalr/start", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"Response": "started"}`))
+	})
+	mux.Handle("/v2.0/signalr/connect", websocket.Handler(func(conn *websocket.Conn) {
+		if conn.Request().URL.Query().Get("connectionToken") != "conn-1" {
+			return
+		}
+		var invocation struct {
+			M string
+			A []string
+		}
+		if err := websocket.JSON.Receive(conn, &invocation); err != nil || invocation.M != "SwitchChannels" {
+			return
+		}
+		subscribed <- invocation.A[0]
+		websocket.Message.Send(conn, `{}`)
+		websocket.Message.Send(conn, broadcast)
+		// Stay open until the client disconnects
+		var discard string
+		websocket.Message.Receive(conn, &discard)
+	}))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := New(Config{StreamURL: server.URL})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var trades []Trade
+	err := client.StreamTrades(ctx, "jwt", TradeChannel([]string{"HPG", "VNM"}), func(trade Trade) {
+		trades = append(trades, trade)
+		cancel()
+	})
+	if err != nil {
+		t.Fatalf("StreamTrades() unexpected error: %v", err)
+	}
+	if channel := <-subscribed; channel != "X-TRADE:HPG-VNM" {
+		t.Errorf("subscribed channel = %q; want X-TRADE:HPG-VNM", channel)
+	}
+	if len(trades) != 1 || trades[0].Symbol != "HPG" || trades[0].LastPrice != 25900 || trades[0].TotalVol != 1200300 {
+		t.Errorf("trades = %+v; want the broadcast HPG trade", trades)
+	}
+}
+
+func TestDecodeStreamMessageError(t *testing.T) {
+	if _, err := decodeStreamMessage([]byte(`{"I": "0", "E": "Unauthorized"}`)); err == nil {
+		t.Error("decodeStreamMessage() of a hub error should fail")
+	}
+	if trades, err := decodeStreamMessage([]byte(`{}`)); len(trades) != 0 || err != nil {
+		t.Errorf("decodeStreamMessage() of a keep-alive = %v, %v; want nothing", trades, err)
+	}
+}
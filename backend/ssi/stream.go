@@ -0,0 +1,197 @@
+package ssi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// streamHub is the SignalR hub publishing market data
+	streamHub = "FcMarketDataV2Hub"
+	// streamProtocol is the ASP.NET SignalR protocol version of the hub
+	streamProtocol = "1.5"
+	// streamIdleTimeout closes a connection that received nothing, not even
+	// a keep-alive, for this long
+	streamIdleTimeout = time.Minute
+)
+
+// Trade is a matched trade pushed by the stream, with the session's running
+// open, high, low and totals of the symbol; prices are in VND
+type Trade struct {
+	Symbol      string `json:"Symbol"`
+	Exchange    string `json:"Exchange"`
+	TradingDate string `json:"TradingDate"` // DateLayout
+	Time        string `json:"Time"`        // HH:MM:SS, market time
+	Open        Number `json:"Open"`
+	High        Number `json:"High"`
+	Low         Number `json:"Low"`
+	LastPrice   Number `json:"LastPrice"`
+	LastVol     Number `json:"LastVol"`
+	TotalVol    Number `json:"TotalVol"`
+	TotalVal    Number `json:"TotalVal"`
+	RefPrice    Number `json:"RefPrice"`
+	Ceiling     Number `json:"Ceiling"`
+	Floor       Number `json:"Floor"`
+}
+
+// TradeChannel returns the stream channel of the trades of symbols (none:
+// every symbol)
+func TradeChannel(symbols []string) string {
+	if len(symbols) == 0 {
+		return "X-TRADE:ALL"
+	}
+	return "X-TRADE:" + strings.Join(symbols, "-")
+}
+
+// StreamTrades connects to the streaming hub with an access token,
+// subscribes to channel and passes every trade to handle. It returns nil
+// once ctx is cancelled, or the error that ended the connection; callers
+// reconnect.
+func (c *Client) StreamTrades(ctx context.Context, token, channel string, handle func(Trade)) error {
+	connectionData := fmt.Sprintf(`[{"name":%q}]`, streamHub)
+	var negotiated struct {
+		ConnectionToken string `json:"ConnectionToken"`
+	}
+	params := map[string]string{"clientProtocol": streamProtocol, "connectionData": connectionData}
+	if err := c.getStream(ctx, token, "stream negotiate", "/v2.0/signalr/negotiate", params, &negotiated); err != nil {
+		return err
+	}
+	if negotiated.ConnectionToken == "" {
+		return fmt.Errorf("SSI stream negotiate response has no connection token")
+	}
+	params["transport"] = "webSockets"
+	params["connectionToken"] = negotiated.ConnectionToken
+
+	conn, err := c.dialStream(ctx, token, params)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Cancelling ctx unblocks the read below
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	var started struct {
+		Response string `json:"Response"`
+	}
+	if err := c.getStream(ctx, token, "stream start", "/v2.0/signalr/start", params, &started); err != nil {
+		return err
+	}
+	invocation := map[string]interface{}{"H": streamHub, "M": "SwitchChannels", "A": []string{channel}, "I": 0}
+	if err := websocket.JSON.Send(conn, invocation); err != nil {
+		return fmt.Errorf("failed to subscribe to SSI channel %s: %w", channel, err)
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(streamIdleTimeout))
+		var message string
+		if err := websocket.Message.Receive(conn, &message); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("SSI stream interrupted: %w", err)
+		}
+		trades, err := decodeStreamMessage([]byte(message))
+		if err != nil {
+			return err
+		}
+		for _, trade := range trades {
+			handle(trade)
+		}
+	}
+}
+
+// dialStream opens the WebSocket transport of the hub
+func (c *Client) dialStream(ctx context.Context, token string, params map[string]string) (*websocket.Conn, error) {
+	endpoint, err := url.Parse(c.streamURL + "/v2.0/signalr/connect")
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSI stream URL: %w", err)
+	}
+	origin := endpoint.Scheme + "://" + endpoint.Host
+	switch endpoint.Scheme {
+	case "https":
+		endpoint.Scheme = "wss"
+	case "http":
+		endpoint.Scheme = "ws"
+	}
+	query := url.Values{}
+	for key, value := range params {
+		query.Set(key, value)
+	}
+	endpoint.RawQuery = query.Encode()
+
+	cfg, err := websocket.NewConfig(endpoint.String(), origin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SSI stream URL: %w", err)
+	}
+	cfg.Header.Set("Authorization", "Bearer "+token)
+	conn, err := cfg.DialContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to the SSI stream: %w", err)
+	}
+	return conn, nil
+}
+
+// getStream reads path of the streaming hub into v
+func (c *Client) getStream(ctx context.Context, token, endpoint, path string, params map[string]string, v interface{}) error {
+	resp, err := c.http.R().SetContext(ctx).SetAuthToken(token).SetQueryParams(params).Get(c.streamURL + path)
+	return c.decode(resp, err, endpoint, v)
+}
+
+// decodeStreamMessage returns the trades broadcast in a SignalR message.
+// Keep-alives ({}) and other data types carry none; a hub error fails.
+func decodeStreamMessage(message []byte) ([]Trade, error) {
+	var frame struct {
+		Error    string `json:"E"`
+		Messages []struct {
+			Hub       string            `json:"H"`
+			Method    string            `json:"M"`
+			Arguments []json.RawMessage `json:"A"`
+		} `json:"M"`
+	}
+	if err := json.Unmarshal(message, &frame); err != nil {
+		return nil, fmt.Errorf("failed to parse SSI stream message: %w", err)
+	}
+	if frame.Error != "" {
+		return nil, fmt.Errorf("SSI stream error: %s", frame.Error)
+	}
+
+	var trades []Trade
+	for _, invocation := range frame.Messages {
+		if !strings.EqualFold(invocation.Method, "Broadcast") || len(invocation.Arguments) == 0 {
+			continue
+		}
+		var broadcast struct {
+			DataType string          `json:"DataType"`
+			Content  json.RawMessage `json:"Content"`
+		}
+		if err := json.Unmarshal(unquoteJSON(invocation.Arguments[0]), &broadcast); err != nil {
+			return nil, fmt.Errorf("failed to parse SSI broadcast: %w", err)
+		}
+		if broadcast.DataType != "X-TRADE" && broadcast.DataType != "X" {
+			continue
+		}
+		var trade Trade
+		if err := json.Unmarshal(unquoteJSON(broadcast.Content), &trade); err != nil {
+			return nil, fmt.Errorf("failed to parse SSI trade: %w", err)
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+// unquoteJSON returns the document inside a JSON string, which is how the
+// hub nests its payloads, or raw itself when it is not a string
+func unquoteJSON(raw json.RawMessage) []byte {
+	var nested string
+	if err := json.Unmarshal(raw, &nested); err == nil {
+		return []byte(nested)
+	}
+	return raw
+}
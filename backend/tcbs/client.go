@@ -0,0 +1,191 @@
+// Package tcbs is a client of the TCBS Open API, through which members read
+// their own brokerage accounts with an API key issued by TCBS. It only speaks
+// HTTP and decodes responses: which member's key is used, token caching and
+// the mapping to portfolio models belong to its callers.
+package tcbs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultBaseURL is the Open API the client calls unless configured otherwise
+const DefaultBaseURL = "https://openapi.tcbs.com.vn"
+
+// ErrInvalidAPIKey is returned when TCBS rejects an API key or the token
+// issued for it (HTTP 401 or 403)
+var ErrInvalidAPIKey = errors.New("TCBS rejected the API key")
+
+// Config configures a Client
+type Config struct {
+	BaseURL   string            // Default DefaultBaseURL
+	Transport http.RoundTripper // Default http.DefaultTransport
+	Timeout   time.Duration     // Per attempt; 0 for none
+	Retries   int               // Attempts after a failed one
+}
+
+// DefaultConfig returns the settings used against the real API
+func DefaultConfig() Config {
+	return Config{
+		BaseURL: DefaultBaseURL,
+		Timeout: 15 * time.Second,
+		Retries: 1,
+	}
+}
+
+// Client calls the TCBS Open API. It is safe for concurrent use.
+type Client struct {
+	http    *resty.Client
+	baseURL string
+}
+
+// New creates a Client with cfg
+func New(cfg Config) *Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+
+	client := resty.New().
+		SetTransport(cfg.Transport).
+		SetTimeout(cfg.Timeout).
+		SetRetryCount(cfg.Retries)
+	// Calls made with a request's context forward its X-Request-ID
+	client.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		if id := logging.RequestID(req.Context()); id != "" {
+			req.SetHeader(logging.RequestIDHeader, id)
+		}
+		return nil
+	})
+
+	return &Client{
+		http:    client,
+		baseURL: strings.TrimRight(cfg.BaseURL, "/"),
+	}
+}
+
+// StatusError is an error response of the API
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("TCBS %s returned status %d", e.Endpoint, e.StatusCode)
+}
+
+// Is makes 401 and 403 responses match ErrInvalidAPIKey
+func (e *StatusError) Is(target error) bool {
+	return target == ErrInvalidAPIKey && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden)
+}
+
+// Token is an access token issued for an API key
+type Token struct {
+	Value     string
+	ExpiresAt time.Time // Zero when TCBS did not say
+}
+
+// Account is a sub-account of the key's owner
+type Account struct {
+	AccountNo string `json:"accountNo"`
+	Type      string `json:"type"` // e.g. normal, margin
+}
+
+// Holding is a stock position of an account
+type Holding struct {
+	Symbol            string  `json:"symbol"`
+	Quantity          int64   `json:"quantity"`
+	AvailableQuantity int64   `json:"availableQuantity"`
+	CostPrice         float64 `json:"costPrice"`
+	MarketPrice       float64 `json:"marketPrice"`
+}
+
+// Balance is the cash position of an account, in VND
+type Balance struct {
+	Cash        float64 `json:"cashBalance"`
+	Available   float64 `json:"availableCash"`
+	BuyingPower float64 `json:"buyingPower"`
+	Debt        float64 `json:"debt"`
+	TotalAsset  float64 `json:"totalAsset"`
+}
+
+// Token exchanges an API key for an access token. A rejected key returns
+// an error matching ErrInvalidAPIKey.
+func (c *Client) Token(ctx context.Context, apiKey string) (Token, error) {
+	var body struct {
+		Token     string `json:"token"`
+		ExpiresIn int    `json:"expiresIn"` // Seconds
+	}
+	resp, err := c.http.R().SetContext(ctx).
+		SetBody(map[string]string{"apiKey": apiKey}).
+		Post(c.baseURL + "/gaia/v1/oauth2/openapi/token")
+	if err := c.decode(resp, err, "token", &body); err != nil {
+		return Token{}, err
+	}
+	if body.Token == "" {
+		return Token{}, fmt.Errorf("TCBS token response has no token")
+	}
+	token := Token{Value: body.Token}
+	if body.ExpiresIn > 0 {
+		token.ExpiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// Accounts returns the sub-accounts the token may read
+func (c *Client) Accounts(ctx context.Context, token string) ([]Account, error) {
+	var body struct {
+		Data []Account `json:"data"`
+	}
+	err := c.get(ctx, token, "accounts", "/aion/v1/accounts", &body)
+	return body.Data, err
+}
+
+// Holdings returns the stock positions of an account
+func (c *Client) Holdings(ctx context.Context, token, accountNo string) ([]Holding, error) {
+	var body struct {
+		Data []Holding `json:"data"`
+	}
+	err := c.get(ctx, token, "holdings", "/aion/v1/accounts/"+url.PathEscape(accountNo)+"/se", &body)
+	return body.Data, err
+}
+
+// Balance returns the cash position of an account
+func (c *Client) Balance(ctx context.Context, token, accountNo string) (Balance, error) {
+	var body struct {
+		Data Balance `json:"data"`
+	}
+	err := c.get(ctx, token, "balance", "/aion/v1/accounts/"+url.PathEscape(accountNo)+"/cash", &body)
+	return body.Data, err
+}
+
+// get reads path with the access token into v
+func (c *Client) get(ctx context.Context, token, endpoint, path string, v interface{}) error {
+	resp, err := c.http.R().SetContext(ctx).SetAuthToken(token).Get(c.baseURL + path)
+	return c.decode(resp, err, endpoint, v)
+}
+
+// decode checks the response of endpoint and parses its body into v
+func (c *Client) decode(resp *resty.Response, err error, endpoint string, v interface{}) error {
+	if err != nil {
+		return fmt.Errorf("failed to call TCBS %s: %w", endpoint, err)
+	}
+	if resp.IsError() {
+		return &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode()}
+	}
+	if err := json.Unmarshal(resp.Body(), v); err != nil {
+		return fmt.Errorf("failed to parse TCBS %s response: %w", endpoint, err)
+	}
+	return nil
+}
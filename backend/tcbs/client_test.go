@@ -0,0 +1,75 @@
+package tcbs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			APIKey string `json:"apiKey"`
+		}
+		if r.Method != http.MethodPost || r.URL.Path != "/gaia/v1/oauth2/openapi/token" || json.NewDecoder(r.Body).Decode(&body) != nil {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		if body.APIKey != "good-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"token": "jwt", "expiresIn": 3600}`))
+	}))
+	defer server.Close()
+	client := New(Config{BaseURL: server.URL})
+
+	token, err := client.Token(context.Background(), "good-key")
+	if err != nil || token.Value != "jwt" || token.ExpiresAt.IsZero() {
+		t.Errorf("Token() = %+v, %v; want the jwt with its expiry", token, err)
+	}
+	if _, err := client.Token(context.Background(), "bad-key"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Token() with a rejected key = %v; want ErrInvalidAPIKey", err)
+	}
+}
+
+func TestAccountQueries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer jwt" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/aion/v1/accounts":
+			w.Write([]byte(`{"data": [{"accountNo": "0001234567", "type": "normal"}]}`))
+		case "/aion/v1/accounts/0001234567/se":
+			w.Write([]byte(`{"data": [{"symbol": "HPG", "quantity": 1000, "availableQuantity": 800, "costPrice": 24.5, "marketPrice": 26.1}]}`))
+		case "/aion/v1/accounts/0001234567/cash":
+			w.Write([]byte(`{"data": {"cashBalance": 15000000, "availableCash": 12000000, "buyingPower": 30000000, "totalAsset": 41100000}}`))
+		default:
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	client := New(Config{BaseURL: server.URL})
+	ctx := context.Background()
+
+	accounts, err := client.Accounts(ctx, "jwt")
+	if err != nil || len(accounts) != 1 || accounts[0].AccountNo != "0001234567" {
+		t.Fatalf("Accounts() = %+v, %v; want the normal account", accounts, err)
+	}
+	holdings, err := client.Holdings(ctx, "jwt", "0001234567")
+	if err != nil || len(holdings) != 1 || holdings[0].Symbol != "HPG" || holdings[0].AvailableQuantity != 800 {
+		t.Errorf("Holdings() = %+v, %v; want the HPG position", holdings, err)
+	}
+	balance, err := client.Balance(ctx, "jwt", "0001234567")
+	if err != nil || balance.Cash != 15000000 || balance.BuyingPower != 30000000 {
+		t.Errorf("Balance() = %+v, %v; want the cash position", balance, err)
+	}
+	if _, err := client.Accounts(ctx, "expired"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("Accounts() with a rejected token = %v; want ErrInvalidAPIKey", err)
+	}
+}
@@ -0,0 +1,181 @@
+// Package vndirect is a client of the VNDirect finfo API, which publishes the
+// listings, daily prices, market index levels and industry classification of
+// HOSE, HNX and UPCOM, and the futures contracts traded on HNX. It only speaks
+// HTTP and decodes responses: quotas, caching and the mapping to the
+// crawler's models belong to its callers, which hook in through Config.
+package vndirect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/datvt88/CPLS/backend/logging"
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultBaseURL is the finfo API the client calls unless configured otherwise
+const DefaultBaseURL = "https://api-finfo.vndirect.com.vn/v4"
+
+// maxPageSize is the largest page the API serves
+const maxPageSize = 9999
+
+// ErrThrottled is returned when VNDirect rejects a request for exceeding its
+// rate limit (HTTP 429)
+var ErrThrottled = errors.New("VNDirect is throttling requests")
+
+// Config configures a Client
+type Config struct {
+	BaseURL   string            // Default DefaultBaseURL
+	Transport http.RoundTripper // Default http.DefaultTransport; wraps every attempt, retries included
+	Timeout   time.Duration     // Per attempt; 0 for none
+	Retries   int               // Attempts after a failed one
+	RetryWait time.Duration     // Wait before the first retry, growing with each one
+	PageSize  int               // Rows requested per page; default and at most 9999
+	// ResponseCache, when set, is asked for the body of every page by URL and
+	// calls fetch for the ones it does not have, e.g. to reuse responses
+	// within a crawl run. Error responses reach it as fetch errors.
+	ResponseCache func(url string, fetch func() ([]byte, error)) ([]byte, error)
+}
+
+// DefaultConfig returns the settings the crawler uses against the real API
+func DefaultConfig() Config {
+	return Config{
+		BaseURL:   DefaultBaseURL,
+		Timeout:   30 * time.Second,
+		Retries:   3,
+		RetryWait: 2 * time.Second,
+		PageSize:  maxPageSize,
+	}
+}
+
+// Client calls the VNDirect finfo API. It is safe for concurrent use.
+type Client struct {
+	http     *resty.Client
+	baseURL  string
+	pageSize int
+	cache    func(url string, fetch func() ([]byte, error)) ([]byte, error)
+}
+
+// New creates a Client with cfg
+func New(cfg Config) *Client {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	if cfg.Transport == nil {
+		cfg.Transport = http.DefaultTransport
+	}
+	if cfg.PageSize <= 0 || cfg.PageSize > maxPageSize {
+		cfg.PageSize = maxPageSize
+	}
+
+	client := resty.New().
+		SetTransport(cfg.Transport).
+		SetTimeout(cfg.Timeout).
+		SetRetryCount(cfg.Retries).
+		SetRetryWaitTime(cfg.RetryWait)
+	// Calls made with a request's context forward its X-Request-ID
+	client.OnBeforeRequest(func(_ *resty.Client, req *resty.Request) error {
+		if id := logging.RequestID(req.Context()); id != "" {
+			req.SetHeader(logging.RequestIDHeader, id)
+		}
+		return nil
+	})
+
+	return &Client{
+		http:     client,
+		baseURL:  strings.TrimRight(cfg.BaseURL, "/"),
+		pageSize: cfg.PageSize,
+		cache:    cfg.ResponseCache,
+	}
+}
+
+// Uncached returns a copy of c that always calls the API, e.g. for health
+// checks that must not be answered from the response cache
+func (c *Client) Uncached() *Client {
+	uncached := *c
+	uncached.cache = nil
+	return &uncached
+}
+
+// StatusError is an error response of the API
+type StatusError struct {
+	Endpoint   string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("VNDirect %s returned status %d", e.Endpoint, e.StatusCode)
+}
+
+// Is makes a 429 response match ErrThrottled
+func (e *StatusError) Is(target error) bool {
+	return target == ErrThrottled && e.StatusCode == http.StatusTooManyRequests
+}
+
+// page is one page of a listing
+type page[T any] struct {
+	Data          []T `json:"data"`
+	CurrentPage   int `json:"currentPage"`
+	Size          int `json:"size"`
+	TotalElements int `json:"totalElements"`
+	TotalPages    int `json:"totalPages"`
+}
+
+// list reads the rows of path matching query (the API's "q" filter) page by
+// page, in the order of sort ("" for the API's), until limit rows were read
+// or the listing ends; limit 0 reads every page
+func list[T any](ctx context.Context, c *Client, endpoint, path, query, sort string, limit int) ([]T, error) {
+	size := c.pageSize
+	if limit > 0 && limit < size {
+		size = limit
+	}
+	params := "q=" + query
+	if sort != "" {
+		params = "sort=" + sort + "&" + params
+	}
+
+	var rows []T
+	for number := 1; ; number++ {
+		url := fmt.Sprintf("%s/%s?%s&size=%d&page=%d", c.baseURL, path, params, size, number)
+		body, err := c.get(ctx, url, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		var p page[T]
+		if err := json.Unmarshal(body, &p); err != nil {
+			return nil, fmt.Errorf("failed to parse %s response: %w", endpoint, err)
+		}
+		rows = append(rows, p.Data...)
+
+		if limit > 0 && len(rows) >= limit {
+			return rows[:limit], nil
+		}
+		// Listings without page counts end with a short page
+		if len(p.Data) < size || (p.TotalPages > 0 && number >= p.TotalPages) {
+			return rows, nil
+		}
+	}
+}
+
+// get fetches url through the response cache
+func (c *Client) get(ctx context.Context, url, endpoint string) ([]byte, error) {
+	fetch := func() ([]byte, error) {
+		resp, err := c.http.R().SetContext(ctx).Get(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", endpoint, err)
+		}
+		if resp.IsError() {
+			return nil, &StatusError{Endpoint: endpoint, StatusCode: resp.StatusCode()}
+		}
+		return resp.Body(), nil
+	}
+	if c.cache == nil {
+		return fetch()
+	}
+	return c.cache(url, fetch)
+}
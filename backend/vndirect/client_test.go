@@ -0,0 +1,219 @@
+package vndirect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// countingTransport counts the requests it forwards
+type countingTransport struct {
+	requests atomic.Int64
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// stocksServer serves a stock list of total rows, paged like the API
+func stocksServer(t *testing.T, total int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v4/stocks" || r.URL.Query().Get("q") != "type:stock~status:listed~floor:HOSE,HNX" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		number, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		var rows []Stock
+		for i := (number - 1) * size; i < number*size && i < total; i++ {
+			rows = append(rows, Stock{Code: fmt.Sprintf("S%03d", i), Exchange: "HOSE"})
+		}
+		fmt.Fprintf(w, `{"data": %s, "currentPage": %d, "size": %d, "totalElements": %d, "totalPages": %d}`,
+			mustJSON(t, rows), number, size, total, (total+size-1)/size)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestListedStocksPages(t *testing.T) {
+	server := stocksServer(t, 25)
+	transport := &countingTransport{}
+	client := New(Config{BaseURL: server.URL + "/v4/", Transport: transport, PageSize: 10})
+
+	stocks, err := client.ListedStocks(context.Background(), []string{"HOSE", "HNX"}, 0)
+	if err != nil {
+		t.Fatalf("ListedStocks() unexpected error: %v", err)
+	}
+	if len(stocks) != 25 || stocks[24].Code != "S024" {
+		t.Errorf("ListedStocks() = %d stocks; want all 25 across pages", len(stocks))
+	}
+	if n := transport.requests.Load(); n != 3 {
+		t.Errorf("requests = %d; want 3 pages through the transport", n)
+	}
+
+	// A limit asks for smaller pages and stops once reached
+	transport.requests.Store(0)
+	stocks, err = client.ListedStocks(context.Background(), []string{"HOSE", "HNX"}, 4)
+	if err != nil || len(stocks) != 4 || transport.requests.Load() != 1 {
+		t.Errorf("ListedStocks(limit 4) = %d stocks in %d requests, %v; want 4 in 1", len(stocks), transport.requests.Load(), err)
+	}
+}
+
+func TestListedQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stocks" || r.URL.Query().Get("q") != "type:etf,cw~status:listed~floor:HOSE" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data": [
+			{"code": "E1VFVN30", "type": "ETF", "exchange": "HOSE", "underlyingSymbol": "VN30"},
+			{"code": "CHPG2401", "type": "CW", "exchange": "HOSE", "underlyingSymbol": "HPG", "exercisePrice": 28.5, "exerciseRatio": "2:1", "maturityDate": "2026-12-28"}
+		]}`))
+	}))
+	defer server.Close()
+
+	rows, err := New(Config{BaseURL: server.URL}).Listed(context.Background(), []string{TypeETF, TypeCoveredWarrant}, []string{"HOSE"}, 0)
+	if err != nil || len(rows) != 2 || rows[1].UnderlyingSymbol != "HPG" || rows[1].ExercisePrice != 28.5 || rows[1].MaturityDate != "2026-12-28" {
+		t.Errorf("Listed() = %+v, %v; want the ETF and the covered warrant with its terms", rows, err)
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	server := stocksServer(t, 3)
+	cached := map[string][]byte{}
+	client := New(Config{BaseURL: server.URL + "/v4", ResponseCache: func(url string, fetch func() ([]byte, error)) ([]byte, error) {
+		if body, ok := cached[url]; ok {
+			return body, nil
+		}
+		body, err := fetch()
+		if err == nil {
+			cached[url] = body
+		}
+		return body, err
+	}})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListedStocks(context.Background(), []string{"HOSE", "HNX"}, 0); err != nil {
+			t.Fatalf("ListedStocks() unexpected error: %v", err)
+		}
+	}
+	if len(cached) != 1 {
+		t.Errorf("cached URLs = %v; want the one page", cached)
+	}
+
+	server.Close()
+	if _, err := client.ListedStocks(context.Background(), []string{"HOSE", "HNX"}, 0); err != nil {
+		t.Errorf("ListedStocks() from the cache = %v; want no request", err)
+	}
+	if _, err := client.Uncached().ListedStocks(context.Background(), []string{"HOSE", "HNX"}, 0); err == nil {
+		t.Error("Uncached().ListedStocks() with the server closed expected an error")
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	status := http.StatusTooManyRequests
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+	client := New(Config{BaseURL: server.URL})
+
+	_, err := client.StockPrices(context.Background(), "HPG", 5)
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 429 || !errors.Is(err, ErrThrottled) {
+		t.Errorf("StockPrices() with 429 = %v; want a throttled StatusError", err)
+	}
+
+	status = http.StatusBadGateway
+	_, err = client.IndexPrices(context.Background(), "VNINDEX", 5)
+	if err == nil || errors.Is(err, ErrThrottled) {
+		t.Errorf("IndexPrices() with 502 = %v; want an error that is not throttling", err)
+	}
+}
+
+func TestStockPricesQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/stock_prices" || q.Get("q") != "code:HPG" || q.Get("sort") != "date:desc" || q.Get("size") != "2" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data": [
+			{"code": "HPG", "date": "2026-03-03", "open": 25, "high": 26, "low": 24.5, "close": 25.8, "volume": 1200},
+			{"code": "HPG", "date": "2026-03-02", "open": 24, "high": 25, "low": 23.5, "close": 25, "volume": 900}
+		]}`))
+	}))
+	defer server.Close()
+
+	prices, err := New(Config{BaseURL: server.URL}).StockPrices(context.Background(), "HPG", 2)
+	if err != nil || len(prices) != 2 || prices[0].Date != "2026-03-03" || prices[0].Close != 25.8 || prices[1].Volume != 900 {
+		t.Errorf("StockPrices() = %+v, %v; want the two candles newest first", prices, err)
+	}
+}
+
+func TestLatestRatiosQuery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ratios/latest" || r.URL.Query().Get("q") != "ratioCode:OUTSTANDING_SHARES" {
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data": [
+			{"code": "HPG", "ratioCode": "OUTSTANDING_SHARES", "reportDate": "2026-09-30", "value": 6396250200}
+		]}`))
+	}))
+	defer server.Close()
+
+	ratios, err := New(Config{BaseURL: server.URL}).LatestRatios(context.Background(), RatioOutstandingShares)
+	if err != nil || len(ratios) != 1 || ratios[0].Code != "HPG" || ratios[0].Value != 6396250200 {
+		t.Errorf("LatestRatios() = %+v, %v; want the share count of HPG", ratios, err)
+	}
+}
+
+func TestFuturesQueries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		switch {
+		case r.URL.Path == "/derivatives" && q.Get("q") == "underlyingSymbol:VN30~status:listed":
+			w.Write([]byte(`{"data": [{"code": "VN30F2611", "underlyingSymbol": "VN30", "lastTradingDate": "2026-11-19", "contractMultiplier": 100000}]}`))
+		case r.URL.Path == "/derivative_prices" && q.Get("q") == "code:VN30F2611" && q.Get("sort") == "date:desc":
+			w.Write([]byte(`{"data": [{"code": "VN30F2611", "date": "2026-10-14", "close": 1402.5, "volume": 210000, "openInterest": 48000}]}`))
+		default:
+			http.Error(w, "unexpected request "+r.URL.String(), http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+	client := New(Config{BaseURL: server.URL})
+
+	contracts, err := client.FuturesContracts(context.Background(), "VN30")
+	if err != nil || len(contracts) != 1 || contracts[0].LastTradingDate != "2026-11-19" || contracts[0].Multiplier != 100000 {
+		t.Errorf("FuturesContracts() = %+v, %v; want VN30F2611", contracts, err)
+	}
+	prices, err := client.FuturesPrices(context.Background(), "VN30F2611", 1)
+	if err != nil || len(prices) != 1 || prices[0].OpenInterest != 48000 {
+		t.Errorf("FuturesPrices() = %+v, %v; want a candle with open interest", prices, err)
+	}
+}
+
+func TestIndustryMembers(t *testing.T) {
+	members := Industry{CodeList: "HPG, hsg,,NKG "}.Members()
+	if len(members) != 3 || members[1] != "HSG" || members[2] != "NKG" {
+		t.Errorf("Members() = %v; want HPG, HSG, NKG", members)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	body, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
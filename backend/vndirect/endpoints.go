@@ -0,0 +1,156 @@
+package vndirect
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Stock is a row of the stock list: a stock, ETF, covered warrant or bond
+type Stock struct {
+	Code             string  `json:"code"`
+	CompanyName      string  `json:"companyName"`
+	CompanyNameEng   string  `json:"companyNameEng"`
+	Exchange         string  `json:"exchange"` // Called floor in queries
+	Type             string  `json:"type"`
+	Status           string  `json:"status"`
+	UnderlyingSymbol string  `json:"underlyingSymbol"` // Covered warrant: underlying stock; ETF: tracked index
+	IssuerName       string  `json:"issuerName"`
+	ExercisePrice    float64 `json:"exercisePrice"`
+	ExerciseRatio    string  `json:"exerciseRatio"`
+	MaturityDate     string  `json:"maturityDate"` // YYYY-MM-DD
+	CouponRate       float64 `json:"couponRate"`
+}
+
+// Instrument types of the stock list
+const (
+	TypeStock          = "stock"
+	TypeETF            = "etf"
+	TypeCoveredWarrant = "cw"
+	TypeBond           = "bond"
+)
+
+// StockPrice is the daily candle of a stock
+type StockPrice struct {
+	Code   string  `json:"code"`
+	Date   string  `json:"date"` // YYYY-MM-DD
+	Open   float64 `json:"open"`
+	High   float64 `json:"high"`
+	Low    float64 `json:"low"`
+	Close  float64 `json:"close"`
+	Volume int64   `json:"volume"`
+}
+
+// IndexPrice is the daily level of a market index (VNINDEX, VN30,
+// HNXINDEX, UPCOMINDEX)
+type IndexPrice struct {
+	Code           string  `json:"code"`
+	Date           string  `json:"date"` // YYYY-MM-DD
+	Open           float64 `json:"open"`
+	High           float64 `json:"high"`
+	Low            float64 `json:"low"`
+	Close          float64 `json:"close"`
+	AccumulatedVol float64 `json:"accumulatedVol"`
+}
+
+// Industry is an ICB industry with its member codes
+type Industry struct {
+	IndustryCode   string `json:"industryCode"`
+	IndustryLevel  string `json:"industryLevel"`
+	VietnameseName string `json:"vietnameseName"`
+	EnglishName    string `json:"englishName"`
+	CodeList       string `json:"codeList"` // Comma-separated member codes
+}
+
+// Members returns the upper-cased member codes of the industry
+func (i Industry) Members() []string {
+	var codes []string
+	for _, code := range strings.Split(i.CodeList, ",") {
+		if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// Ratio is the latest value of a financial ratio of a stock
+type Ratio struct {
+	Code       string  `json:"code"`
+	RatioCode  string  `json:"ratioCode"`
+	ReportDate string  `json:"reportDate"` // YYYY-MM-DD
+	Value      float64 `json:"value"`
+}
+
+// RatioOutstandingShares is the ratio code of the listed share count
+const RatioOutstandingShares = "OUTSTANDING_SHARES"
+
+// ListedStocks returns up to limit (0 for all) listed stocks of floors
+// (HOSE, HNX, UPCOM)
+func (c *Client) ListedStocks(ctx context.Context, floors []string, limit int) ([]Stock, error) {
+	return c.Listed(ctx, []string{TypeStock}, floors, limit)
+}
+
+// Listed returns up to limit (0 for all) listed instruments of types (e.g.
+// TypeStock, TypeETF) on floors
+func (c *Client) Listed(ctx context.Context, types, floors []string, limit int) ([]Stock, error) {
+	query := "type:" + strings.Join(types, ",") + "~status:listed~floor:" + strings.Join(floors, ",")
+	return list[Stock](ctx, c, "stock list", "stocks", query, "", limit)
+}
+
+// StockPrices returns the candles of the last sessions of a stock, newest first
+func (c *Client) StockPrices(ctx context.Context, code string, sessions int) ([]StockPrice, error) {
+	return list[StockPrice](ctx, c, "price history", "stock_prices", "code:"+code, "date:desc", sessions)
+}
+
+// IndexPrices returns the levels of the last sessions of a market index,
+// newest first
+func (c *Client) IndexPrices(ctx context.Context, code string, sessions int) ([]IndexPrice, error) {
+	return list[IndexPrice](ctx, c, "index history", "vnmarket_prices", "code:"+code, "date:desc", sessions)
+}
+
+// Industries returns the ICB industries of level (1 to 4)
+func (c *Client) Industries(ctx context.Context, level int) ([]Industry, error) {
+	query := fmt.Sprintf("industryLevel:%d", level)
+	return list[Industry](ctx, c, "industry classification", "industry_classification", query, "", 0)
+}
+
+// LatestRatios returns the latest value of ratioCode (e.g.
+// RatioOutstandingShares) for every stock publishing it
+func (c *Client) LatestRatios(ctx context.Context, ratioCode string) ([]Ratio, error) {
+	return list[Ratio](ctx, c, "ratios", "ratios/latest", "ratioCode:"+ratioCode, "", 0)
+}
+
+// FuturesContract is a listed futures contract
+type FuturesContract struct {
+	Code             string  `json:"code"` // e.g. VN30F2611
+	UnderlyingSymbol string  `json:"underlyingSymbol"`
+	FirstTradingDate string  `json:"firstTradingDate"` // YYYY-MM-DD
+	LastTradingDate  string  `json:"lastTradingDate"`  // YYYY-MM-DD
+	Multiplier       float64 `json:"contractMultiplier"`
+	Status           string  `json:"status"`
+}
+
+// FuturesPrice is the daily candle of a futures contract
+type FuturesPrice struct {
+	Code         string  `json:"code"`
+	Date         string  `json:"date"` // YYYY-MM-DD
+	Open         float64 `json:"open"`
+	High         float64 `json:"high"`
+	Low          float64 `json:"low"`
+	Close        float64 `json:"close"`
+	Volume       int64   `json:"volume"`
+	OpenInterest int64   `json:"openInterest"`
+}
+
+// FuturesContracts returns the listed futures contracts on underlying (e.g.
+// VN30)
+func (c *Client) FuturesContracts(ctx context.Context, underlying string) ([]FuturesContract, error) {
+	query := "underlyingSymbol:" + underlying + "~status:listed"
+	return list[FuturesContract](ctx, c, "futures contracts", "derivatives", query, "", 0)
+}
+
+// FuturesPrices returns the candles of the last sessions of a futures
+// contract, newest first
+func (c *Client) FuturesPrices(ctx context.Context, code string, sessions int) ([]FuturesPrice, error) {
+	return list[FuturesPrice](ctx, c, "futures prices", "derivative_prices", "code:"+code, "date:desc", sessions)
+}
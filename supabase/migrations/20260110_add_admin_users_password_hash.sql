@@ -0,0 +1,19 @@
+-- Migration: Add password_hash column to admin_users
+-- The Go admin dashboard authenticates against this column using bcrypt.
+-- Existing admins cannot log in until a password is set with:
+--   go run ./cmd/create-admin -email <email>
+
+ALTER TABLE public.admin_users
+  ADD COLUMN IF NOT EXISTS password_hash TEXT;
+
+COMMENT ON COLUMN public.admin_users.password_hash IS 'bcrypt hash of the admin password (never store plaintext)';
+
+-- Verify the column was added
+SELECT
+  column_name,
+  data_type,
+  is_nullable
+FROM information_schema.columns
+WHERE table_schema = 'public'
+  AND table_name = 'admin_users'
+  AND column_name = 'password_hash';
@@ -0,0 +1,27 @@
+-- Migration: Create alert_rules table for operational alerting
+-- Rules are managed from the admin dashboard and evaluated by the Go backend's alert monitor
+
+CREATE TABLE IF NOT EXISTS public.alert_rules (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  name TEXT NOT NULL,
+  type TEXT NOT NULL CHECK (type IN ('no_successful_crawl', 'failed_symbols_ratio', 'stale_candles')),
+  threshold DOUBLE PRECISION NOT NULL CHECK (threshold > 0),
+  channels TEXT NOT NULL DEFAULT 'log',
+  enabled BOOLEAN DEFAULT true,
+  cooldown_minutes INTEGER DEFAULT 60 CHECK (cooldown_minutes >= 0),
+  state TEXT DEFAULT 'ok' CHECK (state IN ('ok', 'firing')),
+  last_message TEXT,
+  last_evaluated_at TIMESTAMPTZ,
+  last_notified_at TIMESTAMPTZ,
+  created_by TEXT,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_enabled ON public.alert_rules(enabled);
+
+-- Seed the default rules (disabled until an admin reviews the channels)
+INSERT INTO public.alert_rules (name, type, threshold, channels, enabled) VALUES
+  ('No successful crawl in 24h', 'no_successful_crawl', 24, 'log', false),
+  ('More than 5% of symbols failed', 'failed_symbols_ratio', 5, 'log', false),
+  ('Freshest candle older than 2 days', 'stale_candles', 2, 'log', false);
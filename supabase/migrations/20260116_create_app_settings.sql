@@ -0,0 +1,12 @@
+-- Migration: Create app_settings table for reloadable runtime configuration
+-- Values override the Go backend's environment variables and are picked up
+-- without a restart (SIGHUP, POST /admin/api/config/reload, or periodic reload).
+-- Keys: crawler.workers, crawler.request_delay, crawler.exchanges,
+--       crawler.excluded_symbols, alerts.monitor_interval, db_query.*, feature.<name>
+
+CREATE TABLE IF NOT EXISTS public.app_settings (
+  key TEXT PRIMARY KEY,
+  value TEXT NOT NULL,
+  updated_by TEXT,
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
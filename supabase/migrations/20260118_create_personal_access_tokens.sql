@@ -0,0 +1,24 @@
+-- Migration: Create personal_access_tokens table for members
+-- Members manage their own read-only tokens via /api/me/tokens and send them as
+-- "Authorization: Bearer cpls_pat_..." to the market data endpoints (Excel, Python).
+-- These are separate from the admin-issued partner keys in api_keys.
+-- Only the SHA-256 hash of each token is stored; the plaintext is shown once at creation.
+
+CREATE TABLE IF NOT EXISTS public.personal_access_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  profile_id UUID NOT NULL REFERENCES public.profiles(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  prefix TEXT NOT NULL,
+  token_hash TEXT NOT NULL UNIQUE,
+  scopes TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_profile_id ON public.personal_access_tokens(profile_id);
+CREATE INDEX IF NOT EXISTS idx_personal_access_tokens_token_hash ON public.personal_access_tokens(token_hash);
+
+-- Tokens are managed only through the backend (service role)
+ALTER TABLE public.personal_access_tokens ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,8 @@
+-- Migration: Pin a response format per API key
+-- Consumers built against the current mixed field names keep receiving them
+-- after API_RESPONSE_FORMAT (the server default) moves to a newer version.
+-- Values: "" (server default) or "<naming>,<envelope>", e.g. "as_is,envelope",
+-- "snake_case,envelope", "camelCase,bare".
+
+ALTER TABLE public.api_keys
+  ADD COLUMN IF NOT EXISTS response_format TEXT NOT NULL DEFAULT '';
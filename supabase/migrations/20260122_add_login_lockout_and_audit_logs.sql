@@ -0,0 +1,28 @@
+-- Migration: Login throttling / account lockout and the audit log
+-- Consecutive failed logins are counted per admin; at login.max_failures the
+-- account is locked until an admin unlocks it (POST /admin/api/admin-users/:id/unlock).
+-- Every login attempt (success, failure, throttled, locked) is written to audit_logs.
+
+ALTER TABLE public.admin_users
+  ADD COLUMN IF NOT EXISTS failed_login_attempts INTEGER NOT NULL DEFAULT 0,
+  ADD COLUMN IF NOT EXISTS locked_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS public.audit_logs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  actor TEXT NOT NULL,
+  action TEXT NOT NULL,
+  outcome TEXT NOT NULL,
+  entity_type TEXT,
+  entity_id TEXT,
+  ip TEXT,
+  user_agent TEXT,
+  details TEXT,
+  created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON public.audit_logs(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON public.audit_logs(action, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON public.audit_logs(actor, created_at DESC);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.audit_logs ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,18 @@
+-- Migration: Record successful admin logins
+-- admin_users.last_login is updated on every successful login and a row is
+-- appended to login_history (IP, user agent, channel). Admins view a user's
+-- history with GET /admin/api/admin-users/:id/login-history.
+
+CREATE TABLE IF NOT EXISTS public.login_history (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  admin_user_id UUID NOT NULL REFERENCES public.admin_users(id) ON DELETE CASCADE,
+  ip TEXT,
+  user_agent TEXT,
+  channel TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_history_admin_user ON public.login_history(admin_user_id, created_at DESC);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.login_history ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,29 @@
+-- Migration: Admin-managed profile deactivation
+-- Admins change membership and deactivate members with PUT /admin/api/profiles/:id.
+-- Deactivating a profile bans the auth user (no new sign-ins or token refreshes)
+-- and the backend revokes the member's personal access tokens.
+
+ALTER TABLE public.profiles
+  ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT true,
+  ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+
+CREATE OR REPLACE FUNCTION public.sync_profile_ban()
+RETURNS TRIGGER
+LANGUAGE plpgsql
+SECURITY DEFINER
+SET search_path = public, auth
+AS $$
+BEGIN
+  UPDATE auth.users
+  SET banned_until = CASE WHEN NEW.active THEN NULL ELSE 'infinity'::timestamptz END
+  WHERE id = NEW.id;
+  RETURN NEW;
+END;
+$$;
+
+DROP TRIGGER IF EXISTS on_profile_active_changed ON public.profiles;
+CREATE TRIGGER on_profile_active_changed
+  AFTER UPDATE OF active ON public.profiles
+  FOR EACH ROW
+  WHEN (OLD.active IS DISTINCT FROM NEW.active)
+  EXECUTE FUNCTION public.sync_profile_ban();
@@ -0,0 +1,22 @@
+-- Migration: Per-admin dashboard widgets
+-- Each admin arranges their dashboard from widgets (crawler status, alert
+-- status, crawl errors, charts, ...). Admins without rows see the default
+-- layout. Managed with /admin/api/dashboard/widgets.
+
+CREATE TABLE IF NOT EXISTS public.dashboard_widgets (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  admin_user_id UUID NOT NULL REFERENCES public.admin_users(id) ON DELETE CASCADE,
+  type TEXT NOT NULL,
+  title TEXT NOT NULL,
+  position INTEGER NOT NULL DEFAULT 0 CHECK (position >= 0),
+  width INTEGER NOT NULL DEFAULT 1 CHECK (width BETWEEN 1 AND 4),
+  refresh_seconds INTEGER NOT NULL DEFAULT 60 CHECK (refresh_seconds BETWEEN 10 AND 3600),
+  settings TEXT NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ DEFAULT now(),
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_dashboard_widgets_admin_user ON public.dashboard_widgets(admin_user_id, position);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.dashboard_widgets ENABLE ROW LEVEL SECURITY;
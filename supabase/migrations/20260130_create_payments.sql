@@ -0,0 +1,27 @@
+-- Migration: Membership payments
+-- Payment providers notify POST /api/payments/webhook. Each signed
+-- notification is recorded here (one row per provider transaction, so
+-- retried notifications are not applied twice); a succeeded payment
+-- upgrades the profile to premium and extends membership_expires_at in the
+-- same transaction.
+
+CREATE TABLE IF NOT EXISTS public.payments (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  provider TEXT NOT NULL,
+  transaction_id TEXT NOT NULL,
+  profile_id UUID NOT NULL REFERENCES public.profiles(id) ON DELETE CASCADE,
+  amount BIGINT NOT NULL CHECK (amount >= 0),
+  currency TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('pending', 'succeeded', 'failed')),
+  months INTEGER NOT NULL CHECK (months BETWEEN 1 AND 36),
+  membership_expires_at TIMESTAMPTZ,
+  payload TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  updated_at TIMESTAMPTZ DEFAULT now(),
+  UNIQUE (provider, transaction_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_payments_profile ON public.payments(profile_id, created_at DESC);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.payments ENABLE ROW LEVEL SECURITY;
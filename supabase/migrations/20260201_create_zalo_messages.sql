@@ -0,0 +1,36 @@
+-- Migration: Zalo Official Account notifications
+-- Messages pushed through the Zalo OA API (alerts to admins, notifications
+-- to members linked by profiles.zalo_id) are tracked in zalo_messages;
+-- delivery and read receipts from the OA webhook update their status.
+-- oauth_tokens keeps the latest OA access/refresh token pair, since Zalo
+-- refresh tokens are single-use.
+
+CREATE TABLE IF NOT EXISTS public.zalo_messages (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  profile_id UUID REFERENCES public.profiles(id) ON DELETE SET NULL,
+  zalo_user_id TEXT NOT NULL,
+  template TEXT NOT NULL,
+  text TEXT NOT NULL,
+  status TEXT NOT NULL,
+  message_id TEXT,
+  error TEXT,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  sent_at TIMESTAMPTZ,
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_zalo_messages_created ON public.zalo_messages(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_zalo_messages_profile ON public.zalo_messages(profile_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_zalo_messages_message_id ON public.zalo_messages(message_id) WHERE message_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS public.oauth_tokens (
+  provider TEXT PRIMARY KEY,
+  access_token TEXT NOT NULL,
+  refresh_token TEXT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.zalo_messages ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.oauth_tokens ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,23 @@
+-- Migration: Data provider credentials
+-- API keys and tokens of market data providers, managed from the admin API so
+-- that rotating a token does not need a redeploy. Values are encrypted by the
+-- backend with AES-256-GCM (PROVIDER_CREDENTIALS_KEY) before they are stored;
+-- only a short hint of each value is kept in plaintext.
+
+CREATE TABLE IF NOT EXISTS public.provider_credentials (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  provider TEXT NOT NULL,
+  name TEXT NOT NULL,
+  encrypted_value TEXT NOT NULL,
+  hint TEXT,
+  updated_by TEXT,
+  last_tested_at TIMESTAMPTZ,
+  last_test_ok BOOLEAN,
+  last_test_error TEXT,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  updated_at TIMESTAMPTZ DEFAULT now(),
+  UNIQUE (provider, name)
+);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.provider_credentials ENABLE ROW LEVEL SECURITY;
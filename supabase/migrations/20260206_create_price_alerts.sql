@@ -0,0 +1,44 @@
+-- Migration: Member price alerts
+-- Members set conditions on a stock (e.g. "close > 30", "rsi < 30"). The
+-- backend evaluates them after every crawl that stores new candles for the
+-- stock, notifies the alert's channels when a condition starts to hold and
+-- keeps every trigger in price_alert_events.
+
+CREATE TABLE IF NOT EXISTS public.price_alerts (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  profile_id UUID NOT NULL REFERENCES public.profiles(id) ON DELETE CASCADE,
+  code TEXT NOT NULL,
+  condition TEXT NOT NULL,
+  channels TEXT NOT NULL DEFAULT '',
+  note TEXT,
+  enabled BOOLEAN DEFAULT true,
+  state TEXT DEFAULT 'ok' CHECK (state IN ('ok', 'triggered')),
+  last_value DOUBLE PRECISION,
+  last_evaluated_at TIMESTAMPTZ,
+  last_triggered_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS public.price_alert_events (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  alert_id UUID NOT NULL REFERENCES public.price_alerts(id) ON DELETE CASCADE,
+  profile_id UUID NOT NULL REFERENCES public.profiles(id) ON DELETE CASCADE,
+  code TEXT NOT NULL,
+  condition TEXT NOT NULL,
+  value DOUBLE PRECISION NOT NULL,
+  candle_date TEXT NOT NULL,
+  close DOUBLE PRECISION,
+  channels TEXT,
+  error TEXT,
+  created_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- A member's alerts, the evaluator's lookup by stock, and the trigger history
+CREATE INDEX IF NOT EXISTS idx_price_alerts_profile ON public.price_alerts(profile_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_price_alerts_code ON public.price_alerts(code) WHERE enabled;
+CREATE INDEX IF NOT EXISTS idx_price_alert_events_profile ON public.price_alert_events(profile_id, created_at DESC);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.price_alerts ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.price_alert_events ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,25 @@
+-- Migration: Member watchlists
+-- Named lists of stock codes per member profile. Symbols keep the member's
+-- order in position; quotes are read from the price buckets in MongoDB.
+
+CREATE TABLE IF NOT EXISTS public.watchlists (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  profile_id UUID NOT NULL REFERENCES public.profiles(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS public.watchlist_symbols (
+  watchlist_id UUID NOT NULL REFERENCES public.watchlists(id) ON DELETE CASCADE,
+  code TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  added_at TIMESTAMPTZ DEFAULT now(),
+  PRIMARY KEY (watchlist_id, code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watchlists_profile ON public.watchlists(profile_id, created_at);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.watchlists ENABLE ROW LEVEL SECURITY;
+ALTER TABLE public.watchlist_symbols ENABLE ROW LEVEL SECURITY;
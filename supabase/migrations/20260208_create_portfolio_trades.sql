@@ -0,0 +1,21 @@
+-- Migration: Member portfolio trades
+-- Buys and sells recorded per member profile. Holdings, cost basis and P&L
+-- are computed from the trades and the latest candles in MongoDB.
+
+CREATE TABLE IF NOT EXISTS public.portfolio_trades (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  profile_id UUID NOT NULL REFERENCES public.profiles(id) ON DELETE CASCADE,
+  code TEXT NOT NULL,
+  side TEXT NOT NULL DEFAULT 'buy' CHECK (side IN ('buy', 'sell')),
+  quantity BIGINT NOT NULL CHECK (quantity > 0),
+  price DOUBLE PRECISION NOT NULL CHECK (price > 0),
+  fee DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (fee >= 0),
+  trade_date TEXT NOT NULL,
+  note TEXT,
+  created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_portfolio_trades_profile ON public.portfolio_trades(profile_id, code, trade_date);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.portfolio_trades ENABLE ROW LEVEL SECURITY;
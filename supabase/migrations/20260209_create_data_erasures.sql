@@ -0,0 +1,19 @@
+-- Migration: Data erasure log
+-- One row per erased profile, kept for compliance after the profile's
+-- personal data is deleted or anonymized. Only a hash of the email is kept.
+
+CREATE TABLE IF NOT EXISTS public.data_erasures (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  profile_id UUID NOT NULL,
+  email_hash TEXT NOT NULL,
+  requested_by TEXT NOT NULL,
+  reason TEXT,
+  rows JSONB NOT NULL DEFAULT '{}'::jsonb,
+  created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_erasures_email_hash ON public.data_erasures(email_hash);
+CREATE INDEX IF NOT EXISTS idx_data_erasures_created_at ON public.data_erasures(created_at DESC);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.data_erasures ENABLE ROW LEVEL SECURITY;
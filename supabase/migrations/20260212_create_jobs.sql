@@ -0,0 +1,38 @@
+-- Migration: Durable background jobs
+-- Crawls, retries of failed symbols, backups and notifications are queued
+-- here instead of running in a goroutine of the instance that asked for
+-- them. Any instance claims due jobs (highest priority first), holds them
+-- under a renewed lock while they run and retries failures with backoff; a
+-- job whose instance stops is claimed again once its lock expires.
+
+CREATE TABLE IF NOT EXISTS public.jobs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  kind TEXT NOT NULL,
+  payload TEXT NOT NULL DEFAULT '{}',
+  unique_key TEXT,
+  priority INTEGER NOT NULL DEFAULT 0,
+  status TEXT NOT NULL CHECK (status IN ('queued', 'running', 'succeeded', 'failed', 'cancelled')),
+  attempts INTEGER NOT NULL DEFAULT 0,
+  max_attempts INTEGER NOT NULL DEFAULT 5,
+  run_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  locked_by TEXT,
+  locked_until TIMESTAMPTZ,
+  last_error TEXT,
+  created_by TEXT,
+  finished_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- The workers' queue of due and abandoned jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON public.jobs(priority DESC, run_at) WHERE status = 'queued';
+CREATE INDEX IF NOT EXISTS idx_jobs_locked ON public.jobs(locked_until) WHERE status = 'running';
+-- Inspection by kind and status, newest first
+CREATE INDEX IF NOT EXISTS idx_jobs_kind ON public.jobs(kind, created_at DESC);
+
+-- At most one unfinished job per unique key (e.g. one full crawl at a time)
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_unique_active ON public.jobs(unique_key)
+  WHERE unique_key IS NOT NULL AND status IN ('queued', 'running');
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.jobs ENABLE ROW LEVEL SECURITY;
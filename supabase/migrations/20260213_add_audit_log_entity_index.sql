@@ -0,0 +1,6 @@
+-- Migration: Index audit log rows by the entity they touched
+-- Serves the most-modified entities chart on the dashboard's security tab
+-- (GET /admin/api/audit-logs/entities).
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON public.audit_logs(entity_type, entity_id, created_at DESC)
+  WHERE entity_id IS NOT NULL;
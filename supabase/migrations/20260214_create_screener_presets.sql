@@ -0,0 +1,26 @@
+-- Migration: Saved screener presets
+-- Named screener criteria (the /api/market/screener query string) saved by
+-- members, or shared with every member by an admin (profile_id NULL). With
+-- notify, the backend re-runs a member's preset after every crawl and
+-- notifies the preset's channels of symbols that started matching;
+-- matched_codes keeps the matches of the last evaluation.
+
+CREATE TABLE IF NOT EXISTS public.screener_presets (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  profile_id UUID REFERENCES public.profiles(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  criteria TEXT NOT NULL,
+  notify BOOLEAN DEFAULT false CHECK (NOT notify OR profile_id IS NOT NULL),
+  channels TEXT NOT NULL DEFAULT '',
+  matched_codes TEXT,
+  last_run_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- A member's presets, and the evaluator's lookup of notifying presets
+CREATE INDEX IF NOT EXISTS idx_screener_presets_profile ON public.screener_presets(profile_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_screener_presets_notify ON public.screener_presets(id) WHERE notify;
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.screener_presets ENABLE ROW LEVEL SECURITY;
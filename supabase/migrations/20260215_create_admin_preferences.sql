@@ -0,0 +1,16 @@
+-- Migration: Per-admin display preferences
+-- The language and timezone the admin panel formats dates and numbers with,
+-- and the page size of its lists. Admins without a row get the defaults
+-- (English, the browser's timezone, 50 rows). Managed with
+-- /admin/api/preferences.
+
+CREATE TABLE IF NOT EXISTS public.admin_preferences (
+  admin_user_id UUID PRIMARY KEY REFERENCES public.admin_users(id) ON DELETE CASCADE,
+  language TEXT NOT NULL DEFAULT 'en' CHECK (language IN ('en', 'vi')),
+  timezone TEXT NOT NULL DEFAULT '',
+  page_size INTEGER NOT NULL DEFAULT 50 CHECK (page_size BETWEEN 10 AND 100),
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.admin_preferences ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,11 @@
+-- Migration: Allow sample_mismatch_ratio alert rules
+-- After each full crawl run the Go backend re-fetches a random sample of symbols and
+-- records the share of stored candles that differ from the provider on the run
+
+ALTER TABLE public.alert_rules DROP CONSTRAINT IF EXISTS alert_rules_type_check;
+ALTER TABLE public.alert_rules ADD CONSTRAINT alert_rules_type_check
+  CHECK (type IN ('no_successful_crawl', 'failed_symbols_ratio', 'stale_candles', 'sample_mismatch_ratio'));
+
+-- Seed the default rule (disabled until an admin reviews the channels)
+INSERT INTO public.alert_rules (name, type, threshold, channels, enabled) VALUES
+  ('More than 2% of sampled candles differ from the provider', 'sample_mismatch_ratio', 2, 'log', false);
@@ -0,0 +1,23 @@
+-- Migration: Daily candles in Postgres
+-- One row per daily candle of a stock or market index (prices in thousands
+-- of đồng), the Supabase layout of the MongoDB stock_prices buckets. Used
+-- when PRICE_STORES lists postgres; rows are only ever inserted, a candle
+-- already stored for a date is kept.
+
+CREATE TABLE IF NOT EXISTS public.stock_candles (
+  code TEXT NOT NULL,
+  date DATE NOT NULL,
+  open DOUBLE PRECISION NOT NULL,
+  high DOUBLE PRECISION NOT NULL,
+  low DOUBLE PRECISION NOT NULL,
+  close DOUBLE PRECISION NOT NULL,
+  volume BIGINT NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  PRIMARY KEY (code, date)
+);
+
+-- The crawler's newest date per symbol reads recent rows of every code
+CREATE INDEX IF NOT EXISTS idx_stock_candles_date ON public.stock_candles(date);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.stock_candles ENABLE ROW LEVEL SECURITY;
@@ -0,0 +1,7 @@
+-- Migration: Track in-place updates of daily candles
+-- The candle of the session in progress is upserted on every realtime flush
+-- until the session closes. The candles ETag hashes updated_at, so replacing
+-- a candle changes it even though the row count stays the same.
+
+ALTER TABLE public.stock_candles
+  ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ DEFAULT now();
@@ -0,0 +1,13 @@
+-- Migration: Time-boxed admin lockout
+-- An admin locked after login.max_failures failed logins is unlocked again at
+-- locked_until (login.lockout_duration later), so failed logins by anyone
+-- cannot lock an account for good. POST /admin/api/admin-users/:id/unlock and
+-- cmd/create-admin still lift a lockout early. Accounts locked before this
+-- migration get the default 15 minutes from now.
+
+ALTER TABLE public.admin_users
+  ADD COLUMN IF NOT EXISTS locked_until TIMESTAMPTZ;
+
+UPDATE public.admin_users
+SET locked_until = now() + interval '15 minutes'
+WHERE locked_at IS NOT NULL AND locked_until IS NULL;
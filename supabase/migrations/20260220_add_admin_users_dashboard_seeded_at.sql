@@ -0,0 +1,14 @@
+-- Migration: Seed the default dashboard once per admin
+-- GET /admin/api/dashboard/widgets saves the default widgets on an admin's
+-- first visit and records it in dashboard_seeded_at, so an admin who deletes
+-- every widget keeps an empty dashboard instead of getting the defaults back.
+-- POST /admin/api/dashboard/reset still restores them. Admins who
+-- already have widgets were seeded before this migration.
+
+ALTER TABLE public.admin_users
+  ADD COLUMN IF NOT EXISTS dashboard_seeded_at TIMESTAMPTZ;
+
+UPDATE public.admin_users u
+SET dashboard_seeded_at = now()
+WHERE dashboard_seeded_at IS NULL
+  AND EXISTS (SELECT 1 FROM public.dashboard_widgets w WHERE w.admin_user_id = u.id);
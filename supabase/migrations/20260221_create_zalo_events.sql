@@ -0,0 +1,16 @@
+-- Migration: Replay protection for the Zalo OA webhook
+-- Events whose timestamp is more than 5 minutes from the server clock are
+-- rejected; the ones accepted are remembered here for 10 minutes, keyed by
+-- their signature (Zalo events carry no ID), so a replayed delivery or read
+-- receipt is acknowledged without being applied again.
+
+CREATE TABLE IF NOT EXISTS public.zalo_events (
+  event_id TEXT PRIMARY KEY,
+  event_name TEXT NOT NULL,
+  received_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_zalo_events_received ON public.zalo_events(received_at);
+
+-- Written and read only by the backend (service role)
+ALTER TABLE public.zalo_events ENABLE ROW LEVEL SECURITY;